@@ -21,6 +21,7 @@ echo "Running go vet"
         go vet ./ingest/log
         go vet ./timegrinder
         go vet ./filewatch
+        go vet ./journald
         go vet ./ingesters/utils
         go vet ./ingesters/kafka_consumer
         go vet ./ingesters/SimpleRelay
@@ -40,6 +41,7 @@ echo "Running go test -v"
         go test -v ./ingest/log
         go test -v ./timegrinder
         go test -v ./filewatch
+        go test -v ./journald
         go test -v ./ingesters/utils
         go test -v ./ingesters/kafka_consumer
         go test -v ./ingesters/SimpleRelay
@@ -72,6 +74,7 @@ echo "running staticcheck"
 	staticcheck ./netflow/...
 	staticcheck ./migrate/...
 	staticcheck ./filewatch/...
+	staticcheck ./journald/...
 	staticcheck ./client/...
 	staticcheck ./ingesters/args/...
 	staticcheck ./ingesters/base/...
@@ -85,6 +88,7 @@ echo "running staticcheck"
 	staticcheck ./ingesters/GooglePubSubIngester/...
 	staticcheck ./ingesters/hackernews_ingester/...
 	staticcheck ./ingesters/IPMIIngester/...
+	staticcheck ./ingesters/journald/...
 	staticcheck ./ingesters/kafka_consumer/...
 	staticcheck ./ingesters/KinesisIngester/...
 	staticcheck ./ingesters/massFile/...
@@ -116,6 +120,7 @@ echo "running govulncheck on everything"
         govulncheck -test ./generators/base/...
         govulncheck -test ./generators/gravwellGenerator/...
         govulncheck -test ./filewatch/...
+        govulncheck -test ./journald/...
         govulncheck -test ./client/...
         govulncheck -test ./chancacher/...
         govulncheck -test ./timegrinder/...
@@ -156,6 +161,7 @@ echo "running govulncheck on everything"
         govulncheck -test ./ingesters/AzureEventHubs
         govulncheck -test ./ingesters/utils
         govulncheck -test ./ingesters/IPMIIngester
+        govulncheck -test ./ingesters/journald
         govulncheck -test ./ingesters/regexFile
         govulncheck -test ./ingesters/PacketFleet
         GOOS=linux govulncheck -test ./ingesters/canbus
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofrs/flock"
@@ -39,7 +40,7 @@ const MaxDepth = 1000000
 type ChanCacher struct {
 	In      chan interface{}
 	Out     chan interface{}
-	runDone bool
+	runDone atomic.Bool
 	maxSize int
 
 	cachePath      string
@@ -47,14 +48,14 @@ type ChanCacher struct {
 	cacheR         *fileCounter
 	cacheW         *fileCounter
 	cacheEnc       *gob.Encoder
-	cacheModified  bool
+	cacheModified  atomic.Bool
 	cacheLock      sync.Mutex
-	cacheReading   bool
+	cacheReading   atomic.Bool
 	cachePaused    chan bool
 	cacheDone      chan bool
 	cacheAck       chan bool
 	cacheIsDone    bool
-	cacheCommitted bool
+	cacheCommitted atomic.Bool
 
 	fileLock *flock.Flock
 }
@@ -189,7 +190,7 @@ func NewChanCacher(maxDepth int, cachePath string, maxSize int) (*ChanCacher, er
 			return nil, err
 		}
 		if fi.Size() != 0 {
-			c.cacheModified = true
+			c.cacheModified.Store(true)
 		}
 
 		go c.cacheHandler()
@@ -225,12 +226,12 @@ func (c *ChanCacher) run() {
 		}
 	}
 
-	c.runDone = true
+	c.runDone.Store(true)
 
 	if c.cache {
 		// closing c.In stops reading input, but we allow the cache to drain
 		// before closing c.Out.
-		for c.CacheHasData() && !c.cacheCommitted {
+		for c.CacheHasData() && !c.cacheCommitted.Load() {
 			time.Sleep(100 * time.Millisecond)
 		}
 
@@ -252,7 +253,7 @@ func (c *ChanCacher) cacheHandler() {
 	// the main cache loop. We read from R, putting data into out directly
 	// until R is drained. Once R is drained, wait for W to have data and
 	// for run() to signal that we can swap buffers.
-	c.cacheReading = true
+	c.cacheReading.Store(true)
 	for {
 		var err error
 
@@ -271,7 +272,7 @@ func (c *ChanCacher) cacheHandler() {
 		}
 		// TODO log if err != io.EOF
 
-		c.cacheReading = false
+		c.cacheReading.Store(false)
 		c.cacheR.Seek(0, 0)
 		c.cacheR.Truncate(0)
 
@@ -285,7 +286,7 @@ func (c *ChanCacher) cacheHandler() {
 		}
 
 		// Wait for W to have data.
-		for !c.cacheModified {
+		for !c.cacheModified.Load() {
 			select {
 			case <-c.cacheDone:
 				close(c.cacheAck)
@@ -299,8 +300,8 @@ func (c *ChanCacher) cacheHandler() {
 		c.cacheR, c.cacheW = c.cacheW, c.cacheR
 		c.cacheR.Seek(0, 0)
 		c.cacheEnc = gob.NewEncoder(c.cacheW)
-		c.cacheModified = false
-		c.cacheReading = true
+		c.cacheModified.Store(false)
+		c.cacheReading.Store(true)
 		c.cacheLock.Unlock()
 	}
 }
@@ -317,12 +318,12 @@ func (c *ChanCacher) cacheValue(v interface{}) {
 	defer c.cacheLock.Unlock()
 	c.cacheEnc.Encode(&v)
 	// TODO log if err := c.cacheEnc.Encode(&v); err != nil
-	c.cacheModified = true
+	c.cacheModified.Store(true)
 }
 
 // CacheHasData returns if the cache has outstanding data not written to the output channel.
 func (c *ChanCacher) CacheHasData() bool {
-	return c.cacheModified || c.cacheReading
+	return c.cacheModified.Load() || c.cacheReading.Load()
 }
 
 // BufferSize returns the number of elements on the internal buffer.
@@ -390,7 +391,7 @@ func (c *ChanCacher) Drain() {
 // scenarios.
 func (c *ChanCacher) Commit() {
 	if !c.cache {
-		c.cacheCommitted = true
+		c.cacheCommitted.Store(true)
 		return
 	}
 
@@ -398,7 +399,7 @@ func (c *ChanCacher) Commit() {
 
 	// read from out and write back to the cache
 	readerStopped := false
-	for !c.runDone || len(c.Out) != 0 || !readerStopped {
+	for !c.runDone.Load() || len(c.Out) != 0 || !readerStopped {
 		select {
 		case <-c.cacheAck:
 			readerStopped = true
@@ -417,7 +418,7 @@ func (c *ChanCacher) Commit() {
 		c.fileLock.Unlock()
 	}
 
-	c.cacheCommitted = true
+	c.cacheCommitted.Store(true)
 }
 
 func (c *ChanCacher) finishCache() {
@@ -433,6 +434,8 @@ func (c *ChanCacher) finishCache() {
 // Size returns the number of bytes committed to disk. This does not include data in
 // the in-memory buffer.
 func (c *ChanCacher) Size() int {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
 	return c.cacheR.Count() + c.cacheW.Count()
 }
 
@@ -1,10 +1,13 @@
 package chancacher
 
-import "os"
+import (
+	"os"
+	"sync/atomic"
+)
 
 type fileCounter struct {
 	*os.File
-	count int
+	count atomic.Int64 // the cache reader counts down while other routines check the size
 }
 
 func NewFileCounter(f *os.File) (*fileCounter, error) {
@@ -12,20 +15,21 @@ func NewFileCounter(f *os.File) (*fileCounter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &fileCounter{
-		File:  f,
-		count: int(fi.Size()),
-	}, nil
+	fc := &fileCounter{
+		File: f,
+	}
+	fc.count.Store(fi.Size())
+	return fc, nil
 }
 
 func (f *fileCounter) Write(b []byte) (n int, err error) {
-	f.count += len(b)
+	f.count.Add(int64(len(b)))
 	return f.File.Write(b)
 }
 
 func (f *fileCounter) Read(b []byte) (n int, err error) {
 	n, err = f.File.Read(b)
-	f.count -= n
+	f.count.Add(-int64(n))
 	return
 }
 
@@ -33,5 +37,5 @@ func (f *fileCounter) Count() int {
 	if f == nil || f.File == nil {
 		return 0
 	}
-	return f.count
+	return int(f.count.Load())
 }
@@ -98,6 +98,11 @@ func (wm *WatchManager) SetMaxFilesWatched(max int) {
 	wm.fman.SetMaxFilesWatched(max)
 }
 
+// SetStateSync ties state file updates to a sync barrier, see FilterManager.SetStateSync
+func (wm *WatchManager) SetStateSync(fn func() error) {
+	wm.fman.SetStateSync(fn)
+}
+
 func (wm *WatchManager) Context() context.Context {
 	return wm.ctx
 }
@@ -166,10 +171,62 @@ func (wm *WatchManager) Close() error {
 	return err
 }
 
+// Add starts watching the location described by c, if the manager is already running
+// files that are already in the location are picked up immediately
 func (wm *WatchManager) Add(c WatchConfig) error {
 	wm.mtx.Lock()
 	defer wm.mtx.Unlock()
-	return wm.addNoLock(c)
+	if err := wm.addNoLock(c); err != nil {
+		return err
+	} else if wm.routineRet == nil {
+		return nil //Start will load everything
+	} else if _, ok := wm.watched[c.BaseDir]; !ok {
+		return nil //not there yet, we will pick it up when the directory appears
+	}
+	wfs, err := wm.getWatchedFilesInDir(c.BaseDir)
+	if err != nil {
+		return err
+	}
+	for _, wf := range wfs {
+		if _, err := wm.fman.LoadFile(wf.pth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveConfig stops watching every location added under the named WatchConfig and closes its followers
+func (wm *WatchManager) RemoveConfig(name string) error {
+	wm.mtx.Lock()
+	defer wm.mtx.Unlock()
+	if wm.watcher == nil || wm.fman == nil {
+		return ErrNotReady
+	}
+	for dir, cfgs := range wm.watched {
+		if kept := dropConfig(cfgs, name); len(kept) == 0 {
+			delete(wm.watched, dir)
+			wm.watcher.Remove(dir)
+		} else {
+			wm.watched[dir] = kept
+		}
+	}
+	for dir, cfgs := range wm.removed {
+		if kept := dropConfig(cfgs, name); len(kept) == 0 {
+			delete(wm.removed, dir)
+		} else {
+			wm.removed[dir] = kept
+		}
+	}
+	return wm.fman.RemoveFilter(name)
+}
+
+func dropConfig(cfgs []WatchConfig, name string) (r []WatchConfig) {
+	for _, c := range cfgs {
+		if c.ConfigName != name {
+			r = append(r, c)
+		}
+	}
+	return
 }
 
 func (wm *WatchManager) addNoLock(c WatchConfig) error {
@@ -431,6 +488,9 @@ func (wm *WatchManager) Catchup(qc chan os.Signal) (bool, error) {
 			return quit, err
 		}
 	}
+	if err := wm.fman.RecoverRotated(); err != nil {
+		wm.logger.Warn("failed to recover rotated files", log.KVErr(err))
+	}
 	return false, nil
 }
 
@@ -493,7 +553,9 @@ watchRoutine:
 					continue
 				}
 				if fi.IsDir() {
+					wm.mtx.Lock()
 					parents, ok := wm.watched[filepath.Dir(evt.Name)]
+					wm.mtx.Unlock()
 					if !ok {
 						wm.logger.Error("failed to find parent directory", log.KV("path", evt.Name))
 						continue
@@ -542,6 +604,9 @@ watchRoutine:
 				}
 			}
 		case <-tckr.C:
+			if err := wm.fman.RecoverRotated(); err != nil {
+				wm.logger.Error("failed to recover rotated files", log.KVErr(err))
+			}
 			if err := wm.fman.FlushStates(); err != nil {
 				wm.logger.Error("failed to flush states", log.KVErr(err))
 			}
@@ -888,6 +888,68 @@ func TestSingleWatcherNonExist(t *testing.T) {
 	}
 }
 
+func TestWatcherAddRemoveConfig(t *testing.T) {
+	lha, lhb := newSafeTrackingLH(), newSafeTrackingLH()
+	var dir string
+	var resb map[string]bool
+	fireWatcher(func(workingDir string, w *WatchManager) error {
+		return w.Add(WatchConfig{ConfigName: `a`, BaseDir: workingDir, FileFilter: `a*`, Hnd: lha})
+	}, func(workingDir string) (err error) {
+		//these are already there when the second config shows up
+		if err = os.Mkdir(filepath.Join(workingDir, `sub`), 0700); err != nil {
+			return
+		}
+		_, resb, err = writeLines(filepath.Join(workingDir, `b1`))
+		return
+	}, func(workingDir string) error {
+		dir = workingDir
+		return nil
+	}, func(w *WatchManager) error {
+		//a config added to a running watcher picks up existing files
+		for _, d := range []string{dir, filepath.Join(dir, `sub`)} {
+			if err := w.Add(WatchConfig{ConfigName: `b`, BaseDir: d, FileFilter: `b*`, Hnd: lhb}); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < 100 && lhb.Len() != len(resb); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if lhb.Len() != len(resb) {
+			return fmt.Errorf("added config read %d of %d lines", lhb.Len(), len(resb))
+		} else if w.Filters() != 3 || w.Followers() != 1 || len(w.watched) != 2 {
+			return fmt.Errorf("bad counts %d %d %d", w.Filters(), w.Followers(), len(w.watched))
+		}
+
+		//removing it closes its followers and stops watching locations only it used
+		if err := w.RemoveConfig(`b`); err != nil {
+			return err
+		} else if w.Filters() != 1 || w.Followers() != 0 || len(w.watched) != 1 {
+			return fmt.Errorf("bad counts after remove %d %d %d", w.Filters(), w.Followers(), len(w.watched))
+		}
+		if _, _, err := writeLines(filepath.Join(dir, `b2`)); err != nil {
+			return err
+		}
+		if res, err := writeLinesAndWait(filepath.Join(dir, `a1`), lha); err != nil {
+			return err
+		} else if lha.Len() != len(res) {
+			return fmt.Errorf("remaining config read %d of %d lines", lha.Len(), len(res))
+		} else if lhb.Len() != len(resb) {
+			return fmt.Errorf("removed config still reading, %d lines", lhb.Len())
+		}
+		return nil
+	}, t)
+}
+
+func writeLinesAndWait(pth string, lh *safeTrackingLH) (res map[string]bool, err error) {
+	if _, res, err = writeLines(pth); err != nil {
+		return
+	}
+	for i := 0; i < 100 && lh.Len() != len(res); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return
+}
+
 type safeTrackingLH struct {
 	testTagger
 	sync.Mutex
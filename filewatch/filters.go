@@ -65,17 +65,21 @@ type FilterManager struct {
 	filters         []filter
 	followers       map[FileName]*follower
 	states          map[FileName]*int64
+	idents          map[FileName]fileIdent
+	pending         []rotatedFile
 	stateFile       string
 	stateFout       *os.File
 	maxFilesWatched int
 	logger          ingest.IngestLogger
+	stateSync       func() error
 }
 
 func NewFilterManager(stateFile string) (*FilterManager, error) {
-	fout, states, err := initStateFile(stateFile)
+	fout, states, rs, err := initStateFile(stateFile)
 	if err != nil {
 		return nil, err
 	}
+	idents, pending := loadRotations(states, rs)
 	if err := cleanStates(states); err != nil {
 		fout.Close()
 		return nil, err
@@ -86,6 +90,8 @@ func NewFilterManager(stateFile string) (*FilterManager, error) {
 		stateFile: stateFile,
 		stateFout: fout,
 		states:    states,
+		idents:    idents,
+		pending:   pending,
 		followers: map[FileName]*follower{},
 		logger:    ingest.NoLogger(),
 	}, nil
@@ -162,6 +168,9 @@ func (f *FilterManager) Close() (err error) {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
 
+	//grab the identities while the files are still open
+	f.nolockIdents()
+
 	//we have to actually close followers
 	for _, v := range f.followers {
 		if lerr := v.Close(); lerr != nil {
@@ -173,11 +182,12 @@ func (f *FilterManager) Close() (err error) {
 	//just shitcan filters, no need to close anything
 	f.filters = nil
 
-	if err := f.nolockDumpStates(); err != nil {
-		return err
+	//always close the state file, even if the final states could not be synced
+	if lerr := f.nolockDumpStates(); lerr != nil {
+		err = appendErr(err, lerr)
 	}
-	if err := f.stateFout.Close(); err != nil {
-		return err
+	if lerr := f.stateFout.Close(); lerr != nil {
+		return appendErr(err, lerr)
 	}
 	f.stateFout = nil
 	return
@@ -199,17 +209,66 @@ func (f *FilterManager) Filters() int {
 	return len(f.filters)
 }
 
+// SetStateSync installs a sync barrier for the state file.  When set, the offsets written to the
+// state file are captured before calling fn and only written if fn succeeds, so fn must block
+// until everything handed to the handlers so far has been acknowledged (e.g. IngestMuxer.Sync).
+// A restart then replays unacknowledged lines instead of skipping them.
+func (f *FilterManager) SetStateSync(fn func() error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.stateSync = fn
+}
+
 // FlushStates flushes the current state of followed files to the disk
 // periodically flushing states is a good idea, incase the device crashes, or the process is abruptly killed
 func (f *FilterManager) FlushStates() error {
+	f.mtx.Lock()
+	if f.stateSync == nil {
+		defer f.mtx.Unlock()
+		return f.nolockDumpStates()
+	}
+	//don't hold the lock while waiting on the sync, new files can keep showing up
+	states, fn := f.nolockSnapshotStates(), f.stateSync
+	f.mtx.Unlock()
+	if err := fn(); err != nil {
+		return fmt.Errorf("state sync failed, offsets not updated: %w", err)
+	}
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
-	return f.nolockDumpStates()
+	return f.nolockWriteStates(states)
 }
 
-// nolockDumpStates pushes the current set of states out to a file
+// nolockSnapshotStates copies the current offsets so they can't move while we wait on a sync
+// caller MUST HOLD THE LOCK
+func (f *FilterManager) nolockSnapshotStates() map[FileName]*int64 {
+	states := make(map[FileName]*int64, len(f.states))
+	for k, v := range f.states {
+		var st int64
+		if v != nil {
+			st = *v
+		}
+		states[k] = &st
+	}
+	return states
+}
+
+// nolockDumpStates pushes the current set of states out to a file, if there is a state
+// sync the states are only written once everything up to them has been synced
 // caller MUST HOLD THE LOCK
 func (f *FilterManager) nolockDumpStates() error {
+	if f.stateSync == nil {
+		return f.nolockWriteStates(f.states)
+	}
+	states := f.nolockSnapshotStates()
+	if err := f.stateSync(); err != nil {
+		return fmt.Errorf("state sync failed, offsets not updated: %w", err)
+	}
+	return f.nolockWriteStates(states)
+}
+
+// nolockWriteStates writes the given states out to the state file
+// caller MUST HOLD THE LOCK
+func (f *FilterManager) nolockWriteStates(states map[FileName]*int64) error {
 	if f.stateFout == nil {
 		return nil
 	}
@@ -223,7 +282,15 @@ func (f *FilterManager) nolockDumpStates() error {
 	if err := f.stateFout.Truncate(0); err != nil {
 		return err
 	}
-	if err := gob.NewEncoder(f.stateFout).Encode(f.states); err != nil {
+	enc := gob.NewEncoder(f.stateFout)
+	if err := enc.Encode(states); err != nil {
+		return err
+	}
+	rs := rotationState{
+		Idents:  f.nolockIdents(),
+		Pending: f.pending,
+	}
+	if err := enc.Encode(rs); err != nil {
 		return err
 	}
 	return nil
@@ -249,6 +316,39 @@ func (f *FilterManager) AddFilter(bname, loc string, mtchs []string, lh handler,
 	return nil
 }
 
+// RemoveFilter closes every follower started by the named filter and drops the filter.  States
+// are kept so that a filter added back under the same name picks up where it left off.
+func (f *FilterManager) RemoveFilter(bname string) (err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for k, v := range f.followers {
+		if k.BaseName == bname {
+			delete(f.followers, k)
+			if lerr := v.Close(); lerr != nil {
+				err = appendErr(err, lerr)
+			}
+		}
+	}
+	//follower filter IDs index the filter list, point them at where their filter ends up
+	idx := make(map[int]int, len(f.filters))
+	var kept []filter
+	for i, v := range f.filters {
+		if v.bname != bname {
+			idx[i] = len(kept)
+			kept = append(kept, v)
+		}
+	}
+	f.filters = kept
+	for _, v := range f.followers {
+		if id, ok := idx[v.filterId]; ok {
+			v.filterId = id
+		} else {
+			v.filterId = -1
+		}
+	}
+	return
+}
+
 func (f *FilterManager) RemoveDirectory(path string) error {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
@@ -290,8 +390,11 @@ func (f *FilterManager) nolockRemoveFollower(fpath string, purgeState bool) (rem
 			delete(f.followers, stid)
 			if purgeState {
 				delete(f.states, stid)
+				err = f.closeRotated(stid, fl)
+			} else {
+				err = fl.Close()
 			}
-			if err = fl.Close(); err != nil {
+			if err != nil {
 				return
 			}
 			removed = true
@@ -515,6 +618,9 @@ func (f *FilterManager) launchFollowers(fpath string, deleteState bool) (ok bool
 	if err != nil {
 		return false, err
 	}
+	if isCompressedFile(fpath) {
+		return false, nil //compressed rotations are only read by RecoverRotated
+	}
 
 	//check if this is just a renaming
 	isRename, err := f.checkRename(fpath, id)
@@ -523,6 +629,9 @@ func (f *FilterManager) launchFollowers(fpath string, deleteState bool) (ok bool
 	} else if isRename {
 		return true, nil //just a file renaming, continue
 	}
+	if f.nolockAdoptRotated(fpath, id) {
+		deleteState = false
+	}
 
 	//get base dir
 	fname := filepath.Base(fpath)
@@ -642,7 +751,7 @@ func (f *FilterManager) checkRename(fpath string, id FileId) (isRename bool, err
 			if removeFollower {
 				//this is a move away from the current filter, so delete the follower
 				//and delete the state
-				if err = v.Close(); err != nil {
+				if err = f.closeRotated(k, v); err != nil {
 					return
 				}
 				delete(f.states, k)
@@ -685,6 +794,9 @@ func (f *FilterManager) CatchupFile(wf watchedFile, qc chan os.Signal) (bool, er
 		}
 		return false, err
 	}
+	if isCompressedFile(wf.pth) {
+		return false, nil
+	}
 
 	//check if this is just a renaming
 	isRename, err := f.checkRename(wf.pth, id)
@@ -693,6 +805,7 @@ func (f *FilterManager) CatchupFile(wf watchedFile, qc chan os.Signal) (bool, er
 	} else if isRename {
 		return false, nil //just a file renaming do not attempt to re-add
 	}
+	f.nolockAdoptRotated(wf.pth, id)
 
 	//get base dir
 	fname := filepath.Base(wf.pth)
@@ -731,12 +844,15 @@ func (f *FilterManager) CatchupFile(wf watchedFile, qc chan os.Signal) (bool, er
 // catchupFollower is a linear operation to get outstanding files up to date.
 func (f *FilterManager) catchupFollower(fcfg FollowerConfig, qc chan os.Signal) (bool, error) {
 	f.logger.Info("performing initial catch-up preprocessing for file", log.KV("file", fcfg.FilePath))
-	if fl, err := NewFollower(fcfg); err != nil {
+	fl, err := NewFollower(fcfg)
+	if err != nil {
 		return false, err
 	} else if quit, err := fl.Sync(qc); err != nil || quit {
 		fl.Close()
 		return quit, err
-	} else if err = fl.Close(); err != nil {
+	}
+	f.idents[fl.FileName] = fl.ident()
+	if err = fl.Close(); err != nil {
 		return false, err
 	}
 	f.logger.Info("file preprocessed at startup", log.KV("path", fcfg.FilePath))
@@ -812,7 +928,7 @@ func ReadStateFile(p string) (states map[string]int64, err error) {
 	return
 }
 
-func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err error) {
+func initStateFile(p string) (fout *os.File, states map[FileName]*int64, rs rotationState, err error) {
 	var fi os.FileInfo
 	states = map[FileName]*int64{}
 	//attempt to open state file
@@ -848,7 +964,8 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 		return
 	}
 	if fi.Size() > 0 {
-		if err = gob.NewDecoder(fout).Decode(&states); err != nil {
+		dec := gob.NewDecoder(fout)
+		if err = dec.Decode(&states); err != nil {
 			// hold onto the decode error in case we can't get to a backup
 			serr := err
 			fout.Close()
@@ -868,7 +985,7 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 
 				if count == RENAME_COUNT_MAX {
 					// if we got here then we ran out of attempts
-					return nil, nil, fmt.Errorf("Failed to rename old state file")
+					return nil, nil, rs, fmt.Errorf("Failed to rename old state file")
 				}
 			}
 
@@ -880,6 +997,10 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 			// success!
 			return initStateFile(p)
 		}
+		//rotation tracking is optional, state files written by older versions won't have it
+		if dec.Decode(&rs) != nil {
+			rs = rotationState{}
+		}
 	}
 	return
 }
@@ -0,0 +1,69 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateSync(t *testing.T) {
+	state := filepath.Join(t.TempDir(), `state`)
+	fm, err := NewFilterManager(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	si := fm.addSeekInfo(`app`, `/var/log/app.log`)
+	*si = 100
+	if err = fm.FlushStates(); err != nil {
+		t.Fatal(err)
+	}
+
+	//a failed sync leaves the state file alone
+	errSync := errors.New("not acknowledged")
+	fm.SetStateSync(func() error { return errSync })
+	*si = 200
+	if err = fm.FlushStates(); !errors.Is(err, errSync) {
+		t.Fatalf("bad sync error %v", err)
+	}
+	checkStateFile(t, state, 100)
+
+	//offsets that move while the sync is running are not written
+	fm.SetStateSync(func() error {
+		*si = 300
+		return nil
+	})
+	if err = fm.FlushStates(); err != nil {
+		t.Fatal(err)
+	}
+	checkStateFile(t, state, 200)
+
+	//close also syncs before the final write
+	fm.SetStateSync(func() error { return errSync })
+	if err = fm.Close(); !errors.Is(err, errSync) {
+		t.Fatalf("bad close error %v", err)
+	}
+	checkStateFile(t, state, 200)
+}
+
+func checkStateFile(t *testing.T, state string, want int64) {
+	t.Helper()
+	states, err := ReadStateFile(state)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(states) != 1 {
+		t.Fatalf("bad state count %d", len(states))
+	}
+	for _, v := range states {
+		if v != want {
+			t.Fatalf("bad state %d != %d", v, want)
+		}
+	}
+}
@@ -38,6 +38,12 @@ var (
 	tickInterval  = 5 * time.Second
 )
 
+// lineHolder is implemented by readers that consume lines without handing each one out, they
+// flush their own idle entries and report when they last read a line
+type lineHolder interface {
+	lastRead() time.Time
+}
+
 type handler interface {
 	HandleLog([]byte, time.Time, string) error
 	Tag() string
@@ -51,6 +57,7 @@ type FileId struct {
 type FollowerEngineConfig struct {
 	Engine     int
 	EngineArgs string
+	Multiline  MultilineConfig
 }
 
 type FollowerConfig struct {
@@ -66,6 +73,8 @@ type follower struct {
 	FileName
 	filterId int
 	id       FileId
+	fin      *os.File
+	head     []byte
 	lnr      Reader
 	state    *int64
 	mtx      *sync.Mutex
@@ -102,6 +111,7 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 		StartIndex: *cfg.State,
 		Engine:     cfg.Engine,
 		EngineArgs: cfg.EngineArgs,
+		Multiline:  cfg.Multiline,
 	}
 	lnr, err := NewReader(rdrCfg)
 	if err != nil {
@@ -119,6 +129,7 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 	return &follower{
 		filterId: cfg.FilterID,
 		id:       id,
+		fin:      fin,
 		lnr:      lnr,
 		mtx:      &sync.Mutex{},
 		wg:       &sync.WaitGroup{},
@@ -296,7 +307,6 @@ func (f *follower) IdleDuration() time.Duration {
 // If we got a writeEvent and ReadLine returns an EOF, we need to check
 // and make sure the file wasn't truncated
 func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
-	var hit bool
 	for {
 		ln, ok, sawEOF, err := f.lnr.ReadEntry()
 		if err != nil {
@@ -318,9 +328,14 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 		}
 		if !ok && sawEOF {
 			// e.g. no trailing newline or delimiter, but what IS there has been sitting for XYZ seconds
-			// go ahead and consume it
+			// go ahead and consume it, readers that hold lines decide for themselves when to flush
 			var force bool
-			if idleTime := time.Since(f.lastAct); (idleTime > maxIdleDataTime && allowPartial) || removing {
+			if lh, holds := f.lnr.(lineHolder); holds {
+				if lr := lh.lastRead(); lr.After(f.lastAct) {
+					f.lastAct = lr
+				}
+				force = removing
+			} else if idleTime := time.Since(f.lastAct); (idleTime > maxIdleDataTime && allowPartial) || removing {
 				force = true
 			}
 			if force {
@@ -328,8 +343,8 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 					return err
 				} else if len(ln) > 0 {
 					if err = f.lh.HandleLog(ln, time.Now(), f.FilePath); err == nil {
-						hit = true
 						*f.state = f.lnr.Index()
+						f.lastAct = time.Now()
 					}
 				}
 				return err
@@ -341,9 +356,6 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 			return err
 		}
 		*f.state = f.lnr.Index()
-		hit = true
-	}
-	if hit {
 		f.lastAct = time.Now()
 	}
 	return nil
@@ -11,6 +11,7 @@ package filewatch
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -257,3 +258,80 @@ type testTagger struct{}
 func (tt testTagger) Tag() string {
 	return `default`
 }
+
+type orderedLH struct {
+	testTagger
+	evs []string
+}
+
+func (h *orderedLH) HandleLog(b []byte, ts time.Time, fname string) error {
+	h.evs = append(h.evs, string(b))
+	return nil
+}
+
+func TestFollowerMultilinePause(t *testing.T) {
+	var olh orderedLH
+	var st int64
+	pth := filepath.Join(t.TempDir(), `app.log`)
+	fout, err := os.Create(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fout.Close()
+	fl, err := NewFollower(FollowerConfig{
+		BaseName: baseName,
+		FilePath: pth,
+		State:    &st,
+		Handler:  &olh,
+		FollowerEngineConfig: FollowerEngineConfig{
+			Engine: MultilineEngine,
+			Multiline: MultilineConfig{
+				StartPattern: `^\d`,
+				FlushTimeout: 30 * time.Second,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	mr := fl.lnr.(*MultilineReader)
+	//pause shifts every timestamp the follower and reader keep back as if the writer went quiet for d
+	pause := func(d time.Duration) {
+		fl.lastAct = fl.lastAct.Add(-d)
+		mr.lastLine = mr.lastLine.Add(-d)
+	}
+	write := func(s string) {
+		if _, err := fout.WriteString(s); err != nil {
+			t.Fatal(err)
+		} else if err = fl.processLines(true, false, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//a pause longer than the follower idle time inside an event must not split it
+	write("1 starting\n")
+	pause(maxIdleDataTime + time.Second)
+	write("")
+	if len(olh.evs) != 0 {
+		t.Fatalf("event flushed before the multiline flush timeout %q", olh.evs)
+	}
+	write("  continued\n")
+	if d := fl.IdleDuration(); d > time.Second {
+		t.Fatalf("consuming a line did not count as activity, idle for %v", d)
+	}
+	write("2 next\n")
+	if len(olh.evs) != 1 || olh.evs[0] != "1 starting\n  continued" {
+		t.Fatalf("bad events %q", olh.evs)
+	}
+
+	//once the flush timeout passes the last event and any partial line go out together
+	write("  tail")
+	pause(31 * time.Second)
+	write("")
+	if len(olh.evs) != 2 || olh.evs[1] != "2 next\n  tail" {
+		t.Fatalf("bad idle flush %q", olh.evs)
+	} else if st != mr.Index() || st != int64(len("1 starting\n  continued\n2 next\n  tail")) {
+		t.Fatalf("bad state %d", st)
+	}
+}
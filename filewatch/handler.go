@@ -69,6 +69,7 @@ type LogHandlerConfig struct {
 	AttachFilename          bool
 	Trim                    bool // run trim space on entries
 	TimestampWindow         timegrinder.TimestampWindow
+	FormatLearning          bool // remember the timestamp format of each file
 }
 
 type logWriter interface {
@@ -86,8 +87,9 @@ func NewLogHandler(cfg LogHandlerConfig, w logWriter) (*LogHandler, error) {
 	}
 	if !cfg.IgnoreTS {
 		tcfg := timegrinder.Config{
-			EnableLeftMostSeed: true,
-			TSWindow:           cfg.TimestampWindow,
+			EnableLeftMostSeed:   true,
+			TSWindow:             cfg.TimestampWindow,
+			EnableFormatLearning: cfg.FormatLearning,
 		}
 		if tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
 			return nil, err
@@ -145,6 +147,11 @@ func (lh *LogHandler) Tag() string {
 	return lh.LogHandlerConfig.TagName
 }
 
+// TimeGrinder returns the timegrinder used to extract timestamps, nil if timestamps are ignored
+func (lh *LogHandler) TimeGrinder() *timegrinder.TimeGrinder {
+	return lh.tg
+}
+
 func (lh *LogHandler) HandleLog(b []byte, catchts time.Time, fname string) error {
 	if len(b) == 0 {
 		return nil
@@ -167,7 +174,7 @@ func (lh *LogHandler) HandleLog(b []byte, catchts time.Time, fname string) error
 	}
 
 	if !lh.IgnoreTS {
-		ts, ok, err = lh.tg.Extract(b)
+		ts, ok, err = lh.tg.ExtractKey(timegrinder.FormatKey{Filename: fname}, b)
 		if err != nil {
 			lh.Logger.Error("catastrophic timegrinder failure", log.KVErr(err))
 			return err
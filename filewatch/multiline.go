@@ -0,0 +1,190 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"regexp"
+	"time"
+)
+
+// MultilineReader assembles lines into events such as stack traces and tracebacks.
+// The index only advances past complete events, so an event that is still being
+// assembled is read again if the follower is restarted.
+type MultilineReader struct {
+	lr       *LineReader
+	start    *regexp.Regexp
+	cont     *regexp.Regexp
+	indent   bool
+	maxEvent int
+	flush    time.Duration
+	idx      int64 // end of the last event handed out
+	pending  []byte
+	pendEnd  int64 // end of the last line in the pending event
+	lastLine time.Time
+}
+
+func NewMultilineReader(cfg ReaderConfig) (*MultilineReader, error) {
+	mc := cfg.Multiline
+	if err := mc.Validate(); err != nil {
+		return nil, err
+	}
+	lr, err := NewLineReader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mr := &MultilineReader{
+		lr:       lr,
+		indent:   mc.Indent,
+		maxEvent: mc.MaxEventSize,
+		flush:    mc.FlushTimeout,
+		idx:      cfg.StartIndex,
+		pendEnd:  cfg.StartIndex,
+		lastLine: time.Now(),
+	}
+	if mr.maxEvent <= 0 || (cfg.MaxLineLen > 0 && mr.maxEvent > cfg.MaxLineLen) {
+		mr.maxEvent = cfg.MaxLineLen
+	}
+	if mr.maxEvent <= 0 {
+		mr.maxEvent = maxLine
+	}
+	if mr.flush <= 0 {
+		mr.flush = maxIdleDataTime
+	}
+	//the patterns were checked by Validate
+	if mc.StartPattern != `` {
+		mr.start = regexp.MustCompile(mc.StartPattern)
+	}
+	if mc.ContinuationPattern != `` {
+		mr.cont = regexp.MustCompile(mc.ContinuationPattern)
+	}
+	return mr, nil
+}
+
+// ReadEntry returns the next complete event.  An event is complete when a line that starts a new
+// event arrives, when it reaches the max event size, or when the file has been idle for the flush timeout.
+// The multiline reader owns the idle flush, followers do not force out a partial event on their own.
+func (mr *MultilineReader) ReadEntry() (ev []byte, ok bool, wasEOF bool, err error) {
+	for {
+		var ln []byte
+		var lok bool
+		if ln, lok, wasEOF, err = mr.lr.ReadEntry(); err != nil {
+			return
+		} else if !lok {
+			//nothing new, hand out the last event and any partial line if the file has gone quiet
+			if wasEOF && time.Since(mr.lastLine) >= mr.flush {
+				ev, ok, err = mr.flushRemaining()
+			}
+			return
+		}
+		mr.lastLine = time.Now()
+		if ev, ok = mr.add(ln); ok {
+			return
+		}
+	}
+}
+
+// ReadRemaining hands back everything that is buffered, including a partial trailing line
+func (mr *MultilineReader) ReadRemaining() (ev []byte, err error) {
+	var ok bool
+	if ev, ok, _, err = mr.ReadEntry(); err != nil || ok {
+		return
+	}
+	ev, _, err = mr.flushRemaining()
+	return
+}
+
+// flushRemaining appends any partial trailing line to the pending event and hands it out
+func (mr *MultilineReader) flushRemaining() (ev []byte, ok bool, err error) {
+	var ln []byte
+	if ln, err = mr.lr.ReadRemaining(); err != nil {
+		return
+	} else if len(ln) > 0 {
+		if len(mr.pending) > 0 {
+			mr.pending = append(mr.pending, '\n')
+		}
+		mr.pending = append(mr.pending, ln...)
+		mr.pendEnd = mr.lr.Index()
+	}
+	if len(mr.pending) > 0 {
+		ev, ok = mr.take()
+	}
+	return
+}
+
+// lastRead returns when the reader last consumed a line, lines that are held in a pending
+// event still count as activity on the file
+func (mr *MultilineReader) lastRead() time.Time {
+	return mr.lastLine
+}
+
+// add appends a line to the pending event, handing back the previous event if this line starts a new one
+func (mr *MultilineReader) add(ln []byte) (ev []byte, ok bool) {
+	if len(mr.pending) > 0 && (!mr.continues(ln) || len(mr.pending)+1+len(ln) > mr.maxEvent) {
+		ev, ok = mr.take()
+	}
+	if len(mr.pending) > 0 {
+		mr.pending = append(mr.pending, '\n')
+	}
+	mr.pending = append(mr.pending, ln...)
+	if len(mr.pending) > mr.maxEvent {
+		mr.pending = mr.pending[:mr.maxEvent]
+	}
+	mr.pendEnd = mr.lr.Index()
+	return
+}
+
+func (mr *MultilineReader) continues(ln []byte) bool {
+	if mr.cont != nil && mr.cont.Match(ln) {
+		return true
+	} else if mr.indent && len(ln) > 0 && (ln[0] == ' ' || ln[0] == '\t') {
+		return true
+	} else if mr.start != nil {
+		return !mr.start.Match(ln)
+	}
+	return false
+}
+
+func (mr *MultilineReader) take() (ev []byte, ok bool) {
+	ev, ok = mr.pending, true
+	mr.pending = nil
+	mr.idx = mr.pendEnd
+	return
+}
+
+func (mr *MultilineReader) SeekFile(offset int64) error {
+	mr.pending = nil
+	mr.idx, mr.pendEnd = offset, offset
+	mr.lr.currLine = nil
+	if err := mr.lr.SeekFile(offset); err != nil {
+		return err
+	}
+	mr.lr.brdr.Reset(mr.lr.f)
+	return nil
+}
+
+// Index returns the offset just past the last complete event
+func (mr *MultilineReader) Index() int64 {
+	return mr.idx
+}
+
+func (mr *MultilineReader) Close() error {
+	return mr.lr.Close()
+}
+
+func (mr *MultilineReader) ID() (FileId, error) {
+	return mr.lr.ID()
+}
+
+func (mr *MultilineReader) FileSize() (int64, error) {
+	return mr.lr.FileSize()
+}
+
+func (mr *MultilineReader) LastModTime() (time.Time, error) {
+	return mr.lr.LastModTime()
+}
@@ -0,0 +1,138 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const javaTrace = `2024-01-02 10:00:00 INFO starting up
+2024-01-02 10:00:01 ERROR request failed
+java.lang.IllegalStateException: boom
+	at com.example.Foo.bar(Foo.java:10)
+	at com.example.Main.main(Main.java:3)
+Caused by: java.io.IOException: closed
+	... 2 more
+2024-01-02 10:00:02 INFO recovered
+2024-01-02 10:00:03 WARN tail event
+	with a continuation
+`
+
+func newMultiliner(t *testing.T, data string, start int64, mc MultilineConfig) *MultilineReader {
+	pth := filepath.Join(t.TempDir(), `app.log`)
+	if err := os.WriteFile(pth, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := NewReader(ReaderConfig{
+		Fin:        f,
+		MaxLineLen: defaultMaxLine,
+		StartIndex: start,
+		Engine:     MultilineEngine,
+		Multiline:  mc,
+	})
+	if err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	mr, ok := rdr.(*MultilineReader)
+	if !ok {
+		t.Fatalf("bad reader type %T", rdr)
+	}
+	t.Cleanup(func() { mr.Close() })
+	return mr
+}
+
+func readEvents(t *testing.T, mr *MultilineReader) (evs []string) {
+	for {
+		ev, ok, _, err := mr.ReadEntry()
+		if err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			return
+		}
+		evs = append(evs, string(ev))
+	}
+}
+
+func TestMultilineStartPattern(t *testing.T) {
+	mr := newMultiliner(t, javaTrace, 0, MultilineConfig{
+		StartPattern: `^\d{4}-\d{2}-\d{2} `,
+		FlushTimeout: time.Hour,
+	})
+	evs := readEvents(t, mr)
+	if len(evs) != 3 {
+		t.Fatalf("bad event count %d %q", len(evs), evs)
+	} else if evs[1] != "2024-01-02 10:00:01 ERROR request failed\njava.lang.IllegalStateException: boom\n\tat com.example.Foo.bar(Foo.java:10)\n\tat com.example.Main.main(Main.java:3)\nCaused by: java.io.IOException: closed\n\t... 2 more" {
+		t.Fatalf("bad stack trace event %q", evs[1])
+	}
+	//the last event is still pending, the index must not move past it
+	idx := mr.Index()
+	if want := int64(len(javaTrace) - len("2024-01-02 10:00:03 WARN tail event\n\twith a continuation\n")); idx != want {
+		t.Fatalf("bad index %d != %d", idx, want)
+	}
+	ev, err := mr.ReadRemaining()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(ev) != "2024-01-02 10:00:03 WARN tail event\n\twith a continuation" {
+		t.Fatalf("bad tail event %q", ev)
+	} else if mr.Index() != int64(len(javaTrace)) {
+		t.Fatalf("bad final index %d", mr.Index())
+	}
+
+	//restarting from the saved index picks up the pending event again
+	mr = newMultiliner(t, javaTrace, idx, MultilineConfig{StartPattern: `^\d{4}-\d{2}-\d{2} `, FlushTimeout: time.Millisecond})
+	if evs = readEvents(t, mr); len(evs) != 0 {
+		t.Fatalf("flushed before the timeout %q", evs)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if evs = readEvents(t, mr); len(evs) != 1 || evs[0] != "2024-01-02 10:00:03 WARN tail event\n\twith a continuation" {
+		t.Fatalf("bad flushed event %q", evs)
+	}
+}
+
+func TestMultilineContinuation(t *testing.T) {
+	tb := "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\n    main()\nValueError: bad\nnext event\n"
+	mr := newMultiliner(t, tb, 0, MultilineConfig{
+		Indent:              true,
+		ContinuationPattern: `^\w+Error: `,
+		FlushTimeout:        time.Hour,
+	})
+	evs := readEvents(t, mr)
+	if len(evs) != 1 || evs[0] != "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\n    main()\nValueError: bad" {
+		t.Fatalf("bad traceback %q", evs)
+	}
+
+	//events are split once they hit the max size
+	mr = newMultiliner(t, "start\n a\n b\n c\nend\n", 0, MultilineConfig{Indent: true, MaxEventSize: 12, FlushTimeout: time.Hour})
+	if evs = readEvents(t, mr); len(evs) != 2 || evs[0] != "start\n a\n b" || evs[1] != " c" {
+		t.Fatalf("bad split events %q", evs)
+	}
+}
+
+func TestMultilineConfig(t *testing.T) {
+	bad := []MultilineConfig{
+		{},
+		{StartPattern: `(`},
+		{ContinuationPattern: `[`},
+		{Indent: true, MaxEventSize: -1},
+		{Indent: true, FlushTimeout: -time.Second},
+	}
+	for i, v := range bad {
+		if err := v.Validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
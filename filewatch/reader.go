@@ -10,7 +10,9 @@ package filewatch
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"regexp"
 	"time"
 )
 
@@ -20,9 +22,14 @@ const (
 	maxRemainingRead int = 1024 * 1024
 )
 
+var (
+	ErrMultilineNoRules = errors.New("multiline engine requires a start pattern, continuation pattern, or indent rule")
+)
+
 const (
-	LineEngine  int = 0
-	RegexEngine int = 1
+	LineEngine      int = 0
+	RegexEngine     int = 1
+	MultilineEngine int = 3 // 2 is the windows only EvtxEngine
 )
 
 type Reader interface {
@@ -42,6 +49,40 @@ type ReaderConfig struct {
 	StartIndex int64
 	Engine     int
 	EngineArgs string
+	Multiline  MultilineConfig
+}
+
+// MultilineConfig controls how the multiline engine assembles lines into events.  A line that
+// matches ContinuationPattern, or begins with whitespace when Indent is set, is appended to the
+// current event.  When StartPattern is set, any line that does not match it is also a continuation.
+type MultilineConfig struct {
+	StartPattern        string
+	ContinuationPattern string
+	Indent              bool
+	MaxEventSize        int           // events are split once they reach this size, zero uses the max line length
+	FlushTimeout        time.Duration // how long the last event in an idle file waits for more lines, zero uses the default
+}
+
+// Validate ensures that at least one rule is set and the patterns compile
+func (mc MultilineConfig) Validate() error {
+	if mc.StartPattern == `` && mc.ContinuationPattern == `` && !mc.Indent {
+		return ErrMultilineNoRules
+	} else if mc.MaxEventSize < 0 {
+		return errors.New("multiline max event size is invalid")
+	} else if mc.FlushTimeout < 0 {
+		return errors.New("multiline flush timeout is invalid")
+	}
+	if mc.StartPattern != `` {
+		if _, err := regexp.Compile(mc.StartPattern); err != nil {
+			return fmt.Errorf("invalid multiline start pattern %w", err)
+		}
+	}
+	if mc.ContinuationPattern != `` {
+		if _, err := regexp.Compile(mc.ContinuationPattern); err != nil {
+			return fmt.Errorf("invalid multiline continuation pattern %w", err)
+		}
+	}
+	return nil
 }
 
 type baseReader struct {
@@ -21,6 +21,8 @@ func NewReader(cfg ReaderConfig) (Reader, error) {
 	switch cfg.Engine {
 	case RegexEngine:
 		return NewRegexReader(cfg)
+	case MultilineEngine:
+		return NewMultilineReader(cfg)
 	case LineEngine: //default/empty is line reader
 		return NewLineReader(cfg)
 	}
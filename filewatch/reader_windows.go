@@ -38,6 +38,8 @@ func NewReader(cfg ReaderConfig) (Reader, error) {
 	switch cfg.Engine {
 	case RegexEngine:
 		return NewRegexReader(cfg)
+	case MultilineEngine:
+		return NewMultilineReader(cfg)
 	case LineEngine: //default/empty is line reader
 		//check if the filetype is .evtx, if it is, force the EvtxReader
 		//this ONLY works on windows, its kind of a hack, but i don't want to try and
@@ -0,0 +1,449 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// rotationHeadSize is how much of the start of a file we remember so that we can
+	// recognize it once it has been compressed by a log rotation
+	rotationHeadSize = 1024
+
+	// maxPendingRotations bounds the number of rotated files we will go looking for
+	maxPendingRotations = 64
+
+	// maxPendingRotationAge is how long we will look for a rotated file before giving up on it
+	maxPendingRotationAge = 24 * time.Hour
+
+	// bzip2HeaderSize covers the stream header and the magic of the first block
+	bzip2HeaderSize = 10
+)
+
+var (
+	errRotationMismatch = errors.New("compressed file does not match the rotated file")
+	errRotationTooLarge = errors.New("rotated file remainder exceeds the decompression limit")
+
+	// maxRotatedRemainder bounds how much of a compressed rotation is decompressed to disk
+	maxRotatedRemainder int64 = 1024 * 1024 * 1024
+
+	bzip2Magic      = []byte{'B', 'Z', 'h'}
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// fileIdent identifies the file behind a state so that we can find it after it has been rotated
+type fileIdent struct {
+	Id   FileId
+	Head []byte
+}
+
+// rotatedFile is a file that was rotated away before we finished reading it
+type rotatedFile struct {
+	Name   FileName
+	Ident  fileIdent
+	Offset int64
+	Time   time.Time
+}
+
+// rotationState is stored in the state file right after the states, older versions
+// only decode the states and never see it
+type rotationState struct {
+	Idents  map[FileName]fileIdent
+	Pending []rotatedFile
+}
+
+// isCompressedFile checks that a file has a compressed extension and starts with a complete and
+// valid header for that format, so a text file is never mistaken for one.  Compressed files are never
+// followed as text, their contents are only read when recovering the remainder of a rotated file.
+func isCompressedFile(fpath string) bool {
+	ext := strings.ToLower(filepath.Ext(fpath))
+	switch ext {
+	case `.gz`, `.bz2`, `.zst`, `.zstd`:
+	default:
+		return false
+	}
+	fin, err := os.Open(fpath)
+	if err != nil {
+		return false
+	}
+	defer fin.Close()
+	switch ext {
+	case `.gz`:
+		//the gzip reader parses the whole header, including any optional fields
+		_, err = gzip.NewReader(bufio.NewReader(fin))
+		return err == nil
+	case `.bz2`:
+		hdr := make([]byte, bzip2HeaderSize)
+		if _, err = io.ReadFull(fin, hdr); err != nil {
+			return false
+		}
+		return bytes.HasPrefix(hdr, bzip2Magic) && hdr[3] >= '1' && hdr[3] <= '9' &&
+			(bytes.Equal(hdr[4:], bzip2BlockMagic) || bytes.Equal(hdr[4:], bzip2EndMagic))
+	}
+	hdr := make([]byte, zstd.HeaderMaxSize)
+	n, _ := io.ReadFull(fin, hdr)
+	var zh zstd.Header
+	return zh.Decode(hdr[:n]) == nil
+}
+
+// loadRotations checks the saved states against the files on disk, any state whose file was
+// replaced while we were down becomes a pending rotation and the new file is read from the start.
+// This MUST be called before the states are cleaned.
+func loadRotations(states map[FileName]*int64, rs rotationState) (idents map[FileName]fileIdent, pending []rotatedFile) {
+	idents = make(map[FileName]fileIdent, len(rs.Idents))
+	now := time.Now()
+	for _, p := range rs.Pending {
+		if now.Sub(p.Time) < maxPendingRotationAge {
+			pending = append(pending, p)
+		}
+	}
+	for k, st := range states {
+		ident, ok := rs.Idents[k]
+		if !ok || st == nil {
+			continue
+		}
+		same, err := ident.matches(k.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			//can't tell, leave it alone
+			idents[k] = ident
+			continue
+		} else if same {
+			idents[k] = ident
+			continue
+		}
+		pending = append(pending, rotatedFile{
+			Name:   k,
+			Ident:  ident,
+			Offset: *st,
+			Time:   now,
+		})
+		if err == nil {
+			//a new file took its place
+			*st = 0
+		}
+	}
+	if len(pending) > maxPendingRotations {
+		pending = pending[len(pending)-maxPendingRotations:]
+	}
+	return
+}
+
+// matches checks that the file at fpath is the identified file, the head is checked as well
+// as the file id because file ids are reused once a file is deleted
+func (fi fileIdent) matches(fpath string) (bool, error) {
+	fin, err := openDeletableFile(fpath)
+	if err != nil {
+		return false, err
+	}
+	defer fin.Close()
+	id, err := getFileId(fin)
+	if err != nil || id != fi.Id {
+		return false, err
+	}
+	head := make([]byte, len(fi.Head))
+	if _, err = io.ReadFull(fin, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		return false, err
+	}
+	return bytes.Equal(head, fi.Head), nil
+}
+
+// ident returns the identity of the followed file, the head is filled in as the file grows
+func (f *follower) ident() fileIdent {
+	if len(f.head) < rotationHeadSize && f.fin != nil {
+		buf := make([]byte, rotationHeadSize)
+		if n, _ := f.fin.ReadAt(buf, 0); n > len(f.head) {
+			f.head = buf[:n]
+		}
+	}
+	return fileIdent{Id: f.id, Head: f.head}
+}
+
+// nolockIdents refreshes the identity of every followed file and drops identities without a state
+// caller MUST HOLD THE LOCK
+func (f *FilterManager) nolockIdents() map[FileName]fileIdent {
+	for k, fl := range f.followers {
+		f.idents[k] = fl.ident()
+	}
+	for k := range f.idents {
+		if _, ok := f.states[k]; !ok {
+			delete(f.idents, k)
+		}
+	}
+	return f.idents
+}
+
+// closeRotated closes a follower whose file was removed or moved away, if the follower
+// did not get to the end of the file we go looking for the rest of it.
+// caller MUST HOLD THE LOCK
+func (f *FilterManager) closeRotated(stid FileName, fl *follower) error {
+	size, ident := fl.fileSize(), fl.ident()
+	err := fl.Close()
+	if off := *fl.state; off < size {
+		f.logger.Info("rotated file has unread data",
+			log.KV("path", stid.FilePath), log.KV("follower", stid.BaseName),
+			log.KV("state", off), log.KV("size", size))
+		f.addPending(rotatedFile{
+			Name:   stid,
+			Ident:  ident,
+			Offset: off,
+			Time:   time.Now(),
+		})
+	}
+	delete(f.idents, stid)
+	return err
+}
+
+func (f *FilterManager) addPending(p rotatedFile) {
+	if len(f.pending) >= maxPendingRotations {
+		f.logger.Warn("too many rotated files pending, dropping the oldest",
+			log.KV("path", f.pending[0].Name.FilePath), log.KV("state", f.pending[0].Offset))
+		f.pending = f.pending[1:]
+	}
+	f.pending = append(f.pending, p)
+}
+
+// nolockAdoptRotated hands the saved offset of a pending rotation to a file that was renamed
+// to another name that is still matched by the same filter.  Returns true if a state was set.
+// caller MUST HOLD THE LOCK
+func (f *FilterManager) nolockAdoptRotated(fpath string, id FileId) bool {
+	fdir, fname := filepath.Dir(fpath), filepath.Base(fpath)
+	for i, p := range f.pending {
+		if p.Ident.Id != id || filepath.Dir(p.Name.FilePath) != fdir {
+			continue
+		} else if same, err := p.Ident.matches(fpath); err != nil || !same {
+			continue
+		}
+		for _, v := range f.filters {
+			if v.bname == p.Name.BaseName && v.loc == fdir && f.matchFile(v.mtchs, fname) {
+				*f.addSeekInfo(v.bname, fpath) = p.Offset
+				f.pending = append(f.pending[:i], f.pending[i+1:]...)
+				f.logger.Info("resuming rotated file", log.KV("path", fpath),
+					log.KV("original", p.Name.FilePath), log.KV("state", p.Offset))
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RecoverRotated looks for the files behind pending rotations and hands their unread data to the
+// handler of the filter that was following them.  A rotated file is found either by its file id,
+// when it was renamed to a name that no filter follows, or by its name and the start of its
+// decompressed contents when it was compressed.  Compressed files are only read once they are
+// complete, so a rotation that is still being compressed is picked up on a later pass.
+func (f *FilterManager) RecoverRotated() (err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	now := time.Now()
+	for i := 0; i < len(f.pending); {
+		p := &f.pending[i]
+		var done bool
+		if now.Sub(p.Time) > maxPendingRotationAge {
+			f.logger.Warn("giving up on rotated file", log.KV("path", p.Name.FilePath), log.KV("state", p.Offset))
+			done = true
+		} else if done, err = f.nolockRecoverRotated(p); err != nil {
+			return
+		}
+		if done {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+		} else {
+			i++
+		}
+	}
+	return
+}
+
+// nolockRecoverRotated scans the directory of a pending rotation, done is true once the rotation
+// has been dealt with and no longer needs to be tracked.  The offset of the rotation is advanced past
+// whatever was handed out, so a drain that fails part way resumes where it stopped.
+func (f *FilterManager) nolockRecoverRotated(p *rotatedFile) (done bool, err error) {
+	fdir := filepath.Dir(p.Name.FilePath)
+	prefix := filepath.Base(p.Name.FilePath)
+	var v *filter
+	for i := range f.filters {
+		if f.filters[i].bname == p.Name.BaseName && f.filters[i].loc == fdir {
+			v = &f.filters[i]
+			break
+		}
+	}
+	if v == nil {
+		//nobody left to hand the data to
+		done = true
+		return
+	}
+	des, err := os.ReadDir(fdir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, de := range des {
+		if !de.Type().IsRegular() {
+			continue
+		}
+		fpath := filepath.Join(fdir, de.Name())
+		if isCompressedFile(fpath) {
+			if len(p.Ident.Head) == 0 || !strings.HasPrefix(de.Name(), prefix) {
+				continue
+			}
+			n, lerr := f.drainCompressed(*p, v, fpath)
+			p.Offset += n
+			if lerr == nil {
+				f.logger.Info("recovered rotated file from compressed copy",
+					log.KV("path", p.Name.FilePath), log.KV("compressed", fpath), log.KV("state", p.Offset))
+				done = true
+				return
+			} else if lerr == errRotationTooLarge {
+				f.logger.Warn("rotated file is too large to recover from its compressed copy",
+					log.KV("path", p.Name.FilePath), log.KV("compressed", fpath), log.KV("state", p.Offset))
+				done = true
+				return
+			} else if n > 0 {
+				f.logger.Warn("failed to recover all of rotated file from compressed copy",
+					log.KV("path", p.Name.FilePath), log.KV("compressed", fpath), log.KV("state", p.Offset), log.KVErr(lerr))
+			}
+			//either not our file or still being compressed, a later pass will try again
+		} else if same, lerr := p.Ident.matches(fpath); lerr == nil && same {
+			if f.matchFile(v.mtchs, de.Name()) {
+				//still followed under its new name, adoption takes care of it
+				continue
+			}
+			var n int64
+			n, err = f.drainPlain(*p, v, fpath)
+			if p.Offset += n; err != nil {
+				return
+			}
+			f.logger.Info("recovered rotated file", log.KV("path", p.Name.FilePath),
+				log.KV("rotated", fpath), log.KV("state", p.Offset))
+			done = true
+			return
+		}
+	}
+	return
+}
+
+// drainPlain reads a renamed file from the saved offset, returning how far past it the handed out entries reach
+func (f *FilterManager) drainPlain(p rotatedFile, v *filter, fpath string) (int64, error) {
+	fin, err := openDeletableFile(fpath)
+	if err != nil {
+		return 0, err
+	}
+	return drainFile(fin, p.Offset, v, p.Name.FilePath)
+}
+
+// drainCompressed decompresses everything past the saved offset into a temporary file and reads it,
+// returning how far past the saved offset the handed out entries reach.  The start of the decompressed
+// data must match the head of the rotated file and the whole remainder must decompress cleanly within
+// maxRotatedRemainder before anything is handed out, so a partial copy never produces entries.
+func (f *FilterManager) drainCompressed(p rotatedFile, v *filter, fpath string) (n int64, err error) {
+	var fin, tmp *os.File
+	if fin, err = os.Open(fpath); err != nil {
+		return
+	}
+	defer fin.Close()
+	rdr, err := utils.NewCompressedReader(fin)
+	if err != nil {
+		return
+	}
+	if c, ok := rdr.(io.Closer); ok {
+		defer c.Close()
+	}
+	head := make([]byte, len(p.Ident.Head))
+	if _, err = io.ReadFull(rdr, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errRotationMismatch
+		}
+		return
+	} else if !bytes.Equal(head, p.Ident.Head) {
+		err = errRotationMismatch
+		return
+	}
+
+	if tmp, err = os.CreateTemp(``, `filewatch-rotated-`); err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if p.Offset < int64(len(head)) {
+		_, err = tmp.Write(head[p.Offset:])
+	} else {
+		_, err = io.CopyN(io.Discard, rdr, p.Offset-int64(len(head)))
+	}
+	if err == nil {
+		var sz int64
+		if sz, err = io.CopyN(tmp, rdr, maxRotatedRemainder+1); err == io.EOF {
+			err = nil
+		} else if err == nil && sz > maxRotatedRemainder {
+			err = errRotationTooLarge
+		}
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, 0)
+	}
+	if err != nil {
+		tmp.Close()
+		return
+	}
+	return drainFile(tmp, 0, v, p.Name.FilePath)
+}
+
+// drainFile reads every entry out of the file from offset using the engine of the filter, the file is closed.
+// The returned count is how far past offset the entries that were handled successfully reach.
+func drainFile(fin *os.File, offset int64, v *filter, fpath string) (n int64, err error) {
+	rdr, err := NewReader(ReaderConfig{
+		Fin:        fin,
+		MaxLineLen: defaultMaxLine,
+		StartIndex: offset,
+		Engine:     v.Engine,
+		EngineArgs: v.EngineArgs,
+		Multiline:  v.Multiline,
+	})
+	if err != nil {
+		fin.Close()
+		return
+	}
+	defer rdr.Close()
+	for {
+		var ln []byte
+		var ok bool
+		if ln, ok, _, err = rdr.ReadEntry(); err != nil || !ok {
+			break
+		} else if err = v.lh.HandleLog(ln, time.Now(), fpath); err != nil {
+			return
+		}
+		n = rdr.Index() - offset
+	}
+	if err != nil {
+		return
+	}
+	var ln []byte
+	if ln, err = rdr.ReadRemaining(); err == nil && len(ln) > 0 {
+		if err = v.lh.HandleLog(ln, time.Now(), fpath); err == nil {
+			n = rdr.Index() - offset
+		}
+	}
+	return
+}
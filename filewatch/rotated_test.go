@@ -0,0 +1,313 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func rotLines(start, end int) []byte {
+	var bb bytes.Buffer
+	for i := start; i < end; i++ {
+		fmt.Fprintf(&bb, "rotated line %d with some padding to push the head out past a kilobyte\n", i)
+	}
+	return bb.Bytes()
+}
+
+// rotFilterManager reads the first 40 lines of app.log with one filter manager, closes it, then
+// appends 60 more lines and hands back the state file so the caller can rotate before restarting
+func rotFilterManager(t *testing.T, mtch string) (dir, state string) {
+	dir, state = t.TempDir(), filepath.Join(t.TempDir(), `state`)
+	pth := filepath.Join(dir, `app.log`)
+	if err := os.WriteFile(pth, rotLines(0, 40), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fm, err := NewFilterManager(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lh := newSafeTrackingLH()
+	if err = fm.AddFilter(`app`, dir, []string{mtch}, lh, FollowerEngineConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fm.CatchupFile(watchedFile{pth: pth, size: fi.Size(), modTime: fi.ModTime()}, nil); err != nil {
+		t.Fatal(err)
+	} else if lh.Len() != 40 {
+		t.Fatalf("bad initial line count %d", lh.Len())
+	} else if err = fm.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fout, err := os.OpenFile(pth, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fout.Write(rotLines(40, 100)); err != nil {
+		t.Fatal(err)
+	} else if err = fout.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func restartFilterManager(t *testing.T, dir, state, mtch string) (*FilterManager, *safeTrackingLH) {
+	lh := newSafeTrackingLH()
+	return restartFilterManagerLH(t, dir, state, mtch, lh), lh
+}
+
+func restartFilterManagerLH(t *testing.T, dir, state, mtch string, lh handler) *FilterManager {
+	fm, err := NewFilterManager(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fm.Close() })
+	if err = fm.AddFilter(`app`, dir, []string{mtch}, lh, FollowerEngineConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	return fm
+}
+
+// failingLH hands off lines until its budget runs out and then fails
+type failingLH struct {
+	*safeTrackingLH
+	budget int
+}
+
+func (h *failingLH) HandleLog(b []byte, ts time.Time, fname string) error {
+	if h.budget <= 0 {
+		return errors.New("handler failed")
+	}
+	h.budget--
+	return h.safeTrackingLH.HandleLog(b, ts, fname)
+}
+
+func rotGzip(t *testing.T) []byte {
+	var bb bytes.Buffer
+	gw := gzip.NewWriter(&bb)
+	if _, err := gw.Write(rotLines(0, 100)); err != nil {
+		t.Fatal(err)
+	} else if err = gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func checkRemainder(t *testing.T, lh *safeTrackingLH) {
+	if lh.Len() != 60 {
+		t.Fatalf("bad recovered line count %d", lh.Len())
+	}
+	for i := 40; i < 100; i++ {
+		if _, ok := lh.mp[fmt.Sprintf("rotated line %d with some padding to push the head out past a kilobyte", i)]; !ok {
+			t.Fatalf("missing line %d", i)
+		}
+	}
+}
+
+func TestRotatedCompressed(t *testing.T) {
+	dir, state := rotFilterManager(t, `app.log`)
+	pth := filepath.Join(dir, `app.log`)
+
+	//rotate and compress the way logrotate does, with a new file taking the old name
+	gz := rotGzip(t)
+	gzpth := filepath.Join(dir, `app.log.1.gz`)
+	if err := os.WriteFile(gzpth, gz[:len(gz)/2], 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.Remove(pth); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(pth, []byte("a brand new file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fm, lh := restartFilterManager(t, dir, state, `app.log*`)
+	if len(fm.pending) != 1 {
+		t.Fatalf("bad pending count %d", len(fm.pending))
+	} else if si := fm.seekInfo(`app`, pth); si == nil || *si != 0 {
+		t.Fatal("replacement file did not start over")
+	} else if !isCompressedFile(gzpth) {
+		t.Fatal("failed to detect compressed file")
+	}
+	//compressed files are never followed, even when a filter matches them
+	if ok, err := fm.LoadFile(gzpth); err != nil || ok {
+		t.Fatalf("followed a compressed file %v %v", ok, err)
+	}
+
+	//a partial copy is left alone until it is complete
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if lh.Len() != 0 || len(fm.pending) != 1 {
+		t.Fatalf("recovered from a partial copy %d %d", lh.Len(), len(fm.pending))
+	}
+	if err := os.WriteFile(gzpth, gz, 0600); err != nil {
+		t.Fatal(err)
+	} else if err = fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 {
+		t.Fatal("rotation still pending")
+	}
+	checkRemainder(t, lh)
+}
+
+func TestRotatedRenamed(t *testing.T) {
+	//renamed to something the filter does not follow, the remainder is read straight out of it
+	dir, state := rotFilterManager(t, `app.log`)
+	pth := filepath.Join(dir, `app.log`)
+	if err := os.Rename(pth, filepath.Join(dir, `app.old`)); err != nil {
+		t.Fatal(err)
+	}
+	fm, lh := restartFilterManager(t, dir, state, `app.log`)
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 {
+		t.Fatal("rotation still pending")
+	}
+	checkRemainder(t, lh)
+
+	//renamed to something that is still followed, the new name picks up the old offset
+	dir, state = rotFilterManager(t, `app.log*`)
+	pth = filepath.Join(dir, `app.log`)
+	npth := filepath.Join(dir, `app.log.1`)
+	if err := os.Rename(pth, npth); err != nil {
+		t.Fatal(err)
+	}
+	fm, lh = restartFilterManager(t, dir, state, `app.log*`)
+	fi, err := os.Stat(npth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fm.CatchupFile(watchedFile{pth: npth, size: fi.Size(), modTime: fi.ModTime()}, nil); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 {
+		t.Fatal("rotation still pending")
+	}
+	checkRemainder(t, lh)
+}
+
+func TestRotatedResume(t *testing.T) {
+	//a handler that fails part way through a rotation picks up where it left off
+	dir, state := rotFilterManager(t, `app.log`)
+	if err := os.Rename(filepath.Join(dir, `app.log`), filepath.Join(dir, `app.old`)); err != nil {
+		t.Fatal(err)
+	}
+	lh := &failingLH{safeTrackingLH: newSafeTrackingLH(), budget: 25}
+	fm := restartFilterManagerLH(t, dir, state, `app.log`, lh)
+	if err := fm.RecoverRotated(); err == nil {
+		t.Fatal("handler failure not reported")
+	} else if len(fm.pending) != 1 || lh.Len() != 25 {
+		t.Fatalf("bad partial recovery %d %d", len(fm.pending), lh.Len())
+	}
+	lh.budget = 1000
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 {
+		t.Fatal("rotation still pending")
+	} else if lh.cnt != 60 {
+		t.Fatalf("lines were handed out more than once %d", lh.cnt)
+	}
+	checkRemainder(t, lh.safeTrackingLH)
+
+	//the same goes for compressed copies
+	dir, state = rotFilterManager(t, `app.log`)
+	if err := os.Remove(filepath.Join(dir, `app.log`)); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(dir, `app.log.1.gz`), rotGzip(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lh = &failingLH{safeTrackingLH: newSafeTrackingLH(), budget: 25}
+	fm = restartFilterManagerLH(t, dir, state, `app.log`, lh)
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 1 || lh.Len() != 25 {
+		t.Fatalf("bad partial recovery %d %d", len(fm.pending), lh.Len())
+	}
+	lh.budget = 1000
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 {
+		t.Fatal("rotation still pending")
+	} else if lh.cnt != 60 {
+		t.Fatalf("lines were handed out more than once %d", lh.cnt)
+	}
+	checkRemainder(t, lh.safeTrackingLH)
+}
+
+func TestRotatedTooLarge(t *testing.T) {
+	defer func(v int64) { maxRotatedRemainder = v }(maxRotatedRemainder)
+	maxRotatedRemainder = 1024
+	dir, state := rotFilterManager(t, `app.log`)
+	if err := os.Remove(filepath.Join(dir, `app.log`)); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(dir, `app.log.1.gz`), rotGzip(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fm, lh := restartFilterManager(t, dir, state, `app.log`)
+	if err := fm.RecoverRotated(); err != nil {
+		t.Fatal(err)
+	} else if len(fm.pending) != 0 || lh.Len() != 0 {
+		t.Fatalf("oversized rotation was not dropped %d %d", len(fm.pending), lh.Len())
+	}
+}
+
+func TestIsCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	gz := rotGzip(t)
+	var zb bytes.Buffer
+	zw, err := zstd.NewWriter(&zb)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = zw.Write(rotLines(0, 10)); err != nil {
+		t.Fatal(err)
+	} else if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bz := append([]byte("BZh9"), bzip2BlockMagic...)
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool
+	}{
+		{`a.gz`, gz, true},
+		{`a.GZ`, gz[:20], true},
+		{`a.zst`, zb.Bytes(), true},
+		{`a.zstd`, zb.Bytes(), true},
+		{`a.bz2`, bz, true},
+		{`gz.log`, gz, false},          //no compressed extension
+		{`zst.log`, zb.Bytes(), false}, //no compressed extension
+		{`b.gz`, gz[:4], false},        //incomplete header
+		{`c.gz`, []byte("\x1f\x8bnot really gzip"), false},
+		{`b.bz2`, []byte("BZh9 is how this line starts"), false},
+		{`c.bz2`, bz[:6], false},
+		{`b.zst`, []byte{0x28, 0xb5, 0x2f, 0xfd}, false},
+		{`c.zst`, []byte("plain text"), false},
+		{`empty.gz`, nil, false},
+	}
+	for _, tt := range tests {
+		pth := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(pth, tt.data, 0600); err != nil {
+			t.Fatal(err)
+		} else if isCompressedFile(pth) != tt.ok {
+			t.Fatalf("%s: compressed should be %v", tt.name, tt.ok)
+		}
+	}
+	if isCompressedFile(filepath.Join(dir, `missing.gz`)) {
+		t.Fatal("missing file is compressed")
+	}
+}
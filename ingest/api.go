@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/crewjam/rfc5424"
+	"github.com/gravwell/gravwell/v3/ingest/config"
 )
 
 const (
@@ -28,11 +29,17 @@ const (
 )
 
 const (
-	configurationBlockSize          uint32          = 1
+	configurationBlockSize          uint32          = 2
 	maxStreamConfigurationBlockSize uint32          = 1024 * 1024 //just a sanity check
 	maxIngestStateSize              uint32          = 1024 * 1024
 	CompressNone                    CompressionType = 0
 	CompressSnappy                  CompressionType = 0x10
+	CompressZstd                    CompressionType = 0x20
+	CompressLZ4                     CompressionType = 0x30
+
+	// CompressionLevelDefault tells the compressor to use its own default level
+	CompressionLevelDefault int8 = 0
+	maxZstdCompressionLevel int8 = config.MaxZstdCompressionLevel
 )
 
 var (
@@ -67,7 +74,8 @@ type Logger interface {
 
 // StreamConfiguration is a structure that can be sent back and
 type StreamConfiguration struct {
-	Compression CompressionType
+	Compression      CompressionType
+	CompressionLevel int8 // only honored by compression types that support levels
 }
 
 func (c StreamConfiguration) Write(wtr io.Writer) (err error) {
@@ -118,6 +126,9 @@ func (c StreamConfiguration) encode(buff []byte) (err error) {
 		return
 	}
 	buff[0] = byte(c.Compression)
+	if len(buff) > 1 {
+		buff[1] = byte(c.CompressionLevel)
+	}
 	return
 }
 
@@ -127,6 +138,12 @@ func (c *StreamConfiguration) decode(buff []byte) (err error) {
 		return
 	}
 	c.Compression = CompressionType(buff[0])
+	//older clients only send the compression type
+	if len(buff) > 1 {
+		c.CompressionLevel = int8(buff[1])
+	} else {
+		c.CompressionLevel = CompressionLevelDefault
+	}
 
 	err = c.validate()
 	return
@@ -136,26 +153,64 @@ func (c *StreamConfiguration) validate() (err error) {
 	if err = c.Compression.validate(); err != nil {
 		return
 	}
+	if c.CompressionLevel < 0 || c.CompressionLevel > maxZstdCompressionLevel {
+		err = fmt.Errorf("Invalid compression level %d", c.CompressionLevel)
+		return
+	}
 
 	return
 }
 
+// compat returns a StreamConfiguration that a remote side speaking the given API version can handle.
+// Compression types the remote side does not advertise are downgraded to snappy.
+func (c StreamConfiguration) compat(apiVersion uint16) (r StreamConfiguration) {
+	r = c
+	if apiVersion < MINIMUM_EXT_COMPRESSION_VERSION {
+		switch c.Compression {
+		case CompressZstd, CompressLZ4:
+			r.Compression = CompressSnappy
+		}
+		r.CompressionLevel = CompressionLevelDefault
+	}
+	return
+}
+
 func (ct CompressionType) validate() (err error) {
 	switch ct {
 	case CompressNone:
 	case CompressSnappy:
+	case CompressZstd:
+	case CompressLZ4:
 	default:
 		err = fmt.Errorf("Unknown compression id %x", ct)
 	}
 	return
 }
 
+func (ct CompressionType) String() string {
+	switch ct {
+	case CompressNone:
+		return `none`
+	case CompressSnappy:
+		return `snappy`
+	case CompressZstd:
+		return `zstd`
+	case CompressLZ4:
+		return `lz4`
+	}
+	return fmt.Sprintf("unknown(%x)", uint8(ct))
+}
+
 func ParseCompression(v string) (ct CompressionType, err error) {
 	switch strings.ToLower(strings.TrimSpace(v)) {
 	case ``:
 	case `none`:
 	case `snappy`:
 		ct = CompressSnappy
+	case `zstd`, `zstandard`:
+		ct = CompressZstd
+	case `lz4`:
+		ct = CompressLZ4
 	default:
 		err = fmt.Errorf("Unknown compression type %q", v)
 	}
@@ -177,8 +232,10 @@ type IngesterState struct {
 	CacheSize     uint64
 	LastSeen      time.Time
 	Children      map[string]IngesterState
-	Configuration json.RawMessage `json:",omitempty"`
-	Metadata      json.RawMessage `json:",omitempty"`
+	Configuration json.RawMessage             `json:",omitempty"`
+	Metadata      json.RawMessage             `json:",omitempty"`
+	ConfigOverlay *ConfigOverlayStatus        `json:",omitempty"` // outcome of the most recent configuration overlay
+	TagThrottle   map[string]TagThrottleState `json:",omitempty"` // per tag rate limit and priority state
 }
 
 type writeCounter struct {
@@ -297,6 +354,16 @@ func (s IngesterState) Copy() (r IngesterState) {
 	for k, v := range s.Children {
 		r.Children[k] = v.Copy()
 	}
+	if s.ConfigOverlay != nil {
+		co := *s.ConfigOverlay
+		r.ConfigOverlay = &co
+	}
+	if s.TagThrottle != nil {
+		r.TagThrottle = make(map[string]TagThrottleState, len(s.TagThrottle))
+		for k, v := range s.TagThrottle {
+			r.TagThrottle[k] = v
+		}
+	}
 	return
 }
 
@@ -336,8 +403,10 @@ func (s IngesterState) MarshalJSON() ([]byte, error) {
 		CacheSize     uint64
 		LastSeen      time.Time
 		Children      mis
-		Configuration json.RawMessage `json:",omitempty"`
-		Metadata      json.RawMessage `json:",omitempty"`
+		Configuration json.RawMessage             `json:",omitempty"`
+		Metadata      json.RawMessage             `json:",omitempty"`
+		ConfigOverlay *ConfigOverlayStatus        `json:",omitempty"`
+		TagThrottle   map[string]TagThrottleState `json:",omitempty"`
 	}{
 		UUID:          s.UUID,
 		Name:          s.Name,
@@ -355,6 +424,8 @@ func (s IngesterState) MarshalJSON() ([]byte, error) {
 		Children:      mis{mp: s.Children},
 		Configuration: s.Configuration,
 		Metadata:      s.Metadata,
+		ConfigOverlay: s.ConfigOverlay,
+		TagThrottle:   s.TagThrottle,
 	}
 	return json.Marshal(x)
 }
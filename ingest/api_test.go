@@ -13,6 +13,8 @@ import (
 	"encoding/binary"
 	"reflect"
 	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
 )
 
 func TestStreamConfigurationEncodeDecode(t *testing.T) {
@@ -100,3 +102,73 @@ func TestIngestState(t *testing.T) {
 		t.Fatalf("ReadWrite failure: %+v != %+v\n", x, y)
 	}
 }
+
+func TestStreamConfigurationCompat(t *testing.T) {
+	x := StreamConfiguration{
+		Compression:      CompressZstd,
+		CompressionLevel: 19,
+	}
+	if y := x.compat(MINIMUM_EXT_COMPRESSION_VERSION); !reflect.DeepEqual(x, y) {
+		t.Fatalf("compat modified a supported configuration: %+v != %+v", x, y)
+	}
+	if y := x.compat(MINIMUM_EXT_COMPRESSION_VERSION - 1); y.Compression != CompressSnappy || y.CompressionLevel != CompressionLevelDefault {
+		t.Fatalf("compat failed to fall back to snappy: %+v", y)
+	}
+
+	//old clients only send a single byte, make sure we still decode
+	var y StreamConfiguration
+	if err := y.decode([]byte{byte(CompressLZ4)}); err != nil {
+		t.Fatal(err)
+	} else if y.Compression != CompressLZ4 || y.CompressionLevel != CompressionLevelDefault {
+		t.Fatalf("bad decode: %+v", y)
+	}
+	if err := y.decode([]byte{byte(CompressZstd), 0x7f}); err == nil {
+		t.Fatal("Failed to catch bad compression level")
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	tests := map[string]CompressionType{
+		``:       CompressNone,
+		`none`:   CompressNone,
+		`snappy`: CompressSnappy,
+		` ZSTD `: CompressZstd,
+		`lz4`:    CompressLZ4,
+	}
+	for k, v := range tests {
+		if ct, err := ParseCompression(k); err != nil {
+			t.Fatal(err)
+		} else if ct != v {
+			t.Fatalf("%q parsed to %v not %v", k, ct, v)
+		}
+	}
+	if _, err := ParseCompression(`brotli`); err == nil {
+		t.Fatal("failed to catch bad compression type")
+	}
+}
+
+func TestGetStreamConfig(t *testing.T) {
+	tests := []struct {
+		cfg   config.IngestStreamConfig
+		ct    CompressionType
+		level int8
+	}{
+		{config.IngestStreamConfig{}, CompressNone, 0},
+		{config.IngestStreamConfig{Compression_Type: `zstd`}, CompressNone, 0},
+		{config.IngestStreamConfig{Enable_Compression: true}, CompressSnappy, 0},
+		{config.IngestStreamConfig{Enable_Compression: true, Compression_Type: `none`}, CompressNone, 0},
+		{config.IngestStreamConfig{Enable_Compression: true, Compression_Type: ` None `}, CompressNone, 0},
+		{config.IngestStreamConfig{Enable_Compression: true, Compression_Type: `lz4`}, CompressLZ4, 0},
+		{config.IngestStreamConfig{Enable_Compression: true, Compression_Type: `zstd`, Compression_Level: 3}, CompressZstd, 3},
+	}
+	for i, tst := range tests {
+		if sc, err := getStreamConfig(tst.cfg); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		} else if sc.Compression != tst.ct || sc.CompressionLevel != tst.level {
+			t.Fatalf("%d: bad stream config %+v", i, sc)
+		}
+	}
+	if _, err := getStreamConfig(config.IngestStreamConfig{Enable_Compression: true, Compression_Type: `brotli`}); err == nil {
+		t.Fatal("failed to catch bad compression type")
+	}
+}
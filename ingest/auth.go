@@ -18,6 +18,7 @@ import (
 	"errors"
 	"io"
 	"math/rand"
+	"sync"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 )
@@ -26,7 +27,7 @@ const (
 	// The number of times to hash the shared secret
 	HASH_ITERATIONS uint16 = 16
 	// Auth protocol version number
-	VERSION uint16 = 0x9
+	VERSION uint16 = 0xB
 	// Authenticated, but not ready for ingest
 	STATE_AUTHENTICATED uint32 = 0xBEEF42
 	// Not authenticated
@@ -67,6 +68,7 @@ var (
 
 	prng        *rand.Rand
 	prngCounter int
+	prngMtx     sync.Mutex // rand.Rand is not safe for concurrent use and indexers hand out challenges in parallel
 )
 
 var tenantAuthHeader = [32]byte{
@@ -180,6 +182,7 @@ func VerifyResponse(auth AuthHash, chal Challenge, resp ChallengeResponse) error
 	return nil
 }
 
+// checkAndReseedPRNG must be called with the prngMtx held
 func checkAndReseedPRNG() {
 	prngCounter -= 1
 	if prngCounter <= 0 {
@@ -193,11 +196,13 @@ func checkAndReseedPRNG() {
 // NewChallenge generates a random hash string and a random iteration count
 func NewChallenge(auth AuthHash) (Challenge, error) {
 	var chal [32]byte
+	prngMtx.Lock()
 	checkAndReseedPRNG()
 	iter := uint16(10000 + prng.Intn(10000))
 	for i := 0; i < len(chal); i++ {
 		chal[i] = byte(prng.Intn(0xff))
 	}
+	prngMtx.Unlock()
 	return Challenge{
 		Iterate:       iter,
 		RandChallenge: chal,
@@ -0,0 +1,168 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	lz4BlockHeaderSize int = 8
+	lz4MaxBlockSize    int = 64 * 1024
+)
+
+var (
+	ErrInvalidLZ4Block = errors.New("invalid lz4 block header")
+)
+
+// newZstdWriter builds a streaming zstd encoder, level is the zstd compression level (1-22)
+// and a zero value uses the encoders default.
+func newZstdWriter(wtr io.Writer, level int8) (*zstd.Encoder, error) {
+	opts := []zstd.EOption{
+		zstd.WithEncoderConcurrency(1),
+	}
+	if level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(int(level))))
+	}
+	return zstd.NewWriter(wtr, opts...)
+}
+
+// newZstdReader builds a streaming zstd decoder that does not read ahead of what has been flushed by the other side.
+func newZstdReader(rdr io.Reader) (io.Reader, error) {
+	dec, err := zstd.NewReader(rdr, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+// The LZ4 frame reader will block until the entire read buffer is filled, which deadlocks
+// an ack stream, so we use a trivial block framing instead.  Each block is prefixed with
+// the uncompressed size and the payload size, if they are equal the payload is stored raw.
+type lz4BlockWriter struct {
+	wtr  io.Writer
+	c    lz4.Compressor
+	buff []byte
+	out  []byte
+}
+
+func newLZ4Writer(wtr io.Writer) *lz4BlockWriter {
+	return &lz4BlockWriter{
+		wtr:  wtr,
+		buff: make([]byte, 0, lz4MaxBlockSize),
+		out:  make([]byte, lz4BlockHeaderSize+lz4.CompressBlockBound(lz4MaxBlockSize)),
+	}
+}
+
+func (w *lz4BlockWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		m := lz4MaxBlockSize - len(w.buff)
+		if m > len(b) {
+			m = len(b)
+		}
+		w.buff = append(w.buff, b[:m]...)
+		b = b[m:]
+		n += m
+		if len(w.buff) == lz4MaxBlockSize {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Flush compresses and writes whatever is buffered as a single block.
+func (w *lz4BlockWriter) Flush() (err error) {
+	if len(w.buff) == 0 {
+		return
+	}
+	var n int
+	payload := w.out[lz4BlockHeaderSize:]
+	if n, err = w.c.CompressBlock(w.buff, payload); err != nil {
+		return
+	} else if n == 0 || n >= len(w.buff) {
+		//not compressible, ship it raw
+		n = copy(payload, w.buff)
+	}
+	binary.LittleEndian.PutUint32(w.out, uint32(len(w.buff)))
+	binary.LittleEndian.PutUint32(w.out[4:], uint32(n))
+	if _, err = w.wtr.Write(w.out[:lz4BlockHeaderSize+n]); err == nil {
+		w.buff = w.buff[:0]
+	}
+	return
+}
+
+// Close flushes any pending data, it does NOT close the underlying writer.
+func (w *lz4BlockWriter) Close() error {
+	return w.Flush()
+}
+
+type lz4BlockReader struct {
+	rdr  io.Reader
+	hdr  []byte
+	in   []byte
+	buff []byte
+	data []byte
+}
+
+func newLZ4Reader(rdr io.Reader) *lz4BlockReader {
+	return &lz4BlockReader{
+		rdr:  rdr,
+		hdr:  make([]byte, lz4BlockHeaderSize),
+		in:   make([]byte, lz4.CompressBlockBound(lz4MaxBlockSize)),
+		buff: make([]byte, lz4MaxBlockSize),
+	}
+}
+
+// Read returns data from a single block at most, so that we never block waiting on data that was not sent.
+func (r *lz4BlockReader) Read(b []byte) (n int, err error) {
+	if len(r.data) == 0 {
+		if err = r.readBlock(); err != nil {
+			return
+		}
+	}
+	n = copy(b, r.data)
+	r.data = r.data[n:]
+	return
+}
+
+func (r *lz4BlockReader) readBlock() (err error) {
+	if _, err = io.ReadFull(r.rdr, r.hdr); err != nil {
+		return
+	}
+	rawSize := int(binary.LittleEndian.Uint32(r.hdr))
+	sz := int(binary.LittleEndian.Uint32(r.hdr[4:]))
+	if rawSize == 0 || rawSize > lz4MaxBlockSize || sz == 0 || sz > rawSize {
+		return ErrInvalidLZ4Block
+	}
+	if _, err = io.ReadFull(r.rdr, r.in[:sz]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if sz == rawSize {
+		r.data = r.buff[:copy(r.buff, r.in[:sz])]
+		return
+	}
+	var n int
+	if n, err = lz4.UncompressBlock(r.in[:sz], r.buff[:rawSize]); err != nil {
+		return
+	} else if n != rawSize {
+		return ErrInvalidLZ4Block
+	}
+	r.data = r.buff[:n]
+	return
+}
@@ -39,6 +39,9 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -58,6 +61,7 @@ import (
 const (
 	defaultLogLevel = `ERROR`
 	minThrottle     = (1024 * 1024) / 8
+	minTagThrottle  = 1024
 )
 
 const (
@@ -67,6 +71,7 @@ const (
 	envEncTarget         string = `GRAVWELL_ENCRYPTED_TARGETS`
 	envPipeTarget        string = `GRAVWELL_PIPE_TARGETS`
 	envCompressionTarget string = `GRAVWELL_ENABLE_COMPRESSION`
+	envCompressionLevel  string = `GRAVWELL_COMPRESSION_LEVEL`
 	envCacheMode         string = `GRAVWELL_CACHE_MODE`
 	envCachePath         string = `GRAVWELL_CACHE_PATH`
 	envMaxCache          string = `GRAVWELL_CACHE_SIZE`
@@ -103,6 +108,8 @@ type IngestConfig struct {
 	Connection_Timeout         string   `json:",omitempty"`
 	Verify_Remote_Certificates bool     `json:"-"` //legacy, will be removed
 	Insecure_Skip_TLS_Verify   bool     `json:",omitempty"`
+	Client_Certificate         string   `json:",omitempty"` // PEM certificate presented to Encrypted-Backend-Target indexers
+	Client_Key                 string   `json:",omitempty"` // PEM private key for Client-Certificate
 	Cleartext_Backend_Target   []string `json:",omitempty"`
 	Encrypted_Backend_Target   []string `json:",omitempty"`
 	Pipe_Backend_Target        []string `json:",omitempty"`
@@ -112,6 +119,8 @@ type IngestConfig struct {
 	Disable_Self_Ingest        bool     //do not ship logs via the gravwell tag
 	Source_Override            string   `json:",omitempty"` // override normal source if desired
 	Rate_Limit                 string   `json:",omitempty"`
+	Tag_Rate_Limit             []string `json:",omitempty"` // <rate>:<tag>[,<tag>...] bandwidth limit applied to each listed tag
+	Tag_Priority               []string `json:",omitempty"` // <high|normal>:<tag>[,<tag>...] scheduling class for each listed tag
 	Ingester_UUID              string   `json:",omitempty"`
 	Cache_Depth                int      `json:",omitempty"`
 	Cache_Mode                 string   `json:",omitempty"`
@@ -123,10 +132,69 @@ type IngestConfig struct {
 	Stats_Sample_Interval      string   `json:",omitempty"` // if set to > 0 duration then we periodically throw stats
 	Timestamp_Max_Past_Delta   string   // if set to > 0 (e.g. "1h"), set TS of entries further than this in the past to now
 	Timestamp_Max_Future_Delta string   // if set to > 0, set TS of entries further that this in the future to now.
+	Target_Group               []string `json:",omitempty"` // <group>:<target>[,<target>...] restrict a subset of targets to a group
+	Target_Group_Tags          []string `json:",omitempty"` // <group>:<tag>[,<tag>...] tags that are only sent to the group
+	Replicate                  bool     `json:",omitempty"` // deliver every entry to every target
+	Replication_Quorum         int      `json:",omitempty"` // targets that must acknowledge an entry, zero means all of them
+	Metrics_Listen_Address     string   `json:",omitempty"` // [host]:port to serve prometheus metrics on, disabled when empty
+	Config_Overlay_Key         []string `json:",omitempty"` // base64 ed25519 public keys trusted to sign remote configuration overlays
 }
 
 type IngestStreamConfig struct {
-	Enable_Compression bool `json:",omitempty"`
+	Enable_Compression bool   `json:",omitempty"`
+	Compression_Type   string `json:",omitempty"` // snappy (default), zstd, lz4, or none
+	Compression_Level  int    `json:",omitempty"` // zstd only, 1-22, zero means use the default
+}
+
+// loadDefaults pulls the compression settings out of the environment.  GRAVWELL_ENABLE_COMPRESSION
+// may be either a boolean or the name of a compression type, naming a type implies compression is enabled.
+func (isc *IngestStreamConfig) loadDefaults() error {
+	if !isc.Enable_Compression {
+		if v, err := loadEnv(envCompressionTarget); err == nil {
+			if isc.Enable_Compression, err = ParseBool(v); err != nil {
+				isc.Enable_Compression = true
+				isc.Compression_Type = v
+			}
+		} else if err != errNoEnvArg {
+			return err
+		}
+	}
+	return LoadEnvVar(&isc.Compression_Level, envCompressionLevel, 0)
+}
+
+// Verify checks that the compression type and level are sensible.
+func (isc *IngestStreamConfig) Verify() (err error) {
+	isc.Compression_Type, err = VerifyCompression(isc.Compression_Type, isc.Compression_Level)
+	return
+}
+
+// MaxZstdCompressionLevel is the highest Compression-Level zstd accepts
+const MaxZstdCompressionLevel = 22
+
+// compressionLevels maps every Compression-Type name to the highest Compression-Level it accepts,
+// types with a maximum of zero do not support levels.
+var compressionLevels = map[string]int{
+	``:          0,
+	`none`:      0,
+	`snappy`:    0,
+	`lz4`:       0,
+	`zstd`:      MaxZstdCompressionLevel,
+	`zstandard`: MaxZstdCompressionLevel,
+}
+
+// VerifyCompression checks a Compression-Type and Compression-Level pair, a level of zero always
+// means the compressor default.  The normalized type name is returned.
+func VerifyCompression(typ string, level int) (string, error) {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	max, ok := compressionLevels[typ]
+	if !ok {
+		return typ, fmt.Errorf("Unknown Compression-Type %q", typ)
+	} else if level != 0 && max == 0 {
+		return typ, fmt.Errorf("Compression-Level is not supported with Compression-Type %q", typ)
+	} else if level < 0 || level > max {
+		return typ, fmt.Errorf("Invalid Compression-Level %d, %s levels are 1-%d", level, typ, max)
+	}
+	return typ, nil
 }
 
 type TimeFormat struct {
@@ -163,7 +231,7 @@ func (ic *IngestConfig) loadDefaults() error {
 		return err
 	}
 	//Compression
-	if err := LoadEnvVar(&ic.Enable_Compression, envCompressionTarget, false); err != nil {
+	if err := ic.IngestStreamConfig.loadDefaults(); err != nil {
 		return err
 	}
 	// Cache
@@ -217,6 +285,9 @@ func (ic *IngestConfig) Verify() error {
 			}
 		}
 	}
+	if err := ic.IngestStreamConfig.Verify(); err != nil {
+		return err
+	}
 	//ensure there is at least one target
 	if (len(ic.Cleartext_Backend_Target) + len(ic.Encrypted_Backend_Target) + len(ic.Pipe_Backend_Target)) == 0 {
 		return ErrNoConnections
@@ -279,8 +350,12 @@ func (ic *IngestConfig) Verify() error {
 	case "":
 		ic.Cache_Mode = CACHE_MODE_DEFAULT
 	case "always", "fail":
+	case "wal":
+		if ic.Ingest_Cache_Path == `` {
+			return errors.New("Cache-Mode wal requires an Ingest-Cache-Path")
+		}
 	default:
-		return errors.New("Cache-Mode must be [always,fail]")
+		return errors.New("Cache-Mode must be [always,fail,wal]")
 	}
 	if ic.Cache_Depth == 0 {
 		ic.Cache_Depth = CACHE_DEPTH_DEFAULT
@@ -294,6 +369,29 @@ func (ic *IngestConfig) Verify() error {
 		}
 	}
 
+	groups, err := ic.TargetGroups()
+	if err != nil {
+		return err
+	}
+	tps, err := ic.TagPolicies()
+	if err != nil {
+		return err
+	} else if err = verifyTagPriority(ic.Replicate, tps, groups); err != nil {
+		return err
+	}
+	if err := ic.verifyReplication(); err != nil {
+		return err
+	}
+	if err := ic.verifyMetrics(); err != nil {
+		return err
+	}
+	if _, err := ic.ConfigOverlayKeys(); err != nil {
+		return err
+	}
+	if err := ic.verifyClientCertificate(); err != nil {
+		return err
+	}
+
 	if len(ic.Timestamp_Max_Past_Delta) > 0 {
 		if _, err := time.ParseDuration(ic.Timestamp_Max_Past_Delta); err != nil {
 			return fmt.Errorf("Could not parse Timestamp-Max-Past-Delta: %v", err)
@@ -582,3 +680,64 @@ func (ctf CustomTimeFormat) LoadFormats(tg *timegrinder.TimeGrinder) (err error)
 	}
 	return
 }
+
+func (ic *IngestConfig) verifyReplication() error {
+	if !ic.Replicate {
+		if ic.Replication_Quorum != 0 {
+			return errors.New("Replication-Quorum requires Replicate")
+		}
+		return nil
+	}
+	if len(ic.Target_Group) > 0 {
+		return errors.New("Replicate and Target-Group are mutually exclusive")
+	} else if strings.ToLower(ic.Cache_Mode) == "wal" {
+		return errors.New("Replicate cannot be used with Cache-Mode wal")
+	}
+	targets := len(ic.Cleartext_Backend_Target) + len(ic.Encrypted_Backend_Target) + len(ic.Pipe_Backend_Target)
+	if ic.Replication_Quorum < 0 || ic.Replication_Quorum > targets {
+		return fmt.Errorf("Replication-Quorum %d must be between 0 and the number of targets (%d)", ic.Replication_Quorum, targets)
+	}
+	return nil
+}
+
+func (ic *IngestConfig) verifyMetrics() error {
+	if ic.Metrics_Listen_Address == `` {
+		return nil
+	}
+	//an empty host is fine, it means listen on everything
+	if _, port, err := net.SplitHostPort(ic.Metrics_Listen_Address); err != nil {
+		return fmt.Errorf("Invalid Metrics-Listen-Address %q %w", ic.Metrics_Listen_Address, err)
+	} else if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("Invalid Metrics-Listen-Address %q port %q is invalid", ic.Metrics_Listen_Address, port)
+	}
+	return nil
+}
+
+func (ic *IngestConfig) verifyClientCertificate() error {
+	if ic.Client_Certificate == `` && ic.Client_Key == `` {
+		return nil
+	} else if ic.Client_Certificate == `` || ic.Client_Key == `` {
+		return errors.New("Client-Certificate and Client-Key must be specified together")
+	} else if len(ic.Encrypted_Backend_Target) == 0 {
+		return errors.New("Client-Certificate requires an Encrypted-Backend-Target")
+	}
+	if _, err := tls.LoadX509KeyPair(ic.Client_Certificate, ic.Client_Key); err != nil {
+		return fmt.Errorf("Invalid Client-Certificate %q %w", ic.Client_Certificate, err)
+	}
+	return nil
+}
+
+// ConfigOverlayKeys returns the public keys trusted to sign configuration overlays delivered by
+// indexers, remote configuration overlays are disabled when no keys are configured.
+func (ic *IngestConfig) ConfigOverlayKeys() (keys []ed25519.PublicKey, err error) {
+	for _, v := range ic.Config_Overlay_Key {
+		var b []byte
+		if b, err = base64.StdEncoding.DecodeString(strings.TrimSpace(v)); err != nil {
+			return nil, fmt.Errorf("Invalid Config-Overlay-Key %q %w", v, err)
+		} else if len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Invalid Config-Overlay-Key %q is not an ed25519 public key", v)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	return
+}
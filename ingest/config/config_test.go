@@ -9,7 +9,10 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"net"
+	"os"
 	"testing"
 )
 
@@ -53,3 +56,231 @@ func TestParseSourceIntegter(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamConfigEnv(t *testing.T) {
+	t.Setenv(envCompressionTarget, `zstd`)
+	t.Setenv(envCompressionLevel, `19`)
+	var isc IngestStreamConfig
+	if err := isc.loadDefaults(); err != nil {
+		t.Fatal(err)
+	} else if err = isc.Verify(); err != nil {
+		t.Fatal(err)
+	} else if !isc.Enable_Compression || isc.Compression_Type != `zstd` || isc.Compression_Level != 19 {
+		t.Fatalf("bad stream config: %+v", isc)
+	}
+
+	//legacy boolean values should still work
+	t.Setenv(envCompressionTarget, `true`)
+	os.Unsetenv(envCompressionLevel)
+	isc = IngestStreamConfig{}
+	if err := isc.loadDefaults(); err != nil {
+		t.Fatal(err)
+	} else if err = isc.Verify(); err != nil {
+		t.Fatal(err)
+	} else if !isc.Enable_Compression || isc.Compression_Type != `` {
+		t.Fatalf("bad stream config: %+v", isc)
+	}
+
+	isc = IngestStreamConfig{Enable_Compression: true, Compression_Type: `lz4`, Compression_Level: 3}
+	if err := isc.Verify(); err == nil {
+		t.Fatal("failed to catch compression level on lz4")
+	}
+	isc = IngestStreamConfig{Enable_Compression: true, Compression_Type: `brotli`}
+	if err := isc.Verify(); err == nil {
+		t.Fatal("failed to catch bad compression type")
+	}
+}
+
+func TestVerifyCompression(t *testing.T) {
+	tests := []struct {
+		typ   string
+		level int
+		norm  string
+		ok    bool
+	}{
+		{``, 0, ``, true},
+		{` Snappy `, 0, `snappy`, true},
+		{`none`, 0, `none`, true},
+		{`lz4`, 0, `lz4`, true},
+		{`ZSTD`, 0, `zstd`, true},
+		{`zstandard`, MaxZstdCompressionLevel, `zstandard`, true},
+		{`zstd`, MaxZstdCompressionLevel + 1, `zstd`, false},
+		{`zstd`, -1, `zstd`, false},
+		{`snappy`, 1, `snappy`, false},
+		{``, 3, ``, false},
+		{`brotli`, 0, `brotli`, false},
+	}
+	for _, tst := range tests {
+		norm, err := VerifyCompression(tst.typ, tst.level)
+		if (err == nil) != tst.ok {
+			t.Fatalf("%q %d: bad result %v", tst.typ, tst.level, err)
+		} else if norm != tst.norm {
+			t.Fatalf("%q %d: bad normalized type %q", tst.typ, tst.level, norm)
+		}
+	}
+}
+
+func TestTargetGroups(t *testing.T) {
+	ic := IngestConfig{
+		Cleartext_Backend_Target: []string{`10.0.0.1`, `10.0.0.2:4023`},
+		Encrypted_Backend_Target: []string{`10.0.0.3`},
+		Target_Group:             []string{`secure:10.0.0.2, 10.0.0.3`},
+		Target_Group_Tags:        []string{`secure:pci,hr`, `secure: payroll`},
+	}
+	tgs, err := ic.TargetGroups()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tgs) != 1 {
+		t.Fatalf("bad group count %d", len(tgs))
+	}
+	tg := tgs[0]
+	if tg.Name != `secure` {
+		t.Fatalf("bad name %q", tg.Name)
+	} else if len(tg.Targets) != 2 || tg.Targets[0] != `tcp://10.0.0.2:4023` || tg.Targets[1] != `tls://10.0.0.3:4024` {
+		t.Fatalf("bad targets %v", tg.Targets)
+	} else if len(tg.Tags) != 3 || tg.Tags[2] != `payroll` {
+		t.Fatalf("bad tags %v", tg.Tags)
+	}
+
+	bad := []IngestConfig{
+		{Cleartext_Backend_Target: []string{`10.0.0.1`}, Target_Group: []string{`secure:10.0.0.9`}},
+		{Cleartext_Backend_Target: []string{`10.0.0.1`}, Target_Group: []string{`secure`}},
+		{Cleartext_Backend_Target: []string{`10.0.0.1`}, Target_Group: []string{`se/cure:10.0.0.1`}},
+		{Cleartext_Backend_Target: []string{`10.0.0.1`}, Target_Group_Tags: []string{`secure:pci`}},
+		{Cleartext_Backend_Target: []string{`10.0.0.1`}, Target_Group: []string{`a:10.0.0.1`, `b:10.0.0.1`}},
+	}
+	for i, v := range bad {
+		if _, err := v.TargetGroups(); err == nil {
+			t.Fatalf("failed to catch bad target group %d", i)
+		}
+	}
+}
+
+func TestReplicationConfig(t *testing.T) {
+	ic := IngestConfig{
+		Cleartext_Backend_Target: []string{`10.0.0.1`, `10.0.0.2`},
+		Replicate:                true,
+		Replication_Quorum:       1,
+	}
+	if err := ic.verifyReplication(); err != nil {
+		t.Fatal(err)
+	}
+	ic.Replication_Quorum = 3
+	if err := ic.verifyReplication(); err == nil {
+		t.Fatal("failed to catch oversized quorum")
+	}
+	ic.Replication_Quorum = 0
+	ic.Target_Group = []string{`a:10.0.0.1`}
+	if err := ic.verifyReplication(); err == nil {
+		t.Fatal("failed to catch replication with target groups")
+	}
+	ic.Target_Group = nil
+	ic.Cache_Mode = `WAL`
+	if err := ic.verifyReplication(); err == nil {
+		t.Fatal("failed to catch replication with a write-ahead log")
+	}
+	ic = IngestConfig{Replication_Quorum: 1}
+	if err := ic.verifyReplication(); err == nil {
+		t.Fatal("failed to catch quorum without replication")
+	}
+}
+
+func TestMetricsConfig(t *testing.T) {
+	for _, v := range []string{``, `:9100`, `127.0.0.1:9100`, `[::1]:9100`} {
+		ic := IngestConfig{Metrics_Listen_Address: v}
+		if err := ic.verifyMetrics(); err != nil {
+			t.Fatalf("rejected valid metrics address %q: %v", v, err)
+		}
+	}
+	for _, v := range []string{`127.0.0.1`, `:0`, `:http`, `127.0.0.1:70000`} {
+		ic := IngestConfig{Metrics_Listen_Address: v}
+		if err := ic.verifyMetrics(); err == nil {
+			t.Fatalf("failed to catch bad metrics address %q", v)
+		}
+	}
+}
+
+func TestConfigOverlayKeys(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := IngestConfig{Config_Overlay_Key: []string{base64.StdEncoding.EncodeToString(pub)}}
+	if keys, err := ic.ConfigOverlayKeys(); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 1 || !keys[0].Equal(pub) {
+		t.Fatalf("bad keys %v", keys)
+	}
+	for _, v := range []string{`notbase64!`, base64.StdEncoding.EncodeToString(pub[:16])} {
+		ic = IngestConfig{Config_Overlay_Key: []string{v}}
+		if _, err := ic.ConfigOverlayKeys(); err == nil {
+			t.Fatalf("failed to catch bad key %q", v)
+		}
+	}
+}
+
+func TestClientCertificateConfig(t *testing.T) {
+	ic := IngestConfig{}
+	if err := ic.verifyClientCertificate(); err != nil {
+		t.Fatal(err)
+	}
+	ic = IngestConfig{Client_Certificate: `/tmp/cert.pem`, Encrypted_Backend_Target: []string{`10.0.0.1`}}
+	if err := ic.verifyClientCertificate(); err == nil {
+		t.Fatal("failed to catch missing Client-Key")
+	}
+	ic = IngestConfig{Client_Certificate: `/tmp/cert.pem`, Client_Key: `/tmp/key.pem`}
+	if err := ic.verifyClientCertificate(); err == nil {
+		t.Fatal("failed to catch missing Encrypted-Backend-Target")
+	}
+	ic.Encrypted_Backend_Target = []string{`10.0.0.1`}
+	ic.Client_Certificate = t.TempDir() + `/missing.pem`
+	if err := ic.verifyClientCertificate(); err == nil {
+		t.Fatal("failed to catch unreadable certificate")
+	}
+}
+
+func TestTagPolicies(t *testing.T) {
+	ic := IngestConfig{
+		Tag_Rate_Limit: []string{`1Mbit:syslog, netflow`, `512kbit:debug`},
+		Tag_Priority:   []string{`HIGH:alerts,syslog`},
+	}
+	tps, err := ic.TagPolicies()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tps) != 4 {
+		t.Fatalf("bad policy count %d", len(tps))
+	}
+	if tps[0].Tag != `alerts` || !tps[0].High() || tps[0].RateLimit != 0 {
+		t.Fatalf("bad alerts policy %+v", tps[0])
+	} else if tps[1].Tag != `debug` || tps[1].High() || tps[1].RateLimit != 512*1024 {
+		t.Fatalf("bad debug policy %+v", tps[1])
+	} else if tps[3].Tag != `syslog` || !tps[3].High() || tps[3].RateLimit != 1024*1024 {
+		t.Fatalf("bad syslog policy %+v", tps[3])
+	}
+
+	bad := []IngestConfig{
+		{Tag_Rate_Limit: []string{`1Mbit`}},
+		{Tag_Rate_Limit: []string{`fast:syslog`}},
+		{Tag_Rate_Limit: []string{`100bit:syslog`}},
+		{Tag_Rate_Limit: []string{`1Mbit:syslog`, `2Mbit:syslog`}},
+		{Tag_Priority: []string{`urgent:syslog`}},
+		{Tag_Priority: []string{`high:syslog`, `normal:syslog`}},
+	}
+	for i, v := range bad {
+		if _, err := v.TagPolicies(); err == nil {
+			t.Fatalf("failed to catch bad tag policy %d", i)
+		}
+	}
+
+	//high priority tags cannot be replicated or restricted to a target group
+	groups := []TargetGroup{{Name: `secure`, Tags: []string{`pci`}}}
+	if err = verifyTagPriority(false, tps, groups); err != nil {
+		t.Fatal(err)
+	} else if err = verifyTagPriority(true, tps, nil); err == nil {
+		t.Fatal("failed to catch high priority tag with replication")
+	} else if err = verifyTagPriority(false, tps, []TargetGroup{{Name: `secure`, Tags: []string{`alerts`}}}); err == nil {
+		t.Fatal("failed to catch high priority tag in a target group")
+	} else if err = verifyTagPriority(true, tps[1:3], groups); err != nil {
+		t.Fatalf("normal priority tags rejected %v", err)
+	}
+}
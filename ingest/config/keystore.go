@@ -0,0 +1,247 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	envKeystore           string = `GRAVWELL_KEYSTORE`
+	envKeystorePassphrase string = `GRAVWELL_KEYSTORE_PASSPHRASE`
+
+	DefaultKeystorePath = `/opt/gravwell/etc/ingesters.keystore`
+
+	keystoreVersion    = 1
+	keystoreIterations = 600000
+	keystoreSaltSize   = 16
+	keystoreKeySize    = 32
+	maxKeystoreSize    = 4 * mb
+)
+
+var (
+	ErrKeystoreNoPassphrase = errors.New("keystore passphrase is not set")
+	ErrKeystoreVersion      = errors.New("unsupported keystore version")
+	ErrKeystoreDecrypt      = errors.New("failed to decrypt keystore, bad passphrase or corrupted file")
+	ErrKeystoreTooLarge     = errors.New("keystore is too large")
+	ErrKeystoreEmptyName    = errors.New("keystore entry name is empty")
+)
+
+// Keystore is a local file of named secrets encrypted with AES-256-GCM under a key derived
+// from a passphrase with PBKDF2-SHA256.  Ingesters reference entries with ${keystore:name}.
+type Keystore struct {
+	path       string
+	passphrase string
+	values     map[string]string
+}
+
+type keystoreFile struct {
+	Version    int
+	Iterations int
+	Salt       []byte
+	Nonce      []byte
+	Data       []byte
+}
+
+// NewKeystore creates an empty keystore that will be written to path by Save
+func NewKeystore(path, passphrase string) (*Keystore, error) {
+	if passphrase == `` {
+		return nil, ErrKeystoreNoPassphrase
+	}
+	return &Keystore{
+		path:       path,
+		passphrase: passphrase,
+		values:     map[string]string{},
+	}, nil
+}
+
+// OpenKeystore reads and decrypts an existing keystore
+func OpenKeystore(path, passphrase string) (ks *Keystore, err error) {
+	if ks, err = NewKeystore(path, passphrase); err != nil {
+		return
+	}
+	var fi os.FileInfo
+	var b []byte
+	if fi, err = os.Stat(path); err != nil {
+		return nil, err
+	} else if fi.Size() > maxKeystoreSize {
+		return nil, ErrKeystoreTooLarge
+	} else if b, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+	var kf keystoreFile
+	if err = json.Unmarshal(b, &kf); err != nil {
+		return nil, fmt.Errorf("invalid keystore %w", err)
+	} else if kf.Version != keystoreVersion {
+		return nil, fmt.Errorf("%w %d", ErrKeystoreVersion, kf.Version)
+	}
+	var aead cipher.AEAD
+	if aead, err = keystoreCipher(passphrase, kf.Salt, kf.Iterations); err != nil {
+		return nil, err
+	} else if len(kf.Nonce) != aead.NonceSize() {
+		return nil, ErrKeystoreDecrypt
+	}
+	if b, err = aead.Open(nil, kf.Nonce, kf.Data, nil); err != nil {
+		return nil, ErrKeystoreDecrypt
+	} else if err = json.Unmarshal(b, &ks.values); err != nil {
+		return nil, fmt.Errorf("invalid keystore contents %w", err)
+	}
+	if ks.values == nil {
+		ks.values = map[string]string{}
+	}
+	return
+}
+
+func keystoreCipher(passphrase string, salt []byte, iter int) (cipher.AEAD, error) {
+	if iter <= 0 || len(salt) == 0 {
+		return nil, ErrKeystoreDecrypt
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iter, keystoreKeySize)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(blk)
+}
+
+// Get returns the value of a named entry
+func (ks *Keystore) Get(name string) (v string, ok bool) {
+	v, ok = ks.values[name]
+	return
+}
+
+// Set adds or replaces a named entry, call Save to persist it
+func (ks *Keystore) Set(name, value string) error {
+	if name == `` {
+		return ErrKeystoreEmptyName
+	}
+	ks.values[name] = value
+	return nil
+}
+
+// Delete removes a named entry, call Save to persist the removal
+func (ks *Keystore) Delete(name string) {
+	delete(ks.values, name)
+}
+
+// Names returns the sorted names of every entry
+func (ks *Keystore) Names() (r []string) {
+	for k := range ks.values {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return
+}
+
+// Save encrypts the keystore with a fresh salt and nonce and atomically replaces the file
+func (ks *Keystore) Save() (err error) {
+	kf := keystoreFile{
+		Version:    keystoreVersion,
+		Iterations: keystoreIterations,
+		Salt:       make([]byte, keystoreSaltSize),
+	}
+	if _, err = rand.Read(kf.Salt); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = keystoreCipher(ks.passphrase, kf.Salt, kf.Iterations); err != nil {
+		return
+	}
+	kf.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(kf.Nonce); err != nil {
+		return
+	}
+	var b []byte
+	if b, err = json.Marshal(ks.values); err != nil {
+		return
+	}
+	kf.Data = aead.Seal(nil, kf.Nonce, b, nil)
+	if b, err = json.Marshal(kf); err != nil {
+		return
+	}
+
+	var fout *os.File
+	if fout, err = os.CreateTemp(filepath.Dir(ks.path), filepath.Base(ks.path)+`.tmp`); err != nil {
+		return
+	}
+	tpath := fout.Name()
+	if err = fout.Chmod(0600); err == nil {
+		if err = writeFull(fout, b); err == nil {
+			err = fout.Sync()
+		}
+	}
+	if lerr := fout.Close(); lerr != nil && err == nil {
+		err = lerr
+	}
+	if err == nil {
+		err = os.Rename(tpath, ks.path)
+	}
+	if err != nil {
+		os.Remove(tpath)
+	}
+	return
+}
+
+// keystoreProvider resolves ${keystore:name} references from the keystore named by GRAVWELL_KEYSTORE
+// using the passphrase in GRAVWELL_KEYSTORE_PASSPHRASE or GRAVWELL_KEYSTORE_PASSPHRASE_FILE.
+// Key derivation is deliberately slow so the decrypted keystore is cached until the file changes.
+type keystoreProvider struct {
+	sync.Mutex
+	path       string
+	passphrase string
+	mod        time.Time
+	size       int64
+	ks         *Keystore
+}
+
+func (kp *keystoreProvider) Resolve(name string) (v string, err error) {
+	pth := os.Getenv(envKeystore)
+	if pth == `` {
+		pth = DefaultKeystorePath
+	}
+	var pass string
+	if pass, err = loadEnv(envKeystorePassphrase); err != nil {
+		err = ErrKeystoreNoPassphrase
+		return
+	}
+	var fi os.FileInfo
+	if fi, err = os.Stat(pth); err != nil {
+		return
+	}
+
+	kp.Lock()
+	defer kp.Unlock()
+	if kp.ks == nil || kp.path != pth || kp.passphrase != pass || !kp.mod.Equal(fi.ModTime()) || kp.size != fi.Size() {
+		var ks *Keystore
+		if ks, err = OpenKeystore(pth, pass); err != nil {
+			return
+		}
+		kp.ks, kp.path, kp.passphrase, kp.mod, kp.size = ks, pth, pass, fi.ModTime(), fi.Size()
+	}
+	var ok bool
+	if v, ok = kp.ks.Get(name); !ok {
+		err = ErrNotFound
+	}
+	return
+}
@@ -115,9 +115,13 @@ func LoadConfigOverlays(v interface{}, pth string) (err error) {
 }
 
 // LoadConfigBytes parses the contents of b into the given interface v.
-func LoadConfigBytes(v interface{}, b []byte) error {
+// Any ${env:NAME}, ${file:/path}, or secret provider references are resolved before parsing,
+// so values handed to VariableConfig.MapTo have already been resolved.
+func LoadConfigBytes(v interface{}, b []byte) (err error) {
 	if int64(len(b)) > maxConfigSize {
 		return ErrConfigFileTooLarge
+	} else if b, err = resolveReferences(b); err != nil {
+		return
 	}
 	return gcfg.ReadStringInto(v, string(b))
 }
@@ -0,0 +1,390 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	refStart   = `${`
+	refEscape  = `$${`
+	refEnd     = '}'
+	refSep     = `:`
+	secretMask = `********`
+
+	envScheme      = `env`
+	fileScheme     = `file`
+	vaultScheme    = `vault`
+	keystoreScheme = `keystore`
+)
+
+var (
+	ErrInvalidScheme      = errors.New("invalid secret provider scheme")
+	ErrNilProvider        = errors.New("nil secret provider")
+	ErrUnterminatedRef    = errors.New("unterminated reference")
+	ErrEmptyReference     = errors.New("empty reference")
+	ErrSecretFileTooLarge = errors.New("secret file is too large")
+)
+
+// SecretProvider resolves configuration references of the form ${scheme:ref}.
+// Every configuration value that held a reference, no matter the provider, is treated
+// as a secret and is masked whenever a configuration is marshalled with MaskSecrets.
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// secretField names a configuration value that held a reference, the name is normalized
+// the way the parser matches it to a struct field
+type secretField struct {
+	subsection string
+	name       string
+}
+
+var (
+	provMtx   sync.RWMutex
+	providers = map[string]SecretProvider{
+		envScheme:      envProvider{},
+		fileScheme:     fileProvider{},
+		vaultScheme:    &VaultProvider{},
+		keystoreScheme: &keystoreProvider{},
+	}
+
+	secretMtx    sync.RWMutex
+	secretFields = map[secretField]struct{}{}
+)
+
+// RegisterSecretProvider adds or replaces the provider used for ${scheme:ref} references.
+// Schemes are lower case letters, digits, dashes, and underscores and must start with a letter.
+func RegisterSecretProvider(scheme string, p SecretProvider) error {
+	if p == nil {
+		return ErrNilProvider
+	} else if !validScheme(scheme) {
+		return fmt.Errorf("%w %q", ErrInvalidScheme, scheme)
+	}
+	provMtx.Lock()
+	providers[scheme] = p
+	provMtx.Unlock()
+	return nil
+}
+
+func validScheme(s string) bool {
+	if len(s) == 0 || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func getProvider(scheme string) (p SecretProvider, ok bool) {
+	provMtx.RLock()
+	p, ok = providers[scheme]
+	provMtx.RUnlock()
+	return
+}
+
+// ResolveReference resolves a single scheme:ref reference, the ${} wrapper is optional.
+// The value is not tied to a configuration field so MaskSecrets cannot mask it.
+func ResolveReference(ref string) (v string, err error) {
+	ref = strings.TrimSuffix(strings.TrimPrefix(ref, refStart), string(refEnd))
+	scheme, r, ok := strings.Cut(ref, refSep)
+	if !ok {
+		return ``, fmt.Errorf("%w %q", ErrInvalidScheme, ref)
+	}
+	p, ok := getProvider(scheme)
+	if !ok {
+		return ``, fmt.Errorf("%w %q", ErrInvalidScheme, scheme)
+	}
+	return resolve(p, scheme, r)
+}
+
+func resolve(p SecretProvider, scheme, ref string) (v string, err error) {
+	if ref = strings.TrimSpace(ref); ref == `` {
+		err = fmt.Errorf("%s %w", scheme, ErrEmptyReference)
+	} else if v, err = p.Resolve(ref); err != nil {
+		err = fmt.Errorf("failed to resolve %s reference %q %w", scheme, ref, err)
+	}
+	return
+}
+
+// resolveReferences walks the raw configuration and replaces every ${scheme:ref} that names
+// a registered provider with its value, quoting and escaping the value so that the gcfg parser
+// reads it back verbatim.  Comments, section headers, and backtick raw strings are left alone,
+// as are references that do not name a provider, such as preprocessor templates like ${_SRC_}.
+// $${ is an escape for a literal ${.  Every value that held a reference is registered with MaskSecrets.
+func resolveReferences(b []byte) ([]byte, error) {
+	if !bytes.Contains(b, []byte(refStart)) {
+		return b, nil //fast path, nothing to do
+	}
+	var out bytes.Buffer
+	out.Grow(len(b))
+	var fld secretField
+	lineStart, inQuote := true, false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if lineStart {
+			switch c {
+			case ' ', '\t', '\r':
+				out.WriteByte(c)
+				continue
+			case '[', '#', ';':
+				//section headers and comments are copied through to the end of the line
+				end := bytes.IndexByte(b[i:], '\n')
+				if end < 0 {
+					end = len(b) - i
+				}
+				if c == '[' {
+					fld.subsection = parseSubsection(b[i : i+end])
+				}
+				out.Write(b[i : i+end])
+				i += end - 1
+				continue
+			}
+			lineStart = false
+			fld.name = variableName(b[i:])
+		}
+		switch {
+		case c == '\n':
+			lineStart, inQuote = true, false
+			out.WriteByte(c)
+		case c == '\\' && i+1 < len(b):
+			//escapes and line continuations are copied verbatim
+			out.Write(b[i : i+2])
+			i++
+		case c == '"':
+			inQuote = !inQuote
+			out.WriteByte(c)
+		case !inQuote && (c == '#' || c == ';'):
+			end := bytes.IndexByte(b[i:], '\n')
+			if end < 0 {
+				end = len(b) - i
+			}
+			out.Write(b[i : i+end])
+			i += end - 1
+		case !inQuote && c == '`':
+			//raw strings are never resolved
+			end := bytes.IndexByte(b[i+1:], '`')
+			if end < 0 {
+				end = len(b) - i - 1
+			}
+			out.Write(b[i : i+end+2])
+			i += end + 1
+		case bytes.HasPrefix(b[i:], []byte(refEscape)):
+			out.WriteString(refStart)
+			i += len(refEscape) - 1
+		case bytes.HasPrefix(b[i:], []byte(refStart)):
+			n, resolved, err := writeReference(&out, b[i:], inQuote)
+			if err != nil {
+				return nil, err
+			} else if resolved {
+				registerSecretField(fld)
+			}
+			i += n - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// parseSubsection pulls the subsection out of a [section "subsection"] line, it is empty for a plain [section]
+func parseSubsection(ln []byte) (subsection string) {
+	hdr := string(ln)
+	if end := strings.IndexByte(hdr, ']'); end > 0 {
+		hdr = hdr[1:end]
+	} else {
+		hdr = hdr[1:]
+	}
+	_, subsection, _ = strings.Cut(strings.TrimSpace(hdr), ` `)
+	if subsection = strings.TrimSpace(subsection); len(subsection) >= 2 && subsection[0] == '"' {
+		subsection = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(subsection[1 : len(subsection)-1])
+	}
+	return
+}
+
+// variableName returns the normalized name of the variable assigned on the line starting at b
+func variableName(b []byte) string {
+	if end := bytes.IndexAny(b, "=\n"); end >= 0 {
+		b = b[:end]
+	}
+	return normalizeFieldName(string(bytes.TrimSpace(b)))
+}
+
+// normalizeFieldName maps a variable name onto the struct field name the parser would use
+func normalizeFieldName(v string) string {
+	return strings.ToLower(strings.ReplaceAll(v, `-`, `_`))
+}
+
+// writeReference resolves the reference at the start of b and returns the number of bytes consumed,
+// resolved is false when the bytes were not a provider reference and were copied through
+func writeReference(out *bytes.Buffer, b []byte, inQuote bool) (n int, resolved bool, err error) {
+	end := bytes.IndexAny(b, "}\n")
+	if end < 0 || b[end] != refEnd {
+		//not a reference we could ever handle, let the consumer of the value deal with it
+		if end < 0 {
+			end = len(b)
+		}
+		if ref := string(b[len(refStart):end]); isProviderRef(ref) {
+			err = fmt.Errorf("%w %q", ErrUnterminatedRef, ref)
+			return
+		}
+		out.WriteString(refStart)
+		return len(refStart), false, nil
+	}
+	ref := string(b[len(refStart):end])
+	scheme, r, _ := strings.Cut(ref, refSep)
+	p, ok := getProvider(scheme)
+	if !ok || !strings.Contains(ref, refSep) {
+		out.WriteString(refStart)
+		return len(refStart), false, nil
+	}
+	var v string
+	if v, err = resolve(p, scheme, r); err != nil {
+		return
+	}
+	if !inQuote {
+		out.WriteByte('"')
+	}
+	out.WriteString(escapeValue(v))
+	if !inQuote {
+		out.WriteByte('"')
+	}
+	n, resolved = end+1, true
+	return
+}
+
+func isProviderRef(ref string) bool {
+	scheme, _, ok := strings.Cut(ref, refSep)
+	if !ok {
+		return false
+	}
+	_, ok = getProvider(scheme)
+	return ok
+}
+
+// escapeValue escapes a value for use inside a gcfg double quoted string
+func escapeValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(v)
+}
+
+type envProvider struct{}
+
+func (envProvider) Resolve(ref string) (string, error) {
+	if v, ok := os.LookupEnv(ref); ok {
+		return v, nil
+	}
+	return ``, ErrNotFound
+}
+
+type fileProvider struct{}
+
+func (fileProvider) Resolve(ref string) (v string, err error) {
+	var fin *os.File
+	if fin, err = os.Open(ref); err != nil {
+		return
+	}
+	defer fin.Close()
+	var b []byte
+	if b, err = io.ReadAll(io.LimitReader(fin, maxFileValueSize+1)); err != nil {
+		return
+	} else if int64(len(b)) > maxFileValueSize {
+		err = ErrSecretFileTooLarge
+		return
+	}
+	v = strings.TrimRight(string(b), "\r\n")
+	return
+}
+
+func registerSecretField(fld secretField) {
+	if fld.name == `` {
+		return
+	}
+	secretMtx.Lock()
+	secretFields[fld] = struct{}{}
+	secretMtx.Unlock()
+}
+
+// MaskSecrets replaces the value of every field of a JSON encoded configuration that was set from
+// a ${scheme:ref} reference with a mask.  Fields are matched by name, fields from a named subsection
+// must also sit directly beneath a key with the subsection name.  Keys are never modified.
+func MaskSecrets(msg []byte) ([]byte, error) {
+	secretMtx.RLock()
+	set := make([]secretField, 0, len(secretFields))
+	for k := range secretFields {
+		set = append(set, k)
+	}
+	secretMtx.RUnlock()
+	if len(set) == 0 || len(msg) == 0 {
+		return msg, nil
+	}
+
+	var obj interface{}
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return json.Marshal(maskValue(obj, ``, set))
+}
+
+func maskValue(v interface{}, parent string, set []secretField) interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		for i := range x {
+			x[i] = maskValue(x[i], parent, set)
+		}
+	case map[string]interface{}:
+		for k, xv := range x {
+			if isSecretField(k, parent, set) {
+				x[k] = maskField(xv)
+			} else {
+				x[k] = maskValue(xv, k, set)
+			}
+		}
+	}
+	return v
+}
+
+func isSecretField(key, parent string, set []secretField) bool {
+	name := normalizeFieldName(key)
+	for _, f := range set {
+		if f.name == name && (f.subsection == `` || f.subsection == parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskField masks a field value, multi-value fields have every value masked
+func maskField(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		if x != `` {
+			return secretMask
+		}
+	case json.Number:
+		return secretMask
+	case []interface{}:
+		for i := range x {
+			x[i] = maskField(x[i])
+		}
+	}
+	return v
+}
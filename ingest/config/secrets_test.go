@@ -0,0 +1,242 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	t.Setenv(`GW_TEST_HOST`, `10.0.0.1`)
+	t.Setenv(`GW_TEST_NUM`, `42`)
+	t.Setenv(`GW_TEST_UGLY`, "a \"quoted\" value; with # and \\ and\nnewline")
+	secretPath := filepath.Join(tempDir, `secret`)
+	if err := os.WriteFile(secretPath, []byte("supersecret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b := []byte(`
+	[global]
+	foo = ${env:GW_TEST_HOST}:4023 # ${env:NOT_SET_IN_COMMENT}
+	bar = ${env:GW_TEST_NUM}
+	foo-bar-baz="${env:GW_TEST_UGLY}"
+	;foo = ${env:NOT_SET_EITHER}
+
+	[item "A"]
+	name = "token=${file:` + secretPath + `}"
+	value = 1
+
+	[item "B"]
+	name = $${env:GW_TEST_HOST}
+
+	[preprocessor "tmpl"]
+	Template="${_SRC_} ${stuff} ${nope, Chuck Testa!} ${unknown:thing}"
+	Raw=` + "`${env:NOT_SET_RAW}`" + `
+	Count=${env:GW_TEST_NUM}
+	`)
+	var ts testStruct
+	if err := LoadConfigBytes(&ts, b); err != nil {
+		t.Fatal(err)
+	}
+	if ts.Global.Foo != `10.0.0.1:4023` || ts.Global.Bar != 42 {
+		t.Fatalf("bad global values %q %d", ts.Global.Foo, ts.Global.Bar)
+	} else if ts.Global.Foo_Bar_Baz != os.Getenv(`GW_TEST_UGLY`) {
+		t.Fatalf("value did not round trip: %q", ts.Global.Foo_Bar_Baz)
+	} else if ts.Item[`A`].Name != `token=supersecret` {
+		t.Fatalf("bad file reference %q", ts.Item[`A`].Name)
+	} else if ts.Item[`B`].Name != `${env:GW_TEST_HOST}` {
+		t.Fatalf("bad escaped reference %q", ts.Item[`B`].Name)
+	}
+	var pp struct {
+		Template string
+		Raw      string
+		Count    int
+	}
+	if err := ts.Preprocessor[`tmpl`].MapTo(&pp); err != nil {
+		t.Fatal(err)
+	} else if pp.Template != `${_SRC_} ${stuff} ${nope, Chuck Testa!} ${unknown:thing}` {
+		t.Fatalf("template was modified: %q", pp.Template)
+	} else if pp.Raw != `${env:NOT_SET_RAW}` || pp.Count != 42 {
+		t.Fatalf("bad preprocessor values %q %d", pp.Raw, pp.Count)
+	}
+
+	//missing references and unterminated references are errors
+	bad := []string{
+		"[global]\nfoo=${env:GW_TEST_NOT_SET}\n",
+		"[global]\nfoo=${file:" + filepath.Join(tempDir, `nope`) + "}\n",
+		"[global]\nfoo=${env:GW_TEST_HOST\n",
+		"[global]\nfoo=${env:}\n",
+	}
+	for _, v := range bad {
+		if err := LoadConfigBytes(&ts, []byte(v)); err == nil {
+			t.Fatalf("failed to catch bad reference %q", v)
+		}
+	}
+}
+
+type testSecretProvider map[string]string
+
+func (tsp testSecretProvider) Resolve(ref string) (string, error) {
+	if v, ok := tsp[ref]; ok {
+		return v, nil
+	}
+	return ``, ErrNotFound
+}
+
+func TestMaskSecrets(t *testing.T) {
+	if err := RegisterSecretProvider(`Bad Scheme`, testSecretProvider{}); err == nil {
+		t.Fatal("failed to catch bad scheme")
+	} else if err = RegisterSecretProvider(`testsp`, nil); err == nil {
+		t.Fatal("failed to catch nil provider")
+	} else if err = RegisterSecretProvider(`testsp`, testSecretProvider{`pw`: `hunter2hunter2`}); err != nil {
+		t.Fatal(err)
+	}
+	secretMtx.Lock()
+	secretFields = map[secretField]struct{}{}
+	secretMtx.Unlock()
+	t.Setenv(`GW_TEST_PLAIN`, `notasecret`)
+	b := []byte(`
+	[global]
+	foo = ${env:GW_TEST_PLAIN}
+	bar = 7
+	foo-bar-baz = "https://user:${testsp:pw}@example.com"
+
+	[item "A"]
+	name = ${testsp:pw}
+	value = 1
+
+	[item "B"]
+	name = hunter2hunter2
+	value = 2
+	`)
+	var ts testStruct
+	if err := LoadConfigBytes(&ts, b); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := json.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	} else if msg, err = MaskSecrets(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	//every field that held a reference is masked, fields that did not are left alone even if they hold the same value
+	var masked testStruct
+	if err = json.Unmarshal(msg, &masked); err != nil {
+		t.Fatal(err)
+	} else if masked.Global.Foo != secretMask || masked.Global.Foo_Bar_Baz != secretMask {
+		t.Fatalf("global references were not masked: %s", msg)
+	} else if masked.Global.Bar != 7 {
+		t.Fatalf("plain global value was modified: %s", msg)
+	} else if masked.Item[`A`].Name != secretMask || masked.Item[`A`].Value != 1 {
+		t.Fatalf("bad subsection masking: %s", msg)
+	} else if masked.Item[`B`].Name != `hunter2hunter2` {
+		t.Fatalf("field without a reference was masked: %s", msg)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`X-Vault-Token`) != `testtoken` {
+			http.Error(w, `permission denied`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case `/v1/secret/data/gravwell`:
+			w.Write([]byte(`{"data":{"data":{"secret":"kv2value","port":4023},"metadata":{"version":3}}}`))
+		case `/v1/kv/gravwell`:
+			w.Write([]byte(`{"data":{"secret":"kv1value"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	vp := &VaultProvider{Address: srv.URL, Token: `testtoken`}
+	good := map[string]string{
+		`secret/data/gravwell#secret`: `kv2value`,
+		`/secret/data/gravwell#port`:  `4023`,
+		`kv/gravwell#secret`:          `kv1value`,
+	}
+	for ref, want := range good {
+		if v, err := vp.Resolve(ref); err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		} else if v != want {
+			t.Fatalf("%s: %q != %q", ref, v, want)
+		}
+	}
+	if _, err := vp.Resolve(`secret/data/gravwell#nope`); !errors.Is(err, ErrVaultKeyNotFound) {
+		t.Fatalf("bad missing key error %v", err)
+	} else if _, err = vp.Resolve(`secret/data/gravwell`); !errors.Is(err, ErrVaultMissingKey) {
+		t.Fatalf("bad missing key error %v", err)
+	} else if _, err = vp.Resolve(`secret/data/other#secret`); err == nil {
+		t.Fatal("failed to catch missing secret")
+	}
+	vp.Token = `wrong`
+	if _, err := vp.Resolve(`kv/gravwell#secret`); err == nil {
+		t.Fatal("failed to catch bad token")
+	}
+
+	//the default provider pulls from the environment
+	t.Setenv(envVaultAddr, srv.URL)
+	t.Setenv(envVaultToken, `testtoken`)
+	var ts testStruct
+	if err := LoadConfigBytes(&ts, []byte("[global]\nfoo=${vault:kv/gravwell#secret}\n")); err != nil {
+		t.Fatal(err)
+	} else if ts.Global.Foo != `kv1value` {
+		t.Fatalf("bad vault value %q", ts.Global.Foo)
+	}
+}
+
+func TestKeystore(t *testing.T) {
+	pth := filepath.Join(tempDir, `test.keystore`)
+	ks, err := NewKeystore(pth, `correct horse`)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = ks.Set(`ingest-secret`, `IngestSecrets`); err != nil {
+		t.Fatal(err)
+	} else if err = ks.Set(`other`, "multi\nline"); err != nil {
+		t.Fatal(err)
+	} else if err = ks.Set(``, `x`); err == nil {
+		t.Fatal("failed to catch empty name")
+	} else if err = ks.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(pth); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Fatalf("bad keystore permissions %v", fi.Mode())
+	}
+
+	if _, err = OpenKeystore(pth, `wrong`); !errors.Is(err, ErrKeystoreDecrypt) {
+		t.Fatalf("bad wrong passphrase error %v", err)
+	}
+	if ks, err = OpenKeystore(pth, `correct horse`); err != nil {
+		t.Fatal(err)
+	} else if v, ok := ks.Get(`ingest-secret`); !ok || v != `IngestSecrets` {
+		t.Fatalf("bad keystore value %q %v", v, ok)
+	} else if names := ks.Names(); len(names) != 2 || names[0] != `ingest-secret` {
+		t.Fatalf("bad names %v", names)
+	}
+
+	t.Setenv(envKeystore, pth)
+	t.Setenv(envKeystorePassphrase, `correct horse`)
+	var ts testStruct
+	if err := LoadConfigBytes(&ts, []byte("[global]\nfoo=${keystore:other}\nfoo-bar-baz=\"x${keystore:ingest-secret}\"\n")); err != nil {
+		t.Fatal(err)
+	} else if ts.Global.Foo != "multi\nline" || ts.Global.Foo_Bar_Baz != `xIngestSecrets` {
+		t.Fatalf("bad keystore values %q %q", ts.Global.Foo, ts.Global.Foo_Bar_Baz)
+	} else if err = LoadConfigBytes(&ts, []byte("[global]\nfoo=${keystore:nope}\n")); err == nil {
+		t.Fatal("failed to catch missing keystore entry")
+	}
+}
@@ -0,0 +1,118 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	TagPriorityNormal = `normal`
+	TagPriorityHigh   = `high`
+)
+
+var (
+	ErrInvalidTagRateLimit = errors.New("Invalid Tag-Rate-Limit, expected <rate>:<tag>[,<tag>...]")
+	ErrInvalidTagPriority  = errors.New("Invalid Tag-Priority, expected <high|normal>:<tag>[,<tag>...]")
+)
+
+// TagPolicy controls how entries for a single tag are admitted and scheduled by the muxer.
+// RateLimit is in bits per second and applies to each tag on its own, zero means unlimited.
+// Entries for high priority tags are sent ahead of other entries when the connection is backed up.
+type TagPolicy struct {
+	Tag       string
+	RateLimit int64
+	Priority  string
+}
+
+// High returns true if the tag is in the high priority class
+func (tp TagPolicy) High() bool {
+	return tp.Priority == TagPriorityHigh
+}
+
+// TagPolicies resolves the Tag-Rate-Limit and Tag-Priority parameters into a list of
+// per tag policies sorted by tag name.
+func (ic *IngestConfig) TagPolicies() (tps []TagPolicy, err error) {
+	if len(ic.Tag_Rate_Limit) == 0 && len(ic.Tag_Priority) == 0 {
+		return
+	}
+	pols := map[string]*TagPolicy{}
+	get := func(tag string) *TagPolicy {
+		if p, ok := pols[tag]; ok {
+			return p
+		}
+		p := &TagPolicy{Tag: tag, Priority: TagPriorityNormal}
+		pols[tag] = p
+		return p
+	}
+	for _, v := range ic.Tag_Rate_Limit {
+		rs, tags, ok := splitGroupList(v)
+		if !ok {
+			return nil, ErrInvalidTagRateLimit
+		}
+		var bps int64
+		if bps, err = ParseRate(rs); err != nil {
+			return nil, fmt.Errorf("Tag-Rate-Limit %q has an invalid rate %w", v, err)
+		} else if bps < minTagThrottle {
+			return nil, fmt.Errorf("Tag-Rate-Limit %q cannot be below 1kbit", v)
+		}
+		for _, tag := range tags {
+			p := get(tag)
+			if p.RateLimit != 0 && p.RateLimit != bps {
+				return nil, fmt.Errorf("tag %q is assigned more than one Tag-Rate-Limit", tag)
+			}
+			p.RateLimit = bps
+		}
+	}
+	for _, v := range ic.Tag_Priority {
+		class, tags, ok := splitGroupList(v)
+		if !ok {
+			return nil, ErrInvalidTagPriority
+		}
+		switch class = strings.ToLower(class); class {
+		case TagPriorityHigh, TagPriorityNormal:
+		default:
+			return nil, fmt.Errorf("Tag-Priority %q has unknown class %q", v, class)
+		}
+		for _, tag := range tags {
+			p := get(tag)
+			if p.Priority != TagPriorityNormal && p.Priority != class {
+				return nil, fmt.Errorf("tag %q is assigned more than one Tag-Priority", tag)
+			}
+			p.Priority = class
+		}
+	}
+	for _, p := range pols {
+		tps = append(tps, *p)
+	}
+	sort.Slice(tps, func(i, j int) bool { return tps[i].Tag < tps[j].Tag })
+	return
+}
+
+// verifyTagPriority rejects high priority tags that the muxer cannot schedule ahead of other entries,
+// replicas and target groups have feeders of their own without a priority lane
+func verifyTagPriority(replicate bool, tps []TagPolicy, groups []TargetGroup) error {
+	for _, p := range tps {
+		if !p.High() {
+			continue
+		} else if replicate {
+			return fmt.Errorf("Tag-Priority high for tag %q cannot be used with Replicate", p.Tag)
+		}
+		for _, g := range groups {
+			if slices.Contains(g.Tags, p.Tag) {
+				return fmt.Errorf("Tag-Priority high for tag %q cannot be used, the tag is restricted to Target-Group %q", p.Tag, g.Name)
+			}
+		}
+	}
+	return nil
+}
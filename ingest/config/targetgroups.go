@@ -0,0 +1,147 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidTargetGroup = errors.New("Invalid Target-Group, expected <group>:<target>[,<target>...]")
+	ErrInvalidGroupTags   = errors.New("Invalid Target-Group-Tags, expected <group>:<tag>[,<tag>...]")
+)
+
+// TargetGroup is a named subset of indexer targets.  Entries with a tag
+// assigned to the group are only sent to targets in the group, all other
+// entries go to every target.
+type TargetGroup struct {
+	Name    string
+	Targets []string // fully qualified targets, e.g. tls://10.0.0.1:4024
+	Tags    []string
+}
+
+// TargetGroups resolves the Target-Group and Target-Group-Tags parameters into
+// a list of groups.  Group targets must also be listed as a backend target, they
+// are returned in the same form as Targets() so they can be matched up directly.
+func (ic *IngestConfig) TargetGroups() (tgs []TargetGroup, err error) {
+	if len(ic.Target_Group) == 0 && len(ic.Target_Group_Tags) == 0 {
+		return
+	}
+	groups := map[string]int{}
+	for _, v := range ic.Target_Group {
+		name, vals, ok := splitGroupList(v)
+		if !ok {
+			return nil, ErrInvalidTargetGroup
+		}
+		idx, ok := groups[name]
+		if !ok {
+			idx = len(tgs)
+			groups[name] = idx
+			tgs = append(tgs, TargetGroup{Name: name})
+		}
+		for _, t := range vals {
+			var resolved []string
+			if resolved = ic.resolveTarget(t); len(resolved) == 0 {
+				return nil, fmt.Errorf("Target-Group %q target %q is not a backend target", name, t)
+			}
+			tgs[idx].Targets = append(tgs[idx].Targets, resolved...)
+		}
+	}
+	for _, v := range ic.Target_Group_Tags {
+		name, vals, ok := splitGroupList(v)
+		if !ok {
+			return nil, ErrInvalidGroupTags
+		}
+		idx, ok := groups[name]
+		if !ok {
+			return nil, fmt.Errorf("Target-Group-Tags references unknown group %q", name)
+		}
+		tgs[idx].Tags = append(tgs[idx].Tags, vals...)
+	}
+	err = verifyTargetGroups(tgs)
+	return
+}
+
+func (ic *IngestConfig) resolveTarget(t string) (r []string) {
+	for _, v := range ic.Cleartext_Backend_Target {
+		if AppendDefaultPort(v, DefaultCleartextPort) == AppendDefaultPort(t, DefaultCleartextPort) {
+			r = append(r, "tcp://"+AppendDefaultPort(v, DefaultCleartextPort))
+		}
+	}
+	for _, v := range ic.Encrypted_Backend_Target {
+		if AppendDefaultPort(v, DefaultTLSPort) == AppendDefaultPort(t, DefaultTLSPort) {
+			r = append(r, "tls://"+AppendDefaultPort(v, DefaultTLSPort))
+		}
+	}
+	for _, v := range ic.Pipe_Backend_Target {
+		if v == t {
+			r = append(r, "pipe://"+v)
+		}
+	}
+	return
+}
+
+// splitGroupList splits "name:a,b,c" into its name and trimmed values
+func splitGroupList(v string) (name string, vals []string, ok bool) {
+	if name, v, ok = strings.Cut(v, `:`); !ok {
+		return
+	}
+	if name = strings.TrimSpace(name); name == `` {
+		ok = false
+		return
+	}
+	for _, s := range strings.Split(v, `,`) {
+		if s = strings.TrimSpace(s); s != `` {
+			vals = append(vals, s)
+		}
+	}
+	ok = len(vals) > 0
+	return
+}
+
+func verifyTargetGroups(tgs []TargetGroup) error {
+	targets := map[string]string{}
+	tags := map[string]string{}
+	for _, tg := range tgs {
+		if err := checkTargetGroupName(tg.Name); err != nil {
+			return err
+		} else if len(tg.Targets) == 0 {
+			return fmt.Errorf("Target-Group %q has no targets", tg.Name)
+		}
+		for _, t := range tg.Targets {
+			if g, ok := targets[t]; ok && g != tg.Name {
+				return fmt.Errorf("target %q is a member of both Target-Group %q and %q", t, g, tg.Name)
+			}
+			targets[t] = tg.Name
+		}
+		for _, t := range tg.Tags {
+			if g, ok := tags[t]; ok && g != tg.Name {
+				return fmt.Errorf("tag %q is assigned to both Target-Group %q and %q", t, g, tg.Name)
+			}
+			tags[t] = tg.Name
+		}
+	}
+	return nil
+}
+
+// target group names end up in cache paths, so keep them boring
+func checkTargetGroupName(name string) error {
+	if name == `` {
+		return errors.New("empty Target-Group name")
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			continue
+		}
+		return fmt.Errorf("Target-Group name %q contains invalid character %q", name, r)
+	}
+	return nil
+}
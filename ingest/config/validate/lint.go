@@ -0,0 +1,363 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gravwell/gcfg"
+	"github.com/gravwell/gcfg/scanner"
+	"github.com/gravwell/gcfg/token"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+const (
+	confExt         = `.conf`
+	referenceStart  = `${`
+	preprocessorKey = `Preprocessor`
+)
+
+var (
+	utf8Bom = []byte("\ufeff")
+	gcfgLoc = regexp.MustCompile(`^\d+:\d+: `)
+)
+
+// Finding is a single problem found while linting a configuration file
+type Finding struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Msg)
+}
+
+// Lint checks the configuration file at pth, and any .conf overlays in confdPath, against the layout
+// of the configuration structure cfg without loading it.  Unknown sections and keys, values that
+// cannot be stored in their variable, preprocessor blocks with unknown keys, and preprocessors that
+// no section references are reported with their line numbers.  Values containing ${ references are
+// not type checked as they are resolved on the target system.
+func Lint(cfg interface{}, pth, confdPath string) ([]Finding, error) {
+	t, err := configType(cfg)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{
+		root:    t,
+		fields:  gcfgFields(t),
+		pps:     map[string]*ppBlock{},
+		refs:    map[string]bool{},
+		fileIdx: map[string]int{},
+	}
+	if err = l.lintFile(pth); err != nil {
+		return nil, err
+	}
+	if confdPath != `` {
+		var dents []os.DirEntry
+		if dents, err = os.ReadDir(confdPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, dent := range dents {
+			if !dent.Type().IsRegular() || filepath.Ext(dent.Name()) != confExt {
+				continue
+			}
+			if err = l.lintFile(filepath.Join(confdPath, dent.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	l.lintPreprocessors()
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if fi, fj := l.fileIdx[l.findings[i].File], l.fileIdx[l.findings[j].File]; fi != fj {
+			return fi < fj
+		}
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return l.findings, nil
+}
+
+type linter struct {
+	root     reflect.Type
+	fields   []field
+	findings []Finding
+	pps      map[string]*ppBlock
+	ppOrder  []string
+	refs     map[string]bool
+	fileIdx  map[string]int
+}
+
+type lintSection struct {
+	header string
+	name   string
+	sub    string
+	typ    reflect.Type //nil when the section is unknown or takes any variable
+	pp     *ppBlock
+}
+
+type lintVar struct {
+	file  string
+	line  int
+	name  string
+	lit   string
+	blank bool
+}
+
+type ppBlock struct {
+	file string
+	line int
+	vars []lintVar
+}
+
+func (l *linter) add(file string, line int, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) lintFile(pth string) (err error) {
+	var b []byte
+	if b, err = os.ReadFile(pth); err != nil {
+		return
+	}
+	l.fileIdx[pth] = len(l.fileIdx)
+	b = bytes.TrimPrefix(b, utf8Bom)
+	fset := token.NewFileSet()
+	file := fset.AddFile(pth, fset.Base(), len(b))
+	var s scanner.Scanner
+	var serr bool
+	s.Init(file, b, func(p token.Position, m string) {
+		if !serr {
+			l.add(pth, p.Line, "%s", m)
+		}
+		serr = true
+	}, 0)
+	var sect *lintSection
+	pos, tok, lit := s.Scan()
+	// syntax errors stop linting the file, the remainder cannot be trusted
+	syntax := func(msg string) {
+		if !serr {
+			l.add(pth, fset.Position(pos).Line, "%s", msg)
+		}
+		serr = true
+	}
+	for !serr {
+		switch tok {
+		case token.EOF:
+			return
+		case token.EOL, token.COMMENT:
+			pos, tok, lit = s.Scan()
+		case token.LBRACK:
+			line := fset.Position(pos).Line
+			if pos, tok, lit = s.Scan(); tok != token.IDENT {
+				syntax("expected section name")
+				continue
+			}
+			name, sub := lit, ``
+			if pos, tok, lit = s.Scan(); tok == token.STRING {
+				sub = unquote(lit)
+				pos, tok, lit = s.Scan()
+			}
+			if tok != token.RBRACK {
+				syntax("expected right bracket")
+				continue
+			}
+			pos, tok, lit = s.Scan()
+			sect = l.section(pth, line, name, sub)
+		case token.IDENT:
+			v := lintVar{file: pth, line: fset.Position(pos).Line, name: lit}
+			pos, tok, lit = s.Scan()
+			if v.blank = tok == token.EOF || tok == token.EOL || tok == token.COMMENT; !v.blank {
+				if tok != token.ASSIGN {
+					syntax("expected '='")
+					continue
+				} else if pos, tok, lit = s.Scan(); tok != token.STRING {
+					syntax("expected value")
+					continue
+				}
+				v.lit = lit
+				pos, tok, lit = s.Scan()
+			}
+			if sect == nil {
+				l.add(pth, v.line, "variable %q is not in a section", v.name)
+			} else {
+				l.variable(sect, v)
+			}
+		default:
+			syntax("expected section header or variable declaration")
+		}
+	}
+	return
+}
+
+func (l *linter) section(file string, line int, name, sub string) (sect *lintSection) {
+	sect = &lintSection{header: name, name: name, sub: sub}
+	if sub != `` {
+		sect.header = fmt.Sprintf("%s %q", name, sub)
+	}
+	f, ok := findField(l.fields, name)
+	if !ok {
+		l.add(file, line, "unknown section [%s]", sect.header)
+		return
+	}
+	if f.typ == procConfigType {
+		if sub == `` {
+			l.add(file, line, "section [%s] requires a name", name)
+			return
+		}
+		if sect.pp = l.pps[sub]; sect.pp == nil {
+			sect.pp = &ppBlock{file: file, line: line}
+			l.pps[sub] = sect.pp
+			l.ppOrder = append(l.ppOrder, sub)
+		}
+		return
+	}
+	st, named, ok := sectionType(f.typ)
+	if !ok {
+		l.add(file, line, "unknown section [%s]", sect.header)
+	} else if named && sub == `` {
+		l.add(file, line, "section [%s] requires a name", name)
+	} else if !named && sub != `` {
+		l.add(file, line, "section [%s] does not take a name", name)
+	} else if !freeForm(st) {
+		sect.typ = st
+	}
+	return
+}
+
+func (l *linter) variable(sect *lintSection, v lintVar) {
+	if sect.pp != nil {
+		sect.pp.vars = append(sect.pp.vars, v)
+		return
+	} else if sect.typ == nil {
+		return
+	}
+	f, ok := findField(gcfgFields(sect.typ), v.name)
+	if !ok {
+		l.add(v.file, v.line, "unknown key %q in section [%s]", v.name, sect.header)
+		return
+	}
+	if f.goName == preprocessorKey && !v.blank {
+		l.refs[unquote(v.lit)] = true
+	}
+	if strings.Contains(v.lit, referenceStart) {
+		return
+	}
+	//parse the lone variable into a fresh configuration so gcfg performs its usual type handling
+	doc := fmt.Sprintf("[%s", sect.name)
+	if sect.sub != `` {
+		doc += ` ` + quote(sect.sub)
+	}
+	doc += "]\n" + v.name
+	if !v.blank {
+		doc += ` = ` + v.lit
+	}
+	if err := gcfg.ReadStringInto(reflect.New(l.root).Interface(), doc+"\n"); err != nil {
+		l.add(v.file, v.line, "invalid value for %q in section [%s]: %s", v.name, sect.header, locTrim(err))
+	}
+}
+
+func (l *linter) lintPreprocessors() {
+	for _, name := range l.ppOrder {
+		pp := l.pps[name]
+		if !l.refs[name] {
+			l.add(pp.file, pp.line, "preprocessor %q is not referenced by any section", name)
+		}
+		var typ *lintVar
+		for i := range pp.vars {
+			if strings.EqualFold(pp.vars[i].name, typeKey) {
+				typ = &pp.vars[i]
+			}
+		}
+		if typ == nil {
+			l.add(pp.file, pp.line, "preprocessor %q is missing a %s", name, typeKey)
+			continue
+		}
+		id := strings.TrimSpace(strings.ToLower(unquote(typ.lit)))
+		t, err := processors.ProcessorConfigType(id)
+		if err != nil {
+			l.add(typ.file, typ.line, "preprocessor %q has unknown %s %q", name, typeKey, unquote(typ.lit))
+			continue
+		}
+		free := freeForm(t)
+		fields := gcfgFields(t)
+		for _, v := range pp.vars {
+			if strings.EqualFold(v.name, typeKey) || free {
+				continue
+			}
+			if _, ok := findField(fields, v.name); !ok {
+				l.add(v.file, v.line, "unknown key %q in preprocessor %q", v.name, name)
+				continue
+			} else if strings.Contains(v.lit, referenceStart) {
+				continue
+			}
+			doc := fmt.Sprintf("[preprocessor %s]\n%s = %s\n%s", quote(name), typeKey, id, v.name)
+			if !v.blank {
+				doc += ` = ` + v.lit
+			}
+			var c struct {
+				Preprocessor processors.ProcessorConfig
+			}
+			if err = gcfg.ReadStringInto(&c, doc+"\n"); err == nil {
+				err = c.Preprocessor[name].MapTo(reflect.New(t).Interface())
+			}
+			if err != nil {
+				l.add(v.file, v.line, "invalid value for %q in preprocessor %q: %s", v.name, name, locTrim(err))
+			}
+		}
+	}
+}
+
+// locTrim removes the position gcfg adds to errors, which refers to the generated document
+func locTrim(err error) string {
+	s := err.Error()
+	if m := gcfgLoc.FindStringIndex(s); m != nil {
+		s = s[m[1]:]
+	}
+	if i := strings.Index(s, ` at section `); i > 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// unquote implements gcfg value unquoting, invalid sequences are kept as is
+func unquote(s string) string {
+	var b strings.Builder
+	var esc bool
+	for _, c := range s {
+		if esc {
+			switch c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			}
+			b.WriteRune(c)
+			esc = false
+			continue
+		}
+		switch c {
+		case '"':
+		case '\\':
+			esc = true
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
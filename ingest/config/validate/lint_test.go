@@ -0,0 +1,169 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package validate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+type testListener struct {
+	Bind_String   string
+	Tag_Name      string
+	Keep_Priority bool
+	Preprocessor  []string
+}
+
+type testReadType struct {
+	Global struct {
+		config.IngestConfig
+		Worker_Count int
+	}
+	Attach       attach.AttachConfig
+	Listener     map[string]*testListener
+	Preprocessor processors.ProcessorConfig
+}
+
+const testConfig = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-Target=172.17.0.2:4023 #comment
+Worker-Count = lots
+Max-Ingest-Cache=${env:CACHE}
+Log-Levle=INFO
+
+[Attach]
+anything = goes
+
+[Listener "default"]
+	Bind-String="0.0.0.0:7777"
+	Tag-Name=default
+	Preprocessor=extract
+	Keep-Priority=maybe
+
+[Listner "typo"]
+	Bind-String=1
+
+[Global "named"]
+
+[Preprocessor "extract"]
+	Type = regexextract
+	Regex = "(?P<foo>.+)"
+	Templat = "${foo}"
+
+[Preprocessor "unused"]
+	Type = sample
+	Rate = abc
+
+[Preprocessor "plug"]
+	Type = plugin
+	Anything = x
+`
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, `test.conf`)
+	confd := filepath.Join(dir, `conf.d`)
+	if err := os.WriteFile(pth, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.Mkdir(confd, 0700); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(confd, `a.conf`), []byte("[Preprocessor \"extra\"]\nType=nope\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Lint(testReadType{}, pth, confd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{File: pth, Line: 5, Msg: `invalid value for "Worker-Count"`},
+		{File: pth, Line: 7, Msg: `unknown key "Log-Levle"`},
+		{File: pth, Line: 16, Msg: `invalid value for "Keep-Priority"`},
+		{File: pth, Line: 18, Msg: `unknown section [Listner "typo"]`},
+		{File: pth, Line: 21, Msg: `section [Global] does not take a name`},
+		{File: pth, Line: 26, Msg: `unknown key "Templat" in preprocessor "extract"`},
+		{File: pth, Line: 28, Msg: `preprocessor "unused" is not referenced`},
+		{File: pth, Line: 30, Msg: `invalid value for "Rate" in preprocessor "unused"`},
+		{File: pth, Line: 32, Msg: `preprocessor "plug" is not referenced`},
+		{File: filepath.Join(confd, `a.conf`), Line: 1, Msg: `preprocessor "extra" is not referenced`},
+		{File: filepath.Join(confd, `a.conf`), Line: 2, Msg: `unknown Type "nope"`},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("got %d findings, expected %d: %v", len(findings), len(want), findings)
+	}
+	for i, w := range want {
+		f := findings[i]
+		if f.File != w.File || f.Line != w.Line || !strings.Contains(f.Msg, w.Msg) {
+			t.Fatalf("bad finding %d: %v != %v", i, f, w)
+		}
+	}
+
+	//syntax errors stop linting the file
+	if err = os.WriteFile(pth, []byte("[Global]\nLog_Level=INFO\nLog-Levle=INFO\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if findings, err = Lint(&testReadType{}, pth, ``); err != nil {
+		t.Fatal(err)
+	} else if len(findings) != 1 || findings[0].Line != 2 {
+		t.Fatalf("bad syntax findings %v", findings)
+	}
+	if _, err = Lint(nil, pth, ``); err != ErrNilConfig {
+		t.Fatalf("bad nil config error %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	b, err := Schema(&testReadType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Properties map[string]struct {
+			Type                 string
+			Properties           map[string]map[string]interface{}
+			AdditionalProperties interface{}
+		}
+		Defs map[string]struct {
+			Properties map[string]map[string]interface{}
+		} `json:"$defs"`
+	}
+	if err = json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	} else if len(s.Properties) != 4 {
+		t.Fatalf("bad sections %v", s.Properties)
+	}
+	gbl := s.Properties[`Global`].Properties
+	if gbl[`Worker-Count`][`type`] != `integer` || gbl[`Ingest-Secret`][`type`] != `string` {
+		t.Fatalf("bad global properties %v", gbl)
+	} else if gbl[`Cleartext-Backend-Target`][`type`] != `array` {
+		t.Fatalf("bad multi-value property %v", gbl[`Cleartext-Backend-Target`])
+	}
+	if lst, ok := s.Properties[`Listener`].AdditionalProperties.(map[string]interface{}); !ok {
+		t.Fatalf("bad listener schema %v", s.Properties[`Listener`])
+	} else if props, ok := lst[`properties`].(map[string]interface{}); !ok || props[`Keep-Priority`] == nil {
+		t.Fatalf("bad listener properties %v", lst)
+	}
+	for _, id := range processors.ProcessorTypes() {
+		d, ok := s.Defs[`preprocessor-`+id]
+		if !ok {
+			t.Fatalf("missing preprocessor %s", id)
+		} else if d.Properties[typeKey][`const`] != id {
+			t.Fatalf("bad preprocessor %s type %v", id, d.Properties[typeKey])
+		}
+	}
+	if d := s.Defs[`preprocessor-regexextract`]; d.Properties[`Drop-Misses`][`type`] != `boolean` {
+		t.Fatalf("bad regexextract properties %v", d.Properties)
+	}
+}
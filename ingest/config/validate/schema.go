@@ -0,0 +1,289 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package validate
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/gravwell/gcfg"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+const (
+	schemaDraft     = `https://json-schema.org/draft/2020-12/schema`
+	preprocessorDef = `preprocessor`
+	typeKey         = `Type`
+)
+
+var (
+	ErrNilConfig     = errors.New("config is nil")
+	ErrInvalidConfig = errors.New("config is not a structure")
+
+	procConfigType      = reflect.TypeOf(processors.ProcessorConfig{})
+	varConfigType       = reflect.TypeOf(&config.VariableConfig{})
+	idxerType           = reflect.TypeOf(gcfg.Idxer{})
+	casedIdxerType      = reflect.TypeOf(gcfg.CasedIdxer{})
+	bigIntType          = reflect.TypeOf(big.Int{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type schema map[string]interface{}
+
+// Schema generates a JSON Schema describing the file layout of the configuration structure cfg,
+// typically an ingester's cfgReadType.  Sections are objects, named subsections are objects keyed
+// by the subsection name, and each preprocessor type reachable through processors.ProcessorLoadConfig
+// is described under $defs.  Configuration files treat section and key names as case insensitive,
+// the schema uses the dashed form found in the example configurations.
+func Schema(cfg interface{}) ([]byte, error) {
+	t, err := configType(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defs := schema{}
+	root := sectionsSchema(t, defs)
+	root[`$schema`] = schemaDraft
+	if len(defs) > 0 {
+		root[`$defs`] = defs
+	}
+	return json.MarshalIndent(root, "", "\t")
+}
+
+func configType(cfg interface{}) (t reflect.Type, err error) {
+	if cfg == nil {
+		return nil, ErrNilConfig
+	}
+	if t = reflect.TypeOf(cfg); t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		err = fmt.Errorf("%w: %T", ErrInvalidConfig, cfg)
+	}
+	return
+}
+
+func sectionsSchema(t reflect.Type, defs schema) schema {
+	props := schema{}
+	for _, f := range gcfgFields(t) {
+		if f.typ == procConfigType {
+			defs[preprocessorDef] = preprocessorsSchema(defs)
+			props[f.name()] = schema{
+				`type`:                 `object`,
+				`additionalProperties`: schema{`$ref`: `#/$defs/` + preprocessorDef},
+			}
+		} else if st, named, ok := sectionType(f.typ); !ok {
+			continue
+		} else if named {
+			props[f.name()] = schema{
+				`type`:                 `object`,
+				`additionalProperties`: variablesSchema(st),
+			}
+		} else {
+			props[f.name()] = variablesSchema(st)
+		}
+	}
+	return schema{
+		`type`:                 `object`,
+		`properties`:           props,
+		`additionalProperties`: false,
+	}
+}
+
+// sectionType returns the structure that holds the variables of a section and whether
+// the section is a map of named subsections
+func sectionType(t reflect.Type) (st reflect.Type, named, ok bool) {
+	switch t.Kind() {
+	case reflect.Struct:
+		st, ok = t, true
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Ptr && t.Elem().Elem().Kind() == reflect.Struct {
+			st, named, ok = t.Elem().Elem(), true, true
+		}
+	}
+	return
+}
+
+func variablesSchema(t reflect.Type) schema {
+	if freeForm(t) {
+		return freeFormSchema()
+	}
+	props := schema{}
+	for _, f := range gcfgFields(t) {
+		if vs := valueSchema(f.typ); vs != nil {
+			props[f.name()] = vs
+		}
+	}
+	return schema{
+		`type`:                 `object`,
+		`properties`:           props,
+		`additionalProperties`: false,
+	}
+}
+
+func freeFormSchema() schema {
+	return schema{
+		`type`: `object`,
+		`additionalProperties`: schema{
+			`type`:  []string{`string`, `array`},
+			`items`: schema{`type`: `string`},
+		},
+	}
+}
+
+// valueSchema describes a variable the way gcfg sets it, unnamed slices are multi-valued
+func valueSchema(t reflect.Type) schema {
+	if t.Kind() == reflect.Ptr && t.Name() == `` && t.Elem().Kind() == reflect.Slice && t.Elem().Name() == `` {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Name() == `` {
+		if is := scalarSchema(t.Elem()); is != nil {
+			return schema{`type`: `array`, `items`: is}
+		}
+		return nil
+	}
+	return scalarSchema(t)
+}
+
+func scalarSchema(t reflect.Type) schema {
+	if t.Kind() == reflect.Ptr && t.Name() == `` {
+		t = t.Elem()
+	}
+	if t == bigIntType {
+		return schema{`type`: `integer`}
+	} else if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return schema{`type`: `string`}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return schema{`type`: `string`}
+	case reflect.Bool:
+		return schema{`type`: `boolean`}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return schema{`type`: `integer`}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return schema{`type`: `integer`, `minimum`: 0}
+	case reflect.Float32, reflect.Float64:
+		return schema{`type`: `number`}
+	}
+	return nil
+}
+
+func preprocessorsSchema(defs schema) schema {
+	var refs []schema
+	for _, id := range processors.ProcessorTypes() {
+		t, err := processors.ProcessorConfigType(id)
+		if err != nil {
+			continue
+		}
+		name := preprocessorDef + `-` + id
+		defs[name] = processorSchema(id, t)
+		refs = append(refs, schema{`$ref`: `#/$defs/` + name})
+	}
+	return schema{`oneOf`: refs}
+}
+
+func processorSchema(id string, t reflect.Type) schema {
+	if freeForm(t) {
+		s := freeFormSchema()
+		s[`properties`] = schema{typeKey: schema{`const`: id}}
+		s[`required`] = []string{typeKey}
+		return s
+	}
+	props := schema{typeKey: schema{`const`: id}}
+	for _, f := range gcfgFields(t) {
+		if vs := processorValueSchema(f.typ); vs != nil {
+			props[f.procName()] = vs
+		}
+	}
+	return schema{
+		`type`:                 `object`,
+		`properties`:           props,
+		`required`:             []string{typeKey},
+		`additionalProperties`: false,
+	}
+}
+
+// processorValueSchema describes a variable the way config.VariableConfig.MapTo sets it
+func processorValueSchema(t reflect.Type) schema {
+	switch t.Kind() {
+	case reflect.Slice:
+		return schema{`type`: `array`, `items`: schema{`type`: `string`}}
+	case reflect.Struct, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan, reflect.Array:
+		return nil
+	}
+	return scalarSchema(t)
+}
+
+type field struct {
+	goName string
+	ident  string //gcfg tag override
+	typ    reflect.Type
+}
+
+// gcfgFields returns the settable variables of a structure, fields of embedded structures
+// are promoted the same way gcfg and config.VariableConfig.MapTo find them
+func gcfgFields(t reflect.Type) (r []field) {
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		ident, _, _ := strings.Cut(f.Tag.Get(`gcfg`), `,`)
+		r = append(r, field{goName: f.Name, ident: ident, typ: f.Type})
+	}
+	return
+}
+
+// name is the name used in configuration files
+func (f field) name() string {
+	if f.ident != `` {
+		return f.ident
+	}
+	return f.procName()
+}
+
+// procName is the name used in preprocessor blocks, which only accept dashes
+func (f field) procName() string {
+	return strings.ReplaceAll(f.goName, `_`, `-`)
+}
+
+// matches implements the gcfg name folding, case is ignored and dashes are underscores.
+// Preprocessor blocks only accept dashes but underscores are never valid in a name.
+func (f field) matches(n string) bool {
+	if f.ident != `` {
+		return strings.EqualFold(f.ident, n)
+	}
+	return strings.EqualFold(strings.ReplaceAll(n, `-`, `_`), f.goName)
+}
+
+func findField(fields []field, n string) (f field, ok bool) {
+	for _, f = range fields {
+		if f.matches(n) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// freeForm reports whether a structure accepts arbitrary variables, such as attach
+// and plugin configurations
+func freeForm(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		switch t.Field(i).Type {
+		case idxerType, casedIdxerType, varConfigType:
+			return true
+		}
+	}
+	return false
+}
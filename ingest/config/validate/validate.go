@@ -25,6 +25,8 @@ const (
 
 var (
 	vflag = flag.Bool("validate", false, "Load configuration file and exit")
+	lflag = flag.Bool("lint", false, "Check configuration files for unknown keys, bad values, and unused preprocessors without loading them and exit")
+	sflag = flag.Bool("schema", false, "Print a JSON Schema describing the configuration file and exit")
 )
 
 // ValidateConfig will take a configuration handling function and two paths.
@@ -44,7 +46,7 @@ func ValidateIngesterConfig(fnc interface{}, pth, confdPath string) {
 }
 
 func validateConfig(fnc interface{}, pth, confdPath string, assertIngester bool) {
-	if !*vflag {
+	if !*vflag && !*lflag && !*sflag {
 		return
 	}
 	//check the parameters
@@ -66,6 +68,12 @@ func validateConfig(fnc interface{}, pth, confdPath string, assertIngester bool)
 		os.Exit(exitCode)
 	}
 
+	if *sflag {
+		printSchema(fnType)
+	} else if *lflag {
+		lintConfig(fnType, pth, confdPath)
+	}
+
 	args := []reflect.Value{reflect.ValueOf(pth)}
 	if argc := fnType.NumIn(); argc < 1 || argc > 2 {
 		fmt.Printf("Given configuration function expects %d parameters instead of 1 or 2\n", argc)
@@ -109,6 +117,65 @@ func validateConfig(fnc interface{}, pth, confdPath string, assertIngester bool)
 	os.Exit(0) //all good
 }
 
+// readTyper is implemented by configuration objects whose file layout is described by another
+// structure, such as the cfgReadType most ingesters read into before building their configuration
+type readTyper interface {
+	ConfigReadType() interface{}
+}
+
+// configReadType returns a zero value of the structure that describes the file layout
+// read by a configuration function
+func configReadType(fnType reflect.Type) (interface{}, error) {
+	t := fnType.Out(0)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, t)
+	}
+	v := reflect.New(t).Interface()
+	if rt, ok := v.(readTyper); ok {
+		return rt.ConfigReadType(), nil
+	}
+	return v, nil
+}
+
+func printSchema(fnType reflect.Type) {
+	cfg, err := configReadType(fnType)
+	if err != nil {
+		fmt.Printf("Failed to get configuration layout: %v\n", err)
+		os.Exit(exitCode)
+	}
+	b, err := Schema(cfg)
+	if err != nil {
+		fmt.Printf("Failed to generate schema: %v\n", err)
+		os.Exit(exitCode)
+	}
+	fmt.Println(string(b))
+	os.Exit(0)
+}
+
+func lintConfig(fnType reflect.Type, pth, confdPath string) {
+	cfg, err := configReadType(fnType)
+	if err != nil {
+		fmt.Printf("Failed to get configuration layout: %v\n", err)
+		os.Exit(exitCode)
+	}
+	findings, err := Lint(cfg, pth, confdPath)
+	if err != nil {
+		fmt.Printf("Failed to lint %q: %v\n", pth, err)
+		os.Exit(exitCode)
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	if len(findings) > 0 {
+		os.Exit(exitCode)
+	}
+	fmt.Println(pth, "has no lint findings")
+	os.Exit(0)
+}
+
 type validator interface {
 	Verify() error
 }
@@ -121,7 +188,7 @@ func callVerifyFunc(obj interface{}) (err error) {
 	var ok bool
 	var vv validator
 	if obj == nil {
-		err = errors.New("config is nil")
+		err = ErrNilConfig
 	} else if vv, ok = obj.(validator); !ok {
 		err = errors.New("config object does not implement Verify interface")
 	} else {
@@ -0,0 +1,161 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	envVaultAddr      string = `VAULT_ADDR`
+	envVaultToken     string = `VAULT_TOKEN`
+	envVaultNamespace string = `VAULT_NAMESPACE`
+	envVaultCACert    string = `VAULT_CACERT`
+
+	vaultTimeout     = 10 * time.Second
+	maxVaultResponse = 1024 * 1024
+	vaultKeySep      = `#`
+)
+
+var (
+	ErrVaultNotConfigured = errors.New("vault address is not set")
+	ErrVaultMissingKey    = errors.New("vault reference must be of the form path#key")
+	ErrVaultKeyNotFound   = errors.New("key not found in vault secret")
+)
+
+// VaultProvider resolves references against a HashiCorp Vault compatible KV secrets engine
+// over HTTP.  References take the form path#key, for example secret/data/gravwell#ingest-secret
+// for a KV version 2 mount or secret/gravwell#ingest-secret for a version 1 mount.
+//
+// Empty fields are populated from the VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE, and VAULT_CACERT
+// environment variables each time a reference is resolved, the token may also be read from VAULT_TOKEN_FILE.
+type VaultProvider struct {
+	Address   string
+	Token     string
+	Namespace string
+	Client    *http.Client
+}
+
+func (vp *VaultProvider) Resolve(ref string) (v string, err error) {
+	pth, key, ok := strings.Cut(ref, vaultKeySep)
+	if pth = strings.Trim(pth, `/`); !ok || pth == `` || key == `` {
+		err = ErrVaultMissingKey
+		return
+	}
+	addr, token, ns := vp.Address, vp.Token, vp.Namespace
+	if addr == `` {
+		addr = os.Getenv(envVaultAddr)
+	}
+	if token == `` {
+		token, _ = loadEnv(envVaultToken)
+	}
+	if ns == `` {
+		ns = os.Getenv(envVaultNamespace)
+	}
+	if addr == `` {
+		err = ErrVaultNotConfigured
+		return
+	}
+	var u *url.URL
+	if u, err = url.Parse(strings.TrimRight(addr, `/`) + `/v1/` + pth); err != nil {
+		return
+	}
+	clnt := vp.Client
+	if clnt == nil {
+		if clnt, err = vaultClient(); err != nil {
+			return
+		}
+	}
+
+	ctx, cf := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cf()
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil); err != nil {
+		return
+	}
+	if token != `` {
+		req.Header.Set(`X-Vault-Token`, token)
+	}
+	if ns != `` {
+		req.Header.Set(`X-Vault-Namespace`, ns)
+	}
+	var resp *http.Response
+	if resp, err = clnt.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("vault returned %s", resp.Status)
+		return
+	}
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxVaultResponse)).Decode(&body); err != nil {
+		err = fmt.Errorf("invalid vault response %w", err)
+		return
+	}
+	return vaultValue(body.Data, key)
+}
+
+// vaultValue extracts a key from a KV secret, version 2 engines nest the values under data
+func vaultValue(data map[string]json.RawMessage, key string) (v string, err error) {
+	if raw, ok := data[`data`]; ok {
+		if _, ok = data[`metadata`]; ok {
+			var inner map[string]json.RawMessage
+			if err = json.Unmarshal(raw, &inner); err != nil {
+				return
+			}
+			data = inner
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		err = ErrVaultKeyNotFound
+		return
+	}
+	//secrets are almost always strings, but hand back the raw JSON for anything else
+	if err = json.Unmarshal(raw, &v); err != nil {
+		v, err = string(raw), nil
+	}
+	return
+}
+
+func vaultClient() (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if pth := os.Getenv(envVaultCACert); pth != `` {
+		b, err := os.ReadFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s %w", envVaultCACert, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s %q", envVaultCACert, pth)
+		}
+		tr.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+	return &http.Client{
+		Transport: tr,
+		Timeout:   vaultTimeout,
+	}, nil
+}
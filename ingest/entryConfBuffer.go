@@ -10,6 +10,7 @@ package ingest
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 )
@@ -32,6 +33,7 @@ var (
 type entryConfirmation struct {
 	EntryID entrySendID
 	Ent     *entry.Entry
+	Sent    time.Time // only populated when ack latency is being tracked
 }
 
 // This structure and its methods is NOT thread safe, the caller
@@ -132,19 +134,24 @@ func (ecb *entryConfBuffer) Free() int {
 
 // A confirmation removes the ID from our queue
 func (ecb *entryConfBuffer) Confirm(id entrySendID) error {
+	_, err := ecb.confirm(id)
+	return err
+}
+
+// confirm removes the ID from our queue and hands back the confirmation
+func (ecb *entryConfBuffer) confirm(id entrySendID) (*entryConfirmation, error) {
 	if ecb.count <= 0 {
-		return errEmptyConfBuff
+		return nil, errEmptyConfBuff
 	}
 	//check the head first as that is what SHOULD be hitting
 	ec := ecb.buff[ecb.head]
 	if ec == nil {
-		return errCorruptConfBuff
+		return nil, errCorruptConfBuff
 	}
 	if ec.EntryID != id {
 		return ecb.popUnalligned(id)
 	}
-	_, err := ecb.popHead()
-	return err
+	return ecb.popHead()
 }
 
 // typically used when we need to resend something
@@ -165,12 +172,11 @@ func (ecb *entryConfBuffer) GetEntry(id entrySendID) (*entry.Entry, error) {
 	return nil, errEntryNotFound
 }
 
-func (ecb *entryConfBuffer) popHead() (*entry.Entry, error) {
-	var ent *entry.Entry
-	if ecb.buff[ecb.head] == nil {
+func (ecb *entryConfBuffer) popHead() (*entryConfirmation, error) {
+	var ec *entryConfirmation
+	if ec = ecb.buff[ecb.head]; ec == nil {
 		return nil, errors.New("head is nil")
 	}
-	ent = ecb.buff[ecb.head].Ent
 	ecb.buff[ecb.head] = nil
 	//adjust index and count
 	ecb.head++
@@ -179,18 +185,17 @@ func (ecb *entryConfBuffer) popHead() (*entry.Entry, error) {
 	if ecb.head == ecb.capacity {
 		ecb.head = 0
 	}
-	return ent, nil
+	return ec, nil
 }
 
 // this can be extremely expensive, but should only be happening on
 // error conditions. Its job is to go find an ID, remove it from the
 // list and shift all items forward to fill the gap
-func (ecb *entryConfBuffer) popUnalligned(id entrySendID) error {
+func (ecb *entryConfBuffer) popUnalligned(id entrySendID) (*entryConfirmation, error) {
 	var curr, next int
 	//simple sanity check in case we are popping the head
 	if ecb.buff[ecb.head] != nil && ecb.buff[ecb.head].EntryID == id {
-		_, err := ecb.popHead()
-		return err
+		return ecb.popHead()
 	}
 	//not the head, so go do the hard work
 	for i := ecb.head; i < ecb.count; i++ {
@@ -198,11 +203,12 @@ func (ecb *entryConfBuffer) popUnalligned(id entrySendID) error {
 			i = 0
 		}
 		if ecb.buff[i] == nil {
-			return errCorruptConfBuff
+			return nil, errCorruptConfBuff
 		}
 		//found the ID, so remove it and shift forward
 		//if this hits we ARE going to return
 		if ecb.buff[i].EntryID == id {
+			ec := ecb.buff[i]
 			//remove the ID from the list
 			for ; i < ecb.count; i++ {
 				if i == ecb.capacity {
@@ -222,11 +228,11 @@ func (ecb *entryConfBuffer) popUnalligned(id entrySendID) error {
 			//just decrement count and don't need to shift head
 			ecb.count--
 
-			return nil
+			return ec, nil
 		}
 	}
 
-	return errEntryNotFound
+	return nil, errEntryNotFound
 }
 
 func (ecb *entryConfBuffer) Add(ec *entryConfirmation) error {
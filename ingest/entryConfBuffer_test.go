@@ -32,7 +32,7 @@ func TestOrderedPushPop(t *testing.T) {
 		t.Fatal(err)
 	}
 	for i := entrySendID(0); i < entrySendID(8); i++ {
-		err = entcb.Add(&entryConfirmation{i, ent})
+		err = entcb.Add(&entryConfirmation{EntryID: i, Ent: ent})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -52,7 +52,7 @@ func TestUnorderedPushPop(t *testing.T) {
 		t.Fatal(err)
 	}
 	for i := entrySendID(1); i <= entrySendID(8); i++ {
-		err = entcb.Add(&entryConfirmation{i, ent})
+		err = entcb.Add(&entryConfirmation{EntryID: i, Ent: ent})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -72,7 +72,7 @@ func TestMixedUnorderedPushPop(t *testing.T) {
 		t.Fatal(err)
 	}
 	for i := entrySendID(1); i <= entrySendID(8); i++ {
-		err = entcb.Add(&entryConfirmation{i, ent})
+		err = entcb.Add(&entryConfirmation{EntryID: i, Ent: ent})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -84,7 +84,7 @@ func TestMixedUnorderedPushPop(t *testing.T) {
 		}
 	}
 	for i := entrySendID(9); i <= entrySendID(16); i++ {
-		err = entcb.Add(&entryConfirmation{i, ent})
+		err = entcb.Add(&entryConfirmation{EntryID: i, Ent: ent})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 const (
@@ -63,8 +64,9 @@ type TagManager interface {
 }
 
 type ackCommand struct {
-	cmd IngestCommand
-	val uint64 //this can be converted to any number of things, id, time.Duration, etc...
+	cmd  IngestCommand
+	val  uint64 //this can be converted to any number of things, id, time.Duration, etc...
+	data []byte //payload for commands that carry one, see hasPayload
 }
 
 type EntryReader struct {
@@ -72,6 +74,7 @@ type EntryReader struct {
 	flshr      flusher
 	bIO        *bufio.Reader
 	bAckWriter *bufio.Writer
+	ackMtx     *sync.Mutex // guards bAckWriter between the ack routine and stream configuration
 	errCount   uint32
 	mtx        *sync.Mutex
 	wg         *sync.WaitGroup
@@ -92,6 +95,7 @@ type EntryReader struct {
 	igStateMtx        *sync.Mutex
 	igState           IngesterState           // the most recent state message received
 	stateCallbacks    []IngesterStateCallback // functions to be called when an IngesterState message is received
+	authorizer        PeerAuthorizer          // optional, decides if the peer may continue once it has identified itself
 	pendingDittoBlock []*entry.Entry
 }
 
@@ -117,6 +121,7 @@ func NewEntryReaderEx(cfg EntryReaderWriterConfig) (*EntryReader, error) {
 		conn:       cfg.Conn,
 		bIO:        bufio.NewReaderSize(cfg.Conn, cfg.BufferSize),
 		bAckWriter: bufio.NewWriterSize(cfg.Conn, ackEncodeSize*cfg.OutstandingEntryCount),
+		ackMtx:     &sync.Mutex{},
 		mtx:        &sync.Mutex{},
 		wg:         &sync.WaitGroup{},
 		ackChan:    make(chan ackCommand, cfg.OutstandingEntryCount),
@@ -223,7 +228,11 @@ func (er *EntryReader) ConfigureStream() (err error) {
 		return
 	} else if err = req.validate(); err != nil {
 		return
-	} else if err = req.Write(er.bAckWriter); err != nil {
+	}
+	//the ack routine may be running, hold it off while we respond and swap out the writer
+	er.ackMtx.Lock()
+	defer er.ackMtx.Unlock()
+	if err = req.Write(er.bAckWriter); err != nil {
 		return
 	} else if err = er.bAckWriter.Flush(); err != nil {
 		return
@@ -233,23 +242,39 @@ func (er *EntryReader) ConfigureStream() (err error) {
 
 	//we are in good shape, configure the stream
 	if req.Compression != CompressNone {
-		err = er.startCompression(req.Compression)
+		err = er.startCompression(req.Compression, req.CompressionLevel)
 	}
 	return
 }
 
 // startCompression gets the entryReader/Writer ready to work with a compressed connection
 // caller MUST HOLD THE LOCK
-func (er *EntryReader) startCompression(ct CompressionType) (err error) {
+func (er *EntryReader) startCompression(ct CompressionType, level int8) (err error) {
 	switch ct {
 	case CompressNone: //do nothing
 	case CompressSnappy:
 		//get a writer rolling
 		wtr := snappy.NewBufferedWriter(er.conn)
 		er.flshr = wtr
-		er.bAckWriter.Reset(newSnappyFlushWriter(wtr))
+		er.bAckWriter.Reset(newAutoFlushWriter(wtr))
 		//get a reader rolling
 		er.bIO.Reset(snappy.NewReader(er.conn))
+	case CompressZstd:
+		var rdr io.Reader
+		var wtr *zstd.Encoder
+		if wtr, err = newZstdWriter(er.conn, level); err != nil {
+			return
+		} else if rdr, err = newZstdReader(er.conn); err != nil {
+			return
+		}
+		er.flshr = wtr
+		er.bAckWriter.Reset(newAutoFlushWriter(wtr))
+		er.bIO.Reset(rdr)
+	case CompressLZ4:
+		wtr := newLZ4Writer(er.conn)
+		er.flshr = wtr
+		er.bAckWriter.Reset(newAutoFlushWriter(wtr))
+		er.bIO.Reset(newLZ4Reader(er.conn))
 	default:
 		err = fmt.Errorf("Unknown compression id %x", ct)
 	}
@@ -709,6 +734,7 @@ func (er *EntryReader) SetupConnection() (err error) {
 			er.ackChan <- ackCommand{cmd: CONFIRM_API_VER_MAGIC, val: uint64(0)}
 		default:
 			// any other command means the ingester is no longer interested in identifying itself, so we're done
+			err = er.authorizePeer()
 			return
 		}
 	}
@@ -754,7 +780,10 @@ func (er *EntryReader) ackRoutine() {
 			if !ok {
 				return
 			}
-			if to, err = er.fillAndSendAckBuffer(buff, v, tmr.C); err != nil {
+			er.ackMtx.Lock()
+			to, err = er.fillAndSendAckBuffer(buff, v, tmr.C)
+			er.ackMtx.Unlock()
+			if err != nil {
 				er.routineCleanFail(err)
 				return
 			}
@@ -768,11 +797,12 @@ func (er *EntryReader) ackRoutine() {
 				er.routineCleanFail(err)
 				return
 			}
-			if err = er.writeAll(keepalivebuff[:off]); err != nil {
-				er.routineCleanFail(err)
-				return
+			er.ackMtx.Lock()
+			if err = er.writeAll(keepalivebuff[:off]); err == nil {
+				err = er.bAckWriter.Flush()
 			}
-			if err = er.bAckWriter.Flush(); err != nil {
+			er.ackMtx.Unlock()
+			if err != nil {
 				er.routineCleanFail(err)
 				return
 			}
@@ -786,6 +816,10 @@ func (er *EntryReader) fillAndSendAckBuffer(b []byte, v ackCommand, toch <-chan
 	var n int
 	var flush bool
 	var ok bool
+	if v.hasPayload() {
+		err = er.writePayloadCommand(v)
+		return
+	}
 	//encode value into the buffer
 	if off, flush, err = v.encode(b); err != nil {
 		return
@@ -805,6 +839,19 @@ feedLoop:
 			if !ok {
 				break
 			}
+			//payload commands bypass the buffer, send what we have first to preserve ordering
+			if v.hasPayload() {
+				if off > 0 {
+					if err = er.writeAll(b[:off]); err != nil {
+						return
+					}
+					off = 0
+				}
+				if err = er.writePayloadCommand(v); err != nil {
+					return
+				}
+				break feedLoop
+			}
 			//check that we have room
 			if (v.size() + off) >= len(b) {
 				//ok, flush and keep rolling
@@ -1032,6 +1079,11 @@ func (ac *ackCommand) decode(rdr *bufio.Reader, blocking bool) (ok bool, err err
 		}
 		ac.val = binary.LittleEndian.Uint64(val)
 		ok = true
+	case CONFIG_OVERLAY_MAGIC:
+		if ac.data, err = readPayload(rdr, maxConfigOverlaySize); err != nil {
+			return
+		}
+		ok = true
 	default:
 		err = errUnknownCommand
 	}
@@ -1042,6 +1094,11 @@ func (ac *ackCommand) shouldFlush() bool {
 	return ac.cmd == FORCE_ACK_MAGIC
 }
 
+// hasPayload indicates that the command carries a variable sized payload and is written outside the ack buffer
+func (ac *ackCommand) hasPayload() bool {
+	return ac.cmd == CONFIG_OVERLAY_MAGIC
+}
+
 func setReadBuffer(c interface{}, n int) error {
 	switch v := c.(type) {
 	case *net.IPConn:
@@ -14,12 +14,15 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
 	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 const (
@@ -43,6 +46,8 @@ const (
 	MINIMUM_INGEST_STATE_VERSION    uint16 = 0x6 // minimum server version to send detailed ingester state messages
 	MINIMUM_INGEST_EV_VERSION       uint16 = 0x8 // minimum server version to send enumerated values attached to entries
 	MINIMUM_DITTO_VERSION           uint16 = 0x9 // minimum server version to send ditto blocks
+	MINIMUM_EXT_COMPRESSION_VERSION uint16 = 0xA // minimum server version to negotiate zstd and lz4 compression
+	MINIMUM_CONFIG_OVERLAY_VERSION  uint16 = 0xB // minimum ingester version to receive configuration overlays
 
 	maxThrottleDur time.Duration = 5 * time.Second
 
@@ -72,6 +77,7 @@ const (
 	INGESTER_STATE_MAGIC         IngestCommand = 0x44556600
 	CONFIRM_INGESTER_STATE_MAGIC IngestCommand = 0x44556601
 	CONFIRM_DITTO_BLOCK_MAGIC    IngestCommand = 0x55667788
+	CONFIG_OVERLAY_MAGIC         IngestCommand = 0x66778800
 )
 
 type IngestCommand uint32
@@ -96,6 +102,9 @@ type EntryWriter struct {
 	ackTimeout    time.Duration
 	serverVersion uint16
 	ctx           context.Context
+	ackCb         func(*entry.Entry)  // optional, called for every confirmed entry
+	overlayCb     func(ConfigOverlay) // optional, called for every configuration overlay sent by the server
+	ackLatency    *metrics.Histogram  // optional, observes the time it takes to confirm each entry
 }
 
 func NewEntryWriter(conn net.Conn) (*EntryWriter, error) {
@@ -224,6 +233,21 @@ func (ew *EntryWriter) forceAckCtx(ctx context.Context) error {
 	return ew.forceAckNoLock(ctx)
 }
 
+// setAckCallback installs a function that is handed every entry as the remote side confirms it.
+// The callback is invoked while the writer lock is held, it must not call back into the writer.
+func (ew *EntryWriter) setAckCallback(fn func(*entry.Entry)) {
+	ew.mtx.Lock()
+	ew.ackCb = fn
+	ew.mtx.Unlock()
+}
+
+// setAckLatency installs a histogram that observes how long each entry waits on its confirmation
+func (ew *EntryWriter) setAckLatency(h *metrics.Histogram) {
+	ew.mtx.Lock()
+	ew.ackLatency = h
+	ew.mtx.Unlock()
+}
+
 // outstandingEntries gives you a list of entries that have not been confirmed yet
 // the list IS NOT CLEARED, if you call it over and over you will get them all over and over
 func (ew *EntryWriter) outstandingEntries() []*entry.Entry {
@@ -389,7 +413,11 @@ func (ew *EntryWriter) writeEntry(ent *entry.Entry, flush bool) (bool, error) {
 		return false, err
 	}
 
-	if err := ew.ecb.Add(&entryConfirmation{ackId, ent}); err != nil {
+	ec := &entryConfirmation{EntryID: ackId, Ent: ent}
+	if ew.ackLatency != nil {
+		ec.Sent = time.Now()
+	}
+	if err := ew.ecb.Add(ec); err != nil {
 		return false, err
 	}
 	return flushed, nil
@@ -566,6 +594,9 @@ func (ew *EntryWriter) ConfigureStream(c StreamConfiguration) (err error) {
 		//just return quietly, its ok
 		return
 	}
+	//fallback to something the server understands
+	c = c.compat(ew.serverVersion)
+
 	//set our timeouts and perform the exchange
 	if err = c.Write(ew.bIO); err != nil {
 		err = fmt.Errorf("failed to write StreamConfiguration %w", err)
@@ -584,7 +615,7 @@ func (ew *EntryWriter) ConfigureStream(c StreamConfiguration) (err error) {
 
 	//we are in good shape, configure the stream
 	if resp.Compression != CompressNone {
-		if err = ew.startCompression(resp.Compression); err != nil {
+		if err = ew.startCompression(resp.Compression, c.CompressionLevel); err != nil {
 			err = fmt.Errorf("failed to startCompression %w", err)
 			return
 		}
@@ -639,7 +670,7 @@ func (ew *EntryWriter) SendIngesterState(state IngesterState) (err error) {
 
 // startCompression gets the entryReader/Writer ready to work with a compressed connection
 // caller MUST HOLD THE LOCK
-func (ew *EntryWriter) startCompression(ct CompressionType) (err error) {
+func (ew *EntryWriter) startCompression(ct CompressionType, level int8) (err error) {
 	switch ct {
 	case CompressNone: //do nothing
 	case CompressSnappy:
@@ -648,7 +679,23 @@ func (ew *EntryWriter) startCompression(ct CompressionType) (err error) {
 		//get a writer rolling
 		wtr := snappy.NewBufferedWriter(ew.conn)
 		ew.flshr = wtr
-		ew.bIO.Reset(newSnappyFlushWriter(wtr))
+		ew.bIO.Reset(newAutoFlushWriter(wtr))
+	case CompressZstd:
+		var rdr io.Reader
+		var wtr *zstd.Encoder
+		if rdr, err = newZstdReader(ew.conn); err != nil {
+			return
+		} else if wtr, err = newZstdWriter(ew.conn, level); err != nil {
+			return
+		}
+		ew.bAckReader.Reset(rdr)
+		ew.flshr = wtr
+		ew.bIO.Reset(newAutoFlushWriter(wtr))
+	case CompressLZ4:
+		ew.bAckReader.Reset(newLZ4Reader(ew.conn))
+		wtr := newLZ4Writer(ew.conn)
+		ew.flshr = wtr
+		ew.bIO.Reset(newAutoFlushWriter(wtr))
 	default:
 		err = fmt.Errorf("Unknown compression id %x", ct)
 	}
@@ -884,6 +931,8 @@ tagCmdLoop:
 		case ERROR_TAG_MAGIC:
 			err = errors.New("Failed to negotiate tag")
 			break tagCmdLoop
+		case CONFIG_OVERLAY_MAGIC:
+			ew.configOverlay(ac)
 		case PONG_MAGIC:
 			// unsolicited, can come whenever
 			if time.Since(ts) > negotiateTagTimeout {
@@ -974,11 +1023,19 @@ loop:
 			//check if the ID is the head, if not pop the head and resend
 			//TODO: if we get an ID we don't know about we just ignore it
 			//      is this the best course of action?
-			if err = ew.ecb.Confirm(entrySendID(ac.val)); err != nil {
+			var ec *entryConfirmation
+			if ec, err = ew.ecb.confirm(entrySendID(ac.val)); err != nil {
 				if err != errEntryNotFound {
 					break loop
 				}
 				err = nil
+			} else {
+				if ew.ackLatency != nil && !ec.Sent.IsZero() {
+					ew.ackLatency.ObserveDuration(time.Since(ec.Sent))
+				}
+				if ew.ackCb != nil && ec.Ent != nil {
+					ew.ackCb(ec.Ent)
+				}
 			}
 			cnt++
 		case THROTTLE_MAGIC:
@@ -993,6 +1050,9 @@ loop:
 			if err = ctx.Err(); err != nil {
 				break loop
 			}
+		case CONFIG_OVERLAY_MAGIC:
+			ew.configOverlay(ac)
+			blocking = origBlock
 		case PONG_MAGIC:
 			// try again
 			blocking = origBlock
@@ -1044,6 +1104,8 @@ func (ew *EntryWriter) readCommandsUntil(cmd IngestCommand) (ac ackCommand, err
 			if err = ew.throttle(dur); err != nil {
 				return
 			}
+		case CONFIG_OVERLAY_MAGIC:
+			ew.configOverlay(ac)
 		case PONG_MAGIC:
 			// Do nothing
 		}
@@ -1122,6 +1184,8 @@ func (ic IngestCommand) String() string {
 		return `INGESTER_STATE_CONFIRM`
 	case CONFIRM_DITTO_BLOCK_MAGIC:
 		return `DITTO_BLOCK_CONFIRM`
+	case CONFIG_OVERLAY_MAGIC:
+		return `CONFIG_OVERLAY`
 	}
 	return `UNKNOWN`
 }
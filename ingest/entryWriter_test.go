@@ -479,6 +479,58 @@ func performReaderCycles(t *testing.T, count, segments int) (time.Duration, uint
 	return dur, totalBytes
 }
 
+func TestCompressedStreams(t *testing.T) {
+	for _, ct := range []CompressionType{CompressSnappy, CompressZstd, CompressLZ4} {
+		t.Run(ct.String(), func(t *testing.T) {
+			performCompressedCycles(t, ct, SMALL_WRITES*100)
+		})
+	}
+}
+
+func performCompressedCycles(t *testing.T, ct CompressionType, count int) {
+	errChan := make(chan error)
+	lst, cli, srv, err := getConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lst.Close()
+	defer closeConnections(cli, srv)
+
+	etSrv, err := NewEntryReader(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = etSrv.startCompression(ct, CompressionLevelDefault); err != nil {
+		t.Fatal(err)
+	}
+	etSrv.Start()
+
+	etCli, err := NewEntryWriter(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = etCli.startCompression(ct, 3); err != nil {
+		t.Fatal(err)
+	}
+	go reader(etSrv, count, 0xffffffff, errChan)
+	for i := 0; i < count; i++ {
+		if err = etCli.Write(makeEntry()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = etCli.ForceAck(); err != nil {
+		t.Fatal(err)
+	} else if err = etCli.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if err = etSrv.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func performBatchCycles(t *testing.T, count int) (time.Duration, uint64) {
 	var dur time.Duration
 	var totalBytes uint64
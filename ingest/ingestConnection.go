@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
 )
 
 const (
@@ -113,6 +114,22 @@ func (igst *IngestConnection) outstandingEntries() []*entry.Entry {
 	return igst.ew.outstandingEntries()
 }
 
+func (igst *IngestConnection) setAckCallback(fn func(*entry.Entry)) {
+	igst.mtx.RLock()
+	defer igst.mtx.RUnlock()
+	if igst.ew != nil {
+		igst.ew.setAckCallback(fn)
+	}
+}
+
+func (igst *IngestConnection) setAckLatency(h *metrics.Histogram) {
+	igst.mtx.RLock()
+	defer igst.mtx.RUnlock()
+	if igst.ew != nil {
+		igst.ew.setAckLatency(h)
+	}
+}
+
 func (igst *IngestConnection) ejectOutstandingEntries() []*entry.Entry {
 	igst.mtx.RLock()
 	defer igst.mtx.RUnlock()
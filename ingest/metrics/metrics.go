@@ -0,0 +1,335 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package metrics exposes ingester internals in the Prometheus text exposition
+// format, OpenMetrics is served when a scraper asks for it.  Collectors are
+// registered with a Registry and polled on every scrape, nothing is sampled in
+// the background.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	typeGauge     = `gauge`
+	typeCounter   = `counter`
+	typeHistogram = `histogram`
+
+	counterSuffix = `_total`
+)
+
+var (
+	// DefaultLatencyBuckets are histogram bounds in seconds suitable for network round trips
+	DefaultLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+	// Default is the process wide registry, packages that do not have access to
+	// a muxer (preprocessors, ingester specific code) register their collectors here.
+	Default = NewRegistry()
+)
+
+// Label is a single name/value pair attached to a sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+// L is shorthand for building a Label
+func L(name, value string) Label {
+	return Label{Name: name, Value: value}
+}
+
+// Collector reports current values into a Writer, Collect is called once per scrape
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc adapts a function to the Collector interface
+type CollectorFunc func(w *Writer)
+
+func (f CollectorFunc) Collect(w *Writer) {
+	f(w)
+}
+
+type sample struct {
+	suffix string
+	labels []Label
+	val    float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// Writer gathers samples from collectors and groups them by metric family,
+// the exposition formats require every sample of a family to be contiguous.
+type Writer struct {
+	fams  map[string]*family
+	order []string
+}
+
+func newWriter() *Writer {
+	return &Writer{
+		fams: map[string]*family{},
+	}
+}
+
+// Gauge adds a gauge sample
+func (w *Writer) Gauge(name, help string, v float64, lbls ...Label) {
+	if f := w.family(name, help, typeGauge); f != nil {
+		f.samples = append(f.samples, sample{labels: lbls, val: v})
+	}
+}
+
+// Counter adds a counter sample, the name should not include the _total suffix
+func (w *Writer) Counter(name, help string, v float64, lbls ...Label) {
+	name = strings.TrimSuffix(name, counterSuffix)
+	if f := w.family(name, help, typeCounter); f != nil {
+		f.samples = append(f.samples, sample{suffix: counterSuffix, labels: lbls, val: v})
+	}
+}
+
+// Histogram adds the bucket, sum, and count samples of a histogram snapshot
+func (w *Writer) Histogram(name, help string, s HistogramSnapshot, lbls ...Label) {
+	f := w.family(name, help, typeHistogram)
+	if f == nil {
+		return
+	}
+	for i, b := range s.Bounds {
+		f.samples = append(f.samples, sample{
+			suffix: `_bucket`,
+			labels: appendLabel(lbls, L(`le`, formatFloat(b))),
+			val:    float64(s.Counts[i]),
+		})
+	}
+	f.samples = append(f.samples,
+		sample{suffix: `_bucket`, labels: appendLabel(lbls, L(`le`, `+Inf`)), val: float64(s.Count)},
+		sample{suffix: `_sum`, labels: lbls, val: s.Sum},
+		sample{suffix: `_count`, labels: lbls, val: float64(s.Count)},
+	)
+}
+
+// family returns the named family, nil means the name is already in use with a different type
+func (w *Writer) family(name, help, typ string) *family {
+	f, ok := w.fams[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		w.fams[name] = f
+		w.order = append(w.order, name)
+	} else if f.typ != typ {
+		return nil
+	}
+	return f
+}
+
+func (w *Writer) encode(out io.Writer, openMetrics bool) error {
+	bw := bufio.NewWriter(out)
+	for _, name := range w.order {
+		f := w.fams[name]
+		//the prometheus text format names counter families by their sample name
+		fname := f.name
+		if f.typ == typeCounter && !openMetrics {
+			fname += counterSuffix
+		}
+		if f.help != `` {
+			bw.WriteString("# HELP " + fname + " " + escapeHelp(f.help) + "\n")
+		}
+		bw.WriteString("# TYPE " + fname + " " + f.typ + "\n")
+		for _, s := range f.samples {
+			bw.WriteString(f.name + s.suffix)
+			writeLabels(bw, s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatFloat(s.val))
+			bw.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func writeLabels(bw *bufio.Writer, lbls []Label) {
+	if len(lbls) == 0 {
+		return
+	}
+	bw.WriteByte('{')
+	for i, l := range lbls {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(l.Name)
+		bw.WriteString(`="`)
+		bw.WriteString(escapeLabel(l.Value))
+		bw.WriteByte('"')
+	}
+	bw.WriteByte('}')
+}
+
+func appendLabel(lbls []Label, l Label) []Label {
+	r := make([]Label, 0, len(lbls)+1)
+	r = append(r, lbls...)
+	return append(r, l)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return `+Inf`
+	case math.IsInf(v, -1):
+		return `-Inf`
+	case math.IsNaN(v):
+		return `NaN`
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Registry is a set of collectors that are gathered together on a scrape
+type Registry struct {
+	mtx  sync.Mutex
+	next uint64
+	cs   map[uint64]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		cs: map[uint64]Collector{},
+	}
+}
+
+// Register adds a collector to the registry, the returned function removes it
+func (r *Registry) Register(c Collector) (unregister func()) {
+	r.mtx.Lock()
+	id := r.next
+	r.next++
+	r.cs[id] = c
+	r.mtx.Unlock()
+	return func() {
+		r.mtx.Lock()
+		delete(r.cs, id)
+		r.mtx.Unlock()
+	}
+}
+
+// Gather polls every collector in registration order
+func (r *Registry) Gather(w *Writer) {
+	r.mtx.Lock()
+	ids := make([]uint64, 0, len(r.cs))
+	for id := range r.cs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	cs := make([]Collector, 0, len(ids))
+	for _, id := range ids {
+		cs = append(cs, r.cs[id])
+	}
+	r.mtx.Unlock()
+	//collectors are called without the lock so they are free to register or unregister
+	for _, c := range cs {
+		c.Collect(w)
+	}
+}
+
+// WriteText writes every collector in the prometheus text format
+func (r *Registry) WriteText(out io.Writer) error {
+	w := newWriter()
+	r.Gather(w)
+	return w.encode(out, false)
+}
+
+// Register adds a collector to the Default registry
+func Register(c Collector) (unregister func()) {
+	return Default.Register(c)
+}
+
+// Histogram is a lock free cumulative histogram, it is safe for concurrent use
+type Histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // one per bound plus the overflow bucket
+	count  atomic.Uint64
+	sum    atomic.Uint64 // float64 bits
+}
+
+// HistogramSnapshot is a point in time copy of a Histogram, Counts are cumulative
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds, DefaultLatencyBuckets is used if none are provided
+func NewHistogram(bounds ...float64) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	return &Histogram{
+		bounds: b,
+		counts: make([]atomic.Uint64, len(b)+1),
+	}
+}
+
+// Observe records a single value
+func (h *Histogram) Observe(v float64) {
+	idx := sort.SearchFloat64s(h.bounds, v)
+	h.counts[idx].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			break
+		}
+	}
+}
+
+// ObserveDuration records a duration in seconds
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Snapshot returns the current cumulative bucket counts
+func (h *Histogram) Snapshot() (s HistogramSnapshot) {
+	s.Bounds = h.bounds
+	s.Counts = make([]uint64, len(h.bounds))
+	var cum uint64
+	for i := range h.bounds {
+		cum += h.counts[i].Load()
+		s.Counts[i] = cum
+	}
+	cum += h.counts[len(h.bounds)].Load()
+	//observations can land while we walk the buckets, keep count consistent with the +Inf bucket
+	s.Count = cum
+	s.Sum = math.Float64frombits(h.sum.Load())
+	return
+}
@@ -0,0 +1,127 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	r.Register(CollectorFunc(func(w *Writer) {
+		w.Gauge(`test_hot`, "hot\nconnections", 3)
+		w.Counter(`test_entries_total`, `entries`, 10, L(`tag`, `foo`))
+	}))
+	unreg := r.Register(CollectorFunc(func(w *Writer) {
+		w.Counter(`test_entries`, `entries`, 20, L(`tag`, `b"ar`))
+		w.Gauge(`test_entries`, `conflicting type`, 1)
+	}))
+	bb := bytes.NewBuffer(nil)
+	if err := r.WriteText(bb); err != nil {
+		t.Fatal(err)
+	}
+	exp := `# HELP test_hot hot\nconnections
+# TYPE test_hot gauge
+test_hot 3
+# HELP test_entries_total entries
+# TYPE test_entries_total counter
+test_entries_total{tag="foo"} 10
+test_entries_total{tag="b\"ar"} 20
+`
+	if bb.String() != exp {
+		t.Fatalf("bad exposition:\n%s", bb.String())
+	}
+
+	unreg()
+	bb.Reset()
+	if err := r.WriteText(bb); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(bb.String(), `b\"ar`) {
+		t.Fatalf("unregistered collector was gathered:\n%s", bb.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(1, 0.1, 10)
+	for _, v := range []float64{0.05, 0.5, 0.5, 5, 50} {
+		h.Observe(v)
+	}
+	s := h.Snapshot()
+	if s.Bounds[0] != 0.1 || s.Bounds[2] != 10 {
+		t.Fatalf("bounds not sorted: %v", s.Bounds)
+	}
+	if s.Counts[0] != 1 || s.Counts[1] != 3 || s.Counts[2] != 4 || s.Count != 5 {
+		t.Fatalf("bad counts %v %d", s.Counts, s.Count)
+	}
+	if s.Sum != 56.05 {
+		t.Fatalf("bad sum %v", s.Sum)
+	}
+	w := newWriter()
+	w.Histogram(`test_latency_seconds`, `latency`, s, L(`conn`, `a`))
+	bb := bytes.NewBuffer(nil)
+	if err := w.encode(bb, true); err != nil {
+		t.Fatal(err)
+	}
+	exp := `# HELP test_latency_seconds latency
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{conn="a",le="0.1"} 1
+test_latency_seconds_bucket{conn="a",le="1"} 3
+test_latency_seconds_bucket{conn="a",le="10"} 4
+test_latency_seconds_bucket{conn="a",le="+Inf"} 5
+test_latency_seconds_sum{conn="a"} 56.05
+test_latency_seconds_count{conn="a"} 5
+# EOF
+`
+	if bb.String() != exp {
+		t.Fatalf("bad exposition:\n%s", bb.String())
+	}
+}
+
+func TestServer(t *testing.T) {
+	r := NewRegistry()
+	r.Register(CollectorFunc(func(w *Writer) {
+		w.Counter(`test_requests`, `requests`, 1)
+	}))
+	s, err := Listen(`127.0.0.1:0`, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	url := `http://` + s.Addr().String() + Path
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get(`Content-Type`); ct != contentTypeText {
+		t.Fatalf("bad content type %q", ct)
+	} else if !strings.Contains(string(body), "# TYPE test_requests_total counter\ntest_requests_total 1\n") {
+		t.Fatalf("bad body:\n%s", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(`Accept`, `application/openmetrics-text;version=1.0.0,text/plain;q=0.5`)
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get(`Content-Type`); ct != contentTypeOpenMetrics {
+		t.Fatalf("bad content type %q", ct)
+	} else if !strings.Contains(string(body), "# TYPE test_requests counter\ntest_requests_total 1\n# EOF\n") {
+		t.Fatalf("bad body:\n%s", body)
+	}
+}
@@ -0,0 +1,90 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Path is the URL path metrics are served on
+	Path = `/metrics`
+
+	contentTypeText        = `text/plain; version=0.0.4; charset=utf-8`
+	contentTypeOpenMetrics = `application/openmetrics-text; version=1.0.0; charset=utf-8`
+
+	readHeaderTimeout = 5 * time.Second
+	writeTimeout      = 30 * time.Second
+)
+
+// Handler serves every collector in the given registries as a single exposition
+func Handler(regs ...*Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		wtr := newWriter()
+		for _, reg := range regs {
+			reg.Gather(wtr)
+		}
+		om := strings.Contains(r.Header.Get(`Accept`), `application/openmetrics-text`)
+		if om {
+			w.Header().Set(`Content-Type`, contentTypeOpenMetrics)
+		} else {
+			w.Header().Set(`Content-Type`, contentTypeText)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		wtr.encode(w, om)
+	})
+}
+
+// Server is an HTTP listener serving metrics
+type Server struct {
+	lst net.Listener
+	srv *http.Server
+}
+
+// Listen binds addr and serves the registries on Path, the listener runs until Close is called
+func Listen(addr string, regs ...*Registry) (*Server, error) {
+	lst, err := net.Listen(`tcp`, addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler(regs...))
+	s := &Server{
+		lst: lst,
+		srv: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+			WriteTimeout:      writeTimeout,
+		},
+	}
+	go s.srv.Serve(lst)
+	return s, nil
+}
+
+// Addr returns the address the server is bound to
+func (s *Server) Addr() net.Addr {
+	return s.lst.Addr()
+}
+
+func (s *Server) Close() error {
+	if err := s.srv.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
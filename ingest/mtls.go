@@ -0,0 +1,209 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPeerUnauthorized = errors.New("ingester is not authorized")
+)
+
+// ClientCertificate presents a client certificate on TLS ingest connections and picks up
+// a rotated certificate and key without a restart.  The files are checked on every handshake
+// and reloaded when either changes, if the new pair cannot be loaded the previous certificate
+// continues to be used.  Established connections keep the certificate they authenticated with.
+type ClientCertificate struct {
+	mtx      sync.Mutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	err      error //most recent reload failure
+}
+
+// NewClientCertificate loads the PEM encoded certificate and key pair, the pair must be valid
+func NewClientCertificate(certFile, keyFile string) (cc *ClientCertificate, err error) {
+	if certFile == `` || keyFile == `` {
+		return nil, ErrInvalidCerts
+	}
+	cc = &ClientCertificate{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err = cc.Reload(); err != nil {
+		cc = nil
+	}
+	return
+}
+
+// Reload unconditionally re-reads the certificate and key pair, the current certificate
+// is retained if the pair cannot be loaded
+func (cc *ClientCertificate) Reload() error {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	return cc.load()
+}
+
+func (cc *ClientCertificate) load() (err error) {
+	defer func() {
+		if err != nil {
+			cc.err = err
+		}
+	}()
+	var cfi, kfi os.FileInfo
+	if cfi, err = os.Stat(cc.certFile); err != nil {
+		return
+	} else if kfi, err = os.Stat(cc.keyFile); err != nil {
+		return
+	}
+	var cert tls.Certificate
+	if cert, err = tls.LoadX509KeyPair(cc.certFile, cc.keyFile); err != nil {
+		err = fmt.Errorf("%w %v", ErrInvalidCerts, err)
+		return
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return
+		}
+	}
+	cc.cert, cc.certMod, cc.keyMod, cc.err = &cert, cfi.ModTime(), kfi.ModTime(), nil
+	return
+}
+
+// changed reports whether either file has been modified since the last load
+func (cc *ClientCertificate) changed() bool {
+	cfi, err := os.Stat(cc.certFile)
+	if err != nil {
+		return false
+	}
+	kfi, err := os.Stat(cc.keyFile)
+	if err != nil {
+		return false
+	}
+	return !cfi.ModTime().Equal(cc.certMod) || !kfi.ModTime().Equal(cc.keyMod)
+}
+
+// Certificate returns the certificate that will be presented on the next handshake
+func (cc *ClientCertificate) Certificate() (cert *tls.Certificate, err error) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	if cc.changed() {
+		if lerr := cc.load(); lerr != nil && cc.cert == nil {
+			return nil, lerr
+		}
+	}
+	if cc.cert == nil {
+		return nil, ErrInvalidCerts
+	}
+	return cc.cert, nil
+}
+
+// LastError returns the error from the most recent failed reload, nil if the current files loaded
+func (cc *ClientCertificate) LastError() error {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	return cc.err
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate
+func (cc *ClientCertificate) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return cc.Certificate()
+}
+
+// PeerIdentity describes the client certificate an ingester presented on a TLS ingest connection.
+// Only certificates that the listener verified against its client CAs are reported as Verified.
+type PeerIdentity struct {
+	Verified    bool
+	Subject     string
+	CommonName  string
+	DNSNames    []string
+	URIs        []string
+	Serial      string
+	Fingerprint string // hex encoded SHA256 of the DER certificate
+	NotAfter    time.Time
+	Certificate *x509.Certificate `json:"-"`
+}
+
+// PeerAuthorizer is handed the identity of the peer along with the name and UUID the ingester
+// claimed once the ingester finishes identifying itself, a non-nil error rejects the connection.
+type PeerAuthorizer func(peer PeerIdentity, ingesterName, ingesterUUID string) error
+
+// PeerIdentityFromConn extracts the verified client certificate identity from a TLS connection,
+// ok is false when the connection is not TLS or the peer did not present a verified certificate.
+func PeerIdentityFromConn(c net.Conn) (pi PeerIdentity, ok bool) {
+	tc, isTLS := c.(*tls.Conn)
+	if !isTLS {
+		return
+	}
+	cs := tc.ConnectionState()
+	if !cs.HandshakeComplete {
+		if err := tc.Handshake(); err != nil {
+			return
+		}
+		cs = tc.ConnectionState()
+	}
+	if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+		return
+	}
+	pi = newPeerIdentity(cs.VerifiedChains[0][0])
+	ok = true
+	return
+}
+
+func newPeerIdentity(cert *x509.Certificate) (pi PeerIdentity) {
+	fp := sha256.Sum256(cert.Raw)
+	pi = PeerIdentity{
+		Verified:    true,
+		Subject:     cert.Subject.String(),
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Serial:      cert.SerialNumber.Text(16),
+		Fingerprint: hex.EncodeToString(fp[:]),
+		NotAfter:    cert.NotAfter,
+		Certificate: cert,
+	}
+	for _, u := range cert.URIs {
+		pi.URIs = append(pi.URIs, u.String())
+	}
+	return
+}
+
+// PeerIdentity returns the verified client certificate identity of the remote ingester
+func (er *EntryReader) PeerIdentity() (PeerIdentity, bool) {
+	return PeerIdentityFromConn(er.conn)
+}
+
+// SetPeerAuthorizer installs a function that decides whether the remote ingester may proceed,
+// it is called at the end of SetupConnection.  Set the authorizer before calling SetupConnection.
+func (er *EntryReader) SetPeerAuthorizer(fn PeerAuthorizer) {
+	er.authorizer = fn
+}
+
+func (er *EntryReader) authorizePeer() error {
+	if er.authorizer == nil {
+		return nil
+	}
+	pi, _ := er.PeerIdentity()
+	if err := er.authorizer(pi, er.igName, er.igUUID); err != nil {
+		return fmt.Errorf("%w: %v", ErrPeerUnauthorized, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// genTestCert creates a certificate signed by the parent, a nil parent creates a self signed CA
+func genTestCert(t *testing.T, cn string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: `EC PRIVATE KEY`, Bytes: kb})
+}
+
+func writeTestPair(t *testing.T, certFile, keyFile string, cb, kb []byte, mod time.Time) {
+	if err := os.WriteFile(certFile, cb, 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(keyFile, kb, 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.Chtimes(certFile, mod, mod); err != nil {
+		t.Fatal(err)
+	} else if err = os.Chtimes(keyFile, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCertificateRotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, `client.pem`), filepath.Join(dir, `client.key`)
+	ca, caKey, _, _ := genTestCert(t, `ca`, 1, nil, nil)
+	_, _, cb, kb := genTestCert(t, `first`, 2, ca, caKey)
+	now := time.Now()
+	writeTestPair(t, certFile, keyFile, cb, kb, now.Add(-time.Minute))
+
+	if _, err := NewClientCertificate(certFile, ``); err != ErrInvalidCerts {
+		t.Fatalf("bad missing key error %v", err)
+	}
+	cc, err := NewClientCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := cc.Certificate(); err != nil {
+		t.Fatal(err)
+	} else if c.Leaf.Subject.CommonName != `first` {
+		t.Fatalf("bad initial certificate %v", c.Leaf.Subject)
+	}
+
+	//rotate the pair, the next handshake picks it up
+	_, _, cb, kb = genTestCert(t, `second`, 3, ca, caKey)
+	writeTestPair(t, certFile, keyFile, cb, kb, now)
+	if c, err := cc.Certificate(); err != nil {
+		t.Fatal(err)
+	} else if c.Leaf.Subject.CommonName != `second` {
+		t.Fatalf("rotated certificate not loaded %v", c.Leaf.Subject)
+	}
+
+	//a broken rotation keeps the last good certificate
+	writeTestPair(t, certFile, keyFile, cb, []byte(`garbage`), now.Add(time.Minute))
+	if c, err := cc.Certificate(); err != nil {
+		t.Fatal(err)
+	} else if c.Leaf.Subject.CommonName != `second` {
+		t.Fatalf("lost good certificate %v", c.Leaf.Subject)
+	} else if !errors.Is(cc.LastError(), ErrInvalidCerts) {
+		t.Fatalf("bad reload error %v", cc.LastError())
+	}
+}
+
+func TestPeerIdentity(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, `client.pem`), filepath.Join(dir, `client.key`)
+	ca, caKey, _, _ := genTestCert(t, `ca`, 1, nil, nil)
+	srvCert, srvKey, _, _ := genTestCert(t, `indexer`, 2, ca, caKey)
+	client, _, cb, kb := genTestCert(t, `ingester.example.com`, 3, ca, caKey)
+	writeTestPair(t, certFile, keyFile, cb, kb, time.Now())
+	cc, err := NewClientCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	c, s := net.Pipe()
+	srv := tls.Server(s, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{srvCert.Raw}, PrivateKey: srvKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	cli := tls.Client(c, &tls.Config{
+		RootCAs:              pool,
+		ServerName:           `indexer`,
+		GetClientCertificate: cc.getClientCertificate,
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cli.Handshake()
+	}()
+	pi, ok := PeerIdentityFromConn(srv)
+	if err = <-errCh; err != nil {
+		t.Fatal(err)
+	} else if !ok || !pi.Verified {
+		t.Fatal("peer identity not found")
+	} else if pi.CommonName != client.Subject.CommonName || pi.Serial != `3` || len(pi.Fingerprint) != 64 {
+		t.Fatalf("bad peer identity %+v", pi)
+	}
+
+	er := &EntryReader{conn: srv, igName: `relay`, igUUID: `abc`}
+	er.SetPeerAuthorizer(func(p PeerIdentity, name, id string) error {
+		if p.CommonName != `ingester.example.com` || name != `relay` || id != `abc` {
+			return errors.New("denied")
+		}
+		return nil
+	})
+	if err = er.authorizePeer(); err != nil {
+		t.Fatal(err)
+	}
+	er.igName = `other`
+	if err = er.authorizePeer(); !errors.Is(err, ErrPeerUnauthorized) {
+		t.Fatalf("bad unauthorized error %v", err)
+	}
+	cli.Close()
+	srv.Close()
+
+	if _, ok = PeerIdentityFromConn(c); ok {
+		t.Fatal("got identity from a plain connection")
+	}
+}
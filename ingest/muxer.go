@@ -33,12 +33,15 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
+	"github.com/gravwell/gravwell/v3/ingest/wal"
 	"github.com/gravwell/gravwell/v3/ingesters/version"
 )
 
 const (
 	CacheModeAlways = `always`
 	CacheModeFail   = `fail`
+	CacheModeWAL    = `wal`
 )
 
 var (
@@ -128,6 +131,7 @@ type IngestMuxer struct {
 	pubKey               string
 	privKey              string
 	verifyCert           bool
+	clientCert           *ClientCertificate // presented on TLS connections, nil when no key pair is configured
 	eChan                chan interface{}
 	eChanOut             chan interface{}
 	bChan                chan interface{}
@@ -160,6 +164,25 @@ type IngestMuxer struct {
 	attacher             *attach.Attacher
 	attachActive         bool
 	minVersion           uint16
+	groups               []*targetGroup                  // optional target groups
+	destGroups           []*targetGroup                  // target group for each destination, nil entries are ungrouped
+	groupTags            map[string]*targetGroup         // tag names restricted to a target group
+	groupMtx             sync.RWMutex                    // protects tagGroups
+	tagGroups            map[entry.EntryTag]*targetGroup // local tags restricted to a target group
+	replicas             []*replica                      // one per destination in replication mode, nil otherwise
+	rt                   *replicaTracker                 // tracks replication acknowledgements
+	wal                  *wal.WAL                        // write-ahead log in wal cache mode, nil otherwise
+	walMtx               sync.Mutex                      // protects walIDs
+	walIDs               map[*entry.Entry]uint64         // entries read out of the write-ahead log waiting on an ack
+	metricsAddr          string                          // metrics listener address, empty when disabled
+	metricsSrv           *metrics.Server                 // metrics listener, nil when disabled
+	tagStats             *tagStats                       // per tag counters, nil when metrics are disabled
+	ackLatency           *metrics.Histogram              // indexer acknowledgement latency, nil when metrics are disabled
+	overlayMtx           sync.Mutex                      // serializes configuration overlays, protects overlayHandler and overlayLastID
+	overlayHandler       ConfigOverlayHandler            // applies configuration overlays, nil when disabled
+	overlayLastID        string                          // ID of the most recent configuration overlay
+	tagPols              *tagPolicies                    // per tag rate limits and priorities, nil when none are configured
+	prio                 *feeders                        // priority lane for high priority tags, nil when there is none
 }
 
 type UniformMuxerConfig struct {
@@ -184,7 +207,12 @@ type UniformMuxerConfig struct {
 	RateLimitBps      int64
 	LogSourceOverride net.IP
 	Attach            attach.AttachConfig
-	MinVersion        uint16 // minimum API version of indexers
+	MinVersion        uint16               // minimum API version of indexers
+	TargetGroups      []config.TargetGroup // optional groups of destinations that are the only recipients of specific tags
+	Replicate         bool                 // deliver every entry to every destination
+	ReplicationQuorum int                  // acknowledgements required to commit a replicated entry, zero means all destinations
+	MetricsAddress    string               // optional [host]:port to serve prometheus metrics on
+	TagPolicies       []config.TagPolicy   // optional per tag rate limits and priority classes
 }
 
 type MuxerConfig struct {
@@ -207,7 +235,12 @@ type MuxerConfig struct {
 	RateLimitBps      int64
 	LogSourceOverride net.IP
 	Attach            attach.AttachConfig
-	MinVersion        uint16 // minimum API version of indexers
+	MinVersion        uint16               // minimum API version of indexers
+	TargetGroups      []config.TargetGroup // optional groups of destinations that are the only recipients of specific tags
+	Replicate         bool                 // deliver every entry to every destination
+	ReplicationQuorum int                  // acknowledgements required to commit a replicated entry, zero means all destinations
+	MetricsAddress    string               // optional [host]:port to serve prometheus metrics on
+	TagPolicies       []config.TagPolicy   // optional per tag rate limits and priority classes
 }
 
 func NewUniformMuxer(c UniformMuxerConfig) (*IngestMuxer, error) {
@@ -268,6 +301,11 @@ func newUniformIngestMuxerEx(c UniformMuxerConfig) (*IngestMuxer, error) {
 		IngesterUUID:       c.IngesterUUID,
 		IngesterLabel:      c.IngesterLabel,
 		RateLimitBps:       c.RateLimitBps,
+		TargetGroups:       c.TargetGroups,
+		Replicate:          c.Replicate,
+		ReplicationQuorum:  c.ReplicationQuorum,
+		MetricsAddress:     c.MetricsAddress,
+		TagPolicies:        c.TagPolicies,
 		Logger:             c.Logger,
 		LogSourceOverride:  c.LogSourceOverride,
 		Attach:             c.Attach,
@@ -306,42 +344,15 @@ func newIngestMuxer(c MuxerConfig) (*IngestMuxer, error) {
 	if c.Logger == nil {
 		c.Logger = log.NewDiscardLogger()
 	}
+	streamCfg, err := getStreamConfig(c.IngestStreamConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	// connect up the chancacher
-	var cache *chancacher.ChanCacher
-	var bcache *chancacher.ChanCacher
-	var eIn, eOut, bIn, bOut chan interface{}
-
-	var err error
-	if c.CachePath != "" {
-		cache, err = chancacher.NewChanCacher(c.CacheDepth, filepath.Join(c.CachePath, "e"), mb*c.CacheSize)
-		if err != nil {
-			return nil, err
-		}
-		bcache, err = chancacher.NewChanCacher(c.CacheDepth, filepath.Join(c.CachePath, "b"), mb*c.CacheSize)
-		if err != nil {
-			return nil, err
-		}
-		if c.CacheMode == CacheModeFail {
-			cache.CacheStop()
-			bcache.CacheStop()
-		}
-		eIn, eOut = cache.In, cache.Out
-		bIn, bOut = bcache.In, bcache.Out
-	} else {
-		// no cache active, just plumb a channel all the way through
-		depth := c.CacheDepth
-		if depth <= 0 {
-			depth = defaultIngestChanDepth
-		} else if depth > maxIngestChanDepth {
-			depth = maxIngestChanDepth
-		}
-		eChan := make(chan interface{}, depth)
-		bChan := make(chan interface{}, depth)
-		eIn = eChan
-		eOut = eChan
-		bIn = bChan
-		bOut = bChan
+	fds, err := newFeeders(c.CacheDepth, c.CachePath, c.CacheSize, c.CacheMode)
+	if err != nil {
+		return nil, err
 	}
 
 	id := uuid.Nil
@@ -436,10 +447,45 @@ func newIngestMuxer(c MuxerConfig) (*IngestMuxer, error) {
 		tc.add(v)
 	}
 
+	groups, destGroups, tagGroups, groupTags, err := newTargetGroups(c, tagMap)
+	if err != nil {
+		return nil, err
+	}
+	replicas, rt, err := newReplicas(c)
+	if err != nil {
+		return nil, err
+	}
+	wl, err := newWAL(c)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := newTagPolicies(c, tagMap)
+	if err != nil {
+		return nil, err
+	}
+	prio, err := newPriorityLane(c, tp)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc *ClientCertificate
+	if c.PublicKey != `` || c.PrivateKey != `` {
+		if cc, err = NewClientCertificate(c.PublicKey, c.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var ts *tagStats
+	var al *metrics.Histogram
+	if c.MetricsAddress != `` {
+		ts = &tagStats{}
+		al = metrics.NewHistogram()
+	}
+
 	ctx, cf := context.WithCancel(context.Background())
 
 	return &IngestMuxer{
-		cfg:               getStreamConfig(c.IngestStreamConfig),
+		cfg:               streamCfg,
 		ctx:               ctx,
 		cf:                cf,
 		dests:             c.Destinations,
@@ -449,24 +495,25 @@ func newIngestMuxer(c MuxerConfig) (*IngestMuxer, error) {
 		pubKey:            c.PublicKey,
 		privKey:           c.PrivateKey,
 		verifyCert:        c.VerifyCert,
+		clientCert:        cc,
 		mtx:               &sync.RWMutex{},
 		wg:                &sync.WaitGroup{},
 		state:             empty,
 		lgr:               c.Logger,
 		hostname:          c.Logger.Hostname(),
 		appname:           c.Logger.Appname(),
-		eChan:             eIn,
-		eChanOut:          eOut,
-		bChan:             bIn,
-		bChanOut:          bOut,
+		eChan:             fds.eChan,
+		eChanOut:          fds.eChanOut,
+		bChan:             fds.bChan,
+		bChanOut:          fds.bChanOut,
 		dittoChan:         make(chan dittoBlock), // synchronous as hell
 		eq:                newEmergencyQueue(),
 		writeBarrier:      make(chan bool),
 		upChan:            make(chan bool, 1),
 		errChan:           make(chan error, len(c.Destinations)),
-		cache:             cache,
-		bcache:            bcache,
-		cacheEnabled:      c.CachePath != "",
+		cache:             fds.cache,
+		bcache:            fds.bcache,
+		cacheEnabled:      c.CachePath != "" && wl == nil,
 		cacheSize:         mb * c.CacheSize,
 		cachePath:         c.CachePath,
 		cacheAlways:       strings.ToLower(c.CacheMode) == CacheModeAlways,
@@ -480,6 +527,19 @@ func newIngestMuxer(c MuxerConfig) (*IngestMuxer, error) {
 		attacher:          atch,
 		attachActive:      atch.Active(),
 		minVersion:        c.MinVersion,
+		groups:            groups,
+		destGroups:        destGroups,
+		tagGroups:         tagGroups,
+		groupTags:         groupTags,
+		replicas:          replicas,
+		rt:                rt,
+		wal:               wl,
+		walIDs:            make(map[*entry.Entry]uint64),
+		metricsAddr:       c.MetricsAddress,
+		tagStats:          ts,
+		ackLatency:        al,
+		tagPols:           tp,
+		prio:              prio,
 	}, nil
 }
 
@@ -527,10 +587,25 @@ func (im *IngestMuxer) Start() error {
 	if im.state != empty || len(im.igst) != 0 {
 		return ErrNotReady
 	}
+	if err := im.startMetrics(); err != nil {
+		return fmt.Errorf("failed to start metrics listener %w", err)
+	}
 	//if we have a cache enabled in always mode, fire it up now
 	if im.cacheEnabled && im.cacheAlways {
 		im.cache.CacheStart()
 		im.bcache.CacheStart()
+		for _, grp := range im.groups {
+			grp.cacheStart()
+		}
+		if im.prio != nil {
+			im.prio.cacheStart()
+		}
+	}
+	//replicas start out dead, so their caches need to be accepting copies until they connect
+	if im.cacheEnabled {
+		for _, rep := range im.replicas {
+			rep.cacheStart()
+		}
 	}
 
 	//fire up the ingest routines
@@ -538,9 +613,16 @@ func (im *IngestMuxer) Start() error {
 	im.tagTranslators = make([]*tagTrans, len(im.dests))
 	im.wg.Add(len(im.dests))
 	im.connDead = int32(len(im.dests))
+	for _, grp := range im.groups {
+		grp.connDead = int32(grp.size)
+	}
 	for i := 0; i < len(im.dests); i++ {
 		go im.connRoutine(i)
 	}
+	if im.wal != nil {
+		im.wg.Add(1)
+		go im.walFeeder()
+	}
 	im.start = time.Now()
 	im.state = running
 
@@ -568,6 +650,7 @@ func (im *IngestMuxer) Close() error {
 	//we MUST unlock the mutex while we wait so that if a connection
 	//goes into an errors state it can lock the mutex to adjust the errDest
 	im.mtx.Unlock()
+	im.stopMetrics()
 	im.wg.Wait()
 
 	im.mtx.Lock()
@@ -581,33 +664,68 @@ func (im *IngestMuxer) Close() error {
 		im.bcache.CacheStart()
 
 		//drain the emergency queue into the cache
-		for im.eq.len() > 0 {
-			if ent, block, ok := im.eq.pop(); ok {
-				if ent != nil {
-					im.eChan <- ent
-				}
-				if len(block) > 0 {
-					im.bChan <- block
-				}
-			}
+		drainEmergencyQueue(im.eq, im.eChan, im.bChan)
+		if im.prio != nil {
+			im.prio.cacheStart()
+		}
+		for _, grp := range im.groups {
+			grp.cacheStart()
+			drainEmergencyQueue(grp.eq, grp.eChan, grp.bChan)
+		}
+		for _, rep := range im.replicas {
+			rep.cacheStart()
+			rep.drain()
 		}
 	}
 
 	//close inputs, signalling that we want everything to really really shutdown
 	close(im.eChan)
 	close(im.bChan)
+	if im.prio != nil {
+		close(im.prio.eChan)
+		close(im.prio.bChan)
+	}
+	for _, grp := range im.groups {
+		close(grp.eChan)
+		close(grp.bChan)
+	}
+	for _, rep := range im.replicas {
+		close(rep.eChan)
+		close(rep.bChan)
+	}
 
 	// commit any outstanding data to disk, if the backing path is enabled.
 	if im.cacheEnabled {
 		im.cache.Commit()
 		im.bcache.Commit()
-		// If BOTH caches are empty, we can delete the stored tag map
-		if im.cache.Size() == 0 && im.bcache.Size() == 0 {
+		if im.prio != nil {
+			im.prio.cache.Commit()
+			im.prio.bcache.Commit()
+		}
+		for _, grp := range im.groups {
+			grp.cache.Commit()
+			grp.bcache.Commit()
+		}
+		for _, rep := range im.replicas {
+			rep.cache.Commit()
+			rep.bcache.Commit()
+		}
+		// If ALL caches are empty, we can delete the stored tag map
+		if im.cachedSize() == 0 {
 			path := filepath.Join(im.cachePath, "tagcache")
 			os.Remove(path)
 		}
 	}
 
+	// anything that was not acknowledged stays in the write-ahead log and is replayed on the next start
+	if im.wal != nil {
+		if err := im.wal.Close(); err != nil {
+			im.Error("failed to close write-ahead log", log.KVErr(err))
+		} else if im.wal.Pending() == 0 {
+			os.Remove(filepath.Join(im.cachePath, "tagcache"))
+		}
+	}
+
 	//everyone is dead, clean up
 	close(im.upChan)
 	return nil
@@ -619,8 +737,8 @@ func (im *IngestMuxer) ingesterStateDirty() (dirty bool) {
 		dirty = true
 	} else if im.ingesterStateUpdated {
 		dirty = true
-	} else if im.cacheEnabled {
-		sz := uint64(im.cache.Size()) + uint64(im.bcache.Size())
+	} else if im.cacheEnabled || im.wal != nil {
+		sz := uint64(im.cachedSize())
 		if im.ingesterState.CacheSize != sz {
 			dirty = true
 		}
@@ -640,12 +758,12 @@ func (im *IngestMuxer) getIngesterState(lastPush time.Time, lastEntryCount uint6
 	im.mtx.Lock()
 
 	// update the cache stats real quick
-	if im.cacheEnabled {
-		im.ingesterState.CacheSize = uint64(im.cache.Size())
-		im.ingesterState.CacheSize += uint64(im.bcache.Size())
+	if im.cacheEnabled || im.wal != nil {
+		im.ingesterState.CacheSize = uint64(im.cachedSize())
 	}
 	im.ingesterState.Uptime = time.Since(im.start)
 	im.ingesterState.Tags = im.tags
+	im.ingesterState.TagThrottle = im.tagPols.state()
 
 	// The ingesterState object is of type ingest.IngesterState which contains a map of children.
 	// You must make a deep copy (which is what Copy does) if you are going to concurrently read and write it.
@@ -719,6 +837,8 @@ func (im *IngestMuxer) WillBlock() bool {
 		return true // we dead jim
 	} else if nHot > 0 {
 		return false //writer is alive
+	} else if im.wal != nil {
+		return im.cacheSize > 0 && im.wal.Size() >= int64(im.cacheSize)
 	} else if !im.cacheEnabled {
 		return true // no writers alive and cache is not enabled
 	}
@@ -728,11 +848,22 @@ func (im *IngestMuxer) WillBlock() bool {
 		return true
 	} else if im.bcache.Size() >= im.cacheSize {
 		return true
+	} else if im.prio != nil && (im.prio.cache.Size() >= im.cacheSize || im.prio.bcache.Size() >= im.cacheSize) {
+		return true
 	}
 
 	return false
 }
 
+// ReloadClientCertificate re-reads the TLS client certificate and key pair, new connections present
+// the reloaded certificate.  The certificate is also reloaded automatically when the files change.
+func (im *IngestMuxer) ReloadClientCertificate() error {
+	if im.clientCert == nil {
+		return nil
+	}
+	return im.clientCert.Reload()
+}
+
 func (im *IngestMuxer) SetRawConfiguration(obj interface{}) (err error) {
 	if obj == nil {
 		return
@@ -740,6 +871,8 @@ func (im *IngestMuxer) SetRawConfiguration(obj interface{}) (err error) {
 	var msg []byte
 	if msg, err = json.Marshal(obj); err != nil {
 		return
+	} else if msg, err = config.MaskSecrets(msg); err != nil {
+		return
 	}
 	im.mtx.Lock()
 	im.ingesterState.Configuration = json.RawMessage(msg)
@@ -839,6 +972,8 @@ func (im *IngestMuxer) NegotiateTag(name string) (tg entry.EntryTag, err error)
 	tg = entry.EntryTag(tagNext + 1)
 	im.tagMap[name] = tg
 	im.tc.add(tg)
+	im.registerGroupTag(name, tg)
+	im.tagPols.register(name, tg)
 
 	// update the tag cache
 	if im.cachePath != "" {
@@ -869,6 +1004,20 @@ func (im *IngestMuxer) Sync(to time.Duration) error {
 }
 
 func (im *IngestMuxer) SyncContext(ctx context.Context, to time.Duration) error {
+	ts := time.Now()
+	if err := im.syncConnections(ctx, to); err != nil {
+		return err
+	} else if im.wal != nil {
+		//everything has been acknowledged, make sure the write-ahead log knows it
+		return im.wal.Flush()
+	} else if im.rt == nil {
+		return nil
+	}
+	//in replication mode a sync also means every entry has reached quorum
+	return im.waitForQuorum(ctx, ts, to)
+}
+
+func (im *IngestMuxer) syncConnections(ctx context.Context, to time.Duration) error {
 	if atomic.LoadInt32(&im.connHot) == 0 && !im.cacheEnabled {
 		return ErrAllConnsDown
 	}
@@ -885,7 +1034,7 @@ func (im *IngestMuxer) SyncContext(ctx context.Context, to time.Duration) error
 			return err
 		}
 		time.Sleep(10 * time.Millisecond)
-		if len(im.eChanOut) == 0 && len(im.bChanOut) == 0 && len(im.eChan) == 0 && len(im.bChan) == 0 {
+		if im.pipelinesEmpty() {
 			// all pipelines are empty
 			break
 		}
@@ -997,8 +1146,8 @@ func (im *IngestMuxer) WaitForHotContext(ctx context.Context, to time.Duration)
 	} else if cnt > 0 {
 		return nil
 	}
-	//if we have a cache enabled in always mode, just short circuit out
-	if im.cacheEnabled && im.cacheAlways {
+	//if we have a cache enabled in always mode or a write-ahead log, just short circuit out
+	if (im.cacheEnabled && im.cacheAlways) || im.wal != nil {
 		return nil
 	}
 
@@ -1061,7 +1210,12 @@ func (im *IngestMuxer) Hot() (int, error) {
 }
 
 // goHot is a convenience function used by routines when they become active
-func (im *IngestMuxer) goHot() {
+func (im *IngestMuxer) goHot(grp *targetGroup, rep *replica) {
+	if grp != nil {
+		im.groupHot(grp)
+	} else if rep != nil {
+		im.replicaHot(rep)
+	}
 	atomic.AddInt32(&im.connDead, -1)
 	//attempt a single on going hot, but don't block
 	//increment the hot counter
@@ -1070,6 +1224,9 @@ func (im *IngestMuxer) goHot() {
 		if im.cacheEnabled && !im.cacheAlways {
 			im.cache.CacheStop()
 			im.bcache.CacheStop()
+			if im.prio != nil {
+				im.prio.cacheStop()
+			}
 		}
 	}
 	select {
@@ -1079,13 +1236,21 @@ func (im *IngestMuxer) goHot() {
 }
 
 // goDead is a convenience function used by routines when they become dead
-func (im *IngestMuxer) goDead() {
+func (im *IngestMuxer) goDead(grp *targetGroup, rep *replica) {
+	if grp != nil {
+		im.groupDead(grp)
+	} else if rep != nil {
+		im.replicaDead(rep)
+	}
 	//decrement the hot counter
 	if atomic.AddInt32(&im.connHot, -1) == 0 {
 		// if the cache is enabled AND we are not in always cache mode start things
 		if im.cacheEnabled && !im.cacheAlways {
 			im.cache.CacheStart()
 			im.bcache.CacheStart()
+			if im.prio != nil {
+				im.prio.cacheStart()
+			}
 		}
 	}
 	atomic.AddInt32(&im.connDead, 1)
@@ -1141,13 +1306,21 @@ func (im *IngestMuxer) WriteEntry(e *entry.Entry) error {
 	if im.attachActive {
 		im.attacher.Attach(e)
 	}
+	if im.replicas != nil {
+		return im.replicate(nil, nil, []*entry.Entry{e}, false)
+	} else if im.wal != nil {
+		return im.walAppend(nil, nil, []*entry.Entry{e})
+	}
+	//the relay routines may rewrite the tag once they have the entry
+	tag, size := e.Tag, uint64(len(e.Data))
 	select {
-	case im.eChan <- e:
+	case im.entryChan(tag) <- e:
 	case <-im.writeBarrier:
 		return ErrNotRunning
 	}
 	im.ingesterState.Entries++
-	im.ingesterState.Size += uint64(len(e.Data))
+	im.ingesterState.Size += size
+	im.tagStats.add(tag, size)
 	return nil
 }
 
@@ -1169,10 +1342,17 @@ func (im *IngestMuxer) WriteEntryContext(ctx context.Context, e *entry.Entry) er
 	if im.attachActive {
 		im.attacher.Attach(e)
 	}
+	if im.replicas != nil {
+		return im.replicate(ctx, nil, []*entry.Entry{e}, false)
+	} else if im.wal != nil {
+		return im.walAppend(ctx, nil, []*entry.Entry{e})
+	}
+	tag, size := e.Tag, uint64(len(e.Data))
 	select {
-	case im.eChan <- e:
+	case im.entryChan(tag) <- e:
 		im.ingesterState.Entries++
-		im.ingesterState.Size += uint64(len(e.Data))
+		im.ingesterState.Size += size
+		im.tagStats.add(tag, size)
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-im.writeBarrier:
@@ -1200,10 +1380,18 @@ func (im *IngestMuxer) WriteEntryTimeout(e *entry.Entry, d time.Duration) (err e
 		im.attacher.Attach(e)
 	}
 	tmr := time.NewTimer(d)
+	defer tmr.Stop()
+	if im.replicas != nil {
+		return im.replicate(nil, tmr.C, []*entry.Entry{e}, false)
+	} else if im.wal != nil {
+		return im.walAppend(nil, tmr.C, []*entry.Entry{e})
+	}
+	tag, size := e.Tag, uint64(len(e.Data))
 	select {
-	case im.eChan <- e:
+	case im.entryChan(tag) <- e:
 		im.ingesterState.Entries++
-		im.ingesterState.Size += uint64(len(e.Data))
+		im.ingesterState.Size += size
+		im.tagStats.add(tag, size)
 	case <-tmr.C:
 		err = ErrWriteTimeout
 	case <-im.writeBarrier:
@@ -1240,14 +1428,18 @@ func (im *IngestMuxer) WriteBatch(b []*entry.Entry) error {
 			im.attacher.Attach(e)
 		}
 	}
-	select {
-	case im.bChan <- b:
-	case <-im.writeBarrier:
-		return ErrNotRunning
+	if im.replicas != nil {
+		return im.replicate(nil, nil, b, true)
+	} else if im.wal != nil {
+		return im.walAppend(nil, nil, b)
 	}
-	im.ingesterState.Entries += uint64(len(b))
-	for i := range b {
-		im.ingesterState.Size += uint64(len(b[i].Data))
+	for _, gb := range im.splitBatch(b) {
+		select {
+		case gb.ch <- gb.ents:
+			im.countBatch(gb.ents)
+		case <-im.writeBarrier:
+			return ErrNotRunning
+		}
 	}
 	return nil
 }
@@ -1255,6 +1447,9 @@ func (im *IngestMuxer) WriteBatch(b []*entry.Entry) error {
 // WriteBatchContext puts a slice of entries into the queue to be sent out by the first
 // available entry writer routine.  The entry writer routines will consume the
 // entire slice, so extremely large slices will go to a single indexer.
+// A batch that spans target groups or priority lanes is queued in parts, the context is
+// only honored until the first part is queued so a cancelled write never leaves part
+// of the batch behind.
 // if a cancellation context isn't needed, use WriteBatch
 func (im *IngestMuxer) WriteBatchContext(ctx context.Context, b []*entry.Entry) error {
 	if len(b) == 0 {
@@ -1283,20 +1478,38 @@ func (im *IngestMuxer) WriteBatchContext(ctx context.Context, b []*entry.Entry)
 			im.attacher.Attach(e)
 		}
 	}
-	select {
-	case im.bChan <- b:
-		im.ingesterState.Entries += uint64(len(b))
-		for i := range b {
-			im.ingesterState.Size += uint64(len(b[i].Data))
+	if im.replicas != nil {
+		return im.replicate(ctx, nil, b, true)
+	} else if im.wal != nil {
+		return im.walAppend(ctx, nil, b)
+	}
+	done := ctx.Done()
+	for _, gb := range im.splitBatch(b) {
+		select {
+		case gb.ch <- gb.ents:
+			im.countBatch(gb.ents)
+			//the rest of the batch has to follow, the caller can't tell what was written otherwise
+			done = nil
+		case <-done:
+			return ctx.Err()
+		case <-im.writeBarrier:
+			return ErrNotRunning
 		}
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-im.writeBarrier:
-		return ErrNotRunning
 	}
 	return nil
 }
 
+// countBatch adds a batch that was queued to the ingester and tag stats
+func (im *IngestMuxer) countBatch(ents []*entry.Entry) {
+	var size uint64
+	for i := range ents {
+		size += uint64(len(ents[i].Data))
+	}
+	im.ingesterState.Entries += uint64(len(ents))
+	im.ingesterState.Size += size
+	im.tagStats.addBatch(im.tagStats.sizes(ents))
+}
+
 // Write puts together the arguments to create an entry and writes it
 // to the queue to be sent out by the first available
 // entry writer routine, if all routines are dead, THIS WILL BLOCK once the
@@ -1341,9 +1554,19 @@ func (im *IngestMuxer) WriteContext(ctx context.Context, tm entry.Timestamp, tag
 func (im *IngestMuxer) DittoWriteContext(ctx context.Context, b []entry.Entry) error {
 	var err error
 	var wg sync.WaitGroup
+	if im.replicas != nil {
+		//a ditto block is handed to a single connection, it cannot fan out to every replica
+		return ErrDittoReplication
+	} else if im.wal != nil {
+		//ditto blocks bypass the feeders, so they would never be logged
+		return ErrDittoWAL
+	}
 	for i := range b {
 		if b[i].Tag != entry.GravwellTagId && !im.tc.has(b[i].Tag) {
 			return ErrUnknownTag
+		} else if im.tagGroup(b[i].Tag) != nil {
+			//ditto blocks go out whole on whichever connection picks them up
+			return ErrDittoGroupedTag
 		}
 	}
 	cb := func(e error) {
@@ -1399,7 +1622,7 @@ func (im *IngestMuxer) getNewConnSet(csc chan connSet, connFailure chan bool, or
 			return
 		}
 		//attempt to clear the emergency queue and throw at our new connection
-		if !im.eq.clear(nc.ig, nc.tt) || nc.ig.Sync() != nil {
+		if !im.clearEmergencyQueues(nc) || nc.ig.Sync() != nil {
 			//try to send, if we can't just roll on
 			select {
 			case connFailure <- shouldSleep:
@@ -1426,22 +1649,28 @@ func tickerInterval() time.Duration {
 
 func (im *IngestMuxer) shouldSched() (ok bool) {
 	//if pipelines are empty, schedule ourselves so that we can get a better distribution of entries
-	if x := len(im.igst); x == 1 {
-		//only one connection, do not schedule ever
+	if x := len(im.igst); x == 1 || im.replicas != nil {
+		//only one connection or every connection has its own feeders, do not schedule ever
 		return
 	}
 	//there is more than one connection
 	if im.cacheEnabled {
 		//check what the cache says
 		ok = im.cache.BufferSize() == 0 && im.bcache.BufferSize() == 0
+		if ok && im.prio != nil {
+			ok = im.prio.cache.BufferSize() == 0 && im.prio.bcache.BufferSize() == 0
+		}
 	} else {
 		//no cache, so just check the channels
 		ok = len(im.eChanOut) == 0 && len(im.bChanOut) == 0
+		if ok && im.prio != nil {
+			ok = len(im.prio.eChanOut) == 0 && len(im.prio.bChanOut) == 0
+		}
 	}
 	return
 }
 
-func (im *IngestMuxer) writeRelayRoutine(csc chan connSet, connFailure chan bool) {
+func (im *IngestMuxer) writeRelayRoutine(csc chan connSet, connFailure chan bool, grp *targetGroup, rep *replica) {
 	tmr := time.NewTimer(tickerInterval())
 	defer tmr.Stop()
 	defer close(connFailure)
@@ -1460,11 +1689,63 @@ func (im *IngestMuxer) writeRelayRoutine(csc chan connSet, connFailure chan bool
 	bC := im.bChanOut
 	dC := im.dittoChan // not cached
 
+	//members of a target group also service the entries that are restricted to the group
+	//and replicas service their own copies of every entry
+	var gEC, gBC chan interface{}
+	if grp != nil {
+		gEC = grp.eChanOut
+		gBC = grp.bChanOut
+	} else if rep != nil {
+		gEC = rep.eChanOut
+		gBC = rep.bChanOut
+	}
+	//high priority tags have their own lane which is emptied before anything else is serviced
+	pEC, pBC := im.laneChans(rep)
+
+	//rate limited tags are held back here rather than at the writer so they never hold up other tags
+	thr := newThrottle(im.tagPols, rep)
+	defer thr.flush(im)
+	send := func(e *entry.Entry, b []*entry.Entry) bool {
+		if e != nil {
+			nc, ok = im.relayEntry(nc, e, csc, connFailure)
+		} else if len(b) > 0 {
+			nc, ok = im.relayBatch(nc, b, csc, connFailure)
+		} else {
+			ok = true
+		}
+		return ok
+	}
+	relay := func(e *entry.Entry, b []*entry.Entry) bool {
+		return send(thr.hold(e, b))
+	}
+
+	//drainPriority relays everything waiting in the priority lane, the lower lanes call it again
+	//once they hold an item so high priority entries that showed up during the select still go first
+	drainPriority := func() bool {
+		if pEC == nil && pBC == nil {
+			return true
+		}
+		return relayPriority(&pEC, &pBC, thr, relay)
+	}
+
 	var lastStatePushEntryCount uint64
 	var lastStatePush time.Time
 
 inputLoop:
 	for {
+		if !drainPriority() {
+			break inputLoop
+		} else if eC == nil && bC == nil && dC == nil && gEC == nil && gBC == nil && pEC == nil && pBC == nil {
+			return
+		}
+		//stop pulling from the feeders while the throttle is holding all it can
+		lEC, lBC, lgEC, lgBC, lpEC, lpBC := eC, bC, gEC, gBC, pEC, pBC
+		if thr.full() {
+			lEC, lBC, lgEC, lgBC = nil, nil, nil, nil
+			if thr.prioFull() {
+				lpEC, lpBC = nil, nil
+			}
+		}
 		select {
 		case <-im.ctx.Done():
 			//the caller will detect that we exited and will take care of getting outstanding entries
@@ -1479,7 +1760,7 @@ inputLoop:
 		case db, ok := <-dC:
 			if !ok {
 				dC = nil
-				if eC == nil && bC == nil {
+				if eC == nil && bC == nil && gEC == nil && gBC == nil && pEC == nil && pBC == nil {
 					return
 				}
 				continue
@@ -1518,143 +1799,111 @@ inputLoop:
 
 			// let somebody else have a turn
 			runtime.Gosched()
-		case ee, ok := <-eC:
+		case ee, ok := <-lEC:
 			if !ok {
 				eC = nil
-				if bC == nil && dC == nil {
+				if bC == nil && dC == nil && gEC == nil && gBC == nil && pEC == nil && pBC == nil {
 					return
 				}
 				continue
 			}
-			if ee == nil {
+			e := rep.feederEntry(ee)
+			if e == nil {
 				continue
+			} else if !drainPriority() {
+				im.recycleEntry(e, rep)
+				break inputLoop
 			}
-
-			e := ee.(*entry.Entry)
-
-			if ttag, err = nc.translateTag(e.Tag); err != nil {
-				// If the ingest muxer has no idea what this tag is, drop it and notify
-				if name, ok := im.LookupTag(e.Tag); !ok {
-					//we have controls in the muxer to prevent this, this shouldn't actually be possible
-					im.Error("Got entry tagged with completely unknown intermediate tag, dropping it",
-						log.KV("tagvalue", e.Tag),
-						log.KV("ingester", im.name),
-						log.KV("ingesteruuid", im.uuid),
-						log.KVErr(err),
-					)
-				} else {
-					im.Info("Got entry with new tag, need to renegotiate connection",
-						log.KV("tag", name),
-						log.KV("tagvalue", e.Tag),
-						log.KV("ingester", im.name),
-						log.KV("ingesteruuid", im.uuid),
-						log.KVErr(err),
-					)
-					// Could not translate, but it's a valid tag the muxer has seen before.
-					// We need to push this to the equeue and reconnect
-					// so we get the correct tag set.
-					// DO NOT reverse translate, muxer knows about the tag
-					im.recycleEntry(e)
-				}
-				im.syncAndCloseConnection(nc)
-				if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
-					break inputLoop
+			if !relay(e, nil) {
+				break inputLoop
+			}
+		case bb, ok := <-lBC:
+			if !ok {
+				bC = nil
+				if eC == nil && dC == nil && gEC == nil && gBC == nil && pEC == nil && pBC == nil {
+					return
 				}
-				continue inputLoop
+				continue
 			}
-			e.Tag = ttag
-
-			if len(e.SRC) == 0 {
-				e.SRC = nc.src
+			b := rep.feederBatch(bb)
+			if len(b) == 0 {
+				continue
+			} else if !drainPriority() {
+				im.recycleEntryBatch(b, rep)
+				break inputLoop
 			}
-			if err = nc.ig.WriteEntry(e); err != nil {
-				e.Tag = nc.tt.reverse(e.Tag)
-				im.recycleEntry(e)
-				im.syncAndCloseConnection(nc)
-				if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
-					break inputLoop
+			if !relay(nil, b) {
+				break inputLoop
+			}
+		case ee, ok := <-lgEC:
+			if !ok {
+				gEC = nil
+				if eC == nil && bC == nil && dC == nil && gBC == nil && pEC == nil && pBC == nil {
+					return
 				}
-				continue inputLoop
+				continue
+			}
+			e := rep.feederEntry(ee)
+			if e == nil {
+				continue
+			} else if !drainPriority() {
+				im.recycleEntry(e, rep)
+				break inputLoop
 			}
-			//hack to get better distribution across connections in an muxer
-			if im.shouldSched() {
-				runtime.Gosched()
+			if !relay(e, nil) {
+				break inputLoop
 			}
-		case bb, ok := <-bC:
+		case bb, ok := <-lgBC:
 			if !ok {
-				bC = nil
-				if eC == nil && dC == nil {
+				gBC = nil
+				if eC == nil && bC == nil && dC == nil && gEC == nil && pEC == nil && pBC == nil {
 					return
 				}
 				continue
 			}
-			if bb == nil {
+			b := rep.feederBatch(bb)
+			if len(b) == 0 {
 				continue
+			} else if !drainPriority() {
+				im.recycleEntryBatch(b, rep)
+				break inputLoop
 			}
-
-			b := bb.([]*entry.Entry)
-			for i := range b {
-				if b[i] != nil {
-					if ttag, err = nc.translateTag(b[i].Tag); err != nil {
-						if name, ok := im.LookupTag(b[i].Tag); !ok {
-							//we have controls in the muxer to prevent this, this shouldn't actually be possible
-							im.Error("Got entry tagged with completely unknown intermediate tag, dropping it",
-								log.KV("tagvalue", b[i].Tag),
-								log.KV("ingester", im.name),
-								log.KV("ingesteruuid", im.uuid),
-								log.KVErr(err),
-							)
-							//discard this entry, this isn't real and there is no way to get here
-							b[i] = nil //this is safe, we check for this everywhere
-							// first, reverse anything we've translated already
-							for j := 0; j < i; j++ {
-								b[j].Tag = nc.tt.reverse(b[j].Tag)
-							}
-							im.recycleEntryBatch(b) //recycle and save what we can
-						} else {
-							im.Info("Got entry with new tag, need to renegotiate connection",
-								log.KV("tag", name),
-								log.KV("tagvalue", b[i].Tag),
-								log.KV("ingester", im.name),
-								log.KV("ingesteruuid", im.uuid),
-								log.KVErr(err),
-							)
-							// Could not translate! We need to push this to the equeue and reconnect
-							// so we get the correct tag set.
-
-							// first, reverse anything we've translated already
-							for j := 0; j < i; j++ {
-								b[j].Tag = nc.tt.reverse(b[j].Tag)
-							}
-							im.recycleEntryBatch(b)
-						}
-						im.syncAndCloseConnection(nc)
-						if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
-							break inputLoop
-						}
-						continue inputLoop
-					}
-					b[i].Tag = ttag
-
-					if len(b[i].SRC) == 0 {
-						b[i].SRC = nc.src
-					}
-				}
+			if !relay(nil, b) {
+				break inputLoop
 			}
-			var n int
-			if n, err = nc.ig.writeBatchEntry(b); err != nil {
-				for i := n; i < len(b); i++ {
-					b[i].Tag = nc.tt.reverse(b[i].Tag)
+		case ee, ok := <-lpEC:
+			if !ok {
+				pEC = nil
+				if eC == nil && bC == nil && dC == nil && gEC == nil && gBC == nil && pBC == nil {
+					return
 				}
-				im.recycleEntryBatch(b[n:])
-				im.syncAndCloseConnection(nc)
-				if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
-					break inputLoop
+				continue
+			}
+			e := rep.feederEntry(ee)
+			if e == nil {
+				continue
+			}
+			if !relay(e, nil) {
+				break inputLoop
+			}
+		case bb, ok := <-lpBC:
+			if !ok {
+				pBC = nil
+				if eC == nil && bC == nil && dC == nil && gEC == nil && gBC == nil && pEC == nil {
+					return
 				}
+				continue
+			}
+			b := rep.feederBatch(bb)
+			if len(b) == 0 {
+				continue
 			}
-			//hack to get better distribution across connections in an muxer
-			if im.shouldSched() {
-				runtime.Gosched()
+			if !relay(nil, b) {
+				break inputLoop
+			}
+		case <-thr.C():
+			if !thr.release(send) {
+				break inputLoop
 			}
 		case tnc, ok = <-csc: //in case we get an unexpected new connection
 			//because this is unexpected
@@ -1697,7 +1946,7 @@ inputLoop:
 			}
 
 			//then we try to clear the emergency queue
-			if !im.eq.clear(nc.ig, nc.tt) {
+			if !im.clearEmergencyQueues(nc) {
 				//treat this as failure, sync and close the connection
 				im.syncAndCloseConnection(nc)
 				if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
@@ -1710,6 +1959,127 @@ inputLoop:
 	}
 }
 
+// relayEntry translates and writes a single entry to the current connection.  If the write fails the entry
+// is recycled and we attempt to get a new connection, the returned connSet should be used from here on out.
+// A false return means that no new connection could be acquired and the relay routine should exit.
+func (im *IngestMuxer) relayEntry(nc connSet, e *entry.Entry, csc chan connSet, connFailure chan bool) (connSet, bool) {
+	var ttag entry.EntryTag
+	var err error
+	if ttag, err = nc.translateTag(e.Tag); err != nil {
+		// If the ingest muxer has no idea what this tag is, drop it and notify
+		if name, ok := im.LookupTag(e.Tag); !ok {
+			//we have controls in the muxer to prevent this, this shouldn't actually be possible
+			im.Error("Got entry tagged with completely unknown intermediate tag, dropping it",
+				log.KV("tagvalue", e.Tag),
+				log.KV("ingester", im.name),
+				log.KV("ingesteruuid", im.uuid),
+				log.KVErr(err),
+			)
+			im.walDiscard(e)
+		} else {
+			im.Info("Got entry with new tag, need to renegotiate connection",
+				log.KV("tag", name),
+				log.KV("tagvalue", e.Tag),
+				log.KV("ingester", im.name),
+				log.KV("ingesteruuid", im.uuid),
+				log.KVErr(err),
+			)
+			// Could not translate, but it's a valid tag the muxer has seen before.
+			// We need to push this to the equeue and reconnect
+			// so we get the correct tag set.
+			// DO NOT reverse translate, muxer knows about the tag
+			im.recycleEntry(e, nc.rep)
+		}
+		im.syncAndCloseConnection(nc)
+		return im.getNewConnSet(csc, connFailure, false, false)
+	}
+	e.Tag = ttag
+
+	if len(e.SRC) == 0 {
+		e.SRC = nc.src
+	}
+	if err = nc.ig.WriteEntry(e); err != nil {
+		e.Tag = nc.tt.reverse(e.Tag)
+		im.recycleEntry(e, nc.rep)
+		im.syncAndCloseConnection(nc)
+		return im.getNewConnSet(csc, connFailure, false, false)
+	}
+	//hack to get better distribution across connections in an muxer
+	if im.shouldSched() {
+		runtime.Gosched()
+	}
+	return nc, true
+}
+
+// relayBatch translates and writes a batch of entries to the current connection, the semantics match relayEntry
+func (im *IngestMuxer) relayBatch(nc connSet, b []*entry.Entry, csc chan connSet, connFailure chan bool) (connSet, bool) {
+	var ttag entry.EntryTag
+	var err error
+	var ok bool
+	for i := range b {
+		if b[i] != nil {
+			if ttag, err = nc.translateTag(b[i].Tag); err != nil {
+				if name, ok := im.LookupTag(b[i].Tag); !ok {
+					//we have controls in the muxer to prevent this, this shouldn't actually be possible
+					im.Error("Got entry tagged with completely unknown intermediate tag, dropping it",
+						log.KV("tagvalue", b[i].Tag),
+						log.KV("ingester", im.name),
+						log.KV("ingesteruuid", im.uuid),
+						log.KVErr(err),
+					)
+					//discard this entry, this isn't real and there is no way to get here
+					im.walDiscard(b[i])
+					b[i] = nil //this is safe, we check for this everywhere
+					// first, reverse anything we've translated already
+					for j := 0; j < i; j++ {
+						b[j].Tag = nc.tt.reverse(b[j].Tag)
+					}
+					im.recycleEntryBatch(b, nc.rep) //recycle and save what we can
+				} else {
+					im.Info("Got entry with new tag, need to renegotiate connection",
+						log.KV("tag", name),
+						log.KV("tagvalue", b[i].Tag),
+						log.KV("ingester", im.name),
+						log.KV("ingesteruuid", im.uuid),
+						log.KVErr(err),
+					)
+					// Could not translate! We need to push this to the equeue and reconnect
+					// so we get the correct tag set.
+
+					// first, reverse anything we've translated already
+					for j := 0; j < i; j++ {
+						b[j].Tag = nc.tt.reverse(b[j].Tag)
+					}
+					im.recycleEntryBatch(b, nc.rep)
+				}
+				im.syncAndCloseConnection(nc)
+				return im.getNewConnSet(csc, connFailure, false, false)
+			}
+			b[i].Tag = ttag
+
+			if len(b[i].SRC) == 0 {
+				b[i].SRC = nc.src
+			}
+		}
+	}
+	var n int
+	if n, err = nc.ig.writeBatchEntry(b); err != nil {
+		for i := n; i < len(b); i++ {
+			b[i].Tag = nc.tt.reverse(b[i].Tag)
+		}
+		im.recycleEntryBatch(b[n:], nc.rep)
+		im.syncAndCloseConnection(nc)
+		if nc, ok = im.getNewConnSet(csc, connFailure, false, false); !ok {
+			return nc, false
+		}
+	}
+	//hack to get better distribution across connections in an muxer
+	if im.shouldSched() {
+		runtime.Gosched()
+	}
+	return nc, true
+}
+
 func (im *IngestMuxer) syncAndCloseConnection(nc connSet) {
 	nc.ig.syncTimeout(connectionShutdownSyncTimeout)
 	nc.ig.Close()
@@ -1723,7 +2093,7 @@ func (im *IngestMuxer) recycleConnection(nc connSet) {
 			ents[i].Tag = nc.tt.reverse(ents[i].Tag)
 		}
 	}
-	im.recycleEntryBatch(ents)
+	im.recycleEntryBatch(ents, nc.rep)
 }
 
 // connRoutine starts up the entry relay routine, then sits waiting to
@@ -1753,7 +2123,9 @@ func (im *IngestMuxer) connRoutine(igIdx int) {
 	ncc := make(chan connSet, 1)
 	defer close(ncc)
 
-	go im.writeRelayRoutine(ncc, connErrNotif)
+	grp := im.destGroup(igIdx)
+	rep := im.getReplica(igIdx)
+	go im.writeRelayRoutine(ncc, connErrNotif, grp, rep)
 
 	connErrNotif <- false // no sleep, get on it
 
@@ -1765,7 +2137,7 @@ func (im *IngestMuxer) connRoutine(igIdx int) {
 		//if there is a cache enabled we will drop it into there when the muxer shuts down
 		if igst != nil {
 			igst.Close()
-			im.goDead() //let the world know of our failures
+			im.goDead(grp, rep) //let the world know of our failures
 
 			//pull any entries out of the ingest connection and put them into the emergency queue
 			ents := igst.ejectOutstandingEntries()
@@ -1774,7 +2146,7 @@ func (im *IngestMuxer) connRoutine(igIdx int) {
 					ents[i].Tag = tt.reverse(ents[i].Tag)
 				}
 			}
-			im.recycleEntryBatch(ents)
+			im.recycleEntryBatch(ents, rep)
 			im.mtx.Lock()
 			im.igst[igIdx] = nil
 			im.tagTranslators[igIdx] = nil
@@ -1819,45 +2191,76 @@ func (im *IngestMuxer) connRoutine(igIdx int) {
 			return
 		}
 
+		igst.setAckLatency(im.ackLatency)
+		igst.setConfigOverlayCallback(im.configOverlayReceived)
+		if rep != nil {
+			igst.setAckCallback(rep.acked)
+		} else if im.wal != nil {
+			igst.setAckCallback(im.walAcked)
+		}
+
 		im.mtx.Lock()
 		im.igst[igIdx] = igst
 		im.tagTranslators[igIdx] = tt
 		im.mtx.Unlock()
 
-		im.goHot()
+		im.goHot(grp, rep)
 		ncc <- connSet{
 			dst: dst.Address,
 			src: src,
 			ig:  igst,
 			tt:  tt,
+			grp: grp,
+			rep: rep,
 		}
 	}
 }
 
-func (im *IngestMuxer) recycleEntryBatch(ents []*entry.Entry) {
+func (im *IngestMuxer) recycleEntryBatch(ents []*entry.Entry, rep *replica) {
 	if len(ents) == 0 {
 		return
+	} else if rep != nil {
+		//replica copies can only ever go back to the same replica
+		rep.recycle(ents)
+		return
 	}
 
-	//we wait for up to one second to push values onto feeder channels
-	//if nothing eats them by then, we drop them into the emergency queue
-	//and bail out
-	tmr := time.NewTimer(recycleTimeout)
-	defer tmr.Stop()
+	//entries restricted to a target group must go back to the group
+	for _, gb := range im.splitBatch(ents) {
+		eq := im.eq
+		if gb.grp != nil {
+			eq = gb.grp.eq
+		}
 
-	select {
-	case <-tmr.C:
-		im.eq.push(nil, ents)
-	case im.bChan <- ents:
+		//we wait for up to one second to push values onto feeder channels
+		//if nothing eats them by then, we drop them into the emergency queue
+		//and bail out
+		tmr := time.NewTimer(recycleTimeout)
+		select {
+		case <-tmr.C:
+			eq.push(nil, gb.ents)
+		case gb.ch <- gb.ents:
+		}
+		tmr.Stop()
 	}
 }
 
-func (im *IngestMuxer) recycleEntry(ent *entry.Entry) {
+func (im *IngestMuxer) recycleEntry(ent *entry.Entry, rep *replica) {
 	if ent == nil {
 		return
-	} else if len(im.dests) == 1 || atomic.LoadInt32(&im.connHot) == 0 {
+	} else if rep != nil {
+		rep.recycle([]*entry.Entry{ent})
+		return
+	}
+	eq, eC := im.eq, im.entryChan(ent.Tag)
+	single, hot := len(im.dests) == 1, atomic.LoadInt32(&im.connHot)
+	if grp := im.tagGroup(ent.Tag); grp != nil {
+		eq, eC = grp.eq, grp.eChan
+		single, hot = grp.size == 1, atomic.LoadInt32(&grp.connHot)
+	}
+	if single || hot == 0 {
 		// no one can help us, just shove it in
-		im.eq.push(ent, nil)
+		eq.push(ent, nil)
 		return
 	}
 
@@ -1869,8 +2272,8 @@ func (im *IngestMuxer) recycleEntry(ent *entry.Entry) {
 
 	select {
 	case <-tmr.C:
-		im.eq.push(ent, nil)
-	case im.eChan <- ent:
+		eq.push(ent, nil)
+	case eC <- ent:
 	}
 }
 
@@ -1930,7 +2333,7 @@ loop:
 			log.KV("version", version.GetVersion()),
 			log.KV("ingesteruuid", im.uuid))
 		im.mtx.RLock()
-		if ig, err = initConnection(tgt, im.tags, im.pubKey, im.privKey, im.verifyCert, im.ctx); err != nil {
+		if ig, err = initConnection(tgt, im.tags, &TLSCerts{ClientCert: im.clientCert}, im.verifyCert, im.ctx); err != nil {
 			im.mtx.RUnlock()
 			if isFatalConnError(err) {
 				im.Error("fatal connection error",
@@ -2279,6 +2682,8 @@ type connSet struct {
 	tt  *tagTrans
 	dst string
 	src net.IP
+	grp *targetGroup // nil if the connection is not a member of a target group
+	rep *replica     // nil if the muxer is not in replication mode
 }
 
 func (nc connSet) translateTag(t entry.EntryTag) (rt entry.EntryTag, err error) {
@@ -2408,9 +2813,23 @@ func (tt *tagTrans) reverse(t entry.EntryTag) entry.EntryTag {
 	return 0
 }
 
-func getStreamConfig(cfg config.IngestStreamConfig) (sc StreamConfiguration) {
-	if cfg.Enable_Compression {
+func getStreamConfig(cfg config.IngestStreamConfig) (sc StreamConfiguration, err error) {
+	if !cfg.Enable_Compression {
+		return
+	}
+	//the config package owns the table of valid type names and levels
+	var typ string
+	if typ, err = config.VerifyCompression(cfg.Compression_Type, cfg.Compression_Level); err != nil {
+		return
+	} else if typ == `none` {
+		//an explicit none turns compression off even when it is enabled
+		return
+	} else if sc.Compression, err = ParseCompression(typ); err != nil {
+		return
+	} else if sc.Compression == CompressNone {
+		//compression is enabled but no type was specified, use the old default
 		sc.Compression = CompressSnappy
 	}
+	sc.CompressionLevel = int8(cfg.Compression_Level)
 	return
 }
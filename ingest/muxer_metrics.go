@@ -0,0 +1,141 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
+)
+
+const (
+	metricsPrefix = `gravwell_ingest_`
+)
+
+type tagCounter struct {
+	entries atomic.Uint64
+	bytes   atomic.Uint64
+}
+
+// tagStats counts entries and bytes handed to the muxer for each tag, a nil tagStats counts nothing
+type tagStats struct {
+	m sync.Map // entry.EntryTag -> *tagCounter
+}
+
+// tagSize is the tag and size of an entry, captured before the entry is handed to a relay
+// routine that may rewrite its tag
+type tagSize struct {
+	tag  entry.EntryTag
+	size uint64
+}
+
+func (ts *tagStats) add(tag entry.EntryTag, size uint64) {
+	if ts == nil {
+		return
+	}
+	v, ok := ts.m.Load(tag)
+	if !ok {
+		v, _ = ts.m.LoadOrStore(tag, &tagCounter{})
+	}
+	tc := v.(*tagCounter)
+	tc.entries.Add(1)
+	tc.bytes.Add(size)
+}
+
+// sizes captures the tags and sizes of a batch, it returns nil when metrics are disabled
+func (ts *tagStats) sizes(ents []*entry.Entry) (r []tagSize) {
+	if ts == nil {
+		return
+	}
+	r = make([]tagSize, len(ents))
+	for i, e := range ents {
+		r[i] = tagSize{tag: e.Tag, size: uint64(len(e.Data))}
+	}
+	return
+}
+
+func (ts *tagStats) addBatch(szs []tagSize) {
+	if ts == nil {
+		return
+	}
+	for _, v := range szs {
+		ts.add(v.tag, v.size)
+	}
+}
+
+// startMetrics fires up the metrics listener if one is configured, the caller must hold the muxer lock
+func (im *IngestMuxer) startMetrics() (err error) {
+	if im.metricsAddr == `` {
+		return
+	}
+	reg := metrics.NewRegistry()
+	reg.Register(metrics.CollectorFunc(im.collectMetrics))
+	//the default registry picks up preprocessor and ingester specific collectors
+	im.metricsSrv, err = metrics.Listen(im.metricsAddr, reg, metrics.Default)
+	return
+}
+
+func (im *IngestMuxer) stopMetrics() {
+	if im.metricsSrv != nil {
+		im.metricsSrv.Close()
+	}
+}
+
+func (im *IngestMuxer) collectMetrics(w *metrics.Writer) {
+	w.Gauge(metricsPrefix+`info`, `Ingester identity, the value is always 1`, 1,
+		metrics.L(`name`, im.name), metrics.L(`version`, im.version), metrics.L(`uuid`, im.uuid))
+	w.Gauge(metricsPrefix+`connections_hot`, `Indexer connections that are up`, float64(atomic.LoadInt32(&im.connHot)))
+	w.Gauge(metricsPrefix+`connections_dead`, `Indexer connections that are down`, float64(atomic.LoadInt32(&im.connDead)))
+
+	eqd := im.eq.len()
+	for _, grp := range im.groups {
+		eqd += grp.eq.len()
+	}
+	for _, rep := range im.replicas {
+		eqd += rep.eq.len()
+	}
+	w.Gauge(metricsPrefix+`emergency_queue_depth`, `Entries and blocks waiting in the emergency queues`, float64(eqd))
+
+	im.mtx.RLock()
+	var up time.Duration
+	if !im.start.IsZero() {
+		up = time.Since(im.start)
+	}
+	var cached int
+	if im.cacheEnabled || im.wal != nil {
+		cached = im.cachedSize()
+	}
+	names := make(map[entry.EntryTag]string, len(im.tagMap))
+	for k, v := range im.tagMap {
+		names[v] = k
+	}
+	im.mtx.RUnlock()
+	names[entry.GravwellTagId] = entry.GravwellTagName
+
+	w.Gauge(metricsPrefix+`uptime_seconds`, `Seconds since the muxer was started`, up.Seconds())
+	w.Gauge(metricsPrefix+`cache_size_bytes`, `Bytes held in the ingest cache`, float64(cached))
+
+	im.tagStats.m.Range(func(k, v any) bool {
+		tag := k.(entry.EntryTag)
+		name, ok := names[tag]
+		if !ok {
+			name = strconv.Itoa(int(tag))
+		}
+		tc := v.(*tagCounter)
+		w.Counter(metricsPrefix+`entries`, `Entries written to the muxer`, float64(tc.entries.Load()), metrics.L(`tag`, name))
+		w.Counter(metricsPrefix+`bytes`, `Entry data bytes written to the muxer`, float64(tc.bytes.Load()), metrics.L(`tag`, name))
+		return true
+	})
+
+	w.Histogram(metricsPrefix+`ack_latency_seconds`, `Time between sending an entry and the indexer confirming it`, im.ackLatency.Snapshot())
+}
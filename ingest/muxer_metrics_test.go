@@ -0,0 +1,83 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
+)
+
+func TestMuxerMetrics(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations:   []Target{ti.target()},
+		Tags:           []string{`foo`, `bar`},
+		IngesterName:   `metricstest`,
+		MetricsAddress: `127.0.0.1:0`,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 10
+	var batch []*entry.Entry
+	for i := 0; i < count; i++ {
+		batch = append(batch, &entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))})
+	}
+	if err = im.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	} else if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(`hello`)}); err != nil {
+		t.Fatal(err)
+	} else if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(`http://` + im.metricsSrv.Addr().String() + metrics.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{
+		`gravwell_ingest_info{name="metricstest",version="",uuid=""} 1`,
+		`gravwell_ingest_connections_hot 1`,
+		`gravwell_ingest_connections_dead 0`,
+		`gravwell_ingest_emergency_queue_depth 0`,
+		`gravwell_ingest_cache_size_bytes 0`,
+		`gravwell_ingest_entries_total{tag="foo"} 11`,
+		`gravwell_ingest_bytes_total{tag="foo"} 75`,
+		`gravwell_ingest_ack_latency_seconds_count 11`,
+	} {
+		if !strings.Contains(string(body), v+"\n") {
+			t.Fatalf("missing %q from metrics:\n%s", v, body)
+		}
+	}
+	if strings.Contains(string(body), `tag="bar"`) {
+		t.Fatalf("unused tag reported:\n%s", body)
+	}
+
+	if err = im.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = http.Get(`http://` + im.metricsSrv.Addr().String() + metrics.Path); err == nil {
+		t.Fatal("metrics listener still up after close")
+	}
+}
@@ -0,0 +1,552 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	testSecret = `testing`
+)
+
+// testIndexer is a bare bones indexer which accepts muxer connections and records what it is sent
+type testIndexer struct {
+	sync.Mutex
+	lst     net.Listener
+	tagBase entry.EntryTag
+	tags    map[entry.EntryTag]string
+	ents    map[string]int // data -> times seen
+	tagged  map[string]string
+	order   []string // data in the order it arrived
+	conns   []net.Conn
+	wg      sync.WaitGroup
+}
+
+func newTestIndexer(t *testing.T, tagBase entry.EntryTag) *testIndexer {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := &testIndexer{
+		lst:     lst,
+		tagBase: tagBase,
+		tags:    map[entry.EntryTag]string{},
+		ents:    map[string]int{},
+		tagged:  map[string]string{},
+	}
+	ti.wg.Add(1)
+	go ti.acceptRoutine()
+	return ti
+}
+
+func (ti *testIndexer) target() Target {
+	return Target{Address: `tcp://` + ti.lst.Addr().String(), Secret: testSecret}
+}
+
+func (ti *testIndexer) acceptRoutine() {
+	defer ti.wg.Done()
+	for {
+		conn, err := ti.lst.Accept()
+		if err != nil {
+			return
+		}
+		ti.Lock()
+		ti.conns = append(ti.conns, conn)
+		ti.Unlock()
+		ti.wg.Add(1)
+		go ti.connRoutine(conn)
+	}
+}
+
+func (ti *testIndexer) connRoutine(conn net.Conn) {
+	defer ti.wg.Done()
+	defer conn.Close()
+	if err := ti.handshake(conn); err != nil {
+		return
+	}
+	er, err := NewEntryReader(conn)
+	if err != nil {
+		return
+	}
+	defer er.Close()
+	if err = er.Start(); err != nil {
+		return
+	} else if err = er.SetupConnection(); err != nil {
+		return
+	} else if err = er.IngestOK(true); err != nil {
+		return
+	}
+	if err = er.ConfigureStream(); err != nil {
+		return
+	}
+	for {
+		ent, err := er.Read()
+		if err == ErrPendingDittoBlock {
+			var ents []*entry.Entry
+			if ents, err = er.GetPendingDittoBlock(); err != nil {
+				return
+			}
+			for _, e := range ents {
+				ti.record(e)
+			}
+			if err = er.AckDittoBlock(); err != nil {
+				return
+			}
+			continue
+		} else if err != nil {
+			return
+		}
+		ti.record(ent)
+	}
+}
+
+func (ti *testIndexer) record(ent *entry.Entry) {
+	ti.Lock()
+	ti.ents[string(ent.Data)]++
+	ti.tagged[string(ent.Data)] = ti.tags[ent.Tag]
+	ti.order = append(ti.order, string(ent.Data))
+	ti.Unlock()
+}
+
+func (ti *testIndexer) handshake(conn net.Conn) (err error) {
+	var auth AuthHash
+	var chal Challenge
+	var resp ChallengeResponse
+	var tr TagRequest
+	var state StateResponse
+	if auth, err = GenAuthHash(testSecret); err != nil {
+		return
+	} else if chal, err = NewChallenge(auth); err != nil {
+		return
+	} else if err = chal.Write(conn); err != nil {
+		return
+	} else if err = resp.Read(conn); err != nil {
+		return
+	} else if err = VerifyResponse(auth, chal, resp); err != nil {
+		return
+	}
+	state.ID = STATE_AUTHENTICATED
+	if err = state.Write(conn); err != nil {
+		return
+	} else if err = tr.Read(conn); err != nil {
+		return
+	}
+	tagResp := TagResponse{
+		Count: tr.Count,
+		Tags:  map[string]entry.EntryTag{},
+	}
+	ti.Lock()
+	for i, name := range tr.Tags {
+		//offset tag IDs so every indexer has a different mapping
+		tg := ti.tagBase + entry.EntryTag(i)
+		if name == entry.GravwellTagName {
+			tg = entry.GravwellTagId
+		}
+		tagResp.Tags[name] = tg
+		ti.tags[tg] = name
+	}
+	ti.Unlock()
+	if err = tagResp.Write(conn); err != nil {
+		return
+	}
+	return state.Read(conn) // hot
+}
+
+func (ti *testIndexer) count(data string) int {
+	ti.Lock()
+	defer ti.Unlock()
+	return ti.ents[data]
+}
+
+func (ti *testIndexer) tag(data string) string {
+	ti.Lock()
+	defer ti.Unlock()
+	return ti.tagged[data]
+}
+
+func (ti *testIndexer) arrived() []string {
+	ti.Lock()
+	defer ti.Unlock()
+	return append([]string(nil), ti.order...)
+}
+
+func (ti *testIndexer) Close() {
+	ti.lst.Close()
+	ti.Lock()
+	for _, c := range ti.conns {
+		c.Close()
+	}
+	ti.Unlock()
+	ti.wg.Wait()
+}
+
+// deadTarget hands back a target that will refuse connections
+func deadTarget(t *testing.T) Target {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lst.Addr().String()
+	lst.Close()
+	return Target{Address: `tcp://` + addr, Secret: testSecret}
+}
+
+func startTestMuxer(t *testing.T, c MuxerConfig) *IngestMuxer {
+	im, err := NewMuxer(c)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = im.Start(); err != nil {
+		t.Fatal(err)
+	} else if err = im.WaitForHot(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return im
+}
+
+func waitForHotCount(t *testing.T, im *IngestMuxer, cnt int) {
+	for i := 0; i < 500; i++ {
+		if n, err := im.Hot(); err != nil {
+			t.Fatal(err)
+		} else if n >= cnt {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d hot connections", cnt)
+}
+
+func TestReplicatedWrites(t *testing.T) {
+	tis := []*testIndexer{newTestIndexer(t, 1), newTestIndexer(t, 100)}
+	defer func() {
+		for _, ti := range tis {
+			ti.Close()
+		}
+	}()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{tis[0].target(), tis[1].target()},
+		Tags:         []string{`foo`, `bar`},
+		Replicate:    true,
+	})
+	defer im.Close()
+	waitForHotCount(t, im, 2)
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar, err := im.GetTag(`bar`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 100
+	var b []*entry.Entry
+	for i := 0; i < count; i++ {
+		e := &entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}
+		if i%2 == 0 {
+			if err = im.WriteEntry(e); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			e.Tag = bar
+			b = append(b, e)
+		}
+	}
+	if err = im.WriteBatch(b); err != nil {
+		t.Fatal(err)
+	}
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < count; i++ {
+		data := fmt.Sprintf("entry %d", i)
+		tag := `foo`
+		if i%2 != 0 {
+			tag = `bar`
+		}
+		for j, ti := range tis {
+			if n := ti.count(data); n != 1 {
+				t.Fatalf("indexer %d saw %q %d times", j, data, n)
+			} else if tg := ti.tag(data); tg != tag {
+				t.Fatalf("indexer %d got %q with tag %q != %q", j, data, tg, tag)
+			}
+		}
+	}
+	rs, err := im.ReplicationStats()
+	if err != nil {
+		t.Fatal(err)
+	} else if rs.Replicas != 2 || rs.Quorum != 2 || rs.Hot != 2 {
+		t.Fatalf("bad replication stats %+v", rs)
+	} else if rs.Pending != 0 || rs.Committed != count {
+		t.Fatalf("bad replication counts %+v", rs)
+	}
+}
+
+func TestReplicationQuorum(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	dead := deadTarget(t)
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations:      []Target{ti.target(), dead},
+		Tags:              []string{`foo`},
+		Replicate:         true,
+		ReplicationQuorum: 1,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//write more than the dead replica's feeder can hold so that copies land in the emergency queue
+	count := 2 * defaultIngestChanDepth
+	for i := 0; i < count; i++ {
+		if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := im.ReplicationStats()
+	if err != nil {
+		t.Fatal(err)
+	} else if rs.Hot != 1 || rs.Pending != 0 || rs.Committed != uint64(count) {
+		t.Fatalf("bad replication stats %+v", rs)
+	}
+	if rep := im.replicas[1]; rep.eq.len() == 0 || len(rep.eChan) == 0 {
+		t.Fatal("dead replica is not holding copies")
+	}
+}
+
+func TestReplicationQuorumUnavailable(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{ti.target(), deadTarget(t)},
+		Tags:         []string{`foo`},
+		Replicate:    true,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte("test")}); err != nil {
+		t.Fatal(err)
+	}
+	if err = im.Sync(5 * time.Second); err != ErrQuorumUnavailable {
+		t.Fatalf("expected quorum error, got %v", err)
+	}
+	if rs, err := im.ReplicationStats(); err != nil {
+		t.Fatal(err)
+	} else if rs.Pending != 1 || rs.Committed != 0 {
+		t.Fatalf("bad replication stats %+v", rs)
+	}
+	if n := ti.count("test"); n != 1 {
+		t.Fatalf("live replica saw entry %d times", n)
+	}
+}
+
+func TestReplicationConfig(t *testing.T) {
+	dests := []Target{{Address: `tcp://10.0.0.1:4023`}, {Address: `tcp://10.0.0.2:4023`}}
+	if _, err := NewMuxer(MuxerConfig{Destinations: dests, Tags: []string{`foo`}, Replicate: true, ReplicationQuorum: 3}); err != ErrInvalidQuorum {
+		t.Fatalf("failed to catch bad quorum: %v", err)
+	}
+	if _, err := NewMuxer(MuxerConfig{Destinations: dests, Tags: []string{`foo`}, Replicate: true, ReplicationQuorum: -1}); err != ErrInvalidQuorum {
+		t.Fatalf("failed to catch bad quorum: %v", err)
+	}
+	if im, err := NewMuxer(MuxerConfig{Destinations: dests, Tags: []string{`foo`}, Replicate: true}); err != nil {
+		t.Fatal(err)
+	} else if err = im.DittoWriteContext(context.Background(), []entry.Entry{{Data: []byte(`x`)}}); err != ErrDittoReplication {
+		t.Fatalf("bad error on replicated ditto write: %v", err)
+	}
+	im, err := NewMuxer(MuxerConfig{Destinations: dests, Tags: []string{`foo`}})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = im.ReplicationStats(); err != ErrNotReplicating {
+		t.Fatalf("bad error on non-replicated muxer: %v", err)
+	}
+	if n := replicaCacheName(`tls://[fe80::1]:4024`); n != `tls____fe80__1__4024` {
+		t.Fatalf("bad cache name %q", n)
+	}
+}
+
+func TestReplicationCache(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations:      []Target{ti.target(), deadTarget(t)},
+		Tags:              []string{`foo`},
+		Replicate:         true,
+		ReplicationQuorum: 1,
+		CachePath:         t.TempDir(),
+		CacheMode:         CacheModeFail,
+		CacheSize:         16,
+		CacheDepth:        16,
+	})
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 64
+	for i := 0; i < count; i++ {
+		if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err = im.Close(); err != nil {
+		t.Fatal(err)
+	}
+	//everything destined for the dead replica should have been committed to its cache
+	if sz := im.replicas[1].cacheSize(); sz == 0 {
+		t.Fatal("dead replica cache is empty")
+	} else if sz = im.replicas[0].cacheSize(); sz != 0 {
+		t.Fatalf("live replica cached %d bytes", sz)
+	}
+}
+
+// writeUntilTimeout writes entries with a short timeout until one times out, returning how many made it
+func writeUntilTimeout(t *testing.T, im *IngestMuxer, tag entry.EntryTag, max int) int {
+	for i := 0; i < max; i++ {
+		err := im.WriteEntryTimeout(&entry.Entry{TS: entry.Now(), Tag: tag, Data: []byte(fmt.Sprintf("entry %d", i))}, 50*time.Millisecond)
+		if err == ErrWriteTimeout {
+			return i
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("no backpressure after %d writes", max)
+	return 0
+}
+
+func TestReplicationPendingCap(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{ti.target(), deadTarget(t)},
+		Tags:         []string{`foo`},
+		Replicate:    true,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//quorum needs the dead replica, so nothing ever commits
+	const max = 8
+	im.rt.mtx.Lock()
+	im.rt.maxPending = max
+	im.rt.mtx.Unlock()
+	if n := writeUntilTimeout(t, im, foo, 2*max); n != max {
+		t.Fatalf("writer held off after %d writes, expected %d", n, max)
+	}
+	if rs, err := im.ReplicationStats(); err != nil {
+		t.Fatal(err)
+	} else if rs.Pending != max {
+		t.Fatalf("bad replication stats %+v", rs)
+	}
+	//the write that was held off must not have reached anyone
+	if err = im.Sync(time.Second); err != ErrQuorumUnavailable {
+		t.Fatalf("expected quorum error, got %v", err)
+	} else if n := ti.count(fmt.Sprintf("entry %d", max)); n != 0 {
+		t.Fatalf("live replica saw a failed write %d times", n)
+	}
+}
+
+func TestReplicationHeldCap(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations:      []Target{ti.target(), deadTarget(t)},
+		Tags:              []string{`foo`},
+		Replicate:         true,
+		ReplicationQuorum: 1,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const max = 16
+	rep := im.replicas[1]
+	rep.mtx.Lock()
+	rep.maxHeld = max
+	rep.mtx.Unlock()
+	//the dead replica's feeder fills, then it parks copies until it hits its cap and drops
+	//the rest, the live replica makes quorum on its own so writers are never held off
+	const count = 4 * defaultIngestChanDepth
+	for i := 0; i < count; i++ {
+		if err = im.WriteEntryTimeout(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}, time.Second); err != nil {
+			t.Fatalf("write %d failed %v", i, err)
+		}
+	}
+	rep.mtx.Lock()
+	held := len(rep.inflight)
+	rep.mtx.Unlock()
+	if held != max {
+		t.Fatalf("dead replica holding %d copies", held)
+	}
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	} else if rs, err := im.ReplicationStats(); err != nil {
+		t.Fatal(err)
+	} else if rs.Dropped != count-defaultIngestChanDepth-max || rs.Pending != 0 || rs.Committed != count {
+		t.Fatalf("bad replication stats %+v", rs)
+	}
+	for i := 0; i < count; i++ {
+		if c := ti.count(fmt.Sprintf("entry %d", i)); c != 1 {
+			t.Fatalf("live replica saw entry %d %d times", i, c)
+		}
+	}
+}
+
+func TestReplicationAllOrNothing(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{ti.target(), deadTarget(t)},
+		Tags:         []string{`foo`},
+		Replicate:    true,
+	})
+	defer im.Close()
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//fill the dead replica's feeder, the next write times out waiting on it
+	for i := 0; i < defaultIngestChanDepth; i++ {
+		if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//the live replica already took its copy, so the dead one must get one too
+	if err = im.WriteEntryTimeout(&entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte("late")}, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if rep := im.replicas[1]; rep.eq.len() != 1 {
+		t.Fatalf("dead replica parked %d writes", rep.eq.len())
+	}
+	for i := 0; i < 500 && ti.count("late") == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := ti.count("late"); n != 1 {
+		t.Fatalf("live replica saw entry %d times", n)
+	}
+}
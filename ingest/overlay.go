@@ -0,0 +1,276 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	maxConfigOverlaySize    uint32 = 1024 * 1024
+	configOverlayExt               = `.conf`
+	configOverlayDomain            = "gravwell config overlay v1\x00"
+	configOverlayHdrSize    int    = 4 + 4 //cmd plus payload length
+	maxConfigOverlayNameLen        = 128
+)
+
+var (
+	ErrConfigOverlayNotSupported = errors.New("ingester does not support configuration overlays")
+	ErrConfigOverlayTooLarge     = errors.New("configuration overlay is too large")
+	ErrConfigOverlayName         = errors.New("invalid configuration overlay name")
+	ErrConfigOverlayID           = errors.New("configuration overlay is missing an ID")
+	ErrConfigOverlaySignature    = errors.New("configuration overlay signature is invalid")
+	ErrConfigOverlayNoKeys       = errors.New("no configuration overlay keys are configured")
+	ErrConfigOverlayTarget       = errors.New("configuration overlay is for a different ingester")
+	ErrConfigOverlayUnbound      = errors.New("configuration overlay must have a target ingester or an expiration")
+	ErrConfigOverlayExpired      = errors.New("configuration overlay has expired")
+)
+
+// ConfigOverlay is a signed configuration overlay file delivered to an ingester by an indexer.
+// Ingesters that accept overlays write Data into their configuration overlay directory as Name
+// and reload, an overlay with empty Data removes the named overlay.
+type ConfigOverlay struct {
+	ID        string    // opaque identifier chosen by the sender, echoed back in the status
+	Name      string    // file name within the overlay directory, must end in .conf
+	Target    string    // UUID of the ingester the overlay is meant for, empty for any ingester
+	Issued    time.Time // overlays not issued after the last applied overlay of the same name are rejected
+	Expires   time.Time // overlays are rejected after this time, required when Target is empty
+	Data      []byte
+	Signature []byte // ed25519 signature of SigningBytes
+}
+
+// ConfigOverlayStatus reports the outcome of the most recent configuration overlay in the ingester state
+type ConfigOverlayStatus struct {
+	ID         string
+	Name       string
+	Applied    bool
+	RolledBack bool   `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	Time       time.Time
+}
+
+// ConfigOverlayHandler validates and applies a configuration overlay, the returned status
+// is reported to indexers in the next ingester state update.
+type ConfigOverlayHandler func(ConfigOverlay) ConfigOverlayStatus
+
+// SigningBytes returns the bytes covered by the overlay signature, every field is length prefixed
+// so that moving bytes between fields invalidates the signature.
+func (co ConfigOverlay) SigningBytes() []byte {
+	b := make([]byte, 0, len(configOverlayDomain)+len(co.ID)+len(co.Name)+len(co.Target)+len(co.Data)+40)
+	b = append(b, configOverlayDomain...)
+	for _, v := range []string{co.ID, co.Name, co.Target} {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(co.Issued.UnixNano()))
+	var exp int64
+	if !co.Expires.IsZero() {
+		exp = co.Expires.UnixNano()
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(exp))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(co.Data)))
+	return append(b, co.Data...)
+}
+
+// Sign populates the overlay signature using the given private key
+func (co *ConfigOverlay) Sign(key ed25519.PrivateKey) {
+	co.Signature = ed25519.Sign(key, co.SigningBytes())
+}
+
+// Validate checks that the overlay is well formed, it does not check the signature.
+// An overlay that is not bound to a single ingester must expire so it cannot be replayed forever.
+func (co ConfigOverlay) Validate() error {
+	if co.ID == `` {
+		return ErrConfigOverlayID
+	} else if len(co.Data) > int(maxConfigOverlaySize) {
+		return ErrConfigOverlayTooLarge
+	} else if co.Target == `` && co.Expires.IsZero() {
+		return ErrConfigOverlayUnbound
+	}
+	return ValidateConfigOverlayName(co.Name)
+}
+
+// Verify checks that the overlay is well formed, meant for the ingester with the given UUID,
+// not expired, and signed by one of the given keys
+func (co ConfigOverlay) Verify(uuid string, keys []ed25519.PublicKey) error {
+	if err := co.Validate(); err != nil {
+		return err
+	} else if len(keys) == 0 {
+		return ErrConfigOverlayNoKeys
+	} else if co.Target != `` && !strings.EqualFold(co.Target, uuid) {
+		return ErrConfigOverlayTarget
+	} else if !co.Expires.IsZero() && time.Now().After(co.Expires) {
+		return ErrConfigOverlayExpired
+	}
+	msg := co.SigningBytes()
+	for _, k := range keys {
+		if len(k) == ed25519.PublicKeySize && ed25519.Verify(k, msg, co.Signature) {
+			return nil
+		}
+	}
+	return ErrConfigOverlaySignature
+}
+
+// ValidateConfigOverlayName ensures an overlay name is a plain .conf file name that cannot escape
+// the overlay directory
+func ValidateConfigOverlayName(name string) error {
+	if len(name) <= len(configOverlayExt) || len(name) > maxConfigOverlayNameLen {
+		return ErrConfigOverlayName
+	} else if filepath.Ext(name) != configOverlayExt || strings.HasPrefix(name, `.`) {
+		return ErrConfigOverlayName
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' {
+			return ErrConfigOverlayName
+		}
+	}
+	return nil
+}
+
+func (co ConfigOverlay) encode() ([]byte, error) {
+	b, err := json.Marshal(co)
+	if err != nil {
+		return nil, err
+	} else if len(b) > int(maxConfigOverlaySize) {
+		return nil, ErrConfigOverlayTooLarge
+	}
+	return b, nil
+}
+
+func (co *ConfigOverlay) decode(b []byte) error {
+	return json.Unmarshal(b, co)
+}
+
+// SetConfigOverlayHandler installs the function that applies configuration overlays delivered
+// by indexers.  Overlays are ignored until a handler is installed, overlays are handed to the
+// handler one at a time and an overlay with the same ID as the last one handled is ignored.
+func (im *IngestMuxer) SetConfigOverlayHandler(h ConfigOverlayHandler) {
+	im.overlayMtx.Lock()
+	im.overlayHandler = h
+	im.overlayMtx.Unlock()
+}
+
+// configOverlayReceived is called by the ingest connections, it must not block
+func (im *IngestMuxer) configOverlayReceived(co ConfigOverlay) {
+	go im.handleConfigOverlay(co)
+}
+
+func (im *IngestMuxer) handleConfigOverlay(co ConfigOverlay) {
+	im.overlayMtx.Lock()
+	defer im.overlayMtx.Unlock()
+	if im.overlayHandler == nil {
+		im.Warn("ignoring configuration overlay, overlays are not enabled", log.KV("id", co.ID), log.KV("name", co.Name))
+		return
+	} else if co.ID == im.overlayLastID {
+		return //delivered by more than one indexer
+	}
+	im.overlayLastID = co.ID
+	st := im.overlayHandler(co)
+	if st.ID == `` {
+		st.ID = co.ID
+	}
+	if st.Name == `` {
+		st.Name = co.Name
+	}
+	if st.Time.IsZero() {
+		st.Time = time.Now()
+	}
+	if st.Applied {
+		im.Info("applied configuration overlay", log.KV("id", st.ID), log.KV("name", st.Name))
+	} else {
+		im.Error("failed to apply configuration overlay", log.KV("id", st.ID), log.KV("name", st.Name),
+			log.KV("rolledback", st.RolledBack), log.KV("error", st.Error))
+	}
+	im.mtx.Lock()
+	im.ingesterState.ConfigOverlay = &st
+	im.ingesterStateUpdated = true
+	im.mtx.Unlock()
+}
+
+// SendConfigOverlay delivers a configuration overlay to the remote ingester, the outcome
+// is reported in a subsequent ingester state message.
+func (er *EntryReader) SendConfigOverlay(co ConfigOverlay) (err error) {
+	if !er.started {
+		return errAckRoutineClosed
+	} else if er.igAPIVersion < MINIMUM_CONFIG_OVERLAY_VERSION {
+		return ErrConfigOverlayNotSupported
+	} else if err = co.Validate(); err != nil {
+		return
+	}
+	var b []byte
+	if b, err = co.encode(); err != nil {
+		return
+	}
+	er.ackChan <- ackCommand{cmd: CONFIG_OVERLAY_MAGIC, data: b}
+	return er.errState
+}
+
+// writePayloadCommand writes a command that carries a variable sized payload directly
+// to the connection, they are too large for the ack buffer
+func (er *EntryReader) writePayloadCommand(v ackCommand) (err error) {
+	hdr := make([]byte, configOverlayHdrSize)
+	binary.LittleEndian.PutUint32(hdr, uint32(v.cmd))
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(v.data)))
+	if err = er.writeAll(hdr); err == nil {
+		if err = er.writeAll(v.data); err == nil {
+			err = er.bAckWriter.Flush()
+		}
+	}
+	return
+}
+
+// configOverlay decodes a received overlay and hands it to the overlay callback
+func (ew *EntryWriter) configOverlay(ac ackCommand) {
+	if ew.overlayCb == nil {
+		return
+	}
+	var co ConfigOverlay
+	if err := co.decode(ac.data); err == nil {
+		ew.overlayCb(co)
+	}
+}
+
+// setConfigOverlayCallback installs a function that is handed every configuration overlay sent by the remote side.
+// The callback is invoked while the writer lock is held, it must not block.
+func (ew *EntryWriter) setConfigOverlayCallback(fn func(ConfigOverlay)) {
+	ew.mtx.Lock()
+	ew.overlayCb = fn
+	ew.mtx.Unlock()
+}
+
+func (igst *IngestConnection) setConfigOverlayCallback(fn func(ConfigOverlay)) {
+	igst.mtx.RLock()
+	defer igst.mtx.RUnlock()
+	if igst.ew != nil {
+		igst.ew.setConfigOverlayCallback(fn)
+	}
+}
+
+func readPayload(rdr io.Reader, max uint32) (b []byte, err error) {
+	var sz uint32
+	if err = binary.Read(rdr, binary.LittleEndian, &sz); err != nil {
+		return
+	} else if sz > max {
+		err = fmt.Errorf("%w: %d > %d", ErrConfigOverlayTooLarge, sz, max)
+		return
+	}
+	b = make([]byte, sz)
+	_, err = io.ReadFull(rdr, b)
+	return
+}
@@ -0,0 +1,155 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+func testOverlay(t *testing.T) (co ConfigOverlay, pub ed25519.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	co = ConfigOverlay{
+		ID:     `abc123`,
+		Name:   `listeners.conf`,
+		Target: `0f9d7c4e-8e0a-4bd4-9c39-5d0a3c5b8c11`,
+		Issued: time.Now(),
+		Data:   []byte("[Listener \"test\"]\n\tBind-String=0.0.0.0:7777\n"),
+	}
+	co.Sign(priv)
+	return
+}
+
+func TestConfigOverlayVerify(t *testing.T) {
+	co, pub := testOverlay(t)
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = co.Verify(co.Target, []ed25519.PublicKey{other, pub}); err != nil {
+		t.Fatal(err)
+	} else if err = co.Verify(co.Target, []ed25519.PublicKey{other}); err != ErrConfigOverlaySignature {
+		t.Fatalf("bad wrong key error %v", err)
+	} else if err = co.Verify(co.Target, nil); err != ErrConfigOverlayNoKeys {
+		t.Fatalf("bad missing keys error %v", err)
+	} else if err = co.Verify(`8a2f1b0c-1111-4bd4-9c39-5d0a3c5b8c11`, []ed25519.PublicKey{pub}); err != ErrConfigOverlayTarget {
+		t.Fatalf("bad target error %v", err)
+	}
+
+	//overlays for any ingester must expire
+	c := co
+	c.Target = ``
+	if err = c.Verify(co.Target, []ed25519.PublicKey{pub}); err != ErrConfigOverlayUnbound {
+		t.Fatalf("bad unbound error %v", err)
+	}
+	c.Expires = time.Now().Add(-time.Second)
+	if err = c.Verify(co.Target, []ed25519.PublicKey{pub}); err != ErrConfigOverlayExpired {
+		t.Fatalf("bad expired error %v", err)
+	}
+
+	//every field is covered by the signature
+	tampers := []func(*ConfigOverlay){
+		func(c *ConfigOverlay) { c.ID = `abc124` },
+		func(c *ConfigOverlay) { c.Name = `other.conf` },
+		func(c *ConfigOverlay) { c.Target, c.Expires = ``, time.Now().Add(time.Hour) },
+		func(c *ConfigOverlay) { c.Expires = time.Now().Add(time.Hour) },
+		func(c *ConfigOverlay) { c.Issued = c.Issued.Add(time.Second) },
+		func(c *ConfigOverlay) { c.Data = append(bytes.Clone(c.Data), '\n') },
+		func(c *ConfigOverlay) { c.ID, c.Name = `abc123l`, `isteners.conf` },
+	}
+	for i, f := range tampers {
+		c = co
+		f(&c)
+		if err = c.Verify(co.Target, []ed25519.PublicKey{pub}); err != ErrConfigOverlaySignature {
+			t.Fatalf("tamper %d not caught: %v", i, err)
+		}
+	}
+}
+
+func TestConfigOverlayName(t *testing.T) {
+	for _, v := range []string{`a.conf`, `listeners-01.conf`, `my_overlay.v2.conf`} {
+		if err := ValidateConfigOverlayName(v); err != nil {
+			t.Fatalf("rejected valid name %q: %v", v, err)
+		}
+	}
+	for _, v := range []string{``, `.conf`, `a.cfg`, `../a.conf`, `dir/a.conf`, `.hidden.conf`, `a b.conf`, `a\b.conf`} {
+		if err := ValidateConfigOverlayName(v); err != ErrConfigOverlayName {
+			t.Fatalf("failed to catch bad name %q: %v", v, err)
+		}
+	}
+}
+
+func TestConfigOverlayCommand(t *testing.T) {
+	errChan := make(chan error)
+	lst, cli, srv, err := getConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lst.Close()
+
+	etSrv, err := NewEntryReader(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etSrv.Start()
+
+	etCli, err := NewEntryWriter(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etCli.serverVersion = VERSION
+	overlays := make(chan ConfigOverlay, 1)
+	etCli.setConfigOverlayCallback(func(co ConfigOverlay) {
+		overlays <- co
+	})
+
+	co, pub := testOverlay(t)
+	if err = etSrv.SendConfigOverlay(co); err != ErrConfigOverlayNotSupported {
+		t.Fatalf("sent overlay to an old ingester: %v", err)
+	}
+	etSrv.igAPIVersion = VERSION
+
+	count := 1000
+	go reader(etSrv, count, 0, errChan)
+	for i := 0; i < count; i++ {
+		if err = etCli.Write(makeEntry()); err != nil {
+			t.Fatal(err)
+		}
+		if i == count/2 {
+			if err = etSrv.SendConfigOverlay(co); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = etCli.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-overlays:
+		if err = got.Verify(co.Target, []ed25519.PublicKey{pub}); err != nil {
+			t.Fatalf("received overlay failed verification: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("overlay was not received")
+	}
+
+	if err = etCli.Close(); err != nil {
+		t.Fatal(err)
+	} else if err = <-errChan; err != nil {
+		t.Fatal(err)
+	} else if err = etSrv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	closeConnections(cli, srv)
+}
@@ -0,0 +1,402 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	DedupProcessor string = `dedup`
+
+	dedupFieldData = `data`
+	dedupFieldTag  = `tag`
+	dedupFieldSrc  = `src`
+	dedupFieldTS   = `ts`
+
+	defaultDedupWindow     = time.Minute
+	defaultDedupMaxEntries = 1024 * 1024
+
+	dedupStateMagic = `GWDEDUP1`
+)
+
+var (
+	ErrInvalidDedupField    = errors.New("Fields must be one or more of data, tag, src, and ts")
+	ErrInvalidDedupWindow   = errors.New("Window must be a positive duration")
+	ErrInvalidDedupMax      = errors.New("Max-Entries cannot be negative")
+	ErrInvalidDedupJSON     = errors.New("JSON-Field cannot be empty")
+	ErrInvalidDedupState    = errors.New("Dedup state file is corrupt")
+	ErrDedupNothingToHash   = errors.New("At least one of Fields or JSON-Field must be set")
+	ErrDedupStateIsNotAFile = errors.New("State-File is not a regular file")
+)
+
+// DedupConfig drops entries that repeat an entry seen within the window, e.g. when two
+// redundant relays deliver the same stream.  The selected parts of each entry are hashed and the
+// hashes are remembered for Window after they are first seen, up to Max-Entries hashes.
+// Fields defaults to tag and data, or just tag when JSON-Field is set.
+type DedupConfig struct {
+	Fields      []string // entry parts hashed: data, tag, src, and ts
+	JSON_Field  []string // JSON fields extracted from the data and hashed, e.g. JSON-Field=event.id
+	Window      string   // entries repeated within the window are dropped, defaults to 1m
+	Max_Entries int      // upper bound on remembered hashes, the oldest are forgotten first
+	State_File  string   // optional file that carries remembered hashes across restarts
+}
+
+type dedupParams struct {
+	fields []string
+	paths  [][]string
+	window time.Duration
+	max    int
+}
+
+// DedupLoadConfig loads the configuration for the dedup processor
+func DedupLoadConfig(vc *config.VariableConfig) (c DedupConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	}
+	_, err = c.validate()
+	return
+}
+
+func (c *DedupConfig) validate() (p dedupParams, err error) {
+	for _, v := range c.Fields {
+		for _, f := range strings.Split(v, `,`) {
+			switch f = strings.ToLower(strings.TrimSpace(f)); f {
+			case dedupFieldData, dedupFieldTag, dedupFieldSrc, dedupFieldTS:
+				if !slices.Contains(p.fields, f) {
+					p.fields = append(p.fields, f)
+				}
+			default:
+				err = fmt.Errorf("%w: %q", ErrInvalidDedupField, f)
+				return
+			}
+		}
+	}
+	for _, v := range c.JSON_Field {
+		if strings.TrimSpace(v) == `` {
+			err = ErrInvalidDedupJSON
+			return
+		}
+		p.paths = append(p.paths, unquoteFields(splitRespectQuotes(v, dotSplitter)))
+	}
+	if len(c.Fields) == 0 {
+		if p.fields = []string{dedupFieldTag}; len(p.paths) == 0 {
+			p.fields = append(p.fields, dedupFieldData)
+		}
+	} else if len(p.fields) == 0 && len(p.paths) == 0 {
+		err = ErrDedupNothingToHash
+		return
+	}
+
+	p.window = defaultDedupWindow
+	if c.Window != `` {
+		if p.window, err = time.ParseDuration(c.Window); err != nil {
+			err = fmt.Errorf("invalid Window %q: %w", c.Window, err)
+			return
+		} else if p.window <= 0 {
+			err = ErrInvalidDedupWindow
+			return
+		}
+	}
+	if c.Max_Entries < 0 {
+		err = ErrInvalidDedupMax
+		return
+	} else if p.max = c.Max_Entries; p.max == 0 {
+		p.max = defaultDedupMaxEntries
+	}
+
+	if c.State_File != `` {
+		var fi os.FileInfo
+		if fi, err = os.Stat(c.State_File); err == nil && !fi.Mode().IsRegular() {
+			err = ErrDedupStateIsNotAFile
+		} else if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	return
+}
+
+// dedupRecord is a remembered hash and the time in unix nanoseconds that it was first seen
+type dedupRecord struct {
+	h uint64
+	t int64
+}
+
+// NewDedup creates a new dedup processor, remembered hashes are loaded from the state file if one exists
+func NewDedup(cfg DedupConfig, tagger Tagger) (*Dedup, error) {
+	d := &Dedup{
+		tgr:  tagger,
+		tags: map[entry.EntryTag]string{},
+		hsh:  fnv.New64a(),
+		now:  time.Now,
+	}
+	if err := d.init(cfg); err != nil {
+		return nil, err
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Dedup remembers hashes in a ring ordered by the time they were first seen, so expiring
+// and evicting both pop from the head.  The seen map holds the newest time for every hash;
+// a ring record that no longer matches the map is stale and is skipped when popped.
+type Dedup struct {
+	nocloser
+	DedupConfig
+	dedupParams
+	tgr  Tagger
+	tags map[entry.EntryTag]string
+	hsh  hash.Hash64
+	now  func() time.Time
+	buf  [16]byte
+
+	seen map[uint64]int64
+	ring []dedupRecord
+	head int
+	cnt  int
+}
+
+// Config updates the configuration for the dedup processor, remembered hashes are kept
+// when the hashed parts of the entry do not change
+func (d *Dedup) Config(v interface{}, tagger Tagger) (err error) {
+	if v == nil {
+		err = ErrNilConfig
+	} else if cfg, ok := v.(DedupConfig); ok {
+		d.tgr = tagger
+		err = d.init(cfg)
+	} else {
+		err = fmt.Errorf("Invalid configuration, unknown type %T", v)
+	}
+	return
+}
+
+func (d *Dedup) init(cfg DedupConfig) (err error) {
+	var p dedupParams
+	if p, err = cfg.validate(); err != nil {
+		return
+	}
+	var old []dedupRecord
+	if slices.Equal(p.fields, d.fields) && slices.EqualFunc(p.paths, d.paths, slices.Equal) {
+		old = d.records()
+	}
+	d.DedupConfig = cfg
+	d.dedupParams = p
+	d.seen = map[uint64]int64{}
+	d.ring = nil
+	d.head, d.cnt = 0, 0
+	for _, r := range old {
+		d.insert(r)
+	}
+	return
+}
+
+// Process drops every entry whose hash was first seen less than a window ago
+func (d *Dedup) Process(ents []*entry.Entry) (rset []*entry.Entry, err error) {
+	if len(ents) == 0 {
+		return
+	}
+	now := d.now().UnixNano()
+	d.expire(now)
+	rset = ents[:0]
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		h := d.hash(ent)
+		if t, ok := d.seen[h]; ok && now-t < int64(d.window) {
+			continue
+		}
+		d.insert(dedupRecord{h: h, t: now})
+		rset = append(rset, ent)
+	}
+	return
+}
+
+// Close writes the remembered hashes to the state file
+func (d *Dedup) Close() error {
+	return d.save()
+}
+
+func (d *Dedup) hash(ent *entry.Entry) uint64 {
+	d.hsh.Reset()
+	for _, f := range d.fields {
+		switch f {
+		case dedupFieldData:
+			d.write(ent.Data)
+		case dedupFieldTag:
+			//hash the name so hashes survive tag renumbering across restarts
+			d.write([]byte(d.tagName(ent.Tag)))
+		case dedupFieldSrc:
+			d.write(ent.SRC.To16())
+		case dedupFieldTS:
+			binary.LittleEndian.PutUint64(d.buf[:8], uint64(ent.TS.Sec))
+			binary.LittleEndian.PutUint64(d.buf[8:], uint64(ent.TS.Nsec))
+			d.hsh.Write(d.buf[:])
+		}
+	}
+	for _, p := range d.paths {
+		if v, _, _, err := jsonparser.Get(ent.Data, p...); err == nil {
+			d.write(v)
+		} else {
+			d.write(nil)
+		}
+	}
+	return d.hsh.Sum64()
+}
+
+// write length prefixes every part so that adjacent parts cannot alias each other
+func (d *Dedup) write(b []byte) {
+	binary.LittleEndian.PutUint64(d.buf[:8], uint64(len(b)))
+	d.hsh.Write(d.buf[:8])
+	d.hsh.Write(b)
+}
+
+func (d *Dedup) tagName(tag entry.EntryTag) (s string) {
+	var ok bool
+	if s, ok = d.tags[tag]; !ok {
+		if s, ok = d.tgr.LookupTag(tag); !ok {
+			s = strconv.Itoa(int(tag))
+		}
+		d.tags[tag] = s
+	}
+	return
+}
+
+func (d *Dedup) insert(r dedupRecord) {
+	if d.cnt == len(d.ring) {
+		if len(d.ring) < d.max {
+			d.grow()
+		} else {
+			d.pop()
+		}
+	}
+	d.ring[(d.head+d.cnt)%len(d.ring)] = r
+	d.cnt++
+	d.seen[r.h] = r.t
+}
+
+// grow doubles the ring up to the maximum so idle processors don't hold a full sized ring
+func (d *Dedup) grow() {
+	ring := make([]dedupRecord, min(d.max, max(2*len(d.ring), 1024)))
+	for i := 0; i < d.cnt; i++ {
+		ring[i] = d.ring[(d.head+i)%len(d.ring)]
+	}
+	d.ring = ring
+	d.head = 0
+}
+
+func (d *Dedup) pop() {
+	r := d.ring[d.head]
+	if t, ok := d.seen[r.h]; ok && t == r.t {
+		delete(d.seen, r.h)
+	}
+	d.head = (d.head + 1) % len(d.ring)
+	d.cnt--
+}
+
+func (d *Dedup) expire(now int64) {
+	for d.cnt > 0 && now-d.ring[d.head].t >= int64(d.window) {
+		d.pop()
+	}
+}
+
+// records returns the live records oldest first
+func (d *Dedup) records() (rs []dedupRecord) {
+	for i := 0; i < d.cnt; i++ {
+		r := d.ring[(d.head+i)%len(d.ring)]
+		if d.seen[r.h] == r.t {
+			rs = append(rs, r)
+		}
+	}
+	return
+}
+
+func (d *Dedup) load() (err error) {
+	if d.State_File == `` {
+		return
+	}
+	var fin *os.File
+	if fin, err = os.Open(d.State_File); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	defer fin.Close()
+	rdr := bufio.NewReader(fin)
+	magic := make([]byte, len(dedupStateMagic))
+	if _, err = io.ReadFull(rdr, magic); err != nil || string(magic) != dedupStateMagic {
+		return fmt.Errorf("%w: %s", ErrInvalidDedupState, d.State_File)
+	}
+	now := d.now().UnixNano()
+	var buf [16]byte
+	for {
+		if _, err = io.ReadFull(rdr, buf[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidDedupState, d.State_File)
+		}
+		r := dedupRecord{
+			h: binary.LittleEndian.Uint64(buf[:8]),
+			t: int64(binary.LittleEndian.Uint64(buf[8:])),
+		}
+		if now-r.t < int64(d.window) {
+			d.insert(r)
+		}
+	}
+}
+
+func (d *Dedup) save() (err error) {
+	if d.State_File == `` {
+		return
+	}
+	tmp := filepath.Join(filepath.Dir(d.State_File), `.`+filepath.Base(d.State_File)+`.tmp`)
+	var fout *os.File
+	if fout, err = os.Create(tmp); err != nil {
+		return
+	}
+	wtr := bufio.NewWriter(fout)
+	wtr.WriteString(dedupStateMagic)
+	var buf [16]byte
+	for _, r := range d.records() {
+		binary.LittleEndian.PutUint64(buf[:8], r.h)
+		binary.LittleEndian.PutUint64(buf[8:], uint64(r.t))
+		wtr.Write(buf[:])
+	}
+	if err = wtr.Flush(); err == nil {
+		err = fout.Sync()
+	}
+	if lerr := fout.Close(); err == nil {
+		err = lerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, d.State_File)
+	} else {
+		os.Remove(tmp)
+	}
+	return
+}
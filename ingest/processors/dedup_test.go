@@ -0,0 +1,216 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func newTestDedup(t *testing.T, cfg DedupConfig, tagger Tagger, clk *sampleClock) *Dedup {
+	d, err := NewDedup(cfg, tagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = clk.now
+	return d
+}
+
+func dedupEnts(tag entry.EntryTag, src string, data ...string) (ents []*entry.Entry) {
+	for _, v := range data {
+		ents = append(ents, &entry.Entry{
+			Tag:  tag,
+			SRC:  net.ParseIP(src),
+			Data: []byte(v),
+		})
+	}
+	return
+}
+
+func TestDedupConfig(t *testing.T) {
+	b := []byte(`
+	[preprocessor "dd"]
+		type = dedup
+		Fields = tag
+		Fields = src
+		JSON-Field = "event.id"
+		Window = 30s
+		Max-Entries = 100
+	`)
+	var tc attachTestConfig
+	if err := config.LoadConfigBytes(&tc, b); err != nil {
+		t.Fatal(err)
+	}
+	vc := tc.Preprocessor[`dd`]
+	if cfg, err := ProcessorLoadConfig(vc); err != nil {
+		t.Fatal(err)
+	} else if dc, ok := cfg.(DedupConfig); !ok || len(dc.Fields) != 2 || len(dc.JSON_Field) != 1 {
+		t.Fatalf("bad config %+v", cfg)
+	}
+	var tagger testTagger
+	if p, err := newProcessor(vc, &tagger); err != nil {
+		t.Fatal(err)
+	} else if d, ok := p.(*Dedup); !ok {
+		t.Fatalf("bad processor type %T", p)
+	} else if d.window != 30*time.Second || d.max != 100 || len(d.paths[0]) != 2 {
+		t.Fatalf("bad processor params %+v", d.dedupParams)
+	}
+
+	//defaults
+	var dc DedupConfig
+	if p, err := dc.validate(); err != nil {
+		t.Fatal(err)
+	} else if len(p.fields) != 2 || p.window != defaultDedupWindow || p.max != defaultDedupMaxEntries {
+		t.Fatalf("bad defaults %+v", p)
+	}
+	dc.JSON_Field = []string{`id`}
+	if p, err := dc.validate(); err != nil {
+		t.Fatal(err)
+	} else if len(p.fields) != 1 || p.fields[0] != dedupFieldTag {
+		t.Fatalf("bad JSON defaults %+v", p)
+	}
+
+	bad := []DedupConfig{
+		{Fields: []string{`data`, `body`}},
+		{Fields: []string{``}},
+		{JSON_Field: []string{` `}},
+		{Window: `later`},
+		{Window: `0s`},
+		{Max_Entries: -1},
+		{State_File: t.TempDir()},
+	}
+	for i, v := range bad {
+		if _, err := v.validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d %+v", i, v)
+		}
+	}
+}
+
+func TestDedupProcess(t *testing.T) {
+	var tagger testTagger
+	foo, _ := tagger.NegotiateTag(`foo`)
+	bar, _ := tagger.NegotiateTag(`bar`)
+	clk := &sampleClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	d := newTestDedup(t, DedupConfig{Window: `10s`}, &tagger, clk)
+
+	//two relays deliver the same stream, duplicates within the batch are dropped too
+	ents := dedupEnts(foo, `10.0.0.1`, `a`, `b`, `c`, `a`)
+	ents = append(ents, dedupEnts(foo, `10.0.0.2`, `a`, `b`, `c`)...)
+	ents = append(ents, dedupEnts(bar, `10.0.0.2`, `a`)...)
+	if set, err := d.Process(ents); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 4 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+
+	//repeats inside the window are still dropped and do not extend it
+	clk.t = clk.t.Add(9 * time.Second)
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `a`, `d`)); len(set) != 1 || string(set[0].Data) != `d` {
+		t.Fatalf("bad dedup result %d", len(set))
+	}
+	clk.t = clk.t.Add(time.Second)
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `a`, `d`)); len(set) != 1 || string(set[0].Data) != `a` {
+		t.Fatalf("bad dedup result after the window %d", len(set))
+	}
+
+	//the source is only hashed when asked for
+	if err := d.Config(DedupConfig{Fields: []string{`data,src`}}, &tagger); err != nil {
+		t.Fatal(err)
+	}
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `x`, `x`)); len(set) != 1 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+	if set, _ := d.Process(dedupEnts(bar, `10.0.0.2`, `x`)); len(set) != 1 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+}
+
+func TestDedupJSON(t *testing.T) {
+	var tagger testTagger
+	foo, _ := tagger.NegotiateTag(`foo`)
+	clk := &sampleClock{t: time.Now()}
+	d := newTestDedup(t, DedupConfig{JSON_Field: []string{`event.id`}}, &tagger, clk)
+	ents := dedupEnts(foo, `10.0.0.1`,
+		`{"event": {"id": 1}, "relay": "a"}`,
+		`{"event": {"id": 1}, "relay": "b"}`,
+		`{"event": {"id": 2}, "relay": "a"}`,
+		`{"relay": "a"}`,
+		`{"relay": "b"}`,
+	)
+	if set, err := d.Process(ents); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 3 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+}
+
+func TestDedupBounded(t *testing.T) {
+	var tagger testTagger
+	foo, _ := tagger.NegotiateTag(`foo`)
+	clk := &sampleClock{t: time.Now()}
+	d := newTestDedup(t, DedupConfig{Max_Entries: 2000}, &tagger, clk)
+	for i := 0; i < 10000; i++ {
+		if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, fmt.Sprintf("%d", i))); len(set) != 1 {
+			t.Fatalf("unique entry %d was dropped", i)
+		}
+	}
+	if len(d.seen) != 2000 || len(d.ring) != 2000 {
+		t.Fatalf("dedup state is not bounded: %d %d", len(d.seen), len(d.ring))
+	}
+	//the oldest were forgotten, the newest were not
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `0`, `9999`)); len(set) != 1 || string(set[0].Data) != `0` {
+		t.Fatalf("bad eviction %d", len(set))
+	}
+}
+
+func TestDedupState(t *testing.T) {
+	var tagger testTagger
+	foo, _ := tagger.NegotiateTag(`foo`)
+	clk := &sampleClock{t: time.Now()}
+	cfg := DedupConfig{Window: `1m`, State_File: filepath.Join(t.TempDir(), `dedup.state`)}
+	d := newTestDedup(t, cfg, &tagger, clk)
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `a`, `b`)); len(set) != 2 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+	clk.t = clk.t.Add(30 * time.Second)
+	if set, _ := d.Process(dedupEnts(foo, `10.0.0.1`, `c`)); len(set) != 1 {
+		t.Fatalf("bad dedup count %d", len(set))
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	//a restarted processor with renumbered tags still remembers, but only within the window
+	var tagger2 testTagger
+	tagger2.NegotiateTag(`bar`)
+	foo2, _ := tagger2.NegotiateTag(`foo`)
+	clk.t = clk.t.Add(45 * time.Second)
+	d, err := NewDedup(cfg, &tagger2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = clk.now
+	set, _ := d.Process(dedupEnts(foo2, `10.0.0.1`, `a`, `b`, `c`))
+	if len(set) != 2 || string(set[0].Data) != `a` || string(set[1].Data) != `b` {
+		t.Fatalf("bad restored state %d", len(set))
+	}
+
+	if err = os.WriteFile(cfg.State_File, []byte(`garbage`), 0640); err != nil {
+		t.Fatal(err)
+	} else if _, err = NewDedup(cfg, &tagger2); err == nil {
+		t.Fatal("failed to catch a corrupt state file")
+	}
+}
@@ -0,0 +1,190 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	ExpressionProcessor string = `expression`
+
+	expressionActionDrop   = `drop`
+	expressionActionRoute  = `route`
+	expressionActionAttach = `attach`
+)
+
+var (
+	ErrMissingExpression  = errors.New("Expression is required")
+	ErrInvalidExprAction  = errors.New("Action must be one of drop, route, or attach")
+	ErrMissingRouteTag    = errors.New("Route-Tag is required with the route action")
+	ErrMissingExprEVName  = errors.New("EV-Name is required with the attach action")
+	ErrUnexpectedExprArgs = errors.New("Route-Tag, EV-Name, and EV-Value are only valid with their actions")
+)
+
+// ExpressionConfig applies an action to every entry that matches an expression, e.g.
+//
+//	Expression = tag == 'syslog' && src in 10.0.0.0/8 && json('level') >= 4
+//
+// The expression can reference tag, src, ts, and data along with the json, ev, and regex
+// extraction functions.  Strings should be single quoted, double quotes are consumed by the config parser.
+type ExpressionConfig struct {
+	Expression string // condition that selects entries
+	Action     string // drop (default), route, or attach
+	Route_Tag  string // tag matching entries are sent to with the route action
+	EV_Name    string // enumerated value name attached to matching entries with the attach action
+	EV_Value   string // expression evaluated for the attached value, defaults to true
+}
+
+// ExpressionLoadConfig loads the configuration for the expression processor
+func ExpressionLoadConfig(vc *config.VariableConfig) (c ExpressionConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	}
+	_, _, err = c.validate()
+	return
+}
+
+func (c *ExpressionConfig) validate() (cond, val exprNode, err error) {
+	if strings.TrimSpace(c.Expression) == `` {
+		err = ErrMissingExpression
+		return
+	}
+	if cond, err = parseExpression(c.Expression, true); err != nil {
+		err = fmt.Errorf("invalid Expression: %w", err)
+		return
+	}
+	switch c.action() {
+	case expressionActionDrop:
+		if c.Route_Tag != `` || c.EV_Name != `` || c.EV_Value != `` {
+			err = ErrUnexpectedExprArgs
+		}
+	case expressionActionRoute:
+		if c.Route_Tag == `` {
+			err = ErrMissingRouteTag
+		} else if c.EV_Name != `` || c.EV_Value != `` {
+			err = ErrUnexpectedExprArgs
+		} else if err = ingest.CheckTag(c.Route_Tag); err != nil {
+			err = fmt.Errorf("invalid Route-Tag %q: %w", c.Route_Tag, err)
+		}
+	case expressionActionAttach:
+		if c.EV_Name == `` {
+			err = ErrMissingExprEVName
+		} else if c.Route_Tag != `` {
+			err = ErrUnexpectedExprArgs
+		} else if c.EV_Value != `` {
+			if val, err = parseExpression(c.EV_Value, false); err != nil {
+				err = fmt.Errorf("invalid EV-Value: %w", err)
+			}
+		} else {
+			val = &exprConst{v: boolValue(true)}
+		}
+	default:
+		err = ErrInvalidExprAction
+	}
+	return
+}
+
+func (c *ExpressionConfig) action() string {
+	if a := strings.ToLower(strings.TrimSpace(c.Action)); a != `` {
+		return a
+	}
+	return expressionActionDrop
+}
+
+// NewExpression creates a new expression processor
+func NewExpression(cfg ExpressionConfig, tagger Tagger) (*Expression, error) {
+	e := &Expression{}
+	if err := e.init(cfg, tagger); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+type Expression struct {
+	nocloser
+	ExpressionConfig
+	tgr    Tagger
+	cond   exprNode
+	val    exprNode
+	action string
+	tag    entry.EntryTag
+}
+
+// Config updates the configuration for the expression processor
+func (e *Expression) Config(v interface{}, tagger Tagger) (err error) {
+	if v == nil {
+		err = ErrNilConfig
+	} else if cfg, ok := v.(ExpressionConfig); ok {
+		err = e.init(cfg, tagger)
+	} else {
+		err = fmt.Errorf("Invalid configuration, unknown type %T", v)
+	}
+	return
+}
+
+func (e *Expression) init(cfg ExpressionConfig, tagger Tagger) (err error) {
+	var cond, val exprNode
+	if cond, val, err = cfg.validate(); err != nil {
+		return
+	}
+	action := cfg.action()
+	var tag entry.EntryTag
+	if action == expressionActionRoute {
+		if tag, err = tagger.NegotiateTag(cfg.Route_Tag); err != nil {
+			return fmt.Errorf("Failed to negotiate tag %s: %w", cfg.Route_Tag, err)
+		}
+	}
+	e.ExpressionConfig = cfg
+	e.tgr = tagger
+	e.cond = cond
+	e.val = val
+	e.action = action
+	e.tag = tag
+	return
+}
+
+// Process applies the configured action to every entry that matches the expression
+func (e *Expression) Process(ents []*entry.Entry) (rset []*entry.Entry, err error) {
+	if len(ents) == 0 {
+		return
+	}
+	env := exprEnv{
+		tgr: e.tgr,
+		now: time.Now(),
+	}
+	rset = ents[:0]
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		env.reset(ent)
+		if e.cond.eval(&env).truthy() {
+			switch e.action {
+			case expressionActionDrop:
+				continue
+			case expressionActionRoute:
+				ent.Tag = e.tag
+			case expressionActionAttach:
+				if v := e.val.eval(&env).native(); v != nil {
+					ent.AddEnumeratedValueEx(e.EV_Name, v)
+				}
+			}
+		}
+		rset = append(rset, ent)
+	}
+	return
+}
@@ -0,0 +1,1467 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+// This file implements the small typed expression language used by the expression preprocessor.
+//
+// Expressions are parsed once into a tree of nodes, every node knows its static type so most
+// mistakes (comparing an IP to a boolean, adding a number to a network) are caught when
+// the config is loaded.  Values pulled out of JSON and enumerated values are typed at runtime,
+// mismatches there simply evaluate to false rather than failing the entry.
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+type exprType uint8
+
+const (
+	exprAny exprType = iota // only known at runtime
+	exprNull
+	exprBool
+	exprInt
+	exprFloat
+	exprString
+	exprIP
+	exprCIDR
+	exprTime
+	exprDuration
+	exprList
+)
+
+func (t exprType) String() string {
+	switch t {
+	case exprAny:
+		return `any`
+	case exprNull:
+		return `null`
+	case exprBool:
+		return `bool`
+	case exprInt:
+		return `int`
+	case exprFloat:
+		return `float`
+	case exprString:
+		return `string`
+	case exprIP:
+		return `ip`
+	case exprCIDR:
+		return `cidr`
+	case exprTime:
+		return `time`
+	case exprDuration:
+		return `duration`
+	case exprList:
+		return `list`
+	}
+	return `unknown`
+}
+
+func (t exprType) numeric() bool {
+	return t == exprInt || t == exprFloat
+}
+
+// exprValue is a runtime value, durations are stored in i as nanoseconds
+type exprValue struct {
+	t  exprType
+	b  bool
+	i  int64
+	f  float64
+	s  string
+	ip net.IP
+	n  *net.IPNet
+	tm time.Time
+	l  []exprValue
+}
+
+var nullValue = exprValue{t: exprNull}
+
+func boolValue(b bool) exprValue {
+	return exprValue{t: exprBool, b: b}
+}
+
+func (v exprValue) truthy() bool {
+	return v.t == exprBool && v.b
+}
+
+func (v exprValue) float() float64 {
+	if v.t == exprInt {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+// native converts the value into something that can be attached as an enumerated value
+func (v exprValue) native() interface{} {
+	switch v.t {
+	case exprBool:
+		return v.b
+	case exprInt:
+		return v.i
+	case exprFloat:
+		return v.f
+	case exprIP:
+		return v.ip
+	case exprTime:
+		return v.tm
+	case exprDuration:
+		return time.Duration(v.i)
+	case exprNull:
+		return nil
+	}
+	return v.String()
+}
+
+func (v exprValue) String() string {
+	switch v.t {
+	case exprBool:
+		return strconv.FormatBool(v.b)
+	case exprInt:
+		return strconv.FormatInt(v.i, 10)
+	case exprFloat:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	case exprString:
+		return v.s
+	case exprIP:
+		return v.ip.String()
+	case exprCIDR:
+		return v.n.String()
+	case exprTime:
+		return v.tm.Format(time.RFC3339Nano)
+	case exprDuration:
+		return time.Duration(v.i).String()
+	case exprList:
+		s := make([]string, 0, len(v.l))
+		for _, x := range v.l {
+			s = append(s, x.String())
+		}
+		return `[` + strings.Join(s, `, `) + `]`
+	}
+	return ``
+}
+
+// nativeValue converts enumerated values into expression values
+func nativeValue(val interface{}) exprValue {
+	switch x := val.(type) {
+	case nil:
+		return nullValue
+	case bool:
+		return boolValue(x)
+	case int8:
+		return exprValue{t: exprInt, i: int64(x)}
+	case uint8:
+		return exprValue{t: exprInt, i: int64(x)}
+	case int16:
+		return exprValue{t: exprInt, i: int64(x)}
+	case uint16:
+		return exprValue{t: exprInt, i: int64(x)}
+	case int32:
+		return exprValue{t: exprInt, i: int64(x)}
+	case uint32:
+		return exprValue{t: exprInt, i: int64(x)}
+	case int:
+		return exprValue{t: exprInt, i: int64(x)}
+	case int64:
+		return exprValue{t: exprInt, i: x}
+	case uint:
+		return exprValue{t: exprInt, i: int64(x)}
+	case uint64:
+		if x > math.MaxInt64 {
+			return exprValue{t: exprFloat, f: float64(x)}
+		}
+		return exprValue{t: exprInt, i: int64(x)}
+	case float32:
+		return exprValue{t: exprFloat, f: float64(x)}
+	case float64:
+		return exprValue{t: exprFloat, f: x}
+	case string:
+		return exprValue{t: exprString, s: x}
+	case []byte:
+		return exprValue{t: exprString, s: string(x)}
+	case net.IP:
+		return exprValue{t: exprIP, ip: x}
+	case time.Time:
+		return exprValue{t: exprTime, tm: x}
+	case entry.Timestamp:
+		return exprValue{t: exprTime, tm: x.StandardTime()}
+	case time.Duration:
+		return exprValue{t: exprDuration, i: int64(x)}
+	}
+	return exprValue{t: exprString, s: fmt.Sprintf("%v", val)}
+}
+
+// convert attempts to turn a value into the requested type, strings are parsed
+func (v exprValue) convert(t exprType) (r exprValue, ok bool) {
+	if v.t == t {
+		return v, true
+	}
+	switch t {
+	case exprInt:
+		switch v.t {
+		case exprFloat:
+			return exprValue{t: exprInt, i: int64(v.f)}, true
+		case exprString:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v.s), 10, 64); err == nil {
+				return exprValue{t: exprInt, i: i}, true
+			} else if f, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64); err == nil {
+				return exprValue{t: exprInt, i: int64(f)}, true
+			}
+		case exprBool:
+			if v.b {
+				return exprValue{t: exprInt, i: 1}, true
+			}
+			return exprValue{t: exprInt}, true
+		case exprDuration:
+			return exprValue{t: exprInt, i: v.i}, true
+		case exprTime:
+			return exprValue{t: exprInt, i: v.tm.Unix()}, true
+		}
+	case exprFloat:
+		switch v.t {
+		case exprInt:
+			return exprValue{t: exprFloat, f: float64(v.i)}, true
+		case exprString:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64); err == nil {
+				return exprValue{t: exprFloat, f: f}, true
+			}
+		case exprDuration:
+			return exprValue{t: exprFloat, f: time.Duration(v.i).Seconds()}, true
+		}
+	case exprString:
+		if v.t != exprNull {
+			return exprValue{t: exprString, s: v.String()}, true
+		}
+	case exprBool:
+		if v.t == exprString {
+			if b, err := strconv.ParseBool(strings.TrimSpace(v.s)); err == nil {
+				return boolValue(b), true
+			}
+		}
+	case exprIP:
+		if v.t == exprString {
+			if ip := net.ParseIP(strings.TrimSpace(v.s)); ip != nil {
+				return exprValue{t: exprIP, ip: ip}, true
+			}
+		}
+	case exprCIDR:
+		if v.t == exprString {
+			if _, n, err := net.ParseCIDR(strings.TrimSpace(v.s)); err == nil {
+				return exprValue{t: exprCIDR, n: n}, true
+			}
+		}
+	case exprTime:
+		if v.t == exprString {
+			if tm, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(v.s)); err == nil {
+				return exprValue{t: exprTime, tm: tm}, true
+			}
+		}
+	case exprDuration:
+		if v.t == exprString {
+			if d, err := time.ParseDuration(strings.TrimSpace(v.s)); err == nil {
+				return exprValue{t: exprDuration, i: int64(d)}, true
+			}
+		}
+	}
+	return nullValue, false
+}
+
+// compare orders two values, ok is false if they cannot be compared
+func compareValues(a, b exprValue) (c int, ok bool) {
+	if a.t == exprNull || b.t == exprNull {
+		return
+	}
+	//line up the types, strings are converted to whatever they are being compared with
+	if a.t != b.t {
+		if a.t.numeric() && b.t.numeric() {
+			return cmpFloat(a.float(), b.float()), true
+		} else if a.t == exprString {
+			if a, ok = a.convert(b.t); !ok {
+				return
+			}
+		} else if b.t == exprString {
+			if b, ok = b.convert(a.t); !ok {
+				return
+			}
+		} else {
+			return
+		}
+	}
+	ok = true
+	switch a.t {
+	case exprBool:
+		if a.b != b.b {
+			if a.b {
+				c = 1
+			} else {
+				c = -1
+			}
+		}
+	case exprInt, exprDuration:
+		if a.i < b.i {
+			c = -1
+		} else if a.i > b.i {
+			c = 1
+		}
+	case exprFloat:
+		c = cmpFloat(a.f, b.f)
+	case exprString:
+		c = strings.Compare(a.s, b.s)
+	case exprIP:
+		c = bytes.Compare(a.ip.To16(), b.ip.To16())
+	case exprTime:
+		c = a.tm.Compare(b.tm)
+	case exprCIDR:
+		if a.n.String() != b.n.String() {
+			c = 1
+		}
+	default:
+		ok = false
+	}
+	return
+}
+
+func cmpFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// exprEnv is the per-entry evaluation state
+type exprEnv struct {
+	ent  *entry.Entry
+	tgr  Tagger
+	now  time.Time
+	data *string // lazily converted entry data
+}
+
+func (env *exprEnv) reset(ent *entry.Entry) {
+	env.ent = ent
+	env.data = nil
+}
+
+func (env *exprEnv) dataString() string {
+	if env.data == nil {
+		s := string(env.ent.Data)
+		env.data = &s
+	}
+	return *env.data
+}
+
+type exprNode interface {
+	typ() exprType
+	eval(env *exprEnv) exprValue
+}
+
+type exprConst struct {
+	v exprValue
+}
+
+func (n *exprConst) typ() exprType           { return n.v.t }
+func (n *exprConst) eval(*exprEnv) exprValue { return n.v }
+
+const (
+	fieldTag  = `tag`
+	fieldSrc  = `src`
+	fieldTS   = `ts`
+	fieldData = `data`
+)
+
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) typ() exprType {
+	switch n.name {
+	case fieldSrc:
+		return exprIP
+	case fieldTS:
+		return exprTime
+	}
+	return exprString
+}
+
+func (n *fieldNode) eval(env *exprEnv) exprValue {
+	switch n.name {
+	case fieldTag:
+		if env.tgr != nil {
+			if name, ok := env.tgr.LookupTag(env.ent.Tag); ok {
+				return exprValue{t: exprString, s: name}
+			}
+		}
+		return nullValue
+	case fieldSrc:
+		if env.ent.SRC == nil {
+			return nullValue
+		}
+		return exprValue{t: exprIP, ip: env.ent.SRC}
+	case fieldTS:
+		return exprValue{t: exprTime, tm: env.ent.TS.StandardTime()}
+	case fieldData:
+		return exprValue{t: exprString, s: env.dataString()}
+	}
+	return nullValue
+}
+
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) typ() exprType { return exprBool }
+func (n *notNode) eval(env *exprEnv) exprValue {
+	return boolValue(!n.x.eval(env).truthy())
+}
+
+type logicNode struct {
+	and  bool
+	l, r exprNode
+}
+
+func (n *logicNode) typ() exprType { return exprBool }
+func (n *logicNode) eval(env *exprEnv) exprValue {
+	if n.l.eval(env).truthy() {
+		if !n.and {
+			return boolValue(true)
+		}
+	} else if n.and {
+		return boolValue(false)
+	}
+	return boolValue(n.r.eval(env).truthy())
+}
+
+type cmpNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (n *cmpNode) typ() exprType { return exprBool }
+func (n *cmpNode) eval(env *exprEnv) exprValue {
+	c, ok := compareValues(n.l.eval(env), n.r.eval(env))
+	switch n.op {
+	case `==`:
+		return boolValue(ok && c == 0)
+	case `!=`:
+		return boolValue(!ok || c != 0)
+	case `<`:
+		return boolValue(ok && c < 0)
+	case `<=`:
+		return boolValue(ok && c <= 0)
+	case `>`:
+		return boolValue(ok && c > 0)
+	case `>=`:
+		return boolValue(ok && c >= 0)
+	}
+	return boolValue(false)
+}
+
+type matchNode struct {
+	x      exprNode
+	rx     *regexp.Regexp
+	negate bool
+}
+
+func (n *matchNode) typ() exprType { return exprBool }
+func (n *matchNode) eval(env *exprEnv) exprValue {
+	var matched bool
+	if f, ok := n.x.(*fieldNode); ok && f.name == fieldData {
+		matched = n.rx.Match(env.ent.Data) //skip the string conversion
+	} else if v, ok := n.x.eval(env).convert(exprString); ok {
+		matched = n.rx.MatchString(v.s)
+	} else {
+		return boolValue(false)
+	}
+	return boolValue(matched != n.negate)
+}
+
+type inNode struct {
+	l, r   exprNode
+	negate bool
+}
+
+func (n *inNode) typ() exprType { return exprBool }
+func (n *inNode) eval(env *exprEnv) exprValue {
+	return boolValue(valueIn(n.l.eval(env), n.r.eval(env)) != n.negate)
+}
+
+func valueIn(v, set exprValue) bool {
+	if v.t == exprNull {
+		return false
+	}
+	switch set.t {
+	case exprCIDR:
+		if ip, ok := v.convert(exprIP); ok {
+			return set.n.Contains(ip.ip)
+		}
+	case exprString:
+		if s, ok := v.convert(exprString); ok {
+			return strings.Contains(set.s, s.s)
+		}
+	case exprList:
+		for _, x := range set.l {
+			if x.t == exprCIDR && v.t != exprCIDR {
+				if valueIn(v, x) {
+					return true
+				}
+			} else if c, ok := compareValues(v, x); ok && c == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type arithNode struct {
+	op   byte
+	t    exprType
+	l, r exprNode
+}
+
+func (n *arithNode) typ() exprType { return n.t }
+func (n *arithNode) eval(env *exprEnv) exprValue {
+	return arith(n.op, n.l.eval(env), n.r.eval(env))
+}
+
+func arith(op byte, a, b exprValue) exprValue {
+	if a.t == exprNull || b.t == exprNull {
+		return nullValue
+	}
+	//strings pulled out of JSON are frequently numbers
+	if a.t == exprString && b.t.numeric() {
+		if x, ok := a.convert(exprFloat); ok {
+			a = x
+		}
+	} else if b.t == exprString && a.t.numeric() {
+		if x, ok := b.convert(exprFloat); ok {
+			b = x
+		}
+	}
+	switch {
+	case a.t == exprInt && b.t == exprInt:
+		switch op {
+		case '+':
+			return exprValue{t: exprInt, i: a.i + b.i}
+		case '-':
+			return exprValue{t: exprInt, i: a.i - b.i}
+		case '*':
+			return exprValue{t: exprInt, i: a.i * b.i}
+		case '/':
+			if b.i != 0 {
+				return exprValue{t: exprInt, i: a.i / b.i}
+			}
+		case '%':
+			if b.i != 0 {
+				return exprValue{t: exprInt, i: a.i % b.i}
+			}
+		}
+	case a.t.numeric() && b.t.numeric():
+		x, y := a.float(), b.float()
+		switch op {
+		case '+':
+			return exprValue{t: exprFloat, f: x + y}
+		case '-':
+			return exprValue{t: exprFloat, f: x - y}
+		case '*':
+			return exprValue{t: exprFloat, f: x * y}
+		case '/':
+			if y != 0 {
+				return exprValue{t: exprFloat, f: x / y}
+			}
+		case '%':
+			if y != 0 {
+				return exprValue{t: exprFloat, f: math.Mod(x, y)}
+			}
+		}
+	case a.t == exprString && b.t == exprString && op == '+':
+		return exprValue{t: exprString, s: a.s + b.s}
+	case a.t == exprTime && b.t == exprDuration && (op == '+' || op == '-'):
+		d := time.Duration(b.i)
+		if op == '-' {
+			d = -d
+		}
+		return exprValue{t: exprTime, tm: a.tm.Add(d)}
+	case a.t == exprDuration && b.t == exprTime && op == '+':
+		return exprValue{t: exprTime, tm: b.tm.Add(time.Duration(a.i))}
+	case a.t == exprTime && b.t == exprTime && op == '-':
+		return exprValue{t: exprDuration, i: int64(a.tm.Sub(b.tm))}
+	case a.t == exprDuration && b.t == exprDuration && (op == '+' || op == '-'):
+		if op == '-' {
+			return exprValue{t: exprDuration, i: a.i - b.i}
+		}
+		return exprValue{t: exprDuration, i: a.i + b.i}
+	}
+	return nullValue
+}
+
+// arithType works out the static type of an arithmetic operation, ok is false if it can never succeed
+func arithType(op byte, a, b exprType) (t exprType, ok bool) {
+	if a == exprAny || b == exprAny {
+		return exprAny, true
+	}
+	//this mirrors arith above using zero values of each type
+	zero := func(t exprType) exprValue {
+		switch t {
+		case exprCIDR:
+			return exprValue{t: t, n: &net.IPNet{}}
+		case exprIP:
+			return exprValue{t: t, ip: net.IPv4zero}
+		}
+		return exprValue{t: t, i: 1, f: 1}
+	}
+	if r := arith(op, zero(a), zero(b)); r.t != exprNull {
+		return r.t, true
+	}
+	return exprNull, false
+}
+
+type negNode struct {
+	x exprNode
+}
+
+func (n *negNode) typ() exprType { return n.x.typ() }
+func (n *negNode) eval(env *exprEnv) exprValue {
+	v := n.x.eval(env)
+	switch v.t {
+	case exprInt, exprDuration:
+		v.i = -v.i
+	case exprFloat:
+		v.f = -v.f
+	case exprString:
+		if f, ok := v.convert(exprFloat); ok {
+			f.f = -f.f
+			return f
+		}
+		return nullValue
+	default:
+		return nullValue
+	}
+	return v
+}
+
+type listNode struct {
+	elems []exprNode
+}
+
+func (n *listNode) typ() exprType { return exprList }
+func (n *listNode) eval(env *exprEnv) exprValue {
+	l := make([]exprValue, 0, len(n.elems))
+	for _, e := range n.elems {
+		l = append(l, e.eval(env))
+	}
+	return exprValue{t: exprList, l: l}
+}
+
+type jsonNode struct {
+	path []string
+}
+
+func (n *jsonNode) typ() exprType { return exprAny }
+func (n *jsonNode) eval(env *exprEnv) exprValue {
+	v, dt, _, err := jsonparser.Get(env.ent.Data, n.path...)
+	if err != nil {
+		return nullValue
+	}
+	switch dt {
+	case jsonparser.String:
+		if s, err := jsonparser.ParseString(v); err == nil {
+			return exprValue{t: exprString, s: s}
+		}
+		return exprValue{t: exprString, s: string(v)}
+	case jsonparser.Number:
+		if i, err := jsonparser.ParseInt(v); err == nil {
+			return exprValue{t: exprInt, i: i}
+		} else if f, err := jsonparser.ParseFloat(v); err == nil {
+			return exprValue{t: exprFloat, f: f}
+		}
+	case jsonparser.Boolean:
+		if b, err := jsonparser.ParseBoolean(v); err == nil {
+			return boolValue(b)
+		}
+	case jsonparser.Null, jsonparser.NotExist:
+		return nullValue
+	}
+	return exprValue{t: exprString, s: string(v)}
+}
+
+type evNode struct {
+	name string
+}
+
+func (n *evNode) typ() exprType { return exprAny }
+func (n *evNode) eval(env *exprEnv) exprValue {
+	if v, ok := env.ent.GetEnumeratedValue(n.name); ok {
+		return nativeValue(v)
+	}
+	return nullValue
+}
+
+type regexNode struct {
+	rx  *regexp.Regexp
+	idx int
+}
+
+func (n *regexNode) typ() exprType { return exprString }
+func (n *regexNode) eval(env *exprEnv) exprValue {
+	m := n.rx.FindSubmatchIndex(env.ent.Data)
+	if m == nil || m[n.idx*2] < 0 {
+		return nullValue
+	}
+	return exprValue{t: exprString, s: string(env.ent.Data[m[n.idx*2]:m[n.idx*2+1]])}
+}
+
+type nowNode struct{}
+
+func (n *nowNode) typ() exprType { return exprTime }
+func (n *nowNode) eval(env *exprEnv) exprValue {
+	return exprValue{t: exprTime, tm: env.now}
+}
+
+// funcNode covers the general purpose functions that operate on evaluated arguments
+type funcNode struct {
+	fn   *exprFunc
+	args []exprNode
+}
+
+func (n *funcNode) typ() exprType { return n.fn.ret }
+func (n *funcNode) eval(env *exprEnv) exprValue {
+	var buff [2]exprValue
+	args := buff[:0]
+	for _, a := range n.args {
+		args = append(args, a.eval(env))
+	}
+	return n.fn.call(args)
+}
+
+type exprFunc struct {
+	args []exprType // argument types, any accepts everything
+	ret  exprType
+	call func(args []exprValue) exprValue
+}
+
+func stringFunc(fn func(string) string) *exprFunc {
+	return &exprFunc{
+		args: []exprType{exprString},
+		ret:  exprString,
+		call: func(args []exprValue) exprValue {
+			if s, ok := args[0].convert(exprString); ok {
+				return exprValue{t: exprString, s: fn(s.s)}
+			}
+			return nullValue
+		},
+	}
+}
+
+func stringTestFunc(fn func(string, string) bool) *exprFunc {
+	return &exprFunc{
+		args: []exprType{exprString, exprString},
+		ret:  exprBool,
+		call: func(args []exprValue) exprValue {
+			a, ok := args[0].convert(exprString)
+			if !ok {
+				return boolValue(false)
+			}
+			b, ok := args[1].convert(exprString)
+			return boolValue(ok && fn(a.s, b.s))
+		},
+	}
+}
+
+func convertFunc(t exprType) *exprFunc {
+	return &exprFunc{
+		args: []exprType{exprAny},
+		ret:  t,
+		call: func(args []exprValue) exprValue {
+			v, _ := args[0].convert(t)
+			return v
+		},
+	}
+}
+
+var exprFuncs = map[string]*exprFunc{
+	`lower`:    stringFunc(strings.ToLower),
+	`upper`:    stringFunc(strings.ToUpper),
+	`trim`:     stringFunc(strings.TrimSpace),
+	`contains`: stringTestFunc(strings.Contains),
+	`prefix`:   stringTestFunc(strings.HasPrefix),
+	`suffix`:   stringTestFunc(strings.HasSuffix),
+	`int`:      convertFunc(exprInt),
+	`float`:    convertFunc(exprFloat),
+	`string`:   convertFunc(exprString),
+	`ip`:       convertFunc(exprIP),
+	`len`: {
+		args: []exprType{exprAny},
+		ret:  exprInt,
+		call: func(args []exprValue) exprValue {
+			switch args[0].t {
+			case exprNull:
+				return nullValue
+			case exprList:
+				return exprValue{t: exprInt, i: int64(len(args[0].l))}
+			}
+			s, _ := args[0].convert(exprString)
+			return exprValue{t: exprInt, i: int64(len(s.s))}
+		},
+	},
+	`exists`: {
+		args: []exprType{exprAny},
+		ret:  exprBool,
+		call: func(args []exprValue) exprValue {
+			return boolValue(args[0].t != exprNull)
+		},
+	},
+}
+
+// assignable reports whether a value of static type have can be handed to something expecting want
+func assignable(have, want exprType) bool {
+	return want == exprAny || have == exprAny || have == want ||
+		(want == exprString && have != exprList) ||
+		(want.numeric() && have.numeric())
+}
+
+// comparable reports whether two static types can ever compare successfully
+func comparable(a, b exprType) bool {
+	switch {
+	case a == exprAny || b == exprAny || a == b:
+		return a != exprList && b != exprList
+	case a.numeric() && b.numeric():
+		return true
+	case a == exprString:
+		return b != exprList && b != exprBool
+	case b == exprString:
+		return a != exprList && a != exprBool
+	}
+	return false
+}
+
+// coerce converts string constants to the type they are being used as, this is what lets
+// the config say src == '10.0.0.1' or ts > '2024-01-01T00:00:00Z'
+func coerce(n exprNode, want exprType) (exprNode, error) {
+	if c, ok := n.(*exprConst); ok && c.v.t == exprString && want != exprString && want != exprAny && want != exprList {
+		v, ok := c.v.convert(want)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a valid %v", c.v.s, want)
+		}
+		return &exprConst{v: v}, nil
+	}
+	return n, nil
+}
+
+// coerceSet converts string constants on the right side of an in operator into networks and
+// addresses, strict requires every string to be one or the other
+func coerceSet(n exprNode, strict bool) (exprNode, error) {
+	switch x := n.(type) {
+	case *exprConst:
+		if x.v.t == exprString {
+			if v, ok := x.v.convert(exprCIDR); ok {
+				return &exprConst{v: v}, nil
+			} else if strict {
+				return nil, fmt.Errorf("%q is not a valid %v", x.v.s, exprCIDR)
+			}
+		}
+	case *listNode:
+		for i, e := range x.elems {
+			c, ok := e.(*exprConst)
+			if !ok || c.v.t != exprString {
+				continue
+			}
+			if v, ok := c.v.convert(exprCIDR); ok {
+				x.elems[i] = &exprConst{v: v}
+			} else if v, ok = c.v.convert(exprIP); ok && strict {
+				x.elems[i] = &exprConst{v: v}
+			} else if strict {
+				return nil, fmt.Errorf("%q is not a valid %v or %v", c.v.s, exprIP, exprCIDR)
+			}
+		}
+	}
+	return n, nil
+}
+
+// tokens
+
+type tokenType uint8
+
+const (
+	tokEOF tokenType = iota
+	tokIdent
+	tokString
+	tokLiteral // numbers, IPs, networks, and durations
+	tokOp
+)
+
+type token struct {
+	t   tokenType
+	s   string
+	pos int
+}
+
+var exprOps = map[string]struct{}{
+	`&&`: empty, `||`: empty, `!`: empty,
+	`==`: empty, `!=`: empty, `<`: empty, `<=`: empty, `>`: empty, `>=`: empty, `=~`: empty, `!~`: empty,
+	`+`: empty, `-`: empty, `*`: empty, `/`: empty, `%`: empty,
+	`(`: empty, `)`: empty, `[`: empty, `]`: empty, `,`: empty,
+}
+
+func lexExpression(s string) (toks []token, err error) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '\'' || c == '`':
+			//raw strings, a doubled quote is an escaped quote
+			start := i
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				} else if s[i] == c {
+					if i+1 < len(s) && s[i+1] == c {
+						sb.WriteByte(c)
+						i++
+						continue
+					}
+					break
+				}
+				sb.WriteByte(s[i])
+			}
+			i++
+			toks = append(toks, token{t: tokString, s: sb.String(), pos: start})
+		case c == '"':
+			start := i
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			var v string
+			if v, err = strconv.Unquote(s[start:i]); err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", start, err)
+			}
+			toks = append(toks, token{t: tokString, s: v, pos: start})
+		case c >= '0' && c <= '9':
+			start := i
+			var seps int
+			for ; i < len(s); i++ {
+				r := s[i]
+				if r == '.' || r == ':' {
+					seps++
+				} else if r == '/' {
+					//a slash is division unless this is shaping up to be a network
+					if seps < 2 || i+1 >= len(s) || s[i+1] < '0' || s[i+1] > '9' {
+						break
+					}
+				} else if !(r >= '0' && r <= '9') && !unicode.IsLetter(rune(r)) {
+					break
+				}
+			}
+			toks = append(toks, token{t: tokLiteral, s: s[start:i], pos: start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for ; i < len(s) && (s[i] == '_' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))); i++ {
+			}
+			toks = append(toks, token{t: tokIdent, s: s[start:i], pos: start})
+		default:
+			op := string(c)
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case `&&`, `||`, `==`, `!=`, `<=`, `>=`, `=~`, `!~`:
+					op = two
+				}
+			}
+			if _, ok := exprOps[op]; !ok {
+				return nil, fmt.Errorf("unexpected %q at offset %d", op, i)
+			}
+			toks = append(toks, token{t: tokOp, s: op, pos: i})
+			i += len(op)
+		}
+	}
+	toks = append(toks, token{t: tokEOF, pos: len(s)})
+	return
+}
+
+// parseLiteral works out what an unquoted literal is
+func parseLiteral(s string) (v exprValue, err error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return exprValue{t: exprInt, i: i}, nil
+	} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return exprValue{t: exprFloat, f: f}, nil
+	} else if d, err := time.ParseDuration(s); err == nil {
+		return exprValue{t: exprDuration, i: int64(d)}, nil
+	} else if ip := net.ParseIP(s); ip != nil {
+		return exprValue{t: exprIP, ip: ip}, nil
+	} else if _, n, err := net.ParseCIDR(s); err == nil {
+		return exprValue{t: exprCIDR, n: n}, nil
+	}
+	err = fmt.Errorf("invalid literal %q", s)
+	return
+}
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+// parseExpression compiles an expression, the result must be usable as a condition if cond is set
+func parseExpression(s string, cond bool) (n exprNode, err error) {
+	p := exprParser{}
+	if p.toks, err = lexExpression(s); err != nil {
+		return
+	}
+	if n, err = p.parseOr(); err != nil {
+		return
+	} else if t := p.peek(); t.t != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.s, t.pos)
+	}
+	if cond {
+		if t := n.typ(); t != exprBool && t != exprAny {
+			return nil, fmt.Errorf("expression is a %v, not a condition", t)
+		}
+	}
+	return
+}
+
+func (p *exprParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.t != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.t != tokOp && t.t != tokIdent {
+		return ``, false
+	}
+	for _, op := range ops {
+		if t.s == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return ``, false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		if t.t == tokEOF {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q at offset %d, found %q", op, t.pos, t.s)
+	}
+	return nil
+}
+
+func checkCondition(n exprNode, op string) error {
+	if t := n.typ(); t != exprBool && t != exprAny {
+		return fmt.Errorf("operator %s requires a condition, not a %v", op, t)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(`||`, `or`)
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		} else if err = checkCondition(l, op); err != nil {
+			return nil, err
+		} else if err = checkCondition(r, op); err != nil {
+			return nil, err
+		}
+		l = &logicNode{l: l, r: r}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(`&&`, `and`)
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		} else if err = checkCondition(l, op); err != nil {
+			return nil, err
+		} else if err = checkCondition(r, op); err != nil {
+			return nil, err
+		}
+		l = &logicNode{and: true, l: l, r: r}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if op, ok := p.accept(`!`, `not`); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		} else if err = checkCondition(x, op); err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	pos := p.peek().pos
+	op, ok := p.accept(`==`, `!=`, `<`, `<=`, `>`, `>=`, `=~`, `!~`, `in`, `not`)
+	if !ok {
+		return l, nil
+	}
+	if op == `not` {
+		if err = p.expect(`in`); err != nil {
+			return nil, err
+		}
+		op = `not in`
+	}
+	r, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case `=~`, `!~`:
+		c, ok := r.(*exprConst)
+		if !ok || c.v.t != exprString {
+			return nil, fmt.Errorf("operator %s at offset %d requires a quoted regular expression", op, pos)
+		} else if !assignable(l.typ(), exprString) {
+			return nil, fmt.Errorf("operator %s at offset %d cannot match a %v", op, pos, l.typ())
+		}
+		rx, err := regexp.Compile(c.v.s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", c.v.s, err)
+		}
+		return &matchNode{x: l, rx: rx, negate: op == `!~`}, nil
+	case `in`, `not in`:
+		lt := l.typ()
+		if lt != exprString {
+			if r, err = coerceSet(r, lt == exprIP); err != nil {
+				return nil, err
+			}
+		}
+		switch rt := r.typ(); {
+		case rt == exprCIDR:
+			if lt != exprIP && lt != exprString && lt != exprAny {
+				return nil, fmt.Errorf("a %v cannot be in a network", lt)
+			}
+		case rt == exprString:
+			if !assignable(lt, exprString) {
+				return nil, fmt.Errorf("a %v cannot be in a string", lt)
+			}
+		case rt != exprList && rt != exprAny:
+			return nil, fmt.Errorf("operator %s at offset %d requires a list, network, or string, not a %v", op, pos, rt)
+		}
+		return &inNode{l: l, r: r, negate: op == `not in`}, nil
+	}
+	if l, err = coerce(l, r.typ()); err != nil {
+		return nil, err
+	} else if r, err = coerce(r, l.typ()); err != nil {
+		return nil, err
+	} else if !comparable(l.typ(), r.typ()) {
+		return nil, fmt.Errorf("cannot compare %v %s %v at offset %d", l.typ(), op, r.typ(), pos)
+	}
+	return &cmpNode{op: op, l: l, r: r}, nil
+}
+
+func (p *exprParser) parseAdd() (exprNode, error) {
+	l, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.peek().pos
+		op, ok := p.accept(`+`, `-`)
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if l, err = newArith(op[0], l, r, pos); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseMul() (exprNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.peek().pos
+		op, ok := p.accept(`*`, `/`, `%`)
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if l, err = newArith(op[0], l, r, pos); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func newArith(op byte, l, r exprNode, pos int) (exprNode, error) {
+	var err error
+	//a time on one side turns a string constant on the other into a duration or time
+	if lt := l.typ(); lt == exprTime || lt == exprDuration {
+		want := exprDuration
+		if lt == exprTime && op == '-' {
+			if c, ok := r.(*exprConst); ok && c.v.t == exprString {
+				if _, ok := c.v.convert(exprTime); ok {
+					want = exprTime
+				}
+			}
+		}
+		if r, err = coerce(r, want); err != nil {
+			return nil, err
+		}
+	}
+	t, ok := arithType(op, l.typ(), r.typ())
+	if !ok {
+		return nil, fmt.Errorf("invalid operation %v %c %v at offset %d", l.typ(), op, r.typ(), pos)
+	}
+	return &arithNode{op: op, t: t, l: l, r: r}, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.accept(`-`); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch t := x.typ(); t {
+		case exprInt, exprFloat, exprDuration, exprAny:
+		default:
+			return nil, fmt.Errorf("cannot negate a %v", t)
+		}
+		//fold negative constants so they can be used as function arguments
+		if c, ok := x.(*exprConst); ok {
+			return &exprConst{v: (&negNode{x: c}).eval(nil)}, nil
+		}
+		return &negNode{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.t {
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tokString:
+		return &exprConst{v: exprValue{t: exprString, s: t.s}}, nil
+	case tokLiteral:
+		v, err := parseLiteral(t.s)
+		if err != nil {
+			return nil, fmt.Errorf("%w at offset %d", err, t.pos)
+		}
+		return &exprConst{v: v}, nil
+	case tokOp:
+		switch t.s {
+		case `(`:
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			} else if err = p.expect(`)`); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case `[`:
+			return p.parseList()
+		}
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.s, t.pos)
+	}
+	//identifiers
+	if _, ok := p.accept(`(`); ok {
+		return p.parseCall(t)
+	}
+	switch t.s {
+	case `true`, `false`:
+		return &exprConst{v: boolValue(t.s == `true`)}, nil
+	case fieldTag, fieldSrc, fieldTS, fieldData:
+		return &fieldNode{name: t.s}, nil
+	}
+	return nil, fmt.Errorf("unknown identifier %q at offset %d", t.s, t.pos)
+}
+
+func (p *exprParser) parseList() (exprNode, error) {
+	ln := &listNode{}
+	if _, ok := p.accept(`]`); ok {
+		return ln, nil
+	}
+	for {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		ln.elems = append(ln.elems, n)
+		if _, ok := p.accept(`]`); ok {
+			break
+		} else if err = p.expect(`,`); err != nil {
+			return nil, err
+		}
+	}
+	//constant lists are evaluated once
+	vals := make([]exprValue, 0, len(ln.elems))
+	for _, e := range ln.elems {
+		c, ok := e.(*exprConst)
+		if !ok {
+			return ln, nil
+		}
+		vals = append(vals, c.v)
+	}
+	if len(vals) > 0 && vals[0].t != exprString {
+		return &exprConst{v: exprValue{t: exprList, l: vals}}, nil
+	}
+	//string lists might still be coerced into addresses and networks
+	return ln, nil
+}
+
+func (p *exprParser) parseArgs() (args []exprNode, err error) {
+	if _, ok := p.accept(`)`); ok {
+		return
+	}
+	for {
+		var n exprNode
+		if n, err = p.parseOr(); err != nil {
+			return
+		}
+		args = append(args, n)
+		if _, ok := p.accept(`)`); ok {
+			return
+		} else if err = p.expect(`,`); err != nil {
+			return
+		}
+	}
+}
+
+// constStrings requires that every argument be a quoted string
+func constStrings(name string, args []exprNode, min, max int) (r []string, err error) {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return nil, fmt.Errorf("%s requires %d arguments", name, min)
+		}
+		return nil, fmt.Errorf("%s requires %d to %d arguments", name, min, max)
+	}
+	for _, a := range args {
+		c, ok := a.(*exprConst)
+		if !ok || c.v.t != exprString {
+			return nil, fmt.Errorf("%s arguments must be quoted strings", name)
+		}
+		r = append(r, c.v.s)
+	}
+	return
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	switch name.s {
+	case `json`:
+		s, err := constStrings(name.s, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{path: unquoteFields(splitRespectQuotes(s[0], dotSplitter))}, nil
+	case `ev`:
+		s, err := constStrings(name.s, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &evNode{name: s[0]}, nil
+	case `regex`:
+		s, err := constStrings(name.s, args, 1, 2)
+		if err != nil {
+			return nil, err
+		}
+		rx, err := regexp.Compile(s[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", s[0], err)
+		}
+		n := &regexNode{rx: rx}
+		if rx.NumSubexp() > 0 {
+			n.idx = 1 //first capture group by default
+		}
+		if len(s) == 2 {
+			if n.idx = rx.SubexpIndex(s[1]); n.idx < 0 {
+				if i, err := strconv.Atoi(s[1]); err == nil && i >= 0 && i <= rx.NumSubexp() {
+					n.idx = i
+				} else {
+					return nil, fmt.Errorf("regular expression %q has no group %q", s[0], s[1])
+				}
+			}
+		}
+		return n, nil
+	case `now`:
+		if len(args) != 0 {
+			return nil, fmt.Errorf("now takes no arguments")
+		}
+		return &nowNode{}, nil
+	}
+	fn, ok := exprFuncs[name.s]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at offset %d", name.s, name.pos)
+	} else if len(args) != len(fn.args) {
+		return nil, fmt.Errorf("%s requires %d arguments", name.s, len(fn.args))
+	}
+	for i := range args {
+		if args[i], err = coerce(args[i], fn.args[i]); err != nil {
+			return nil, err
+		} else if !assignable(args[i].typ(), fn.args[i]) {
+			return nil, fmt.Errorf("%s argument %d must be a %v, not a %v", name.s, i+1, fn.args[i], args[i].typ())
+		}
+	}
+	return &funcNode{fn: fn, args: args}, nil
+}
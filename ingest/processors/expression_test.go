@@ -0,0 +1,240 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func TestExpressionParse(t *testing.T) {
+	good := []string{
+		`tag == 'syslog' && src in 10.0.0.0/8 && json('level') >= 4`,
+		`src in ['10.0.0.0/8', '192.168.1.1'] || src == '::1'`,
+		`not (data =~ '^\d+$') and ts > '2024-01-01T00:00:00Z'`,
+		`ts < now() - 1h30m`,
+		`regex('user=(\w+)') in ['root', 'admin']`,
+		`len(data) > 1024 || contains(lower(data), 'error')`,
+		`ev('count') * 2 >= 10.5`,
+		`exists(json("a.b")) && tag not in ['foo', "bar"]`,
+		`data =~ 'it''s'`,
+		`json('x')`,
+	}
+	for _, v := range good {
+		if _, err := parseExpression(v, true); err != nil {
+			t.Fatalf("failed to parse %q: %v", v, err)
+		}
+	}
+	bad := []string{
+		``,
+		`tag ==`,
+		`tag == 'foo' &&`,
+		`(tag == 'foo'`,
+		`src == true`,
+		`src in 'notanetwork'`,
+		`ts > 'yesterday'`,
+		`data =~ '('`,
+		`data =~ tag`,
+		`len(data)`,
+		`tag + 1`,
+		`src + 1h`,
+		`-tag`,
+		`foo == 1`,
+		`bar(data)`,
+		`json(tag)`,
+		`regex('(a)', 'nope') == 'a'`,
+		`tag == 'a' & tag == 'b'`,
+		`'unterminated`,
+		`10.0.0.300 == src`,
+	}
+	for _, v := range bad {
+		if _, err := parseExpression(v, true); err == nil {
+			t.Fatalf("failed to catch bad expression %q", v)
+		}
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	var tagger testTagger
+	syslog, _ := tagger.NegotiateTag(`syslog`)
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ent := &entry.Entry{
+		TS:   entry.FromStandard(ts),
+		Tag:  syslog,
+		SRC:  net.ParseIP(`10.1.2.3`),
+		Data: []byte(`{"level": 5, "user": {"name": "root"}, "code": "404", "ok": true} user=bob`),
+	}
+	if err := ent.AddEnumeratedValueEx(`count`, int32(7)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`tag == 'syslog'`, true},
+		{`tag != 'syslog'`, false},
+		{`src in 10.0.0.0/8`, true},
+		{`src in 192.168.0.0/16`, false},
+		{`src not in ['192.168.0.0/16', '10.1.2.3']`, false},
+		{`src == '10.1.2.3'`, true},
+		{`json('level') >= 4`, true},
+		{`json('level') > 5`, false},
+		{`json('code') == 404`, true},
+		{`json('code') + 1 == 405`, true},
+		{`json('user.name') == 'root'`, true},
+		{`json('ok')`, true},
+		{`json('missing') == 'x'`, false},
+		{`json('missing') != 'x'`, true},
+		{`!exists(json('missing'))`, true},
+		{`regex('user=(\w+)') == 'bob'`, true},
+		{`regex('user=(?P<who>\w+)', 'who') in ['alice', 'bob']`, true},
+		{`regex('nomatch=(\w+)') == 'bob'`, false},
+		{`ev('count') == 7 && ev('count') * 2 > 13.5`, true},
+		{`ev('nope') > 1`, false},
+		{`ts > '2024-01-01T00:00:00Z' && ts < '2024-12-31T00:00:00Z'`, true},
+		{`ts - '2024-06-01T11:00:00Z' == 1h`, true},
+		{`ts + 1h > now()`, false},
+		{`data =~ 'user=\w+$' && data !~ 'admin'`, true},
+		{`'user' in data`, true},
+		{`len(data) > 10 && prefix(data, '{') && suffix(upper(data), 'BOB')`, true},
+		{`int('12') + float('0.5') == 12.5`, true},
+		{`(tag == 'nope' || src in 10.0.0.0/8) and not json('ok') == false`, true},
+		{`-json('level') < -4`, true},
+		{`json('level') / 0 == 0`, false},
+	}
+	env := exprEnv{tgr: &tagger, now: time.Now()}
+	for _, tst := range tests {
+		n, err := parseExpression(tst.expr, true)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tst.expr, err)
+		}
+		env.reset(ent)
+		if r := n.eval(&env).truthy(); r != tst.match {
+			t.Fatalf("%q evaluated to %v", tst.expr, r)
+		}
+	}
+}
+
+func TestExpressionConfig(t *testing.T) {
+	b := []byte(`
+	[preprocessor "expr"]
+		type = expression
+		Expression = "tag == 'syslog' && json('level') >= 4"
+		Action = route
+		Route-Tag = alerts
+	`)
+	var tc attachTestConfig
+	if err := config.LoadConfigBytes(&tc, b); err != nil {
+		t.Fatal(err)
+	}
+	vc := tc.Preprocessor[`expr`]
+	if cfg, err := ProcessorLoadConfig(vc); err != nil {
+		t.Fatal(err)
+	} else if ec, ok := cfg.(ExpressionConfig); !ok || ec.Route_Tag != `alerts` {
+		t.Fatalf("bad config %+v", cfg)
+	}
+	var tagger testTagger
+	if p, err := newProcessor(vc, &tagger); err != nil {
+		t.Fatal(err)
+	} else if _, ok := p.(*Expression); !ok {
+		t.Fatalf("bad processor type %T", p)
+	} else if _, ok = tagger.mp[`alerts`]; !ok {
+		t.Fatal("route tag was not negotiated")
+	}
+
+	bad := []ExpressionConfig{
+		{},
+		{Expression: `tag ==`},
+		{Expression: `len(data)`},
+		{Expression: `true`, Action: `explode`},
+		{Expression: `true`, Action: `route`},
+		{Expression: `true`, Action: `route`, Route_Tag: `bad tag`},
+		{Expression: `true`, Action: `attach`},
+		{Expression: `true`, Action: `attach`, EV_Name: `x`, EV_Value: `nope(`},
+		{Expression: `true`, Route_Tag: `foo`},
+	}
+	for i, v := range bad {
+		if _, _, err := v.validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d %+v", i, v)
+		}
+	}
+}
+
+func TestExpressionProcess(t *testing.T) {
+	var tagger testTagger
+	syslog, _ := tagger.NegotiateTag(`syslog`)
+	other, _ := tagger.NegotiateTag(`other`)
+	mk := func() []*entry.Entry {
+		return []*entry.Entry{
+			{Tag: syslog, SRC: net.ParseIP(`10.0.0.1`), Data: []byte(`{"level": 5}`)},
+			{Tag: syslog, SRC: net.ParseIP(`10.0.0.2`), Data: []byte(`{"level": 1}`)},
+			{Tag: other, SRC: net.ParseIP(`10.0.0.3`), Data: []byte(`{"level": 6}`)},
+			{Tag: syslog, SRC: net.ParseIP(`172.16.0.1`), Data: []byte(`{"level": 7}`)},
+		}
+	}
+	expr := `tag == 'syslog' && src in 10.0.0.0/8 && json('level') >= 4`
+
+	//drop
+	e, err := NewExpression(ExpressionConfig{Expression: expr}, &tagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := e.Process(mk())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(set) != 3 || set[0].SRC.String() != `10.0.0.2` {
+		t.Fatalf("bad drop result %d", len(set))
+	}
+
+	//route
+	if e, err = NewExpression(ExpressionConfig{Expression: expr, Action: `Route`, Route_Tag: `alerts`}, &tagger); err != nil {
+		t.Fatal(err)
+	}
+	alerts := tagger.mp[`alerts`]
+	if set, err = e.Process(mk()); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 4 {
+		t.Fatalf("route dropped entries: %d", len(set))
+	}
+	for i, ent := range set {
+		if (ent.Tag == alerts) != (i == 0) {
+			t.Fatalf("entry %d routed incorrectly to %d", i, ent.Tag)
+		}
+	}
+
+	//attach
+	cfg := ExpressionConfig{Expression: expr, Action: `attach`, EV_Name: `severity`, EV_Value: `'high-' + string(json('level'))`}
+	if e, err = NewExpression(cfg, &tagger); err != nil {
+		t.Fatal(err)
+	}
+	if set, err = e.Process(mk()); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := set[0].GetEnumeratedValue(`severity`); !ok || v != `high-5` {
+		t.Fatalf("bad attached value %v %v", v, ok)
+	}
+	for _, ent := range set[1:] {
+		if _, ok := ent.GetEnumeratedValue(`severity`); ok {
+			t.Fatal("value attached to a non-matching entry")
+		}
+	}
+	cfg.EV_Value = ``
+	if err = e.Config(cfg, &tagger); err != nil {
+		t.Fatal(err)
+	}
+	if set, err = e.Process(mk()); err != nil {
+		t.Fatal(err)
+	} else if v, ok := set[0].GetEnumeratedValue(`severity`); !ok || v != true {
+		t.Fatalf("bad default attached value %v %v", v, ok)
+	}
+}
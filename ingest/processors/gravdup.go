@@ -76,6 +76,8 @@ func NewGravwellForwarder(cfg GravwellForwarderConfig, tgr Tagger) (*GravwellFor
 		Auth:               cfg.Secret(),
 		LogLevel:           cfg.LogLevel(),
 		VerifyCert:         !cfg.InsecureSkipTLSVerification(),
+		PublicKey:          cfg.Client_Certificate,
+		PrivateKey:         cfg.Client_Key,
 		IngesterName:       GravwellForwarderProcessor,
 		IngesterVersion:    version.GetVersion(),
 		IngesterUUID:       uuid.New().String(),
@@ -0,0 +1,363 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+const (
+	KVExtractProcessor string = `kvextract`
+
+	kvFormatAuto = `auto`
+	kvFormatKV   = `kv`
+	kvFormatCEF  = `cef`
+	kvFormatLEEF = `leef`
+
+	kvTypeAuto     = `auto`
+	kvTypeString   = `string`
+	kvTypeInt      = `int`
+	kvTypeUint     = `uint`
+	kvTypeFloat    = `float`
+	kvTypeBool     = `bool`
+	kvTypeIP       = `ip`
+	kvTypeMAC      = `mac`
+	kvTypeTime     = `time`
+	kvTypeDuration = `duration`
+
+	defaultKVDelimiter = `=`
+	defaultKVQuotes    = `"`
+)
+
+var (
+	ErrInvalidKVFormat    = errors.New("Format must be one of auto, kv, cef, or leef")
+	ErrMissingKVFields    = errors.New("At least one Field or Timestamp-Field is required")
+	ErrInvalidKVField     = errors.New("Field must be formatted as name[:type[:ev-name]]")
+	ErrInvalidKVType      = errors.New("Field type must be one of auto, string, int, uint, float, bool, ip, mac, time, or duration")
+	ErrDuplicateKVEV      = errors.New("Duplicate enumerated value name")
+	ErrUnexpectedKVParams = errors.New("Pair-Delimiter, KV-Delimiter, and Quote are only valid with the auto and kv formats")
+)
+
+// KVExtractConfig parses CEF, LEEF 1.0 and 2.0, or generic key value data and attaches
+// selected fields as typed enumerated values, the entry data is not modified.
+// Fields are specified as name[:type[:ev-name]], for example:
+//
+//	Field=src:ip
+//	Field=spt:int:src_port
+//	Field=Severity
+//
+// CEF header fields are Version, DeviceVendor, DeviceProduct, DeviceVersion, SignatureID, Name, and Severity.
+// LEEF header fields are Version, Vendor, Product, ProductVersion, and EventID.
+type KVExtractConfig struct {
+	Format                string   // auto (default), kv, cef, or leef; auto looks for a CEF or LEEF header and falls back to kv
+	Field                 []string // fields to attach, the type defaults to auto and the name to the field name
+	Pair_Delimiter        string   // separator between kv pairs, defaults to whitespace
+	KV_Delimiter          string   // separator between a kv key and value, defaults to =
+	Quote                 string   // characters that may quote kv values, defaults to "
+	Timestamp_Field       string   // optional field that sets the entry timestamp
+	Timestamp_Override    string   // optional timegrinder format override for the timestamp and time typed fields
+	Assume_Local_Timezone bool
+	Drop_Misses           bool // drop entries where no field is found
+	Strict_Extraction     bool // with Drop-Misses, drop entries where any field is missing
+}
+
+type kvField struct {
+	name string
+	typ  string
+	ev   string
+}
+
+// KVExtractLoadConfig loads the configuration for the kvextract processor
+func KVExtractLoadConfig(vc *config.VariableConfig) (c KVExtractConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	}
+	_, err = c.validate()
+	return
+}
+
+func (c *KVExtractConfig) validate() (flds []kvField, err error) {
+	switch c.format() {
+	case kvFormatAuto, kvFormatKV:
+	case kvFormatCEF, kvFormatLEEF:
+		if c.Pair_Delimiter != `` || c.KV_Delimiter != `` || c.Quote != `` {
+			err = ErrUnexpectedKVParams
+			return
+		}
+	default:
+		err = ErrInvalidKVFormat
+		return
+	}
+	if c.Strict_Extraction && !c.Drop_Misses {
+		err = ErrMissStrictConflict
+		return
+	}
+	evs := map[string]bool{}
+	for _, v := range c.Field {
+		bits := strings.Split(v, `:`)
+		if len(bits) > 3 {
+			err = fmt.Errorf("%w: %q", ErrInvalidKVField, v)
+			return
+		}
+		f := kvField{name: strings.TrimSpace(bits[0]), typ: kvTypeAuto}
+		if len(bits) > 1 {
+			if t := strings.ToLower(strings.TrimSpace(bits[1])); t != `` {
+				f.typ = t
+			}
+		}
+		if f.ev = f.name; len(bits) > 2 {
+			f.ev = strings.TrimSpace(bits[2])
+		}
+		if f.name == `` || f.ev == `` {
+			err = fmt.Errorf("%w: %q", ErrInvalidKVField, v)
+			return
+		}
+		switch f.typ {
+		case kvTypeAuto, kvTypeString, kvTypeInt, kvTypeUint, kvTypeFloat, kvTypeBool,
+			kvTypeIP, kvTypeMAC, kvTypeTime, kvTypeDuration:
+		default:
+			err = fmt.Errorf("%w: %q", ErrInvalidKVType, f.typ)
+			return
+		}
+		if evs[f.ev] {
+			err = fmt.Errorf("%w: %q", ErrDuplicateKVEV, f.ev)
+			return
+		}
+		evs[f.ev] = true
+		flds = append(flds, f)
+	}
+	if len(flds) == 0 && c.Timestamp_Field == `` {
+		err = ErrMissingKVFields
+	} else if ov := strings.TrimSpace(c.Timestamp_Override); ov != `` {
+		err = timegrinder.ValidateFormatOverride(ov)
+	}
+	return
+}
+
+func (c *KVExtractConfig) format() string {
+	if f := strings.ToLower(strings.TrimSpace(c.Format)); f != `` {
+		return f
+	}
+	return kvFormatAuto
+}
+
+// NewKVExtractor creates a new kvextract processor
+func NewKVExtractor(cfg KVExtractConfig) (*KVExtractor, error) {
+	kv := &KVExtractor{}
+	if err := kv.init(cfg); err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+type KVExtractor struct {
+	nocloser
+	KVExtractConfig
+	format string
+	fields []kvField
+	kvp    kvParser
+	tg     *timegrinder.TimeGrinder
+	pairs  []kvPair
+}
+
+// Config updates the configuration for the kvextract processor
+func (kv *KVExtractor) Config(v interface{}) (err error) {
+	if v == nil {
+		err = ErrNilConfig
+	} else if cfg, ok := v.(KVExtractConfig); ok {
+		err = kv.init(cfg)
+	} else {
+		err = fmt.Errorf("Invalid configuration, unknown type %T", v)
+	}
+	return
+}
+
+func (kv *KVExtractor) init(cfg KVExtractConfig) (err error) {
+	var flds []kvField
+	if flds, err = cfg.validate(); err != nil {
+		return
+	}
+	var tg *timegrinder.TimeGrinder
+	if tg, err = timegrinder.New(timegrinder.Config{FormatOverride: cfg.Timestamp_Override}); err != nil {
+		return
+	}
+	if cfg.Assume_Local_Timezone {
+		tg.SetLocalTime()
+	}
+	kv.KVExtractConfig = cfg
+	kv.format = cfg.format()
+	kv.fields = flds
+	kv.tg = tg
+	kv.kvp = kvParser{
+		pairDelim: strings.TrimSpace(cfg.Pair_Delimiter),
+		kvDelim:   cfg.KV_Delimiter,
+		quotes:    cfg.Quote,
+	}
+	if kv.kvp.kvDelim == `` {
+		kv.kvp.kvDelim = defaultKVDelimiter
+	}
+	if kv.kvp.quotes == `` {
+		kv.kvp.quotes = defaultKVQuotes
+	}
+	return
+}
+
+// Process attaches the configured fields to every entry
+func (kv *KVExtractor) Process(ents []*entry.Entry) (rset []*entry.Entry, err error) {
+	if len(ents) == 0 {
+		return
+	}
+	rset = ents[:0]
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		if kv.extract(ent) {
+			rset = append(rset, ent)
+		}
+	}
+	return
+}
+
+// extract parses the entry and attaches values, it returns false if the entry should be dropped
+func (kv *KVExtractor) extract(ent *entry.Entry) bool {
+	kv.pairs = kv.parse(ent.Data, kv.pairs[:0])
+	var hits int
+	for _, f := range kv.fields {
+		if v, ok := kv.lookup(f.name); ok {
+			if val, ok := kv.convert(f.typ, v); ok {
+				if ent.AddEnumeratedValueEx(f.ev, val) == nil {
+					hits++
+				}
+			}
+		}
+	}
+	if kv.Timestamp_Field != `` {
+		if v, ok := kv.lookup(kv.Timestamp_Field); ok {
+			if ts, ok, err := kv.tg.Extract(v); err == nil && ok {
+				ent.TS = entry.FromStandard(ts)
+			}
+		}
+	}
+	if kv.Drop_Misses && len(kv.fields) > 0 {
+		if hits == 0 || (kv.Strict_Extraction && hits != len(kv.fields)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (kv *KVExtractor) parse(b []byte, pairs []kvPair) []kvPair {
+	var ok bool
+	switch kv.format {
+	case kvFormatCEF:
+		if idx := findHeader(b, cefPrefix); idx >= 0 {
+			pairs, _ = parseCEF(b[idx:], pairs)
+		}
+	case kvFormatLEEF:
+		if idx := findHeader(b, leefPrefix); idx >= 0 {
+			pairs, _ = parseLEEF(b[idx:], pairs)
+		}
+	case kvFormatKV:
+		pairs = kv.kvp.parse(b, pairs)
+	default:
+		if idx := findHeader(b, cefPrefix); idx >= 0 {
+			pairs, ok = parseCEF(b[idx:], pairs)
+		} else if idx = findHeader(b, leefPrefix); idx >= 0 {
+			pairs, ok = parseLEEF(b[idx:], pairs)
+		}
+		if !ok {
+			pairs = kv.kvp.parse(b, pairs[:0])
+		}
+	}
+	return pairs
+}
+
+// lookup returns the first value for a key
+func (kv *KVExtractor) lookup(name string) ([]byte, bool) {
+	for _, p := range kv.pairs {
+		if p.k == name {
+			return p.v, true
+		}
+	}
+	return nil, false
+}
+
+func (kv *KVExtractor) convert(typ string, v []byte) (val interface{}, ok bool) {
+	var err error
+	s := string(v)
+	switch typ {
+	case kvTypeString:
+		return s, true
+	case kvTypeInt:
+		val, err = strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case kvTypeUint:
+		val, err = strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	case kvTypeFloat:
+		val, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case kvTypeBool:
+		val, err = strconv.ParseBool(strings.TrimSpace(s))
+	case kvTypeIP:
+		return kvIP(s)
+	case kvTypeMAC:
+		val, err = net.ParseMAC(strings.TrimSpace(s))
+	case kvTypeDuration:
+		val, err = time.ParseDuration(strings.TrimSpace(s))
+	case kvTypeTime:
+		var ts time.Time
+		if ts, ok, err = kv.tg.Extract(v); err != nil || !ok {
+			return nil, false
+		}
+		return ts, true
+	default:
+		return kvInfer(s), true
+	}
+	return val, err == nil
+}
+
+func kvIP(s string) (interface{}, bool) {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return nil, false
+	} else if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return ip, true
+}
+
+// kvInfer picks the narrowest of int, float, bool, IP, and string
+func kvInfer(s string) interface{} {
+	if s == `` {
+		return s
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v
+	}
+	if c := s[0]; (c >= '0' && c <= '9') || c == '-' || c == '.' {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	if s == `true` || s == `false` {
+		return s == `true`
+	}
+	if v, ok := kvIP(s); ok {
+		return v
+	}
+	return s
+}
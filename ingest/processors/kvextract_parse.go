@@ -0,0 +1,297 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+var (
+	cefPrefix  = []byte(`CEF:`)
+	leefPrefix = []byte(`LEEF:`)
+
+	// header field names for CEF and LEEF, the version is the text following CEF: or LEEF:
+	cefHeaderNames  = []string{`Version`, `DeviceVendor`, `DeviceProduct`, `DeviceVersion`, `SignatureID`, `Name`, `Severity`}
+	leefHeaderNames = []string{`Version`, `Vendor`, `Product`, `ProductVersion`, `EventID`}
+)
+
+type kvPair struct {
+	k string
+	v []byte
+}
+
+// kvParser splits generic key value data
+type kvParser struct {
+	pairDelim string //empty means any run of whitespace
+	kvDelim   string
+	quotes    string
+}
+
+// findHeader locates a CEF or LEEF header, which is frequently preceded by a syslog header
+func findHeader(b, prefix []byte) int {
+	for off := 0; ; {
+		idx := bytes.Index(b[off:], prefix)
+		if idx < 0 {
+			return -1
+		}
+		idx += off
+		if idx == 0 || b[idx-1] == ' ' || b[idx-1] == '\t' {
+			return idx
+		}
+		off = idx + len(prefix)
+	}
+}
+
+// splitHeader pulls n pipe delimited fields off the front of b, pipes and backslashes may be escaped
+func splitHeader(b []byte, n int, escapes bool) (flds [][]byte, rest []byte, ok bool) {
+	start := 0
+	for i := 0; i < len(b) && len(flds) < n; i++ {
+		switch b[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case '|':
+			fld := b[start:i]
+			if escapes && bytes.IndexByte(fld, '\\') >= 0 {
+				fld = unescapeBytes(fld, func(c byte) (byte, bool) {
+					return c, c == '|' || c == '\\'
+				})
+			}
+			flds = append(flds, fld)
+			start = i + 1
+		}
+	}
+	if len(flds) != n {
+		return
+	}
+	return flds, b[start:], true
+}
+
+// parseCEF parses CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func parseCEF(b []byte, pairs []kvPair) ([]kvPair, bool) {
+	flds, ext, ok := splitHeader(b[len(cefPrefix):], len(cefHeaderNames), true)
+	if !ok {
+		return pairs, false
+	}
+	for i, fld := range flds {
+		pairs = append(pairs, kvPair{k: cefHeaderNames[i], v: fld})
+	}
+	return parseCEFExtension(ext, pairs), true
+}
+
+// parseCEFExtension handles the extension, where values are unquoted and may contain spaces.
+// A key is a run of key characters that starts after a space and ends at an unescaped equals sign.
+func parseCEFExtension(ext []byte, pairs []kvPair) []kvPair {
+	var keyStart, valStart int
+	var key string
+	haveKey := false
+	for i := 0; i < len(ext); i++ {
+		if ext[i] == '\\' {
+			i++
+			continue
+		} else if ext[i] != '=' {
+			continue
+		}
+		ks := i
+		for ks > 0 && isCEFKeyChar(ext[ks-1]) {
+			ks--
+		}
+		if ks == i || (ks > 0 && ext[ks-1] != ' ') {
+			continue //an equals sign in the middle of a value
+		}
+		if haveKey {
+			pairs = append(pairs, kvPair{k: key, v: unescapeCEFValue(ext[valStart:ks])})
+		}
+		keyStart, valStart = ks, i+1
+		key = string(ext[keyStart:i])
+		haveKey = true
+	}
+	if haveKey {
+		pairs = append(pairs, kvPair{k: key, v: unescapeCEFValue(ext[valStart:])})
+	}
+	return pairs
+}
+
+func isCEFKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == '.' || c == '-' || c == '[' || c == ']'
+}
+
+func unescapeCEFValue(v []byte) []byte {
+	v = bytes.TrimRight(v, " ")
+	if bytes.IndexByte(v, '\\') < 0 {
+		return v
+	}
+	return unescapeBytes(v, func(c byte) (byte, bool) {
+		switch c {
+		case '=', '\\':
+			return c, true
+		case 'n':
+			return '\n', true
+		case 'r':
+			return '\r', true
+		}
+		return c, false
+	})
+}
+
+// parseLEEF parses LEEF:1.0|Vendor|Product|Version|EventID|attributes and
+// LEEF:2.0|Vendor|Product|Version|EventID|Delimiter|attributes, attributes are tab delimited by default
+func parseLEEF(b []byte, pairs []kvPair) ([]kvPair, bool) {
+	flds, attrs, ok := splitHeader(b[len(leefPrefix):], len(leefHeaderNames), false)
+	if !ok {
+		return pairs, false
+	}
+	for i, fld := range flds {
+		pairs = append(pairs, kvPair{k: leefHeaderNames[i], v: fld})
+	}
+	delim := []byte{'\t'}
+	if bytes.HasPrefix(flds[0], []byte(`2`)) {
+		if idx := bytes.IndexByte(attrs, '|'); idx >= 0 {
+			if d, ok := leefDelimiter(attrs[:idx]); ok {
+				delim = d
+				attrs = attrs[idx+1:]
+			}
+		}
+	}
+	for _, attr := range bytes.Split(attrs, delim) {
+		if idx := bytes.IndexByte(attr, '='); idx > 0 {
+			pairs = append(pairs, kvPair{k: string(attr[:idx]), v: attr[idx+1:]})
+		}
+	}
+	return pairs, true
+}
+
+// leefDelimiter decodes the LEEF 2.0 delimiter field, which is a single character or a hex value like x09 or 0x5E
+func leefDelimiter(v []byte) ([]byte, bool) {
+	if len(v) == 1 {
+		return v, true
+	}
+	s := strings.ToLower(string(v))
+	if strings.HasPrefix(s, `0x`) {
+		s = s[2:]
+	} else if strings.HasPrefix(s, `x`) {
+		s = s[1:]
+	} else {
+		return nil, false
+	}
+	if len(s) == 0 || len(s) > 4 {
+		return nil, false
+	}
+	r, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || r == 0 {
+		return nil, false
+	}
+	return []byte(string(rune(r))), true
+}
+
+func (p *kvParser) parse(b []byte, pairs []kvPair) []kvPair {
+	for len(b) > 0 {
+		b = p.skipDelims(b)
+		if len(b) == 0 {
+			break
+		}
+		//find the key
+		end := p.nextDelim(b)
+		kidx := strings.Index(string(b[:end]), p.kvDelim)
+		if kidx <= 0 {
+			b = b[end:] //not a pair
+			continue
+		}
+		key := strings.TrimSpace(string(b[:kidx]))
+		b = b[kidx+len(p.kvDelim):]
+		if p.pairDelim != `` {
+			b = bytes.TrimLeft(b, " \t")
+		}
+
+		var val []byte
+		if len(b) > 0 && strings.IndexByte(p.quotes, b[0]) >= 0 {
+			val, b = p.quoted(b)
+			//anything between the closing quote and the next delimiter is dropped
+			b = b[p.nextDelim(b):]
+		} else {
+			end = p.nextDelim(b)
+			val = b[:end]
+			if p.pairDelim != `` {
+				val = bytes.TrimRight(val, " \t")
+			}
+			b = b[end:]
+		}
+		if key != `` {
+			pairs = append(pairs, kvPair{k: key, v: val})
+		}
+	}
+	return pairs
+}
+
+func (p *kvParser) skipDelims(b []byte) []byte {
+	for len(b) > 0 {
+		if b[0] == ' ' || b[0] == '\t' {
+			b = b[1:]
+		} else if p.pairDelim != `` && bytes.HasPrefix(b, []byte(p.pairDelim)) {
+			b = b[len(p.pairDelim):]
+		} else {
+			break
+		}
+	}
+	return b
+}
+
+func (p *kvParser) nextDelim(b []byte) int {
+	if p.pairDelim == `` {
+		if idx := bytes.IndexAny(b, " \t"); idx >= 0 {
+			return idx
+		}
+	} else if idx := bytes.Index(b, []byte(p.pairDelim)); idx >= 0 {
+		return idx
+	}
+	return len(b)
+}
+
+// quoted pulls a quoted value off the front of b, backslash escapes the quote and itself
+func (p *kvParser) quoted(b []byte) (val, rest []byte) {
+	q := b[0]
+	escaped := false
+	for i := 1; i < len(b); i++ {
+		switch {
+		case b[i] == '\\':
+			escaped = true
+			i++
+		case b[i] == q:
+			val = b[1:i]
+			if escaped {
+				val = unescapeBytes(val, func(c byte) (byte, bool) {
+					return c, c == q || c == '\\'
+				})
+			}
+			return val, b[i+1:]
+		}
+	}
+	//unterminated, take everything
+	return b[1:], nil
+}
+
+// unescapeBytes copies v, replacing backslash escapes accepted by the lookup
+func unescapeBytes(v []byte, lookup func(byte) (byte, bool)) []byte {
+	out := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			if c, ok := lookup(v[i+1]); ok {
+				out = append(out, c)
+				i++
+				continue
+			}
+		}
+		out = append(out, v[i])
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func kvPairMap(pairs []kvPair) map[string]string {
+	mp := map[string]string{}
+	for _, p := range pairs {
+		if _, ok := mp[p.k]; !ok {
+			mp[p.k] = string(p.v)
+		}
+	}
+	return mp
+}
+
+func checkPairs(t *testing.T, in string, pairs []kvPair, want map[string]string) {
+	t.Helper()
+	got := kvPairMap(pairs)
+	for k, v := range want {
+		if gv, ok := got[k]; !ok || gv != v {
+			t.Fatalf("%q: bad value for %q: %q != %q\n%v", in, k, gv, v, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%q: bad pair count %d != %d\n%v", in, len(got), len(want), got)
+	}
+}
+
+func TestKVExtractParseCEF(t *testing.T) {
+	in := `<134>Jan 01 12:00:00 fw01 CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed url=http://x.com/?a\=b&c=d cs1=line1\nline2 cs2Label=C:\\temp`
+	idx := findHeader([]byte(in), cefPrefix)
+	if idx < 0 {
+		t.Fatal("failed to find CEF header")
+	}
+	pairs, ok := parseCEF([]byte(in[idx:]), nil)
+	if !ok {
+		t.Fatal("failed to parse CEF")
+	}
+	checkPairs(t, in, pairs, map[string]string{
+		`Version`:       `0`,
+		`DeviceVendor`:  `Security`,
+		`DeviceProduct`: `threat|manager`,
+		`DeviceVersion`: `1.0`,
+		`SignatureID`:   `100`,
+		`Name`:          `worm successfully stopped`,
+		`Severity`:      `10`,
+		`src`:           `10.0.0.1`,
+		`dst`:           `2.1.2.2`,
+		`spt`:           `1232`,
+		`msg`:           `Detected a threat. No action needed`,
+		`url`:           `http://x.com/?a=b&c=d`,
+		`cs1`:           "line1\nline2",
+		`cs2Label`:      `C:\temp`,
+	})
+	if _, ok = parseCEF([]byte(`CEF:0|only|three`), nil); ok {
+		t.Fatal("failed to catch a short CEF header")
+	}
+}
+
+func TestKVExtractParseLEEF(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{
+			in: "LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tusrName=joe smith",
+			want: map[string]string{`Version`: `1.0`, `Vendor`: `Microsoft`, `Product`: `MSExchange`, `ProductVersion`: `4.0 SP1`,
+				`EventID`: `15345`, `src`: `192.0.2.0`, `dst`: `172.50.123.1`, `sev`: `5`, `usrName`: `joe smith`},
+		},
+		{
+			in: `Jan 01 host LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^proto=6`,
+			want: map[string]string{`Version`: `2.0`, `Vendor`: `Lancope`, `Product`: `StealthWatch`, `ProductVersion`: `1.0`,
+				`EventID`: `41`, `src`: `10.0.1.8`, `dst`: `10.0.0.5`, `proto`: `6`},
+		},
+		{
+			in: `LEEF:2.0|Vendor|Product|1.0|42|0x7C|src=1.1.1.1|dst=2.2.2.2`,
+			want: map[string]string{`Version`: `2.0`, `Vendor`: `Vendor`, `Product`: `Product`, `ProductVersion`: `1.0`,
+				`EventID`: `42`, `src`: `1.1.1.1`, `dst`: `2.2.2.2`},
+		},
+		{
+			in: "LEEF:2.0|Vendor|Product|1.0|43|src=1.1.1.1\tdst=2.2.2.2",
+			want: map[string]string{`Version`: `2.0`, `Vendor`: `Vendor`, `Product`: `Product`, `ProductVersion`: `1.0`,
+				`EventID`: `43`, `src`: `1.1.1.1`, `dst`: `2.2.2.2`},
+		},
+	}
+	for _, tst := range tests {
+		idx := findHeader([]byte(tst.in), leefPrefix)
+		if idx < 0 {
+			t.Fatalf("failed to find LEEF header in %q", tst.in)
+		}
+		pairs, ok := parseLEEF([]byte(tst.in[idx:]), nil)
+		if !ok {
+			t.Fatalf("failed to parse %q", tst.in)
+		}
+		checkPairs(t, tst.in, pairs, tst.want)
+	}
+}
+
+func TestKVExtractParseKV(t *testing.T) {
+	tests := []struct {
+		p    kvParser
+		in   string
+		want map[string]string
+	}{
+		{
+			p:    kvParser{kvDelim: `=`, quotes: `"`},
+			in:   `date=2024-01-01 action=deny  msg="hello \"world\"" user= bogus proto=tcp`,
+			want: map[string]string{`date`: `2024-01-01`, `action`: `deny`, `msg`: `hello "world"`, `user`: ``, `proto`: `tcp`},
+		},
+		{
+			p:    kvParser{pairDelim: `,`, kvDelim: `:`, quotes: `"'`},
+			in:   `a: 1, b : 'two, three' ,c:4,,d:"x:y"`,
+			want: map[string]string{`a`: `1`, `b`: `two, three`, `c`: `4`, `d`: `x:y`},
+		},
+		{
+			p:    kvParser{pairDelim: `;`, kvDelim: `=>`, quotes: `"`},
+			in:   `k1=>v1;k2=>v 2;k3=>"unterminated`,
+			want: map[string]string{`k1`: `v1`, `k2`: `v 2`, `k3`: `unterminated`},
+		},
+	}
+	for _, tst := range tests {
+		checkPairs(t, tst.in, tst.p.parse([]byte(tst.in), nil), tst.want)
+	}
+}
+
+func TestKVExtractConfig(t *testing.T) {
+	b := []byte(`
+	[preprocessor "fw"]
+		type = kvextract
+		Format = cef
+		Field = src:ip
+		Field = spt:int:src_port
+		Field = Severity
+		Timestamp-Field = rt
+		Drop-Misses = true
+	`)
+	var tc attachTestConfig
+	if err := config.LoadConfigBytes(&tc, b); err != nil {
+		t.Fatal(err)
+	}
+	vc := tc.Preprocessor[`fw`]
+	if cfg, err := ProcessorLoadConfig(vc); err != nil {
+		t.Fatal(err)
+	} else if kc, ok := cfg.(KVExtractConfig); !ok || len(kc.Field) != 3 {
+		t.Fatalf("bad config %+v", cfg)
+	}
+	var tagger testTagger
+	if p, err := newProcessor(vc, &tagger); err != nil {
+		t.Fatal(err)
+	} else if kv, ok := p.(*KVExtractor); !ok {
+		t.Fatalf("bad processor type %T", p)
+	} else if kv.fields[1] != (kvField{name: `spt`, typ: kvTypeInt, ev: `src_port`}) || kv.fields[2].typ != kvTypeAuto {
+		t.Fatalf("bad fields %+v", kv.fields)
+	}
+
+	bad := []KVExtractConfig{
+		{},
+		{Format: `xml`, Field: []string{`a`}},
+		{Format: `cef`, Field: []string{`a`}, Pair_Delimiter: `,`},
+		{Field: []string{`a:int:b:c`}},
+		{Field: []string{`:int`}},
+		{Field: []string{`a:complex`}},
+		{Field: []string{`a::`}},
+		{Field: []string{`a`, `b::a`}},
+		{Field: []string{`a`}, Strict_Extraction: true},
+		{Field: []string{`a`}, Timestamp_Override: `NotAFormat`},
+	}
+	for i, v := range bad {
+		if _, err := v.validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d %+v", i, v)
+		}
+	}
+}
+
+func TestKVExtractProcess(t *testing.T) {
+	kv, err := NewKVExtractor(KVExtractConfig{
+		Field: []string{
+			`src:ip`, `dpt:int:dst_port`, `bytes:uint`, `ratio:float`, `ok:bool`, `mac:mac`,
+			`dur:duration`, `start:time`, `Severity`, `act`, `cnt`,
+		},
+		Timestamp_Field: `rt`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	ents := []*entry.Entry{
+		{Data: []byte(`CEF:0|Vendor|FW|1.0|100|deny|7|src=10.1.1.1 dpt=443 act=deny all cnt=12 rt=2024-03-04T05:06:07Z`)},
+		{Data: []byte(`src=fe80::1 bytes=1024 ratio=0.5 ok=true mac=00:11:22:33:44:55 dur=1m30s start="2024-01-02T03:04:05Z"`)},
+		{Data: []byte(`dpt=notanumber`)},
+	}
+	set, err := kv.Process(ents)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(set) != 3 {
+		t.Fatalf("entries were dropped: %d", len(set))
+	}
+
+	want := []map[string]interface{}{
+		{`src`: net.ParseIP(`10.1.1.1`).To4(), `dst_port`: int64(443), `Severity`: int64(7), `act`: `deny all`, `cnt`: int64(12)},
+		{`src`: net.ParseIP(`fe80::1`), `bytes`: uint64(1024), `ratio`: 0.5, `ok`: true, `mac`: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55},
+			`dur`: 90 * time.Second, `start`: entry.FromStandard(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))},
+		{},
+	}
+	for i, ent := range set {
+		if ent.EVB.Count() != len(want[i]) {
+			t.Fatalf("entry %d has %d values, expected %d", i, ent.EVB.Count(), len(want[i]))
+		}
+		for k, v := range want[i] {
+			if ev, ok := ent.GetEnumeratedValue(k); !ok {
+				t.Fatalf("entry %d is missing %s", i, k)
+			} else if fmt.Sprintf("%T %v", ev, ev) != fmt.Sprintf("%T %v", v, v) {
+				t.Fatalf("entry %d bad value for %s: %T %v != %T %v", i, k, ev, ev, v, v)
+			}
+		}
+	}
+	if !set[0].TS.StandardTime().Equal(ts) {
+		t.Fatalf("timestamp was not set: %v", set[0].TS)
+	}
+
+	//drop misses and strict extraction
+	kv, err = NewKVExtractor(KVExtractConfig{Field: []string{`a:int`, `b`}, Drop_Misses: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mk := func() []*entry.Entry {
+		return []*entry.Entry{
+			{Data: []byte(`a=1 b=2`)},
+			{Data: []byte(`a=1`)},
+			{Data: []byte(`a=x`)},
+			{Data: []byte(`nothing here`)},
+		}
+	}
+	if set, _ = kv.Process(mk()); len(set) != 2 {
+		t.Fatalf("bad drop misses count %d", len(set))
+	}
+	cfg := kv.KVExtractConfig
+	cfg.Strict_Extraction = true
+	if err = kv.Config(cfg); err != nil {
+		t.Fatal(err)
+	} else if set, _ = kv.Process(mk()); len(set) != 1 {
+		t.Fatalf("bad strict count %d", len(set))
+	}
+}
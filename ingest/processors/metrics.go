@@ -0,0 +1,56 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
+)
+
+var (
+	dropCounters sync.Map // preprocessor name -> *atomic.Uint64
+)
+
+func init() {
+	metrics.Register(metrics.CollectorFunc(collectDrops))
+}
+
+// dropCounter returns the shared drop counter for a preprocessor, sets that use the
+// same preprocessor configuration block share a counter
+func dropCounter(name string) *atomic.Uint64 {
+	v, _ := dropCounters.LoadOrStore(name, &atomic.Uint64{})
+	return v.(*atomic.Uint64)
+}
+
+// processorName is used when a processor is added without a configuration name
+func processorName(p Processor) string {
+	name := fmt.Sprintf("%T", p)
+	if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.ToLower(name)
+}
+
+func collectDrops(w *metrics.Writer) {
+	var names []string
+	dropCounters.Range(func(k, v any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	for _, name := range names {
+		w.Counter(`gravwell_ingest_preprocessor_dropped`, `Entries dropped by a preprocessor`,
+			float64(dropCounter(name).Load()), metrics.L(`preprocessor`, name))
+	}
+}
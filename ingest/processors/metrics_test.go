@@ -0,0 +1,54 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/metrics"
+)
+
+func TestDropMetrics(t *testing.T) {
+	var tw testWriter
+	ps := NewProcessorSet(&tw)
+	p, err := NewRegexDropper(RegexDropConfig{Regex: `^drop`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.addNamedProcessor(`metricsdrop`, p)
+	ents := []*entry.Entry{
+		{Data: []byte(`drop a`)},
+		{Data: []byte(`keep b`)},
+		{Data: []byte(`drop c`)},
+		{Data: []byte(`drop d`)},
+	}
+	if err = ps.ProcessBatch(ents); err != nil {
+		t.Fatal(err)
+	}
+	if n := dropCounter(`metricsdrop`).Load(); n != 3 {
+		t.Fatalf("bad drop count %d", n)
+	} else if len(tw.ents) != 1 {
+		t.Fatalf("bad output count %d", len(tw.ents))
+	}
+	//unnamed processors are reported by type
+	if name := processorName(p); name != `regexdropper` {
+		t.Fatalf("bad processor name %q", name)
+	}
+
+	bb := bytes.NewBuffer(nil)
+	if err = metrics.Default.WriteText(bb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bb.String(), `gravwell_ingest_preprocessor_dropped_total{preprocessor="metricsdrop"} 3`) {
+		t.Fatalf("drop counter missing from metrics:\n%s", bb.String())
+	}
+}
@@ -17,8 +17,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest/attach"
 	"github.com/gravwell/gravwell/v3/ingest/config"
@@ -38,13 +43,53 @@ var (
 	ErrNotReady         = errors.New("ProcessorSet not ready")
 	ErrInvalidEntry     = errors.New("ErrInvalidEntry")
 
+	// expireInterval is how often a set checks processors that hold entries for a window
+	expireInterval = 250 * time.Millisecond
+
 	emptyStruct = []byte(`{}`)
+
+	// processorTypes is the registry of preprocessor Types available on every platform, osProcessorTypes
+	// holds the platform specific ones.  CheckProcessor and ProcessorTypes are both driven by these lists
+	// and ProcessorLoadConfig must handle everything in them.
+	processorTypes = []string{
+		CSVRouterProcessor,
+		CiscoISEProcessor,
+		DropProcessor,
+		ForwarderProcessor,
+		GravwellForwarderProcessor,
+		GzipProcessor,
+		JsonArraySplitProcessor,
+		JsonExtractProcessor,
+		JsonFilterProcessor,
+		JsonRouterProcessor,
+		JsonTimestampProcessor,
+		PluginProcessor,
+		RegexExtractProcessor,
+		RegexRouterProcessor,
+		RegexTimestampProcessor,
+		SrcRouterProcessor,
+		VpcProcessor,
+		CorelightProcessor,
+		SyslogRouterProcessor,
+		TagSrcRouterProcessor,
+		RegexReplaceProcessor,
+		RegexDropProcessor,
+		AttachProcessor,
+		ExpressionProcessor,
+		SampleProcessor,
+		DedupProcessor,
+		RedactProcessor,
+		KVExtractProcessor,
+	}
 )
 
 type ProcessorSet struct {
 	sync.Mutex
-	wtr entWriter
-	set []Processor
+	wtr     entWriter
+	set     []Processor
+	drops   []*atomic.Uint64 // entries dropped by each processor in the set
+	expStop chan struct{}    // stops the expiration routine, nil if no processor expires entries
+	expDone chan struct{}
 }
 
 type ProcessorConfig map[string]*config.VariableConfig
@@ -56,38 +101,52 @@ type Processor interface {
 	Close() error //give the processor a chance to tidy up
 }
 
+// expirer is implemented by processors that hold entries for a window, the set calls Expire
+// every expireInterval so held entries are released even when nothing new arrives
+type expirer interface {
+	Expire() []*entry.Entry
+}
+
 func CheckProcessor(id string) error {
 	id = strings.TrimSpace(strings.ToLower(id))
-	switch id {
-	case CSVRouterProcessor:
-	case CiscoISEProcessor:
-	case DropProcessor:
-	case ForwarderProcessor:
-	case GravwellForwarderProcessor:
-	case GzipProcessor:
-	case JsonArraySplitProcessor:
-	case JsonExtractProcessor:
-	case JsonFilterProcessor:
-	case JsonRouterProcessor:
-	case JsonTimestampProcessor:
-	case PluginProcessor:
-	case RegexExtractProcessor:
-	case RegexRouterProcessor:
-	case RegexTimestampProcessor:
-	case SrcRouterProcessor:
-	case VpcProcessor:
-	case CorelightProcessor:
-	case SyslogRouterProcessor:
-	case TagSrcRouterProcessor:
-	case RegexReplaceProcessor:
-	case RegexDropProcessor:
-	case AttachProcessor:
-	default:
-		return checkProcessorOS(id)
+	if !slices.Contains(processorTypes, id) && !slices.Contains(osProcessorTypes, id) {
+		return ErrUnknownProcessor
 	}
 	return nil
 }
 
+// ProcessorTypes returns the sorted Type identifiers of every preprocessor supported on this platform
+func ProcessorTypes() (r []string) {
+	r = append(r, processorTypes...)
+	r = append(r, osProcessorTypes...)
+	sort.Strings(r)
+	return
+}
+
+// ProcessorConfigType returns the type of the configuration structure that ProcessorLoadConfig
+// produces for the given preprocessor Type, the structure's exported fields are the keys
+// accepted in a preprocessor block.
+func ProcessorConfigType(id string) (t reflect.Type, err error) {
+	id = strings.TrimSpace(strings.ToLower(id))
+	if err = CheckProcessor(id); err != nil {
+		return
+	}
+	var c struct {
+		Preprocessor ProcessorConfig
+	}
+	if err = config.LoadConfigBytes(&c, []byte(fmt.Sprintf("[%s \"x\"]\n%s=%s\n", preProcSectName, preProcTypeName, id))); err != nil {
+		return
+	}
+	//an empty block rarely passes validation, all we want is the type that comes back
+	cfg, _ := ProcessorLoadConfig(c.Preprocessor[`x`])
+	if cfg == nil {
+		err = ErrUnknownProcessor
+		return
+	}
+	t = reflect.TypeOf(cfg)
+	return
+}
+
 type Tagger interface {
 	NegotiateTag(name string) (entry.EntryTag, error)
 	LookupTag(entry.EntryTag) (string, bool)
@@ -157,6 +216,16 @@ func ProcessorLoadConfig(vc *config.VariableConfig) (cfg interface{}, err error)
 		cfg, err = RegexDropLoadConfig(vc)
 	case AttachProcessor:
 		cfg, err = AttachLoadConfig(vc)
+	case ExpressionProcessor:
+		cfg, err = ExpressionLoadConfig(vc)
+	case SampleProcessor:
+		cfg, err = SampleLoadConfig(vc)
+	case DedupProcessor:
+		cfg, err = DedupLoadConfig(vc)
+	case RedactProcessor:
+		cfg, err = RedactLoadConfig(vc)
+	case KVExtractProcessor:
+		cfg, err = KVExtractLoadConfig(vc)
 	default:
 		cfg, err = processorLoadConfigOS(vc)
 	}
@@ -344,6 +413,36 @@ func newProcessor(vc *config.VariableConfig, tgr Tagger) (p Processor, err error
 			return
 		}
 		p, err = NewAttachProcessor(cfg)
+	case ExpressionProcessor:
+		var cfg ExpressionConfig
+		if cfg, err = ExpressionLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewExpression(cfg, tgr)
+	case SampleProcessor:
+		var cfg SampleConfig
+		if cfg, err = SampleLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewSample(cfg)
+	case DedupProcessor:
+		var cfg DedupConfig
+		if cfg, err = DedupLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewDedup(cfg, tgr)
+	case RedactProcessor:
+		var cfg RedactConfig
+		if cfg, err = RedactLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewRedact(cfg)
+	case KVExtractProcessor:
+		var cfg KVExtractConfig
+		if cfg, err = KVExtractLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewKVExtractor(cfg)
 	default:
 		p, err = newProcessorOS(vc, tgr)
 	}
@@ -369,9 +468,62 @@ func (pr *ProcessorSet) Count() int {
 }
 
 func (pr *ProcessorSet) AddProcessor(p Processor) {
+	pr.addNamedProcessor(processorName(p), p)
+}
+
+// addNamedProcessor adds a processor whose drops are reported under the given name
+func (pr *ProcessorSet) addNamedProcessor(name string, p Processor) {
 	pr.Lock()
 	defer pr.Unlock()
 	pr.set = append(pr.set, p)
+	pr.drops = append(pr.drops, dropCounter(name))
+	if _, ok := p.(expirer); ok && pr.expStop == nil {
+		pr.expStop, pr.expDone = make(chan struct{}), make(chan struct{})
+		go pr.expireRoutine(pr.expStop, pr.expDone)
+	}
+}
+
+func (pr *ProcessorSet) expireRoutine(stop, done chan struct{}) {
+	defer close(done)
+	tckr := time.NewTicker(expireInterval)
+	defer tckr.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tckr.C:
+			pr.Lock()
+			pr.expire()
+			pr.Unlock()
+		}
+	}
+}
+
+// expire sends anything the processors release down through the rest of the set, entries
+// that can't be written are counted as drops by the processor that released them.
+// caller MUST HOLD THE LOCK
+func (pr *ProcessorSet) expire() {
+	if pr.wtr == nil {
+		return
+	}
+	for i, v := range pr.set {
+		e, ok := v.(expirer)
+		if !ok {
+			continue
+		}
+		ents := e.Expire()
+		if len(ents) == 0 {
+			continue
+		}
+		n := len(ents)
+		var err error
+		if ents, err = pr.processItemsOnFlush(pr.set[i+1:], ents); err == nil && len(ents) > 0 {
+			err = pr.writeSet(ents)
+		}
+		if err != nil {
+			pr.drops[i].Add(uint64(n))
+		}
+	}
 }
 
 func (pr *ProcessorSet) Process(ent *entry.Entry) (err error) {
@@ -487,6 +639,9 @@ func (pr *ProcessorSet) processItems(ents []*entry.Entry) (set []*entry.Entry, e
 			}
 			break // something intentionally returned an error, break out
 		}
+		if d := len(orig) - len(set); d > 0 {
+			pr.drops[i].Add(uint64(d))
+		}
 	}
 	return
 }
@@ -506,21 +661,41 @@ func (pr *ProcessorSet) processItemsOnFlush(prs []Processor, ents []*entry.Entry
 	return
 }
 
+// Flush pushes any entries held by the preprocessors through the rest of the set and out to
+// the writer without closing anything, so a following sync on the writer covers them.
+func (pr *ProcessorSet) Flush() (err error) {
+	pr.Lock()
+	defer pr.Unlock()
+	for i, v := range pr.set {
+		if v != nil {
+			err = addError(pr.flushProcessor(i, v), err)
+		}
+	}
+	return
+}
+
+// flushProcessor flushes a single preprocessor down through the processors that follow it
+func (pr *ProcessorSet) flushProcessor(i int, v Processor) (err error) {
+	if ents := v.Flush(); len(ents) > 0 {
+		if ents, err = pr.processItemsOnFlush(pr.set[i+1:], ents); err == nil && len(ents) > 0 {
+			err = pr.writeSet(ents)
+		}
+	}
+	return
+}
+
 // Close will close the underlying preprocessors within the set.
 // This function DOES NOT close the ingest muxer handle.
 // It is ONLY for shutting down preprocessors
 func (pr *ProcessorSet) Close() (err error) {
+	if pr.expStop != nil {
+		close(pr.expStop)
+		<-pr.expDone
+		pr.expStop = nil
+	}
 	for i, v := range pr.set {
 		if v != nil {
-			if ents := v.Flush(); len(ents) > 0 {
-				if ents, lerr := pr.processItemsOnFlush(pr.set[i+1:], ents); lerr != nil {
-					err = addError(lerr, err)
-				} else if len(ents) > 0 {
-					if lerr := pr.writeSet(ents); lerr != nil {
-						err = addError(lerr, err)
-					}
-				}
-			}
+			err = addError(pr.flushProcessor(i, v), err)
 			if lerr := v.Close(); lerr != nil {
 				err = addError(lerr, err)
 			}
@@ -555,7 +730,7 @@ func (pc ProcessorConfig) ProcessorSet(t tagWriter, names []string) (pr *Process
 			err = fmt.Errorf("%s %v", n, err)
 			return
 		}
-		pr.AddProcessor(p)
+		pr.addNamedProcessor(n, p)
 	}
 	return
 }
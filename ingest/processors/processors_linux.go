@@ -14,13 +14,8 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 )
 
-func checkProcessorOS(id string) error {
-	switch id {
-	case PersistentBufferProcessor:
-	default:
-		return ErrUnknownProcessor
-	}
-	return nil
+var osProcessorTypes = []string{
+	PersistentBufferProcessor,
 }
 
 func processorLoadConfigOS(vc *config.VariableConfig) (cfg interface{}, err error) {
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"sync"
 	"testing"
 
@@ -49,6 +50,32 @@ func TestCheckProcessors(t *testing.T) {
 	}
 }
 
+func TestProcessorConfigType(t *testing.T) {
+	seen := map[string]bool{}
+	for _, id := range ProcessorTypes() {
+		if seen[id] {
+			t.Fatalf("%s is registered twice", id)
+		}
+		seen[id] = true
+		if err := CheckProcessor(id); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if ct, err := ProcessorConfigType(id); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		} else if ct.Kind() != reflect.Struct {
+			t.Fatalf("%s: config is not a structure: %v", id, ct)
+		}
+	}
+	if ct, err := ProcessorConfigType(` GzIp `); err != nil {
+		t.Fatal(err)
+	} else if ct != reflect.TypeOf(GzipDecompressorConfig{}) {
+		t.Fatalf("bad gzip config type %v", ct)
+	}
+	if _, err := ProcessorConfigType(`nope`); err != ErrUnknownProcessor {
+		t.Fatalf("bad unknown processor error %v", err)
+	}
+}
+
 func TestEmptyProcessorSet(t *testing.T) {
 	ps := NewProcessorSet(nil)
 	ent := entry.Entry{
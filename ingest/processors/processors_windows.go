@@ -14,14 +14,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 )
 
-func checkProcessorOS(id string) error {
-	id = strings.TrimSpace(strings.ToLower(id))
-	switch id {
-	default:
-		return ErrUnknownProcessor
-	}
-	return nil
-}
+var osProcessorTypes []string
 
 func processorLoadConfigOS(vc *config.VariableConfig) (cfg interface{}, err error) {
 	var pb preprocessorBase
@@ -0,0 +1,394 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	RedactProcessor string = `redact`
+
+	redactActionMask     = `mask`
+	redactActionHash     = `hash`
+	redactActionTokenize = `tokenize`
+
+	defaultRedactMask       = `[REDACTED]`
+	defaultRedactHashLength = 16
+)
+
+var (
+	ErrInvalidRedactAction   = errors.New("Action must be one of mask, hash, or tokenize")
+	ErrMissingRedactRules    = errors.New("At least one Regex, JSON-Field, or Detector is required")
+	ErrInvalidRedactDetector = errors.New("Detector must be one of email, ipv4, ipv6, creditcard, or ssn")
+	ErrMissingRedactKey      = errors.New("Key-File or Key-Env is required with the hash and tokenize actions")
+	ErrMultipleRedactKeys    = errors.New("Only one of Key-File and Key-Env may be set")
+	ErrEmptyRedactKey        = errors.New("Redaction key is empty")
+	ErrInvalidRedactHashLen  = errors.New("Hash-Length must be between 1 and 64")
+	ErrUnexpectedRedactArgs  = errors.New("Mask is only valid with the mask action and Hash-Length with the hash action")
+)
+
+// RedactConfig removes sensitive values from entries before they leave the ingester.
+// Values are found by regular expressions, JSON fields, and built-in detectors, and are then
+// masked, replaced with a keyed HMAC, or replaced with a keyed token that keeps the shape of the value.
+// The hash and tokenize actions are deterministic for a given key, so pseudonyms can still be correlated.
+type RedactConfig struct {
+	Action         string   // mask (default), hash, or tokenize
+	Regex          []string // capture groups are redacted if the expression has any, otherwise the whole match
+	JSON_Field     []string // JSON fields whose values are redacted, e.g. JSON-Field=user.email
+	Detector       []string // built-in detectors: email, ipv4, ipv6, creditcard, and ssn
+	Mask           string   // replacement for the mask action, defaults to [REDACTED]
+	Key_File       string   // file holding the secret for the hash and tokenize actions
+	Key_Env        string   // environment variable holding the secret for the hash and tokenize actions
+	Hash_Length    int      // hex characters of the HMAC kept by the hash action, defaults to 16
+	Rule_EV_Prefix string   // if set, each rule that fires attaches an EV named prefix+rule with its count
+}
+
+// redactRule is a single source of values, rules are named after their detector or
+// as json1, json2, ... and regex1, regex2, ... in configuration order
+type redactRule struct {
+	name  string
+	kind  string
+	rx    *regexp.Regexp
+	valid func(data []byte, s, e int) bool
+	split func(data []byte, s, e int) [][2]int
+	path  []string
+}
+
+// RedactLoadConfig loads the configuration for the redact processor
+func RedactLoadConfig(vc *config.VariableConfig) (c RedactConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	}
+	_, _, err = c.validate()
+	return
+}
+
+func (c *RedactConfig) validate() (rules []redactRule, key []byte, err error) {
+	action := c.action()
+	switch action {
+	case redactActionMask:
+		if c.Hash_Length != 0 {
+			err = ErrUnexpectedRedactArgs
+			return
+		}
+	case redactActionHash:
+		if c.Mask != `` {
+			err = ErrUnexpectedRedactArgs
+			return
+		} else if c.Hash_Length < 0 || c.Hash_Length > sha256.Size*2 {
+			err = ErrInvalidRedactHashLen
+			return
+		}
+	case redactActionTokenize:
+		if c.Mask != `` || c.Hash_Length != 0 {
+			err = ErrUnexpectedRedactArgs
+			return
+		}
+	default:
+		err = ErrInvalidRedactAction
+		return
+	}
+
+	for i, v := range c.JSON_Field {
+		if strings.TrimSpace(v) == `` {
+			err = errors.New("JSON-Field cannot be empty")
+			return
+		}
+		rules = append(rules, redactRule{
+			name: `json` + strconv.Itoa(i+1),
+			path: unquoteFields(splitRespectQuotes(v, dotSplitter)),
+		})
+	}
+	for i, v := range c.Regex {
+		var rx *regexp.Regexp
+		if v == `` {
+			err = errors.New("Regex cannot be empty")
+			return
+		} else if rx, err = regexp.Compile(v); err != nil {
+			err = fmt.Errorf("invalid Regex %q: %w", v, err)
+			return
+		}
+		rules = append(rules, redactRule{
+			name: `regex` + strconv.Itoa(i+1),
+			rx:   rx,
+		})
+	}
+	for _, v := range c.Detector {
+		for _, name := range strings.Split(v, `,`) {
+			name = strings.ToLower(strings.TrimSpace(name))
+			det, ok := redactDetectors[name]
+			if !ok {
+				err = fmt.Errorf("%w: %q", ErrInvalidRedactDetector, name)
+				return
+			}
+			rules = append(rules, redactRule{
+				name:  name,
+				kind:  name,
+				rx:    det.rx,
+				valid: det.valid,
+				split: det.split,
+			})
+		}
+	}
+	if len(rules) == 0 {
+		err = ErrMissingRedactRules
+		return
+	}
+
+	if c.Key_File != `` && c.Key_Env != `` {
+		err = ErrMultipleRedactKeys
+		return
+	} else if action == redactActionMask {
+		if c.Key_File != `` || c.Key_Env != `` {
+			err = errors.New("Key-File and Key-Env are only valid with the hash and tokenize actions")
+		}
+		return
+	}
+	if c.Key_File != `` {
+		if key, err = os.ReadFile(c.Key_File); err != nil {
+			err = fmt.Errorf("failed to read Key-File: %w", err)
+			return
+		}
+		key = bytes.TrimRight(key, "\r\n")
+	} else if c.Key_Env != `` {
+		key = []byte(os.Getenv(c.Key_Env))
+	} else {
+		err = ErrMissingRedactKey
+		return
+	}
+	if len(key) == 0 {
+		err = ErrEmptyRedactKey
+	}
+	return
+}
+
+func (c *RedactConfig) action() string {
+	if a := strings.ToLower(strings.TrimSpace(c.Action)); a != `` {
+		return a
+	}
+	return redactActionMask
+}
+
+// NewRedact creates a new redact processor
+func NewRedact(cfg RedactConfig) (*Redact, error) {
+	r := &Redact{}
+	if err := r.init(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type Redact struct {
+	nocloser
+	RedactConfig
+	action  string
+	rules   []redactRule
+	mask    []byte
+	hashLen int
+	mac     hash.Hash
+	counts  []int
+	ks      []byte
+	scratch []byte
+}
+
+// Config updates the configuration for the redact processor
+func (r *Redact) Config(v interface{}) (err error) {
+	if v == nil {
+		err = ErrNilConfig
+	} else if cfg, ok := v.(RedactConfig); ok {
+		err = r.init(cfg)
+	} else {
+		err = fmt.Errorf("Invalid configuration, unknown type %T", v)
+	}
+	return
+}
+
+func (r *Redact) init(cfg RedactConfig) (err error) {
+	var rules []redactRule
+	var key []byte
+	if rules, key, err = cfg.validate(); err != nil {
+		return
+	}
+	r.RedactConfig = cfg
+	r.action = cfg.action()
+	r.rules = rules
+	r.counts = make([]int, len(rules))
+	if r.mask = []byte(cfg.Mask); len(r.mask) == 0 {
+		r.mask = []byte(defaultRedactMask)
+	}
+	if r.hashLen = cfg.Hash_Length; r.hashLen == 0 {
+		r.hashLen = defaultRedactHashLength
+	}
+	r.mac = nil
+	if key != nil {
+		r.mac = hmac.New(sha256.New, key)
+	}
+	return
+}
+
+// Process redacts every value found by the rules
+func (r *Redact) Process(ents []*entry.Entry) ([]*entry.Entry, error) {
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		data := ent.Data
+		for i := range r.rules {
+			if r.rules[i].path != nil {
+				data, r.counts[i] = r.redactJSON(data, &r.rules[i])
+			} else {
+				data, r.counts[i] = r.redactRegex(data, &r.rules[i])
+			}
+		}
+		ent.Data = data
+		if r.Rule_EV_Prefix != `` {
+			for i, n := range r.counts {
+				if n > 0 {
+					ent.AddEnumeratedValueEx(r.Rule_EV_Prefix+r.rules[i].name, int64(n))
+				}
+			}
+		}
+	}
+	return ents, nil
+}
+
+func (r *Redact) redactRegex(data []byte, rl *redactRule) ([]byte, int) {
+	matches := rl.rx.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return data, 0
+	}
+	var out []byte
+	var last, n int
+	for _, m := range matches {
+		if len(m) > 2 {
+			m = m[2:] //just the capture groups
+		}
+		for j := 0; j+1 < len(m); j += 2 {
+			s, e := m[j], m[j+1]
+			if s < last || e <= s {
+				continue //group did not participate or is nested in one we already took
+			} else if rl.valid != nil && !rl.valid(data, s, e) {
+				continue
+			} else if rl.split != nil {
+				for _, w := range rl.split(data, s, e) {
+					out = append(out, data[last:w[0]]...)
+					out = r.redactValue(out, rl.kind, data[w[0]:w[1]])
+					last = w[1]
+					n++
+				}
+				continue
+			}
+			out = append(out, data[last:s]...)
+			out = r.redactValue(out, rl.kind, data[s:e])
+			last = e
+			n++
+		}
+	}
+	if n == 0 {
+		return data, 0
+	}
+	return append(out, data[last:]...), n
+}
+
+// redactJSON replaces the value of a JSON field with a redacted string
+func (r *Redact) redactJSON(data []byte, rl *redactRule) ([]byte, int) {
+	v, vt, _, err := jsonparser.Get(data, rl.path...)
+	if err != nil || vt == jsonparser.Null || vt == jsonparser.NotExist {
+		return data, 0
+	}
+	if vt == jsonparser.String {
+		if s, err := jsonparser.ParseString(v); err == nil {
+			v = []byte(s)
+		}
+	}
+	r.scratch = r.redactValue(r.scratch[:0], ``, v)
+	nv, err := json.Marshal(string(r.scratch))
+	if err != nil {
+		return data, 0
+	}
+	out, err := jsonparser.Set(data, nv, rl.path...)
+	if err != nil {
+		return data, 0
+	}
+	return out, 1
+}
+
+func (r *Redact) redactValue(out []byte, kind string, v []byte) []byte {
+	switch r.action {
+	case redactActionHash:
+		r.mac.Reset()
+		r.mac.Write(v)
+		r.ks = r.mac.Sum(r.ks[:0])
+		return append(out, hex.EncodeToString(r.ks)[:r.hashLen]...)
+	case redactActionTokenize:
+		return r.tokenize(out, kind, v)
+	}
+	return append(out, r.mask...)
+}
+
+// tokenize replaces every letter and digit using a keystream derived from the value, so the token
+// has the same shape as the value.  Addresses stay valid addresses and card numbers pass the Luhn check.
+func (r *Redact) tokenize(out []byte, kind string, v []byte) []byte {
+	ks := r.keystream(v, max(len(v), 16))
+	switch kind {
+	case redactDetectIPv4:
+		if ip, ok := parseRedactIP(v); ok && ip.Is4() {
+			return appendTokenIP(out, ip, ks)
+		}
+	case redactDetectIPv6:
+		if ip, ok := parseRedactIP(v); ok && !ip.Is4() {
+			return appendTokenIP(out, ip, ks)
+		}
+	}
+	start := len(out)
+	for i, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			c = '0' + ks[i]%10
+		case c >= 'a' && c <= 'z':
+			c = 'a' + ks[i]%26
+		case c >= 'A' && c <= 'Z':
+			c = 'A' + ks[i]%26
+		}
+		out = append(out, c)
+	}
+	if kind == redactDetectCreditCard {
+		fixLuhn(out[start:])
+	}
+	return out
+}
+
+// keystream is an HMAC-SHA256 in counter mode keyed on the value
+func (r *Redact) keystream(v []byte, n int) []byte {
+	ks := r.ks[:0]
+	for ctr := uint32(0); len(ks) < n; ctr++ {
+		r.mac.Reset()
+		r.mac.Write([]byte{byte(ctr >> 24), byte(ctr >> 16), byte(ctr >> 8), byte(ctr)})
+		r.mac.Write(v)
+		ks = r.mac.Sum(ks)
+	}
+	r.ks = ks
+	return ks
+}
@@ -0,0 +1,174 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bytes"
+	"net/netip"
+	"regexp"
+)
+
+const (
+	redactDetectEmail      = `email`
+	redactDetectIPv4       = `ipv4`
+	redactDetectIPv6       = `ipv6`
+	redactDetectCreditCard = `creditcard`
+	redactDetectSSN        = `ssn`
+)
+
+type redactDetector struct {
+	rx    *regexp.Regexp
+	valid func(data []byte, s, e int) bool
+	split func(data []byte, s, e int) [][2]int //narrows a valid candidate down to the values within it
+}
+
+// the detector expressions are deliberately loose, candidates are confirmed by the validators
+var redactDetectors = map[string]redactDetector{
+	redactDetectEmail: {
+		rx: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	},
+	redactDetectIPv4: {
+		rx: regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}`),
+		valid: func(data []byte, s, e int) bool {
+			ip, ok := parseRedactIP(data[s:e])
+			return ok && ip.Is4() && redactBounded(data, s, e, `.`)
+		},
+	},
+	redactDetectIPv6: {
+		rx: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}(?:[0-9a-f]{1,4}|(?:\d{1,3}\.){3}\d{1,3})?`),
+		valid: func(data []byte, s, e int) bool {
+			ip, ok := parseRedactIP(data[s:e])
+			return ok && ip.Is6() && redactBounded(data, s, e, `:.`)
+		},
+	},
+	redactDetectCreditCard: {
+		//candidates are whole runs of digits so a card next to other numbers is still found
+		rx: regexp.MustCompile(`\d(?:[ \-]?\d){12,}`),
+		valid: func(data []byte, s, e int) bool {
+			return redactBounded(data, s, e, ``)
+		},
+		split: cardWindows,
+	},
+	redactDetectSSN: {
+		rx: regexp.MustCompile(`\d{3}-\d{2}-\d{4}`),
+		valid: func(data []byte, s, e int) bool {
+			//the whole number is only redacted if it could be an issued SSN
+			if e-s != 11 || !redactBounded(data, s, e, `-`) {
+				return false
+			}
+			v := data[s:e]
+			area, group, serial := v[:3], v[4:6], v[7:]
+			return !bytes.Equal(area, []byte(`000`)) && !bytes.Equal(area, []byte(`666`)) && area[0] != '9' &&
+				!bytes.Equal(group, []byte(`00`)) && !bytes.Equal(serial, []byte(`0000`))
+		},
+	},
+}
+
+// redactBounded makes sure a candidate is not part of a larger word or number
+func redactBounded(data []byte, s, e int, extra string) bool {
+	isWord := func(c byte) bool {
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' ||
+			bytes.IndexByte([]byte(extra), c) >= 0
+	}
+	return (s == 0 || !isWord(data[s-1])) && (e == len(data) || !isWord(data[e]))
+}
+
+func parseRedactIP(v []byte) (ip netip.Addr, ok bool) {
+	var err error
+	if ip, err = netip.ParseAddr(string(v)); err == nil {
+		ok = ip.Zone() == ``
+	}
+	return
+}
+
+// appendTokenIP replaces the address bytes with the keystream, keeping the address family
+func appendTokenIP(out []byte, ip netip.Addr, ks []byte) []byte {
+	if ip.Is4() {
+		var b [4]byte
+		copy(b[:], ks)
+		return netip.AddrFrom4(b).AppendTo(out)
+	}
+	var b [16]byte
+	copy(b[:], ks)
+	return netip.AddrFrom16(b).AppendTo(out)
+}
+
+// cardWindows returns every card number in a run of digits, a card must start and end on a group
+// boundary and pass the Luhn check.  Windows are taken left to right, longest first.
+func cardWindows(data []byte, s, e int) (r [][2]int) {
+	var digits []int
+	for i := s; i < e; i++ {
+		if data[i] >= '0' && data[i] <= '9' {
+			digits = append(digits, i)
+		}
+	}
+	startOK := func(i int) bool {
+		return i == 0 || digits[i]-digits[i-1] > 1
+	}
+	endOK := func(i int) bool {
+		return i == len(digits)-1 || digits[i+1]-digits[i] > 1
+	}
+	for i := 0; i+13 <= len(digits); i++ {
+		if !startOK(i) {
+			continue
+		}
+		for j := min(i+19, len(digits)) - 1; j >= i+12; j-- {
+			if ws, we := digits[i], digits[j]+1; endOK(j) && luhnValid(data[ws:we]) {
+				r = append(r, [2]int{ws, we})
+				i = j
+				break
+			}
+		}
+	}
+	return
+}
+
+// luhnValid checks a 13 to 19 digit card number, spaces and dashes are ignored
+func luhnValid(v []byte) bool {
+	var sum, cnt int
+	for i := len(v) - 1; i >= 0; i-- {
+		c := v[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if cnt%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		cnt++
+	}
+	return cnt >= 13 && cnt <= 19 && sum%10 == 0
+}
+
+// fixLuhn rewrites the final digit so the number passes the Luhn check
+func fixLuhn(v []byte) {
+	last := bytes.LastIndexFunc(v, func(r rune) bool { return r >= '0' && r <= '9' })
+	if last < 0 {
+		return
+	}
+	var sum, cnt int
+	for i := last - 1; i >= 0; i-- {
+		c := v[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if cnt%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		cnt++
+	}
+	v[last] = '0' + byte((10-sum%10)%10)
+}
@@ -0,0 +1,213 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+func redactOne(t *testing.T, r *Redact, data string) *entry.Entry {
+	ent := &entry.Entry{Data: []byte(data)}
+	if set, err := r.Process([]*entry.Entry{ent}); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 1 {
+		t.Fatalf("redact dropped an entry")
+	}
+	return ent
+}
+
+func TestRedactConfig(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), `key`)
+	if err := os.WriteFile(keyFile, []byte("sekrit\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(`REDACT_TEST_KEY`, `sekrit`)
+	b := []byte(`
+	[preprocessor "pii"]
+		type = redact
+		Action = hash
+		Regex = "user=(\\S+)"
+		JSON-Field = user.email
+		Detector = email
+		Detector = "ssn, creditcard"
+		Key-File = "` + keyFile + `"
+		Rule-EV-Prefix = redacted_
+	`)
+	var tc attachTestConfig
+	if err := config.LoadConfigBytes(&tc, b); err != nil {
+		t.Fatal(err)
+	}
+	vc := tc.Preprocessor[`pii`]
+	if cfg, err := ProcessorLoadConfig(vc); err != nil {
+		t.Fatal(err)
+	} else if rc, ok := cfg.(RedactConfig); !ok || len(rc.Detector) != 2 {
+		t.Fatalf("bad config %+v", cfg)
+	}
+	var tagger testTagger
+	if p, err := newProcessor(vc, &tagger); err != nil {
+		t.Fatal(err)
+	} else if r, ok := p.(*Redact); !ok {
+		t.Fatalf("bad processor type %T", p)
+	} else if len(r.rules) != 5 || r.rules[0].name != `json1` || r.rules[1].name != `regex1` || r.rules[4].name != `creditcard` {
+		t.Fatalf("bad rules %+v", r.rules)
+	}
+
+	//the file and environment keys are the same secret
+	fromFile, err := NewRedact(RedactConfig{Action: `hash`, Detector: []string{`email`}, Key_File: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromEnv, err := NewRedact(RedactConfig{Action: `hash`, Detector: []string{`email`}, Key_Env: `REDACT_TEST_KEY`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := redactOne(t, fromFile, `a@b.com`), redactOne(t, fromEnv, `a@b.com`); string(a.Data) != string(b.Data) {
+		t.Fatalf("keys differ: %s %s", a.Data, b.Data)
+	}
+
+	bad := []RedactConfig{
+		{},
+		{Action: `scramble`, Detector: []string{`email`}},
+		{Detector: []string{`phone`}},
+		{Regex: []string{`(`}},
+		{Regex: []string{``}},
+		{JSON_Field: []string{` `}},
+		{Action: `hash`, Detector: []string{`email`}},
+		{Action: `hash`, Detector: []string{`email`}, Key_Env: `REDACT_TEST_MISSING_KEY`},
+		{Action: `hash`, Detector: []string{`email`}, Key_File: keyFile + `.nope`},
+		{Action: `hash`, Detector: []string{`email`}, Key_File: keyFile, Key_Env: `REDACT_TEST_KEY`},
+		{Action: `hash`, Detector: []string{`email`}, Key_File: keyFile, Hash_Length: 65},
+		{Action: `hash`, Detector: []string{`email`}, Key_File: keyFile, Mask: `x`},
+		{Action: `tokenize`, Detector: []string{`email`}, Key_File: keyFile, Hash_Length: 8},
+		{Detector: []string{`email`}, Key_File: keyFile},
+		{Detector: []string{`email`}, Hash_Length: 8},
+	}
+	for i, v := range bad {
+		if _, _, err := v.validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d %+v", i, v)
+		}
+	}
+}
+
+func TestRedactDetectors(t *testing.T) {
+	r, err := NewRedact(RedactConfig{
+		Detector:       []string{`email,ipv4,ipv6,creditcard,ssn`},
+		Mask:           `X`,
+		Rule_EV_Prefix: `pii_`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, out string
+	}{
+		{`login from bob.smith+tag@mail.example.co.uk ok`, `login from X ok`},
+		{`src=10.1.2.3 dst=192.168.100.200:443`, `src=X dst=X:443`},
+		{`version 1.2.3.4.5 and 999.1.1.1`, `version 1.2.3.4.5 and 999.1.1.1`},
+		{`from fe80::1 to 2001:db8::dead:beef port 22`, `from X to X port 22`},
+		{`std::vector at 12:30:45`, `std::vector at 12:30:45`},
+		{`card 4111 1111 1111 1111 and 4111-1111-1111-1112`, `card X and 4111-1111-1111-1112`},
+		{`cc=5500005555555559;`, `cc=X;`},
+		{`id=12 4111111111111111`, `id=12 X`},
+		{`card 4111111111111111 1234`, `card X 1234`},
+		{`cards 4111111111111111 5500005555555559`, `cards X X`},
+		{`order 12345678901234567890`, `order 12345678901234567890`},
+		{`ssn 123-45-6789 not 666-12-3456 or 123-00-4567 or 1123-45-6789`, `ssn X not 666-12-3456 or 123-00-4567 or 1123-45-6789`},
+	}
+	for _, tst := range tests {
+		if ent := redactOne(t, r, tst.in); string(ent.Data) != tst.out {
+			t.Fatalf("bad redaction of %q\n%q != %q", tst.in, ent.Data, tst.out)
+		}
+	}
+	ent := redactOne(t, r, `a@b.com c@d.org 10.0.0.1`)
+	if v, ok := ent.GetEnumeratedValue(`pii_email`); !ok || v != int64(2) {
+		t.Fatalf("bad email count %v %v", v, ok)
+	} else if v, ok = ent.GetEnumeratedValue(`pii_ipv4`); !ok || v != int64(1) {
+		t.Fatalf("bad ipv4 count %v %v", v, ok)
+	} else if _, ok = ent.GetEnumeratedValue(`pii_ssn`); ok {
+		t.Fatal("rule that did not fire was recorded")
+	}
+}
+
+func TestRedactRegexJSON(t *testing.T) {
+	r, err := NewRedact(RedactConfig{
+		Regex:      []string{`user=(\w+)|uid=(\d+)`, `token \S+`},
+		JSON_Field: []string{`user.name`, `user.id`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ent := redactOne(t, r, `user=bob uid=1000 token abc123`)
+	if string(ent.Data) != `user=[REDACTED] uid=[REDACTED] [REDACTED]` {
+		t.Fatalf("bad regex redaction %q", ent.Data)
+	}
+	ent = redactOne(t, r, `{"user": {"name": "bob \"the builder\"", "id": 1000}, "msg": "hi"}`)
+	if v, err := jsonparser.GetString(ent.Data, `user`, `name`); err != nil || v != defaultRedactMask {
+		t.Fatalf("bad JSON redaction %q %v", ent.Data, err)
+	} else if v, err = jsonparser.GetString(ent.Data, `user`, `id`); err != nil || v != defaultRedactMask {
+		t.Fatalf("bad JSON redaction %q %v", ent.Data, err)
+	} else if v, err = jsonparser.GetString(ent.Data, `msg`); err != nil || v != `hi` {
+		t.Fatalf("JSON redaction damaged other fields %q %v", ent.Data, err)
+	}
+	//entries without the fields are untouched
+	if ent = redactOne(t, r, `{"msg": "hi"}`); string(ent.Data) != `{"msg": "hi"}` {
+		t.Fatalf("bad JSON redaction %q", ent.Data)
+	}
+}
+
+func TestRedactHashTokenize(t *testing.T) {
+	t.Setenv(`REDACT_TEST_KEY`, `sekrit`)
+	h, err := NewRedact(RedactConfig{Action: `hash`, Detector: []string{`email`}, Key_Env: `REDACT_TEST_KEY`, Hash_Length: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := redactOne(t, h, `from a@b.com to c@d.com cc a@b.com`)
+	parts := strings.Fields(string(a.Data))
+	if len(parts) != 6 || len(parts[1]) != 12 || parts[1] != parts[5] || parts[1] == parts[3] {
+		t.Fatalf("bad hash redaction %q", a.Data)
+	}
+
+	tk, err := NewRedact(RedactConfig{Action: `tokenize`, Detector: []string{`email,ipv4,ipv6,creditcard,ssn`}, Key_Env: `REDACT_TEST_KEY`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := `Bob.Smith99@example.com 10.1.2.3 2001:db8::1 4111-1111-1111-1111 123-45-6789`
+	out := string(redactOne(t, tk, in).Data)
+	if out == in || out != string(redactOne(t, tk, in).Data) {
+		t.Fatalf("tokens are not deterministic %q", out)
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 5 {
+		t.Fatalf("bad token count %q", out)
+	}
+	if !regexp.MustCompile(`^[A-Z][a-z]{2}\.[A-Z][a-z]{4}\d\d@[a-z]{7}\.[a-z]{3}$`).MatchString(fields[0]) {
+		t.Fatalf("email token lost its shape %q", fields[0])
+	}
+	if ip, err := netip.ParseAddr(fields[1]); err != nil || !ip.Is4() {
+		t.Fatalf("bad ipv4 token %q", fields[1])
+	}
+	if ip, err := netip.ParseAddr(fields[2]); err != nil || ip.Is4() {
+		t.Fatalf("bad ipv6 token %q", fields[2])
+	}
+	if len(fields[3]) != 19 || !luhnValid([]byte(fields[3])) || fields[3][4] != '-' {
+		t.Fatalf("bad card token %q", fields[3])
+	}
+	if !regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`).MatchString(fields[4]) {
+		t.Fatalf("bad ssn token %q", fields[4])
+	}
+}
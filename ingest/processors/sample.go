@@ -0,0 +1,424 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	SampleProcessor string = `sample`
+
+	sampleModeRatio     = `ratio`
+	sampleModeReservoir = `reservoir`
+	sampleModeBucket    = `bucket`
+
+	sampleKeyNone  = `none`
+	sampleKeyTag   = `tag`
+	sampleKeySrc   = `src`
+	sampleKeyRegex = `regex`
+	sampleKeyJSON  = `json`
+
+	defaultSampleWindow = time.Second
+	defaultSampleRateEV = `sample_rate`
+)
+
+var (
+	ErrInvalidSampleMode      = errors.New("Mode must be one of ratio, reservoir, or bucket")
+	ErrInvalidSampleRatio     = errors.New("Ratio must be greater than 0 and no greater than 1")
+	ErrInvalidSampleThreshold = errors.New("Threshold cannot be negative")
+	ErrInvalidSampleWindow    = errors.New("Window must be a positive duration")
+	ErrInvalidReservoirSize   = errors.New("Reservoir-Size must be greater than 0")
+	ErrInvalidSampleRate      = errors.New("Rate must be greater than 0")
+	ErrInvalidSampleBurst     = errors.New("Burst cannot be negative")
+	ErrInvalidSampleKey       = errors.New("Key must be one of none, tag, src, regex, or json")
+	ErrMissingSampleKeyRegex  = errors.New("Key-Regex is required with the regex key")
+	ErrMissingSampleKeyField  = errors.New("Key-Field is required with the json key")
+	ErrUnexpectedSampleArgs   = errors.New("Sampling parameters are only valid with their mode and key")
+)
+
+// SampleConfig thins out noisy sources.  Every mode tracks entries per key, where the key is
+// the tag, the SRC, a regular expression extraction, or a JSON field; entries without a key share one.
+//
+// The ratio mode passes Threshold entries per key per Window and keeps the Ratio fraction after that.
+// The reservoir mode holds entries for a Window and releases a uniform sample of at most Reservoir-Size per key
+// when the Window closes, whether or not new entries arrive.
+// The bucket mode is a token bucket per key that passes Rate entries per second with bursts of up to Burst.
+//
+// Entries kept while sampling carry an enumerated value holding the fraction of entries that were kept,
+// so search-time counts can be scaled by its inverse.  Entries that passed unsampled carry nothing.
+type SampleConfig struct {
+	Mode           string  // ratio (default), reservoir, or bucket
+	Ratio          float64 // fraction of entries kept once the threshold is crossed in ratio mode
+	Threshold      int     // entries per key per window that pass before ratio sampling starts
+	Window         string  // window duration, defaults to 1s
+	Reservoir_Size int     // entries released per key per window in reservoir mode
+	Rate           float64 // entries per second per key in bucket mode
+	Burst          int     // token bucket depth in bucket mode, defaults to Rate
+	Key            string  // none (default), tag, src, regex, or json
+	Key_Regex      string  // regular expression for the regex key, the first capture group is used if present
+	Key_Field      string  // JSON field for the json key, e.g. Key-Field=src.ip
+	Rate_EV        string  // enumerated value name for the sample rate, defaults to sample_rate
+}
+
+type sampleParams struct {
+	mode   string
+	window time.Duration
+	burst  float64
+	key    string
+	rx     *regexp.Regexp
+	rxIdx  int
+	path   []string
+	rateEV string
+}
+
+// SampleLoadConfig loads the configuration for the sample processor
+func SampleLoadConfig(vc *config.VariableConfig) (c SampleConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	}
+	_, err = c.validate()
+	return
+}
+
+func (c *SampleConfig) validate() (p sampleParams, err error) {
+	p.mode = strings.ToLower(strings.TrimSpace(c.Mode))
+	if p.mode == `` {
+		p.mode = sampleModeRatio
+	}
+	p.window = defaultSampleWindow
+	if c.Window != `` {
+		if p.window, err = time.ParseDuration(c.Window); err != nil {
+			err = fmt.Errorf("invalid Window %q: %w", c.Window, err)
+			return
+		} else if p.window <= 0 {
+			err = ErrInvalidSampleWindow
+			return
+		}
+	}
+	if c.Threshold < 0 {
+		err = ErrInvalidSampleThreshold
+		return
+	}
+
+	switch p.mode {
+	case sampleModeRatio:
+		if c.Ratio <= 0 || c.Ratio > 1 {
+			err = ErrInvalidSampleRatio
+		} else if c.Reservoir_Size != 0 || c.Rate != 0 || c.Burst != 0 {
+			err = ErrUnexpectedSampleArgs
+		}
+	case sampleModeReservoir:
+		if c.Reservoir_Size <= 0 {
+			err = ErrInvalidReservoirSize
+		} else if c.Ratio != 0 || c.Threshold != 0 || c.Rate != 0 || c.Burst != 0 {
+			err = ErrUnexpectedSampleArgs
+		}
+	case sampleModeBucket:
+		if c.Rate <= 0 {
+			err = ErrInvalidSampleRate
+		} else if c.Burst < 0 {
+			err = ErrInvalidSampleBurst
+		} else if c.Ratio != 0 || c.Threshold != 0 || c.Reservoir_Size != 0 {
+			err = ErrUnexpectedSampleArgs
+		}
+		if p.burst = float64(c.Burst); p.burst == 0 {
+			p.burst = max(c.Rate, 1)
+		}
+	default:
+		err = ErrInvalidSampleMode
+	}
+	if err != nil {
+		return
+	}
+
+	if p.key = strings.ToLower(strings.TrimSpace(c.Key)); p.key == `` {
+		p.key = sampleKeyNone
+	}
+	switch p.key {
+	case sampleKeyNone, sampleKeyTag, sampleKeySrc:
+		if c.Key_Regex != `` || c.Key_Field != `` {
+			err = ErrUnexpectedSampleArgs
+		}
+	case sampleKeyRegex:
+		if c.Key_Regex == `` {
+			err = ErrMissingSampleKeyRegex
+		} else if c.Key_Field != `` {
+			err = ErrUnexpectedSampleArgs
+		} else if p.rx, err = regexp.Compile(c.Key_Regex); err != nil {
+			err = fmt.Errorf("invalid Key-Regex: %w", err)
+		} else if p.rx.NumSubexp() > 0 {
+			p.rxIdx = 1
+		}
+	case sampleKeyJSON:
+		if c.Key_Field == `` {
+			err = ErrMissingSampleKeyField
+		} else if c.Key_Regex != `` {
+			err = ErrUnexpectedSampleArgs
+		} else {
+			p.path = unquoteFields(splitRespectQuotes(c.Key_Field, dotSplitter))
+		}
+	default:
+		err = ErrInvalidSampleKey
+	}
+
+	if p.rateEV = c.Rate_EV; p.rateEV == `` {
+		p.rateEV = defaultSampleRateEV
+	}
+	return
+}
+
+// NewSample creates a new sample processor
+func NewSample(cfg SampleConfig) (*Sample, error) {
+	s := &Sample{
+		rng: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		now: time.Now,
+	}
+	if err := s.init(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type sampleSlot struct {
+	seq uint64
+	ent *entry.Entry
+}
+
+// sampleState tracks a single key over the current window
+type sampleState struct {
+	seen   uint64
+	kept   uint64
+	tokens float64
+	last   time.Time
+	res    []sampleSlot
+}
+
+type Sample struct {
+	nocloser
+	SampleConfig
+	sampleParams
+	rng       *rand.Rand
+	now       func() time.Time
+	windowEnd time.Time
+	seq       uint64
+	keys      map[string]*sampleState
+}
+
+// Config updates the configuration for the sample processor, all sampling state is reset
+// and any entries held in reservoirs are released with the next call to Process
+func (s *Sample) Config(v interface{}) (err error) {
+	if v == nil {
+		err = ErrNilConfig
+	} else if cfg, ok := v.(SampleConfig); ok {
+		err = s.init(cfg)
+	} else {
+		err = fmt.Errorf("Invalid configuration, unknown type %T", v)
+	}
+	return
+}
+
+func (s *Sample) init(cfg SampleConfig) (err error) {
+	var p sampleParams
+	if p, err = cfg.validate(); err != nil {
+		return
+	}
+	s.SampleConfig = cfg
+	s.sampleParams = p
+	s.windowEnd = time.Time{}
+	if s.keys == nil {
+		s.keys = map[string]*sampleState{}
+	}
+	//a held reservoir still has to go out, everything else starts over
+	for k, st := range s.keys {
+		if len(st.res) == 0 {
+			delete(s.keys, k)
+		}
+	}
+	return
+}
+
+// Process passes, drops, or holds entries according to the sampling mode, reservoirs are released
+// on the first call after their window closes
+func (s *Sample) Process(ents []*entry.Entry) (rset []*entry.Entry, err error) {
+	now := s.now()
+	if !now.Before(s.windowEnd) {
+		rset = s.roll(now)
+	}
+	if len(ents) == 0 {
+		return
+	}
+	if s.mode == sampleModeReservoir {
+		for _, ent := range ents {
+			if ent != nil {
+				s.hold(ent)
+			}
+		}
+		return
+	}
+	if len(rset) == 0 {
+		rset = ents[:0]
+	}
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		st := s.state(ent, now)
+		st.seen++
+		var keep bool
+		var rate float64
+		switch s.mode {
+		case sampleModeRatio:
+			if st.seen <= uint64(s.Threshold) {
+				keep = true
+			} else if s.Ratio == 1 || s.rng.Float64() < s.Ratio {
+				keep, rate = true, s.Ratio
+			}
+		case sampleModeBucket:
+			st.refill(now, s.Rate, s.burst)
+			if st.tokens >= 1 {
+				st.tokens--
+				keep = true
+			}
+			if st.kept+1 < st.seen {
+				rate = float64(st.kept+1) / float64(st.seen)
+			}
+		}
+		if !keep {
+			continue
+		}
+		st.kept++
+		if rate > 0 && rate < 1 {
+			ent.AddEnumeratedValueEx(s.rateEV, rate)
+		}
+		rset = append(rset, ent)
+	}
+	return
+}
+
+// Flush releases every entry held in a reservoir
+func (s *Sample) Flush() []*entry.Entry {
+	return s.drain()
+}
+
+// Expire closes out the window once it has passed, so reservoirs are released on time
+// even when no new entries arrive
+func (s *Sample) Expire() []*entry.Entry {
+	if s.mode != sampleModeReservoir {
+		return nil
+	}
+	if now := s.now(); !now.Before(s.windowEnd) {
+		return s.roll(now)
+	}
+	return nil
+}
+
+// roll closes out the current window
+func (s *Sample) roll(now time.Time) (out []*entry.Entry) {
+	out = s.drain()
+	for k, st := range s.keys {
+		if s.mode == sampleModeBucket {
+			//a bucket that has refilled is no different from a new one
+			if st.refill(now, s.Rate, s.burst); st.tokens < s.burst {
+				st.seen, st.kept = 0, 0
+				continue
+			}
+		}
+		delete(s.keys, k)
+	}
+	s.windowEnd = now.Add(s.window)
+	return
+}
+
+// drain pulls everything out of the reservoirs in the order it arrived
+func (s *Sample) drain() (out []*entry.Entry) {
+	var slots []sampleSlot
+	for _, st := range s.keys {
+		if len(st.res) == 0 {
+			continue
+		}
+		if st.seen > uint64(len(st.res)) {
+			rate := float64(len(st.res)) / float64(st.seen)
+			for _, v := range st.res {
+				v.ent.AddEnumeratedValueEx(s.rateEV, rate)
+			}
+		}
+		slots = append(slots, st.res...)
+		//whatever arrives after a drain is sampled afresh
+		st.res, st.seen = nil, 0
+	}
+	if len(slots) == 0 {
+		return
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].seq < slots[j].seq })
+	out = make([]*entry.Entry, 0, len(slots))
+	for _, v := range slots {
+		out = append(out, v.ent)
+	}
+	return
+}
+
+// hold adds an entry to its reservoir, replacing a random resident once the reservoir is full
+func (s *Sample) hold(ent *entry.Entry) {
+	st := s.state(ent, time.Time{})
+	st.seen++
+	s.seq++
+	slot := sampleSlot{seq: s.seq, ent: ent}
+	if len(st.res) < s.Reservoir_Size {
+		st.res = append(st.res, slot)
+	} else if j := s.rng.Uint64N(st.seen); j < uint64(len(st.res)) {
+		st.res[j] = slot
+	}
+}
+
+func (s *Sample) state(ent *entry.Entry, now time.Time) *sampleState {
+	k := s.keyOf(ent)
+	st, ok := s.keys[k]
+	if !ok {
+		st = &sampleState{tokens: s.burst, last: now}
+		s.keys[k] = st
+	}
+	return st
+}
+
+func (s *Sample) keyOf(ent *entry.Entry) string {
+	switch s.key {
+	case sampleKeyTag:
+		return strconv.FormatUint(uint64(ent.Tag), 10)
+	case sampleKeySrc:
+		return string(ent.SRC)
+	case sampleKeyRegex:
+		if m := s.rx.FindSubmatch(ent.Data); m != nil {
+			return string(m[s.rxIdx])
+		}
+	case sampleKeyJSON:
+		if v, _, _, err := jsonparser.Get(ent.Data, s.path...); err == nil {
+			return string(v)
+		}
+	}
+	return ``
+}
+
+func (st *sampleState) refill(now time.Time, rate, burst float64) {
+	if now.After(st.last) {
+		st.tokens = min(burst, st.tokens+now.Sub(st.last).Seconds()*rate)
+		st.last = now
+	}
+}
@@ -0,0 +1,307 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/jsonparser"
+)
+
+type sampleClock struct {
+	t time.Time
+}
+
+func (c *sampleClock) now() time.Time {
+	return c.t
+}
+
+func newTestSample(t *testing.T, cfg SampleConfig) (*Sample, *sampleClock) {
+	s, err := NewSample(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &sampleClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s.now = clk.now
+	s.rng = rand.New(rand.NewPCG(1, 2))
+	return s, clk
+}
+
+func sampleEnts(cnt int, tag entry.EntryTag, src string) (ents []*entry.Entry) {
+	for i := 0; i < cnt; i++ {
+		ents = append(ents, &entry.Entry{
+			Tag:  tag,
+			SRC:  net.ParseIP(src),
+			Data: fmt.Appendf(nil, `{"host": %q, "seq": %d} user=%s`, src, i, src),
+		})
+	}
+	return
+}
+
+func TestSampleConfig(t *testing.T) {
+	b := []byte(`
+	[preprocessor "dns"]
+		type = sample
+		Mode = ratio
+		Ratio = 0.25
+		Threshold = 100
+		Window = 10s
+		Key = json
+		Key-Field = "query.name"
+		Rate-EV = rate
+	`)
+	var tc attachTestConfig
+	if err := config.LoadConfigBytes(&tc, b); err != nil {
+		t.Fatal(err)
+	}
+	vc := tc.Preprocessor[`dns`]
+	if cfg, err := ProcessorLoadConfig(vc); err != nil {
+		t.Fatal(err)
+	} else if sc, ok := cfg.(SampleConfig); !ok || sc.Ratio != 0.25 || sc.Threshold != 100 {
+		t.Fatalf("bad config %+v", cfg)
+	}
+	var tagger testTagger
+	if p, err := newProcessor(vc, &tagger); err != nil {
+		t.Fatal(err)
+	} else if s, ok := p.(*Sample); !ok {
+		t.Fatalf("bad processor type %T", p)
+	} else if s.window != 10*time.Second || len(s.path) != 2 || s.rateEV != `rate` {
+		t.Fatalf("bad processor params %+v", s.sampleParams)
+	}
+
+	bad := []SampleConfig{
+		{},
+		{Ratio: 1.5},
+		{Ratio: 0.5, Threshold: -1},
+		{Ratio: 0.5, Window: `soon`},
+		{Ratio: 0.5, Window: `-1s`},
+		{Ratio: 0.5, Rate: 10},
+		{Mode: `reservoir`},
+		{Mode: `reservoir`, Reservoir_Size: 10, Ratio: 0.5},
+		{Mode: `bucket`},
+		{Mode: `bucket`, Rate: 10, Burst: -1},
+		{Mode: `bucket`, Rate: 10, Threshold: 5},
+		{Mode: `shuffle`},
+		{Ratio: 0.5, Key: `host`},
+		{Ratio: 0.5, Key: `regex`},
+		{Ratio: 0.5, Key: `regex`, Key_Regex: `(`},
+		{Ratio: 0.5, Key: `json`},
+		{Ratio: 0.5, Key: `tag`, Key_Field: `foo`},
+	}
+	for i, v := range bad {
+		if _, err := v.validate(); err == nil {
+			t.Fatalf("failed to catch bad config %d %+v", i, v)
+		}
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	s, _ := newTestSample(t, SampleConfig{Ratio: 0.25, Threshold: 10, Key: `tag`})
+	ents := append(sampleEnts(1000, 1, `10.0.0.1`), sampleEnts(5, 2, `10.0.0.2`)...)
+	set, err := s.Process(ents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unsampled, sampled, other int
+	for _, ent := range set {
+		if ent.Tag == 2 {
+			other++
+		} else if v, ok := ent.GetEnumeratedValue(defaultSampleRateEV); !ok {
+			unsampled++
+		} else if v != 0.25 {
+			t.Fatalf("bad sample rate %v", v)
+		} else {
+			sampled++
+		}
+	}
+	if unsampled != 10 || other != 5 {
+		t.Fatalf("threshold was not applied per key: %d %d", unsampled, other)
+	} else if sampled < 200 || sampled > 300 {
+		t.Fatalf("sampled %d of 990 entries at 0.25", sampled)
+	}
+}
+
+func TestSampleReservoir(t *testing.T) {
+	s, clk := newTestSample(t, SampleConfig{Mode: `reservoir`, Reservoir_Size: 5, Key: `src`})
+	ents := append(sampleEnts(100, 1, `10.0.0.1`), sampleEnts(3, 1, `10.0.0.2`)...)
+	if set, err := s.Process(ents); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 0 {
+		t.Fatalf("reservoir released %d entries early", len(set))
+	}
+
+	//nothing comes out until the window closes
+	clk.t = clk.t.Add(500 * time.Millisecond)
+	if set, _ := s.Process(nil); len(set) != 0 {
+		t.Fatalf("reservoir released %d entries early", len(set))
+	}
+	clk.t = clk.t.Add(time.Second)
+	set, err := s.Process(sampleEnts(1, 1, `10.0.0.3`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(set) != 8 {
+		t.Fatalf("reservoir released %d entries", len(set))
+	}
+	var sampled int
+	last := int64(-1)
+	for _, ent := range set {
+		if ent.SRC.String() == `10.0.0.1` {
+			if seq, err := jsonparser.GetInt(ent.Data, `seq`); err != nil || seq <= last {
+				t.Fatalf("reservoir entries are out of order: %d after %d", seq, last)
+			} else {
+				last = seq
+			}
+		}
+		if v, ok := ent.GetEnumeratedValue(defaultSampleRateEV); ok {
+			if v != 0.05 || ent.SRC.String() != `10.0.0.1` {
+				t.Fatalf("bad sample rate %v on %v", v, ent.SRC)
+			}
+			sampled++
+		}
+	}
+	if sampled != 5 {
+		t.Fatalf("bad sampled count %d", sampled)
+	}
+
+	//the entry for the new window comes out on flush
+	if set = s.Flush(); len(set) != 1 || set[0].SRC.String() != `10.0.0.3` {
+		t.Fatalf("bad flush %d", len(set))
+	} else if _, ok := set[0].GetEnumeratedValue(defaultSampleRateEV); ok {
+		t.Fatal("unsampled entry has a sample rate")
+	}
+}
+
+func TestSampleSetFlush(t *testing.T) {
+	var tw testWriter
+	ps := NewProcessorSet(&tw)
+	s, _ := newTestSample(t, SampleConfig{Mode: `reservoir`, Reservoir_Size: 5, Key: `src`})
+	ps.AddProcessor(s)
+	//the test writer refuses the empty batches a filling reservoir hands back, so feed it directly
+	ps.Lock()
+	set, err := s.Process(sampleEnts(10, 1, `10.0.0.1`))
+	ps.Unlock()
+	if err != nil || len(set) != 0 {
+		t.Fatalf("reservoir released %d entries early %v", len(set), err)
+	}
+
+	//a flush writes out what is held and leaves the set running
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	} else if len(tw.ents) != 5 {
+		t.Fatalf("flush wrote %d entries", len(tw.ents))
+	} else if v, ok := tw.ents[0].GetEnumeratedValue(defaultSampleRateEV); !ok || v != 0.5 {
+		t.Fatalf("bad sample rate %v", v)
+	}
+
+	//the reservoir starts over after a flush
+	tw.ents = nil
+	ps.Lock()
+	_, err = s.Process(sampleEnts(3, 1, `10.0.0.1`))
+	ps.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(tw.ents) != 3 {
+		t.Fatalf("wrote %d entries after flush", len(tw.ents))
+	}
+	for _, ent := range tw.ents {
+		if _, ok := ent.GetEnumeratedValue(defaultSampleRateEV); ok {
+			t.Fatal("unsampled entry has a sample rate")
+		}
+	}
+}
+
+func TestSampleExpire(t *testing.T) {
+	var tw testWriter
+	ps := NewProcessorSet(&tw)
+	defer ps.Close()
+	s, clk := newTestSample(t, SampleConfig{Mode: `reservoir`, Reservoir_Size: 5, Key: `src`})
+	ps.AddProcessor(s)
+	//the expiration routine works under the set lock, so the test does too
+	ps.Lock()
+	_, err := s.Process(sampleEnts(10, 1, `10.0.0.1`))
+	ps.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := func() int {
+		ps.Lock()
+		defer ps.Unlock()
+		return len(tw.ents)
+	}
+	time.Sleep(2 * expireInterval)
+	if n := written(); n != 0 {
+		t.Fatalf("reservoir released %d entries before the window closed", n)
+	}
+
+	//once the window closes the reservoir goes out without any new entries showing up
+	ps.Lock()
+	clk.t = clk.t.Add(2 * time.Second)
+	ps.Unlock()
+	for start := time.Now(); written() != 5; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("reservoir was not released, %d entries written", written())
+		}
+	}
+
+	//only reservoirs are expired
+	r, rclk := newTestSample(t, SampleConfig{Ratio: 0.5, Threshold: 1})
+	if set, err := r.Process(sampleEnts(4, 1, `10.0.0.1`)); err != nil || len(set) == 0 {
+		t.Fatalf("bad ratio sample %d %v", len(set), err)
+	}
+	rclk.t = rclk.t.Add(2 * time.Second)
+	if set := r.Expire(); len(set) != 0 {
+		t.Fatalf("ratio mode expired %d entries", len(set))
+	}
+}
+
+func TestSampleBucket(t *testing.T) {
+	s, clk := newTestSample(t, SampleConfig{Mode: `bucket`, Rate: 10, Burst: 5, Key: `regex`, Key_Regex: `user=(\S+)`})
+	set, err := s.Process(append(sampleEnts(20, 1, `10.0.0.1`), sampleEnts(2, 1, `10.0.0.2`)...))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(set) != 7 {
+		t.Fatalf("bucket passed %d entries", len(set))
+	}
+	for _, ent := range set {
+		if _, ok := ent.GetEnumeratedValue(defaultSampleRateEV); ok {
+			t.Fatal("entries within the burst have a sample rate")
+		}
+	}
+
+	//half a second refills 5 tokens
+	clk.t = clk.t.Add(500 * time.Millisecond)
+	if set, err = s.Process(sampleEnts(20, 1, `10.0.0.1`)); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 5 {
+		t.Fatalf("bucket passed %d entries", len(set))
+	}
+	for i, ent := range set {
+		want := float64(6+i) / float64(21+i)
+		if v, ok := ent.GetEnumeratedValue(defaultSampleRateEV); !ok || v != want {
+			t.Fatalf("bad sample rate on %d: %v != %v", i, v, want)
+		}
+	}
+
+	//idle buckets are pruned once they refill
+	clk.t = clk.t.Add(time.Second)
+	if _, err = s.Process(nil); err != nil {
+		t.Fatal(err)
+	} else if len(s.keys) != 0 {
+		t.Fatalf("idle buckets were not pruned: %d", len(s.keys))
+	}
+}
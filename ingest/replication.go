@@ -0,0 +1,506 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	replicaCacheDir = `replicas`
+	// replicaMaxPending caps how many entries can be waiting on quorum before writers are held off,
+	// it does not apply with the cache enabled as the backlog spills to disk instead
+	replicaMaxPending = 256 * 1024
+	// replicaMaxHeld caps how many copies a single replica can have parked or waiting on an ack, a replica
+	// at the cap that is not needed for quorum drops its copies rather than holding up writers
+	replicaMaxHeld  = 256 * 1024
+	replicaRoomPoll = 10 * time.Millisecond
+)
+
+var (
+	ErrInvalidQuorum           = errors.New("Replication quorum must be between zero and the number of destinations")
+	ErrReplicationTargetGroups = errors.New("Target groups cannot be used in replication mode")
+	ErrQuorumUnavailable       = errors.New("Not enough replicas are connected to reach quorum")
+	ErrNotReplicating          = errors.New("Muxer is not in replication mode")
+	ErrDittoReplication        = errors.New("Ditto blocks cannot be written in replication mode")
+)
+
+func init() {
+	gob.Register(&replicaEntry{})
+	gob.Register([]*replicaEntry{})
+}
+
+// ReplicationStats reports on the state of a muxer in replication mode
+type ReplicationStats struct {
+	Replicas  int    // number of destinations every entry is delivered to
+	Quorum    int    // number of acknowledgements required to commit an entry
+	Hot       int    // number of replicas currently connected
+	Pending   int    // entries which have not been acknowledged by a quorum of replicas
+	Committed uint64 // entries which have been acknowledged by a quorum of replicas
+	Dropped   uint64 // copies given up by replicas that fell too far behind the quorum
+}
+
+// replicaEntry is what travels down a replica feeder.  The ID ties the copy back to its
+// replication record and survives a round trip through the cache.
+type replicaEntry struct {
+	ID  uint64
+	Ent *entry.Entry
+}
+
+// replica is a single destination in replication mode.  Every replica gets its own copy of every entry,
+// so each one has its own feeders, cache, and emergency queue.
+type replica struct {
+	//connHot is accessed atomically, keep it at the top for alignment
+	connHot int32
+	feeders
+	eq       *emergencyQueue
+	rt       *replicaTracker
+	mtx      sync.Mutex
+	inflight map[*entry.Entry]uint64 // copies handed to the connection or emergency queue, waiting on an ack
+	maxHeld  int
+	dropped  atomic.Uint64
+}
+
+// replicaTracker counts acknowledgements for every replicated entry until it reaches quorum
+type replicaTracker struct {
+	mtx        sync.Mutex
+	quorum     int
+	next       uint64
+	pending    map[uint64]int
+	committed  uint64
+	maxPending int
+}
+
+func newReplicaTracker(quorum int) *replicaTracker {
+	return &replicaTracker{
+		quorum:     quorum,
+		maxPending: replicaMaxPending,
+		//start at a random ID so that copies replayed from a previous run's cache can't collide with ours
+		next:    rand.Uint64() | 1,
+		pending: make(map[uint64]int),
+	}
+}
+
+func (rt *replicaTracker) track() (id uint64) {
+	rt.mtx.Lock()
+	id = rt.next
+	if rt.next++; rt.next == 0 {
+		rt.next = 1 // zero means untracked
+	}
+	rt.pending[id] = 0
+	rt.mtx.Unlock()
+	return
+}
+
+func (rt *replicaTracker) ack(id uint64) {
+	rt.mtx.Lock()
+	if cnt, ok := rt.pending[id]; ok {
+		if cnt++; cnt >= rt.quorum {
+			delete(rt.pending, id)
+			rt.committed++
+		} else {
+			rt.pending[id] = cnt
+		}
+	}
+	rt.mtx.Unlock()
+}
+
+func (rt *replicaTracker) drop(ids []uint64) {
+	rt.mtx.Lock()
+	for _, id := range ids {
+		delete(rt.pending, id)
+	}
+	rt.mtx.Unlock()
+}
+
+// full reports whether enough entries are waiting on quorum that writers should be held off
+func (rt *replicaTracker) full() (r bool) {
+	rt.mtx.Lock()
+	r = len(rt.pending) >= rt.maxPending
+	rt.mtx.Unlock()
+	return
+}
+
+func (rt *replicaTracker) stats() (pending int, committed uint64) {
+	rt.mtx.Lock()
+	pending, committed = len(rt.pending), rt.committed
+	rt.mtx.Unlock()
+	return
+}
+
+// newReplicas builds a replica for every destination, a nil return means replication is disabled
+func newReplicas(c MuxerConfig) (reps []*replica, rt *replicaTracker, err error) {
+	if !c.Replicate {
+		return
+	}
+	if len(c.TargetGroups) > 0 {
+		err = ErrReplicationTargetGroups
+		return
+	}
+	quorum := c.ReplicationQuorum
+	if quorum == 0 {
+		quorum = len(c.Destinations)
+	} else if quorum < 0 || quorum > len(c.Destinations) {
+		err = ErrInvalidQuorum
+		return
+	}
+	rt = newReplicaTracker(quorum)
+	for _, d := range c.Destinations {
+		rep := &replica{
+			eq:       newEmergencyQueue(),
+			rt:       rt,
+			inflight: make(map[*entry.Entry]uint64),
+			maxHeld:  replicaMaxHeld,
+		}
+		var cachePath string
+		if c.CachePath != `` {
+			cachePath = filepath.Join(c.CachePath, replicaCacheDir, replicaCacheName(d.Address))
+		}
+		if rep.feeders, err = newFeeders(c.CacheDepth, cachePath, c.CacheSize, c.CacheMode); err != nil {
+			return
+		}
+		reps = append(reps, rep)
+	}
+	return
+}
+
+// replicaCacheName turns a destination into something that is safe to use as a directory name,
+// we use the address rather than the index so that caches follow targets if the config is reordered
+func replicaCacheName(addr string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, addr)
+}
+
+// hold registers copies that are about to be written to a connection or parked in the emergency queue
+func (rep *replica) hold(res []*replicaEntry) {
+	rep.mtx.Lock()
+	for _, re := range res {
+		if re != nil && re.Ent != nil && re.ID != 0 {
+			rep.inflight[re.Ent] = re.ID
+		}
+	}
+	rep.mtx.Unlock()
+}
+
+// full reports whether the replica is holding as many copies as it is allowed to
+func (rep *replica) full() (r bool) {
+	rep.mtx.Lock()
+	r = len(rep.inflight) >= rep.maxHeld
+	rep.mtx.Unlock()
+	return
+}
+
+// park puts copies in the emergency queue, the replica picks them up as soon as it has a connection
+func (rep *replica) park(res []*replicaEntry) {
+	ents := make([]*entry.Entry, len(res))
+	for i := range res {
+		ents[i] = res[i].Ent
+	}
+	rep.hold(res)
+	rep.eq.push(nil, ents)
+}
+
+// lag parks copies for a replica that has fallen behind, once it is holding as many copies as it is
+// allowed to the copies are dropped so the replicas that make quorum are not held up
+func (rep *replica) lag(res []*replicaEntry) {
+	if rep.full() {
+		rep.dropped.Add(uint64(len(res)))
+		return
+	}
+	rep.park(res)
+}
+
+// release wraps entries back up with their IDs so they can go back down a feeder
+func (rep *replica) release(ents []*entry.Entry) (res []*replicaEntry) {
+	res = make([]*replicaEntry, 0, len(ents))
+	rep.mtx.Lock()
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		id := rep.inflight[ent]
+		delete(rep.inflight, ent)
+		res = append(res, &replicaEntry{ID: id, Ent: ent})
+	}
+	rep.mtx.Unlock()
+	return
+}
+
+// acked is handed to the entry writer and is called as the indexer confirms each entry
+func (rep *replica) acked(ent *entry.Entry) {
+	rep.mtx.Lock()
+	id, ok := rep.inflight[ent]
+	delete(rep.inflight, ent)
+	rep.mtx.Unlock()
+	if ok {
+		rep.rt.ack(id)
+	}
+}
+
+// feederEntry pulls an entry out of a feeder value, replica copies are registered so their acks can be counted
+func (rep *replica) feederEntry(v interface{}) *entry.Entry {
+	switch t := v.(type) {
+	case *entry.Entry:
+		return t
+	case *replicaEntry:
+		if rep != nil {
+			rep.hold([]*replicaEntry{t})
+		}
+		return t.Ent
+	}
+	return nil
+}
+
+// feederBatch is the batch version of feederEntry
+func (rep *replica) feederBatch(v interface{}) []*entry.Entry {
+	switch t := v.(type) {
+	case []*entry.Entry:
+		return t
+	case []*replicaEntry:
+		if rep != nil {
+			rep.hold(t)
+		}
+		ents := make([]*entry.Entry, 0, len(t))
+		for _, re := range t {
+			if re != nil && re.Ent != nil {
+				ents = append(ents, re.Ent)
+			}
+		}
+		return ents
+	}
+	return nil
+}
+
+// recycle pushes copies that did not make it to the indexer back to this replica and only this replica
+func (rep *replica) recycle(ents []*entry.Entry) {
+	if len(ents) == 0 {
+		return
+	}
+	res := rep.release(ents)
+	tmr := time.NewTimer(recycleTimeout)
+	defer tmr.Stop()
+	select {
+	case <-tmr.C:
+		rep.park(res)
+	case rep.bChan <- res:
+	}
+}
+
+// drain pushes anything in the emergency queue down the feeders, this is used when shutting down with a cache
+func (rep *replica) drain() {
+	for rep.eq.len() > 0 {
+		if ent, block, ok := rep.eq.pop(); ok {
+			if ent != nil {
+				block = append(block, ent)
+			}
+			if len(block) > 0 {
+				rep.bChan <- rep.release(block)
+			}
+		}
+	}
+}
+
+func (im *IngestMuxer) getReplica(idx int) *replica {
+	if idx < 0 || idx >= len(im.replicas) {
+		return nil
+	}
+	return im.replicas[idx]
+}
+
+func (im *IngestMuxer) replicaHot(rep *replica) {
+	atomic.StoreInt32(&rep.connHot, 1)
+	if im.cacheEnabled && !im.cacheAlways {
+		rep.cacheStop()
+	}
+}
+
+func (im *IngestMuxer) replicaDead(rep *replica) {
+	atomic.StoreInt32(&rep.connHot, 0)
+	if im.cacheEnabled && !im.cacheAlways {
+		rep.cacheStart()
+	}
+}
+
+// replicate hands a copy of every entry to every replica, the caller is responsible for validating entries.
+// Either ctx or to may be nil.
+//
+// A write is all or nothing.  If an error is returned no replica received a copy, once the first replica
+// has taken its copy every other replica is guaranteed one as well; replicas that cannot take theirs before
+// the write gives up have it parked in their emergency queue.  The exception is a replica that is holding as
+// many copies as it is allowed to and is not needed for quorum, it drops its copy.  Writers are held off while
+// too many entries are waiting on quorum or too few replicas have room to make quorum.
+func (im *IngestMuxer) replicate(ctx context.Context, to <-chan time.Time, ents []*entry.Entry, batch bool) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err = im.replicaWait(ctx, to); err != nil {
+		return
+	}
+	ids := make([]uint64, len(ents))
+	for i := range ents {
+		ids[i] = im.rt.track()
+	}
+	var late bool
+	var delivered int
+	for _, rep := range im.replicas {
+		res := make([]*replicaEntry, len(ents))
+		for i, ent := range ents {
+			cp := *ent // each replica translates tags and sets SRC independently
+			res[i] = &replicaEntry{ID: ids[i], Ent: &cp}
+		}
+		ch, v := rep.eChan, interface{}(res[0])
+		if batch {
+			ch, v = rep.bChan, res
+		}
+		if late {
+			//we already gave up waiting on a replica, the rest get one shot before being parked
+			select {
+			case ch <- v:
+			default:
+				rep.lag(res)
+			}
+			continue
+		}
+		if err = im.replicaSend(ctx, to, rep, ch, res, v); err != nil {
+			if delivered == 0 {
+				//nobody has a copy, the write never happened
+				im.rt.drop(ids)
+				return
+			}
+			//other replicas already have their copies, this one has to catch up
+			rep.lag(res)
+			late = true
+			err = nil
+		}
+		delivered++
+	}
+	im.ingesterState.Entries += uint64(len(ents))
+	for i := range ents {
+		im.ingesterState.Size += uint64(len(ents[i].Data))
+	}
+	im.tagStats.addBatch(im.tagStats.sizes(ents))
+	return
+}
+
+// replicaWait applies backpressure, it blocks until enough replicas have room for more copies to make
+// quorum and, when there is no cache to spill to, until the number of entries waiting on quorum is under the cap
+func (im *IngestMuxer) replicaWait(ctx context.Context, to <-chan time.Time) error {
+	var tmr *time.Timer
+	for !im.replicaRoom() {
+		if tmr == nil {
+			tmr = time.NewTimer(replicaRoomPoll)
+			defer tmr.Stop()
+		} else {
+			tmr.Reset(replicaRoomPoll)
+		}
+		select {
+		case <-tmr.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-to:
+			return ErrWriteTimeout
+		case <-im.writeBarrier:
+			return ErrNotRunning
+		}
+	}
+	return nil
+}
+
+func (im *IngestMuxer) replicaRoom() bool {
+	if !im.cacheEnabled && im.rt.full() {
+		return false
+	}
+	//admission follows the quorum-th fastest replica, anything slower than that drops its copies
+	var room int
+	for _, rep := range im.replicas {
+		if !rep.full() {
+			room++
+		}
+	}
+	return room >= im.rt.quorum
+}
+
+func (im *IngestMuxer) replicaSend(ctx context.Context, to <-chan time.Time, rep *replica, ch chan interface{}, res []*replicaEntry, v interface{}) error {
+	select {
+	case ch <- v:
+		return nil
+	default:
+	}
+	//the feeder is full, if the other replicas make quorum without this one it is the laggard and
+	//must not hold up writers, so it parks the copy to catch up on later or drops it if it is too far
+	//behind.  If the cache is enabled the feeder is already spilling to disk so we just wait on it
+	others := int(atomic.LoadInt32(&im.connHot)) - int(atomic.LoadInt32(&rep.connHot))
+	if !im.cacheEnabled && others >= im.rt.quorum {
+		rep.lag(res)
+		return nil
+	}
+	select {
+	case ch <- v:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-to:
+		return ErrWriteTimeout
+	case <-im.writeBarrier:
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// waitForQuorum waits for every replicated entry to be acknowledged by a quorum of replicas
+func (im *IngestMuxer) waitForQuorum(ctx context.Context, ts time.Time, to time.Duration) error {
+	for {
+		if pending, _ := im.rt.stats(); pending == 0 {
+			return nil
+		} else if int(atomic.LoadInt32(&im.connHot)) < im.rt.quorum {
+			return ErrQuorumUnavailable
+		} else if err := ctx.Err(); err != nil {
+			return err
+		} else if to > 0 && time.Since(ts) > to {
+			return ErrTimeout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ReplicationStats returns the current replication state, ErrNotReplicating is returned if the muxer
+// was not configured in replication mode
+func (im *IngestMuxer) ReplicationStats() (rs ReplicationStats, err error) {
+	if im.rt == nil {
+		err = ErrNotReplicating
+		return
+	}
+	im.mtx.RLock()
+	defer im.mtx.RUnlock()
+	if im.state != running {
+		err = ErrNotRunning
+		return
+	}
+	rs.Replicas = len(im.replicas)
+	rs.Quorum = im.rt.quorum
+	for _, rep := range im.replicas {
+		rs.Hot += int(atomic.LoadInt32(&rep.connHot))
+		rs.Dropped += rep.dropped.Load()
+	}
+	rs.Pending, rs.Committed = im.rt.stats()
+	return
+}
@@ -48,7 +48,8 @@ var (
 )
 
 type TLSCerts struct {
-	Cert tls.Certificate
+	Cert       tls.Certificate
+	ClientCert *ClientCertificate // optional, rotating client certificate presented instead of Cert
 }
 
 // ConnectionType cracks out the type of connection and returns its type, the target, and/or an error
@@ -82,10 +83,21 @@ func InitializeConnection(dst, authString string, tags []string, pubKey, privKey
 		Address: dst,
 		Secret:  authString,
 	}
-	return initConnection(tgt, tags, pubKey, privKey, verifyRemoteKey, context.Background())
+	t, _, err := ConnectionType(dst)
+	if err != nil {
+		return nil, err
+	}
+	var certs *TLSCerts
+	if t == `tls` {
+		//build up the certs so they can be thrown at the new TLS connection
+		if certs, err = getCerts(pubKey, privKey); err != nil {
+			return nil, err
+		}
+	}
+	return initConnection(tgt, tags, certs, verifyRemoteKey, context.Background())
 }
 
-func initConnection(tgt Target, tags []string, pubKey, privKey string, verifyRemoteKey bool, parentCtx context.Context) (*IngestConnection, error) {
+func initConnection(tgt Target, tags []string, certs *TLSCerts, verifyRemoteKey bool, parentCtx context.Context) (*IngestConnection, error) {
 	if len(tags) > int(entry.MaxTagId) {
 		return nil, ErrTooManyTags
 	}
@@ -100,14 +112,7 @@ func initConnection(tgt Target, tags []string, pubKey, privKey string, verifyRem
 	switch t {
 	//figure out which connection is specified
 	case "tls":
-		if err = verifyTlsKeys(pubKey, privKey); err != nil {
-			return nil, err
-		}
-		//build up the certs so they can be thrown at the new TLS connection
-		certs, err := getCerts(pubKey, privKey)
-		if err != nil {
-			return nil, err
-		} else if certs == nil {
+		if certs == nil {
 			return nil, ErrInvalidCerts
 		}
 		return newTLSConnection(dest, tgt.Tenant, auth, certs, verifyRemoteKey, tags, parentCtx)
@@ -122,12 +127,6 @@ func initConnection(tgt Target, tags []string, pubKey, privKey string, verifyRem
 	return nil, ErrInvalidDest
 }
 
-// verifyTlsKeys function will verify that public and private keys can be parsed
-func verifyTlsKeys(pub, priv string) error {
-	_, err := getCerts(pub, priv)
-	return err
-}
-
 func getCerts(pub, priv string) (*TLSCerts, error) {
 	var cert tls.Certificate
 	var err error
@@ -137,7 +136,7 @@ func getCerts(pub, priv string) (*TLSCerts, error) {
 			return nil, ErrInvalidCerts
 		}
 	}
-	certs := &TLSCerts{cert} //nil on remote pub because we aren't verifying
+	certs := &TLSCerts{Cert: cert} //nil on remote pub because we aren't verifying
 	return certs, nil
 }
 
@@ -219,7 +218,11 @@ func newTlsConn(dst string, certs *TLSCerts, verify bool) (net.Conn, net.IP, err
 		InsecureSkipVerify: !verify,
 	}
 	if certs != nil {
-		config.Certificates = []tls.Certificate{certs.Cert}
+		if certs.ClientCert != nil {
+			config.GetClientCertificate = certs.ClientCert.getClientCertificate
+		} else {
+			config.Certificates = []tls.Certificate{certs.Cert}
+		}
 	}
 
 	dialer := &net.Dialer{Timeout: 5 * time.Second}
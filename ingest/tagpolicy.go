@@ -0,0 +1,415 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"golang.org/x/time/rate"
+)
+
+const (
+	priorityCacheDir = `priority`
+
+	// waits shorter than this are just the limiter smoothing things out and are not counted as throttling
+	minTagThrottleWait = time.Millisecond
+
+	// number of entries and blocks a relay routine will hold back for rate limited tags before it stops
+	// pulling from its feeders, the priority lane is allowed twice as many
+	maxThrottled = 1024
+)
+
+var (
+	ErrPriorityReplication = errors.New("high priority tags cannot be used with replication")
+	ErrPriorityTargetGroup = errors.New("high priority tags cannot be restricted to a target group")
+)
+
+// TagThrottleState reports the admission policy and throttling history of a single tag
+type TagThrottleState struct {
+	Priority     string
+	RateLimit    int64         `json:",omitempty"` // bits per second, zero is unlimited
+	Throttled    uint64        // number of entries and blocks that were held back by the rate limit
+	ThrottleTime time.Duration // total time entries were held back by the rate limit
+}
+
+type tagPolicy struct {
+	config.TagPolicy
+	lm        *rate.Limiter //nil when the tag is not rate limited
+	burst     int
+	mtx       sync.Mutex
+	reps      map[*replica]*rate.Limiter //per replica limiters, created as replicas relay the tag
+	throttled atomic.Uint64
+	waited    atomic.Int64
+}
+
+// tagPolicies holds the per tag rate limits and priority classes
+type tagPolicies struct {
+	mtx     sync.RWMutex
+	byName  map[string]*tagPolicy
+	byTag   map[entry.EntryTag]*tagPolicy
+	high    bool // at least one tag is high priority
+	limited bool // at least one tag is rate limited
+	held    atomic.Int64
+}
+
+func newTagPolicies(c MuxerConfig, tagMap map[string]entry.EntryTag) (tp *tagPolicies, err error) {
+	if len(c.TagPolicies) == 0 {
+		return
+	}
+	tp = &tagPolicies{
+		byName: make(map[string]*tagPolicy, len(c.TagPolicies)),
+		byTag:  make(map[entry.EntryTag]*tagPolicy, len(c.TagPolicies)),
+	}
+	for _, v := range c.TagPolicies {
+		if err = CheckTag(v.Tag); err != nil {
+			return nil, fmt.Errorf("tag policy has invalid tag %q %w", v.Tag, err)
+		} else if _, ok := tp.byName[v.Tag]; ok {
+			return nil, fmt.Errorf("tag %q has more than one policy", v.Tag)
+		}
+		p := &tagPolicy{TagPolicy: v}
+		if v.RateLimit > 0 {
+			//limits are in bits, the limiter counts entry bytes and allows a one second burst
+			bps := v.RateLimit / 8
+			if bps <= 0 {
+				bps = 1
+			}
+			p.burst = int(bps)
+			p.lm = rate.NewLimiter(rate.Limit(bps), p.burst)
+		}
+		tp.high = tp.high || v.High()
+		tp.limited = tp.limited || p.lm != nil
+		tp.byName[v.Tag] = p
+		if local, ok := tagMap[v.Tag]; ok {
+			tp.byTag[local] = p
+		}
+	}
+	return
+}
+
+// register is called when a new tag is negotiated so that its policy applies
+func (tp *tagPolicies) register(name string, tg entry.EntryTag) {
+	if tp == nil {
+		return
+	}
+	if p, ok := tp.byName[name]; ok {
+		tp.mtx.Lock()
+		tp.byTag[tg] = p
+		tp.mtx.Unlock()
+	}
+}
+
+func (tp *tagPolicies) get(tg entry.EntryTag) (p *tagPolicy) {
+	if tp == nil {
+		return
+	}
+	tp.mtx.RLock()
+	p = tp.byTag[tg]
+	tp.mtx.RUnlock()
+	return
+}
+
+// isHigh returns true if the local tag is in the high priority class
+func (tp *tagPolicies) isHigh(tg entry.EntryTag) bool {
+	if tp == nil || !tp.high {
+		return false
+	}
+	p := tp.get(tg)
+	return p != nil && p.High()
+}
+
+// limiter returns the limiter that paces the tag, every replica gets its own copy of each entry
+// so every replica gets its own limiter
+func (p *tagPolicy) limiter(rep *replica) (lm *rate.Limiter) {
+	if lm = p.lm; rep == nil || lm == nil {
+		return
+	}
+	p.mtx.Lock()
+	if lm = p.reps[rep]; lm == nil {
+		if p.reps == nil {
+			p.reps = map[*replica]*rate.Limiter{}
+		}
+		lm = rate.NewLimiter(p.lm.Limit(), p.burst)
+		p.reps[rep] = lm
+	}
+	p.mtx.Unlock()
+	return
+}
+
+// reserve takes n bytes out of the tag's budget and returns how long they must be held before sending
+func (p *tagPolicy) reserve(rep *replica, now time.Time, n int) (d time.Duration) {
+	if p == nil || p.lm == nil || n <= 0 {
+		return
+	}
+	lm := p.limiter(rep)
+	for n > 0 {
+		sz := min(n, p.burst)
+		//reservations no larger than the burst always succeed and each one lands after the last
+		d = lm.ReserveN(now, sz).DelayFrom(now)
+		n -= sz
+	}
+	if d >= minTagThrottleWait {
+		p.throttled.Add(1)
+		p.waited.Add(int64(d))
+	}
+	return
+}
+
+// holding returns true if any relay routine is holding back entries for a rate limited tag
+func (tp *tagPolicies) holding() bool {
+	return tp != nil && tp.held.Load() > 0
+}
+
+func (tp *tagPolicies) state() (r map[string]TagThrottleState) {
+	if tp == nil {
+		return
+	}
+	r = make(map[string]TagThrottleState, len(tp.byName))
+	for name, p := range tp.byName {
+		r[name] = TagThrottleState{
+			Priority:     p.Priority,
+			RateLimit:    p.RateLimit,
+			Throttled:    p.throttled.Load(),
+			ThrottleTime: time.Duration(p.waited.Load()),
+		}
+	}
+	return
+}
+
+// newPriorityLane builds the feeders that carry high priority entries, there is no lane when
+// no tag is high priority.  Replicated and target group entries keep their own feeders so
+// high priority tags cannot be used with either.
+func newPriorityLane(c MuxerConfig, tp *tagPolicies) (*feeders, error) {
+	if tp == nil || !tp.high {
+		return nil, nil
+	} else if c.Replicate {
+		return nil, ErrPriorityReplication
+	}
+	for _, g := range c.TargetGroups {
+		for _, tag := range g.Tags {
+			if p, ok := tp.byName[tag]; ok && p.High() {
+				return nil, fmt.Errorf("tag %q in target group %q %w", tag, g.Name, ErrPriorityTargetGroup)
+			}
+		}
+	}
+	var cachePath string
+	if c.CachePath != `` {
+		cachePath = filepath.Join(c.CachePath, priorityCacheDir)
+	}
+	f, err := newFeeders(c.CacheDepth, cachePath, c.CacheSize, c.CacheMode)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// laneChans returns the priority lane feeder outputs, nil channels when there is no lane
+func (im *IngestMuxer) laneChans(rep *replica) (eC, bC chan interface{}) {
+	if im.prio != nil && rep == nil {
+		eC, bC = im.prio.eChanOut, im.prio.bChanOut
+	}
+	return
+}
+
+// relayPriority relays everything waiting in the priority lane before the relay routine goes back to
+// servicing the other feeders, so high priority entries jump the line when the connection is backed up
+// or a cache is draining.  A closed lane is set to nil so the other lane is still drained.  Draining stops
+// early if the throttle cannot hold any more rate limited entries.
+// A false return means no new connection could be acquired.
+func relayPriority(pEC, pBC *chan interface{}, thr *throttle, relay func(*entry.Entry, []*entry.Entry) bool) bool {
+	for !thr.prioFull() && (*pEC != nil || *pBC != nil) {
+		select {
+		case ee, eok := <-*pEC:
+			if !eok {
+				*pEC = nil
+			} else if e := (*replica)(nil).feederEntry(ee); e != nil && !relay(e, nil) {
+				return false
+			}
+		case bb, bok := <-*pBC:
+			if !bok {
+				*pBC = nil
+			} else if b := (*replica)(nil).feederBatch(bb); len(b) > 0 && !relay(nil, b) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+type throttled struct {
+	at  time.Time
+	ent *entry.Entry
+	blk []*entry.Entry
+}
+
+// throttle holds back the entries of rate limited tags inside a relay routine until the tag has the
+// budget to send them, entries of every other tag keep flowing past them.  A nil throttle holds nothing.
+type throttle struct {
+	tp  *tagPolicies
+	rep *replica
+	q   []throttled
+	tmr *time.Timer
+}
+
+func newThrottle(tp *tagPolicies, rep *replica) *throttle {
+	if tp == nil || !tp.limited {
+		return nil
+	}
+	tmr := time.NewTimer(time.Hour)
+	tmr.Stop()
+	return &throttle{tp: tp, rep: rep, tmr: tmr}
+}
+
+// hold reserves budget for an entry or a block and queues whatever has to wait,
+// the entry or the part of the block that can be sent right away is returned.
+func (t *throttle) hold(e *entry.Entry, b []*entry.Entry) (*entry.Entry, []*entry.Entry) {
+	if t == nil {
+		return e, b
+	}
+	now := time.Now()
+	if e != nil {
+		if d := t.tp.get(e.Tag).reserve(t.rep, now, len(e.Data)); d > 0 {
+			t.queue(throttled{at: now.Add(d), ent: e})
+			e = nil
+		}
+		return e, nil
+	}
+
+	//a block is reserved per tag, only the tags that have to wait are pulled out of it
+	sizes := map[*tagPolicy]int{}
+	for _, v := range b {
+		if v != nil {
+			if p := t.tp.get(v.Tag); p != nil && p.lm != nil {
+				sizes[p] += len(v.Data)
+			}
+		}
+	}
+	delays := map[*tagPolicy]time.Duration{}
+	for p, sz := range sizes {
+		if d := p.reserve(t.rep, now, sz); d > 0 {
+			delays[p] = d
+		}
+	}
+	if len(delays) == 0 {
+		return nil, b
+	}
+	ready := make([]*entry.Entry, 0, len(b))
+	waiting := map[*tagPolicy][]*entry.Entry{}
+	for _, v := range b {
+		if v == nil {
+			continue
+		} else if p := t.tp.get(v.Tag); delays[p] > 0 {
+			waiting[p] = append(waiting[p], v)
+		} else {
+			ready = append(ready, v)
+		}
+	}
+	for p, ents := range waiting {
+		t.queue(throttled{at: now.Add(delays[p]), blk: ents})
+	}
+	return nil, ready
+}
+
+func (t *throttle) queue(v throttled) {
+	t.q = append(t.q, v)
+	t.tp.held.Add(1)
+	t.arm()
+}
+
+// arm points the timer at the next entry that is due
+func (t *throttle) arm() {
+	if len(t.q) == 0 {
+		t.tmr.Stop()
+		return
+	}
+	next := t.q[0].at
+	for _, v := range t.q[1:] {
+		if v.at.Before(next) {
+			next = v.at
+		}
+	}
+	t.tmr.Reset(time.Until(next))
+}
+
+// C fires when held entries are due, it is nil when nothing is held
+func (t *throttle) C() <-chan time.Time {
+	if t == nil || len(t.q) == 0 {
+		return nil
+	}
+	return t.tmr.C
+}
+
+// release hands every due entry and block to send in the order they were held, a false return from
+// send stops the release and anything left stays held.
+func (t *throttle) release(send func(*entry.Entry, []*entry.Entry) bool) (ok bool) {
+	ok = true
+	now := time.Now()
+	keep := t.q[:0]
+	for i, v := range t.q {
+		if v.at.After(now) {
+			keep = append(keep, v)
+			continue
+		}
+		t.tp.held.Add(-1)
+		if ok = send(v.ent, v.blk); !ok {
+			keep = append(keep, t.q[i+1:]...)
+			break
+		}
+	}
+	clear(t.q[len(keep):])
+	t.q = keep
+	t.arm()
+	return
+}
+
+// full returns true when the relay routine should stop pulling from its normal feeders
+func (t *throttle) full() bool {
+	return t != nil && len(t.q) >= maxThrottled
+}
+
+// prioFull returns true when the relay routine should stop pulling from the priority lane
+func (t *throttle) prioFull() bool {
+	return t != nil && len(t.q) >= 2*maxThrottled
+}
+
+// flush pushes everything still held into the emergency queues when the relay routine exits,
+// the next routine to come up picks them back up
+func (t *throttle) flush(im *IngestMuxer) {
+	if t == nil {
+		return
+	}
+	t.tmr.Stop()
+	for _, v := range t.q {
+		t.tp.held.Add(-1)
+		ents := v.blk
+		if v.ent != nil {
+			ents = []*entry.Entry{v.ent}
+		}
+		if t.rep != nil {
+			t.rep.park(t.rep.release(ents))
+			continue
+		}
+		for _, gb := range im.splitBatch(ents) {
+			if gb.grp != nil {
+				gb.grp.eq.push(nil, gb.ents)
+			} else {
+				im.eq.push(nil, gb.ents)
+			}
+		}
+	}
+	t.q = nil
+}
@@ -0,0 +1,302 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func newTagPolicyMuxer(t *testing.T, pols []config.TagPolicy) *IngestMuxer {
+	im, err := NewMuxer(MuxerConfig{
+		Destinations: []Target{
+			{Address: `tcp://10.0.0.1:4023`, Secret: `x`},
+			{Address: `tcp://10.0.0.2:4023`, Secret: `x`},
+		},
+		Tags:        []string{`default`, `alerts`, `debug`},
+		TagPolicies: pols,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return im
+}
+
+func TestTagPriorityRouting(t *testing.T) {
+	im := newTagPolicyMuxer(t, []config.TagPolicy{
+		{Tag: `alerts`, Priority: config.TagPriorityHigh},
+		{Tag: `audit`, Priority: config.TagPriorityHigh},
+		{Tag: `debug`, Priority: config.TagPriorityNormal, RateLimit: 1024 * 1024},
+	})
+	if im.prio == nil {
+		t.Fatal("missing priority lane")
+	}
+	def, _ := im.GetTag(`default`)
+	alerts, _ := im.GetTag(`alerts`)
+	debug, _ := im.GetTag(`debug`)
+	if im.entryChan(def) != im.eChan || im.entryChan(debug) != im.eChan {
+		t.Fatal("normal tag routed to the priority lane")
+	} else if im.entryChan(alerts) != im.prio.eChan {
+		t.Fatal("high priority tag not routed to the priority lane")
+	}
+
+	//audit is not known yet, negotiating it should pick up its policy
+	audit, err := im.NegotiateTag(`audit`)
+	if err != nil {
+		t.Fatal(err)
+	} else if im.entryChan(audit) != im.prio.eChan {
+		t.Fatal("negotiated tag did not land in the priority lane")
+	}
+
+	b := []*entry.Entry{{Tag: def}, {Tag: alerts}, nil, {Tag: debug}, {Tag: audit}}
+	gbs := im.splitBatch(b)
+	if len(gbs) != 2 {
+		t.Fatalf("bad split count %d", len(gbs))
+	}
+	for _, gb := range gbs {
+		if gb.ch != im.bChan && gb.ch != im.prio.bChan {
+			t.Fatalf("bad batch channel %+v", gb)
+		} else if len(gb.ents) != 2 {
+			t.Fatalf("bad batch size %+v", gb)
+		}
+	}
+
+	//no high priority tags means no lane
+	if im = newTagPolicyMuxer(t, []config.TagPolicy{{Tag: `debug`, RateLimit: 1024 * 1024}}); im.prio != nil {
+		t.Fatal("priority lane built without high priority tags")
+	}
+
+	//replicas and target groups have their own feeders, high priority tags cannot ride them
+	high := []config.TagPolicy{{Tag: `alerts`, Priority: config.TagPriorityHigh}}
+	tp, err := newTagPolicies(MuxerConfig{TagPolicies: high}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newPriorityLane(MuxerConfig{Replicate: true}, tp); !errors.Is(err, ErrPriorityReplication) {
+		t.Fatalf("bad replication error %v", err)
+	}
+	grps := []config.TargetGroup{{Name: `secure`, Tags: []string{`alerts`}}}
+	if _, err = newPriorityLane(MuxerConfig{TargetGroups: grps}, tp); !errors.Is(err, ErrPriorityTargetGroup) {
+		t.Fatalf("bad target group error %v", err)
+	}
+	grps[0].Tags = []string{`debug`}
+	if _, err = newPriorityLane(MuxerConfig{TargetGroups: grps}, tp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTagRateLimit(t *testing.T) {
+	im := newTagPolicyMuxer(t, []config.TagPolicy{
+		{Tag: `debug`, Priority: config.TagPriorityNormal, RateLimit: 8 * 1024}, // 1KB/s
+	})
+	def, _ := im.GetTag(`default`)
+	debug, _ := im.GetTag(`debug`)
+	thr := newThrottle(im.tagPols, nil)
+	if thr == nil {
+		t.Fatal("no throttle with a rate limited tag")
+	}
+
+	//the first second is free, the next 100 bytes have to wait while other tags go straight through
+	if e, _ := thr.hold(&entry.Entry{Tag: debug, Data: make([]byte, 1024)}, nil); e == nil {
+		t.Fatal("entry within the burst was held")
+	} else if e, _ = thr.hold(&entry.Entry{Tag: debug, Data: make([]byte, 100)}, nil); e != nil {
+		t.Fatal("entry over the limit was not held")
+	} else if e, _ = thr.hold(&entry.Entry{Tag: def, Data: make([]byte, 4096)}, nil); e == nil {
+		t.Fatal("unlimited tag was held")
+	}
+	_, b := thr.hold(nil, []*entry.Entry{{Tag: debug, Data: make([]byte, 100)}, {Tag: def, Data: make([]byte, 4096)}})
+	if len(b) != 1 || b[0].Tag != def {
+		t.Fatalf("bad ready block %+v", b)
+	} else if !im.tagPols.holding() || thr.C() == nil {
+		t.Fatal("throttle is not holding anything")
+	}
+
+	start := time.Now()
+	var sent []int
+	for len(thr.q) > 0 {
+		select {
+		case <-thr.C():
+		case <-time.After(2 * time.Second):
+			t.Fatal("held entries were never released")
+		}
+		thr.release(func(e *entry.Entry, b []*entry.Entry) bool {
+			if e != nil {
+				sent = append(sent, 1)
+			} else {
+				sent = append(sent, len(b))
+			}
+			return true
+		})
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("rate limit not applied, released after %v", d)
+	} else if len(sent) != 2 || im.tagPols.holding() || thr.C() != nil {
+		t.Fatalf("bad release %v", sent)
+	}
+	st := im.tagPols.state()
+	if s, ok := st[`debug`]; !ok || s.Throttled != 2 || s.ThrottleTime <= 0 || s.RateLimit != 8*1024 {
+		t.Fatalf("bad throttle state %+v", st)
+	}
+
+	//every replica gets its own budget
+	p := im.tagPols.get(debug)
+	now := time.Now()
+	if p.reserve(&replica{}, now, 1024) != 0 || p.reserve(&replica{}, now, 1024) != 0 {
+		t.Fatal("replicas share a rate limit")
+	}
+
+	//anything still held when the relay exits lands in the emergency queue
+	thr.hold(&entry.Entry{Tag: debug, Data: make([]byte, 1024)}, nil)
+	thr.flush(im)
+	if im.eq.len() != 1 || im.tagPols.holding() {
+		t.Fatalf("flush left %d in the emergency queue", im.eq.len())
+	}
+
+	//no rate limits means no throttle
+	if newThrottle(newTagPolicyMuxer(t, []config.TagPolicy{{Tag: `alerts`, Priority: config.TagPriorityHigh}}).tagPols, nil) != nil {
+		t.Fatal("throttle built without rate limits")
+	}
+
+	//state copies must not share the map
+	s := IngesterState{TagThrottle: st}
+	c := s.Copy()
+	c.TagThrottle[`debug`] = TagThrottleState{}
+	if s.TagThrottle[`debug`].Throttled != 2 {
+		t.Fatal("copy shares the tag throttle map")
+	}
+}
+
+func TestTagThrottleRelay(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im, err := NewMuxer(MuxerConfig{
+		Destinations: []Target{ti.target()},
+		Tags:         []string{`default`, `alerts`, `debug`},
+		TagPolicies: []config.TagPolicy{
+			{Tag: `alerts`, Priority: config.TagPriorityHigh},
+			{Tag: `debug`, RateLimit: 8 * 1024}, // 1KB/s
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if err = im.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer im.Close()
+	if err = im.WaitForHot(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	def, _ := im.GetTag(`default`)
+	alerts, _ := im.GetTag(`alerts`)
+	debug, _ := im.GetTag(`debug`)
+
+	//a throttled tag must not hold up the writer or anything written after it
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		data := fmt.Appendf(nil, "debug %d %s", i, strings.Repeat(`x`, 500))
+		if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: debug, Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.WriteBatch([]*entry.Entry{
+		{TS: entry.Now(), Tag: alerts, Data: []byte(`high`)},
+		{TS: entry.Now(), Tag: def, Data: []byte(`normal`)},
+	}); err != nil {
+		t.Fatal(err)
+	} else if d := time.Since(start); d > 250*time.Millisecond {
+		t.Fatalf("writers were held up for %v", d)
+	}
+
+	//a sync has to wait for the held entries
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	} else if d := time.Since(start); d < 750*time.Millisecond {
+		t.Fatalf("rate limit not applied, sync finished in %v", d)
+	}
+	order := ti.arrived()
+	if len(order) != 6 {
+		t.Fatalf("sync returned with %d of 6 entries", len(order))
+	} else if !slices.Contains(order[:4], `high`) || !slices.Contains(order[:4], `normal`) {
+		t.Fatalf("throttled tag held up other tags %q", order)
+	}
+	for i, v := range order[4:] {
+		if !strings.HasPrefix(v, fmt.Sprintf("debug %d ", i+2)) {
+			t.Fatalf("throttled entries out of order %q", order)
+		}
+	}
+}
+
+func TestTagPriorityDrain(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im, err := NewMuxer(MuxerConfig{
+		Destinations: []Target{ti.target()},
+		Tags:         []string{`default`, `alerts`},
+		TagPolicies:  []config.TagPolicy{{Tag: `alerts`, Priority: config.TagPriorityHigh}},
+		CacheDepth:   128,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := im.GetTag(`default`)
+	alerts, _ := im.GetTag(`alerts`)
+
+	//backlog every lane before the relay routine exists, normal entries go in first
+	const count = 32
+	newEnt := func(tg entry.EntryTag, name string, i int) *entry.Entry {
+		return &entry.Entry{TS: entry.Now(), Tag: tg, Data: []byte(fmt.Sprintf("%s %d", name, i))}
+	}
+	for i := 0; i < count; i++ {
+		im.eChan <- newEnt(def, `normal`, i)
+		im.bChan <- []*entry.Entry{newEnt(def, `normal batch`, i)}
+	}
+	for i := 0; i < count; i++ {
+		im.prio.eChan <- newEnt(alerts, `high`, i)
+		im.prio.bChan <- []*entry.Entry{newEnt(alerts, `high batch`, i)}
+	}
+	if err = im.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer im.Close()
+	if err = im.WaitForHot(5 * time.Second); err != nil {
+		t.Fatal(err)
+	} else if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for i := 0; i < 500; i++ {
+		if order = ti.arrived(); len(order) == 4*count {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(order) != 4*count {
+		t.Fatalf("indexer saw %d of %d entries", len(order), 4*count)
+	}
+	for i, v := range order {
+		high := strings.HasPrefix(v, `high`)
+		exp := `default`
+		if high {
+			exp = `alerts`
+		}
+		if high != (i < 2*count) {
+			t.Fatalf("entry %d %q arrived out of priority order", i, v)
+		} else if ti.tag(v) != exp {
+			t.Fatalf("%q has bad tag %q", v, ti.tag(v))
+		}
+	}
+}
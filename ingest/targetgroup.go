@@ -0,0 +1,393 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gravwell/gravwell/v3/chancacher"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	targetGroupCacheDir = `groups`
+)
+
+var (
+	ErrUnknownTargetGroup = errors.New("Unknown target group")
+	ErrDittoGroupedTag    = errors.New("Ditto blocks cannot carry tags that are restricted to a target group")
+)
+
+// targetGroup is a subset of the muxer destinations which are the only connections allowed
+// to receive entries for a set of tags.  Each group gets its own feeder channels, cache, and
+// emergency queue so that restricted entries can never leak out to a connection outside the group.
+type targetGroup struct {
+	//connHot and connDead are accessed atomically, keep them at the top for alignment
+	connHot  int32
+	connDead int32
+	name     string
+	size     int // number of destinations in the group
+	feeders
+	eq *emergencyQueue
+}
+
+// feeders are the channels (and optional caches) which carry entries to the write relay routines
+type feeders struct {
+	eChan    chan interface{}
+	eChanOut chan interface{}
+	bChan    chan interface{}
+	bChanOut chan interface{}
+	cache    *chancacher.ChanCacher
+	bcache   *chancacher.ChanCacher
+}
+
+func newFeeders(depth int, cachePath string, cacheSize int, cacheMode string) (f feeders, err error) {
+	//the write-ahead log sits in front of the feeders, so they never need a cache of their own
+	if cachePath != "" && strings.ToLower(cacheMode) != CacheModeWAL {
+		if f.cache, err = chancacher.NewChanCacher(depth, filepath.Join(cachePath, "e"), mb*cacheSize); err != nil {
+			return
+		}
+		if f.bcache, err = chancacher.NewChanCacher(depth, filepath.Join(cachePath, "b"), mb*cacheSize); err != nil {
+			return
+		}
+		if cacheMode == CacheModeFail {
+			f.cache.CacheStop()
+			f.bcache.CacheStop()
+		}
+		f.eChan, f.eChanOut = f.cache.In, f.cache.Out
+		f.bChan, f.bChanOut = f.bcache.In, f.bcache.Out
+		return
+	}
+	// no cache active, just plumb a channel all the way through
+	if depth <= 0 {
+		depth = defaultIngestChanDepth
+	} else if depth > maxIngestChanDepth {
+		depth = maxIngestChanDepth
+	}
+	eChan := make(chan interface{}, depth)
+	bChan := make(chan interface{}, depth)
+	f.eChan, f.eChanOut = eChan, eChan
+	f.bChan, f.bChanOut = bChan, bChan
+	return
+}
+
+// empty returns true if there is nothing sitting in the feeder channels
+func (f feeders) empty() bool {
+	return len(f.eChanOut) == 0 && len(f.bChanOut) == 0 && len(f.eChan) == 0 && len(f.bChan) == 0
+}
+
+func (f feeders) cacheStart() {
+	if f.cache != nil {
+		f.cache.CacheStart()
+		f.bcache.CacheStart()
+	}
+}
+
+func (f feeders) cacheStop() {
+	if f.cache != nil {
+		f.cache.CacheStop()
+		f.bcache.CacheStop()
+	}
+}
+
+func (f feeders) cacheSize() (sz int) {
+	if f.cache != nil {
+		sz = f.cache.Size() + f.bcache.Size()
+	}
+	return
+}
+
+// newTargetGroups builds the target groups and hands back the group for each destination
+func newTargetGroups(c MuxerConfig, tagMap map[string]entry.EntryTag) (groups []*targetGroup, destGroups []*targetGroup, tagGroups map[entry.EntryTag]*targetGroup, groupTags map[string]*targetGroup, err error) {
+	if len(c.TargetGroups) == 0 {
+		return
+	}
+	destIdx := make(map[string]int, len(c.Destinations))
+	for i, d := range c.Destinations {
+		destIdx[d.Address] = i
+	}
+	destGroups = make([]*targetGroup, len(c.Destinations))
+	tagGroups = make(map[entry.EntryTag]*targetGroup)
+	groupTags = make(map[string]*targetGroup)
+	for _, tg := range c.TargetGroups {
+		grp := &targetGroup{
+			name: tg.Name,
+			eq:   newEmergencyQueue(),
+		}
+		for _, t := range tg.Targets {
+			idx, ok := destIdx[t]
+			if !ok {
+				err = fmt.Errorf("target group %q target %q is not a destination", tg.Name, t)
+				return
+			} else if destGroups[idx] != nil && destGroups[idx] != grp {
+				err = fmt.Errorf("target %q is a member of target groups %q and %q", t, destGroups[idx].name, tg.Name)
+				return
+			} else if destGroups[idx] == nil {
+				destGroups[idx] = grp
+				grp.size++
+			}
+		}
+		if grp.size == 0 {
+			err = fmt.Errorf("target group %q has no targets", tg.Name)
+			return
+		}
+		for _, name := range tg.Tags {
+			if err = CheckTag(name); err != nil {
+				err = fmt.Errorf("target group %q has invalid tag %q %w", tg.Name, name, err)
+				return
+			} else if g, ok := groupTags[name]; ok && g != grp {
+				err = fmt.Errorf("tag %q is assigned to target groups %q and %q", name, g.name, tg.Name)
+				return
+			}
+			groupTags[name] = grp
+			if local, ok := tagMap[name]; ok {
+				tagGroups[local] = grp
+			}
+		}
+		var cachePath string
+		if c.CachePath != `` {
+			cachePath = filepath.Join(c.CachePath, targetGroupCacheDir, tg.Name)
+		}
+		if grp.feeders, err = newFeeders(c.CacheDepth, cachePath, c.CacheSize, c.CacheMode); err != nil {
+			return
+		}
+		groups = append(groups, grp)
+	}
+	return
+}
+
+// destGroup returns the target group that a destination belongs to, nil means it only services ungrouped entries
+func (im *IngestMuxer) destGroup(idx int) *targetGroup {
+	if idx < 0 || idx >= len(im.destGroups) {
+		return nil
+	}
+	return im.destGroups[idx]
+}
+
+// tagGroup returns the target group a local tag is restricted to, nil means it can go anywhere
+func (im *IngestMuxer) tagGroup(tg entry.EntryTag) (grp *targetGroup) {
+	if len(im.groups) == 0 || tg == entry.GravwellTagId {
+		return
+	}
+	im.groupMtx.RLock()
+	grp = im.tagGroups[tg]
+	im.groupMtx.RUnlock()
+	return
+}
+
+// entryChan returns the channel that an entry with the given local tag should be written to
+func (im *IngestMuxer) entryChan(tg entry.EntryTag) chan interface{} {
+	if grp := im.tagGroup(tg); grp != nil {
+		return grp.eChan
+	} else if im.prio != nil && im.tagPols.isHigh(tg) {
+		return im.prio.eChan
+	}
+	return im.eChan
+}
+
+// batchChan returns the batch channel for ungrouped entries with the given local tag
+func (im *IngestMuxer) batchChan(tg entry.EntryTag) chan interface{} {
+	if im.prio != nil && im.tagPols.isHigh(tg) {
+		return im.prio.bChan
+	}
+	return im.bChan
+}
+
+type groupBatch struct {
+	grp  *targetGroup
+	ch   chan interface{}
+	ents []*entry.Entry
+}
+
+// splitBatch breaks a batch up by target group and priority lane, if there are no groups
+// or lanes the batch is handed back untouched
+func (im *IngestMuxer) splitBatch(b []*entry.Entry) []groupBatch {
+	if len(im.groups) == 0 && im.prio == nil {
+		return []groupBatch{{ch: im.bChan, ents: b}}
+	}
+	var ret []groupBatch
+	idx := map[chan interface{}]int{}
+	for _, ent := range b {
+		if ent == nil {
+			continue
+		}
+		grp := im.tagGroup(ent.Tag)
+		ch := im.batchChan(ent.Tag)
+		if grp != nil {
+			ch = grp.bChan
+		}
+		i, ok := idx[ch]
+		if !ok {
+			i = len(ret)
+			idx[ch] = i
+			ret = append(ret, groupBatch{grp: grp, ch: ch})
+		}
+		ret[i].ents = append(ret[i].ents, ent)
+	}
+	return ret
+}
+
+// registerGroupTag is called when a new tag is negotiated so that it lands in its target group
+// caller must hold the muxer lock
+func (im *IngestMuxer) registerGroupTag(name string, tg entry.EntryTag) {
+	if grp, ok := im.groupTags[name]; ok {
+		im.groupMtx.Lock()
+		im.tagGroups[tg] = grp
+		im.groupMtx.Unlock()
+	}
+}
+
+// clearEmergencyQueues attempts to push the general emergency queue and any target group or
+// replica queue the connection belongs to down the connection.
+func (im *IngestMuxer) clearEmergencyQueues(nc connSet) bool {
+	if !im.eq.clear(nc.ig, nc.tt) {
+		return false
+	} else if nc.grp != nil {
+		return nc.grp.eq.clear(nc.ig, nc.tt)
+	} else if nc.rep != nil {
+		return nc.rep.eq.clear(nc.ig, nc.tt)
+	}
+	return true
+}
+
+func (im *IngestMuxer) groupHot(grp *targetGroup) {
+	atomic.AddInt32(&grp.connDead, -1)
+	if atomic.AddInt32(&grp.connHot, 1) == 1 {
+		if im.cacheEnabled && !im.cacheAlways {
+			grp.cacheStop()
+		}
+	}
+}
+
+func (im *IngestMuxer) groupDead(grp *targetGroup) {
+	if atomic.AddInt32(&grp.connHot, -1) == 0 {
+		if im.cacheEnabled && !im.cacheAlways {
+			grp.cacheStart()
+		}
+	}
+	atomic.AddInt32(&grp.connDead, 1)
+}
+
+func (im *IngestMuxer) getGroup(name string) (*targetGroup, error) {
+	for _, grp := range im.groups {
+		if grp.name == name {
+			return grp, nil
+		}
+	}
+	return nil, ErrUnknownTargetGroup
+}
+
+// TargetGroups returns the names of all configured target groups
+func (im *IngestMuxer) TargetGroups() (names []string) {
+	for _, grp := range im.groups {
+		names = append(names, grp.name)
+	}
+	return
+}
+
+// GroupHot returns how many connections in the named target group are functioning
+func (im *IngestMuxer) GroupHot(name string) (int, error) {
+	im.mtx.RLock()
+	defer im.mtx.RUnlock()
+	if im.state != running {
+		return -1, ErrNotRunning
+	}
+	grp, err := im.getGroup(name)
+	if err != nil {
+		return -1, err
+	}
+	return int(atomic.LoadInt32(&grp.connHot)), nil
+}
+
+// GroupDead returns how many connections in the named target group are currently dead
+func (im *IngestMuxer) GroupDead(name string) (int, error) {
+	im.mtx.RLock()
+	defer im.mtx.RUnlock()
+	if im.state != running {
+		return -1, ErrNotRunning
+	}
+	grp, err := im.getGroup(name)
+	if err != nil {
+		return -1, err
+	}
+	return int(atomic.LoadInt32(&grp.connDead)), nil
+}
+
+// GroupSize returns the total number of connections in the named target group, hot or dead
+func (im *IngestMuxer) GroupSize(name string) (int, error) {
+	grp, err := im.getGroup(name)
+	if err != nil {
+		return -1, err
+	}
+	return grp.size, nil
+}
+
+// pipelinesEmpty returns true if the general, priority, target group, and replica feeders are all empty,
+// the feeder has caught up with the write-ahead log, and no rate limited entries are being held back
+func (im *IngestMuxer) pipelinesEmpty() bool {
+	if len(im.eChanOut) != 0 || len(im.bChanOut) != 0 || len(im.eChan) != 0 || len(im.bChan) != 0 {
+		return false
+	} else if im.wal != nil && im.wal.Backlog() != 0 {
+		return false
+	} else if im.prio != nil && !im.prio.empty() {
+		return false
+	} else if im.tagPols.holding() {
+		return false
+	}
+	for _, grp := range im.groups {
+		if !grp.empty() {
+			return false
+		}
+	}
+	for _, rep := range im.replicas {
+		//copies waiting on a dead replica are covered by the quorum check
+		if atomic.LoadInt32(&rep.connHot) == 1 && !rep.empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedSize returns the number of bytes held in the general, priority, target group, and replica caches
+// or the write-ahead log
+func (im *IngestMuxer) cachedSize() (sz int) {
+	if im.wal != nil {
+		return int(im.wal.Size())
+	} else if !im.cacheEnabled {
+		return
+	}
+	sz = im.cache.Size() + im.bcache.Size()
+	if im.prio != nil {
+		sz += im.prio.cacheSize()
+	}
+	for _, grp := range im.groups {
+		sz += grp.cacheSize()
+	}
+	for _, rep := range im.replicas {
+		sz += rep.cacheSize()
+	}
+	return
+}
+
+func drainEmergencyQueue(eq *emergencyQueue, eChan, bChan chan interface{}) {
+	for eq.len() > 0 {
+		if ent, block, ok := eq.pop(); ok {
+			if ent != nil {
+				eChan <- ent
+			}
+			if len(block) > 0 {
+				bChan <- block
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func newTargetGroupMuxer(t *testing.T, groups []config.TargetGroup) *IngestMuxer {
+	im, err := NewMuxer(MuxerConfig{
+		Destinations: []Target{
+			{Address: `tcp://10.0.0.1:4023`, Secret: `x`},
+			{Address: `tcp://10.0.0.2:4023`, Secret: `x`},
+			{Address: `tcp://10.0.0.3:4023`, Secret: `x`},
+		},
+		Tags:         []string{`default`, `pci`, `hr`},
+		TargetGroups: groups,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return im
+}
+
+func TestTargetGroupRouting(t *testing.T) {
+	im := newTargetGroupMuxer(t, []config.TargetGroup{
+		{Name: `secure`, Targets: []string{`tcp://10.0.0.3:4023`}, Tags: []string{`pci`, `hr`, `payroll`}},
+	})
+	grp, err := im.getGroup(`secure`)
+	if err != nil {
+		t.Fatal(err)
+	} else if grp.size != 1 {
+		t.Fatalf("bad group size %d", grp.size)
+	}
+	if im.destGroup(0) != nil || im.destGroup(1) != nil || im.destGroup(2) != grp {
+		t.Fatal("destinations assigned to the wrong groups")
+	}
+
+	def, _ := im.GetTag(`default`)
+	pci, _ := im.GetTag(`pci`)
+	hr, _ := im.GetTag(`hr`)
+	if im.entryChan(def) != im.eChan || im.entryChan(entry.GravwellTagId) != im.eChan {
+		t.Fatal("ungrouped tag routed to a group")
+	} else if im.entryChan(pci) != grp.eChan || im.entryChan(hr) != grp.eChan {
+		t.Fatal("grouped tag not routed to group")
+	}
+
+	//payroll is not known yet, negotiating it should land it in the group
+	payroll, err := im.NegotiateTag(`payroll`)
+	if err != nil {
+		t.Fatal(err)
+	} else if im.tagGroup(payroll) != grp {
+		t.Fatal("negotiated tag did not land in its group")
+	}
+
+	b := []*entry.Entry{{Tag: def}, {Tag: pci}, nil, {Tag: def}, {Tag: hr}}
+	gbs := im.splitBatch(b)
+	if len(gbs) != 2 {
+		t.Fatalf("bad split count %d", len(gbs))
+	}
+	for _, gb := range gbs {
+		if gb.grp == nil && (gb.ch != im.bChan || len(gb.ents) != 2) {
+			t.Fatalf("bad default batch %+v", gb)
+		} else if gb.grp == grp && (gb.ch != grp.bChan || len(gb.ents) != 2) {
+			t.Fatalf("bad group batch %+v", gb)
+		}
+	}
+}
+
+func TestTargetGroupNoGroups(t *testing.T) {
+	im := newTargetGroupMuxer(t, nil)
+	b := []*entry.Entry{{Tag: 0}, {Tag: 1}}
+	if gbs := im.splitBatch(b); len(gbs) != 1 || gbs[0].ch != im.bChan || len(gbs[0].ents) != 2 {
+		t.Fatalf("bad split %+v", gbs)
+	}
+	if _, err := im.getGroup(`foo`); err != ErrUnknownTargetGroup {
+		t.Fatalf("bad error on missing group: %v", err)
+	}
+}
+
+func TestTargetGroupBatchCancel(t *testing.T) {
+	im := newTargetGroupMuxer(t, []config.TargetGroup{
+		{Name: `secure`, Targets: []string{`tcp://10.0.0.3:4023`}, Tags: []string{`pci`}},
+	})
+	grp, err := im.getGroup(`secure`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//swap in channels we control so the parts of the batch can be held up
+	im.state = running
+	im.bChan = make(chan interface{}, 1)
+	grp.bChan = make(chan interface{})
+	def, _ := im.GetTag(`default`)
+	pci, _ := im.GetTag(`pci`)
+	b := []*entry.Entry{{Tag: def, Data: []byte(`a`)}, {Tag: pci, Data: []byte(`b`)}}
+
+	//cancelled before the first part is queued, nothing is written
+	im.bChan <- nil
+	ctx, cf := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cf()
+	if err = im.WriteBatchContext(ctx, b); err != context.DeadlineExceeded {
+		t.Fatalf("bad error %v", err)
+	}
+	<-im.bChan
+
+	//once a part is queued the cancel is ignored and the rest follows
+	got := make(chan interface{}, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		got <- <-grp.bChan
+	}()
+	ctx, cf = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cf()
+	if err = im.WriteBatchContext(ctx, b); err != nil {
+		t.Fatal(err)
+	} else if ents, ok := (<-im.bChan).([]*entry.Entry); !ok || len(ents) != 1 || ents[0].Tag != def {
+		t.Fatalf("bad default part %v", ents)
+	} else if ents, ok = (<-got).([]*entry.Entry); !ok || len(ents) != 1 || ents[0].Tag != pci {
+		t.Fatalf("bad group part %v", ents)
+	} else if im.ingesterState.Entries != 2 {
+		t.Fatalf("bad entry count %d", im.ingesterState.Entries)
+	}
+}
+
+func TestTargetGroupInvalid(t *testing.T) {
+	bad := [][]config.TargetGroup{
+		{{Name: `a`, Targets: []string{`tcp://10.0.0.9:4023`}}},
+		{{Name: `a`}},
+		{
+			{Name: `a`, Targets: []string{`tcp://10.0.0.1:4023`}},
+			{Name: `b`, Targets: []string{`tcp://10.0.0.1:4023`}},
+		},
+		{
+			{Name: `a`, Targets: []string{`tcp://10.0.0.1:4023`}, Tags: []string{`pci`}},
+			{Name: `b`, Targets: []string{`tcp://10.0.0.2:4023`}, Tags: []string{`pci`}},
+		},
+	}
+	for i, v := range bad {
+		if _, err := NewMuxer(MuxerConfig{
+			Destinations: []Target{{Address: `tcp://10.0.0.1:4023`}, {Address: `tcp://10.0.0.2:4023`}},
+			Tags:         []string{`pci`},
+			TargetGroups: v,
+		}); err == nil {
+			t.Fatalf("failed to catch bad target group config %d", i)
+		}
+	}
+}
+
+func TestTargetGroupDitto(t *testing.T) {
+	tis := []*testIndexer{newTestIndexer(t, 1), newTestIndexer(t, 100)}
+	defer func() {
+		for _, ti := range tis {
+			ti.Close()
+		}
+	}()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{tis[0].target(), tis[1].target()},
+		Tags:         []string{`default`, `pci`},
+		TargetGroups: []config.TargetGroup{
+			{Name: `secure`, Targets: []string{tis[1].target().Address}, Tags: []string{`pci`}},
+		},
+	})
+	defer im.Close()
+	waitForHotCount(t, im, 2)
+	def, _ := im.GetTag(`default`)
+	pci, _ := im.GetTag(`pci`)
+
+	//a grouped tag anywhere in the block rejects the whole block
+	blk := []entry.Entry{
+		{TS: entry.Now(), Tag: def, Data: []byte(`ditto default`)},
+		{TS: entry.Now(), Tag: pci, Data: []byte(`ditto pci`)},
+	}
+	if err := im.DittoWriteContext(context.Background(), blk); err != ErrDittoGroupedTag {
+		t.Fatalf("bad error on grouped ditto block %v", err)
+	} else if err = im.DittoWriteContext(context.Background(), blk[:1]); err != nil {
+		t.Fatal(err)
+	} else if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500 && tis[0].count(`ditto default`)+tis[1].count(`ditto default`) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := tis[0].count(`ditto default`) + tis[1].count(`ditto default`); n != 1 {
+		t.Fatalf("ungrouped ditto entry delivered %d times", n)
+	} else if tis[0].count(`ditto pci`)+tis[1].count(`ditto pci`) != 0 {
+		t.Fatal("rejected ditto block was delivered")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"strings"
@@ -22,7 +23,6 @@ import (
 	"unicode"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
-	"github.com/klauspost/compress/snappy"
 )
 
 var (
@@ -220,22 +220,28 @@ func mergeError(ogErr, newErr error) error {
 // the klauspost snappy writer deprecated the writer that does simple writes and is now forcing a buffered writer
 // this is a little wrapper that forces a flush after every write because we need things to go to the wire when a write
 // happens. It's a hack to get around someone trying to help.
-type autoFlushSnappyWriter struct {
-	wtr *snappy.Writer
+// The zstd and lz4 stream writers behave the same way, so they get wrapped too.
+type flushWriter interface {
+	io.Writer
+	Flush() error
 }
 
-func newSnappyFlushWriter(wtr *snappy.Writer) *autoFlushSnappyWriter {
-	return &autoFlushSnappyWriter{
+type autoFlushWriter struct {
+	wtr flushWriter
+}
+
+func newAutoFlushWriter(wtr flushWriter) *autoFlushWriter {
+	return &autoFlushWriter{
 		wtr: wtr,
 	}
 }
 
-func (afsw *autoFlushSnappyWriter) Write(b []byte) (n int, err error) {
-	if afsw == nil || afsw.wtr == nil {
+func (afw *autoFlushWriter) Write(b []byte) (n int, err error) {
+	if afw == nil || afw.wtr == nil {
 		return -1, errors.New("bad writer")
 	}
-	if n, err = afsw.wtr.Write(b); err == nil {
-		err = afsw.wtr.Flush()
+	if n, err = afw.wtr.Write(b); err == nil {
+		err = afw.wtr.Flush()
 	}
 	return
 }
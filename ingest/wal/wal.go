@@ -0,0 +1,775 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package wal implements a segmented write-ahead log for entries.  Entries are
+// recorded before they are sent, acknowledged as indexers confirm them, and any
+// record that was never acknowledged is replayed when the log is reopened.
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofrs/flock"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	mb = 1024 * 1024
+
+	segPrefix = `seg-`
+	segExt    = `.wal`
+	ackExt    = `.ack`
+	lockName  = `lock`
+
+	// a record is [payload length uint32][crc32 of payload uint32][id uint64][flags uint8][encoded entry]
+	recHeaderSize = 8
+	recIDSize     = 9
+	ackSize       = 8
+
+	minSegmentSize     = 1 * mb
+	maxSegmentSize     = 64 * mb
+	defaultSegmentSize = 16 * mb
+	segmentsPerLog     = 8 // target number of segments when the log size is bounded
+
+	flagNoSRC uint8 = 1 // the entry had no SRC, it will be filled in when it is sent
+
+	maxRecordSize = recIDSize + entry.ENTRY_HEADER_SIZE + int(entry.MaxDataSize) + entry.MaxEvBlockSize
+)
+
+var (
+	ErrClosed         = errors.New("Write-ahead log is closed")
+	ErrLocked         = errors.New("Write-ahead log is in use")
+	ErrInvalidPath    = errors.New("Invalid write-ahead log path")
+	ErrCorruptRecord  = errors.New("Corrupt write-ahead log record")
+	ErrRecordTooLarge = errors.New("Entry is larger than the maximum write-ahead log size")
+)
+
+// Config controls where the log lives and how much disk it may use
+type Config struct {
+	Path        string
+	MaxSize     int64 // maximum bytes on disk, zero means unbounded
+	SegmentSize int64 // size at which segments are rotated, zero means derive it from MaxSize
+}
+
+// Record is an entry read back out of the log, the ID is handed to Ack once the entry is confirmed
+type Record struct {
+	ID  uint64
+	Ent *entry.Entry
+}
+
+type segment struct {
+	path    string
+	first   uint64 // ID of the first record
+	count   uint64 // records written
+	acked   uint64 // records acknowledged
+	bits    []uint64
+	size    int64 // bytes that have been flushed and are safe to read
+	written int64 // bytes written, including anything still buffered
+	ackf    *os.File
+	ackw    *bufio.Writer
+}
+
+// WAL is a segmented write-ahead log.  Append, Next, and Ack may be called concurrently.
+type WAL struct {
+	mtx     sync.Mutex
+	cfg     Config
+	lock    *flock.Flock
+	segs    []*segment // oldest first, the last segment is always the active one
+	wf      *os.File
+	ww      *bufio.Writer
+	next    uint64 // ID of the next record appended
+	size    int64  // bytes on disk across all segments
+	pending int    // records that have not been acknowledged
+	unread  int    // records that have not been handed out by Next
+	notify  chan struct{}
+	closed  bool
+	wbuff   []byte
+
+	//read cursor
+	rs    *segment
+	rf    *os.File
+	rr    *bufio.Reader
+	roff  int64
+	rid   uint64
+	rbuff []byte
+}
+
+// Open opens or creates a write-ahead log, any unacknowledged records from a previous
+// run will be handed out by Next before anything appended after Open.
+func Open(cfg Config) (w *WAL, err error) {
+	if cfg.Path == `` {
+		return nil, ErrInvalidPath
+	}
+	if cfg.MaxSize < 0 {
+		cfg.MaxSize = 0
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = defaultSegmentSize
+		if cfg.MaxSize > 0 {
+			cfg.SegmentSize = cfg.MaxSize / segmentsPerLog
+		}
+		if cfg.SegmentSize < minSegmentSize {
+			cfg.SegmentSize = minSegmentSize
+		} else if cfg.SegmentSize > maxSegmentSize {
+			cfg.SegmentSize = maxSegmentSize
+		}
+	}
+	if err = os.MkdirAll(cfg.Path, 0770); err != nil {
+		return
+	}
+	lock := flock.New(filepath.Join(cfg.Path, lockName))
+	var ok bool
+	if ok, err = lock.TryLock(); err != nil {
+		return
+	} else if !ok {
+		return nil, ErrLocked
+	}
+	w = &WAL{
+		cfg:    cfg,
+		lock:   lock,
+		next:   1,
+		notify: make(chan struct{}),
+	}
+	if err = w.load(); err == nil {
+		err = w.rotate()
+	}
+	if err != nil {
+		w.closeFiles()
+		lock.Unlock()
+		return nil, err
+	}
+	return
+}
+
+// load scans existing segments, truncating torn records and discarding anything fully acknowledged
+func (w *WAL) load() error {
+	dents, err := os.ReadDir(w.cfg.Path)
+	if err != nil {
+		return err
+	}
+	for _, d := range dents {
+		name := d.Name()
+		if d.IsDir() || !strings.HasPrefix(name, segPrefix) || !strings.HasSuffix(name, segExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segPrefix), segExt), 16, 64)
+		if err != nil {
+			continue //not ours
+		}
+		s := &segment{
+			path:  filepath.Join(w.cfg.Path, name),
+			first: first,
+		}
+		if err = s.scan(); err != nil {
+			return err
+		} else if err = s.loadAcks(); err != nil {
+			return err
+		}
+		if s.done() {
+			if err = s.remove(); err != nil {
+				return err
+			}
+			continue
+		}
+		w.segs = append(w.segs, s)
+	}
+	sort.Slice(w.segs, func(i, j int) bool { return w.segs[i].first < w.segs[j].first })
+	for _, s := range w.segs {
+		w.size += s.written
+		w.pending += int(s.count - s.acked)
+		if end := s.first + s.count; end > w.next {
+			w.next = end
+		}
+	}
+	w.unread = w.pending
+	if len(w.segs) > 0 {
+		w.rid = w.segs[0].first
+	} else {
+		w.rid = w.next
+	}
+	return nil
+}
+
+// Append records entries, if the log is bounded Append blocks until acknowledgements free up enough space.
+// If an error is returned some of the entries may have been recorded.
+func (w *WAL) Append(ctx context.Context, ents []*entry.Entry) (err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	var n int
+	for _, ent := range ents {
+		if ent == nil {
+			continue
+		}
+		rsz := int64(recHeaderSize+recIDSize) + int64(ent.Size())
+		if w.cfg.MaxSize > 0 && rsz > w.cfg.MaxSize {
+			err = ErrRecordTooLarge
+			break
+		}
+		for w.cfg.MaxSize > 0 && w.size+rsz > w.cfg.MaxSize && w.size > 0 {
+			if err = w.reclaim(); err != nil || w.size+rsz <= w.cfg.MaxSize {
+				break
+			}
+			// nothing to reclaim, make what we have so far visible to the reader and wait on acks
+			if err = w.commit(); err != nil {
+				break
+			}
+			w.wake()
+			ch := w.notify
+			w.mtx.Unlock()
+			select {
+			case <-ch:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			w.mtx.Lock()
+			if err == nil && w.closed {
+				err = ErrClosed
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+		// we may have given up the lock while waiting, so don't encode until we are ready to write
+		var rec []byte
+		if rec, err = w.encode(ent); err != nil {
+			break
+		}
+		rsz = int64(len(rec))
+		act := w.active()
+		if act.written > 0 && act.written+rsz > w.cfg.SegmentSize {
+			if err = w.rotate(); err != nil {
+				break
+			}
+			act = w.active()
+		}
+		binary.LittleEndian.PutUint64(rec[recHeaderSize:], w.next)
+		binary.LittleEndian.PutUint32(rec[4:], crc32.ChecksumIEEE(rec[recHeaderSize:]))
+		if _, err = w.ww.Write(rec); err != nil {
+			break
+		}
+		act.written += rsz
+		act.count++
+		w.size += rsz
+		w.next++
+		w.pending++
+		w.unread++
+		n++
+	}
+	if n > 0 {
+		if lerr := w.commit(); err == nil {
+			err = lerr
+		}
+		w.wake()
+	}
+	return
+}
+
+// encode builds a record in the write buffer, the ID and checksum are filled in by the caller
+func (w *WAL) encode(ent *entry.Entry) ([]byte, error) {
+	sz := recHeaderSize + recIDSize + int(ent.Size())
+	if cap(w.wbuff) < sz {
+		w.wbuff = make([]byte, sz)
+	}
+	rec := w.wbuff[:sz]
+	n, err := ent.Encode(rec[recHeaderSize+recIDSize:])
+	if err != nil {
+		return nil, err
+	}
+	rec = rec[:recHeaderSize+recIDSize+n]
+	binary.LittleEndian.PutUint32(rec, uint32(recIDSize+n))
+	rec[recHeaderSize+8] = 0
+	if len(ent.SRC) == 0 {
+		rec[recHeaderSize+8] = flagNoSRC
+	}
+	return rec, nil
+}
+
+// Next blocks until there is at least one record to hand out and returns up to max records
+func (w *WAL) Next(ctx context.Context, max int) (recs []Record, err error) {
+	if max <= 0 {
+		max = 1
+	}
+	for {
+		w.mtx.Lock()
+		if w.closed {
+			w.mtx.Unlock()
+			return nil, ErrClosed
+		}
+		recs, err = w.read(max)
+		ch := w.notify
+		w.mtx.Unlock()
+		if err != nil || len(recs) > 0 {
+			return
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (w *WAL) read(max int) (recs []Record, err error) {
+	for len(recs) < max {
+		var ok bool
+		if ok, err = w.readable(); err != nil || !ok {
+			break
+		}
+		var hdr [recHeaderSize]byte
+		if _, err = io.ReadFull(w.rr, hdr[:]); err != nil {
+			w.skipSegment()
+			break
+		}
+		sz := int(binary.LittleEndian.Uint32(hdr[:]))
+		sum := binary.LittleEndian.Uint32(hdr[4:])
+		if sz < recIDSize || int64(sz) > w.rs.size-w.roff-recHeaderSize {
+			err = ErrCorruptRecord
+			w.skipSegment()
+			break
+		}
+		if cap(w.rbuff) < sz {
+			w.rbuff = make([]byte, sz)
+		}
+		payload := w.rbuff[:sz]
+		if _, err = io.ReadFull(w.rr, payload); err != nil {
+			w.skipSegment()
+			break
+		} else if crc32.ChecksumIEEE(payload) != sum {
+			err = ErrCorruptRecord
+			w.skipSegment()
+			break
+		}
+		w.roff += int64(recHeaderSize + sz)
+		id := binary.LittleEndian.Uint64(payload)
+		if id < w.rid || w.rs.isAcked(id) {
+			//acknowledged before we were restarted
+			if id >= w.rid {
+				w.rid = id + 1
+			}
+			continue
+		}
+		ent := &entry.Entry{}
+		if _, err = ent.Decode(payload[recIDSize:]); err != nil {
+			err = fmt.Errorf("%w: %v", ErrCorruptRecord, err)
+			w.skipSegment()
+			break
+		}
+		if payload[8]&flagNoSRC != 0 {
+			ent.SRC = nil
+		}
+		w.rid = id + 1
+		w.unread--
+		recs = append(recs, Record{ID: id, Ent: ent})
+	}
+	if len(recs) > 0 {
+		err = nil // hand back what we have, the error will come up again on the next read
+	}
+	return
+}
+
+// readable makes sure the read cursor is sitting on a segment with records that are safe to read
+func (w *WAL) readable() (bool, error) {
+	for {
+		if w.rs != nil {
+			if w.roff < w.rs.size {
+				return true, nil
+			} else if w.rs == w.active() {
+				return false, nil // caught up with the writer
+			}
+			w.closeReader() // exhausted a sealed segment
+		}
+		var s *segment
+		for _, seg := range w.segs {
+			if seg.first+seg.count > w.rid || seg == w.active() {
+				s = seg
+				break
+			}
+		}
+		if s == nil {
+			return false, nil
+		}
+		f, err := os.Open(s.path)
+		if err != nil {
+			return false, err
+		}
+		if s.first > w.rid {
+			w.rid = s.first
+		}
+		w.rs, w.rf, w.rr, w.roff = s, f, bufio.NewReader(f), 0
+	}
+}
+
+// skipSegment abandons the rest of the segment the reader is on, used when a record cannot be read back
+func (w *WAL) skipSegment() {
+	if s := w.rs; s != nil {
+		w.roff = s.size
+		for ; w.rid < s.first+s.count; w.rid++ {
+			if !s.isAcked(w.rid) {
+				w.unread--
+			}
+		}
+	}
+}
+
+func (w *WAL) closeReader() {
+	if w.rf != nil {
+		w.rf.Close()
+	}
+	w.rs, w.rf, w.rr, w.roff = nil, nil, nil, 0
+}
+
+// Ack marks a record as delivered, segments are deleted once every record in them is acknowledged.
+// Acknowledging a record more than once is harmless.
+func (w *WAL) Ack(id uint64) (err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	s := w.findSegment(id)
+	if s == nil || !s.setAck(id) {
+		return
+	}
+	w.pending--
+	if s.done() && s != w.active() {
+		err = w.remove(s)
+		w.wake()
+		return
+	}
+	err = s.writeAck(id)
+	if s.done() {
+		w.wake() // a writer waiting on space may be able to reclaim the active segment
+	}
+	return
+}
+
+func (w *WAL) findSegment(id uint64) *segment {
+	i := sort.Search(len(w.segs), func(i int) bool {
+		return w.segs[i].first+w.segs[i].count > id
+	})
+	if i < len(w.segs) && w.segs[i].first <= id {
+		return w.segs[i]
+	}
+	return nil
+}
+
+// Flush pushes acknowledgements and records down to disk
+func (w *WAL) Flush() (err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if err = w.commit(); err == nil {
+		err = w.wf.Sync()
+	}
+	for _, s := range w.segs {
+		if lerr := s.syncAcks(); err == nil {
+			err = lerr
+		}
+	}
+	return
+}
+
+// Size returns the number of bytes the log is using on disk
+func (w *WAL) Size() int64 {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.size
+}
+
+// Pending returns the number of records that have not been acknowledged
+func (w *WAL) Pending() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.pending
+}
+
+// Backlog returns the number of records that have not been handed out by Next
+func (w *WAL) Backlog() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.unread
+}
+
+// Close flushes everything to disk and releases the log, unacknowledged records are kept for the next Open
+func (w *WAL) Close() (err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.wake()
+	if err = w.commit(); err == nil {
+		err = w.wf.Sync()
+	}
+	w.closeFiles()
+	// the active segment is just like the rest now, drop anything that is fully acknowledged
+	for _, s := range w.segs {
+		if s.done() || s.count == 0 {
+			if lerr := s.remove(); err == nil {
+				err = lerr
+			}
+		}
+	}
+	w.segs = nil
+	if lerr := w.lock.Unlock(); err == nil {
+		err = lerr
+	}
+	return
+}
+
+func (w *WAL) closeFiles() {
+	w.closeReader()
+	if w.wf != nil {
+		w.wf.Close()
+		w.wf, w.ww = nil, nil
+	}
+	for _, s := range w.segs {
+		s.closeAcks()
+	}
+}
+
+func (w *WAL) active() *segment {
+	if len(w.segs) == 0 {
+		return nil
+	}
+	return w.segs[len(w.segs)-1]
+}
+
+// commit flushes the write buffer so that everything written is visible to the reader
+func (w *WAL) commit() error {
+	if w.ww == nil {
+		return nil
+	}
+	if err := w.ww.Flush(); err != nil {
+		return err
+	}
+	if act := w.active(); act != nil {
+		act.size = act.written
+	}
+	return nil
+}
+
+// rotate seals the active segment and starts a new one, an empty active segment is left alone
+func (w *WAL) rotate() (err error) {
+	if act := w.active(); act != nil && w.wf != nil {
+		if act.count == 0 {
+			return nil
+		}
+		if err = w.commit(); err != nil {
+			return
+		} else if err = w.wf.Sync(); err != nil {
+			return
+		} else if err = w.wf.Close(); err != nil {
+			return
+		}
+		w.wf, w.ww = nil, nil
+	}
+	s := &segment{
+		path:  filepath.Join(w.cfg.Path, fmt.Sprintf("%s%016x%s", segPrefix, w.next, segExt)),
+		first: w.next,
+	}
+	if w.wf, err = os.OpenFile(s.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660); err != nil {
+		return
+	}
+	w.ww = bufio.NewWriter(w.wf)
+	w.segs = append(w.segs, s)
+	// the segment we just sealed may already be fully acknowledged
+	if len(w.segs) > 1 {
+		if prev := w.segs[len(w.segs)-2]; prev.done() {
+			err = w.remove(prev)
+		}
+	}
+	return
+}
+
+// reclaim frees the active segment if every record in it has been acknowledged
+func (w *WAL) reclaim() error {
+	if act := w.active(); act != nil && act.done() {
+		if err := w.commit(); err != nil {
+			return err
+		}
+		return w.rotate()
+	}
+	return nil
+}
+
+func (w *WAL) remove(s *segment) error {
+	for i := range w.segs {
+		if w.segs[i] == s {
+			w.segs = append(w.segs[:i], w.segs[i+1:]...)
+			break
+		}
+	}
+	if w.rs == s {
+		w.closeReader()
+	}
+	w.size -= s.written
+	return s.remove()
+}
+
+// wake releases anyone blocked in Append or Next, caller must hold the lock
+func (w *WAL) wake() {
+	close(w.notify)
+	w.notify = make(chan struct{})
+}
+
+func (s *segment) ackPath() string {
+	return strings.TrimSuffix(s.path, segExt) + ackExt
+}
+
+// scan walks the records in a segment, anything after the last good record is truncated
+func (s *segment) scan() (err error) {
+	var f *os.File
+	if f, err = os.OpenFile(s.path, os.O_RDWR, 0660); err != nil {
+		return
+	}
+	defer f.Close()
+	rdr := bufio.NewReader(f)
+	hdr := make([]byte, recHeaderSize)
+	var payload []byte
+	var off int64
+	for {
+		if _, err = io.ReadFull(rdr, hdr); err != nil {
+			break
+		}
+		sz := int(binary.LittleEndian.Uint32(hdr))
+		if sz < recIDSize || sz > maxRecordSize {
+			break
+		}
+		if cap(payload) < sz {
+			payload = make([]byte, sz)
+		}
+		payload = payload[:sz]
+		if _, err = io.ReadFull(rdr, payload); err != nil {
+			break
+		} else if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(hdr[4:]) {
+			break
+		} else if binary.LittleEndian.Uint64(payload) != s.first+s.count {
+			break
+		}
+		off += int64(recHeaderSize + sz)
+		s.count++
+	}
+	s.size, s.written = off, off
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return
+	} else if fi.Size() != off {
+		// torn write from a crash, drop the tail
+		err = f.Truncate(off)
+	}
+	return
+}
+
+func (s *segment) loadAcks() error {
+	buff, err := os.ReadFile(s.ackPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for len(buff) >= ackSize {
+		s.setAck(binary.LittleEndian.Uint64(buff))
+		buff = buff[ackSize:]
+	}
+	return nil
+}
+
+func (s *segment) setAck(id uint64) bool {
+	if id < s.first || id >= s.first+s.count {
+		return false
+	}
+	off := id - s.first
+	idx, bit := int(off/64), uint64(1)<<(off%64)
+	for len(s.bits) <= idx {
+		s.bits = append(s.bits, 0)
+	}
+	if s.bits[idx]&bit != 0 {
+		return false
+	}
+	s.bits[idx] |= bit
+	s.acked++
+	return true
+}
+
+func (s *segment) isAcked(id uint64) bool {
+	if id < s.first {
+		return false
+	}
+	off := id - s.first
+	idx := int(off / 64)
+	return idx < len(s.bits) && s.bits[idx]&(uint64(1)<<(off%64)) != 0
+}
+
+func (s *segment) done() bool {
+	return s.count > 0 && s.acked == s.count
+}
+
+func (s *segment) writeAck(id uint64) (err error) {
+	if s.ackw == nil {
+		if s.ackf, err = os.OpenFile(s.ackPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0660); err != nil {
+			return
+		}
+		s.ackw = bufio.NewWriter(s.ackf)
+	}
+	var buff [ackSize]byte
+	binary.LittleEndian.PutUint64(buff[:], id)
+	_, err = s.ackw.Write(buff[:])
+	return
+}
+
+func (s *segment) syncAcks() (err error) {
+	if s.ackw != nil {
+		if err = s.ackw.Flush(); err == nil {
+			err = s.ackf.Sync()
+		}
+	}
+	return
+}
+
+func (s *segment) closeAcks() {
+	if s.ackw != nil {
+		s.ackw.Flush()
+		s.ackf.Sync()
+		s.ackf.Close()
+		s.ackf, s.ackw = nil, nil
+	}
+}
+
+func (s *segment) remove() (err error) {
+	s.closeAcks()
+	if err = os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = os.Remove(s.ackPath()); err != nil && os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
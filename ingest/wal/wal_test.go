@@ -0,0 +1,282 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package wal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func makeEnts(start, cnt int) (ents []*entry.Entry) {
+	for i := start; i < start+cnt; i++ {
+		ents = append(ents, &entry.Entry{
+			TS:   entry.FromStandard(time.Unix(int64(i), 0)),
+			Tag:  entry.EntryTag(i % 4),
+			SRC:  net.ParseIP("10.0.0.1").To4(),
+			Data: []byte(fmt.Sprintf("entry %d", i)),
+		})
+	}
+	return
+}
+
+func readN(t *testing.T, w *WAL, n int) (recs []Record) {
+	t.Helper()
+	ctx, cf := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cf()
+	for len(recs) < n {
+		r, err := w.Next(ctx, n-len(recs))
+		if err != nil {
+			t.Fatalf("failed to read %d records, got %d: %v", n, len(recs), err)
+		}
+		recs = append(recs, r...)
+	}
+	return
+}
+
+func segmentCount(t *testing.T, dir string) int {
+	t.Helper()
+	m, err := filepath.Glob(filepath.Join(dir, segPrefix+`*`+segExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(m)
+}
+
+func TestAppendNextAck(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := makeEnts(0, 100)
+	ents[7].SRC = nil
+	if err = w.Append(context.Background(), ents); err != nil {
+		t.Fatal(err)
+	}
+	if w.Pending() != 100 || w.Backlog() != 100 {
+		t.Fatalf("bad counts %d %d", w.Pending(), w.Backlog())
+	}
+	recs := readN(t, w, 100)
+	for i, r := range recs {
+		if err := r.Ent.Compare(ents[i]); err != nil {
+			t.Fatalf("record %d mismatch: %v", i, err)
+		}
+		if i > 0 && r.ID != recs[i-1].ID+1 {
+			t.Fatalf("non sequential IDs %d %d", recs[i-1].ID, r.ID)
+		}
+	}
+	if recs[7].Ent.SRC != nil {
+		t.Fatalf("empty SRC was not preserved: %v", recs[7].Ent.SRC)
+	}
+	if w.Backlog() != 0 {
+		t.Fatalf("bad backlog %d", w.Backlog())
+	}
+	for _, r := range recs {
+		if err = w.Ack(r.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//duplicate acks are ignored
+	if err = w.Ack(recs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if w.Pending() != 0 {
+		t.Fatalf("bad pending %d", w.Pending())
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := segmentCount(t, dir); n != 0 {
+		t.Fatalf("fully acked log left %d segments behind", n)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := makeEnts(0, 50)
+	if err = w.Append(context.Background(), ents); err != nil {
+		t.Fatal(err)
+	}
+	//ack every even record
+	for _, r := range readN(t, w, 50) {
+		if r.ID%2 == 0 {
+			w.Ack(r.ID)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Next(context.Background(), 1); err != ErrClosed {
+		t.Fatalf("bad error on closed log: %v", err)
+	}
+
+	if w, err = Open(Config{Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Pending() != 25 {
+		t.Fatalf("bad pending after replay %d", w.Pending())
+	}
+	//new records land behind the replayed ones
+	if err = w.Append(context.Background(), makeEnts(50, 1)); err != nil {
+		t.Fatal(err)
+	}
+	recs := readN(t, w, 26)
+	for i, r := range recs[:25] {
+		if r.ID%2 == 0 {
+			t.Fatalf("acked record %d was replayed", r.ID)
+		} else if err = r.Ent.Compare(ents[i*2]); err != nil {
+			t.Fatalf("replayed record %d mismatch: %v", i, err)
+		}
+	}
+	if string(recs[25].Ent.Data) != `entry 50` {
+		t.Fatalf("bad trailing record %q", recs[25].Ent.Data)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Append(context.Background(), makeEnts(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	p := w.active().path
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	//simulate a crash halfway through a record
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Truncate(p, fi.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+	if w, err = Open(Config{Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.Pending() != 9 {
+		t.Fatalf("bad pending after truncation %d", w.Pending())
+	}
+	recs := readN(t, w, 9)
+	if string(recs[8].Ent.Data) != `entry 8` {
+		t.Fatalf("bad final record %q", recs[8].Ent.Data)
+	}
+}
+
+func TestSegmentRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir, SegmentSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for i := 0; i < 100; i++ {
+		if err = w.Append(context.Background(), makeEnts(i, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := segmentCount(t, dir); n < 3 {
+		t.Fatalf("log did not rotate, %d segments", n)
+	}
+	recs := readN(t, w, 100)
+	for _, r := range recs[:90] {
+		w.Ack(r.ID)
+	}
+	//everything but the final segment or two should be gone
+	if n := segmentCount(t, dir); n > 2 {
+		t.Fatalf("acked segments were not removed, %d segments", n)
+	}
+	for _, r := range recs[90:] {
+		w.Ack(r.ID)
+	}
+	if n := segmentCount(t, dir); n != 1 {
+		t.Fatalf("expected only the active segment, found %d", n)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir, MaxSize: 4096, SegmentSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	big := &entry.Entry{Data: make([]byte, 8192)}
+	if err = w.Append(context.Background(), []*entry.Entry{big}); err != ErrRecordTooLarge {
+		t.Fatalf("oversized record was not rejected: %v", err)
+	}
+
+	ents := makeEnts(0, 500)
+	ctx, cf := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	err = w.Append(ctx, ents)
+	cf()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("append did not block on a full log: %v", err)
+	}
+	if sz := w.Size(); sz > 4096 {
+		t.Fatalf("log exceeded its bound: %d", sz)
+	}
+	written := w.Pending()
+
+	//ack records as they come out, the rest of the append should make it through
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Append(context.Background(), ents[written:])
+	}()
+	for cnt := 0; cnt < len(ents); {
+		for _, r := range readN(t, w, 1) {
+			if err = w.Ack(r.ID); err != nil {
+				t.Fatal(err)
+			}
+			cnt++
+		}
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if w.Pending() != 0 {
+		t.Fatalf("bad pending %d", w.Pending())
+	}
+}
+
+func TestLocked(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Open(Config{Path: dir}); err != ErrLocked {
+		t.Fatalf("log opened twice: %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w, err = Open(Config{Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
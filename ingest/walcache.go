@@ -0,0 +1,133 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/wal"
+)
+
+const (
+	walCacheDir   = `wal`
+	walReadBatch  = 512
+	walRetryDelay = time.Second
+)
+
+var (
+	ErrWALReplication = errors.New("Write-ahead log cache mode cannot be used in replication mode")
+	ErrDittoWAL       = errors.New("Ditto blocks cannot be written in write-ahead log cache mode")
+)
+
+// newWAL opens the write-ahead log when the muxer is in wal cache mode, a nil return means it is disabled
+func newWAL(c MuxerConfig) (*wal.WAL, error) {
+	if c.CachePath == `` || strings.ToLower(c.CacheMode) != CacheModeWAL {
+		return nil, nil
+	} else if c.Replicate {
+		return nil, ErrWALReplication
+	}
+	return wal.Open(wal.Config{
+		Path:    filepath.Join(c.CachePath, walCacheDir),
+		MaxSize: int64(c.CacheSize) * mb,
+	})
+}
+
+// walAppend records entries in the write-ahead log, the feeder routine picks them up from there.
+// Either ctx or to may be nil.
+func (im *IngestMuxer) walAppend(ctx context.Context, to <-chan time.Time, ents []*entry.Entry) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	//the log blocks when it is full, make sure shutting down or the timeout gets us out
+	wctx, cf := context.WithCancel(ctx)
+	defer cf()
+	stop := context.AfterFunc(im.ctx, cf)
+	defer stop()
+	if to != nil {
+		go func() {
+			select {
+			case <-to:
+				cf()
+			case <-wctx.Done():
+			}
+		}()
+	}
+	if err = im.wal.Append(wctx, ents); err != nil {
+		if err == wal.ErrClosed || im.ctx.Err() != nil {
+			err = ErrNotRunning
+		} else if err == context.Canceled && ctx.Err() == nil {
+			err = ErrWriteTimeout
+		}
+		return
+	}
+	im.ingesterState.Entries += uint64(len(ents))
+	for i := range ents {
+		im.ingesterState.Size += uint64(len(ents[i].Data))
+	}
+	im.tagStats.addBatch(im.tagStats.sizes(ents))
+	return
+}
+
+// walFeeder pulls entries out of the write-ahead log and hands them to the write relay routines
+func (im *IngestMuxer) walFeeder() {
+	defer im.wg.Done()
+	for {
+		recs, err := im.wal.Next(im.ctx, walReadBatch)
+		if err != nil {
+			if err == wal.ErrClosed || im.ctx.Err() != nil {
+				return
+			}
+			im.Error("failed to read from write-ahead log", log.KVErr(err))
+			if im.quitableSleep(walRetryDelay) {
+				return
+			}
+			continue
+		}
+		ents := make([]*entry.Entry, 0, len(recs))
+		im.walMtx.Lock()
+		for _, r := range recs {
+			im.walIDs[r.Ent] = r.ID
+			ents = append(ents, r.Ent)
+		}
+		im.walMtx.Unlock()
+		for _, gb := range im.splitBatch(ents) {
+			select {
+			case gb.ch <- gb.ents:
+			case <-im.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// walAcked is handed to the entry writers and releases an entry from the write-ahead log once it is confirmed
+func (im *IngestMuxer) walAcked(ent *entry.Entry) {
+	im.walMtx.Lock()
+	id, ok := im.walIDs[ent]
+	delete(im.walIDs, ent)
+	im.walMtx.Unlock()
+	if ok {
+		if err := im.wal.Ack(id); err != nil && err != wal.ErrClosed {
+			im.Error("failed to acknowledge write-ahead log entry", log.KVErr(err))
+		}
+	}
+}
+
+// walDiscard releases an entry that is being dropped so that it is not replayed forever
+func (im *IngestMuxer) walDiscard(ent *entry.Entry) {
+	if im.wal != nil {
+		im.walAcked(ent)
+	}
+}
@@ -0,0 +1,137 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+func waitForWALDrain(t *testing.T, im *IngestMuxer) {
+	for i := 0; i < 500; i++ {
+		if im.wal.Pending() == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("write-ahead log still has %d unacknowledged entries", im.wal.Pending())
+}
+
+func TestWALWrites(t *testing.T) {
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{ti.target()},
+		Tags:         []string{`foo`},
+		CachePath:    t.TempDir(),
+		CacheMode:    CacheModeWAL,
+		CacheSize:    16,
+	})
+	defer im.Close()
+	if im.cacheEnabled {
+		t.Fatal("channel cache enabled in wal mode")
+	}
+	if err := im.DittoWriteContext(context.Background(), []entry.Entry{{Data: []byte(`x`)}}); err != ErrDittoWAL {
+		t.Fatalf("bad error on ditto write in wal mode: %v", err)
+	}
+	foo, err := im.GetTag(`foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 64
+	var batch []*entry.Entry
+	for i := 0; i < count; i++ {
+		ent := &entry.Entry{TS: entry.Now(), Tag: foo, Data: []byte(fmt.Sprintf("entry %d", i))}
+		if i%2 == 0 {
+			err = im.WriteEntry(ent)
+		} else {
+			batch = append(batch, ent)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	//WaitForHot does not wait in wal mode
+	waitForHotCount(t, im, 1)
+	if err = im.Sync(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		if n := ti.count(fmt.Sprintf("entry %d", i)); n != 1 {
+			t.Fatalf("entry %d delivered %d times", i, n)
+		}
+	}
+	waitForWALDrain(t, im)
+}
+
+func TestWALReplay(t *testing.T) {
+	cachePath := t.TempDir()
+	im := startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{deadTarget(t)},
+		Tags:         []string{`foo`, `bar`},
+		CachePath:    cachePath,
+		CacheMode:    CacheModeWAL,
+	})
+	bar, err := im.GetTag(`bar`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 32
+	for i := 0; i < count; i++ {
+		if err = im.WriteEntry(&entry.Entry{TS: entry.Now(), Tag: bar, Data: []byte(fmt.Sprintf("entry %d", i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if im.cachedSize() == 0 {
+		t.Fatal("write-ahead log is empty")
+	}
+
+	//come back up with a different tag order and a live indexer, everything should be replayed
+	ti := newTestIndexer(t, 1)
+	defer ti.Close()
+	im = startTestMuxer(t, MuxerConfig{
+		Destinations: []Target{ti.target()},
+		Tags:         []string{`bar`},
+		CachePath:    cachePath,
+		CacheMode:    CacheModeWAL,
+	})
+	defer im.Close()
+	waitForHotCount(t, im, 1)
+	waitForWALDrain(t, im)
+	for i := 0; i < count; i++ {
+		data := fmt.Sprintf("entry %d", i)
+		if n := ti.count(data); n != 1 {
+			t.Fatalf("entry %d delivered %d times", i, n)
+		} else if tg := ti.tag(data); tg != `bar` {
+			t.Fatalf("entry %d replayed with tag %q", i, tg)
+		}
+	}
+}
+
+func TestWALReplication(t *testing.T) {
+	if _, err := NewMuxer(MuxerConfig{
+		Destinations: []Target{deadTarget(t), deadTarget(t)},
+		Tags:         []string{`foo`},
+		CachePath:    t.TempDir(),
+		CacheMode:    CacheModeWAL,
+		Replicate:    true,
+	}); err != ErrWALReplication {
+		t.Fatalf("bad error on replication with a write-ahead log: %v", err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 )
 
 const (
@@ -47,13 +48,16 @@ type gbl struct {
 }
 
 type cfgReadType struct {
-	Global                   gbl
-	Attach                   attach.AttachConfig
-	Listener                 map[string]*lst
-	HEC_Compatible_Listener  map[string]*hecCompatible
-	Amazon_Firehose_Listener map[string]*afh
-	Preprocessor             processors.ProcessorConfig
-	TimeFormat               config.CustomTimeFormat
+	Global                      gbl
+	Attach                      attach.AttachConfig
+	Listener                    map[string]*lst
+	HEC_Compatible_Listener     map[string]*hecCompatible
+	Amazon_Firehose_Listener    map[string]*afh
+	OTLP_Listener               map[string]*otlpListener
+	Elastic_Compatible_Listener map[string]*elasticCompatible
+	Loki_Compatible_Listener    map[string]*lokiCompatible
+	Preprocessor                processors.ProcessorConfig
+	TimeFormat                  config.CustomTimeFormat
 }
 
 type lst struct {
@@ -71,13 +75,17 @@ type lst struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	gbl
-	Attach       attach.AttachConfig
-	Listener     map[string]*lst
-	HECListener  map[string]*hecCompatible
-	AFHListener  map[string]*afh
-	Preprocessor processors.ProcessorConfig
-	TimeFormat   config.CustomTimeFormat
+	Attach          attach.AttachConfig
+	Listener        map[string]*lst
+	HECListener     map[string]*hecCompatible
+	AFHListener     map[string]*afh
+	OTLPListener    map[string]*otlpListener
+	ElasticListener map[string]*elasticCompatible
+	LokiListener    map[string]*lokiCompatible
+	Preprocessor    processors.ProcessorConfig
+	TimeFormat      config.CustomTimeFormat
 }
 
 func GetConfig(path, overlayPath string) (*cfgType, error) {
@@ -88,13 +96,16 @@ func GetConfig(path, overlayPath string) (*cfgType, error) {
 		return nil, err
 	}
 	c := &cfgType{
-		gbl:          cr.Global,
-		Attach:       cr.Attach,
-		Listener:     cr.Listener,
-		HECListener:  cr.HEC_Compatible_Listener,
-		AFHListener:  cr.Amazon_Firehose_Listener,
-		Preprocessor: cr.Preprocessor,
-		TimeFormat:   cr.TimeFormat,
+		gbl:             cr.Global,
+		Attach:          cr.Attach,
+		Listener:        cr.Listener,
+		HECListener:     cr.HEC_Compatible_Listener,
+		AFHListener:     cr.Amazon_Firehose_Listener,
+		OTLPListener:    cr.OTLP_Listener,
+		ElasticListener: cr.Elastic_Compatible_Listener,
+		LokiListener:    cr.Loki_Compatible_Listener,
+		Preprocessor:    cr.Preprocessor,
+		TimeFormat:      cr.TimeFormat,
 	}
 	if err := c.Verify(); err != nil {
 		return nil, err
@@ -121,7 +132,7 @@ func (c *cfgType) Verify() error {
 		c.Max_Concurrent_Requests = defaultMaxConcurrentRequests
 	}
 	urls := map[route]string{}
-	if len(c.Listener) == 0 && len(c.HECListener) == 0 && len(c.AFHListener) == 0 {
+	if len(c.Listener) == 0 && len(c.HECListener) == 0 && len(c.AFHListener) == 0 && len(c.OTLPListener) == 0 && len(c.ElasticListener) == 0 && len(c.LokiListener) == 0 {
 		return errors.New("No Listeners specified")
 	}
 	if err := c.Preprocessor.Validate(); err != nil {
@@ -191,6 +202,72 @@ func (c *cfgType) Verify() error {
 		c.AFHListener[k] = v
 	}
 
+	grpcBinds := map[string]string{}
+	for k, v := range c.OTLPListener {
+		pth, err := v.validate(k)
+		if err != nil {
+			return err
+		}
+		rt := newRoute(http.MethodPost, pth)
+		if orig, ok := urls[rt]; ok {
+			return fmt.Errorf("URL %s duplicated in %s (was in %s)", v.URL, k, orig)
+		}
+		if v.GRPC_Bind != `` {
+			if v.GRPC_Bind == c.Bind {
+				return fmt.Errorf("OTLP-Listener %s GRPC-Bind %s conflicts with the global Bind", k, v.GRPC_Bind)
+			} else if orig, ok := grpcBinds[v.GRPC_Bind]; ok {
+				return fmt.Errorf("GRPC-Bind %s duplicated in %s (was in %s)", v.GRPC_Bind, k, orig)
+			}
+			grpcBinds[v.GRPC_Bind] = k
+		}
+		if _, err := v.auth.Validate(); err != nil {
+			return fmt.Errorf("Auth for %s is invalid: %v", k, err)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("HTTP OTLP-Listener %s preprocessor invalid: %v", k, err)
+		}
+		urls[rt] = k
+		c.OTLPListener[k] = v
+	}
+
+	for k, v := range c.ElasticListener {
+		pth, err := v.validate(k)
+		if err != nil {
+			return err
+		}
+		rt := newRoute(http.MethodPost, pth)
+		if orig, ok := urls[rt]; ok {
+			return fmt.Errorf("URL %s duplicated in %s (was in %s)", v.URL, k, orig)
+		}
+		if _, err := v.auth.Validate(); err != nil {
+			return fmt.Errorf("Auth for %s is invalid: %v", k, err)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("HTTP Elastic-Compatible-Listener %s preprocessor invalid: %v", k, err)
+		}
+		urls[rt] = k
+		c.ElasticListener[k] = v
+	}
+
+	for k, v := range c.LokiListener {
+		pth, err := v.validate(k)
+		if err != nil {
+			return err
+		}
+		rt := newRoute(http.MethodPost, pth)
+		if orig, ok := urls[rt]; ok {
+			return fmt.Errorf("URL %s duplicated in %s (was in %s)", v.URL, k, orig)
+		}
+		if _, err := v.auth.Validate(); err != nil {
+			return fmt.Errorf("Auth for %s is invalid: %v", k, err)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("HTTP Loki-Compatible-Listener %s preprocessor invalid: %v", k, err)
+		}
+		urls[rt] = k
+		c.LokiListener[k] = v
+	}
+
 	if len(urls) == 0 {
 		return fmt.Errorf("No listeners specified")
 	}
@@ -231,6 +308,45 @@ func (c *cfgType) Tags() (tags []string, err error) {
 			tagMp[v.Tag_Name] = true
 		}
 	}
+	for k, v := range c.OTLPListener {
+		var ltags []string
+		if ltags, err = v.tags(); err != nil {
+			err = fmt.Errorf("failed to get tags on OTLP-Listener %s %w", k, err)
+			return
+		}
+		for _, lt := range ltags {
+			if _, ok := tagMp[lt]; !ok {
+				tags = append(tags, lt)
+				tagMp[lt] = true
+			}
+		}
+	}
+	for k, v := range c.ElasticListener {
+		var ltags []string
+		if ltags, err = v.tags(); err != nil {
+			err = fmt.Errorf("failed to get tags on Elastic-Compatible-Listener %s %w", k, err)
+			return
+		}
+		for _, lt := range ltags {
+			if _, ok := tagMp[lt]; !ok {
+				tags = append(tags, lt)
+				tagMp[lt] = true
+			}
+		}
+	}
+	for k, v := range c.LokiListener {
+		var ltags []string
+		if ltags, err = v.tags(); err != nil {
+			err = fmt.Errorf("failed to get tags on Loki-Compatible-Listener %s %w", k, err)
+			return
+		}
+		for _, lt := range ltags {
+			if _, ok := tagMp[lt]; !ok {
+				tags = append(tags, lt)
+				tagMp[lt] = true
+			}
+		}
+	}
 
 	if len(tags) == 0 {
 		err = errors.New("No tags specified")
@@ -0,0 +1,520 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	defaultElasticUrl            string = `/`
+	defaultElasticVersion        string = `8.11.0`
+	defaultElasticTimestampField string = `@timestamp`
+	elasticIndexEV               string = `index`
+
+	elasticProductHeader = `X-Elastic-Product`
+	elasticProduct       = `Elasticsearch`
+	elasticBulk          = `_bulk`
+	elasticLicense       = `_license`
+)
+
+var (
+	ErrInvalidElasticVersion = errors.New("Cluster-Version must be formatted as major.minor.patch")
+	ErrElasticBodyTooLarge   = errors.New("request body too large")
+	ErrElasticUnterminated   = errors.New("The bulk request must be terminated by a newline [\\n]")
+
+	elasticVersionRx = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+	// administrative endpoints that shippers use to install templates and policies, we just claim success
+	elasticAdminEndpoints = map[string]bool{
+		`_template`:           true,
+		`_index_template`:     true,
+		`_component_template`: true,
+		`_ilm`:                true,
+		`_ingest`:             true,
+		`_data_stream`:        true,
+	}
+)
+
+// elasticCompatible is a listener that speaks enough of the Elasticsearch API for Beats, Fluent Bit,
+// Logstash, and other shippers to deliver documents via the _bulk endpoint.
+type elasticCompatible struct {
+	auth                     //authentication information, login based authentication is not supported
+	URL               string //base URL, defaults to "/"
+	Tag_Name          string //tag for indexes that do not match a Tag-Match
+	Tag_Match         []string
+	Cluster_Version   string //Elasticsearch version reported to clients, defaults to 8.11.0
+	Timestamp_Field   string //document field that holds the timestamp, defaults to @timestamp
+	Ignore_Timestamps bool
+	Preprocessor      []string
+}
+
+func (ec *elasticCompatible) validate(name string) (string, error) {
+	if len(ec.URL) == 0 {
+		ec.URL = defaultElasticUrl
+	}
+	p, err := url.Parse(ec.URL)
+	if err != nil {
+		return ``, fmt.Errorf("URL structure is invalid: %v", err)
+	}
+	if p.Scheme != `` {
+		return ``, errors.New("May not specify scheme in listening URL")
+	} else if p.Host != `` {
+		return ``, errors.New("May not specify host in listening URL")
+	}
+	pth := path.Clean(p.Path)
+	if len(ec.Tag_Name) == 0 {
+		ec.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(ec.Tag_Name) != nil {
+		return ``, errors.New("Invalid characters in the \"" + ec.Tag_Name + "\"Tag-Name for " + name)
+	}
+	if _, err = ec.tagMatchers(); err != nil {
+		return ``, fmt.Errorf("Elastic-Compatible-Listener %s has invalid Tag-Match %w", name, err)
+	}
+	if ec.Cluster_Version == `` {
+		ec.Cluster_Version = defaultElasticVersion
+	} else if !elasticVersionRx.MatchString(ec.Cluster_Version) {
+		return ``, fmt.Errorf("Elastic-Compatible-Listener %s %w", name, ErrInvalidElasticVersion)
+	}
+	if ec.Timestamp_Field == `` {
+		ec.Timestamp_Field = defaultElasticTimestampField
+	}
+	switch ec.AuthType {
+	case jwtT, cookie:
+		return ``, fmt.Errorf("Elastic-Compatible-Listener %s does not support %s authentication", name, ec.AuthType)
+	}
+	//normalize the path
+	ec.URL = pth
+	return pth, nil
+}
+
+// tagMatchers returns the index to tag mappings, index names may contain glob patterns like filebeat-*
+func (ec *elasticCompatible) tagMatchers() (tags []tagMatcher, err error) {
+	var tm tagMatcher
+	for _, m := range ec.Tag_Match {
+		if tm.Value, tm.Tag, err = extractElementTag(m); err != nil {
+			return
+		} else if _, err = path.Match(tm.Value, ``); err != nil {
+			err = fmt.Errorf("Tag-Match %q has an invalid index pattern %w", m, err)
+			return
+		}
+		tags = append(tags, tm)
+	}
+	return
+}
+
+func (ec *elasticCompatible) tags() (tags []string, err error) {
+	var tms []tagMatcher
+	if tms, err = ec.tagMatchers(); err != nil {
+		return
+	}
+	if ec.Tag_Name != `` {
+		tags = append(tags, ec.Tag_Name)
+	}
+	for _, tm := range tms {
+		tags = append(tags, tm.Tag)
+	}
+	return
+}
+
+type elasticIndexTag struct {
+	pattern string
+	tag     entry.EntryTag
+}
+
+type elasticHandler struct {
+	name       string
+	base       string
+	info       []byte //the pre-rendered cluster info response
+	tag        entry.EntryTag
+	indexTags  []elasticIndexTag
+	tsField    string
+	timeWindow timegrinder.TimestampWindow
+}
+
+func newElasticHandler(name string, ec *elasticCompatible) (eh *elasticHandler, err error) {
+	eh = &elasticHandler{
+		name:    name,
+		base:    ec.URL,
+		tsField: ec.Timestamp_Field,
+	}
+	hsh := fnv.New128a()
+	hsh.Write([]byte(name))
+	uuid := hsh.Sum(nil)
+	info := map[string]interface{}{
+		`name`:         name,
+		`cluster_name`: `gravwell`,
+		`cluster_uuid`: fmt.Sprintf("%x", uuid),
+		`version`: map[string]interface{}{
+			`number`:                              ec.Cluster_Version,
+			`build_flavor`:                        `default`,
+			`build_type`:                          `tar`,
+			`build_hash`:                          `gravwell`,
+			`build_date`:                          `2023-11-04T10:04:57.184859352Z`,
+			`build_snapshot`:                      false,
+			`lucene_version`:                      `9.8.0`,
+			`minimum_wire_compatibility_version`:  `7.17.0`,
+			`minimum_index_compatibility_version`: `7.0.0`,
+		},
+		`tagline`: `You Know, for Search`,
+	}
+	eh.info, err = json.Marshal(info)
+	return
+}
+
+// indexTag picks the tag for an index, the first matching Tag-Match wins
+func (eh *elasticHandler) indexTag(index string) entry.EntryTag {
+	for _, it := range eh.indexTags {
+		if ok, _ := path.Match(it.pattern, index); ok {
+			return it.tag
+		}
+	}
+	return eh.tag
+}
+
+func (eh *elasticHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	w.Header().Set(elasticProductHeader, elasticProduct)
+	rel := strings.Trim(strings.TrimPrefix(path.Clean(r.URL.Path), eh.base), `/`)
+	var segs []string
+	if rel != `` {
+		segs = strings.Split(rel, `/`)
+	}
+	switch {
+	case len(segs) == 0:
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			sendElasticJSON(w, http.StatusOK, eh.info)
+			return
+		}
+	case len(segs) == 1 && segs[0] == elasticLicense:
+		if r.Method == http.MethodGet {
+			sendElasticJSON(w, http.StatusOK, []byte(`{"license":{"status":"active","uid":"gravwell","type":"basic","mode":"basic"}}`))
+			return
+		}
+	case segs[len(segs)-1] == elasticBulk && len(segs) <= 2:
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			var index string
+			if len(segs) == 2 {
+				index = segs[0]
+			}
+			eh.bulk(h, cfg, w, rdr, ip, index)
+			return
+		}
+	case elasticAdminEndpoints[segs[0]]:
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			sendElasticJSON(w, http.StatusOK, []byte(`{"acknowledged":true}`))
+		} else {
+			sendElasticJSON(w, http.StatusOK, []byte(`{}`))
+		}
+		return
+	}
+	sendElasticError(w, http.StatusNotFound, `resource_not_found_exception`, fmt.Sprintf("no handler found for uri [%s] and method [%s]", r.URL.Path, r.Method))
+}
+
+type elasticBulkMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type elasticItemError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type elasticItem struct {
+	Index   string            `json:"_index"`
+	ID      string            `json:"_id"`
+	Version int               `json:"_version,omitempty"`
+	Result  string            `json:"result,omitempty"`
+	Status  int               `json:"status"`
+	Error   *elasticItemError `json:"error,omitempty"`
+}
+
+type elasticBulkResponse struct {
+	Took   int64                    `json:"took"`
+	Errors bool                     `json:"errors"`
+	Items  []map[string]elasticItem `json:"items"`
+}
+
+// bulk handles _bulk NDJSON, only the index and create actions are supported.
+// Each action line is followed by a document line, except for delete actions.
+func (eh *elasticHandler) bulk(h *handler, cfg routeHandler, w http.ResponseWriter, rdr io.Reader, ip net.IP, defIndex string) {
+	start := time.Now()
+	lr := &io.LimitedReader{R: rdr, N: int64(maxBody) + 1}
+	br := &elasticBodyReader{rdr: lr}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 0, 64*1024), maxBody+1)
+
+	var resp elasticBulkResponse
+	var batch []*entry.Entry
+	var batchItems []int
+	var size uint64
+	now := entry.Now()
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]elasticBulkMeta
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			h.lgr.Info("bad request", log.KV("address", ip), log.KV("Elastic-Compatible-Listener", eh.name), log.KVErr(errors.New("malformed bulk action")))
+			sendElasticError(w, http.StatusBadRequest, `illegal_argument_exception`, `Malformed action/metadata line`)
+			return
+		}
+		var op string
+		var meta elasticBulkMeta
+		for k, v := range action {
+			op, meta = k, v
+		}
+		if meta.Index == `` {
+			meta.Index = defIndex
+		}
+		if meta.ID == `` {
+			meta.ID = strconv.FormatUint(rand.Uint64(), 36)
+		}
+		item := elasticItem{Index: meta.Index, ID: meta.ID}
+		switch op {
+		case `index`, `create`:
+			if !sc.Scan() {
+				eh.bodyError(h, w, sc, lr, ip)
+				return
+			}
+			doc := bytes.TrimSpace(sc.Bytes())
+			if !json.Valid(doc) {
+				item.Status = http.StatusBadRequest
+				item.Error = &elasticItemError{Type: `document_parsing_exception`, Reason: `failed to parse document`}
+				break
+			}
+			ent := &entry.Entry{
+				TS:   now,
+				SRC:  ip,
+				Tag:  eh.indexTag(meta.Index),
+				Data: append([]byte(nil), doc...),
+			}
+			if !cfg.ignoreTs {
+				if ts, ok := eh.timestamp(cfg.tg, doc); ok {
+					ent.TS = entry.FromStandard(ts)
+				}
+			}
+			if meta.Index != `` {
+				ent.AddEnumeratedValueEx(elasticIndexEV, meta.Index)
+			}
+			size += ent.Size()
+			batch = append(batch, ent)
+			batchItems = append(batchItems, len(resp.Items))
+			item.Version = 1
+			item.Result = `created`
+			item.Status = http.StatusCreated
+		case `update`:
+			//skip the partial document
+			if !sc.Scan() {
+				eh.bodyError(h, w, sc, lr, ip)
+				return
+			}
+			fallthrough
+		case `delete`:
+			item.Status = http.StatusBadRequest
+			item.Error = &elasticItemError{Type: `illegal_argument_exception`, Reason: op + ` actions are not supported`}
+		default:
+			sendElasticError(w, http.StatusBadRequest, `illegal_argument_exception`, fmt.Sprintf("Malformed action/metadata line, expected one of [create, delete, index, update] but found [%s]", op))
+			return
+		}
+		if item.Error != nil {
+			resp.Errors = true
+		}
+		resp.Items = append(resp.Items, map[string]elasticItem{op: item})
+	}
+	if sc.Err() != nil || lr.N == 0 {
+		eh.bodyError(h, w, sc, lr, ip)
+		return
+	} else if len(resp.Items) == 0 {
+		sendElasticError(w, http.StatusBadRequest, `action_request_validation_exception`, `Validation Failed: 1: no requests added;`)
+		return
+	} else if br.last != '\n' {
+		//the final document may have been cut off in transit
+		eh.bodyError(h, w, sc, lr, ip)
+		return
+	}
+
+	if len(batch) > 0 {
+		if err := cfg.pproc.ProcessBatchContext(batch, exitCtx); err != nil {
+			h.lgr.Error("failed to send entries", log.KV("Elastic-Compatible-Listener", eh.name), log.KVErr(err))
+			//tell the shipper to retry the documents we could not deliver
+			resp.Errors = true
+			for _, i := range batchItems {
+				for op, item := range resp.Items[i] {
+					item.Version, item.Result = 0, ``
+					item.Status = http.StatusTooManyRequests
+					item.Error = &elasticItemError{Type: `es_rejected_execution_exception`, Reason: err.Error()}
+					resp.Items[i][op] = item
+				}
+			}
+		} else {
+			h.entSI.Add(uint64(len(batch)))
+			h.bytesSI.Add(size)
+		}
+	}
+	resp.Took = time.Since(start).Milliseconds()
+	b, err := json.Marshal(resp)
+	if err != nil {
+		sendElasticError(w, http.StatusInternalServerError, `exception`, err.Error())
+		return
+	}
+	sendElasticJSON(w, http.StatusOK, b)
+}
+
+// bodyError responds to a bulk body that could not be completely read, bodies over the size
+// limit get a 413 and everything else, including a body that ends mid line, gets a 400
+func (eh *elasticHandler) bodyError(h *handler, w http.ResponseWriter, sc *bufio.Scanner, lr *io.LimitedReader, ip net.IP) {
+	err := sc.Err()
+	if lr.N == 0 || errors.Is(err, bufio.ErrTooLong) {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("max-body", maxBody), log.KVErr(ErrElasticBodyTooLarge))
+		sendElasticError(w, http.StatusRequestEntityTooLarge, `content_too_long_exception`, ErrElasticBodyTooLarge.Error())
+	} else if err != nil {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KVErr(err))
+		sendElasticError(w, http.StatusBadRequest, `parse_exception`, err.Error())
+	} else {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("Elastic-Compatible-Listener", eh.name), log.KVErr(ErrElasticUnterminated))
+		sendElasticError(w, http.StatusBadRequest, `illegal_argument_exception`, ErrElasticUnterminated.Error())
+	}
+}
+
+// elasticBodyReader remembers the last byte read so we can tell if a bulk body ended with a newline
+type elasticBodyReader struct {
+	rdr  io.Reader
+	last byte
+}
+
+func (ebr *elasticBodyReader) Read(b []byte) (n int, err error) {
+	if n, err = ebr.rdr.Read(b); n > 0 {
+		ebr.last = b[n-1]
+	}
+	return
+}
+
+// timestamp pulls the timestamp field out of a document, numeric values are epoch milliseconds
+func (eh *elasticHandler) timestamp(tg *timegrinder.TimeGrinder, doc []byte) (ts time.Time, ok bool) {
+	v, typ, _, err := jsonparser.Get(doc, eh.tsField)
+	if err != nil {
+		return
+	}
+	switch typ {
+	case jsonparser.Number:
+		var ms float64
+		if ms, err = jsonparser.ParseFloat(v); err != nil {
+			return
+		}
+		ts, ok = time.UnixMilli(int64(ms)), true
+	case jsonparser.String:
+		if tg == nil {
+			return
+		} else if ts, ok, err = tg.Extract(v); err != nil {
+			ok = false
+			return
+		}
+	default:
+		return
+	}
+	if ok {
+		ts = eh.timeWindow.Override(ts)
+	}
+	return
+}
+
+func sendElasticJSON(w http.ResponseWriter, code int, b []byte) {
+	w.Header().Set(`Content-Type`, `application/json; charset=UTF-8`)
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+func sendElasticError(w http.ResponseWriter, code int, typ, reason string) {
+	ee := elasticItemError{Type: typ, Reason: reason}
+	b, _ := json.Marshal(map[string]interface{}{
+		`error`: map[string]interface{}{
+			`root_cause`: []elasticItemError{ee},
+			`type`:       ee.Type,
+			`reason`:     ee.Reason,
+		},
+		`status`: code,
+	})
+	sendElasticJSON(w, code, b)
+}
+
+func includeElasticListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
+	for k, v := range cfg.ElasticListener {
+		var eh *elasticHandler
+		if eh, err = newElasticHandler(k, v); err != nil {
+			return fmt.Errorf("failed to create Elastic-Compatible-Listener %s %w", k, err)
+		}
+		if eh.tag, err = igst.NegotiateTag(v.Tag_Name); err != nil {
+			return fmt.Errorf("failed to pull tag %s %w", v.Tag_Name, err)
+		}
+		var tms []tagMatcher
+		if tms, err = v.tagMatchers(); err != nil {
+			return
+		}
+		for _, tm := range tms {
+			it := elasticIndexTag{pattern: tm.Value}
+			if it.tag, err = igst.NegotiateTag(tm.Tag); err != nil {
+				return fmt.Errorf("failed to pull tag %s %w", tm.Tag, err)
+			}
+			eh.indexTags = append(eh.indexTags, it)
+		}
+		if eh.timeWindow, err = cfg.GlobalTimestampWindow(); err != nil {
+			return fmt.Errorf("TimestampWindow is invalid %w", err)
+		}
+		hcfg := routeHandler{
+			handler: eh.handle,
+			tag:     eh.tag,
+		}
+		if v.Ignore_Timestamps {
+			hcfg.ignoreTs = true
+		} else {
+			if hcfg.tg, err = timegrinder.New(timegrinder.Config{}); err != nil {
+				return fmt.Errorf("Failed to create timegrinder %w", err)
+			} else if err = cfg.TimeFormat.LoadFormats(hcfg.tg); err != nil {
+				return fmt.Errorf("failed to load custom time formats %w", err)
+			}
+		}
+		if _, hcfg.auth, err = v.NewAuthHandler(lgr); err != nil {
+			return fmt.Errorf("failed to get a new authentication handler %w", err)
+		}
+		if hcfg.pproc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			return fmt.Errorf("preprocessor construction error %w", err)
+		}
+		for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut} {
+			if err = hnd.addPrefixHandler(method, v.URL, hcfg); err != nil {
+				return fmt.Errorf("failed to add Elastic-Compatible-Listener handler %w", err)
+			}
+		}
+		debugout("Elastic Handler URL %s handling %s\n", v.URL, v.Tag_Name)
+	}
+	return
+}
@@ -0,0 +1,225 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+type testElasticError struct {
+	Error  elasticItemError `json:"error"`
+	Status int              `json:"status"`
+}
+
+func newTestElasticHandler(t *testing.T) *elasticHandler {
+	ec := &elasticCompatible{URL: `/es`, Tag_Match: []string{`filebeat-*:beats`}}
+	if _, err := ec.validate(`test`); err != nil {
+		t.Fatal(err)
+	}
+	eh, err := newElasticHandler(`test`, ec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eh.tag = 1
+	eh.indexTags = []elasticIndexTag{{pattern: `filebeat-*`, tag: 5}}
+	return eh
+}
+
+func TestElasticBulk(t *testing.T) {
+	setMaxBody(t, 4096)
+	ip := net.ParseIP(`10.0.0.1`)
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	//requests that are rejected outright
+	type bulkErr struct {
+		status int
+		typ    string
+	}
+	//requests that are accepted, items holds the op and status of every response item
+	type bulkOK struct {
+		items  []string
+		errors bool
+		count  int
+	}
+	tests := []struct {
+		name  string
+		url   string
+		body  string
+		werr  error
+		err   *bulkErr
+		ok    *bulkOK
+		check func([]*entry.Entry, *elasticBulkResponse) error
+	}{
+		{
+			name: `index and create`,
+			url:  `/es/logs/_bulk`,
+			body: `{"index":{}}` + "\n" +
+				fmt.Sprintf(`{"@timestamp":%d,"msg":"a"}`, ts.UnixMilli()) + "\n\n" +
+				`{"create":{"_index":"filebeat-8","_id":"abc"}}` + "\n" +
+				`{"@timestamp":"2024-05-01T12:00:00Z","msg":"b"}` + "\n",
+			ok: &bulkOK{items: []string{`index:201`, `create:201`}, count: 2},
+			check: func(ents []*entry.Entry, resp *elasticBulkResponse) error {
+				if string(ents[0].Data) != fmt.Sprintf(`{"@timestamp":%d,"msg":"a"}`, ts.UnixMilli()) || ents[0].Tag != 1 {
+					return fmt.Errorf("bad first entry %s %d", ents[0].Data, ents[0].Tag)
+				} else if ents[1].Tag != 5 {
+					return fmt.Errorf("index was not routed %d", ents[1].Tag)
+				}
+				for i, idx := range []string{`logs`, `filebeat-8`} {
+					if !ents[i].TS.StandardTime().Equal(ts) {
+						return fmt.Errorf("bad timestamp %d %v", i, ents[i].TS)
+					} else if v, ok := ents[i].GetEnumeratedValue(elasticIndexEV); !ok || v != idx {
+						return fmt.Errorf("bad index %d %v", i, v)
+					}
+				}
+				if item := resp.Items[1][`create`]; item.Index != `filebeat-8` || item.ID != `abc` || item.Result != `created` {
+					return fmt.Errorf("bad create item %+v", item)
+				} else if resp.Items[0][`index`].ID == `` {
+					return errors.New("missing generated id")
+				}
+				return nil
+			},
+		},
+		{
+			name: `per item errors`,
+			url:  `/es/_bulk`,
+			body: `{"index":{"_index":"a"}}` + "\n" + `{"msg":` + "\n" +
+				`{"delete":{"_index":"a","_id":"1"}}` + "\n" +
+				`{"update":{"_index":"a","_id":"1"}}` + "\n" + `{"doc":{"index":{}}}` + "\n" +
+				`{"index":{"_index":"a"}}` + "\n" + `{"msg":"ok"}` + "\n",
+			ok: &bulkOK{items: []string{`index:400`, `delete:400`, `update:400`, `index:201`}, errors: true, count: 1},
+			check: func(ents []*entry.Entry, resp *elasticBulkResponse) error {
+				if string(ents[0].Data) != `{"msg":"ok"}` {
+					return fmt.Errorf("bad entry %s", ents[0].Data)
+				} else if e := resp.Items[0][`index`].Error; e == nil || e.Type != `document_parsing_exception` {
+					return fmt.Errorf("bad document error %+v", e)
+				} else if e = resp.Items[2][`update`].Error; e == nil || e.Type != `illegal_argument_exception` {
+					return fmt.Errorf("bad update error %+v", e)
+				}
+				return nil
+			},
+		},
+		{
+			name: `write failure`,
+			url:  `/es/_bulk`,
+			body: `{"index":{}}` + "\n" + `{"msg":"a"}` + "\n" + `{"delete":{}}` + "\n",
+			werr: errors.New("test"),
+			ok:   &bulkOK{items: []string{`index:429`, `delete:400`}, errors: true},
+		},
+		{name: `missing document`, url: `/es/_bulk`, body: `{"index":{}}` + "\n", err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `missing update document`, url: `/es/_bulk`, body: `{"index":{}}` + "\n" + `{}` + "\n" + `{"update":{}}` + "\n", err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `unterminated`, url: `/es/_bulk`, body: `{"index":{}}` + "\n" + `{"msg":"a"}`, err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `truncated`, url: `/es/_bulk`, body: `{"index":{}}` + "\n" + `{"msg":"a"}` + "\n" + `{"index":{}}` + "\n" + `{"ms`, err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `malformed action`, url: `/es/_bulk`, body: `{"index":` + "\n", err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `multiple actions`, url: `/es/_bulk`, body: `{"index":{},"create":{}}` + "\n" + `{}` + "\n", err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `unknown action`, url: `/es/_bulk`, body: `{"upsert":{}}` + "\n" + `{}` + "\n", err: &bulkErr{http.StatusBadRequest, `illegal_argument_exception`}},
+		{name: `empty`, url: `/es/_bulk`, body: "\n\n", err: &bulkErr{http.StatusBadRequest, `action_request_validation_exception`}},
+		{
+			name: `too large`,
+			url:  `/es/_bulk`,
+			body: `{"index":{}}` + "\n" + `{"msg":"` + strings.Repeat(`a`, 4096) + `"}` + "\n",
+			err:  &bulkErr{http.StatusRequestEntityTooLarge, `content_too_long_exception`},
+		},
+	}
+	for _, tst := range tests {
+		h, tw, pproc := newTestHandler()
+		tw.err = tst.werr
+		tg, err := timegrinder.New(timegrinder.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := routeHandler{tg: tg, pproc: pproc}
+		eh := newTestElasticHandler(t)
+		r := httptest.NewRequest(http.MethodPost, tst.url, strings.NewReader(tst.body))
+		w := httptest.NewRecorder()
+		eh.handle(h, cfg, w, r, r.Body, ip)
+		ents := tw.entries()
+		if w.Header().Get(elasticProductHeader) != elasticProduct {
+			t.Fatalf("%s: missing product header", tst.name)
+		}
+		if tst.err != nil {
+			var ee testElasticError
+			if w.Code != tst.err.status {
+				t.Fatalf("%s: bad status %d != %d: %s", tst.name, w.Code, tst.err.status, w.Body.String())
+			} else if err = json.Unmarshal(w.Body.Bytes(), &ee); err != nil {
+				t.Fatalf("%s: bad error response %v", tst.name, err)
+			} else if ee.Status != tst.err.status || ee.Error.Type != tst.err.typ {
+				t.Fatalf("%s: bad error %+v", tst.name, ee)
+			} else if len(ents) != 0 {
+				t.Fatalf("%s: sent %d entries from a rejected request", tst.name, len(ents))
+			}
+			continue
+		}
+		var resp elasticBulkResponse
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: bad status %d: %s", tst.name, w.Code, w.Body.String())
+		} else if err = json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: bad response %v", tst.name, err)
+		} else if resp.Errors != tst.ok.errors || len(resp.Items) != len(tst.ok.items) {
+			t.Fatalf("%s: bad response %+v", tst.name, resp)
+		} else if len(ents) != tst.ok.count {
+			t.Fatalf("%s: bad entry count %d != %d", tst.name, len(ents), tst.ok.count)
+		}
+		for i, exp := range tst.ok.items {
+			if len(resp.Items[i]) != 1 {
+				t.Fatalf("%s: bad item %d %+v", tst.name, i, resp.Items[i])
+			}
+			for op, item := range resp.Items[i] {
+				if got := fmt.Sprintf("%s:%d", op, item.Status); got != exp {
+					t.Fatalf("%s: bad item %d %s != %s", tst.name, i, got, exp)
+				} else if (item.Status >= 300) != (item.Error != nil) {
+					t.Fatalf("%s: item %d status and error disagree %+v", tst.name, i, item)
+				}
+			}
+		}
+		if tst.check != nil {
+			if err = tst.check(ents, &resp); err != nil {
+				t.Fatalf("%s: %v", tst.name, err)
+			}
+		}
+	}
+}
+
+func TestElasticEndpoints(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		status int
+		body   string
+	}{
+		{http.MethodGet, `/es`, http.StatusOK, `"tagline":"You Know, for Search"`},
+		{http.MethodHead, `/es/`, http.StatusOK, `"cluster_name":"gravwell"`},
+		{http.MethodGet, `/es/_license`, http.StatusOK, `"status":"active"`},
+		{http.MethodPut, `/es/_index_template/filebeat`, http.StatusOK, `{"acknowledged":true}`},
+		{http.MethodGet, `/es/_ilm/policy/x`, http.StatusOK, `{}`},
+		{http.MethodGet, `/es/_bulk`, http.StatusNotFound, `resource_not_found_exception`},
+		{http.MethodPost, `/es/a/b/_bulk`, http.StatusNotFound, `resource_not_found_exception`},
+		{http.MethodGet, `/es/logs/_search`, http.StatusNotFound, `resource_not_found_exception`},
+	}
+	h, _, _ := newTestHandler()
+	eh := newTestElasticHandler(t)
+	for _, tst := range tests {
+		r := httptest.NewRequest(tst.method, tst.url, nil)
+		w := httptest.NewRecorder()
+		eh.handle(h, routeHandler{}, w, r, r.Body, nil)
+		if w.Code != tst.status || !strings.Contains(w.Body.String(), tst.body) {
+			t.Fatalf("%s %s: bad response %d %s", tst.method, tst.url, w.Code, w.Body.String())
+		}
+	}
+}
@@ -39,6 +39,12 @@ func (h *handler) loadConfig(cfg *cfgType) (err error) {
 		err = fmt.Errorf("failed to include HEC Listeners %w", err)
 	} else if err = includeAFHListeners(h, h.igst, cfg, h.lgr); err != nil {
 		err = fmt.Errorf("failed to include Amazon Firehose Listeners %w", err)
+	} else if err = includeOTLPListeners(h, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include OTLP Listeners %w", err)
+	} else if err = includeElasticListeners(h, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include Elastic Listeners %w", err)
+	} else if err = includeLokiListeners(h, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include Loki Listeners %w", err)
 	}
 	return
 }
@@ -61,11 +67,13 @@ func (h *handler) hotReload(cfg *cfgType) (err error) {
 
 	//make a fake handler so that we can re=use the maps and just do a hard swap
 	tempHandler := &handler{
-		igst:   h.igst, // may not be needed but no harm either
-		lgr:    h.lgr,  // may not be needed but no harm either
-		mp:     make(map[route]routeHandler),
-		auth:   make(map[route]authHandler),
-		custom: make(map[route]http.Handler),
+		igst:     h.igst, // may not be needed but no harm either
+		lgr:      h.lgr,  // may not be needed but no harm either
+		mp:       make(map[route]routeHandler),
+		auth:     make(map[route]authHandler),
+		custom:   make(map[route]http.Handler),
+		prefix:   make(map[route]routeHandler),
+		otlpGRPC: make(map[string]*otlpHandler),
 	}
 
 	if err = includeStdListeners(tempHandler, h.igst, cfg); err != nil {
@@ -77,6 +85,20 @@ func (h *handler) hotReload(cfg *cfgType) (err error) {
 	} else if err = includeAFHListeners(tempHandler, h.igst, cfg, h.lgr); err != nil {
 		err = fmt.Errorf("failed to include Amazon Firehose Listeners %w", err)
 		return
+	} else if err = includeOTLPListeners(tempHandler, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include OTLP Listeners %w", err)
+		return
+	} else if err = includeElasticListeners(tempHandler, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include Elastic Listeners %w", err)
+		return
+	} else if err = includeLokiListeners(tempHandler, h.igst, cfg, h.lgr); err != nil {
+		err = fmt.Errorf("failed to include Loki Listeners %w", err)
+		return
+	}
+	//bring up any new gRPC binds before the swap so that a bind failure rejects the whole reload
+	if err = h.startOTLPBinds(cfg); err != nil {
+		err = fmt.Errorf("failed to start OTLP gRPC listeners %w", err)
+		return
 	}
 
 	// we got a good reload, lock and swap
@@ -84,7 +106,10 @@ func (h *handler) hotReload(cfg *cfgType) (err error) {
 	h.mp = tempHandler.mp
 	h.auth = tempHandler.auth
 	h.custom = tempHandler.custom
+	h.prefix = tempHandler.prefix
+	h.otlpGRPC = tempHandler.otlpGRPC
 	h.Unlock()
+	h.stopOTLPBinds(tempHandler.otlpGRPC)
 
 	return
 }
@@ -28,6 +28,8 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/ingesters/utils"
 	"github.com/gravwell/gravwell/v3/timegrinder"
+
+	"google.golang.org/grpc"
 )
 
 const (
@@ -60,6 +62,10 @@ type handler struct {
 	mp                    map[route]routeHandler
 	auth                  map[route]authHandler
 	custom                map[route]http.Handler
+	prefix                map[route]routeHandler  // handlers that serve every path below their URL
+	otlpGRPC              map[string]*otlpHandler // OTLP gRPC listeners keyed by bind address
+	otlpSrvs              map[string]*grpc.Server // running OTLP gRPC servers keyed by bind address
+	otlpOpts              []grpc.ServerOption
 	healthCheckURL        string
 	maxConcurrentRequests int64
 	activeRequests        int64
@@ -87,6 +93,9 @@ func newHandler(igst *ingest.IngestMuxer, lgr *log.Logger, reqSI, entSI, bytesSI
 			mp:                    map[route]routeHandler{},
 			auth:                  map[route]authHandler{},
 			custom:                map[route]http.Handler{},
+			prefix:                map[route]routeHandler{},
+			otlpGRPC:              map[string]*otlpHandler{},
+			otlpSrvs:              map[string]*grpc.Server{},
 			igst:                  igst,
 			lgr:                   lgr,
 			reqSI:                 reqSI,
@@ -117,6 +126,10 @@ func (h *handler) checkConflict(r route) error {
 	if _, ok := h.custom[r]; ok {
 		return errors.New("route conflicts with custom handler")
 	}
+	//check prefix handlers
+	if _, ok := h.prefix[r]; ok {
+		return errors.New("route conflicts with prefix handler")
+	}
 	return nil
 }
 
@@ -154,6 +167,35 @@ func (h *handler) addCustomHandler(method, pth string, ah http.Handler) (err err
 	return
 }
 
+// addPrefixHandler registers a handler for a URL and every path below it, exact routes take precedence
+func (h *handler) addPrefixHandler(method, pth string, cfg routeHandler) (err error) {
+	r := newRoute(method, pth)
+	//check if there is a conflict
+	if err = h.checkConflict(r); err == nil {
+		h.Lock()
+		h.prefix[r] = cfg
+		h.Unlock()
+	}
+	return
+}
+
+// lookupRoute finds the handler for a route, exact routes take precedence over prefix handlers, the caller must hold the lock
+func (h *handler) lookupRoute(rt route) (rh routeHandler, ok bool) {
+	if rh, ok = h.mp[rt]; !ok {
+		rh, ok = h.lookupPrefix(rt)
+	}
+	return
+}
+
+// lookupPrefix finds the prefix handler with the longest URL that contains the route, the caller must hold the lock
+func (h *handler) lookupPrefix(rt route) (rh routeHandler, ok bool) {
+	for p := rt.uri; ; p = path.Dir(p) {
+		if rh, ok = h.prefix[route{method: rt.method, uri: p}]; ok || p == `/` || p == `.` {
+			return
+		}
+	}
+}
+
 type ew struct {
 }
 
@@ -270,8 +312,8 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//not an auth, try the actual post URL
-	rh, ok := h.mp[rt]
+	//not an auth, try the actual post URL and then any prefix handlers
+	rh, ok := h.lookupRoute(rt)
 	h.RUnlock()
 	debugout("LOOKUP UP ROUTE: %s %s\n", rt.method, rt.uri)
 	if !ok {
@@ -0,0 +1,130 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+// testWriter collects the entries that make it through a ProcessorSet
+type testWriter struct {
+	sync.Mutex
+	ents []*entry.Entry
+	err  error //returned from every write when set
+}
+
+func (tw *testWriter) WriteEntry(ent *entry.Entry) error {
+	return tw.WriteBatch([]*entry.Entry{ent})
+}
+
+func (tw *testWriter) WriteEntryContext(ctx context.Context, ent *entry.Entry) error {
+	return tw.WriteBatch([]*entry.Entry{ent})
+}
+
+func (tw *testWriter) WriteBatchContext(ctx context.Context, ents []*entry.Entry) error {
+	return tw.WriteBatch(ents)
+}
+
+func (tw *testWriter) WriteBatch(ents []*entry.Entry) error {
+	tw.Lock()
+	defer tw.Unlock()
+	if tw.err != nil {
+		return tw.err
+	}
+	tw.ents = append(tw.ents, ents...)
+	return nil
+}
+
+func (tw *testWriter) entries() []*entry.Entry {
+	tw.Lock()
+	defer tw.Unlock()
+	return append([]*entry.Entry(nil), tw.ents...)
+}
+
+// newTestHandler returns a handler that can service listener handle functions without a muxer
+func newTestHandler() (*handler, *testWriter, *processors.ProcessorSet) {
+	tw := &testWriter{}
+	h := &handler{
+		lgr:     log.NewDiscardLogger(),
+		reqSI:   &utils.StatsItem{},
+		entSI:   &utils.StatsItem{},
+		bytesSI: &utils.StatsItem{},
+		mp:      map[route]routeHandler{},
+		auth:    map[route]authHandler{},
+		custom:  map[route]http.Handler{},
+		prefix:  map[route]routeHandler{},
+	}
+	return h, tw, processors.NewProcessorSet(tw)
+}
+
+// setMaxBody overrides the global body limit for the duration of a test
+func setMaxBody(t *testing.T, v int) {
+	orig := maxBody
+	maxBody = v
+	t.Cleanup(func() { maxBody = orig })
+}
+
+func TestLookupRoute(t *testing.T) {
+	h, _, _ := newTestHandler()
+	for i, v := range []string{`/`, `/es`, `/es/v2/`} {
+		if err := h.addPrefixHandler(http.MethodPost, v, routeHandler{tag: entry.EntryTag(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.addHandler(http.MethodPost, `/es/_bulk`, routeHandler{tag: 10}); err != nil {
+		t.Fatal(err)
+	} else if err = h.addHandler(http.MethodPost, `/es`, routeHandler{}); err == nil {
+		t.Fatal("failed to catch exact route that conflicts with a prefix")
+	} else if err = h.addPrefixHandler(http.MethodPost, `/es/_bulk`, routeHandler{}); err == nil {
+		t.Fatal("failed to catch prefix that conflicts with an exact route")
+	}
+	//a prefix on another method does not conflict
+	if err := h.addPrefixHandler(http.MethodGet, `/es/_bulk`, routeHandler{tag: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		uri    string
+		tag    entry.EntryTag
+	}{
+		{http.MethodPost, `/`, 1},
+		{http.MethodPost, `/foo`, 1},
+		{http.MethodPost, `/esx`, 1}, //prefixes match whole path elements
+		{http.MethodPost, `/es`, 2},
+		{http.MethodPost, `/es/index/_doc`, 2},
+		{http.MethodPost, `/es/_bulk`, 10}, //exact route beats the prefix
+		{http.MethodPost, `/es/_bulk/x`, 2},
+		{http.MethodPost, `/es/v2`, 3},
+		{http.MethodPost, `/es/v2/index/_bulk`, 3},
+		{http.MethodGet, `/es/_bulk`, 20},
+		{http.MethodGet, `/es/_bulk/x/y`, 20},
+	}
+	for _, tst := range tests {
+		rh, ok := h.lookupRoute(newRoute(tst.method, tst.uri))
+		if !ok {
+			t.Fatalf("no route for %s %s", tst.method, tst.uri)
+		} else if rh.tag != tst.tag {
+			t.Fatalf("%s %s went to the wrong handler %d != %d", tst.method, tst.uri, rh.tag, tst.tag)
+		}
+	}
+	for _, v := range []route{newRoute(http.MethodGet, `/es`), newRoute(http.MethodPut, `/`)} {
+		if _, ok := h.lookupRoute(v); ok {
+			t.Fatalf("found a route for %+v", v)
+		}
+	}
+}
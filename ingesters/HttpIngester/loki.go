@@ -0,0 +1,440 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultLokiUrl string = `/loki/api/v1/push`
+
+	lokiProtobufType = `application/x-protobuf`
+	lokiJSONType     = `application/json`
+)
+
+var (
+	ErrLokiUnsupportedType = errors.New("unsupported Loki content type")
+	ErrLokiTooLarge        = errors.New("request body too large")
+	ErrLokiBadLabels       = errors.New("malformed stream labels")
+	ErrLokiBadValue        = errors.New("malformed stream value")
+)
+
+// lokiCompatible is a listener that accepts the Loki push API used by Promtail, Grafana Agent, and Alloy.
+// The chosen label selects the tag and the remaining stream labels are attached as enumerated values.
+type lokiCompatible struct {
+	auth                     //authentication information, login based authentication is not supported
+	URL               string //override the URL, defaults to /loki/api/v1/push
+	Tag_Name          string //default tag
+	Tag_Label         string //stream label whose value is matched against Tag-Match
+	Tag_Match         []string
+	Ignore_Timestamps bool
+	Preprocessor      []string
+}
+
+func (v *lokiCompatible) validate(name string) (string, error) {
+	if len(v.URL) == 0 {
+		v.URL = defaultLokiUrl
+	}
+	p, err := url.Parse(v.URL)
+	if err != nil {
+		return ``, fmt.Errorf("URL structure is invalid: %v", err)
+	}
+	if p.Scheme != `` {
+		return ``, errors.New("May not specify scheme in listening URL")
+	} else if p.Host != `` {
+		return ``, errors.New("May not specify host in listening URL")
+	}
+	pth := p.Path
+	if len(v.Tag_Name) == 0 {
+		v.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(v.Tag_Name) != nil {
+		return ``, errors.New("Invalid characters in the \"" + v.Tag_Name + "\"Tag-Name for " + name)
+	}
+	if _, err = v.tagMatchers(); err != nil {
+		return ``, fmt.Errorf("Loki-Compatible-Listener %s has invalid Tag-Match %w", name, err)
+	} else if len(v.Tag_Match) > 0 && v.Tag_Label == `` {
+		return ``, fmt.Errorf("Loki-Compatible-Listener %s specifies Tag-Match without a Tag-Label", name)
+	}
+	switch v.AuthType {
+	case jwtT, cookie:
+		return ``, fmt.Errorf("Loki-Compatible-Listener %s does not support %s authentication", name, v.AuthType)
+	}
+	//normalize the path
+	v.URL = pth
+	return pth, nil
+}
+
+func (v *lokiCompatible) tagMatchers() (tags []tagMatcher, err error) {
+	var tm tagMatcher
+	for _, m := range v.Tag_Match {
+		if tm.Value, tm.Tag, err = extractElementTag(m); err != nil {
+			return
+		}
+		tags = append(tags, tm)
+	}
+	return
+}
+
+func (v *lokiCompatible) tags() (tags []string, err error) {
+	var tms []tagMatcher
+	if tms, err = v.tagMatchers(); err != nil {
+		return
+	}
+	if v.Tag_Name != `` {
+		tags = append(tags, v.Tag_Name)
+	}
+	for _, tm := range tms {
+		tags = append(tags, tm.Tag)
+	}
+	return
+}
+
+type lokiLabel struct {
+	name  string
+	value string
+}
+
+type lokiEntry struct {
+	ts       time.Time
+	line     string
+	metadata []lokiLabel
+}
+
+type lokiStream struct {
+	labels  []lokiLabel
+	entries []lokiEntry
+}
+
+type lokiHandler struct {
+	name       string
+	tag        entry.EntryTag
+	tagLabel   string
+	tagRouter  map[string]entry.EntryTag
+	timeWindow timegrinder.TimestampWindow
+}
+
+func (lh *lokiHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	ct, _, err := mime.ParseMediaType(r.Header.Get(`Content-Type`))
+	if err != nil || (ct != lokiProtobufType && ct != lokiJSONType) {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("content-type", r.Header.Get(`Content-Type`)), log.KVErr(ErrLokiUnsupportedType))
+		http.Error(w, ErrLokiUnsupportedType.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	lr := io.LimitedReader{R: rdr, N: int64(maxBody) + 1}
+	b, err := io.ReadAll(&lr)
+	if err != nil {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KVErr(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if len(b) > maxBody {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("max-body", maxBody), log.KVErr(ErrLokiTooLarge))
+		http.Error(w, ErrLokiTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var streams []lokiStream
+	if ct == lokiProtobufType {
+		streams, err = decodeLokiProto(b)
+	} else {
+		streams, err = decodeLokiJSON(b)
+	}
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrLokiTooLarge) {
+			//the body decompressed to more than we will take
+			code = http.StatusRequestEntityTooLarge
+		}
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("Loki-Compatible-Listener", lh.name), log.KVErr(err))
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	var batch []*entry.Entry
+	var size uint64
+	now := entry.Now()
+	for _, s := range streams {
+		tag := lh.tag
+		for _, l := range s.labels {
+			if l.name == lh.tagLabel {
+				if t, ok := lh.tagRouter[l.value]; ok {
+					tag = t
+				}
+				break
+			}
+		}
+		for _, le := range s.entries {
+			ent := &entry.Entry{
+				TS:   now,
+				SRC:  ip,
+				Tag:  tag,
+				Data: []byte(le.line),
+			}
+			if !cfg.ignoreTs && !le.ts.IsZero() {
+				ent.TS = entry.FromStandard(lh.timeWindow.Override(le.ts))
+			}
+			for _, l := range s.labels {
+				if l.name != lh.tagLabel {
+					ent.AddEnumeratedValueEx(l.name, l.value)
+				}
+			}
+			for _, l := range le.metadata {
+				ent.AddEnumeratedValueEx(l.name, l.value)
+			}
+			size += ent.Size()
+			batch = append(batch, ent)
+		}
+	}
+	if len(batch) > 0 {
+		if err = cfg.pproc.ProcessBatchContext(batch, exitCtx); err != nil {
+			h.lgr.Error("failed to send entries", log.KV("Loki-Compatible-Listener", lh.name), log.KVErr(err))
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		h.entSI.Add(uint64(len(batch)))
+		h.bytesSI.Add(size)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeLokiProto decodes a snappy compressed logproto.PushRequest
+func decodeLokiProto(b []byte) (streams []lokiStream, err error) {
+	var n int
+	if n, err = snappy.DecodedLen(b); err != nil {
+		return
+	} else if n > maxBody {
+		err = ErrLokiTooLarge
+		return
+	}
+	if b, err = snappy.Decode(nil, b); err != nil {
+		return
+	}
+	err = walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var s lokiStream
+		if err := s.decode(v); err != nil {
+			return err
+		}
+		streams = append(streams, s)
+		return nil
+	})
+	return
+}
+
+func (s *lokiStream) decode(b []byte) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: //labels
+			s.labels, err = parseLokiLabels(string(v))
+		case 2: //entries
+			var le lokiEntry
+			if err = le.decode(v); err == nil {
+				s.entries = append(s.entries, le)
+			}
+		}
+		return
+	})
+}
+
+func (le *lokiEntry) decode(b []byte) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: //google.protobuf.Timestamp
+			var secs, nanos int64
+			err := walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if typ == protowire.VarintType {
+					switch num {
+					case 1:
+						secs = int64(x)
+					case 2:
+						nanos = int64(int32(x))
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			le.ts = time.Unix(secs, nanos)
+		case 2:
+			le.line = string(v)
+		case 3: //structured metadata
+			var l lokiLabel
+			err := walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if typ == protowire.BytesType {
+					switch num {
+					case 1:
+						l.name = string(v)
+					case 2:
+						l.value = string(v)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if l.name != `` {
+				le.metadata = append(le.metadata, l)
+			}
+		}
+		return nil
+	})
+}
+
+type lokiJSONPush struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// decodeLokiJSON decodes a push request where each value is [ "<unix nanoseconds>", "<line>", {metadata} ]
+func decodeLokiJSON(b []byte) (streams []lokiStream, err error) {
+	var req lokiJSONPush
+	if err = json.Unmarshal(b, &req); err != nil {
+		return
+	}
+	for _, js := range req.Streams {
+		var s lokiStream
+		for k, v := range js.Stream {
+			s.labels = append(s.labels, lokiLabel{name: k, value: v})
+		}
+		sort.Slice(s.labels, func(i, j int) bool { return s.labels[i].name < s.labels[j].name })
+		for _, val := range js.Values {
+			if len(val) < 2 || len(val) > 3 {
+				return nil, ErrLokiBadValue
+			}
+			var le lokiEntry
+			var ts string
+			if err = json.Unmarshal(val[0], &ts); err != nil {
+				return nil, ErrLokiBadValue
+			}
+			var ns int64
+			if ns, err = strconv.ParseInt(ts, 10, 64); err != nil {
+				return nil, ErrLokiBadValue
+			}
+			le.ts = time.Unix(0, ns)
+			if err = json.Unmarshal(val[1], &le.line); err != nil {
+				return nil, ErrLokiBadValue
+			}
+			if len(val) == 3 {
+				var md map[string]string
+				if err = json.Unmarshal(val[2], &md); err != nil {
+					return nil, ErrLokiBadValue
+				}
+				for k, v := range md {
+					le.metadata = append(le.metadata, lokiLabel{name: k, value: v})
+				}
+			}
+			s.entries = append(s.entries, le)
+		}
+		streams = append(streams, s)
+	}
+	return
+}
+
+// parseLokiLabels parses a Prometheus style label set such as {job="varlogs", host="a"}
+func parseLokiLabels(s string) (labels []lokiLabel, err error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, ErrLokiBadLabels
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	for len(s) > 0 {
+		idx := strings.IndexByte(s, '=')
+		if idx <= 0 {
+			return nil, ErrLokiBadLabels
+		}
+		l := lokiLabel{name: strings.TrimSpace(s[:idx])}
+		s = strings.TrimSpace(s[idx+1:])
+		var q string
+		if q, err = strconv.QuotedPrefix(s); err != nil {
+			return nil, ErrLokiBadLabels
+		} else if l.value, err = strconv.Unquote(q); err != nil {
+			return nil, ErrLokiBadLabels
+		}
+		labels = append(labels, l)
+		s = strings.TrimSpace(s[len(q):])
+		if len(s) > 0 {
+			if s[0] != ',' {
+				return nil, ErrLokiBadLabels
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+	}
+	return
+}
+
+func includeLokiListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
+	for k, v := range cfg.LokiListener {
+		lh := &lokiHandler{
+			name:     k,
+			tagLabel: v.Tag_Label,
+		}
+		if lh.tag, err = igst.NegotiateTag(v.Tag_Name); err != nil {
+			return fmt.Errorf("failed to pull tag %s %w", v.Tag_Name, err)
+		}
+		var tms []tagMatcher
+		if tms, err = v.tagMatchers(); err != nil {
+			return
+		}
+		lh.tagRouter = make(map[string]entry.EntryTag, len(tms))
+		for _, tm := range tms {
+			if lh.tagRouter[tm.Value], err = igst.NegotiateTag(tm.Tag); err != nil {
+				return fmt.Errorf("failed to pull tag %s %w", tm.Tag, err)
+			}
+		}
+		if lh.timeWindow, err = cfg.GlobalTimestampWindow(); err != nil {
+			return fmt.Errorf("TimestampWindow is invalid %w", err)
+		}
+		hcfg := routeHandler{
+			handler:  lh.handle,
+			tag:      lh.tag,
+			ignoreTs: v.Ignore_Timestamps,
+		}
+		if _, hcfg.auth, err = v.NewAuthHandler(lgr); err != nil {
+			return fmt.Errorf("failed to get a new authentication handler %w", err)
+		}
+		if hcfg.pproc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			return fmt.Errorf("preprocessor construction error %w", err)
+		}
+		if err = hnd.addHandler(http.MethodPost, v.URL, hcfg); err != nil {
+			return fmt.Errorf("failed to add handler for %q %w", v.URL, err)
+		}
+		debugout("Loki Handler URL %s handling %s\n", v.URL, v.Tag_Name)
+	}
+	return
+}
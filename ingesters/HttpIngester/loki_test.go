@@ -0,0 +1,266 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/klauspost/compress/snappy"
+)
+
+var testLokiTS = time.Unix(1700000000, 123456789)
+
+// pbLokiEntry builds a logproto.EntryAdapter, metadata is name value pairs
+func pbLokiEntry(ts time.Time, line string, metadata ...string) []byte {
+	b := pbJoin(
+		pbBytes(1, pbVarint(1, uint64(ts.Unix())), pbVarint(2, uint64(ts.Nanosecond()))),
+		pbString(2, line),
+	)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		b = append(b, pbBytes(3, pbString(1, metadata[i]), pbString(2, metadata[i+1]))...)
+	}
+	return b
+}
+
+// pbLokiPush builds a snappy compressed logproto.PushRequest with a single stream
+func pbLokiPush(labels string, entries ...[]byte) []byte {
+	s := pbString(1, labels)
+	for _, e := range entries {
+		s = append(s, pbBytes(2, e)...)
+	}
+	return snappy.Encode(nil, pbBytes(1, s))
+}
+
+func TestParseLokiLabels(t *testing.T) {
+	good := []struct {
+		val    string
+		labels []lokiLabel
+	}{
+		{`{}`, nil},
+		{` { } `, nil},
+		{`{job="varlogs"}`, []lokiLabel{{`job`, `varlogs`}}},
+		{`{job="varlogs", host = "a" ,env=""}`, []lokiLabel{{`job`, `varlogs`}, {`host`, `a`}, {`env`, ``}}},
+		{`{msg="a \"quoted\" value, with {braces}"}`, []lokiLabel{{`msg`, `a "quoted" value, with {braces}`}}},
+	}
+	for _, tst := range good {
+		labels, err := parseLokiLabels(tst.val)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tst.val, err)
+		} else if !reflect.DeepEqual(labels, tst.labels) {
+			t.Fatalf("bad labels from %q: %+v", tst.val, labels)
+		}
+	}
+	bad := []string{
+		``,
+		`job="a"`,
+		`{job="a"`,
+		`{job=a}`,
+		`{="a"}`,
+		`{job}`,
+		`{job="a" host="b"}`,
+		`{job="a",}x`,
+		`{job="unterminated}`,
+	}
+	for _, v := range bad {
+		if _, err := parseLokiLabels(v); !errors.Is(err, ErrLokiBadLabels) {
+			t.Fatalf("failed to catch bad labels %q: %v", v, err)
+		}
+	}
+}
+
+func TestDecodeLokiJSON(t *testing.T) {
+	streams, err := decodeLokiJSON([]byte(fmt.Sprintf(`{"streams":[
+	{"stream":{"job":"varlogs","host":"a"},"values":[["%d","line one"],["%d","line two",{"trace_id":"abc"}]]},
+	{"stream":{},"values":[]}]}`, testLokiTS.UnixNano(), testLokiTS.UnixNano()+1)))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(streams) != 2 || len(streams[0].entries) != 2 || len(streams[1].entries) != 0 {
+		t.Fatalf("bad streams %+v", streams)
+	}
+	s := streams[0]
+	if !reflect.DeepEqual(s.labels, []lokiLabel{{`host`, `a`}, {`job`, `varlogs`}}) {
+		t.Fatalf("labels are not sorted %+v", s.labels)
+	} else if !s.entries[0].ts.Equal(testLokiTS) || s.entries[0].line != `line one` || len(s.entries[0].metadata) != 0 {
+		t.Fatalf("bad first entry %+v", s.entries[0])
+	} else if s.entries[1].line != `line two` || !reflect.DeepEqual(s.entries[1].metadata, []lokiLabel{{`trace_id`, `abc`}}) {
+		t.Fatalf("bad second entry %+v", s.entries[1])
+	}
+
+	bad := []string{
+		`{"streams":[{"stream":{},"values":[["1"]]}]}`,
+		`{"streams":[{"stream":{},"values":[["1","a",{},"extra"]]}]}`,
+		`{"streams":[{"stream":{},"values":[[1,"a"]]}]}`,
+		`{"streams":[{"stream":{},"values":[["soon","a"]]}]}`,
+		`{"streams":[{"stream":{},"values":[["1",2]]}]}`,
+		`{"streams":[{"stream":{},"values":[["1","a",["not","an","object"]]]}]}`,
+	}
+	for _, v := range bad {
+		if _, err := decodeLokiJSON([]byte(v)); !errors.Is(err, ErrLokiBadValue) {
+			t.Fatalf("failed to catch bad value %s: %v", v, err)
+		}
+	}
+	for _, v := range []string{`{"streams":[`, `{"streams":{}}`, `[]`} {
+		if _, err := decodeLokiJSON([]byte(v)); err == nil {
+			t.Fatalf("failed to catch malformed request %s", v)
+		}
+	}
+}
+
+func TestDecodeLokiProto(t *testing.T) {
+	setMaxBody(t, 4096)
+	good := pbLokiPush(`{job="varlogs", host="a"}`,
+		pbLokiEntry(testLokiTS, `line one`),
+		pbLokiEntry(testLokiTS.Add(time.Second), `line two`, `trace_id`, `abc`, ``, `dropped`),
+	)
+	streams, err := decodeLokiProto(good)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(streams) != 1 || len(streams[0].entries) != 2 {
+		t.Fatalf("bad streams %+v", streams)
+	}
+	s := streams[0]
+	if !reflect.DeepEqual(s.labels, []lokiLabel{{`job`, `varlogs`}, {`host`, `a`}}) {
+		t.Fatalf("bad labels %+v", s.labels)
+	} else if !s.entries[0].ts.Equal(testLokiTS) || s.entries[0].line != `line one` {
+		t.Fatalf("bad first entry %+v", s.entries[0])
+	} else if !s.entries[1].ts.Equal(testLokiTS.Add(time.Second)) || !reflect.DeepEqual(s.entries[1].metadata, []lokiLabel{{`trace_id`, `abc`}}) {
+		t.Fatalf("bad second entry %+v", s.entries[1])
+	}
+	//entries without a timestamp are stamped on arrival
+	if streams, err = decodeLokiProto(pbLokiPush(`{}`, pbString(2, `no ts`))); err != nil {
+		t.Fatal(err)
+	} else if !streams[0].entries[0].ts.IsZero() {
+		t.Fatalf("timestamp from nothing %v", streams[0].entries[0].ts)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		is   error
+	}{
+		{name: `not snappy`, b: []byte(`{"streams":[]}`)},
+		{name: `empty snappy`, b: []byte{}},
+		{name: `truncated snappy`, b: good[:len(good)/2]},
+		{name: `corrupt snappy`, b: append([]byte{good[0]}, bytes.Repeat([]byte{0xff}, len(good)-1)...)},
+		{name: `decompression bomb`, b: snappy.Encode(nil, make([]byte, 4097)), is: ErrLokiTooLarge},
+		{name: `truncated protobuf`, b: snappy.Encode(nil, []byte{0x0a})},
+		{name: `oversize length`, b: snappy.Encode(nil, []byte{0x0a, 0x64, 0x01})},
+		{name: `bad labels`, b: pbLokiPush(`job="a"`), is: ErrLokiBadLabels},
+		{name: `truncated timestamp`, b: pbLokiPush(`{}`, pbBytes(1, []byte{0x08, 0xff}))},
+		{name: `truncated metadata`, b: pbLokiPush(`{}`, pbBytes(3, []byte{0x0a, 0x05, 'a'}))},
+	}
+	for _, tst := range tests {
+		if _, err := decodeLokiProto(tst.b); err == nil {
+			t.Fatalf("%s: failed to catch bad request", tst.name)
+		} else if tst.is != nil && !errors.Is(err, tst.is) {
+			t.Fatalf("%s: bad error %v != %v", tst.name, err, tst.is)
+		}
+	}
+}
+
+func TestLokiHandler(t *testing.T) {
+	setMaxBody(t, 4096)
+	ip := net.ParseIP(`10.0.0.1`)
+	routed := entry.EntryTag(7)
+	jsonBody := fmt.Sprintf(`{"streams":[
+	{"stream":{"job":"varlogs","app":"web"},"values":[["%d","hello",{"trace_id":"abc"}]]},
+	{"stream":{"job":"other"},"values":[["%d","world"]]}]}`, testLokiTS.UnixNano(), testLokiTS.UnixNano())
+	protoBody := pbLokiPush(`{job="varlogs", app="web"}`, pbLokiEntry(testLokiTS, `hello`, `trace_id`, `abc`))
+
+	checkFirst := func(ents []*entry.Entry) error {
+		if string(ents[0].Data) != `hello` || ents[0].Tag != routed || !ents[0].SRC.Equal(ip) {
+			return fmt.Errorf("bad entry %s %d %v", ents[0].Data, ents[0].Tag, ents[0].SRC)
+		} else if !ents[0].TS.StandardTime().Equal(testLokiTS) {
+			return fmt.Errorf("bad timestamp %v", ents[0].TS)
+		} else if _, ok := ents[0].GetEnumeratedValue(`job`); ok {
+			return errors.New("tag label was attached")
+		} else if v, ok := ents[0].GetEnumeratedValue(`app`); !ok || v != `web` {
+			return fmt.Errorf("bad label %v", v)
+		} else if v, ok = ents[0].GetEnumeratedValue(`trace_id`); !ok || v != `abc` {
+			return fmt.Errorf("bad metadata %v", v)
+		}
+		return nil
+	}
+	tests := []struct {
+		name     string
+		ct       string
+		body     []byte
+		werr     error
+		ignoreTs bool
+		status   int
+		count    int
+		check    func([]*entry.Entry) error
+	}{
+		{
+			name: `json`, ct: `application/json; charset=utf-8`, body: []byte(jsonBody), status: http.StatusNoContent, count: 2,
+			check: func(ents []*entry.Entry) error {
+				if err := checkFirst(ents); err != nil {
+					return err
+				} else if string(ents[1].Data) != `world` || ents[1].Tag != 1 {
+					return fmt.Errorf("bad second entry %s %d", ents[1].Data, ents[1].Tag)
+				}
+				return nil
+			},
+		},
+		{name: `protobuf`, ct: lokiProtobufType, body: protoBody, status: http.StatusNoContent, count: 1, check: checkFirst},
+		{
+			name: `ignore timestamps`, ct: lokiProtobufType, body: protoBody, ignoreTs: true, status: http.StatusNoContent, count: 1,
+			check: func(ents []*entry.Entry) error {
+				if ents[0].TS.StandardTime().Equal(testLokiTS) {
+					return errors.New("timestamp was not ignored")
+				}
+				return nil
+			},
+		},
+		{name: `empty`, ct: lokiJSONType, body: []byte(`{"streams":[]}`), status: http.StatusNoContent},
+		{name: `bad content type`, ct: `text/plain`, body: []byte(jsonBody), status: http.StatusUnsupportedMediaType},
+		{name: `malformed snappy`, ct: lokiProtobufType, body: []byte(jsonBody), status: http.StatusBadRequest},
+		{name: `malformed protobuf`, ct: lokiProtobufType, body: snappy.Encode(nil, []byte{0x0a, 0x64, 0x01}), status: http.StatusBadRequest},
+		{name: `malformed json`, ct: lokiJSONType, body: []byte(`{"streams":[`), status: http.StatusBadRequest},
+		{name: `bad labels`, ct: lokiProtobufType, body: pbLokiPush(`job="a"`, pbLokiEntry(testLokiTS, `x`)), status: http.StatusBadRequest},
+		{name: `too large`, ct: lokiJSONType, body: []byte(jsonBody + strings.Repeat(` `, 4096)), status: http.StatusRequestEntityTooLarge},
+		{name: `decompressed too large`, ct: lokiProtobufType, body: snappy.Encode(nil, make([]byte, 8192)), status: http.StatusRequestEntityTooLarge},
+		{name: `write failure`, ct: lokiJSONType, body: []byte(jsonBody), werr: errors.New("test"), status: http.StatusServiceUnavailable},
+	}
+	for _, tst := range tests {
+		h, tw, pproc := newTestHandler()
+		tw.err = tst.werr
+		lh := &lokiHandler{
+			name:      `test`,
+			tag:       1,
+			tagLabel:  `job`,
+			tagRouter: map[string]entry.EntryTag{`varlogs`: routed},
+		}
+		r := httptest.NewRequest(http.MethodPost, defaultLokiUrl, bytes.NewReader(tst.body))
+		r.Header.Set(`Content-Type`, tst.ct)
+		w := httptest.NewRecorder()
+		lh.handle(h, routeHandler{pproc: pproc, ignoreTs: tst.ignoreTs}, w, r, r.Body, ip)
+		ents := tw.entries()
+		if w.Code != tst.status {
+			t.Fatalf("%s: bad status %d != %d: %s", tst.name, w.Code, tst.status, w.Body.String())
+		} else if len(ents) != tst.count {
+			t.Fatalf("%s: bad entry count %d != %d", tst.name, len(ents), tst.count)
+		}
+		if tst.check != nil {
+			if err := tst.check(ents); err != nil {
+				t.Fatalf("%s: %v", tst.name, err)
+			}
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/gravwell/gravwell/v3/debug"
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/ingesters/utils"
 	"github.com/gravwell/gravwell/v3/ingesters/utils/caps"
@@ -110,6 +111,10 @@ func main() {
 	if err = hnd.loadConfig(cfg); err != nil {
 		lg.Fatal("failed to load configuration", log.KVErr(err))
 	}
+	if err = startOTLPGRPC(hnd, cfg); err != nil {
+		lg.Fatal("failed to start OTLP gRPC listeners", log.KVErr(err))
+	}
+
 	var httpLogger *dlog.Logger
 	if debugOn || cfg.LogLevel() == `INFO` {
 		httpLogger = lg.StandardLogger()
@@ -181,14 +186,20 @@ watchExitLoop:
 		break watchExitLoop
 	}
 	debugout("Server is exiting\n")
+	hnd.stopOTLPBinds(nil)
 	ib.AnnounceShutdown()
 
 	exitFn()
 
-	for k, v := range hnd.mp {
-		if v.pproc != nil {
-			if err := v.pproc.Close(); err != nil {
-				lg.Error("failed to close preprocessors for handler", log.KV("preprocessor", k), log.KVErr(err))
+	//a preprocessor set may be shared by several routes, only close it once
+	closed := map[*processors.ProcessorSet]bool{}
+	for _, mp := range []map[route]routeHandler{hnd.mp, hnd.prefix} {
+		for k, v := range mp {
+			if v.pproc != nil && !closed[v.pproc] {
+				closed[v.pproc] = true
+				if err := v.pproc.Close(); err != nil {
+					lg.Error("failed to close preprocessors for handler", log.KV("preprocessor", k), log.KVErr(err))
+				}
 			}
 		}
 	}
@@ -0,0 +1,510 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // OTLP exporters commonly gzip compress gRPC requests
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultOTLPUrl string = `/v1/logs`
+
+	otlpProtobufType = `application/x-protobuf`
+	otlpJSONType     = `application/json`
+
+	otlpLogsService = `opentelemetry.proto.collector.logs.v1.LogsService`
+
+	// google.rpc.Code values used in OTLP/HTTP status responses
+	otlpCodeInvalidArgument = 3
+	otlpCodeUnavailable     = 14
+
+	otlpSeverityEV  = `severity`
+	otlpSevNumEV    = `severity_number`
+	otlpTraceIDEV   = `trace_id`
+	otlpSpanIDEV    = `span_id`
+	otlpScopeEV     = `scope`
+	otlpEventNameEV = `event_name`
+)
+
+var (
+	ErrOTLPUnsupportedType = errors.New("unsupported OTLP content type")
+	ErrOTLPTooLarge        = errors.New("request body too large")
+)
+
+// otlpListener receives OpenTelemetry logs over OTLP/HTTP and optionally OTLP/gRPC.
+// The log body becomes the entry data and resource and log attributes are attached as enumerated values.
+type otlpListener struct {
+	auth                             //authentication information, login based authentication is not supported
+	URL                       string //override the URL, defaults to /v1/logs
+	GRPC_Bind                 string //optional bind address for an OTLP/gRPC receiver, e.g. 0.0.0.0:4317
+	Tag_Name                  string //default tag
+	Tag_Attribute             string //attribute whose value is matched against Tag-Match
+	Tag_Match                 []string
+	Ignore_Timestamps         bool
+	Resource_Attribute_Prefix string //optional prefix applied to enumerated values from resource attributes
+	Preprocessor              []string
+}
+
+func (v *otlpListener) validate(name string) (string, error) {
+	if len(v.URL) == 0 {
+		v.URL = defaultOTLPUrl
+	}
+	p, err := url.Parse(v.URL)
+	if err != nil {
+		return ``, fmt.Errorf("URL structure is invalid: %v", err)
+	}
+	if p.Scheme != `` {
+		return ``, errors.New("May not specify scheme in listening URL")
+	} else if p.Host != `` {
+		return ``, errors.New("May not specify host in listening URL")
+	}
+	pth := p.Path
+	if len(v.Tag_Name) == 0 {
+		v.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(v.Tag_Name) != nil {
+		return ``, errors.New("Invalid characters in the \"" + v.Tag_Name + "\"Tag-Name for " + name)
+	}
+	if _, err = v.tagMatchers(); err != nil {
+		return ``, fmt.Errorf("OTLP-Listener %s has invalid Tag-Match %w", name, err)
+	} else if len(v.Tag_Match) > 0 && v.Tag_Attribute == `` {
+		return ``, fmt.Errorf("OTLP-Listener %s specifies Tag-Match without a Tag-Attribute", name)
+	}
+	if v.GRPC_Bind != `` {
+		if _, _, err = net.SplitHostPort(v.GRPC_Bind); err != nil {
+			return ``, fmt.Errorf("OTLP-Listener %s has an invalid GRPC-Bind %w", name, err)
+		}
+	}
+	switch v.AuthType {
+	case jwtT, cookie:
+		return ``, fmt.Errorf("OTLP-Listener %s does not support %s authentication", name, v.AuthType)
+	}
+	//normalize the path
+	v.URL = pth
+	return pth, nil
+}
+
+func (v *otlpListener) tagMatchers() (tags []tagMatcher, err error) {
+	var tm tagMatcher
+	for _, m := range v.Tag_Match {
+		if tm.Value, tm.Tag, err = extractElementTag(m); err != nil {
+			return
+		}
+		tags = append(tags, tm)
+	}
+	return
+}
+
+func (v *otlpListener) tags() (tags []string, err error) {
+	var tms []tagMatcher
+	if tms, err = v.tagMatchers(); err != nil {
+		return
+	}
+	if v.Tag_Name != `` {
+		tags = append(tags, v.Tag_Name)
+	}
+	for _, tm := range tms {
+		tags = append(tags, tm.Tag)
+	}
+	return
+}
+
+type otlpHandler struct {
+	name       string
+	tag        entry.EntryTag
+	tagAttr    string
+	tagRouter  map[string]entry.EntryTag
+	resPrefix  string
+	ignoreTs   bool
+	timeWindow timegrinder.TimestampWindow
+	auth       authHandler
+	pproc      *processors.ProcessorSet
+}
+
+func (oh *otlpHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	ct, _, err := mime.ParseMediaType(r.Header.Get(`Content-Type`))
+	if err != nil || (ct != otlpProtobufType && ct != otlpJSONType) {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("content-type", r.Header.Get(`Content-Type`)), log.KVErr(ErrOTLPUnsupportedType))
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	lr := io.LimitedReader{R: rdr, N: int64(maxBody) + 1}
+	b, err := io.ReadAll(&lr)
+	if err != nil {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KVErr(err))
+		sendOTLPStatus(w, ct, http.StatusBadRequest, otlpCodeInvalidArgument, err)
+		return
+	} else if len(b) > maxBody {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("max-body", maxBody), log.KVErr(ErrOTLPTooLarge))
+		sendOTLPStatus(w, ct, http.StatusRequestEntityTooLarge, otlpCodeInvalidArgument, ErrOTLPTooLarge)
+		return
+	}
+	var req otlpExportRequest
+	if ct == otlpProtobufType {
+		err = decodeOTLPProto(b, &req)
+	} else {
+		err = decodeOTLPJSON(b, &req)
+	}
+	if err != nil {
+		h.lgr.Info("bad request", log.KV("address", ip), log.KV("OTLP-Listener", oh.name), log.KVErr(err))
+		sendOTLPStatus(w, ct, http.StatusBadRequest, otlpCodeInvalidArgument, err)
+		return
+	}
+	if err = oh.export(h, &req, ip); err != nil {
+		h.lgr.Error("failed to send entries", log.KV("OTLP-Listener", oh.name), log.KVErr(err))
+		sendOTLPStatus(w, ct, http.StatusServiceUnavailable, otlpCodeUnavailable, err)
+		return
+	}
+	//an empty ExportLogsServiceResponse
+	w.Header().Set(`Content-Type`, ct)
+	w.WriteHeader(http.StatusOK)
+	if ct == otlpJSONType {
+		io.WriteString(w, `{}`)
+	}
+}
+
+func sendOTLPStatus(w http.ResponseWriter, ct string, code int, rpcCode int32, err error) {
+	w.Header().Set(`Content-Type`, ct)
+	w.WriteHeader(code)
+	if ct == otlpJSONType {
+		fmt.Fprintf(w, `{"code":%d,"message":%q}`, rpcCode, err.Error())
+	} else {
+		w.Write(encodeOTLPStatus(rpcCode, err.Error()))
+	}
+}
+
+// export converts every log record in the request to an entry and sends them as a single batch
+func (oh *otlpHandler) export(h *handler, req *otlpExportRequest, ip net.IP) (err error) {
+	var batch []*entry.Entry
+	now := entry.Now()
+	for i := range req.ResourceLogs {
+		rl := &req.ResourceLogs[i]
+		for j := range rl.ScopeLogs {
+			sl := &rl.ScopeLogs[j]
+			for k := range sl.LogRecords {
+				batch = append(batch, oh.entry(rl, sl, &sl.LogRecords[k], ip, now))
+			}
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+	var size uint64
+	for _, ent := range batch {
+		size += ent.Size()
+	}
+	if err = oh.pproc.ProcessBatchContext(batch, exitCtx); err == nil {
+		h.entSI.Add(uint64(len(batch)))
+		h.bytesSI.Add(size)
+	}
+	return
+}
+
+func (oh *otlpHandler) entry(rl *otlpResourceLogs, sl *otlpScopeLogs, lr *otlpLogRecord, ip net.IP, now entry.Timestamp) *entry.Entry {
+	ent := &entry.Entry{
+		TS:   now,
+		SRC:  ip,
+		Tag:  oh.tag,
+		Data: lr.Body.bytes(),
+	}
+	if !oh.ignoreTs {
+		if lr.TimeUnixNano != 0 {
+			ent.TS = entry.FromStandard(oh.timeWindow.Override(time.Unix(0, int64(lr.TimeUnixNano))))
+		} else if lr.ObservedTimeUnixNano != 0 {
+			ent.TS = entry.FromStandard(oh.timeWindow.Override(time.Unix(0, int64(lr.ObservedTimeUnixNano))))
+		}
+	}
+	if oh.tagAttr != `` {
+		if tag, ok := oh.routeTag(rl, lr); ok {
+			ent.Tag = tag
+		}
+	}
+
+	//resource attributes go first so that log attributes with the same name win
+	for _, kv := range rl.Resource.Attributes {
+		if val, ok := kv.Value.evValue(); ok && kv.Key != `` {
+			ent.AddEnumeratedValueEx(oh.resPrefix+kv.Key, val)
+		}
+	}
+	if sl.Scope.Name != `` {
+		ent.AddEnumeratedValueEx(otlpScopeEV, sl.Scope.Name)
+	}
+	if lr.SeverityText != `` {
+		ent.AddEnumeratedValueEx(otlpSeverityEV, lr.SeverityText)
+	}
+	if lr.SeverityNumber != 0 {
+		ent.AddEnumeratedValueEx(otlpSevNumEV, int64(lr.SeverityNumber))
+	}
+	if lr.TraceID != `` {
+		ent.AddEnumeratedValueEx(otlpTraceIDEV, lr.TraceID)
+	}
+	if lr.SpanID != `` {
+		ent.AddEnumeratedValueEx(otlpSpanIDEV, lr.SpanID)
+	}
+	if lr.EventName != `` {
+		ent.AddEnumeratedValueEx(otlpEventNameEV, lr.EventName)
+	}
+	for _, kv := range lr.Attributes {
+		if val, ok := kv.Value.evValue(); ok && kv.Key != `` {
+			ent.AddEnumeratedValueEx(kv.Key, val)
+		}
+	}
+	return ent
+}
+
+// routeTag looks for the tag attribute in the log attributes and then the resource attributes
+func (oh *otlpHandler) routeTag(rl *otlpResourceLogs, lr *otlpLogRecord) (tag entry.EntryTag, ok bool) {
+	for _, attrs := range [][]otlpKeyValue{lr.Attributes, rl.Resource.Attributes} {
+		for _, kv := range attrs {
+			if kv.Key == oh.tagAttr && kv.Value.StringValue != nil {
+				tag, ok = oh.tagRouter[*kv.Value.StringValue]
+				return
+			}
+		}
+	}
+	return
+}
+
+// otlpGRPCServer implements the OTLP/gRPC logs service, the listener configuration is looked up
+// on every request so that hot reloads apply to running gRPC servers.
+type otlpGRPCServer struct {
+	h    *handler
+	bind string
+}
+
+func (s *otlpGRPCServer) export(ctx context.Context, b []byte) (resp otlpRawMessage, err error) {
+	s.h.reqSI.Add(1)
+	s.h.RLock()
+	oh, ok := s.h.otlpGRPC[s.bind]
+	s.h.RUnlock()
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "no OTLP listener is configured for this address")
+	}
+	ip := net.ParseIP(`127.0.0.1`)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			if pip := net.ParseIP(host); pip != nil {
+				ip = pip
+			}
+		}
+	}
+	if oh.auth != nil {
+		//the auth handlers work on HTTP requests, so hand them the gRPC metadata as headers
+		r := &http.Request{Header: http.Header{}, URL: &url.URL{}}
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for k, vals := range md {
+				for _, val := range vals {
+					r.Header.Add(k, val)
+				}
+			}
+		}
+		if err = oh.auth.AuthRequest(r); err != nil {
+			s.h.lgr.Info("access denied", log.KV("address", ip), log.KV("OTLP-Listener", oh.name), log.KVErr(err))
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	if s.h.igst.WillBlock() {
+		return nil, status.Error(codes.Unavailable, "ingester is blocked")
+	}
+	var req otlpExportRequest
+	if err = decodeOTLPProto(b, &req); err != nil {
+		s.h.lgr.Info("bad request", log.KV("address", ip), log.KV("OTLP-Listener", oh.name), log.KVErr(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = oh.export(s.h, &req, ip); err != nil {
+		s.h.lgr.Error("failed to send entries", log.KV("OTLP-Listener", oh.name), log.KVErr(err))
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return otlpRawMessage{}, nil
+}
+
+func otlpGRPCExportHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+	var req otlpRawMessage
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	return srv.(*otlpGRPCServer).export(ctx, req)
+}
+
+var otlpLogsServiceDesc = grpc.ServiceDesc{
+	ServiceName: otlpLogsService,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: `Export`, Handler: otlpGRPCExportHandler},
+	},
+}
+
+// otlpRawMessage carries undecoded protobuf messages through gRPC, we decode requests ourselves
+type otlpRawMessage []byte
+
+type otlpCodec struct{}
+
+func (otlpCodec) Name() string {
+	return `proto`
+}
+
+func (otlpCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(otlpRawMessage); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot marshal %T", v)
+}
+
+func (otlpCodec) Unmarshal(b []byte, v interface{}) error {
+	if m, ok := v.(*otlpRawMessage); ok {
+		*m = append((*m)[:0], b...)
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal into %T", v)
+}
+
+// startOTLPGRPC starts a gRPC server for every OTLP listener with a GRPC-Bind.  The TLS and message
+// size settings are global, so servers started by a hot reload use the settings from startup.
+func startOTLPGRPC(hnd *handler, cfg *cfgType) (err error) {
+	opts := []grpc.ServerOption{
+		grpc.ForceServerCodec(otlpCodec{}),
+		grpc.MaxRecvMsgSize(cfg.MaxBody()),
+	}
+	if cfg.TLSEnabled() {
+		var creds credentials.TransportCredentials
+		if creds, err = credentials.NewServerTLSFromFile(cfg.TLS_Certificate_File, cfg.TLS_Key_File); err != nil {
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	hnd.Lock()
+	hnd.otlpOpts = opts
+	hnd.Unlock()
+	return hnd.startOTLPBinds(cfg)
+}
+
+// startOTLPBinds starts gRPC servers for the GRPC-Bind addresses that do not already have one,
+// if any of them fail the servers started by this call are stopped again.
+func (h *handler) startOTLPBinds(cfg *cfgType) (err error) {
+	h.Lock()
+	defer h.Unlock()
+	var started []string
+	for k, v := range cfg.OTLPListener {
+		if v.GRPC_Bind == `` {
+			continue
+		} else if _, ok := h.otlpSrvs[v.GRPC_Bind]; ok {
+			continue
+		}
+		var lst net.Listener
+		if lst, err = net.Listen(`tcp`, v.GRPC_Bind); err != nil {
+			err = fmt.Errorf("OTLP-Listener %s failed to bind to %s %w", k, v.GRPC_Bind, err)
+			break
+		}
+		srv := grpc.NewServer(h.otlpOpts...)
+		srv.RegisterService(&otlpLogsServiceDesc, &otlpGRPCServer{h: h, bind: v.GRPC_Bind})
+		go func(name string) {
+			if err := srv.Serve(lst); err != nil && err != grpc.ErrServerStopped {
+				h.lgr.Error("failed to serve OTLP gRPC", log.KV("OTLP-Listener", name), log.KVErr(err))
+			}
+		}(k)
+		debugout("OTLP gRPC listener %s bound to %s\n", k, v.GRPC_Bind)
+		h.otlpSrvs[v.GRPC_Bind] = srv
+		started = append(started, v.GRPC_Bind)
+	}
+	if err != nil {
+		for _, bind := range started {
+			h.otlpSrvs[bind].Stop()
+			delete(h.otlpSrvs, bind)
+		}
+	}
+	return
+}
+
+// stopOTLPBinds gracefully stops the gRPC servers whose bind address is not in keep, a nil keep stops them all
+func (h *handler) stopOTLPBinds(keep map[string]*otlpHandler) {
+	var srvs []*grpc.Server
+	h.Lock()
+	for bind, srv := range h.otlpSrvs {
+		if _, ok := keep[bind]; !ok {
+			srvs = append(srvs, srv)
+			delete(h.otlpSrvs, bind)
+		}
+	}
+	h.Unlock()
+	//GracefulStop waits on in flight requests, which need the handler lock
+	for _, srv := range srvs {
+		srv.GracefulStop()
+	}
+}
+
+func includeOTLPListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
+	for k, v := range cfg.OTLPListener {
+		oh := &otlpHandler{
+			name:      k,
+			tagAttr:   v.Tag_Attribute,
+			resPrefix: v.Resource_Attribute_Prefix,
+			ignoreTs:  v.Ignore_Timestamps,
+		}
+		if oh.tag, err = igst.NegotiateTag(v.Tag_Name); err != nil {
+			return fmt.Errorf("failed to pull tag %s %w", v.Tag_Name, err)
+		}
+		var tms []tagMatcher
+		if tms, err = v.tagMatchers(); err != nil {
+			return
+		}
+		oh.tagRouter = make(map[string]entry.EntryTag, len(tms))
+		for _, tm := range tms {
+			if oh.tagRouter[tm.Value], err = igst.NegotiateTag(tm.Tag); err != nil {
+				return fmt.Errorf("failed to pull tag %s %w", tm.Tag, err)
+			}
+		}
+		if oh.timeWindow, err = cfg.GlobalTimestampWindow(); err != nil {
+			return fmt.Errorf("TimestampWindow is invalid %w", err)
+		}
+		if _, oh.auth, err = v.NewAuthHandler(lgr); err != nil {
+			return fmt.Errorf("failed to get a new authentication handler %w", err)
+		}
+		if oh.pproc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			return fmt.Errorf("preprocessor construction error %w", err)
+		}
+		hcfg := routeHandler{
+			handler:  oh.handle,
+			tag:      oh.tag,
+			ignoreTs: oh.ignoreTs,
+			auth:     oh.auth,
+			pproc:    oh.pproc,
+		}
+		if err = hnd.addHandler(http.MethodPost, v.URL, hcfg); err != nil {
+			return fmt.Errorf("failed to add handler for %q %w", v.URL, err)
+		}
+		if v.GRPC_Bind != `` {
+			hnd.Lock()
+			hnd.otlpGRPC[v.GRPC_Bind] = oh
+			hnd.Unlock()
+		}
+		debugout("OTLP Handler URL %s handling %s\n", v.URL, v.Tag_Name)
+	}
+	return
+}
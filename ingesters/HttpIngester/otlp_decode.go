@@ -0,0 +1,421 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The OTLP message types below cover the subset of opentelemetry/proto/collector/logs/v1
+// that we ingest, the same structures are filled by the protobuf and the JSON decoders.
+// Protobuf field numbers are taken from the opentelemetry-proto definitions.
+
+const (
+	otlpMaxDepth = 64 //maximum nesting of array and kvlist values
+)
+
+var (
+	ErrOTLPTooDeep   = errors.New("OTLP value nesting is too deep")
+	ErrOTLPBadNumber = errors.New("invalid OTLP numeric value")
+)
+
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         otlpUint64     `json:"timeUnixNano"`
+	ObservedTimeUnixNano otlpUint64     `json:"observedTimeUnixNano"`
+	SeverityNumber       int32          `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId"` //hex encoded
+	SpanID               string         `json:"spanId"`  //hex encoded
+	EventName            string         `json:"eventName"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *otlpInt64      `json:"intValue,omitempty"`
+	DoubleValue *otlpFloat64    `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	KVListValue *otlpKVList     `json:"kvlistValue,omitempty"`
+	BytesValue  []byte          `json:"bytesValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKVList struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// OTLP/JSON encodes 64 bit integers as strings, but plenty of senders use bare numbers
+type otlpInt64 int64
+type otlpUint64 uint64
+type otlpFloat64 float64
+
+func (v *otlpInt64) UnmarshalJSON(b []byte) error {
+	x, err := strconv.ParseInt(string(unquoteJSONNumber(b)), 10, 64)
+	if err != nil {
+		return ErrOTLPBadNumber
+	}
+	*v = otlpInt64(x)
+	return nil
+}
+
+func (v *otlpUint64) UnmarshalJSON(b []byte) error {
+	x, err := strconv.ParseUint(string(unquoteJSONNumber(b)), 10, 64)
+	if err != nil {
+		return ErrOTLPBadNumber
+	}
+	*v = otlpUint64(x)
+	return nil
+}
+
+// UnmarshalJSON also handles the "NaN", "Infinity", and "-Infinity" strings
+func (v *otlpFloat64) UnmarshalJSON(b []byte) error {
+	x, err := strconv.ParseFloat(string(unquoteJSONNumber(b)), 64)
+	if err != nil {
+		return ErrOTLPBadNumber
+	}
+	*v = otlpFloat64(x)
+	return nil
+}
+
+func unquoteJSONNumber(b []byte) []byte {
+	if b = bytes.TrimSpace(b); len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	return b
+}
+
+func decodeOTLPJSON(b []byte, req *otlpExportRequest) error {
+	return json.Unmarshal(b, req)
+}
+
+// empty returns true if no value is set
+func (v *otlpAnyValue) empty() bool {
+	return v.StringValue == nil && v.BoolValue == nil && v.IntValue == nil && v.DoubleValue == nil &&
+		v.ArrayValue == nil && v.KVListValue == nil && v.BytesValue == nil
+}
+
+// native returns the value as a plain Go type, arrays and kvlists become slices and maps
+func (v *otlpAnyValue) native() interface{} {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		if f := float64(*v.DoubleValue); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		//JSON cannot represent these
+		return strconv.FormatFloat(float64(*v.DoubleValue), 'g', -1, 64)
+	case v.BytesValue != nil:
+		return v.BytesValue
+	case v.ArrayValue != nil:
+		r := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for i := range v.ArrayValue.Values {
+			r = append(r, v.ArrayValue.Values[i].native())
+		}
+		return r
+	case v.KVListValue != nil:
+		r := make(map[string]interface{}, len(v.KVListValue.Values))
+		for i := range v.KVListValue.Values {
+			r[v.KVListValue.Values[i].Key] = v.KVListValue.Values[i].Value.native()
+		}
+		return r
+	}
+	return nil
+}
+
+// bytes renders a value as entry data, strings and bytes are used verbatim and everything else is JSON
+func (v *otlpAnyValue) bytes() []byte {
+	switch {
+	case v.StringValue != nil:
+		return []byte(*v.StringValue)
+	case v.BytesValue != nil:
+		return v.BytesValue
+	case v.empty():
+		return nil
+	}
+	b, _ := json.Marshal(v.native())
+	return b
+}
+
+// evValue returns a value suitable for an enumerated value, arrays and kvlists are JSON encoded
+func (v *otlpAnyValue) evValue() (interface{}, bool) {
+	switch {
+	case v.empty():
+		return nil, false
+	case v.ArrayValue != nil, v.KVListValue != nil:
+		b, err := json.Marshal(v.native())
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+	return v.native(), true
+}
+
+// protoField is called for each field in a protobuf message, v holds the payload of
+// length delimited fields and x holds the value of varint and fixed width fields.
+type protoField func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error
+
+// walkProto iterates the fields of a protobuf message, groups are skipped
+func walkProto(b []byte, fn protoField) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v []byte
+		var x uint64
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(b)
+			x = uint64(x32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeOTLPProto decodes an ExportLogsServiceRequest, fields with unexpected wire types are ignored
+func decodeOTLPProto(b []byte, req *otlpExportRequest) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			var rl otlpResourceLogs
+			if err := rl.decode(v); err != nil {
+				return err
+			}
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		return nil
+	})
+}
+
+func (rl *otlpResourceLogs) decode(b []byte) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: //Resource
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+				if num == 1 && typ == protowire.BytesType {
+					rl.Resource.Attributes, err = appendOTLPKeyValue(rl.Resource.Attributes, v, 0)
+				}
+				return
+			})
+		case 2: //ScopeLogs
+			var sl otlpScopeLogs
+			if err := sl.decode(v); err != nil {
+				return err
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		return nil
+	})
+}
+
+func (sl *otlpScopeLogs) decode(b []byte) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: //InstrumentationScope
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if typ == protowire.BytesType {
+					switch num {
+					case 1:
+						sl.Scope.Name = string(v)
+					case 2:
+						sl.Scope.Version = string(v)
+					}
+				}
+				return nil
+			})
+		case 2: //LogRecord
+			var lr otlpLogRecord
+			if err := lr.decode(v); err != nil {
+				return err
+			}
+			sl.LogRecords = append(sl.LogRecords, lr)
+		}
+		return nil
+	})
+}
+
+func (lr *otlpLogRecord) decode(b []byte) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			lr.TimeUnixNano = otlpUint64(x)
+		case num == 11 && typ == protowire.Fixed64Type:
+			lr.ObservedTimeUnixNano = otlpUint64(x)
+		case num == 2 && typ == protowire.VarintType:
+			lr.SeverityNumber = int32(x)
+		case num == 3 && typ == protowire.BytesType:
+			lr.SeverityText = string(v)
+		case num == 5 && typ == protowire.BytesType:
+			err = lr.Body.decode(v, 0)
+		case num == 6 && typ == protowire.BytesType:
+			lr.Attributes, err = appendOTLPKeyValue(lr.Attributes, v, 0)
+		case num == 9 && typ == protowire.BytesType:
+			lr.TraceID = hex.EncodeToString(v)
+		case num == 10 && typ == protowire.BytesType:
+			lr.SpanID = hex.EncodeToString(v)
+		case num == 12 && typ == protowire.BytesType:
+			lr.EventName = string(v)
+		}
+		return
+	})
+}
+
+func appendOTLPKeyValue(kvs []otlpKeyValue, b []byte, depth int) ([]otlpKeyValue, error) {
+	var kv otlpKeyValue
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+		if typ == protowire.BytesType {
+			switch num {
+			case 1:
+				kv.Key = string(v)
+			case 2:
+				err = kv.Value.decode(v, depth)
+			}
+		}
+		return
+	})
+	if err != nil {
+		return kvs, err
+	}
+	return append(kvs, kv), nil
+}
+
+func (av *otlpAnyValue) decode(b []byte, depth int) error {
+	if depth > otlpMaxDepth {
+		return ErrOTLPTooDeep
+	}
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+		//this is a oneof, the last value wins
+		if num >= 1 && num <= 7 {
+			*av = otlpAnyValue{}
+		}
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			s := string(v)
+			av.StringValue = &s
+		case num == 2 && typ == protowire.VarintType:
+			bv := x != 0
+			av.BoolValue = &bv
+		case num == 3 && typ == protowire.VarintType:
+			iv := otlpInt64(int64(x))
+			av.IntValue = &iv
+		case num == 4 && typ == protowire.Fixed64Type:
+			fv := otlpFloat64(math.Float64frombits(x))
+			av.DoubleValue = &fv
+		case num == 5 && typ == protowire.BytesType:
+			av.ArrayValue = &otlpArrayValue{}
+			err = walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					var ev otlpAnyValue
+					if err := ev.decode(v, depth+1); err != nil {
+						return err
+					}
+					av.ArrayValue.Values = append(av.ArrayValue.Values, ev)
+				}
+				return nil
+			})
+		case num == 6 && typ == protowire.BytesType:
+			av.KVListValue = &otlpKVList{}
+			err = walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) (err error) {
+				if num == 1 && typ == protowire.BytesType {
+					av.KVListValue.Values, err = appendOTLPKeyValue(av.KVListValue.Values, v, depth+1)
+				}
+				return
+			})
+		case num == 7 && typ == protowire.BytesType:
+			av.BytesValue = append([]byte{}, v...)
+		}
+		return
+	})
+}
+
+// encodeOTLPStatus builds a google.rpc.Status message for OTLP/HTTP error responses
+func encodeOTLPStatus(code int32, msg string) []byte {
+	var b []byte
+	if code != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(code))
+	}
+	if msg != `` {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, msg)
+	}
+	return b
+}
@@ -0,0 +1,486 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protobuf builders, pbBytes concatenates its parts into a single length delimited field
+func pbBytes(num protowire.Number, parts ...[]byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, bytes.Join(parts, nil))
+}
+
+func pbString(num protowire.Number, s string) []byte {
+	return pbBytes(num, []byte(s))
+}
+
+func pbVarint(num protowire.Number, x uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, x)
+}
+
+func pbFixed64(num protowire.Number, x uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, x)
+}
+
+func pbJoin(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func pbKV(k string, v []byte) []byte {
+	return pbJoin(pbString(1, k), pbBytes(2, v))
+}
+
+// pbRequest wraps log records in a single resource and scope, resAttr is an optional resource attribute
+func pbRequest(resAttr []byte, records ...[]byte) []byte {
+	sl := pbBytes(1, pbString(1, `testscope`), pbString(2, `1.0`))
+	for _, r := range records {
+		sl = append(sl, pbBytes(2, r)...)
+	}
+	var res []byte
+	if resAttr != nil {
+		res = pbBytes(1, resAttr)
+	}
+	rl := pbJoin(pbBytes(1, res), pbBytes(2, sl))
+	return pbBytes(1, rl)
+}
+
+// pbNested builds an AnyValue holding arrays nested depth deep
+func pbNested(depth int) []byte {
+	v := pbString(1, `bottom`)
+	for i := 0; i < depth; i++ {
+		v = pbBytes(5, pbBytes(1, v))
+	}
+	return v
+}
+
+const testOTLPTS uint64 = 1700000000123456789
+
+func testOTLPRecord() []byte {
+	list := pbBytes(5,
+		pbBytes(1, pbString(1, `a`)),
+		pbBytes(1, pbVarint(3, 2)),
+		pbBytes(1, pbBytes(6, pbBytes(1, pbKV(`k`, pbVarint(2, 1))))),
+	)
+	return pbJoin(
+		pbFixed64(1, testOTLPTS),
+		pbVarint(2, 9),
+		pbString(3, `INFO`),
+		pbBytes(5, pbString(1, `hello`)),
+		pbBytes(6, pbKV(`list`, list)),
+		pbBytes(6, pbKV(`ratio`, pbFixed64(4, math.Float64bits(0.5)))),
+		pbBytes(9, []byte{0xde, 0xad, 0xbe, 0xef}),
+		pbBytes(10, []byte{0x01, 0x02}),
+		pbFixed64(11, testOTLPTS+1),
+		pbString(12, `evt`),
+	)
+}
+
+func TestDecodeOTLPProto(t *testing.T) {
+	tag := func(num protowire.Number, typ protowire.Type) []byte {
+		return protowire.AppendTag(nil, num, typ)
+	}
+	oneRecord := func(req *otlpExportRequest) (*otlpLogRecord, error) {
+		if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+			return nil, fmt.Errorf("bad structure %+v", req)
+		}
+		return &req.ResourceLogs[0].ScopeLogs[0].LogRecords[0], nil
+	}
+	tests := []struct {
+		name  string
+		b     []byte
+		err   bool
+		is    error
+		check func(*otlpExportRequest) error
+	}{
+		{
+			name: `nested`,
+			b:    pbRequest(pbKV(`service.name`, pbString(1, `svc`)), testOTLPRecord()),
+			check: func(req *otlpExportRequest) error {
+				lr, err := oneRecord(req)
+				if err != nil {
+					return err
+				}
+				rl := req.ResourceLogs[0]
+				if len(rl.Resource.Attributes) != 1 || rl.Resource.Attributes[0].Key != `service.name` || *rl.Resource.Attributes[0].Value.StringValue != `svc` {
+					return fmt.Errorf("bad resource %+v", rl.Resource)
+				} else if sc := rl.ScopeLogs[0].Scope; sc.Name != `testscope` || sc.Version != `1.0` {
+					return fmt.Errorf("bad scope %+v", sc)
+				}
+				if uint64(lr.TimeUnixNano) != testOTLPTS || uint64(lr.ObservedTimeUnixNano) != testOTLPTS+1 {
+					return fmt.Errorf("bad timestamps %d %d", lr.TimeUnixNano, lr.ObservedTimeUnixNano)
+				} else if lr.SeverityNumber != 9 || lr.SeverityText != `INFO` || lr.EventName != `evt` {
+					return fmt.Errorf("bad severity or event %+v", lr)
+				} else if lr.TraceID != `deadbeef` || lr.SpanID != `0102` {
+					return fmt.Errorf("bad ids %q %q", lr.TraceID, lr.SpanID)
+				} else if string(lr.Body.bytes()) != `hello` {
+					return fmt.Errorf("bad body %q", lr.Body.bytes())
+				} else if len(lr.Attributes) != 2 {
+					return fmt.Errorf("bad attributes %+v", lr.Attributes)
+				}
+				if v, ok := lr.Attributes[0].Value.evValue(); !ok || v != `["a",2,{"k":true}]` {
+					return fmt.Errorf("bad nested attribute %v", v)
+				} else if v, ok = lr.Attributes[1].Value.evValue(); !ok || v != 0.5 {
+					return fmt.Errorf("bad double attribute %v", v)
+				}
+				return nil
+			},
+		},
+		{
+			name: `empty`,
+			check: func(req *otlpExportRequest) error {
+				if len(req.ResourceLogs) != 0 {
+					return errors.New("resource logs from nothing")
+				}
+				return nil
+			},
+		},
+		{
+			name: `unknown fields`,
+			b: pbJoin(
+				pbVarint(2, 5),
+				protowire.AppendFixed32(tag(3, protowire.Fixed32Type), 7),
+				tag(4, protowire.StartGroupType), pbVarint(1, 1), tag(4, protowire.EndGroupType),
+				pbRequest(nil, pbJoin(pbBytes(5, pbString(1, `x`)), pbString(99, `ignored`))),
+			),
+			check: func(req *otlpExportRequest) error {
+				lr, err := oneRecord(req)
+				if err == nil && string(lr.Body.bytes()) != `x` {
+					err = fmt.Errorf("bad body %q", lr.Body.bytes())
+				}
+				return err
+			},
+		},
+		{
+			name: `wrong wire types`,
+			b: pbJoin(
+				pbVarint(1, 1), //ResourceLogs as a varint
+				pbRequest(nil, pbJoin(
+					pbVarint(1, testOTLPTS), //time as a varint
+					pbFixed64(3, 1),         //severity text as fixed64
+					pbVarint(5, 1),          //body as a varint
+					pbBytes(2, []byte(`9`)), //severity number as bytes
+				)),
+			),
+			check: func(req *otlpExportRequest) error {
+				lr, err := oneRecord(req)
+				if err != nil {
+					return err
+				} else if lr.TimeUnixNano != 0 || lr.SeverityText != `` || lr.SeverityNumber != 0 || !lr.Body.empty() {
+					return fmt.Errorf("mistyped fields were decoded %+v", lr)
+				}
+				return nil
+			},
+		},
+		{
+			name: `oneof last wins`,
+			b:    pbRequest(nil, pbBytes(5, pbString(1, `first`), pbVarint(3, 42))),
+			check: func(req *otlpExportRequest) error {
+				lr, err := oneRecord(req)
+				if err != nil {
+					return err
+				} else if lr.Body.StringValue != nil || lr.Body.IntValue == nil || *lr.Body.IntValue != 42 {
+					return fmt.Errorf("bad oneof %+v", lr.Body)
+				}
+				return nil
+			},
+		},
+		{
+			name: `max depth`,
+			b:    pbRequest(nil, pbBytes(5, pbNested(otlpMaxDepth))),
+			check: func(req *otlpExportRequest) error {
+				_, err := oneRecord(req)
+				return err
+			},
+		},
+		{name: `too deep`, b: pbRequest(nil, pbBytes(5, pbNested(otlpMaxDepth+2))), err: true, is: ErrOTLPTooDeep},
+		{name: `too deep kvlist`, b: pbRequest(nil, pbBytes(6, pbKV(`k`, pbNested(otlpMaxDepth+2)))), err: true, is: ErrOTLPTooDeep},
+		{name: `truncated tag`, b: []byte{0x80}, err: true},
+		{name: `field zero`, b: pbString(0, `x`), err: true},
+		{name: `truncated varint`, b: append(tag(2, protowire.VarintType), 0x80, 0x80), err: true},
+		{name: `overlong varint`, b: append(tag(2, protowire.VarintType), bytes.Repeat([]byte{0xff}, 11)...), err: true},
+		{name: `truncated fixed64`, b: append(tag(2, protowire.Fixed64Type), 1, 2, 3), err: true},
+		{name: `oversize length`, b: append(protowire.AppendVarint(tag(1, protowire.BytesType), 1000), `abc`...), err: true},
+		{name: `huge length`, b: protowire.AppendVarint(tag(1, protowire.BytesType), math.MaxUint64), err: true},
+		{name: `unterminated group`, b: pbJoin(tag(5, protowire.StartGroupType), pbVarint(1, 1)), err: true},
+		{
+			name: `nested oversize length`,
+			b:    pbRequest(nil, pbJoin(pbBytes(5, append(protowire.AppendVarint(tag(1, protowire.BytesType), 50), 'x')))),
+			err:  true,
+		},
+		{
+			name: `nested truncated varint`,
+			b:    pbRequest(nil, pbBytes(6, pbKV(`k`, append(tag(3, protowire.VarintType), 0xff)))),
+			err:  true,
+		},
+	}
+	for _, tst := range tests {
+		var req otlpExportRequest
+		err := decodeOTLPProto(tst.b, &req)
+		if tst.err {
+			if err == nil {
+				t.Fatalf("%s: failed to catch bad message", tst.name)
+			} else if tst.is != nil && !errors.Is(err, tst.is) {
+				t.Fatalf("%s: bad error %v != %v", tst.name, err, tst.is)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("%s: %v", tst.name, err)
+		}
+		if err = tst.check(&req); err != nil {
+			t.Fatalf("%s: %v", tst.name, err)
+		}
+	}
+}
+
+func TestDecodeOTLPJSON(t *testing.T) {
+	good := `{"resourceLogs":[{"resource":{"attributes":[{"key":"host","value":{"stringValue":"h1"}}]},
+	"scopeLogs":[{"scope":{"name":"s"},"logRecords":[
+	{"timeUnixNano":"1700000000123456789","severityNumber":9,"body":{"kvlistValue":{"values":[{"key":"a","value":{"intValue":"1"}}]}},
+	 "attributes":[{"key":"n","value":{"intValue":7}},{"key":"d","value":{"doubleValue":"NaN"}},{"key":"b","value":{"bytesValue":"aGk="}}]}]}]}]}`
+	var req otlpExportRequest
+	if err := decodeOTLPJSON([]byte(good), &req); err != nil {
+		t.Fatal(err)
+	}
+	lr := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if uint64(lr.TimeUnixNano) != testOTLPTS || lr.SeverityNumber != 9 {
+		t.Fatalf("bad record %+v", lr)
+	} else if string(lr.Body.bytes()) != `{"a":1}` {
+		t.Fatalf("bad body %s", lr.Body.bytes())
+	}
+	for i, exp := range []interface{}{int64(7), `NaN`, `hi`} {
+		v, ok := lr.Attributes[i].Value.evValue()
+		if bv, isb := v.([]byte); isb {
+			v = string(bv)
+		}
+		if !ok || v != exp {
+			t.Fatalf("bad attribute %d %v != %v", i, v, exp)
+		}
+	}
+
+	bad := []string{
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"soon"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"-1"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"intValue":"1.5"}}]}]}]}`,
+		`{"resourceLogs":[`,
+	}
+	for _, v := range bad {
+		var req otlpExportRequest
+		if err := decodeOTLPJSON([]byte(v), &req); err == nil {
+			t.Fatalf("failed to catch bad request %s", v)
+		}
+	}
+}
+
+func TestOTLPHandler(t *testing.T) {
+	setMaxBody(t, 4096)
+	ip := net.ParseIP(`10.0.0.1`)
+	routed := entry.EntryTag(7)
+	jsonBody := fmt.Sprintf(`{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]},
+	"scopeLogs":[{"scope":{"name":"s"},"logRecords":[
+	{"timeUnixNano":"%d","severityText":"INFO","body":{"stringValue":"hello"}},
+	{"body":{"kvlistValue":{"values":[{"key":"a","value":{"boolValue":true}}]}},"attributes":[{"key":"route","value":{"stringValue":"special"}}]}]}]}]}`, testOTLPTS)
+
+	tests := []struct {
+		name   string
+		ct     string
+		body   []byte
+		werr   error
+		status int
+		count  int
+		check  func([]*entry.Entry, *httptest.ResponseRecorder) error
+	}{
+		{
+			name: `json`, ct: `application/json; charset=utf-8`, body: []byte(jsonBody), status: http.StatusOK, count: 2,
+			check: func(ents []*entry.Entry, w *httptest.ResponseRecorder) error {
+				if w.Body.String() != `{}` {
+					return fmt.Errorf("bad response %q", w.Body.String())
+				} else if string(ents[0].Data) != `hello` || ents[0].Tag != 1 || !ents[0].SRC.Equal(ip) {
+					return fmt.Errorf("bad first entry %+v", ents[0])
+				} else if ents[0].TS != entry.FromStandard(time.Unix(0, int64(testOTLPTS))) {
+					return fmt.Errorf("bad timestamp %v", ents[0].TS)
+				} else if v, ok := ents[0].GetEnumeratedValue(`res_service.name`); !ok || v != `svc` {
+					return fmt.Errorf("missing resource attribute %v", v)
+				} else if v, ok = ents[0].GetEnumeratedValue(otlpSeverityEV); !ok || v != `INFO` {
+					return fmt.Errorf("missing severity %v", v)
+				} else if string(ents[1].Data) != `{"a":true}` || ents[1].Tag != routed {
+					return fmt.Errorf("bad second entry %s %v", ents[1].Data, ents[1].Tag)
+				}
+				return nil
+			},
+		},
+		{
+			name: `protobuf`, ct: otlpProtobufType, status: http.StatusOK, count: 1,
+			body: pbRequest(pbKV(`service.name`, pbString(1, `svc`)), testOTLPRecord()),
+			check: func(ents []*entry.Entry, w *httptest.ResponseRecorder) error {
+				if w.Body.Len() != 0 || w.Header().Get(`Content-Type`) != otlpProtobufType {
+					return fmt.Errorf("bad response %q %q", w.Body.String(), w.Header().Get(`Content-Type`))
+				} else if string(ents[0].Data) != `hello` {
+					return fmt.Errorf("bad data %q", ents[0].Data)
+				} else if v, ok := ents[0].GetEnumeratedValue(otlpTraceIDEV); !ok || v != `deadbeef` {
+					return fmt.Errorf("bad trace id %v", v)
+				} else if v, ok = ents[0].GetEnumeratedValue(`list`); !ok || v != `["a",2,{"k":true}]` {
+					return fmt.Errorf("bad list attribute %v", v)
+				}
+				return nil
+			},
+		},
+		{name: `empty protobuf`, ct: otlpProtobufType, status: http.StatusOK},
+		{name: `bad content type`, ct: `text/plain`, body: []byte(jsonBody), status: http.StatusUnsupportedMediaType},
+		{name: `missing content type`, body: []byte(jsonBody), status: http.StatusUnsupportedMediaType},
+		{
+			name: `malformed protobuf`, ct: otlpProtobufType, body: []byte{0x0a, 0x64, 0x01}, status: http.StatusBadRequest,
+			check: func(_ []*entry.Entry, w *httptest.ResponseRecorder) error {
+				var code uint64
+				var msg string
+				err := walkProto(w.Body.Bytes(), func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+					if num == 1 {
+						code = x
+					} else if num == 2 {
+						msg = string(v)
+					}
+					return nil
+				})
+				if err != nil || code != otlpCodeInvalidArgument || msg == `` {
+					return fmt.Errorf("bad status %d %q %v", code, msg, err)
+				}
+				return nil
+			},
+		},
+		{
+			name: `malformed json`, ct: otlpJSONType, body: []byte(`{"resourceLogs":`), status: http.StatusBadRequest,
+			check: func(_ []*entry.Entry, w *httptest.ResponseRecorder) error {
+				if !strings.HasPrefix(w.Body.String(), `{"code":3,"message":`) {
+					return fmt.Errorf("bad status %q", w.Body.String())
+				}
+				return nil
+			},
+		},
+		{name: `too large`, ct: otlpJSONType, body: bytes.Repeat([]byte(` `), 4097), status: http.StatusRequestEntityTooLarge},
+		{name: `write failure`, ct: otlpJSONType, body: []byte(jsonBody), werr: errors.New("test"), status: http.StatusServiceUnavailable},
+	}
+	for _, tst := range tests {
+		h, tw, pproc := newTestHandler()
+		tw.err = tst.werr
+		oh := &otlpHandler{
+			name:      `test`,
+			tag:       1,
+			tagAttr:   `route`,
+			tagRouter: map[string]entry.EntryTag{`special`: routed},
+			resPrefix: `res_`,
+			pproc:     pproc,
+		}
+		r := httptest.NewRequest(http.MethodPost, defaultOTLPUrl, bytes.NewReader(tst.body))
+		if tst.ct != `` {
+			r.Header.Set(`Content-Type`, tst.ct)
+		}
+		w := httptest.NewRecorder()
+		oh.handle(h, routeHandler{}, w, r, r.Body, ip)
+		ents := tw.entries()
+		if w.Code != tst.status {
+			t.Fatalf("%s: bad status %d != %d: %s", tst.name, w.Code, tst.status, w.Body.String())
+		} else if len(ents) != tst.count {
+			t.Fatalf("%s: bad entry count %d != %d", tst.name, len(ents), tst.count)
+		}
+		if tst.check != nil {
+			if err := tst.check(ents, w); err != nil {
+				t.Fatalf("%s: %v", tst.name, err)
+			}
+		}
+	}
+}
+
+func FuzzDecodeOTLPProto(f *testing.F) {
+	f.Add(pbRequest(pbKV(`service.name`, pbString(1, `svc`)), testOTLPRecord()))
+	f.Add(pbRequest(nil, pbBytes(5, pbNested(otlpMaxDepth+2))))
+	f.Add(pbRequest(nil, pbBytes(6, pbKV(`k`, pbBytes(6, pbBytes(1, pbKV(`j`, pbFixed64(4, math.Float64bits(math.NaN())))))))))
+	f.Add([]byte{0x0a, 0x64, 0x01})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var req otlpExportRequest
+		if err := decodeOTLPProto(b, &req); err != nil {
+			return
+		}
+		//anything that decodes must be renderable as entries
+		oh := &otlpHandler{tagAttr: `k`}
+		for i := range req.ResourceLogs {
+			rl := &req.ResourceLogs[i]
+			for j := range rl.ScopeLogs {
+				sl := &rl.ScopeLogs[j]
+				for k := range sl.LogRecords {
+					oh.entry(rl, sl, &sl.LogRecords[k], nil, entry.Now())
+				}
+			}
+		}
+	})
+}
+
+func TestOTLPGRPCBinds(t *testing.T) {
+	h, _, _ := newTestHandler()
+	h.otlpSrvs = map[string]*grpc.Server{}
+	taken, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	mkcfg := func(binds ...string) *cfgType {
+		c := &cfgType{OTLPListener: map[string]*otlpListener{}}
+		for i, b := range binds {
+			c.OTLPListener[fmt.Sprintf("l%d", i)] = &otlpListener{GRPC_Bind: b}
+		}
+		return c
+	}
+	a, b := `127.0.0.1:0`, `localhost:0`
+
+	if err = startOTLPGRPC(h, mkcfg(a)); err != nil {
+		t.Fatal(err)
+	} else if len(h.otlpSrvs) != 1 {
+		t.Fatalf("bad server count %d", len(h.otlpSrvs))
+	}
+	srv := h.otlpSrvs[a]
+
+	//a reload starts new binds and leaves running ones alone
+	if err = h.startOTLPBinds(mkcfg(a, b)); err != nil {
+		t.Fatal(err)
+	} else if len(h.otlpSrvs) != 2 || h.otlpSrvs[a] != srv {
+		t.Fatalf("bad servers after reload %v", h.otlpSrvs)
+	}
+
+	//a bind failure stops anything the reload started
+	if err = h.startOTLPBinds(mkcfg(a, b, `127.0.0.2:0`, taken.Addr().String())); err == nil {
+		t.Fatal("failed to catch bind failure")
+	} else if len(h.otlpSrvs) != 2 || h.otlpSrvs[a] != srv {
+		t.Fatalf("bad servers after failed reload %v", h.otlpSrvs)
+	}
+
+	//binds that are gone are stopped
+	h.stopOTLPBinds(map[string]*otlpHandler{a: nil})
+	if len(h.otlpSrvs) != 1 || h.otlpSrvs[a] != srv {
+		t.Fatalf("bad servers after stop %v", h.otlpSrvs)
+	}
+	h.stopOTLPBinds(nil)
+	if len(h.otlpSrvs) != 0 {
+		t.Fatalf("servers still running %v", h.otlpSrvs)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 )
 
 const (
@@ -33,6 +34,7 @@ const (
 	lineReader    readerType = iota
 	rfc5424Reader readerType = iota
 	rfc6587Reader readerType = iota
+	relpReader    readerType = iota
 )
 
 var ()
@@ -55,6 +57,7 @@ type baseConfig struct {
 	Timezone_Override         string
 	Source_Override           string
 	Timestamp_Format_Override string //override the timestamp format
+	Timestamp_Format_Learning bool   //remember which timestamp format each source uses
 	Cert_File                 string
 	Key_File                  string
 	Preprocessor              []string
@@ -71,6 +74,7 @@ type cfgReadType struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	config.IngestConfig
 	Attach        attach.AttachConfig
 	Listener      map[string]*listener
@@ -284,7 +288,7 @@ func checkListenerSettings(l *listener) (err error) {
 	if bt, _, err = translateBindType(l.Bind_String); err != nil {
 		return
 	}
-	if l.Drop_Priority && !(lt == rfc5424Reader || lt == rfc6587Reader) {
+	if l.Drop_Priority && !(lt == rfc5424Reader || lt == rfc6587Reader || lt == relpReader) {
 		err = fmt.Errorf("Drop-Priority is not compatible with reader type %s", lt)
 		return
 	}
@@ -292,6 +296,10 @@ func checkListenerSettings(l *listener) (err error) {
 		err = fmt.Errorf("RFC6587 reader type is not compatible with a UDP bind string")
 		return
 	}
+	if lt == relpReader && bt.UDP() {
+		err = fmt.Errorf("RELP reader type is not compatible with a UDP bind string")
+		return
+	}
 	return
 }
 
@@ -368,6 +376,8 @@ func translateReaderType(s string) (readerType, error) {
 		return rfc5424Reader, nil
 	case `rfc6587`:
 		return rfc6587Reader, nil
+	case `relp`:
+		return relpReader, nil
 	case ``:
 		return lineReader, nil
 	}
@@ -382,6 +392,8 @@ func (rt readerType) String() string {
 		return `RFC5424`
 	case rfc6587Reader:
 		return `RFC6587`
+	case relpReader:
+		return `RELP`
 	}
 	return "UNKNOWN"
 }
@@ -70,6 +70,7 @@ func TestBadConfig(t *testing.T) {
 		badConfigWrongListener,
 		badConfigDropPriority,
 		badConfigReaderBind,
+		badConfigRELPUDP,
 	}
 
 	for _, v := range cfgs {
@@ -223,4 +224,16 @@ Log-File=/tmp/simple_relay.log
 	Drop-Priority=true
 	Reader-Type=rfc6587
 `
+
+	badConfigRELPUDP string = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-target=127.0.0.1:4023 #example of adding a cleartext connection
+Log-Level=INFO
+Log-File=/tmp/simple_relay.log
+
+[Listener "rsyslog"]
+	Bind-String="udp://0.0.0.0:2514"
+	Reader-Type=relp
+`
 )
@@ -19,7 +19,6 @@ import (
 	"net"
 	"os"
 	"strings"
-	"sync"
 
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/config"
@@ -40,7 +39,7 @@ type jsonHandlerConfig struct {
 	setLocalTime     bool
 	timezoneOverride string
 	src              net.IP
-	wg               *sync.WaitGroup
+	wg               *connSet
 	formatOverride   string
 	flds             []string
 	proc             *processors.ProcessorSet
@@ -49,134 +48,145 @@ type jsonHandlerConfig struct {
 	maxObjectSize    int64
 	disableCompact   bool
 	tsWindow         timegrinder.TimestampWindow
+	formatLearning   bool
+	tstats           *utils.TimestampStats
 }
 
-func startJSONListeners(cfg *cfgType, igst *ingest.IngestMuxer, wg *sync.WaitGroup, f *flusher, ctx context.Context) error {
-	var err error
-	//short circuit out on empty
-	if len(cfg.JSONListener) == 0 {
-		return nil
+func startJSONListeners(cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) error {
+	for k, v := range cfg.JSONListener {
+		if err := startJSONListener(k, v, cfg, igst, ls); err != nil {
+			return err
+		}
 	}
+	debugout("Started %d json listeners\n", len(cfg.JSONListener))
+	return nil
+}
+
+func startJSONListener(k string, v *jsonListener, cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) (err error) {
 	var window timegrinder.TimestampWindow
 	window, err = cfg.GlobalTimestampWindow()
 	if err != nil {
 		err = fmt.Errorf("Failed to get global timestamp window: %v", err)
 		return err
 	}
-	for k, v := range cfg.JSONListener {
-		if err := v.Validate(); err != nil {
-			return fmt.Errorf("JSONListener %s configuration is invalid: %w", k, err)
-		}
-		jhc := jsonHandlerConfig{
-			name:             k,
-			wg:               wg,
-			tags:             map[string]entry.EntryTag{},
-			ignoreTimestamps: v.Ignore_Timestamps,
-			setLocalTime:     v.Assume_Local_Timezone,
-			timezoneOverride: v.Timezone_Override,
-			ctx:              ctx,
-			formatOverride:   v.Timestamp_Format_Override,
-			timeFormats:      cfg.TimeFormat,
-			maxObjectSize:    int64(v.Max_Object_Size),
-			disableCompact:   v.Disable_Compact,
-			tsWindow:         window,
-		}
-		if jhc.proc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
-			lg.Fatal("preprocessor error", log.KVErr(err))
-		}
-		f.Add(jhc.proc)
-		if jhc.flds, err = v.GetJsonFields(); err != nil {
-			return err
-		}
-		if v.Source_Override != `` {
-			jhc.src = net.ParseIP(v.Source_Override)
-			if jhc.src == nil {
-				return fmt.Errorf("JSONListener %v invalid source override \"%s\"", k, v.Source_Override)
-			}
-		} else if cfg.Source_Override != `` {
-			// global override
-			jhc.src = net.ParseIP(cfg.Source_Override)
-			if jhc.src == nil {
-				return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
-			}
+	if err := v.Validate(); err != nil {
+		return fmt.Errorf("JSONListener %s configuration is invalid: %w", k, err)
+	}
+	jhc := jsonHandlerConfig{
+		name:             k,
+		tags:             map[string]entry.EntryTag{},
+		ignoreTimestamps: v.Ignore_Timestamps,
+		setLocalTime:     v.Assume_Local_Timezone,
+		timezoneOverride: v.Timezone_Override,
+		ctx:              ls.ctx,
+		formatOverride:   v.Timestamp_Format_Override,
+		timeFormats:      cfg.TimeFormat,
+		maxObjectSize:    int64(v.Max_Object_Size),
+		disableCompact:   v.Disable_Compact,
+		tsWindow:         window,
+		formatLearning:   v.Timestamp_Format_Learning,
+		tstats:           ls.tstats,
+	}
+	if jhc.flds, err = v.GetJsonFields(); err != nil {
+		return err
+	}
+	if v.Source_Override != `` {
+		jhc.src = net.ParseIP(v.Source_Override)
+		if jhc.src == nil {
+			return fmt.Errorf("JSONListener %v invalid source override \"%s\"", k, v.Source_Override)
 		}
-		//resolve the default tag
-		if jhc.defTag, err = igst.GetTag(v.Default_Tag); err != nil {
-			return err
+	} else if cfg.Source_Override != `` {
+		// global override
+		jhc.src = net.ParseIP(cfg.Source_Override)
+		if jhc.src == nil {
+			return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
 		}
+	}
+	//resolve the default tag
+	if jhc.defTag, err = igst.GetTag(v.Default_Tag); err != nil {
+		return err
+	}
 
-		//resolve all the other tags
-		tms, err := v.TagMatchers()
+	//resolve all the other tags
+	tms, err := v.TagMatchers()
+	if err != nil {
+		return err
+	}
+	for _, tm := range tms {
+		tg, err := igst.GetTag(tm.Tag)
 		if err != nil {
 			return err
 		}
-		for _, tm := range tms {
-			tg, err := igst.GetTag(tm.Tag)
-			if err != nil {
-				return err
-			}
-			jhc.tags[tm.Value] = tg
-		}
+		jhc.tags[tm.Value] = tg
+	}
+
+	tp, str, err := translateBindType(v.Bind_String)
+	if err != nil {
+		return fmt.Errorf("JSONListener %s invalid bind %q: %w", k, v.Bind_String, err)
+	}
 
-		tp, str, err := translateBindType(v.Bind_String)
+	cs, err := ls.add(sectionRef{kind: jsonListenerSection, name: k, conf: v}, v.Preprocessor, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if err != nil {
-			lg.FatalCode(0, "invalid bind", log.KV("bindstring", v.Bind_String), log.KVErr(err))
+			ls.stop(cs.key())
 		}
+	}()
+	jhc.wg = cs
+	jhc.proc = cs.proc
 
-		if tp.TCP() {
-			//get the socket
-			addr, err := net.ResolveTCPAddr("tcp", str)
-			if err != nil {
-				return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
-			}
-			l, err := net.ListenTCP("tcp", addr)
-			if err != nil {
-				return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go jsonAcceptor(l, connID, igst, jhc, tp)
-		} else if tp.TLS() {
-			config := &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			}
-
-			config.Certificates = make([]tls.Certificate, 1)
-			config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
-			if err != nil {
-				lg.Fatal("failed to load certificate", log.KV("certfile", v.Cert_File), log.KV("keyfile", v.Key_File), log.KVErr(err))
-			}
-			//get the socket
-			addr, err := net.ResolveTCPAddr("tcp", str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("jsonlistener", k), log.KVErr(err))
-			}
-			l, err := tls.Listen("tcp", addr.String(), config)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via TLS", log.KV("address", addr), log.KV("jsonlistener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go jsonAcceptor(l, connID, igst, jhc, tp)
-		} else if tp.UDP() {
-			addr, err := net.ResolveUDPAddr(tp.String(), str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("listener", k), log.KVErr(err))
-			}
-			l, err := net.ListenUDP(tp.String(), addr)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via udp", log.KV("address", addr), log.KV("listener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			wg.Add(1)
-			go jsonAcceptorUDP(l, connID, igst, jhc)
-
+	if tp.TCP() {
+		//get the socket
+		addr, err := net.ResolveTCPAddr("tcp", str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
+		}
+		l, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go jsonAcceptor(l, connID, igst, jhc, tp)
+	} else if tp.TLS() {
+		config := &tls.Config{
+			MinVersion: tls.VersionTLS12,
 		}
 
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
+		if err != nil {
+			return fmt.Errorf("%s failed to load certificate %q and key %q: %w", k, v.Cert_File, v.Key_File, err)
+		}
+		//get the socket
+		addr, err := net.ResolveTCPAddr("tcp", str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
+		}
+		l, err := tls.Listen("tcp", addr.String(), config)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via TLS on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go jsonAcceptor(l, connID, igst, jhc, tp)
+	} else if tp.UDP() {
+		addr, err := net.ResolveUDPAddr(tp.String(), str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
+		}
+		l, err := net.ListenUDP(tp.String(), addr)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via udp on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		cs.Add(1)
+		go jsonAcceptorUDP(l, connID, igst, jhc)
 	}
-	debugout("Started %d json listeners\n", len(cfg.JSONListener))
 	return nil
 }
 
@@ -213,8 +223,9 @@ func jsonAcceptorUDP(conn *net.UDPConn, id int, igst *ingest.IngestMuxer, cfg js
 
 	buff := make([]byte, 16*1024) //local buffer that should be big enough for even the largest UDP packets
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -227,6 +238,8 @@ func jsonAcceptorUDP(conn *net.UDPConn, id int, igst *ingest.IngestMuxer, cfg js
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
@@ -269,7 +282,7 @@ func jsonAcceptorUDP(conn *net.UDPConn, id int, igst *ingest.IngestMuxer, cfg js
 
 func jsonConnHandler(c net.Conn, cfg jsonHandlerConfig, igst *ingest.IngestMuxer) {
 	cfg.wg.Add(1)
-	id := addConn(c)
+	id := addConn(c, cfg.wg)
 	defer cfg.wg.Done()
 	defer delConn(id)
 	defer c.Close()
@@ -296,8 +309,9 @@ func jsonConnHandler(c net.Conn, cfg jsonHandlerConfig, igst *ingest.IngestMuxer
 	if !cfg.ignoreTimestamps {
 		var err error
 		tcfg := timegrinder.Config{
-			TSWindow:           cfg.tsWindow,
-			EnableLeftMostSeed: true,
+			TSWindow:             cfg.tsWindow,
+			EnableLeftMostSeed:   true,
+			EnableFormatLearning: cfg.formatLearning,
 		}
 		tg, err = timegrinder.NewTimeGrinder(tcfg)
 		if err != nil {
@@ -310,6 +324,8 @@ func jsonConnHandler(c net.Conn, cfg jsonHandlerConfig, igst *ingest.IngestMuxer
 		if cfg.setLocalTime {
 			tg.SetLocalTime()
 		}
+		cfg.tstats.Add(cfg.name, tg)
+		defer cfg.tstats.Remove(cfg.name, tg)
 		if cfg.timezoneOverride != `` {
 			if err = tg.SetTimezone(cfg.timezoneOverride); err != nil {
 				ll.Error("failed to set timezone", log.KV("timezone", cfg.timezoneOverride), log.KVErr(err))
@@ -22,7 +22,7 @@ import (
 
 func lineConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	cfg.wg.Add(1)
-	id := addConn(c)
+	id := addConn(c, cfg.wg)
 	defer cfg.wg.Done()
 	defer delConn(id)
 	defer c.Close()
@@ -46,8 +46,9 @@ func lineConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	if !cfg.ignoreTimestamps {
 		var err error
 		tcfg := timegrinder.Config{
-			TSWindow:           cfg.tsWindow,
-			EnableLeftMostSeed: true,
+			TSWindow:             cfg.tsWindow,
+			EnableLeftMostSeed:   true,
+			EnableFormatLearning: cfg.formatLearning,
 		}
 		tg, err = timegrinder.NewTimeGrinder(tcfg)
 		if err != nil {
@@ -60,6 +61,8 @@ func lineConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 		if cfg.setLocalTime {
 			tg.SetLocalTime()
 		}
+		cfg.tstats.Add(cfg.name, tg)
+		defer cfg.tstats.Remove(cfg.name, tg)
 		if cfg.timezoneOverride != `` {
 			err = tg.SetTimezone(cfg.timezoneOverride)
 			if err != nil {
@@ -103,8 +106,9 @@ func lineConnHandlerUDP(c *net.UDPConn, cfg handlerConfig) {
 	sp := []byte("\n")
 	buff := make([]byte, 16*1024) //local buffer that should be big enough for even the largest UDP packets
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -117,6 +121,8 @@ func lineConnHandlerUDP(c *net.UDPConn, cfg handlerConfig) {
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
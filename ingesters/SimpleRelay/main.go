@@ -11,9 +11,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"sync"
 	"time"
 
 	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
@@ -76,39 +74,57 @@ func main() {
 
 	debugout("Started ingester muxer\n")
 
-	connClosers = make(map[int]closer, 1)
+	connClosers = make(map[int]trackedConn, 1)
 	//check capabilities so we can scream and throw a potential warning upstream
 	if !caps.Has(caps.NET_BIND_SERVICE) {
 		lg.Warn("missing capability", log.KV("capability", "NET_BIND_SERVICE"), log.KV("warning", "may not be able to bind to service ports"))
 		debugout("missing capability NET_BIND_SERVICE, may not be able to bind to service ports")
 	}
 
-	wg := &sync.WaitGroup{}
-
-	var flshr flusher
-
 	ctx, cancel := context.WithCancel(context.Background())
+	ls := newListenerSet(ctx, igst)
 
 	//fire off our simple listeners
-	if err := startSimpleListeners(cfg, igst, wg, &flshr, ctx); err != nil {
+	if err := startSimpleListeners(cfg, igst, ls); err != nil {
 		lg.FatalCode(0, "Failed to start simple listeners", log.KV("ingesteruuid", id), log.KVErr(err))
 		return
 	}
 	// fire off our regex listeners
-	if err := startRegexListeners(cfg, igst, wg, &flshr, ctx); err != nil {
+	if err := startRegexListeners(cfg, igst, ls); err != nil {
 		lg.FatalCode(0, "Failed to start regex listeners", log.KV("ingesteruuid", id), log.KVErr(err))
 		return
 	}
 	//fire off our json listeners
-	if err := startJSONListeners(cfg, igst, wg, &flshr, ctx); err != nil {
+	if err := startJSONListeners(cfg, igst, ls); err != nil {
 		lg.FatalCode(0, "Failed to start json listeners", log.KV("ingesteruuid", id), log.KVErr(err))
 		return
 	}
 
+	//watch for SIGHUP so listeners can be added, removed, or changed without a restart
+	rldr, err := ib.NewReloader(igst, ls.reload)
+	if err != nil {
+		lg.FatalCode(0, "failed to create configuration reloader", log.KVErr(err))
+		return
+	} else if err = rldr.Start(); err != nil {
+		lg.FatalCode(0, "failed to start configuration reloader", log.KVErr(err))
+		return
+	}
+	//indexers may push signed configuration overlays when a Config-Overlay-Key is configured
+	if err = rldr.EnableConfigOverlays(); err == nil {
+		lg.Info("remote configuration overlays enabled")
+	} else if err != base.ErrNoOverlayKeys && err != base.ErrNoOverlayDir {
+		lg.FatalCode(0, "failed to enable configuration overlays", log.KVErr(err))
+		return
+	}
+
+	//report timestamp format learning statistics through the ingester state
+	go ls.tstats.Report(ctx, igst, utils.TimestampStatsInterval)
+
 	lg.Info("Ingester running")
 
 	//listen for signals so we can close gracefully
 	utils.WaitForQuit()
+	rldr.Close()
 	ib.AnnounceShutdown()
 	debugout("Closing %d connections\n", connCount())
 	lg.Info("Closing active connections", log.KV("ingesteruuid", id), log.KV("active", connCount()))
@@ -118,25 +134,7 @@ func main() {
 		cancel()
 	}()
 
-	mtx.Lock()
-	for _, v := range connClosers {
-		v.Close()
-	}
-	mtx.Unlock() //must unlock so they can delete their connections
-
-	//wait for everyone to exit with a timeout
-	wch := make(chan bool, 1)
-
-	go func() {
-		wg.Wait()
-		wch <- true
-	}()
-	select {
-	case <-wch:
-	case <-time.After(1 * time.Second):
-		lg.Error("Failed to wait for all connections to close", log.KV("timeout", time.Second), log.KV("active", connCount()))
-	}
-	if err := flshr.Close(); err != nil {
+	if err := ls.Close(); err != nil {
 		lg.Error("failed to close preprocessors", log.KVErr(err))
 	}
 	if err := igst.Sync(utils.ExitSyncTimeout); err != nil {
@@ -153,31 +151,6 @@ func debugout(format string, args ...interface{}) {
 	}
 }
 
-type flusher struct {
-	sync.Mutex
-	set []io.Closer
-}
-
-func (f *flusher) Add(c io.Closer) {
-	if c == nil {
-		return
-	}
-	f.Lock()
-	f.set = append(f.set, c)
-	f.Unlock()
-}
-
-func (f *flusher) Close() (err error) {
-	f.Lock()
-	for _, v := range f.set {
-		if lerr := v.Close(); lerr != nil {
-			err = addError(lerr, err)
-		}
-	}
-	f.Unlock()
-	return
-}
-
 func addError(nerr, err error) error {
 	if nerr == nil {
 		return err
@@ -19,7 +19,6 @@ import (
 	"os"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest"
@@ -27,6 +26,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/log"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
 	"github.com/gravwell/gravwell/v3/timegrinder"
 )
 
@@ -41,7 +41,7 @@ type regexHandlerConfig struct {
 	setLocalTime     bool
 	timezoneOverride string
 	src              net.IP
-	wg               *sync.WaitGroup
+	wg               *connSet
 	formatOverride   string
 	proc             *processors.ProcessorSet
 	ctx              context.Context
@@ -50,118 +50,129 @@ type regexHandlerConfig struct {
 	trimWhitespace   bool
 	maxBuffer        int
 	tsWindow         timegrinder.TimestampWindow
+	formatLearning   bool
+	tstats           *utils.TimestampStats
 }
 
-func startRegexListeners(cfg *cfgType, igst *ingest.IngestMuxer, wg *sync.WaitGroup, f *flusher, ctx context.Context) error {
-	var err error
-	//short circuit out on empty
-	if len(cfg.RegexListener) == 0 {
-		return nil
+func startRegexListeners(cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) error {
+	for k, v := range cfg.RegexListener {
+		if err := startRegexListener(k, v, cfg, igst, ls); err != nil {
+			return err
+		}
 	}
+	debugout("Started %d regex listeners\n", len(cfg.RegexListener))
+	return nil
+}
 
+func startRegexListener(k string, v *regexListener, cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) (err error) {
 	var window timegrinder.TimestampWindow
 	window, err = cfg.GlobalTimestampWindow()
 	if err != nil {
 		err = fmt.Errorf("Failed to get global timestamp window: %v", err)
 		return err
 	}
-	for k, v := range cfg.RegexListener {
-		rhc := regexHandlerConfig{
-			name:             k,
-			wg:               wg,
-			ignoreTimestamps: v.Ignore_Timestamps,
-			setLocalTime:     v.Assume_Local_Timezone,
-			timezoneOverride: v.Timezone_Override,
-			ctx:              ctx,
-			formatOverride:   v.Timestamp_Format_Override,
-			timeFormats:      cfg.TimeFormat,
-			regex:            v.Regex,
-			trimWhitespace:   v.Trim_Whitespace,
-			maxBuffer:        v.Max_Buffer,
-			tsWindow:         window,
+	rhc := regexHandlerConfig{
+		name:             k,
+		ignoreTimestamps: v.Ignore_Timestamps,
+		setLocalTime:     v.Assume_Local_Timezone,
+		timezoneOverride: v.Timezone_Override,
+		ctx:              ls.ctx,
+		formatOverride:   v.Timestamp_Format_Override,
+		timeFormats:      cfg.TimeFormat,
+		regex:            v.Regex,
+		trimWhitespace:   v.Trim_Whitespace,
+		maxBuffer:        v.Max_Buffer,
+		tsWindow:         window,
+		formatLearning:   v.Timestamp_Format_Learning,
+		tstats:           ls.tstats,
+	}
+	if _, err = regexp.Compile(v.Regex); err != nil {
+		return err
+	}
+	if v.Source_Override != `` {
+		rhc.src = net.ParseIP(v.Source_Override)
+		if rhc.src == nil {
+			return fmt.Errorf("RegexListener %v invalid source override \"%s\"", k, v.Source_Override)
 		}
-		if rhc.proc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
-			lg.Fatal("preprocessor error", log.KVErr(err))
+	} else if cfg.Source_Override != `` {
+		// global override
+		rhc.src = net.ParseIP(cfg.Source_Override)
+		if rhc.src == nil {
+			return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
 		}
-		f.Add(rhc.proc)
-		if _, err = regexp.Compile(v.Regex); err != nil {
-			return err
+	}
+	//resolve default tag
+	if rhc.defTag, err = igst.GetTag(v.Tag_Name); err != nil {
+		return err
+	}
+
+	tp, str, err := translateBindType(v.Bind_String)
+	if err != nil {
+		return fmt.Errorf("RegexListener %s invalid bind %q: %w", k, v.Bind_String, err)
+	}
+
+	cs, err := ls.add(sectionRef{kind: regexListenerSection, name: k, conf: v}, v.Preprocessor, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			ls.stop(cs.key())
 		}
-		if v.Source_Override != `` {
-			rhc.src = net.ParseIP(v.Source_Override)
-			if rhc.src == nil {
-				return fmt.Errorf("RegexListener %v invalid source override \"%s\"", k, v.Source_Override)
-			}
-		} else if cfg.Source_Override != `` {
-			// global override
-			rhc.src = net.ParseIP(cfg.Source_Override)
-			if rhc.src == nil {
-				return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
-			}
+	}()
+	rhc.wg = cs
+	rhc.proc = cs.proc
+
+	if tp.TCP() {
+		//get the socket
+		addr, err := net.ResolveTCPAddr("tcp", str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
 		}
-		//resolve default tag
-		if rhc.defTag, err = igst.GetTag(v.Tag_Name); err != nil {
-			return err
+		l, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go regexAcceptor(l, connID, igst, rhc, tp)
+	} else if tp.TLS() {
+		config := &tls.Config{
+			MinVersion: tls.VersionTLS12,
 		}
 
-		tp, str, err := translateBindType(v.Bind_String)
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
 		if err != nil {
-			lg.FatalCode(0, "invalid bind", log.KV("bindstring", v.Bind_String), log.KVErr(err))
+			return fmt.Errorf("%s failed to load certificate %q and key %q: %w", k, v.Cert_File, v.Key_File, err)
 		}
-
-		if tp.TCP() {
-			//get the socket
-			addr, err := net.ResolveTCPAddr("tcp", str)
-			if err != nil {
-				return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
-			}
-			l, err := net.ListenTCP("tcp", addr)
-			if err != nil {
-				return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go regexAcceptor(l, connID, igst, rhc, tp)
-		} else if tp.TLS() {
-			config := &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			}
-
-			config.Certificates = make([]tls.Certificate, 1)
-			config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
-			if err != nil {
-				lg.Fatal("failed to load certificate", log.KV("certfile", v.Cert_File), log.KV("keyfile", v.Key_File), log.KVErr(err))
-			}
-			//get the socket
-			addr, err := net.ResolveTCPAddr("tcp", str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("regexlistener", k), log.KVErr(err))
-			}
-			l, err := tls.Listen("tcp", addr.String(), config)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via TLS", log.KV("address", addr), log.KV("regexlistener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go regexAcceptor(l, connID, igst, rhc, tp)
-		} else if tp.UDP() {
-			addr, err := net.ResolveUDPAddr(`udp`, str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("listener", k), log.KVErr(err))
-			}
-			l, err := net.ListenUDP(`udp`, addr)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via udp", log.KV("address", addr), log.KV("listener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			wg.Add(1)
-			go regexAcceptorUDP(l, connID, rhc, igst)
+		//get the socket
+		addr, err := net.ResolveTCPAddr("tcp", str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
 		}
-
+		l, err := tls.Listen("tcp", addr.String(), config)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via TLS on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go regexAcceptor(l, connID, igst, rhc, tp)
+	} else if tp.UDP() {
+		addr, err := net.ResolveUDPAddr(`udp`, str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
+		}
+		l, err := net.ListenUDP(`udp`, addr)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via udp on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		cs.Add(1)
+		go regexAcceptorUDP(l, connID, rhc, igst)
 	}
-	debugout("Started %d regex listeners\n", len(cfg.RegexListener))
 	return nil
 }
 
@@ -198,8 +209,9 @@ func regexAcceptorUDP(conn *net.UDPConn, id int, cfg regexHandlerConfig, igst *i
 
 	buff := make([]byte, 16*1024) //local buffer that should be big enough for even the largest UDP packets
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -212,6 +224,8 @@ func regexAcceptorUDP(conn *net.UDPConn, id int, cfg regexHandlerConfig, igst *i
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
@@ -266,7 +280,7 @@ func regexAcceptorUDP(conn *net.UDPConn, id int, cfg regexHandlerConfig, igst *i
 
 func regexConnHandler(c net.Conn, cfg regexHandlerConfig, igst *ingest.IngestMuxer) {
 	cfg.wg.Add(1)
-	id := addConn(c)
+	id := addConn(c, cfg.wg)
 	defer cfg.wg.Done()
 	defer delConn(id)
 	defer c.Close()
@@ -304,8 +318,9 @@ func regexConnHandler(c net.Conn, cfg regexHandlerConfig, igst *ingest.IngestMux
 	if !cfg.ignoreTimestamps {
 		var err error
 		tcfg := timegrinder.Config{
-			TSWindow:           cfg.tsWindow,
-			EnableLeftMostSeed: true,
+			TSWindow:             cfg.tsWindow,
+			EnableLeftMostSeed:   true,
+			EnableFormatLearning: cfg.formatLearning,
 		}
 		tg, err = timegrinder.NewTimeGrinder(tcfg)
 		if err != nil {
@@ -318,6 +333,8 @@ func regexConnHandler(c net.Conn, cfg regexHandlerConfig, igst *ingest.IngestMux
 		if cfg.setLocalTime {
 			tg.SetLocalTime()
 		}
+		cfg.tstats.Add(cfg.name, tg)
+		defer cfg.tstats.Remove(cfg.name, tg)
 		if cfg.timezoneOverride != `` {
 			err = tg.SetTimezone(cfg.timezoneOverride)
 			if err != nil {
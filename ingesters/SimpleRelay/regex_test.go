@@ -14,7 +14,6 @@ import (
 	"net"
 	"os"
 	"regexp"
-	"sync"
 	"testing"
 
 	"github.com/gravwell/gravwell/v3/ingest/entry"
@@ -26,7 +25,7 @@ import (
 func makeConfig() regexHandlerConfig {
 	cfg := regexHandlerConfig{
 
-		wg:  &sync.WaitGroup{},
+		wg:  &connSet{},
 		ctx: context.Background(),
 	}
 	return cfg
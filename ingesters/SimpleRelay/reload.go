@@ -0,0 +1,256 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+const (
+	listenerSection      = `Listener`
+	jsonListenerSection  = `JSONListener`
+	regexListenerSection = `RegexListener`
+
+	listenerStopTimeout = time.Second
+)
+
+var (
+	ErrListenerSetClosed = errors.New("listener set is closed")
+	ErrListenerRunning   = errors.New("listener is already running")
+)
+
+// sectionRef identifies a single listener section in the configuration
+type sectionRef struct {
+	kind string
+	name string
+	conf interface{}
+}
+
+func (sr sectionRef) key() string {
+	return fmt.Sprintf("%s %q", sr.kind, sr.name)
+}
+
+// connSet tracks the sockets, connections, and preprocessors owned by a single listener
+// so that it can be torn down without disturbing any other listener
+type connSet struct {
+	sync.WaitGroup
+	sectionRef
+	pp   []string
+	cfg  *cfgType //configuration the listener was started with
+	proc *processors.ProcessorSet
+}
+
+// listenerSet holds every running listener keyed on its section
+type listenerSet struct {
+	sync.Mutex
+	ctx     context.Context
+	igst    *ingest.IngestMuxer
+	running map[string]*connSet
+	tstats  *utils.TimestampStats
+	closed  bool
+}
+
+func newListenerSet(ctx context.Context, igst *ingest.IngestMuxer) *listenerSet {
+	return &listenerSet{
+		ctx:     ctx,
+		igst:    igst,
+		running: map[string]*connSet{},
+		tstats:  utils.NewTimestampStats(),
+	}
+}
+
+// add builds the preprocessor set for a listener and registers it as running
+func (ls *listenerSet) add(sr sectionRef, pp []string, cfg *cfgType) (cs *connSet, err error) {
+	cs = &connSet{
+		sectionRef: sr,
+		pp:         pp,
+		cfg:        cfg,
+	}
+	if cs.proc, err = cfg.Preprocessor.ProcessorSet(ls.igst, pp); err != nil {
+		err = fmt.Errorf("%s preprocessor error %w", sr.key(), err)
+		return
+	}
+	ls.Lock()
+	defer ls.Unlock()
+	if ls.closed {
+		err = ErrListenerSetClosed
+	} else if _, ok := ls.running[sr.key()]; ok {
+		err = ErrListenerRunning
+	}
+	if err != nil {
+		cs.proc.Close()
+		return
+	}
+	ls.running[sr.key()] = cs
+	return
+}
+
+// stop closes a listener and all of its connections, then flushes its preprocessors
+func (ls *listenerSet) stop(key string) (err error) {
+	ls.Lock()
+	cs, ok := ls.running[key]
+	delete(ls.running, key)
+	ls.Unlock()
+	if !ok {
+		return
+	}
+	closeConns(cs)
+	if !waitTimeout(&cs.WaitGroup, listenerStopTimeout) {
+		lg.Error("Failed to wait for listener connections to close", log.KV("listener", key), log.KV("timeout", listenerStopTimeout))
+	}
+	if err = cs.proc.Close(); err != nil {
+		err = fmt.Errorf("%s failed to close preprocessors %w", key, err)
+	}
+	return
+}
+
+// Close shuts down every listener, it is used when the ingester is exiting
+func (ls *listenerSet) Close() (err error) {
+	ls.Lock()
+	ls.closed = true
+	running := ls.running
+	ls.running = map[string]*connSet{}
+	ls.Unlock()
+
+	closeConns(nil)
+	//wait for everyone to exit with a timeout
+	wch := make(chan bool, 1)
+	go func() {
+		for _, cs := range running {
+			cs.Wait()
+		}
+		wch <- true
+	}()
+	select {
+	case <-wch:
+	case <-time.After(listenerStopTimeout):
+		lg.Error("Failed to wait for all connections to close", log.KV("timeout", listenerStopTimeout), log.KV("active", connCount()))
+	}
+	for _, cs := range running {
+		if lerr := cs.proc.Close(); lerr != nil {
+			err = addError(lerr, err)
+		}
+	}
+	return
+}
+
+// reload applies a new configuration, listeners whose sections, preprocessors, or time formats
+// are unchanged keep running so that their sockets are never closed.  The diff is taken against
+// the running listeners, so a listener that failed to start is retried on the next reload.
+func (ls *listenerSet) reload(oldObj, newObj interface{}) (err error) {
+	oldCfg, ok := oldObj.(*cfgType)
+	newCfg, nok := newObj.(*cfgType)
+	if !ok || !nok || newCfg == nil {
+		return fmt.Errorf("invalid configuration types %T %T", oldObj, newObj)
+	}
+	//global settings require a restart, keep running with the originals
+	newCfg.IngestConfig = oldCfg.IngestConfig
+
+	next := newCfg.sections()
+	ls.Lock()
+	current := make(map[string]sectionRef, len(ls.running))
+	for k, cs := range ls.running {
+		current[k] = cs.sectionRef
+	}
+	ls.Unlock()
+
+	d := base.DiffSections(current, next)
+	restart := d.Changed
+	for k := range next {
+		if _, ok := current[k]; !ok || slices.Contains(restart, k) {
+			continue
+		}
+		ls.Lock()
+		cs := ls.running[k]
+		ls.Unlock()
+		if cs != nil && (base.PreprocessorsChanged(cs.cfg.Preprocessor, newCfg.Preprocessor, cs.pp) ||
+			!reflect.DeepEqual(cs.cfg.TimeFormat, newCfg.TimeFormat)) {
+			restart = append(restart, k)
+		}
+	}
+
+	//stop first so that listeners moving between sections can take over their binds
+	for _, k := range append(d.Removed, restart...) {
+		if lerr := ls.stop(k); lerr != nil {
+			err = addError(lerr, err)
+		}
+		lg.Info("stopped listener", log.KV("listener", k))
+	}
+	var failed []string
+	for _, k := range append(restart, d.Added...) {
+		if lerr := ls.start(next[k], newCfg); lerr != nil {
+			err = addError(lerr, err)
+			failed = append(failed, k)
+			lg.Error("failed to start listener", log.KV("listener", k), log.KVErr(lerr))
+			continue
+		}
+		lg.Info("started listener", log.KV("listener", k))
+	}
+	if err != nil {
+		//everything else is running under the new configuration
+		err = &base.PartialReloadError{Failed: failed, Err: err}
+	}
+	return
+}
+
+func (ls *listenerSet) start(sr sectionRef, cfg *cfgType) error {
+	switch sr.kind {
+	case listenerSection:
+		return startSimpleListener(sr.name, sr.conf.(*listener), cfg, ls.igst, ls)
+	case jsonListenerSection:
+		return startJSONListener(sr.name, sr.conf.(*jsonListener), cfg, ls.igst, ls)
+	case regexListenerSection:
+		return startRegexListener(sr.name, sr.conf.(*regexListener), cfg, ls.igst, ls)
+	}
+	return fmt.Errorf("unknown listener section %s", sr.kind)
+}
+
+// sections returns every listener section in the config keyed the same way as a running listener
+func (c *cfgType) sections() map[string]sectionRef {
+	mp := make(map[string]sectionRef, len(c.Listener)+len(c.JSONListener)+len(c.RegexListener))
+	for k, v := range c.Listener {
+		sr := sectionRef{kind: listenerSection, name: k, conf: v}
+		mp[sr.key()] = sr
+	}
+	for k, v := range c.JSONListener {
+		sr := sectionRef{kind: jsonListenerSection, name: k, conf: v}
+		mp[sr.key()] = sr
+	}
+	for k, v := range c.RegexListener {
+		sr := sectionRef{kind: regexListenerSection, name: k, conf: v}
+		mp[sr.key()] = sr
+	}
+	return mp
+}
+
+func waitTimeout(wg *sync.WaitGroup, to time.Duration) bool {
+	wch := make(chan bool, 1)
+	go func() {
+		wg.Wait()
+		wch <- true
+	}()
+	select {
+	case <-wch:
+		return true
+	case <-time.After(to):
+	}
+	return false
+}
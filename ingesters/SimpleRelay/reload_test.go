@@ -0,0 +1,153 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+)
+
+const reloadGlobal = `
+[Global]
+Ingest-Secret=testing
+Cleartext-Backend-Target=127.0.0.1:1
+`
+
+func reloadConfig(t *testing.T, s string) *cfgType {
+	t.Helper()
+	var cr cfgReadType
+	if err := config.LoadConfigBytes(&cr, []byte(reloadGlobal+s)); err != nil {
+		t.Fatal(err)
+	}
+	c := &cfgType{
+		IngestConfig:  cr.Global,
+		Listener:      cr.Listener,
+		JSONListener:  cr.JSONListener,
+		RegexListener: cr.RegexListener,
+		Preprocessor:  cr.Preprocessor,
+		TimeFormat:    cr.TimeFormat,
+	}
+	if err := c.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestReloadListeners(t *testing.T) {
+	lg = log.NewDiscardLogger()
+	connClosers = make(map[int]trackedConn)
+	//the muxer is never started, it just needs to hand out tags
+	igst, err := ingest.NewUniformMuxer(ingest.UniformMuxerConfig{
+		Destinations: []string{`tcp://127.0.0.1:1`},
+		Tags:         []string{`a`, `b`, `c`},
+		Auth:         `testing`,
+		Logger:       lg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls := newListenerSet(context.Background(), igst)
+
+	c1 := reloadConfig(t, `
+	[Listener "a"]
+		Bind-String="udp://127.0.0.1:0"
+		Tag-Name=a
+	[Listener "b"]
+		Bind-String="127.0.0.1:0"
+		Tag-Name=b
+		Preprocessor=gz
+	[preprocessor "gz"]
+		Type=gzip
+	`)
+	if err := startSimpleListeners(c1, igst, ls); err != nil {
+		t.Fatal(err)
+	} else if len(ls.running) != 2 || connCount() != 2 {
+		t.Fatalf("bad running listeners %d %d", len(ls.running), connCount())
+	}
+	a, b := ls.running[`Listener "a"`], ls.running[`Listener "b"`]
+
+	//change a preprocessor referenced by b and add a JSON listener, a must not be touched
+	c2 := reloadConfig(t, `
+	[Listener "a"]
+		Bind-String="udp://127.0.0.1:0"
+		Tag-Name=a
+	[Listener "b"]
+		Bind-String="127.0.0.1:0"
+		Tag-Name=b
+		Preprocessor=gz
+	[JSONListener "c"]
+		Bind-String="tcp://127.0.0.1:0"
+		Default-Tag=c
+		Extractor=foo
+		Tag-Match=bar:a
+	[preprocessor "gz"]
+		Type=gzip
+		Passthrough-Non-Gzip=true
+	`)
+	if err := ls.reload(c1, c2); err != nil {
+		t.Fatal(err)
+	} else if len(ls.running) != 3 || connCount() != 3 {
+		t.Fatalf("bad running listeners %d %d", len(ls.running), connCount())
+	} else if ls.running[`Listener "a"`] != a {
+		t.Fatal("unchanged listener was restarted")
+	} else if ls.running[`Listener "b"`] == b {
+		t.Fatal("listener with a changed preprocessor was not restarted")
+	} else if ls.running[`JSONListener "c"`] == nil {
+		t.Fatal("new listener was not started")
+	}
+
+	//remove everything but a
+	c3 := reloadConfig(t, `
+	[Listener "a"]
+		Bind-String="udp://127.0.0.1:0"
+		Tag-Name=a
+	`)
+	if err := ls.reload(c2, c3); err != nil {
+		t.Fatal(err)
+	} else if len(ls.running) != 1 || connCount() != 1 || ls.running[`Listener "a"`] != a {
+		t.Fatalf("bad running listeners %d %d", len(ls.running), connCount())
+	}
+
+	//a listener that fails to start is retried on the next reload
+	c4 := reloadConfig(t, `
+	[Listener "a"]
+		Bind-String="udp://127.0.0.1:0"
+		Tag-Name=a
+	[Listener "d"]
+		Bind-String="127.0.0.1:0"
+		Tag-Name=notnegotiated
+	`)
+	var perr *base.PartialReloadError
+	if err := ls.reload(c3, c4); !errors.As(err, &perr) {
+		t.Fatalf("failed to catch unknown tag: %v", err)
+	} else if len(perr.Failed) != 1 || perr.Failed[0] != `Listener "d"` {
+		t.Fatalf("bad failed listeners %v", perr.Failed)
+	} else if len(ls.running) != 1 || connCount() != 1 {
+		t.Fatalf("bad running listeners %d %d", len(ls.running), connCount())
+	}
+	if _, err := igst.NegotiateTag(`notnegotiated`); err != nil {
+		t.Fatal(err)
+	} else if err = ls.reload(c4, c4); err != nil {
+		t.Fatal(err)
+	} else if len(ls.running) != 2 {
+		t.Fatalf("bad running listeners %d", len(ls.running))
+	}
+
+	if err := ls.Close(); err != nil {
+		t.Fatal(err)
+	} else if connCount() != 0 {
+		t.Fatalf("connections left open: %d", connCount())
+	}
+}
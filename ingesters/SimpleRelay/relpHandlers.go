@@ -0,0 +1,332 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+// RELP framing, see https://www.rsyslog.com/doc/relp.html
+//
+//	TXNR SP COMMAND SP DATALEN [SP DATA] LF
+const (
+	relpMaxTxnr    = 999999999
+	relpMaxCommand = 32
+	relpMaxDataLen = maxDataSize
+
+	relpCmdOpen        = `open`
+	relpCmdClose       = `close`
+	relpCmdSyslog      = `syslog`
+	relpCmdRsp         = `rsp`
+	relpCmdServerClose = `serverclose`
+
+	relpVersion  = `0`
+	relpSoftware = `gravwell-simplerelay`
+)
+
+var (
+	errRELPBadFrame    = errors.New("malformed RELP frame")
+	errRELPFrameSize   = errors.New("RELP frame exceeds maximum size")
+	errRELPNotOpen     = errors.New("RELP command received before open")
+	errRELPNoSyslog    = errors.New("RELP client does not offer the syslog command")
+	errRELPUnsupported = errors.New("unsupported RELP command")
+)
+
+type relpFrame struct {
+	txnr uint64
+	cmd  string
+	data []byte
+}
+
+// relpSession speaks the server side of RELP, handle is called for each syslog frame and
+// the frame is only acknowledged when handle returns without error.  An error from handle
+// fails the frame and closes the session so the client resends it, handle should only
+// return errors that a resend can fix.
+type relpSession struct {
+	br     *bufio.Reader
+	bw     *bufio.Writer
+	handle func([]byte) error
+	open   bool
+}
+
+func newRELPSession(rw io.ReadWriter, handle func([]byte) error) *relpSession {
+	return &relpSession{
+		br:     bufio.NewReaderSize(rw, initDataSize),
+		bw:     bufio.NewWriter(rw),
+		handle: handle,
+	}
+}
+
+// run services the session until the client closes it, the connection drops, or handle fails.
+// A nil return means the session closed cleanly.
+func (s *relpSession) run() (err error) {
+	for {
+		var f relpFrame
+		if f, err = readRELPFrame(s.br); err != nil {
+			if err == io.EOF {
+				err = nil
+			} else if errors.Is(err, errRELPBadFrame) || errors.Is(err, errRELPFrameSize) {
+				s.serverClose()
+			}
+			return
+		}
+		var done bool
+		if done, err = s.command(f); err != nil || done {
+			return
+		}
+		//hold acks until we have drained what the client already sent, rsyslog windows its frames
+		if s.br.Buffered() == 0 {
+			if err = s.bw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *relpSession) command(f relpFrame) (done bool, err error) {
+	if !s.open && f.cmd != relpCmdOpen {
+		s.respond(f.txnr, `500 `+errRELPNotOpen.Error())
+		s.serverClose()
+		return true, errRELPNotOpen
+	}
+	switch f.cmd {
+	case relpCmdOpen:
+		if !relpOffersSyslog(f.data) {
+			s.respond(f.txnr, `500 `+errRELPNoSyslog.Error())
+			s.serverClose()
+			return true, errRELPNoSyslog
+		}
+		s.open = true
+		s.respond(f.txnr, "200 OK\nrelp_version="+relpVersion+"\nrelp_software="+relpSoftware+"\ncommands="+relpCmdSyslog)
+	case relpCmdSyslog:
+		if err = s.handle(f.data); err != nil {
+			//the client will resend anything we didn't ack when it reconnects
+			s.respond(f.txnr, `500 failed to ingest message`)
+			s.serverClose()
+			return true, err
+		}
+		s.respond(f.txnr, `200 OK`)
+	case relpCmdClose:
+		s.respond(f.txnr, ``)
+		s.serverClose()
+		return true, nil
+	default:
+		s.respond(f.txnr, `500 `+errRELPUnsupported.Error()+` `+f.cmd)
+	}
+	return
+}
+
+func (s *relpSession) respond(txnr uint64, data string) {
+	writeRELPFrame(s.bw, txnr, relpCmdRsp, []byte(data))
+}
+
+// serverClose tells the client we are hanging up and flushes anything outstanding
+func (s *relpSession) serverClose() {
+	writeRELPFrame(s.bw, 0, relpCmdServerClose, nil)
+	s.bw.Flush()
+}
+
+// relpOffersSyslog checks the commands offer in an open frame, clients that don't send one get the benefit of the doubt
+func relpOffersSyslog(data []byte) bool {
+	for _, ln := range bytes.Split(data, []byte("\n")) {
+		if cmds, ok := bytes.CutPrefix(ln, []byte(`commands=`)); ok {
+			for _, c := range bytes.Split(cmds, []byte(`,`)) {
+				if string(bytes.TrimSpace(c)) == relpCmdSyslog {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func readRELPFrame(br *bufio.Reader) (f relpFrame, err error) {
+	var tok []byte
+	var term byte
+	//tolerate stray line breaks between frames
+	for {
+		var b byte
+		if b, err = br.ReadByte(); err != nil {
+			return
+		} else if b != '\n' && b != '\r' {
+			br.UnreadByte()
+			break
+		}
+	}
+	if tok, term, err = readRELPToken(br, 9); err != nil {
+		return
+	} else if term != ' ' {
+		err = errRELPBadFrame
+		return
+	} else if f.txnr, err = strconv.ParseUint(string(tok), 10, 32); err != nil || f.txnr > relpMaxTxnr {
+		err = errRELPBadFrame
+		return
+	}
+	if tok, term, err = readRELPToken(br, relpMaxCommand); err != nil {
+		return
+	} else if term != ' ' || len(tok) == 0 {
+		err = errRELPBadFrame
+		return
+	}
+	f.cmd = string(tok)
+	if tok, term, err = readRELPToken(br, 9); err != nil {
+		return
+	}
+	datalen, perr := strconv.ParseUint(string(tok), 10, 32)
+	if perr != nil {
+		err = errRELPBadFrame
+		return
+	} else if datalen > uint64(relpMaxDataLen) {
+		err = errRELPFrameSize
+		return
+	}
+	if datalen > 0 {
+		if term != ' ' {
+			err = errRELPBadFrame
+			return
+		}
+		f.data = make([]byte, datalen)
+		if _, err = io.ReadFull(br, f.data); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		term = 0
+	}
+	//the trailer, empty frames may have already consumed it
+	if term != '\n' {
+		var b byte
+		if b, err = br.ReadByte(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		} else if b != '\n' {
+			err = errRELPBadFrame
+		}
+	}
+	return
+}
+
+// readRELPToken reads up to max bytes terminated by a space or line feed
+func readRELPToken(br *bufio.Reader, max int) (tok []byte, term byte, err error) {
+	for {
+		var b byte
+		if b, err = br.ReadByte(); err != nil {
+			if err == io.EOF && len(tok) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		if b == ' ' || b == '\n' {
+			term = b
+			return
+		} else if len(tok) >= max {
+			err = errRELPBadFrame
+			return
+		}
+		tok = append(tok, b)
+	}
+}
+
+func writeRELPFrame(w io.Writer, txnr uint64, cmd string, data []byte) error {
+	if len(data) == 0 {
+		_, err := fmt.Fprintf(w, "%d %s 0\n", txnr, cmd)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d %s %d %s\n", txnr, cmd, len(data), data)
+	return err
+}
+
+func relpConnHandlerTCP(c net.Conn, cfg handlerConfig) {
+	cfg.wg.Add(1)
+	id := addConn(c, cfg.wg)
+	defer cfg.wg.Done()
+	defer delConn(id)
+	defer c.Close()
+	var rip net.IP
+
+	if cfg.src == nil {
+		ipstr, _, err := net.SplitHostPort(c.RemoteAddr().String())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get host from rmote addr \"%s\": %v\n", c.RemoteAddr().String(), err)
+			return
+		}
+		if rip = net.ParseIP(ipstr); rip == nil {
+			fmt.Fprintf(os.Stderr, "Failed to get remote addr from \"%s\"\n", ipstr)
+			return
+		}
+	} else {
+		rip = cfg.src
+	}
+
+	tcfg := timegrinder.Config{
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
+	}
+	tg, err := timegrinder.NewTimeGrinder(tcfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get a handle on the timegrinder: %v\n", err)
+		return
+	} else if err = cfg.timeFormats.LoadFormats(tg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load custom time formats: %v\n", err)
+		return
+	}
+	if cfg.setLocalTime {
+		tg.SetLocalTime()
+	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
+	if cfg.timezoneOverride != `` {
+		err = tg.SetTimezone(cfg.timezoneOverride)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set timezone to %v: %v\n", cfg.timezoneOverride, err)
+			return
+		}
+	}
+	if cfg.formatOverride != `` {
+		if err = tg.SetFormatOverride(cfg.formatOverride); err != nil {
+			lg.Error("Failed to load format override", log.KV("override", cfg.formatOverride), log.KVErr(err))
+			return
+		}
+	}
+
+	sess := newRELPSession(c, func(b []byte) error {
+		data := bytes.Trim(b, "\n\r\t \x00")
+		if cfg.dropPriority {
+			data = dropPriority(data)
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		ent, err := handleLog(data, rip, cfg.ignoreTimestamps, cfg.tag, tg)
+		if err != nil {
+			//a resend would fail the same way, ack it so the client moves on
+			lg.Warn("dropping RELP message", log.KV("address", c.RemoteAddr()), log.KV("listener", cfg.name), log.KVErr(err))
+			return nil
+		}
+		//the entry is only acked once the muxer has taken it
+		return cfg.proc.ProcessContext(ent, cfg.ctx)
+	})
+	if err = sess.run(); err != nil && cfg.ctx.Err() == nil {
+		lg.Warn("RELP session ended", log.KV("address", c.RemoteAddr()), log.KV("listener", cfg.name), log.KVErr(err))
+	}
+}
@@ -0,0 +1,143 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRELPFrames(t *testing.T) {
+	good := []struct {
+		val  string
+		txnr uint64
+		cmd  string
+		data string
+	}{
+		{val: "1 open 14 relp_version=0\n", txnr: 1, cmd: `open`, data: `relp_version=0`},
+		{val: "2 syslog 9 <13>hello\n", txnr: 2, cmd: `syslog`, data: `<13>hello`},
+		{val: "\n3 syslog 4 a\nb\n\n", txnr: 3, cmd: `syslog`, data: "a\nb\n"},
+		{val: "4 close 0\n", txnr: 4, cmd: `close`},
+		{val: "5 close 0 \n", txnr: 5, cmd: `close`},
+	}
+	for _, tst := range good {
+		f, err := readRELPFrame(bufio.NewReader(strings.NewReader(tst.val)))
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", tst.val, err)
+		} else if f.txnr != tst.txnr || f.cmd != tst.cmd || string(f.data) != tst.data {
+			t.Fatalf("bad frame from %q: %+v", tst.val, f)
+		}
+	}
+	bad := []string{
+		"x syslog 1 a\n",
+		"1 syslog 5 a\n",
+		"1 syslog 1 ab\n",
+		"1 syslog\n",
+		"1234567890 syslog 1 a\n",
+		"1 syslog 99999999999 a\n",
+	}
+	for _, v := range bad {
+		if _, err := readRELPFrame(bufio.NewReader(strings.NewReader(v))); err == nil {
+			t.Fatalf("failed to catch bad frame %q", v)
+		}
+	}
+	if _, err := readRELPFrame(bufio.NewReader(strings.NewReader(``))); err != io.EOF {
+		t.Fatalf("bad error on empty stream: %v", err)
+	}
+}
+
+// relpFrames builds an open frame followed by a syslog frame for each message
+func relpFrames(offers string, msgs ...string) string {
+	var sb strings.Builder
+	writeRELPFrame(&sb, 1, relpCmdOpen, []byte(offers))
+	for i, m := range msgs {
+		writeRELPFrame(&sb, uint64(i+2), relpCmdSyslog, []byte(m))
+	}
+	return sb.String()
+}
+
+func relpExchange(frames string, handle func([]byte) error) (rsps []relpFrame, err error) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- newRELPSession(srv, handle).run()
+		srv.Close()
+	}()
+	go io.WriteString(cli, frames)
+	br := bufio.NewReader(cli)
+	for {
+		f, rerr := readRELPFrame(br)
+		if rerr != nil {
+			break
+		}
+		rsps = append(rsps, f)
+		if f.cmd == relpCmdServerClose {
+			break
+		}
+	}
+	cli.Close()
+	err = <-done
+	return
+}
+
+func TestRELPSession(t *testing.T) {
+	var got []string
+	frames := relpFrames("relp_version=0\ncommands=syslog,foo", `first`, `second`) + "4 close 0\n"
+	rsps, err := relpExchange(frames, func(b []byte) error {
+		got = append(got, string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got) != 2 || got[0] != `first` || got[1] != `second` {
+		t.Fatalf("bad messages %v", got)
+	} else if len(rsps) != 5 {
+		t.Fatalf("bad response count %d", len(rsps))
+	}
+	for i, f := range rsps[:4] {
+		if f.txnr != uint64(i+1) || f.cmd != relpCmdRsp {
+			t.Fatalf("bad response %d %+v", i, f)
+		}
+	}
+	if !strings.HasPrefix(string(rsps[0].data), "200 OK\n") || string(rsps[1].data) != `200 OK` || len(rsps[3].data) != 0 {
+		t.Fatalf("bad response data %q %q %q", rsps[0].data, rsps[1].data, rsps[3].data)
+	} else if rsps[4].cmd != relpCmdServerClose {
+		t.Fatalf("missing serverclose %+v", rsps[4])
+	}
+
+	//a message the muxer won't take must not be acked
+	errTest := errors.New("test")
+	rsps, err = relpExchange(relpFrames(`relp_version=0`, `a`, `b`), func(b []byte) error {
+		if string(b) == `b` {
+			return errTest
+		}
+		return nil
+	})
+	if err != errTest {
+		t.Fatalf("bad session error %v", err)
+	} else if len(rsps) != 4 || string(rsps[1].data) != `200 OK` || !strings.HasPrefix(string(rsps[2].data), `500 `) || rsps[3].cmd != relpCmdServerClose {
+		t.Fatalf("bad responses %+v", rsps)
+	}
+
+	//syslog before open and clients that can't send syslog are refused
+	for _, v := range []string{"1 syslog 1 a\n", relpFrames(`commands=foo`)} {
+		rsps, err = relpExchange(v, func([]byte) error {
+			t.Error("handler called on a refused session")
+			return nil
+		})
+		if err == nil || len(rsps) != 2 || !strings.HasPrefix(string(rsps[0].data), `500 `) {
+			t.Fatalf("bad refusal of %q: %v %+v", v, err, rsps)
+		}
+	}
+}
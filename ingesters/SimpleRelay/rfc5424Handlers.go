@@ -34,7 +34,7 @@ const (
 
 func rfc5424ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	cfg.wg.Add(1)
-	id := addConn(c)
+	id := addConn(c, cfg.wg)
 	defer cfg.wg.Done()
 	defer delConn(id)
 	defer c.Close()
@@ -56,8 +56,9 @@ func rfc5424ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	}
 
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -71,6 +72,8 @@ func rfc5424ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
@@ -168,8 +171,9 @@ func dropPriority(buff []byte) []byte {
 func rfc5424ConnHandlerUDP(c *net.UDPConn, cfg handlerConfig) {
 	buff := make([]byte, 16*1024) //local buffer that should be big enough for even the largest UDP packets
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -183,6 +187,8 @@ func rfc5424ConnHandlerUDP(c *net.UDPConn, cfg handlerConfig) {
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
@@ -59,7 +59,7 @@ func findGoodRFC6587Header(b []byte) (number, start, end int) {
 
 func rfc6587ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	cfg.wg.Add(1)
-	id := addConn(c)
+	id := addConn(c, cfg.wg)
 	defer cfg.wg.Done()
 	defer delConn(id)
 	defer c.Close()
@@ -81,8 +81,9 @@ func rfc6587ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	}
 
 	tcfg := timegrinder.Config{
-		TSWindow:           cfg.tsWindow,
-		EnableLeftMostSeed: true,
+		TSWindow:             cfg.tsWindow,
+		EnableLeftMostSeed:   true,
+		EnableFormatLearning: cfg.formatLearning,
 	}
 	tg, err := timegrinder.NewTimeGrinder(tcfg)
 	if err != nil {
@@ -96,6 +97,8 @@ func rfc6587ConnHandlerTCP(c net.Conn, cfg handlerConfig) {
 	if cfg.setLocalTime {
 		tg.SetLocalTime()
 	}
+	cfg.tstats.Add(cfg.name, tg)
+	defer cfg.tstats.Remove(cfg.name, tg)
 	if cfg.timezoneOverride != `` {
 		err = tg.SetTimezone(cfg.timezoneOverride)
 		if err != nil {
@@ -22,11 +22,12 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/log"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
 	"github.com/gravwell/gravwell/v3/timegrinder"
 )
 
 var (
-	connClosers map[int]closer
+	connClosers map[int]trackedConn
 	connId      int
 	mtx         sync.Mutex
 )
@@ -35,6 +36,12 @@ type closer interface {
 	Close() error
 }
 
+// trackedConn is a socket or connection along with the listener that owns it
+type trackedConn struct {
+	closer
+	owner *connSet
+}
+
 type handlerConfig struct {
 	name             string
 	tag              entry.EntryTag
@@ -44,124 +51,142 @@ type handlerConfig struct {
 	dropPriority     bool
 	timezoneOverride string
 	src              net.IP
-	wg               *sync.WaitGroup
+	wg               *connSet
 	formatOverride   string
 	proc             *processors.ProcessorSet
 	ctx              context.Context
 	timeFormats      config.CustomTimeFormat
 	tsWindow         timegrinder.TimestampWindow
+	formatLearning   bool
+	tstats           *utils.TimestampStats
 }
 
-func startSimpleListeners(cfg *cfgType, igst *ingest.IngestMuxer, wg *sync.WaitGroup, f *flusher, ctx context.Context) error {
-	//short circuit out on empty
-	if len(cfg.Listener) == 0 {
-		return nil
+func startSimpleListeners(cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) error {
+	//fire up our simple backends
+	for k, v := range cfg.Listener {
+		if err := startSimpleListener(k, v, cfg, igst, ls); err != nil {
+			return err
+		}
 	}
+	debugout("Started %d listeners\n", len(cfg.Listener))
+	return nil
+}
+
+func startSimpleListener(k string, v *listener, cfg *cfgType, igst *ingest.IngestMuxer, ls *listenerSet) (err error) {
 	window, err := cfg.GlobalTimestampWindow()
 	if err != nil {
 		err = fmt.Errorf("Failed to get global timestamp window: %v", err)
 		return err
 	}
-	//fire up our simple backends
-	for k, v := range cfg.Listener {
-		var src net.IP
-		if v.Source_Override != `` {
-			src = net.ParseIP(v.Source_Override)
-			if src == nil {
-				return fmt.Errorf("Listener %v invalid source override \"%s\"", k, v.Source_Override)
-			}
-		} else if cfg.Source_Override != `` {
-			// global override
-			src = net.ParseIP(cfg.Source_Override)
-			if src == nil {
-				return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
-			}
+	var src net.IP
+	if v.Source_Override != `` {
+		src = net.ParseIP(v.Source_Override)
+		if src == nil {
+			return fmt.Errorf("Listener %v invalid source override \"%s\"", k, v.Source_Override)
 		}
-		//get the tag for this listener
-		tag, err := igst.GetTag(v.Tag_Name)
+	} else if cfg.Source_Override != `` {
+		// global override
+		src = net.ParseIP(cfg.Source_Override)
+		if src == nil {
+			return fmt.Errorf("global source override \"%s\" is invalid", cfg.Source_Override)
+		}
+	}
+	//get the tag for this listener
+	tag, err := igst.GetTag(v.Tag_Name)
+	if err != nil {
+		return fmt.Errorf("Listener %s failed to resolve tag %q: %w", k, v.Tag_Name, err)
+	}
+	tp, str, err := translateBindType(v.Bind_String)
+	if err != nil {
+		return fmt.Errorf("Listener %s invalid bind %q: %w", k, v.Bind_String, err)
+	}
+	lrt, err := translateReaderType(v.Reader_Type)
+	if err != nil {
+		return fmt.Errorf("Listener %s invalid reader type %q: %w", k, v.Reader_Type, err)
+	}
+	if lrt == relpReader && cfg.Ingest_Cache_Path == `` {
+		//RELP acks a message once the muxer takes it, without a cache nothing holds it across a restart
+		lg.Warn("RELP listener has no ingest cache, acknowledged messages are lost if the ingester stops before sending them",
+			log.KV("listener", k))
+	}
+
+	cs, err := ls.add(sectionRef{kind: listenerSection, name: k, conf: v}, v.Preprocessor, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if err != nil {
-			lg.Fatal("failed to resolve tag", log.KV("tag", v.Tag_Name), log.KVErr(err))
+			ls.stop(cs.key())
 		}
-		tp, str, err := translateBindType(v.Bind_String)
+	}()
+	hcfg := handlerConfig{
+		name:             k,
+		tag:              tag,
+		lrt:              lrt,
+		ignoreTimestamps: v.Ignore_Timestamps,
+		setLocalTime:     v.Assume_Local_Timezone,
+		dropPriority:     v.Drop_Priority,
+		timezoneOverride: v.Timezone_Override,
+		src:              src,
+		wg:               cs,
+		formatOverride:   v.Timestamp_Format_Override,
+		proc:             cs.proc,
+		ctx:              ls.ctx,
+		timeFormats:      cfg.TimeFormat,
+		tsWindow:         window,
+		formatLearning:   v.Timestamp_Format_Learning,
+		tstats:           ls.tstats,
+	}
+	if tp.TCP() {
+		//get the socket
+		addr, err := net.ResolveTCPAddr(tp.String(), str)
 		if err != nil {
-			lg.FatalCode(0, "invalid bind", log.KV("bindstring", v.Bind_String), log.KVErr(err))
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
 		}
-		lrt, err := translateReaderType(v.Reader_Type)
+		l, err := net.ListenTCP(tp.String(), addr)
 		if err != nil {
-			lg.FatalCode(0, "invalid reader type", log.KV("readertype", v.Reader_Type), log.KVErr(err))
+			return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go acceptor(l, connID, igst, hcfg, tp)
+	} else if tp.TLS() {
+		config := &tls.Config{
+			MinVersion: tls.VersionTLS12,
 		}
 
-		hcfg := handlerConfig{
-			name:             k,
-			tag:              tag,
-			lrt:              lrt,
-			ignoreTimestamps: v.Ignore_Timestamps,
-			setLocalTime:     v.Assume_Local_Timezone,
-			dropPriority:     v.Drop_Priority,
-			timezoneOverride: v.Timezone_Override,
-			src:              src,
-			wg:               wg,
-			formatOverride:   v.Timestamp_Format_Override,
-			ctx:              ctx,
-			timeFormats:      cfg.TimeFormat,
-			tsWindow:         window,
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
+		if err != nil {
+			return fmt.Errorf("%s failed to load certificate %q and key %q: %w", k, v.Cert_File, v.Key_File, err)
 		}
-		if hcfg.proc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
-			lg.Fatal("preprocessor error", log.KVErr(err))
+		//get the socket
+		addr, err := net.ResolveTCPAddr("tcp", str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
 		}
-		f.Add(hcfg.proc)
-		if tp.TCP() {
-			//get the socket
-			addr, err := net.ResolveTCPAddr(tp.String(), str)
-			if err != nil {
-				return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
-			}
-			l, err := net.ListenTCP(tp.String(), addr)
-			if err != nil {
-				return fmt.Errorf("%s Failed to listen on \"%s\": %v\n", k, addr, err)
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go acceptor(l, connID, igst, hcfg, tp)
-		} else if tp.TLS() {
-			config := &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			}
-
-			config.Certificates = make([]tls.Certificate, 1)
-			config.Certificates[0], err = tls.LoadX509KeyPair(v.Cert_File, v.Key_File)
-			if err != nil {
-				lg.FatalCode(0, "failed to load certificate", log.KV("certfile", v.Cert_File), log.KV("keyfile", v.Key_File), log.KVErr(err))
-			}
-			//get the socket
-			addr, err := net.ResolveTCPAddr("tcp", str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("listener", k), log.KVErr(err))
-			}
-			l, err := tls.Listen("tcp", addr.String(), config)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via TLS", log.KV("address", addr), log.KV("listener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			//start the acceptor
-			wg.Add(1)
-			go acceptor(l, connID, igst, hcfg, tp)
-		} else if tp.UDP() {
-			addr, err := net.ResolveUDPAddr(tp.String(), str)
-			if err != nil {
-				lg.FatalCode(0, "invalid Bind-String", log.KV("bindstring", v.Bind_String), log.KV("listener", k), log.KVErr(err))
-			}
-			l, err := net.ListenUDP(tp.String(), addr)
-			if err != nil {
-				lg.FatalCode(0, "failed to listen via udp", log.KV("address", addr), log.KV("listener", k), log.KVErr(err))
-			}
-			connID := addConn(l)
-			wg.Add(1)
-			go acceptorUDP(l, connID, hcfg, igst)
+		l, err := tls.Listen("tcp", addr.String(), config)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via TLS on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		//start the acceptor
+		cs.Add(1)
+		go acceptor(l, connID, igst, hcfg, tp)
+	} else if tp.UDP() {
+		addr, err := net.ResolveUDPAddr(tp.String(), str)
+		if err != nil {
+			return fmt.Errorf("%s Bind-String \"%s\" is invalid: %v\n", k, v.Bind_String, err)
 		}
+		l, err := net.ListenUDP(tp.String(), addr)
+		if err != nil {
+			return fmt.Errorf("%s Failed to listen via udp on \"%s\": %v\n", k, addr, err)
+		}
+		connID := addConn(l, cs)
+		cs.Add(1)
+		go acceptorUDP(l, connID, hcfg, igst)
 	}
-	debugout("Started %d listeners\n", len(cfg.Listener))
 	return nil
 }
 
@@ -194,6 +219,8 @@ func acceptor(lst net.Listener, id int, igst *ingest.IngestMuxer, cfg handlerCon
 			go rfc5424ConnHandlerTCP(conn, cfg)
 		case rfc6587Reader:
 			go rfc6587ConnHandlerTCP(conn, cfg)
+		case relpReader:
+			go relpConnHandlerTCP(conn, cfg)
 		default:
 			lg.Error("invalid reader type", log.KV("readertype", cfg.lrt))
 			return
@@ -225,7 +252,13 @@ func handleLog(b []byte, ip net.IP, ignoreTS bool, tag entry.EntryTag, tg *timeg
 	var ts entry.Timestamp
 	var extracted time.Time
 	if !ignoreTS {
-		if extracted, ok, err = tg.Extract(b); err != nil {
+		if tg.EnableFormatLearning && ip != nil {
+			//UDP listeners share a timegrinder across senders, learn the format of each one
+			extracted, ok, err = tg.ExtractKey(timegrinder.FormatKey{Source: ip.String()}, b)
+		} else {
+			extracted, ok, err = tg.Extract(b)
+		}
+		if err != nil {
 			return
 		}
 		if ok {
@@ -245,11 +278,11 @@ func handleLog(b []byte, ip net.IP, ignoreTS bool, tag entry.EntryTag, tg *timeg
 	return
 }
 
-func addConn(c closer) int {
+func addConn(c closer, owner *connSet) int {
 	mtx.Lock()
 	connId++
 	id := connId
-	connClosers[connId] = c
+	connClosers[connId] = trackedConn{closer: c, owner: owner}
 	mtx.Unlock()
 	return id
 }
@@ -260,6 +293,17 @@ func delConn(id int) {
 	mtx.Unlock()
 }
 
+// closeConns closes every socket and connection owned by a listener, a nil owner closes everything
+func closeConns(owner *connSet) {
+	mtx.Lock()
+	for _, v := range connClosers {
+		if owner == nil || v.owner == owner {
+			v.Close()
+		}
+	}
+	mtx.Unlock() //must unlock so they can delete their connections
+}
+
 func connCount() int {
 	mtx.Lock()
 	defer mtx.Unlock()
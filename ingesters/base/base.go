@@ -230,46 +230,18 @@ func (ib *IngesterBase) GetMuxer() (igst *ingest.IngestMuxer, err error) {
 	}
 	cfg := ch.IngestBaseConfig()
 
-	conns, err := cfg.Targets()
-	if err != nil {
-		ib.Logger.FatalCode(0, "failed to get backend targets from configuration", log.KVErr(err))
-		return
-	}
-	ib.Debug("Handling %d tags over %d targets\n", len(tags), len(conns))
-
-	lmt, err := cfg.RateLimit()
-	if err != nil {
-		ib.Logger.FatalCode(0, "failed to get rate limit from configuration", log.KVErr(err))
-		return
-	}
-	ib.Debug("Rate limiting connection to %d bps\n", lmt)
-
 	//fire up the ingesters
 	ib.Debug("INSECURE skip TLS certificate verification: %v\n", cfg.InsecureSkipTLSVerification())
-	id, ok := cfg.IngesterUUID()
-	if !ok {
-		id = uuid.Nil //set to the zero UUID, we attempt to write one back during init, but if that fails... just use zero
-	}
-	ib.id = id
-	igCfg := ingest.UniformMuxerConfig{
-		IngestStreamConfig: cfg.IngestStreamConfig,
-		Destinations:       conns,
-		Tags:               tags,
-		Auth:               cfg.Secret(),
-		VerifyCert:         !cfg.InsecureSkipTLSVerification(),
-		IngesterName:       ib.IngesterName,
-		IngesterVersion:    version.GetVersion(),
-		IngesterUUID:       id.String(),
-		IngesterLabel:      cfg.Label,
-		RateLimitBps:       lmt,
-		Logger:             ib.Logger,
-		CacheDepth:         cfg.Cache_Depth,
-		CachePath:          cfg.Ingest_Cache_Path,
-		CacheSize:          cfg.Max_Ingest_Cache,
-		CacheMode:          cfg.Cache_Mode,
-		LogSourceOverride:  net.ParseIP(cfg.Log_Source_Override),
-		Attach:             ch.AttachConfig(),
+	igCfg, err := MuxerConfig(cfg, tags, ib.IngesterName)
+	if err != nil {
+		ib.Logger.FatalCode(0, "failed to get ingest configuration", log.KVErr(err))
+		return
 	}
+	ib.Debug("Handling %d tags over %d targets\n", len(tags), len(igCfg.Destinations))
+	ib.Debug("Rate limiting connection to %d bps\n", igCfg.RateLimitBps)
+	ib.id, _ = cfg.IngesterUUID() //the zero UUID if unset, we attempt to write one back during init, but if that fails... just use zero
+	igCfg.Logger = ib.Logger
+	igCfg.Attach = ch.AttachConfig()
 	if igst, err = ingest.NewUniformMuxer(igCfg); err != nil {
 		ib.Logger.Fatal("failed to build our ingest system", log.KVErr(err))
 		return
@@ -296,6 +268,42 @@ func (ib *IngesterBase) GetMuxer() (igst *ingest.IngestMuxer, err error) {
 	return
 }
 
+// MuxerConfig builds a muxer configuration from the global ingest configuration so that every
+// ingester honors the same set of options, callers fill in the Logger and Attach config.
+func MuxerConfig(cfg config.IngestConfig, tags []string, name string) (umc ingest.UniformMuxerConfig, err error) {
+	umc = ingest.UniformMuxerConfig{
+		IngestStreamConfig: cfg.IngestStreamConfig,
+		Tags:               tags,
+		Auth:               cfg.Secret(),
+		VerifyCert:         !cfg.InsecureSkipTLSVerification(),
+		PublicKey:          cfg.Client_Certificate,
+		PrivateKey:         cfg.Client_Key,
+		IngesterName:       name,
+		IngesterVersion:    version.GetVersion(),
+		IngesterLabel:      cfg.Label,
+		CacheDepth:         cfg.Cache_Depth,
+		CachePath:          cfg.Ingest_Cache_Path,
+		CacheSize:          cfg.Max_Ingest_Cache,
+		CacheMode:          cfg.Cache_Mode,
+		LogSourceOverride:  net.ParseIP(cfg.Log_Source_Override),
+		Replicate:          cfg.Replicate,
+		ReplicationQuorum:  cfg.Replication_Quorum,
+		MetricsAddress:     cfg.Metrics_Listen_Address,
+	}
+	id, _ := cfg.IngesterUUID() //the zero UUID if unset
+	umc.IngesterUUID = id.String()
+	if umc.Destinations, err = cfg.Targets(); err != nil {
+		err = fmt.Errorf("failed to get backend targets from configuration %w", err)
+	} else if umc.RateLimitBps, err = cfg.RateLimit(); err != nil {
+		err = fmt.Errorf("failed to get rate limit from configuration %w", err)
+	} else if umc.TargetGroups, err = cfg.TargetGroups(); err != nil {
+		err = fmt.Errorf("failed to get target groups from configuration %w", err)
+	} else if umc.TagPolicies, err = cfg.TagPolicies(); err != nil {
+		err = fmt.Errorf("failed to get tag policies from configuration %w", err)
+	}
+	return
+}
+
 func (ib *IngesterBase) Debug(format string, args ...interface{}) {
 	if ib.Verbose {
 		fmt.Printf(format, args...)
@@ -509,3 +517,13 @@ func verifyConfig(obj interface{}) (err error) {
 	}
 	return
 }
+
+// ConfigLayout is embedded in an ingester configuration to report the structure the configuration
+// file is read into, config validation uses it to lint the file and generate its schema.
+type ConfigLayout[T any] struct{}
+
+// ConfigReadType returns a zero value of the structure that describes the configuration file layout
+func (ConfigLayout[T]) ConfigReadType() interface{} {
+	var v T
+	return v
+}
@@ -0,0 +1,219 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+)
+
+const (
+	overlayDirPerms  os.FileMode = 0750
+	overlayFilePerms os.FileMode = 0640
+	overlayTempGlob              = `.overlay-*.tmp`      //never ends in .conf, so a partial write is never loaded
+	overlayStateFile             = `.overlay-state.json` //overlay names cannot start with a dot, so this cannot be overwritten
+)
+
+var (
+	ErrNoOverlayDir    = errors.New("no configuration overlay directory is configured")
+	ErrNoOverlayKeys   = errors.New("no Config-Overlay-Key is configured")
+	ErrStaleOverlay    = errors.New("configuration overlay was not issued after the last applied overlay")
+	ErrOverlayNoConfig = errors.New("configuration does not provide a global ingest configuration")
+)
+
+// EnableConfigOverlays allows indexers to deliver signed configuration overlays to the ingester.
+// Overlays signed by a Config-Overlay-Key are written into the configuration overlay directory and
+// applied with Reload, if the reload fails the previous overlay is restored.  The outcome is reported
+// to indexers in the ingester state.
+func (r *Reloader) EnableConfigOverlays() error {
+	if r.ib.configOverlay == `` {
+		return ErrNoOverlayDir
+	}
+	ch, ok := r.ib.Cfg.(cfgHelper)
+	if !ok {
+		return ErrOverlayNoConfig
+	}
+	ic := ch.IngestBaseConfig()
+	if keys, err := ic.ConfigOverlayKeys(); err != nil {
+		return err
+	} else if len(keys) == 0 {
+		return ErrNoOverlayKeys
+	}
+	r.igst.SetConfigOverlayHandler(r.applyOverlay)
+	return nil
+}
+
+func (r *Reloader) applyOverlay(co ingest.ConfigOverlay) (st ingest.ConfigOverlayStatus) {
+	st = ingest.ConfigOverlayStatus{ID: co.ID, Name: co.Name}
+	var err error
+	if st.RolledBack, err = r.writeOverlay(co); err != nil {
+		st.Error = err.Error()
+	} else {
+		st.Applied = true
+	}
+	st.Time = time.Now()
+	return
+}
+
+func (r *Reloader) writeOverlay(co ingest.ConfigOverlay) (rolledBack bool, err error) {
+	r.Lock()
+	closed := r.closed
+	ch, ok := r.ib.Cfg.(cfgHelper)
+	r.Unlock()
+	if closed {
+		err = ErrReloaderClosed
+		return
+	} else if !ok {
+		err = ErrOverlayNoConfig
+		return
+	}
+	ic := ch.IngestBaseConfig()
+	var id string
+	if uid, ok := ic.IngesterUUID(); ok {
+		id = uid.String()
+	}
+	keys, err := ic.ConfigOverlayKeys()
+	if err != nil {
+		return
+	} else if err = co.Verify(id, keys); err != nil {
+		return
+	}
+
+	var st overlayState
+	if st, err = loadOverlayState(r.ib.configOverlay); err != nil {
+		return
+	}
+	last, tracked := st[co.Name]
+	pth := filepath.Join(r.ib.configOverlay, co.Name)
+	var old []byte
+	var oldMod time.Time
+	var exists bool
+	if fi, lerr := os.Lstat(pth); lerr == nil {
+		if !fi.Mode().IsRegular() {
+			err = fmt.Errorf("%s is not a regular file", pth)
+			return
+		} else if old, err = os.ReadFile(pth); err != nil {
+			return
+		}
+		exists, oldMod = true, fi.ModTime()
+		if !tracked {
+			last = oldMod //written before the state was tracked, the file carries its issue time
+		}
+	} else if !os.IsNotExist(lerr) {
+		err = lerr
+		return
+	}
+	if !co.Issued.After(last) {
+		err = ErrStaleOverlay
+		return
+	}
+
+	//the overlay is spent as soon as it is accepted, whether or not it applies cleanly
+	st[co.Name] = co.Issued
+	if err = st.save(r.ib.configOverlay); err != nil {
+		return
+	} else if !exists && len(co.Data) == 0 {
+		return //nothing to remove
+	}
+
+	if len(co.Data) == 0 {
+		err = os.Remove(pth)
+	} else {
+		err = writeFileAtomic(pth, co.Data, co.Issued)
+	}
+	if err != nil {
+		return
+	}
+	if err = r.Reload(); err == nil {
+		return
+	}
+
+	//put the previous overlay back and reload again so the running configuration matches the files
+	var rerr error
+	if exists {
+		rerr = writeFileAtomic(pth, old, oldMod)
+	} else {
+		rerr = os.Remove(pth)
+	}
+	if rerr == nil {
+		rerr = r.Reload()
+	}
+	if rerr != nil {
+		err = fmt.Errorf("%w, rollback failed %v", err, rerr)
+	} else {
+		rolledBack = true
+	}
+	return
+}
+
+// overlayState holds the issue time of the last overlay accepted under each name, it is kept apart
+// from the overlays so removing an overlay does not open the door to replaying an older one
+type overlayState map[string]time.Time
+
+func loadOverlayState(dir string) (st overlayState, err error) {
+	var b []byte
+	if b, err = os.ReadFile(filepath.Join(dir, overlayStateFile)); err != nil {
+		if os.IsNotExist(err) {
+			st, err = overlayState{}, nil
+		}
+		return
+	} else if err = json.Unmarshal(b, &st); err == nil && st == nil {
+		st = overlayState{}
+	}
+	return
+}
+
+func (st overlayState) save(dir string) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, overlayStateFile), b, time.Now())
+}
+
+// writeFileAtomic writes the file into place with a rename and stamps it with the given
+// modification time, overlays carry their issue time
+func writeFileAtomic(pth string, b []byte, mod time.Time) (err error) {
+	dir := filepath.Dir(pth)
+	if err = os.MkdirAll(dir, overlayDirPerms); err != nil {
+		return
+	}
+	var fout *os.File
+	if fout, err = os.CreateTemp(dir, overlayTempGlob); err != nil {
+		return
+	}
+	tmp := fout.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	if _, err = fout.Write(b); err != nil {
+		fout.Close()
+		return
+	} else if err = fout.Chmod(overlayFilePerms); err != nil {
+		fout.Close()
+		return
+	} else if err = fout.Sync(); err != nil {
+		fout.Close()
+		return
+	} else if err = fout.Close(); err != nil {
+		return
+	} else if err = os.Chtimes(tmp, mod, mod); err != nil {
+		return
+	}
+	err = os.Rename(tmp, pth)
+	return
+}
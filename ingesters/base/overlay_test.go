@@ -0,0 +1,238 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package base
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const testUUID = `0f9d7c4e-8e0a-4bd4-9c39-5d0a3c5b8c11`
+
+type testListener struct {
+	Tag_Name string
+	Port     int
+}
+
+type testCfg struct {
+	Global   config.IngestConfig
+	Listener map[string]*testListener
+}
+
+func getTestConfig(pth, overlay string) (*testCfg, error) {
+	var c testCfg
+	if err := config.LoadConfigFile(&c, pth); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlay); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *testCfg) Verify() error {
+	for k, v := range c.Listener {
+		if v.Port <= 0 {
+			return fmt.Errorf("listener %s has invalid port %d", k, v.Port)
+		}
+	}
+	return nil
+}
+
+func (c *testCfg) Tags() (tags []string, err error) {
+	for _, v := range c.Listener {
+		tags = append(tags, v.Tag_Name)
+	}
+	sort.Strings(tags)
+	return
+}
+
+func (c *testCfg) IngestBaseConfig() config.IngestConfig {
+	return c.Global
+}
+
+func (c *testCfg) AttachConfig() attach.AttachConfig {
+	return attach.AttachConfig{}
+}
+
+// newTestReloader writes a configuration with a single listener plus the given global parameters
+// and returns a reloader over it, the reload function just records the configurations it is handed
+func newTestReloader(t *testing.T, global string, fn ReloadFunc) (r *Reloader, dir string) {
+	root := t.TempDir()
+	dir = filepath.Join(root, `conf.d`)
+	pth := filepath.Join(root, `test.conf`)
+	cfg := fmt.Sprintf("[Global]\nIngester-UUID=%s\n%s\n[Listener \"base\"]\n\tTag-Name=base\n\tPort=1\n", testUUID, global)
+	if err := os.WriteFile(pth, []byte(cfg), 0640); err != nil {
+		t.Fatal(err)
+	}
+	ib := &IngesterBase{
+		IngesterBaseConfig: IngesterBaseConfig{IngesterName: `test`, AppName: `test`, GetConfigFunc: getTestConfig},
+		Logger:             log.NewDiscardLogger(),
+		configFile:         pth,
+		configOverlay:      dir,
+	}
+	var err error
+	if ib.Cfg, _, err = ib.getConfig(pth, dir); err != nil {
+		t.Fatal(err)
+	}
+	igst, err := ingest.NewMuxer(ingest.MuxerConfig{
+		Destinations: []ingest.Target{{Address: `tcp://127.0.0.1:4023`, Secret: `x`}},
+		Tags:         []string{`base`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err = ib.NewReloader(igst, fn); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func newOverlayReloader(t *testing.T) (*Reloader, string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := `Config-Overlay-Key=` + base64.StdEncoding.EncodeToString(pub)
+	r, dir := newTestReloader(t, global, func(oldCfg, newCfg interface{}) error { return nil })
+	if err = r.EnableConfigOverlays(); err != nil {
+		t.Fatal(err)
+	}
+	return r, dir, priv
+}
+
+func signedOverlay(key ed25519.PrivateKey, id string, issued time.Time, data string) (co ingest.ConfigOverlay) {
+	co = ingest.ConfigOverlay{
+		ID:     id,
+		Name:   `extra.conf`,
+		Target: testUUID,
+		Issued: issued,
+		Data:   []byte(data),
+	}
+	co.Sign(key)
+	return
+}
+
+func testListenerPort(r *Reloader, name string) int {
+	if l, ok := r.ib.Cfg.(*testCfg).Listener[name]; ok {
+		return l.Port
+	}
+	return 0
+}
+
+func TestWriteOverlay(t *testing.T) {
+	r, dir, key := newOverlayReloader(t)
+	defer r.Close()
+	pth := filepath.Join(dir, `extra.conf`)
+	now := time.Now()
+	extra := "[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=2\n"
+
+	co := signedOverlay(key, `1`, now.Add(-time.Minute), extra)
+	if st := r.applyOverlay(co); !st.Applied || st.RolledBack || st.Error != `` || st.ID != `1` {
+		t.Fatalf("bad overlay status %+v", st)
+	} else if b, err := os.ReadFile(pth); err != nil || !bytes.Equal(b, co.Data) {
+		t.Fatalf("overlay not written %v", err)
+	} else if testListenerPort(r, `extra`) != 2 {
+		t.Fatal("overlay not applied")
+	}
+
+	//the same overlay or anything issued before it cannot be applied again
+	if _, err := r.writeOverlay(co); err != ErrStaleOverlay {
+		t.Fatalf("replayed overlay not caught: %v", err)
+	} else if _, err = r.writeOverlay(signedOverlay(key, `2`, now.Add(-2*time.Minute), extra)); err != ErrStaleOverlay {
+		t.Fatalf("stale overlay not caught: %v", err)
+	}
+
+	//a broken overlay is rolled back
+	bad := signedOverlay(key, `3`, now, "[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=0\n")
+	if rb, err := r.writeOverlay(bad); err == nil || !rb {
+		t.Fatalf("broken overlay was not rolled back %v %v", rb, err)
+	} else if b, err := os.ReadFile(pth); err != nil || !bytes.Equal(b, co.Data) {
+		t.Fatalf("previous overlay not restored %v", err)
+	} else if testListenerPort(r, `extra`) != 2 {
+		t.Fatal("previous configuration not restored")
+	}
+
+	//removing the overlay must not reopen it to replays
+	if _, err := r.writeOverlay(signedOverlay(key, `4`, now.Add(time.Second), ``)); err != nil {
+		t.Fatal(err)
+	} else if _, err = os.Stat(pth); !os.IsNotExist(err) {
+		t.Fatalf("overlay not removed %v", err)
+	} else if testListenerPort(r, `extra`) != 0 {
+		t.Fatal("overlay removal not applied")
+	} else if _, err = r.writeOverlay(co); err != ErrStaleOverlay {
+		t.Fatalf("overlay replayed after removal: %v", err)
+	}
+
+	//overlays have to be signed by a configured key and meant for this ingester
+	_, other, _ := ed25519.GenerateKey(nil)
+	if _, err := r.writeOverlay(signedOverlay(other, `5`, now.Add(time.Hour), extra)); err != ingest.ErrConfigOverlaySignature {
+		t.Fatalf("bad signature not caught: %v", err)
+	}
+	co = signedOverlay(key, `6`, now.Add(time.Hour), extra)
+	co.Target = ``
+	co.Sign(key)
+	if _, err := r.writeOverlay(co); err != ingest.ErrConfigOverlayUnbound {
+		t.Fatalf("unbound overlay not caught: %v", err)
+	}
+
+	r.Close()
+	if _, err := r.writeOverlay(signedOverlay(key, `7`, now.Add(time.Hour), extra)); err != ErrReloaderClosed {
+		t.Fatalf("bad closed error %v", err)
+	}
+}
+
+func TestOverlayState(t *testing.T) {
+	dir := t.TempDir()
+	st, err := loadOverlayState(dir)
+	if err != nil || len(st) != 0 {
+		t.Fatalf("bad empty state %v %v", st, err)
+	}
+	ts := time.Now().Round(0)
+	st[`a.conf`] = ts
+	if err = st.save(dir); err != nil {
+		t.Fatal(err)
+	} else if st, err = loadOverlayState(dir); err != nil {
+		t.Fatal(err)
+	} else if !st[`a.conf`].Equal(ts) {
+		t.Fatalf("bad state %v", st)
+	}
+	if err = ingest.ValidateConfigOverlayName(overlayStateFile); err == nil {
+		t.Fatal("an overlay can overwrite the state file")
+	}
+
+	//a corrupt state refuses overlays rather than forgetting what was applied
+	if err = os.WriteFile(filepath.Join(dir, overlayStateFile), []byte(`{`), overlayFilePerms); err != nil {
+		t.Fatal(err)
+	} else if _, err = loadOverlayState(dir); err == nil {
+		t.Fatal("corrupt state not caught")
+	}
+
+	//overlays that predate the state fall back to the file time
+	r, odir, key := newOverlayReloader(t)
+	defer r.Close()
+	issued := time.Now().Add(-time.Minute)
+	if err = writeFileAtomic(filepath.Join(odir, `extra.conf`), []byte("[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=2\n"), issued); err != nil {
+		t.Fatal(err)
+	} else if _, err = r.writeOverlay(signedOverlay(key, `1`, issued.Add(-time.Second), ``)); !errors.Is(err, ErrStaleOverlay) {
+		t.Fatalf("overlay older than the file not caught: %v", err)
+	}
+}
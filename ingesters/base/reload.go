@@ -0,0 +1,251 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package base
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+var (
+	ErrReloaderClosed = errors.New("reloader is closed")
+)
+
+// ReloadFunc applies a newly loaded and verified configuration to a running ingester.
+// The old and new values are the same native type returned by IngesterBaseConfig.GetConfigFunc.
+// If the function returns an error the ingester keeps running under the old configuration,
+// unless that error is a PartialReloadError.
+//
+// Sections that failed to start on a previous reload are part of the old configuration, so a
+// ReloadFunc should diff the new configuration against what is actually running, not oldCfg.
+type ReloadFunc func(oldCfg, newCfg interface{}) error
+
+// PartialReloadError is returned by a ReloadFunc that applied a new configuration but could not
+// start some of its sections.  The new configuration is committed because it is what is running,
+// the failed sections are tracked by the Reloader and retried on the next reload.
+type PartialReloadError struct {
+	Failed []string // sections that are in the new configuration but are not running
+	Err    error
+}
+
+func (e *PartialReloadError) Error() string {
+	if len(e.Failed) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("failed to start %s: %v", strings.Join(e.Failed, ", "), e.Err)
+}
+
+func (e *PartialReloadError) Unwrap() error {
+	return e.Err
+}
+
+// Reloader watches for SIGHUP and hot reloads the ingester configuration.
+// Any new tags are negotiated on the existing muxer before the ReloadFunc is called
+// so that listeners can resolve tags without tearing down indexer connections.
+//
+// Only ingesters that create a Reloader reload on SIGHUP, currently SimpleRelay and fileFollow
+// on Linux and macOS.  HttpIngester handles SIGHUP itself, every other ingester must be restarted.
+type Reloader struct {
+	sync.Mutex
+	ib     *IngesterBase
+	igst   *ingest.IngestMuxer
+	fn     ReloadFunc
+	sig    chan os.Signal
+	done   chan struct{}
+	closed bool
+	failed []string
+}
+
+// NewReloader creates a Reloader that will apply configuration changes using fn.
+// The reloader does not watch for signals until Start is called.
+func (ib *IngesterBase) NewReloader(igst *ingest.IngestMuxer, fn ReloadFunc) (r *Reloader, err error) {
+	if ib == nil || ib.Cfg == nil || (ib.configFile == `` && ib.configOverlay == ``) {
+		err = ErrNotReady
+		return
+	} else if igst == nil || fn == nil {
+		err = ErrInvalidParameter
+		return
+	}
+	r = &Reloader{
+		ib:   ib,
+		igst: igst,
+		fn:   fn,
+		done: make(chan struct{}),
+	}
+	return
+}
+
+// Start begins watching for SIGHUP, each signal triggers a call to Reload
+func (r *Reloader) Start() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return ErrReloaderClosed
+	} else if r.sig != nil {
+		return nil //already running
+	}
+	r.sig = utils.GetSighupChannel()
+	go r.routine(r.sig)
+	return nil
+}
+
+func (r *Reloader) routine(sig chan os.Signal) {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-sig:
+			//pick up a rotated client certificate even if the files kept their modification times
+			if err := r.igst.ReloadClientCertificate(); err != nil {
+				r.ib.Logger.Error("failed to reload client certificate", log.KVErr(err))
+			}
+			if err := r.Reload(); err != nil {
+				r.ib.Logger.Error("failed to reload configuration", log.KV("config", r.ib.configFile), log.KVErr(err))
+			} else {
+				r.ib.Logger.Info("reloaded configuration", log.KV("config", r.ib.configFile))
+			}
+		}
+	}
+}
+
+// Reload re-reads and verifies the configuration, negotiates any new tags, and hands
+// the new configuration to the ReloadFunc.  On success, or a PartialReloadError, the new
+// configuration is assigned into IngesterBase.Cfg and pushed upstream in the ingester state.
+func (r *Reloader) Reload() (err error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return ErrReloaderClosed
+	}
+	var obj interface{}
+	var ch cfgHelper
+	if obj, ch, err = r.ib.getConfig(r.ib.configFile, r.ib.configOverlay); err != nil {
+		err = fmt.Errorf("failed to load configuration %w", err)
+		return
+	} else if err = verifyConfig(obj); err != nil {
+		err = fmt.Errorf("failed to verify configuration %w", err)
+		return
+	} else if reflect.TypeOf(obj) != reflect.TypeOf(r.ib.Cfg) {
+		err = fmt.Errorf("Type Mismatch: %T != %T", obj, r.ib.Cfg)
+		return
+	}
+
+	// the muxer, its targets, and its cache are not rebuilt, let the user know those changes are ignored
+	if och, ok := r.ib.Cfg.(cfgHelper); ok {
+		if !reflect.DeepEqual(och.IngestBaseConfig(), ch.IngestBaseConfig()) {
+			r.ib.Logger.Warn("global configuration changes require a restart and were not applied")
+		}
+	}
+
+	var tags []string
+	if tags, err = ch.Tags(); err != nil {
+		err = fmt.Errorf("Failed to get tags %w", err)
+		return
+	}
+	for _, tag := range tags {
+		if _, err = r.igst.NegotiateTag(tag); err != nil {
+			err = fmt.Errorf("failed to negotiate tag %q %w", tag, err)
+			return
+		}
+	}
+
+	if err = r.fn(r.ib.Cfg, obj); err != nil {
+		var perr *PartialReloadError
+		if !errors.As(err, &perr) {
+			return
+		}
+		r.failed = append([]string(nil), perr.Failed...)
+	} else {
+		r.failed = nil
+	}
+	r.ib.Cfg = obj
+	if lerr := r.igst.SetRawConfiguration(obj); lerr != nil {
+		r.ib.Logger.Warn("failed to update configuration for ingester state messages", log.KVErr(lerr))
+	}
+	return
+}
+
+// Failed returns the sections that failed to start on the last reload, they are retried on the next one
+func (r *Reloader) Failed() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.failed...)
+}
+
+// Close stops watching for signals, it does not wait for an in progress reload to complete
+func (r *Reloader) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return ErrReloaderClosed
+	}
+	r.closed = true
+	if r.sig != nil {
+		signal.Stop(r.sig)
+	}
+	close(r.done)
+	return nil
+}
+
+// ConfigDiff names the configuration sections that were added, removed, or changed
+// between two configurations.  Each list is sorted.
+type ConfigDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns true if the two configurations had identical sections
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSections compares two maps of named configuration sections, such as listeners or followers.
+func DiffSections[V any](oldSections, newSections map[string]V) (d ConfigDiff) {
+	for k, ov := range oldSections {
+		if nv, ok := newSections[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !reflect.DeepEqual(ov, nv) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for k := range newSections {
+		if _, ok := oldSections[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return
+}
+
+// PreprocessorsChanged returns true if any of the named preprocessors differ between two
+// configurations, meaning a section that references them must rebuild its ProcessorSet.
+func PreprocessorsChanged(oldCfg, newCfg processors.ProcessorConfig, names []string) bool {
+	for _, name := range names {
+		ov, ok := oldCfg[name]
+		nv, nok := newCfg[name]
+		if ok != nok || !reflect.DeepEqual(ov, nv) {
+			return true
+		}
+	}
+	return false
+}
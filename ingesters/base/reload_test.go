@@ -0,0 +1,170 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package base
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+func writeTestOverlay(t *testing.T, dir, data string) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(dir, `extra.conf`), []byte(data), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	var calls int
+	var ret error
+	r, dir := newTestReloader(t, ``, func(oldCfg, newCfg interface{}) error {
+		if _, ok := oldCfg.(*testCfg); !ok {
+			t.Fatalf("bad old config type %T", oldCfg)
+		} else if _, ok = newCfg.(*testCfg); !ok {
+			t.Fatalf("bad new config type %T", newCfg)
+		}
+		calls++
+		return ret
+	})
+	defer r.Close()
+
+	writeTestOverlay(t, dir, "[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=2\n")
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	} else if calls != 1 || testListenerPort(r, `extra`) != 2 {
+		t.Fatalf("reload not applied %d", calls)
+	}
+
+	//a configuration that fails verification never reaches the reload function
+	writeTestOverlay(t, dir, "[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=0\n")
+	if err := r.Reload(); err == nil {
+		t.Fatal("invalid configuration not caught")
+	} else if calls != 1 || testListenerPort(r, `extra`) != 2 {
+		t.Fatalf("invalid configuration applied %d", calls)
+	}
+
+	//a failed reload keeps the old configuration
+	ret = errors.New("failed")
+	writeTestOverlay(t, dir, "[Listener \"extra\"]\n\tTag-Name=extra\n\tPort=3\n")
+	if err := r.Reload(); err != ret {
+		t.Fatalf("bad reload error %v", err)
+	} else if calls != 2 || testListenerPort(r, `extra`) != 2 {
+		t.Fatal("failed reload was committed")
+	}
+
+	//a partial reload commits the new configuration and remembers what failed
+	ret = &PartialReloadError{Failed: []string{`extra`}, Err: errors.New("failed")}
+	if err := r.Reload(); err != ret {
+		t.Fatalf("bad reload error %v", err)
+	} else if testListenerPort(r, `extra`) != 3 {
+		t.Fatal("partial reload was not committed")
+	} else if f := r.Failed(); !reflect.DeepEqual(f, []string{`extra`}) {
+		t.Fatalf("bad failed sections %v", f)
+	}
+	ret = nil
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	} else if f := r.Failed(); len(f) != 0 {
+		t.Fatalf("failed sections not cleared %v", f)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	} else if err = r.Reload(); err != ErrReloaderClosed {
+		t.Fatalf("bad closed error %v", err)
+	} else if err = r.Start(); err != ErrReloaderClosed {
+		t.Fatalf("bad closed error %v", err)
+	} else if err = r.Close(); err != ErrReloaderClosed {
+		t.Fatalf("bad double close error %v", err)
+	}
+}
+
+func TestPartialReloadError(t *testing.T) {
+	inner := errors.New("bind failed")
+	var err error = &PartialReloadError{Failed: []string{`a`, `b`}, Err: inner}
+	if err.Error() != `failed to start a, b: bind failed` {
+		t.Fatalf("bad error string %q", err.Error())
+	} else if !errors.Is(err, inner) {
+		t.Fatal("inner error not unwrapped")
+	}
+	var perr *PartialReloadError
+	if !errors.As(err, &perr) || len(perr.Failed) != 2 {
+		t.Fatal("failed to find partial reload error")
+	}
+	if err = (&PartialReloadError{Err: inner}); err.Error() != inner.Error() {
+		t.Fatalf("bad error string %q", err.Error())
+	}
+}
+
+func TestDiffSections(t *testing.T) {
+	tests := []struct {
+		old, new map[string]testListener
+		diff     ConfigDiff
+	}{
+		{nil, nil, ConfigDiff{}},
+		{
+			old: map[string]testListener{`a`: {`a`, 1}},
+			new: map[string]testListener{`a`: {`a`, 1}},
+		},
+		{
+			old:  map[string]testListener{`a`: {`a`, 1}, `b`: {`b`, 2}, `c`: {`c`, 3}},
+			new:  map[string]testListener{`c`: {`c`, 4}, `d`: {`d`, 5}, `b`: {`b`, 2}, `e`: {`e`, 6}},
+			diff: ConfigDiff{Added: []string{`d`, `e`}, Removed: []string{`a`}, Changed: []string{`c`}},
+		},
+		{
+			new:  map[string]testListener{`a`: {`a`, 1}},
+			diff: ConfigDiff{Added: []string{`a`}},
+		},
+	}
+	for i, tt := range tests {
+		d := DiffSections(tt.old, tt.new)
+		if !reflect.DeepEqual(d, tt.diff) {
+			t.Fatalf("%d: bad diff %+v != %+v", i, d, tt.diff)
+		} else if d.Empty() != (len(tt.diff.Added)+len(tt.diff.Removed)+len(tt.diff.Changed) == 0) {
+			t.Fatalf("%d: bad empty %v", i, d.Empty())
+		}
+	}
+}
+
+func TestPreprocessorsChanged(t *testing.T) {
+	load := func(s string) processors.ProcessorConfig {
+		var c struct {
+			Preprocessor processors.ProcessorConfig
+		}
+		if err := config.LoadConfigBytes(&c, []byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		return c.Preprocessor
+	}
+	old := load("[Preprocessor \"a\"]\n\tType=gzip\n[Preprocessor \"b\"]\n\tType=gzip\n")
+	tests := []struct {
+		cfg     string
+		names   []string
+		changed bool
+	}{
+		{"[Preprocessor \"a\"]\n\tType=gzip\n[Preprocessor \"b\"]\n\tType=gzip\n", []string{`a`, `b`}, false},
+		{"[Preprocessor \"a\"]\n\tType=gzip\n[Preprocessor \"b\"]\n\tType=gzip\n\tPassthrough-Non-Gzip=true\n", []string{`a`}, false},
+		{"[Preprocessor \"a\"]\n\tType=gzip\n[Preprocessor \"b\"]\n\tType=gzip\n\tPassthrough-Non-Gzip=true\n", []string{`a`, `b`}, true},
+		{"[Preprocessor \"a\"]\n\tType=gzip\n", []string{`b`}, true},
+		{"[Preprocessor \"a\"]\n\tType=gzip\n[Preprocessor \"c\"]\n\tType=gzip\n", []string{`c`}, true},
+		{"[Preprocessor \"a\"]\n\tType=gzip\n", nil, false},
+	}
+	for i, tt := range tests {
+		if c := PreprocessorsChanged(old, load(tt.cfg), tt.names); c != tt.changed {
+			t.Fatalf("%d: bad changed %v != %v", i, c, tt.changed)
+		}
+	}
+}
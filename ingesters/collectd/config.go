@@ -20,6 +20,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 
 	"collectd.org/network"
 )
@@ -64,6 +65,7 @@ type cfgReadType struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	config.IngestConfig
 	Attach       attach.AttachConfig
 	Collector    map[string]*collector
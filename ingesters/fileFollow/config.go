@@ -24,17 +24,20 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/timegrinder"
 )
 
 const (
-	MAX_CONFIG_SIZE        int64 = (1024 * 1024 * 2) //2MB, even this is crazy large
-	defaultMaxWatchedFiles       = 1024
+	MAX_CONFIG_SIZE         int64 = (1024 * 1024 * 2) //2MB, even this is crazy large
+	defaultMaxWatchedFiles        = 1024
+	defaultStateSyncTimeout       = 30 * time.Second
 )
 
 var (
 	ErrInvalidStateStoreLocation         = errors.New("Empty state storage location")
 	ErrTimestampDelimiterMissingOverride = errors.New("Timestamp delimiting requires a defined timestamp override")
+	ErrMultilineDelimiterConflict        = errors.New("Multiline events cannot be combined with Regex-Delimiter or Timestamp-Delimited")
 )
 
 type bindType int
@@ -62,8 +65,15 @@ type follower struct {
 	Timestamp_Format_Override string //override the timestamp format
 	Timestamp_Delimited       bool
 	Timezone_Override         string
+	Timestamp_Format_Learning bool // remember the timestamp format of each file instead of trying every format
 	Regex_Delimiter           string
-	Preprocessor              []string
+	// multiline event assembly, cannot be combined with Regex-Delimiter or Timestamp-Delimited
+	Multiline_Start_Pattern        string // regex matching the first line of an event
+	Multiline_Continuation_Pattern string // regex matching lines that continue the current event
+	Multiline_Indent               bool   // lines beginning with whitespace continue the current event
+	Multiline_Max_Size             int    // maximum event size in bytes
+	Multiline_Flush_Timeout        string // how long the last event in an idle file waits for more lines
+	Preprocessor                   []string
 	// these two must be used together
 	Timestamp_Regex         string
 	Timestamp_Format_String string
@@ -75,9 +85,14 @@ type global struct {
 	config.IngestConfig
 	Max_Files_Watched    int
 	State_Store_Location string
+	// only advance file offsets once the entries have been acknowledged by the indexers,
+	// preprocessors that hold entries (such as sampling reservoirs) are flushed at every state flush
+	Acknowledged_State bool
+	State_Sync_Timeout string // how long a state flush waits on acknowledgements
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	global
 	Attach       attach.AttachConfig
 	Follower     map[string]*follower
@@ -113,6 +128,8 @@ func (c *cfgType) Verify() error {
 		return err
 	} else if err = c.global.verifyStateStore(); err != nil {
 		return err
+	} else if _, err = c.global.StateSyncTimeout(); err != nil {
+		return err
 	} else if c.global.Max_Files_Watched <= 0 {
 		c.global.Max_Files_Watched = defaultMaxWatchedFiles
 	}
@@ -159,6 +176,9 @@ func (c *cfgType) Verify() error {
 		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
 			return fmt.Errorf("Follower %s preprocessor invalid: %v", k, err)
 		}
+		if _, _, err := v.Multiline(); err != nil {
+			return fmt.Errorf("Follower %s multiline config invalid: %v", k, err)
+		}
 	}
 	return nil
 }
@@ -248,6 +268,34 @@ func (f follower) TimestampDelimited() (rex string, ok bool, err error) {
 	return
 }
 
+// Multiline returns the multiline engine configuration, ok is false when no multiline rules are set
+func (f follower) Multiline() (mc filewatch.MultilineConfig, ok bool, err error) {
+	if f.Multiline_Start_Pattern == `` && f.Multiline_Continuation_Pattern == `` && !f.Multiline_Indent {
+		if f.Multiline_Max_Size != 0 || f.Multiline_Flush_Timeout != `` {
+			err = filewatch.ErrMultilineNoRules
+		}
+		return
+	} else if f.Regex_Delimiter != `` || f.Timestamp_Delimited {
+		err = ErrMultilineDelimiterConflict
+		return
+	}
+	mc = filewatch.MultilineConfig{
+		StartPattern:        f.Multiline_Start_Pattern,
+		ContinuationPattern: f.Multiline_Continuation_Pattern,
+		Indent:              f.Multiline_Indent,
+		MaxEventSize:        f.Multiline_Max_Size,
+	}
+	if f.Multiline_Flush_Timeout != `` {
+		if mc.FlushTimeout, err = time.ParseDuration(f.Multiline_Flush_Timeout); err != nil {
+			return
+		}
+	}
+	if err = mc.Validate(); err == nil {
+		ok = true
+	}
+	return
+}
+
 func (f follower) TimezoneOverride() string {
 	return f.Timezone_Override
 }
@@ -264,6 +312,19 @@ func (g *global) StatePath() string {
 	return g.State_Store_Location
 }
 
+// StateSyncTimeout returns how long a state flush may wait for the ingest muxer to
+// sync when Acknowledged-State is enabled
+func (g *global) StateSyncTimeout() (to time.Duration, err error) {
+	if g.State_Sync_Timeout == `` {
+		to = defaultStateSyncTimeout
+	} else if to, err = time.ParseDuration(g.State_Sync_Timeout); err != nil {
+		err = fmt.Errorf("Invalid State-Sync-Timeout %q: %w", g.State_Sync_Timeout, err)
+	} else if to <= 0 {
+		err = fmt.Errorf("Invalid State-Sync-Timeout %q, must be positive", g.State_Sync_Timeout)
+	}
+	return
+}
+
 func dumpStateFile(pth string) {
 	states, err := filewatch.DecodeStateFile(pth)
 	if err != nil {
@@ -20,7 +20,6 @@ import (
 	"github.com/gravwell/gravwell/v3/debug"
 	"github.com/gravwell/gravwell/v3/filewatch"
 	"github.com/gravwell/gravwell/v3/ingest/log"
-	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/ingesters/utils"
 	"github.com/gravwell/gravwell/v3/timegrinder"
@@ -103,8 +102,6 @@ func main() {
 	wtcher.SetLogger(igst)
 	wtcher.SetMaxFilesWatched(cfg.Max_Files_Watched)
 
-	var procs []*processors.ProcessorSet
-
 	var window timegrinder.TimestampWindow
 	window, err = cfg.GlobalTimestampWindow()
 	if err != nil {
@@ -112,73 +109,27 @@ func main() {
 	}
 
 	//build a list of base directories and globs
-	for k, val := range cfg.Follower {
-		pproc, err := cfg.Preprocessor.ProcessorSet(igst, val.Preprocessor)
-		if err != nil {
-			lg.FatalCode(0, "preprocessor construction error", log.KVErr(err))
-		}
-		procs = append(procs, pproc)
-		//get the tag for this listener
-		tag, err := igst.GetTag(val.Tag_Name)
-		if err != nil {
-			lg.Fatal("failed to resolve tag", log.KV("watcher", k), log.KV("tag", val.Tag_Name), log.KVErr(err))
-		}
-
-		tsFmtOverride, err := val.TimestampOverride()
-		if err != nil {
-			lg.FatalCode(0, "invalid timestamp override", log.KV("timestampoverride", val.Timestamp_Format_Override), log.KVErr(err))
-		}
-
-		//create our handler for this watcher
-		cfg := filewatch.LogHandlerConfig{
-			TagName:                 val.Tag_Name,
-			Tag:                     tag,
-			Src:                     src,
-			IgnoreTS:                val.Ignore_Timestamps,
-			AssumeLocalTZ:           val.Assume_Local_Timezone,
-			IgnorePrefixes:          val.Ignore_Line_Prefix,
-			IgnoreGlobs:             val.Ignore_Glob,
-			TimestampFormatOverride: tsFmtOverride,
-			UserTimeRegex:           val.Timestamp_Regex,
-			UserTimeFormat:          val.Timestamp_Format_String,
-			Logger:                  lg,
-			TimezoneOverride:        val.Timezone_Override,
-			Ctx:                     wtcher.Context(),
-			TimeFormat:              cfg.TimeFormat,
-			AttachFilename:          val.Attach_Filename,
-			Trim:                    val.Trim,
-			TimestampWindow:         window,
-		}
-		if debugOn {
-			cfg.Debugger = debugout
+	tstats := utils.NewTimestampStats()
+	fs := newFollowerSet(wtcher, igst, src, window, tstats)
+	for k := range cfg.Followers() {
+		if err := fs.start(k, cfg); err != nil {
+			wtcher.Close()
+			lg.Fatal("failed to start follower", log.KV("follower", k), log.KVErr(err))
 		}
-		lh, err := filewatch.NewLogHandler(cfg, pproc)
+	}
+	if cfg.Acknowledged_State {
+		//offsets only hit the state file once the indexers have everything up to them
+		to, err := cfg.StateSyncTimeout()
 		if err != nil {
-			lg.Fatal("failed to generate handler", log.KVErr(err))
-		}
-		c := filewatch.WatchConfig{
-			ConfigName: k,
-			BaseDir:    val.Base_Directory,
-			FileFilter: val.File_Filter,
-			Hnd:        lh,
-			Recursive:  val.Recursive,
-		}
-		if rex, ok, err := val.TimestampDelimited(); err != nil {
-			lg.FatalCode(0, "invalid timestamp delimiter", log.KVErr(err))
-		} else if ok {
-			c.Engine = filewatch.RegexEngine
-			c.EngineArgs = rex
-		} else if val.Regex_Delimiter != `` {
-			c.Engine = filewatch.RegexEngine
-			c.EngineArgs = val.Regex_Delimiter
-		} else {
-			c.Engine = filewatch.LineEngine
-		}
-		if err := wtcher.Add(c); err != nil {
-			wtcher.Close()
-			lg.Fatal("failed to add watch directory", log.KV("path", val.Base_Directory),
-				log.KV("filter", val.File_Filter), log.KVErr(err))
+			lg.FatalCode(0, "invalid state sync timeout", log.KVErr(err))
 		}
+		//entries held by preprocessors have been consumed from the files, push them out first
+		wtcher.SetStateSync(func() error {
+			if err := fs.flush(); err != nil {
+				return err
+			}
+			return igst.Sync(to)
+		})
 	}
 	qc := utils.GetQuitChannel()
 	if quit, err := wtcher.Catchup(qc); err != nil {
@@ -195,6 +146,23 @@ func main() {
 			os.Exit(-1)
 		}
 
+		//watch for SIGHUP so followers can be added, removed, or changed without a restart
+		rldr, err := ib.NewReloader(igst, fs.reload)
+		if err != nil {
+			lg.FatalCode(0, "failed to create configuration reloader", log.KVErr(err))
+		} else if err = rldr.Start(); err != nil {
+			lg.FatalCode(0, "failed to start configuration reloader", log.KVErr(err))
+		}
+		//indexers may push signed configuration overlays when a Config-Overlay-Key is configured
+		if err = rldr.EnableConfigOverlays(); err == nil {
+			lg.Info("remote configuration overlays enabled")
+		} else if err != base.ErrNoOverlayKeys && err != base.ErrNoOverlayDir {
+			lg.FatalCode(0, "failed to enable configuration overlays", log.KVErr(err))
+		}
+
+		//format learning statistics show up in the ingester state
+		go tstats.Report(wtcher.Context(), igst, utils.TimestampStatsInterval)
+
 		debugout("Started following %d locations\n", len(cfg.Follower))
 		debugout("Running\n")
 		//listen for signals so we can close gracefully
@@ -202,6 +170,7 @@ func main() {
 		case <-qc:
 		case <-wtcher.Context().Done():
 		}
+		rldr.Close()
 	}
 	debugout("Attempting to close the watcher... ")
 	if err := wtcher.Close(); err != nil {
@@ -210,12 +179,8 @@ func main() {
 	debugout("Done\n")
 
 	//close down all the preprocessors
-	for _, v := range procs {
-		if v != nil {
-			if err := v.Close(); err != nil {
-				lg.Error("failed to close processors", log.KVErr(err))
-			}
-		}
+	if err := fs.Close(); err != nil {
+		lg.Error("failed to close processors", log.KVErr(err))
 	}
 
 	//wait for our ingest relay to exit
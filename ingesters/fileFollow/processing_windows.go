@@ -22,14 +22,15 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/ingesters/utils"
-	"github.com/gravwell/gravwell/v3/ingesters/version"
 	"github.com/gravwell/gravwell/v3/timegrinder"
 )
 
 const (
 	defaultEntryChannelSize int = 1024
 	minServiceLiveTime          = 2 * time.Second
+	serviceIngesterName         = `winfilefollow`
 )
 
 var (
@@ -38,10 +39,8 @@ var (
 
 type mainService struct {
 	cfg         *cfgType
-	secret      string
 	timeout     time.Duration
 	tags        []string
-	conns       []string
 	flocs       map[string]follower
 	igst        *ingest.IngestMuxer
 	timeFormats config.CustomTimeFormat
@@ -49,15 +48,7 @@ type mainService struct {
 	pp          processors.ProcessorConfig
 	procs       []*processors.ProcessorSet
 	srcOverride string
-	logLevel    string
-	uuid        string
-	label       string
 	ctx         context.Context
-	cacheDepth  int
-	cachePath   string
-	cacheSize   int
-	cacheMode   string
-	lmt         int64
 }
 
 func NewService(cfg *cfgType) (*mainService, error) {
@@ -66,13 +57,9 @@ func NewService(cfg *cfgType) (*mainService, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get tags from configuration: %v", err)
 	}
-	conns, err := cfg.Targets()
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get backend targets from configuration: %v", err)
-	}
-	lmt, err := cfg.RateLimit()
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get rate limit from configuration: %w\n", err)
+	//check the ingest settings up front, the muxer is built when the service starts
+	if _, err = base.MuxerConfig(cfg.IngestConfig, tags, serviceIngesterName); err != nil {
+		return nil, err
 	}
 
 	debugout("Acquired tags and targets\n")
@@ -85,8 +72,7 @@ func NewService(cfg *cfgType) (*mainService, error) {
 	//pass in the ingest muxer to the file watcher so it can throw info and errors down the muxer chan
 	wtchr.SetMaxFilesWatched(cfg.Max_Files_Watched)
 
-	id, ok := cfg.IngesterUUID()
-	if !ok {
+	if _, ok := cfg.IngesterUUID(); !ok {
 		return nil, errors.New("Couldn't read ingester UUID")
 	}
 
@@ -94,22 +80,12 @@ func NewService(cfg *cfgType) (*mainService, error) {
 	return &mainService{
 		cfg:         cfg,
 		timeout:     cfg.Timeout(),
-		secret:      cfg.Secret(),
 		tags:        tags,
-		conns:       conns,
 		flocs:       cfg.Followers(), //this copies the map
 		wtchr:       wtchr,
 		timeFormats: cfg.TimeFormat,
 		pp:          cfg.Preprocessor,
-		logLevel:    cfg.LogLevel(),
-		uuid:        id.String(),
-		label:       cfg.Label,
 		srcOverride: cfg.Source_Override,
-		cacheDepth:  cfg.Cache_Depth,
-		cachePath:   cfg.Ingest_Cache_Path,
-		cacheSize:   cfg.Max_Ingest_Cache,
-		cacheMode:   cfg.Cache_Mode,
-		lmt:         lmt,
 	}, nil
 }
 
@@ -266,24 +242,11 @@ func (m *mainService) init(ctx context.Context) error {
 	}
 
 	//fire up the ingesters
-	ingestConfig := ingest.UniformMuxerConfig{
-		Destinations:    m.conns,
-		Tags:            m.tags,
-		Auth:            m.secret,
-		LogLevel:        m.logLevel,
-		IngesterName:    "winfilefollow",
-		IngesterVersion: version.GetVersion(),
-		IngesterUUID:    m.uuid,
-		IngesterLabel:   m.label,
-		RateLimitBps:    m.lmt,
-		CacheDepth:      m.cacheDepth,
-		CachePath:       m.cachePath,
-		CacheSize:       m.cacheSize,
-		CacheMode:       m.cacheMode,
-	}
-	if m.cfg != nil {
-		ingestConfig.Attach = m.cfg.Attach
+	ingestConfig, err := base.MuxerConfig(m.cfg.IngestConfig, m.tags, serviceIngesterName)
+	if err != nil {
+		return err
 	}
+	ingestConfig.Attach = m.cfg.Attach
 
 	debugout("Starting ingester connections ")
 	igst, err := ingest.NewUniformMuxer(ingestConfig)
@@ -313,6 +276,23 @@ func (m *mainService) init(ctx context.Context) error {
 	}
 	infoout("Ingester established %d connections\n", hot)
 	m.wtchr.SetLogger(igst)
+	if m.cfg.Acknowledged_State {
+		//offsets only hit the state file once the indexers have everything up to them
+		to, err := m.cfg.StateSyncTimeout()
+		if err != nil {
+			errorout("Invalid state sync timeout: %v", err)
+			return err
+		}
+		//entries held by preprocessors have been consumed from the files, push them out first
+		m.wtchr.SetStateSync(func() error {
+			for _, v := range m.procs {
+				if err := v.Flush(); err != nil {
+					return err
+				}
+			}
+			return igst.Sync(to)
+		})
+	}
 
 	var src net.IP
 	if m.srcOverride != "" {
@@ -334,6 +314,7 @@ func (m *mainService) init(ctx context.Context) error {
 	}
 
 	//build up the handlers
+	tstats := utils.NewTimestampStats()
 	for k, val := range m.flocs {
 		pproc, err := m.pp.ProcessorSet(igst, val.Preprocessor)
 		if err != nil {
@@ -372,6 +353,7 @@ func (m *mainService) init(ctx context.Context) error {
 			AttachFilename:          val.Attach_Filename,
 			Trim:                    val.Trim,
 			TimestampWindow:         window,
+			FormatLearning:          val.Timestamp_Format_Learning,
 		}
 
 		lh, err := filewatch.NewLogHandler(cfg, pproc)
@@ -379,6 +361,7 @@ func (m *mainService) init(ctx context.Context) error {
 			errorout("Failed to generate handler: %v", err)
 			return err
 		}
+		tstats.Add(k, lh.TimeGrinder())
 		c := filewatch.WatchConfig{
 			ConfigName: k,
 			BaseDir:    val.Base_Directory,
@@ -395,6 +378,12 @@ func (m *mainService) init(ctx context.Context) error {
 		} else if val.Regex_Delimiter != `` {
 			c.Engine = filewatch.RegexEngine
 			c.EngineArgs = val.Regex_Delimiter
+		} else if mc, ok, err := val.Multiline(); err != nil {
+			errorout("Invalid multiline config: %v\n", err)
+			return err
+		} else if ok {
+			c.Engine = filewatch.MultilineEngine
+			c.Multiline = mc
 		} else {
 			c.Engine = filewatch.LineEngine
 		}
@@ -424,6 +413,8 @@ func (m *mainService) init(ctx context.Context) error {
 	if !quit {
 		if err = m.wtchr.Start(); err == nil {
 			debugout("File watcher started\n")
+			//format learning statistics show up in the ingester state
+			go tstats.Report(ctx, igst, utils.TimestampStatsInterval)
 		} else {
 			errorout("Failed to start file watcher: %v\n", err)
 		}
@@ -0,0 +1,274 @@
+//go:build linux || darwin
+// +build linux darwin
+
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gravwell/gravwell/v3/filewatch"
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+var (
+	errFollowerRunning = errors.New("follower is already running")
+)
+
+// runningFollower is a follower section that has been handed to the watcher
+type runningFollower struct {
+	conf follower
+	cfg  *cfgType //configuration the follower was started with
+	proc *processors.ProcessorSet
+	tg   *timegrinder.TimeGrinder
+}
+
+// followerSet holds every running follower keyed on its section name so that followers
+// can be added, removed, or rebuilt without restarting the watcher
+type followerSet struct {
+	sync.Mutex
+	wtcher  *filewatch.WatchManager
+	igst    *ingest.IngestMuxer
+	src     net.IP
+	window  timegrinder.TimestampWindow
+	tstats  *utils.TimestampStats
+	running map[string]*runningFollower
+}
+
+func newFollowerSet(wtcher *filewatch.WatchManager, igst *ingest.IngestMuxer, src net.IP, window timegrinder.TimestampWindow, tstats *utils.TimestampStats) *followerSet {
+	return &followerSet{
+		wtcher:  wtcher,
+		igst:    igst,
+		src:     src,
+		window:  window,
+		tstats:  tstats,
+		running: map[string]*runningFollower{},
+	}
+}
+
+// start builds the handler and preprocessors for a follower and adds it to the watcher
+func (fs *followerSet) start(k string, cfg *cfgType) (err error) {
+	val, ok := cfg.Followers()[k]
+	if !ok {
+		return fmt.Errorf("follower %s is not in the configuration", k)
+	}
+	fs.Lock()
+	_, ok = fs.running[k]
+	fs.Unlock()
+	if ok {
+		return errFollowerRunning
+	}
+
+	rf := &runningFollower{
+		conf: val,
+		cfg:  cfg,
+	}
+	if rf.proc, err = cfg.Preprocessor.ProcessorSet(fs.igst, val.Preprocessor); err != nil {
+		return fmt.Errorf("preprocessor construction error %w", err)
+	}
+	if rf.tg, err = fs.watch(k, val, cfg, rf.proc); err != nil {
+		rf.proc.Close()
+		return
+	}
+	fs.tstats.Add(k, rf.tg)
+	fs.Lock()
+	fs.running[k] = rf
+	fs.Unlock()
+	return
+}
+
+// watch hands a follower to the watcher, returning the timegrinder of its handler
+func (fs *followerSet) watch(k string, val follower, cfg *cfgType, proc *processors.ProcessorSet) (*timegrinder.TimeGrinder, error) {
+	//get the tag for this follower
+	tag, err := fs.igst.GetTag(val.Tag_Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tag %q %w", val.Tag_Name, err)
+	}
+	tsFmtOverride, err := val.TimestampOverride()
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp override %w", err)
+	}
+
+	//create our handler for this watcher
+	lhc := filewatch.LogHandlerConfig{
+		TagName:                 val.Tag_Name,
+		Tag:                     tag,
+		Src:                     fs.src,
+		IgnoreTS:                val.Ignore_Timestamps,
+		AssumeLocalTZ:           val.Assume_Local_Timezone,
+		IgnorePrefixes:          val.Ignore_Line_Prefix,
+		IgnoreGlobs:             val.Ignore_Glob,
+		TimestampFormatOverride: tsFmtOverride,
+		UserTimeRegex:           val.Timestamp_Regex,
+		UserTimeFormat:          val.Timestamp_Format_String,
+		Logger:                  lg,
+		TimezoneOverride:        val.Timezone_Override,
+		Ctx:                     fs.wtcher.Context(),
+		TimeFormat:              cfg.TimeFormat,
+		AttachFilename:          val.Attach_Filename,
+		Trim:                    val.Trim,
+		TimestampWindow:         fs.window,
+		FormatLearning:          val.Timestamp_Format_Learning,
+	}
+	if debugOn {
+		lhc.Debugger = debugout
+	}
+	lh, err := filewatch.NewLogHandler(lhc, proc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate handler %w", err)
+	}
+	c := filewatch.WatchConfig{
+		ConfigName: k,
+		BaseDir:    val.Base_Directory,
+		FileFilter: val.File_Filter,
+		Hnd:        lh,
+		Recursive:  val.Recursive,
+	}
+	if rex, ok, err := val.TimestampDelimited(); err != nil {
+		return nil, fmt.Errorf("invalid timestamp delimiter %w", err)
+	} else if ok {
+		c.Engine = filewatch.RegexEngine
+		c.EngineArgs = rex
+	} else if val.Regex_Delimiter != `` {
+		c.Engine = filewatch.RegexEngine
+		c.EngineArgs = val.Regex_Delimiter
+	} else if mc, ok, err := val.Multiline(); err != nil {
+		return nil, fmt.Errorf("invalid multiline config %w", err)
+	} else if ok {
+		c.Engine = filewatch.MultilineEngine
+		c.Multiline = mc
+	} else {
+		c.Engine = filewatch.LineEngine
+	}
+	if err = fs.wtcher.Add(c); err != nil {
+		//the watcher may have taken part of a recursive config
+		fs.wtcher.RemoveConfig(k)
+		return nil, fmt.Errorf("failed to add watch directory %s %w", val.Base_Directory, err)
+	}
+	return lh.TimeGrinder(), nil
+}
+
+// stop removes a follower from the watcher, closing its files, then flushes its preprocessors.
+// File states are kept so a follower that is rebuilt picks up where it left off.
+func (fs *followerSet) stop(k string) (err error) {
+	fs.Lock()
+	rf, ok := fs.running[k]
+	delete(fs.running, k)
+	fs.Unlock()
+	if !ok {
+		return
+	}
+	if err = fs.wtcher.RemoveConfig(k); err != nil {
+		err = fmt.Errorf("follower %s failed to stop %w", k, err)
+	}
+	fs.tstats.Remove(k, rf.tg)
+	if lerr := rf.proc.Close(); lerr != nil {
+		err = errors.Join(err, fmt.Errorf("follower %s failed to close preprocessors %w", k, lerr))
+	}
+	return
+}
+
+// flush pushes anything held in the running followers' preprocessors to the muxer so an
+// acknowledged state sync covers every line the followers have already consumed
+func (fs *followerSet) flush() (err error) {
+	fs.Lock()
+	procs := make(map[string]*processors.ProcessorSet, len(fs.running))
+	for k, rf := range fs.running {
+		procs[k] = rf.proc
+	}
+	fs.Unlock()
+	for k, proc := range procs {
+		if lerr := proc.Flush(); lerr != nil {
+			err = errors.Join(err, fmt.Errorf("follower %s failed to flush preprocessors %w", k, lerr))
+		}
+	}
+	return
+}
+
+// Close flushes every follower's preprocessors, the watcher must already be closed
+func (fs *followerSet) Close() (err error) {
+	fs.Lock()
+	defer fs.Unlock()
+	for _, rf := range fs.running {
+		if lerr := rf.proc.Close(); lerr != nil {
+			err = errors.Join(err, lerr)
+		}
+	}
+	fs.running = map[string]*runningFollower{}
+	return
+}
+
+// reload applies a new configuration, followers whose sections (including the global time
+// formats) and preprocessors are unchanged keep their files open.  The diff is taken against
+// the running followers, so a follower that failed to start is retried on the next reload.
+func (fs *followerSet) reload(oldObj, newObj interface{}) (err error) {
+	oldCfg, ok := oldObj.(*cfgType)
+	newCfg, nok := newObj.(*cfgType)
+	if !ok || !nok || newCfg == nil {
+		return fmt.Errorf("invalid configuration types %T %T", oldObj, newObj)
+	}
+	//global settings require a restart, keep running with the originals
+	newCfg.global = oldCfg.global
+
+	next := newCfg.Followers()
+	fs.Lock()
+	current := make(map[string]follower, len(fs.running))
+	for k, rf := range fs.running {
+		current[k] = rf.conf
+	}
+	fs.Unlock()
+
+	d := base.DiffSections(current, next)
+	restart := d.Changed
+	for k := range next {
+		if _, ok := current[k]; !ok || slices.Contains(restart, k) {
+			continue
+		}
+		fs.Lock()
+		rf := fs.running[k]
+		fs.Unlock()
+		if rf != nil && base.PreprocessorsChanged(rf.cfg.Preprocessor, newCfg.Preprocessor, rf.conf.Preprocessor) {
+			restart = append(restart, k)
+		}
+	}
+	sort.Strings(restart)
+
+	for _, k := range append(d.Removed, restart...) {
+		if lerr := fs.stop(k); lerr != nil {
+			err = errors.Join(err, lerr)
+		}
+		lg.Info("stopped follower", log.KV("follower", k))
+	}
+	var failed []string
+	for _, k := range append(restart, d.Added...) {
+		if lerr := fs.start(k, newCfg); lerr != nil {
+			err = errors.Join(err, lerr)
+			failed = append(failed, k)
+			lg.Error("failed to start follower", log.KV("follower", k), log.KVErr(lerr))
+			continue
+		}
+		lg.Info("started follower", log.KV("follower", k))
+	}
+	if err != nil {
+		//everything else is running under the new configuration
+		err = &base.PartialReloadError{Failed: failed, Err: err}
+	}
+	return
+}
@@ -0,0 +1,202 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/journald"
+)
+
+const (
+	defaultBookmarkLoc  = `/opt/gravwell/etc/journald.bookmark`
+	defaultPollInterval = time.Second
+)
+
+var (
+	// defaultEnumeratedFields are attached to every entry unless a journal lists its own
+	defaultEnumeratedFields = []string{
+		`_SYSTEMD_UNIT`, `PRIORITY`, `SYSLOG_IDENTIFIER`, `_PID`,
+		`_HOSTNAME`, `_COMM`, `_TRANSPORT`, `_BOOT_ID`,
+	}
+)
+
+type journal struct {
+	Journal_Path      []string // directories to read, defaults to /var/log/journal and /run/log/journal
+	Tag_Name          string
+	Match             []string // FIELD=value matches, same field is ORed different fields are ANDed
+	Unit_Tag          []string // glob=tag, entries from matching systemd units go to another tag
+	Enumerated_Field  []string // journal fields attached as enumerated values
+	Read_History      bool     // read entries that were in the journal before the first start
+	Message_Only      bool     // send just the MESSAGE field instead of every field as JSON
+	Ignore_Timestamps bool
+	Source_Override   string
+	Preprocessor      []string
+}
+
+type unitTag struct {
+	glob string
+	tag  string
+}
+
+type cfgType struct {
+	Global struct {
+		config.IngestConfig
+		Bookmark_Location string
+		Poll_Interval     string
+	}
+	Attach       attach.AttachConfig
+	Journal      map[string]*journal
+	Preprocessor processors.ProcessorConfig
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	}
+
+	if len(c.Journal) == 0 {
+		return errors.New("No Journal sections specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+	if c.Global.Bookmark_Location == `` {
+		c.Global.Bookmark_Location = defaultBookmarkLoc
+	}
+	if _, err := c.PollInterval(); err != nil {
+		return err
+	}
+
+	for k, v := range c.Journal {
+		if len(v.Tag_Name) == 0 {
+			v.Tag_Name = entry.DefaultTagName
+		}
+		if ingest.CheckTag(v.Tag_Name) != nil {
+			return errors.New("Invalid characters in the Tag-Name for " + k)
+		}
+		if _, err := journald.NewMatcher(v.Match); err != nil {
+			return fmt.Errorf("Journal %s: %w", k, err)
+		}
+		if _, err := v.unitTags(); err != nil {
+			return fmt.Errorf("Journal %s: %w", k, err)
+		}
+		if len(v.Enumerated_Field) == 0 {
+			v.Enumerated_Field = defaultEnumeratedFields
+		}
+		for _, f := range v.Enumerated_Field {
+			if !journald.ValidFieldName(f) {
+				return fmt.Errorf("Journal %s has an invalid Enumerated-Field %q", k, f)
+			}
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("Journal %s preprocessor invalid: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := make(map[string]bool, 1)
+	add := func(tag string) {
+		if _, ok := tagMp[tag]; !ok {
+			tags = append(tags, tag)
+			tagMp[tag] = true
+		}
+	}
+
+	for _, v := range c.Journal {
+		if len(v.Tag_Name) == 0 {
+			continue
+		}
+		add(v.Tag_Name)
+		uts, err := v.unitTags()
+		if err != nil {
+			return nil, err
+		}
+		for _, ut := range uts {
+			add(ut.tag)
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// PollInterval is how long journal readers wait between looking for new entries
+func (c *cfgType) PollInterval() (time.Duration, error) {
+	if c.Global.Poll_Interval == `` {
+		return defaultPollInterval, nil
+	}
+	d, err := time.ParseDuration(c.Global.Poll_Interval)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid Poll-Interval %q: %w", c.Global.Poll_Interval, err)
+	} else if d <= 0 {
+		return 0, fmt.Errorf("Invalid Poll-Interval %q, must be positive", c.Global.Poll_Interval)
+	}
+	return d, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global.IngestConfig
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
+
+// unitTags parses the Unit-Tag values, first match wins so order is preserved
+func (j *journal) unitTags() (r []unitTag, err error) {
+	for _, v := range j.Unit_Tag {
+		glob, tag, ok := strings.Cut(v, `=`)
+		glob, tag = strings.TrimSpace(glob), strings.TrimSpace(tag)
+		if !ok || glob == `` || tag == `` {
+			return nil, fmt.Errorf("Unit-Tag %q must be of the form unit-glob=tag", v)
+		} else if _, err = path.Match(glob, ``); err != nil {
+			return nil, fmt.Errorf("Unit-Tag %q has a bad unit glob: %w", v, err)
+		} else if err = ingest.CheckTag(tag); err != nil {
+			return nil, fmt.Errorf("Unit-Tag %q has an invalid tag: %w", v, err)
+		}
+		r = append(r, unitTag{glob: glob, tag: tag})
+	}
+	return
+}
@@ -0,0 +1,315 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
+	_ "time/tzdata"
+
+	"github.com/gravwell/gravwell/v3/debug"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/journald"
+)
+
+const (
+	defaultConfigLoc  = `/opt/gravwell/etc/journald.conf`
+	defaultConfigDLoc = `/opt/gravwell/etc/journald.conf.d`
+	ingesterName      = `journald`
+)
+
+var (
+	lg *log.Logger
+)
+
+type resolvedUnitTag struct {
+	glob string
+	tag  entry.EntryTag
+}
+
+type handlerConfig struct {
+	name             string
+	reader           *journald.Reader
+	bookmark         *journald.BookmarkHandler
+	tag              entry.EntryTag
+	unitTags         []resolvedUnitTag
+	evFields         []string
+	src              net.IP
+	proc             *processors.ProcessorSet
+	ctx              context.Context
+	interval         time.Duration
+	messageOnly      bool
+	ignoreTimestamps bool
+}
+
+func main() {
+	go debug.HandleDebugSignals(ingesterName)
+
+	var cfg *cfgType
+	ibc := base.IngesterBaseConfig{
+		IngesterName:                 ingesterName,
+		AppName:                      ingesterName,
+		DefaultConfigLocation:        defaultConfigLoc,
+		DefaultConfigOverlayLocation: defaultConfigDLoc,
+		GetConfigFunc:                GetConfig,
+	}
+	ib, err := base.Init(ibc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get configuration %v\n", err)
+		return
+	} else if err = ib.AssignConfig(&cfg); err != nil || cfg == nil {
+		fmt.Fprintf(os.Stderr, "failed to assign configuration %v %v\n", err, cfg == nil)
+		return
+	}
+	lg = ib.Logger
+
+	id, ok := cfg.Global.IngesterUUID()
+	if !ok {
+		ib.Logger.FatalCode(0, "could not read ingester UUID")
+	}
+	interval, err := cfg.PollInterval()
+	if err != nil {
+		lg.FatalCode(0, "invalid poll interval", log.KVErr(err))
+	}
+	bookmark, err := journald.NewBookmark(cfg.Global.Bookmark_Location)
+	if err != nil {
+		lg.FatalCode(0, "failed to open bookmark", log.KV("path", cfg.Global.Bookmark_Location), log.KVErr(err))
+	}
+	igst, err := ib.GetMuxer()
+	if err != nil {
+		ib.Logger.FatalCode(0, "failed to get ingest connection", log.KVErr(err))
+		return
+	}
+	defer igst.Close()
+	ib.AnnounceStartup()
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	var handlers []*handlerConfig
+
+	for k, v := range cfg.Journal {
+		var src net.IP
+
+		if v.Source_Override != `` {
+			src = net.ParseIP(v.Source_Override)
+			if src == nil {
+				lg.FatalCode(0, "Source-Override is invalid", log.KV("sourceoverride", v.Source_Override), log.KV("journal", k))
+			}
+		} else if cfg.Global.Source_Override != `` {
+			// global override
+			src = net.ParseIP(cfg.Global.Source_Override)
+			if src == nil {
+				lg.FatalCode(0, "Global Source-Override is invalid", log.KV("sourceoverride", cfg.Global.Source_Override))
+			}
+		}
+
+		tag, err := igst.GetTag(v.Tag_Name)
+		if err != nil {
+			lg.Fatal("failed to resolve tag", log.KV("journal", k), log.KV("tag", v.Tag_Name), log.KVErr(err))
+		}
+		uts, err := v.unitTags()
+		if err != nil {
+			lg.FatalCode(0, "invalid unit tags", log.KV("journal", k), log.KVErr(err))
+		}
+		hcfg := &handlerConfig{
+			name:             k,
+			bookmark:         bookmark,
+			tag:              tag,
+			evFields:         v.Enumerated_Field,
+			src:              src,
+			ctx:              ctx,
+			interval:         interval,
+			messageOnly:      v.Message_Only,
+			ignoreTimestamps: v.Ignore_Timestamps,
+		}
+		for _, ut := range uts {
+			rut := resolvedUnitTag{glob: ut.glob}
+			if rut.tag, err = igst.GetTag(ut.tag); err != nil {
+				lg.Fatal("failed to resolve unit tag", log.KV("journal", k), log.KV("tag", ut.tag), log.KVErr(err))
+			}
+			hcfg.unitTags = append(hcfg.unitTags, rut)
+		}
+
+		mtchr, err := journald.NewMatcher(v.Match)
+		if err != nil {
+			lg.FatalCode(0, "invalid journal match", log.KV("journal", k), log.KVErr(err))
+		}
+		hcfg.reader, err = journald.NewReader(journald.ReaderConfig{
+			Name:        k,
+			Paths:       v.Journal_Path,
+			Bookmark:    bookmark,
+			Matcher:     mtchr,
+			ReadHistory: v.Read_History,
+			Logger:      lg,
+		})
+		if err != nil {
+			lg.FatalCode(0, "failed to create journal reader", log.KV("journal", k), log.KVErr(err))
+		}
+
+		if hcfg.proc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			lg.FatalCode(0, "preprocessor construction error", log.KVErr(err))
+		}
+		handlers = append(handlers, hcfg)
+	}
+
+	for _, h := range handlers {
+		wg.Add(1)
+		go h.run(&wg)
+	}
+
+	// listen for signals so we can close gracefully
+	utils.WaitForQuit()
+	ib.AnnounceShutdown()
+
+	cancel()
+	wg.Wait()
+
+	lg.Info("journald ingester exiting", log.KV("ingesteruuid", id))
+	for _, h := range handlers {
+		if err := h.proc.Close(); err != nil {
+			lg.Error("failed to close preprocessors", log.KV("journal", h.name), log.KVErr(err))
+		}
+		h.reader.Close()
+	}
+	if err := igst.Sync(utils.ExitSyncTimeout); err != nil {
+		lg.Error("failed to sync", log.KVErr(err))
+	}
+	if err := bookmark.Close(); err != nil {
+		lg.Error("failed to close bookmark", log.KVErr(err))
+	}
+	if err := igst.Close(); err != nil {
+		lg.Error("failed to close", log.KVErr(err))
+	}
+}
+
+func (h *handlerConfig) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	tckr := time.NewTicker(h.interval)
+	defer tckr.Stop()
+	for {
+		if err := h.reader.Poll(h.handle); err != nil && h.ctx.Err() == nil {
+			lg.Error("failed to read journal", log.KV("journal", h.name), log.KVErr(err))
+		}
+		if err := h.bookmark.Sync(); err != nil {
+			lg.Error("failed to sync bookmark", log.KV("journal", h.name), log.KVErr(err))
+		}
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-tckr.C:
+		}
+	}
+}
+
+func (h *handlerConfig) handle(je *journald.Entry) error {
+	if err := h.ctx.Err(); err != nil {
+		return err
+	}
+	var data []byte
+	if h.messageOnly {
+		msg, ok := je.Get(`MESSAGE`)
+		if !ok || len(msg) == 0 {
+			return nil
+		}
+		data = append([]byte(nil), msg...)
+	} else {
+		var err error
+		if data, err = encodeEntry(je); err != nil {
+			lg.Error("failed to encode journal entry", log.KV("journal", h.name), log.KVErr(err))
+			return nil
+		}
+	}
+	ent := &entry.Entry{
+		SRC:  h.src,
+		TS:   entry.FromStandard(je.Realtime),
+		Tag:  h.routeTag(je),
+		Data: data,
+	}
+	if h.ignoreTimestamps {
+		ent.TS = entry.Now()
+	}
+	for _, name := range h.evFields {
+		if v, ok := je.Get(name); ok {
+			if err := ent.AddEnumeratedValueEx(evName(name), evValue(name, v)); err != nil {
+				lg.Warn("failed to attach enumerated value", log.KV("journal", h.name), log.KV("field", name), log.KVErr(err))
+			}
+		}
+	}
+	return h.proc.ProcessContext(ent, h.ctx)
+}
+
+// routeTag picks the first unit tag whose glob matches the entry's systemd unit
+func (h *handlerConfig) routeTag(je *journald.Entry) entry.EntryTag {
+	if len(h.unitTags) > 0 {
+		if unit, ok := je.Get(`_SYSTEMD_UNIT`); ok {
+			for _, ut := range h.unitTags {
+				if ok, _ := path.Match(ut.glob, string(unit)); ok {
+					return ut.tag
+				}
+			}
+		}
+	}
+	return h.tag
+}
+
+// encodeEntry renders an entry the way journalctl -o json does, fields that repeat become arrays
+func encodeEntry(je *journald.Entry) ([]byte, error) {
+	mp := make(map[string]interface{}, len(je.Fields)+2)
+	mp[`__REALTIME_TIMESTAMP`] = strconv.FormatInt(je.Realtime.UnixMicro(), 10)
+	mp[`__MONOTONIC_TIMESTAMP`] = strconv.FormatUint(je.Monotonic, 10)
+	for _, f := range je.Fields {
+		var v interface{} = string(f.Value)
+		if !utf8.Valid(f.Value) {
+			v = f.Value
+		}
+		switch cur := mp[f.Name].(type) {
+		case nil:
+			mp[f.Name] = v
+		case []interface{}:
+			mp[f.Name] = append(cur, v)
+		default:
+			mp[f.Name] = []interface{}{cur, v}
+		}
+	}
+	return json.Marshal(mp)
+}
+
+// evName turns a journal field name into an enumerated value name, _SYSTEMD_UNIT becomes systemd_unit
+func evName(name string) string {
+	return strings.ToLower(strings.TrimLeft(name, `_`))
+}
+
+func evValue(name string, v []byte) interface{} {
+	switch name {
+	case `PRIORITY`:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+	case `_PID`, `_UID`, `_GID`:
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+	}
+	return string(v)
+}
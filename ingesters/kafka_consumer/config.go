@@ -24,6 +24,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/ingest/processors/tags"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/timegrinder"
 	"github.com/xdg-go/scram"
 )
@@ -120,6 +121,7 @@ type cfgReadType struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	config.IngestConfig
 	Attach       attach.AttachConfig
 	Consumers    map[string]*consumerCfg
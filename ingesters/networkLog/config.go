@@ -18,6 +18,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/attach"
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 )
 
 const (
@@ -48,6 +49,7 @@ type snif struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	config.IngestConfig
 	Attach  attach.AttachConfig
 	Sniffer map[string]*snif
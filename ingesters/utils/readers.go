@@ -29,6 +29,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/klauspost/compress/zstd"
 )
 
 const (
@@ -46,6 +47,10 @@ const (
 	bzip2Magic2 = 'Z'
 )
 
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
 var (
 	errInvalidColumns = errors.New("invalid csv import columns")
 	csvCols           = []string{`Timestamp`, `Source`, `Tag`, `Data`}
@@ -54,7 +59,7 @@ var (
 )
 
 // NewCompressedReader wraps an io.Reader in a bufio.Reader and automatically
-// detects and handles gzip, bzip2, or raw data streams. It returns an io.Reader
+// detects and handles gzip, bzip2, zstd, or raw data streams. It returns an io.Reader
 // that transparently decompresses the data if compression is detected.
 func NewCompressedReader(r io.Reader) (io.Reader, error) {
 	if r == nil {
@@ -63,8 +68,8 @@ func NewCompressedReader(r io.Reader) (io.Reader, error) {
 
 	br := bufio.NewReader(r)
 
-	// Peek at the first 2 bytes to detect compression format
-	header, err := br.Peek(2)
+	// Peek at the first 4 bytes to detect compression format
+	header, err := br.Peek(len(zstdMagic))
 	if err != nil && err != io.EOF {
 		return nil, errPeekFailed
 	}
@@ -84,6 +89,15 @@ func NewCompressedReader(r io.Reader) (io.Reader, error) {
 		return bzip2.NewReader(br), nil
 	}
 
+	// Check for zstd magic bytes (0x28 0xb5 0x2f 0xfd)
+	if bytes.HasPrefix(header, zstdMagic) {
+		dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+
 	// No compression detected, return the buffered reader
 	return br, nil
 }
@@ -0,0 +1,128 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+const (
+	// TimestampStatsInterval is how often format learning statistics are pushed into the ingester state
+	TimestampStatsInterval = time.Minute
+)
+
+// TimestampStats gathers the format learning statistics of an ingester's timegrinders under the
+// name of the configuration section that owns them.  Timegrinders that go away, such as those of
+// closed connections, keep counting toward their section.  A nil TimestampStats ignores everything.
+type TimestampStats struct {
+	mtx     sync.Mutex
+	live    map[string]map[*timegrinder.TimeGrinder]struct{}
+	retired map[string]timegrinder.Stats
+}
+
+type timestampMetadata struct {
+	TimestampLearning map[string]timegrinder.Stats
+}
+
+func NewTimestampStats() *TimestampStats {
+	return &TimestampStats{
+		live:    map[string]map[*timegrinder.TimeGrinder]struct{}{},
+		retired: map[string]timegrinder.Stats{},
+	}
+}
+
+// Add starts tracking a timegrinder, timegrinders without format learning are ignored
+func (ts *TimestampStats) Add(name string, tg *timegrinder.TimeGrinder) {
+	if ts == nil || tg == nil || !tg.EnableFormatLearning {
+		return
+	}
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	set, ok := ts.live[name]
+	if !ok {
+		set = map[*timegrinder.TimeGrinder]struct{}{}
+		ts.live[name] = set
+	}
+	set[tg] = struct{}{}
+}
+
+// Remove stops tracking a timegrinder, its counters stay with the name but its keys are forgotten
+func (ts *TimestampStats) Remove(name string, tg *timegrinder.TimeGrinder) {
+	if ts == nil || tg == nil {
+		return
+	}
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	set, ok := ts.live[name]
+	if !ok {
+		return
+	} else if _, ok = set[tg]; !ok {
+		return
+	}
+	delete(set, tg)
+	s := tg.Stats()
+	s.Keys = 0
+	r := ts.retired[name]
+	r.Merge(s)
+	ts.retired[name] = r
+}
+
+// Stats returns the merged statistics for every name that has tracked a timegrinder
+func (ts *TimestampStats) Stats() (r map[string]timegrinder.Stats) {
+	if ts == nil {
+		return
+	}
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	r = make(map[string]timegrinder.Stats, len(ts.live))
+	for name, s := range ts.retired {
+		s.Processors = append([]timegrinder.ProcessorStats(nil), s.Processors...)
+		r[name] = s
+	}
+	for name, set := range ts.live {
+		s, ok := r[name]
+		for tg := range set {
+			s.Merge(tg.Stats())
+			ok = true
+		}
+		if ok {
+			r[name] = s
+		}
+	}
+	return
+}
+
+// Report pushes the statistics into the ingester state metadata every interval until the context is done
+func (ts *TimestampStats) Report(ctx context.Context, igst *ingest.IngestMuxer, interval time.Duration) {
+	if ts == nil || igst == nil {
+		return
+	}
+	tckr := time.NewTicker(interval)
+	defer tckr.Stop()
+	var last map[string]timegrinder.Stats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tckr.C:
+		}
+		//only touch the state when something moved so we don't force state pushes
+		if curr := ts.Stats(); len(curr) > 0 && !reflect.DeepEqual(curr, last) {
+			if err := igst.SetMetadata(timestampMetadata{TimestampLearning: curr}); err == nil {
+				last = curr
+			}
+		}
+	}
+}
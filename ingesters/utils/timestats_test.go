@@ -0,0 +1,60 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+func TestTimestampStats(t *testing.T) {
+	var nilStats *TimestampStats
+	nilStats.Add(`a`, nil)
+	if nilStats.Stats() != nil {
+		t.Fatal("nil stats returned something")
+	}
+
+	ts := NewTimestampStats()
+	newTG := func(learn bool) *timegrinder.TimeGrinder {
+		tg, err := timegrinder.New(timegrinder.Config{EnableFormatLearning: learn})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tg
+	}
+	a, b, off := newTG(true), newTG(true), newTG(false)
+	ts.Add(`listener`, a)
+	ts.Add(`listener`, b)
+	ts.Add(`plain`, off)
+	for i, ln := range []string{`2018-04-19T05:50:19Z one`, `2018-04-19T05:50:20Z two`, `no timestamp`} {
+		if _, _, err := a.ExtractKey(timegrinder.FormatKey{Source: `10.0.0.1`}, []byte(ln)); err != nil {
+			t.Fatal(err)
+		} else if i == 0 {
+			b.ExtractKey(timegrinder.FormatKey{Source: `10.0.0.2`}, []byte(ln))
+		}
+	}
+	st := ts.Stats()
+	if _, ok := st[`plain`]; ok || len(st) != 1 {
+		t.Fatalf("timegrinder without learning was tracked %v", st)
+	} else if s := st[`listener`]; s.Hits != 1 || s.Misses != 3 || s.Failures != 1 || s.Keys != 1 {
+		t.Fatalf("bad live stats %+v", s)
+	}
+
+	//a removed timegrinder keeps counting but its keys are gone
+	ts.Remove(`listener`, b)
+	ts.Remove(`listener`, b)
+	if s := ts.Stats()[`listener`]; s.Hits != 1 || s.Misses != 3 || s.Keys != 0 {
+		t.Fatalf("bad stats after remove %+v", s)
+	}
+	ts.Remove(`listener`, a)
+	if s := ts.Stats()[`listener`]; s.Hits != 1 || s.Misses != 3 || s.Keys != 0 {
+		t.Fatalf("bad retired stats %+v", s)
+	}
+}
@@ -0,0 +1,431 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package journald reads systemd journal files directly, without libsystemd.
+// Journal files are scanned object by object, so entries are handed out in the
+// order they were written and a file that is still being written can be read
+// again as it grows.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	headerSignature = "LPKSHHRH"
+
+	// the header grew over time, everything we need is in the first 208 bytes
+	minHeaderSize    = 208
+	objectHeaderSize = 16
+	entryItemsOffset = 64
+	dataPayload      = 64
+	compactPayload   = 72
+
+	objectData  = 1
+	objectEntry = 3
+
+	objCompressedXZ   = 1 << 0
+	objCompressedLZ4  = 1 << 1
+	objCompressedZSTD = 1 << 2
+
+	incompatCompressedXZ   = 1 << 0
+	incompatCompressedLZ4  = 1 << 1
+	incompatKeyedHash      = 1 << 2
+	incompatCompressedZSTD = 1 << 3
+	incompatCompact        = 1 << 4
+	incompatSupported      = incompatCompressedXZ | incompatCompressedLZ4 | incompatKeyedHash | incompatCompressedZSTD | incompatCompact
+
+	// maxObjectSize is a sanity check, journald refuses to write fields anywhere near this large
+	maxObjectSize = 256 * 1024 * 1024
+
+	// maxDataCache bounds the number of decoded data objects we hold on to, the same
+	// fields show up in entry after entry so this saves a lot of reads
+	maxDataCache = 4096
+)
+
+var (
+	ErrBadSignature           = errors.New("not a journal file")
+	ErrUnsupportedFeatures    = errors.New("journal file uses unsupported features")
+	ErrUnsupportedCompression = errors.New("journal object uses unsupported compression")
+	ErrCorrupt                = errors.New("journal file is corrupt")
+
+	// errNotReady means we caught an object that journald is still filling in
+	errNotReady = errors.New("journal object not ready")
+)
+
+// EntryError is returned by Next when a single entry cannot be decoded, the file is
+// left positioned after the entry so reading can carry on with the next one.
+type EntryError struct {
+	Seqnum uint64
+	Err    error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("bad journal entry %d: %v", e.Seqnum, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// ID128 is a systemd 128 bit ID
+type ID128 [16]byte
+
+func (id ID128) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Header holds the parts of a journal file header we care about
+type Header struct {
+	CompatibleFlags   uint32
+	IncompatibleFlags uint32
+	State             uint8
+	FileID            ID128
+	MachineID         ID128
+	BootID            ID128
+	SeqnumID          ID128
+	HeaderSize        uint64
+	ArenaSize         uint64
+	TailObjectOffset  uint64
+	NEntries          uint64
+	TailEntrySeqnum   uint64
+	HeadEntrySeqnum   uint64
+	HeadEntryRealtime uint64
+	TailEntryRealtime uint64
+}
+
+// Field is a single FIELD=value pair, values may be binary
+type Field struct {
+	Name  string
+	Value []byte
+}
+
+// Entry is a single journal entry
+type Entry struct {
+	Seqnum    uint64
+	Realtime  time.Time
+	Monotonic uint64
+	BootID    ID128
+	Fields    []Field
+}
+
+// Get returns the value of the first instance of the named field
+func (e *Entry) Get(name string) (v []byte, ok bool) {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return
+}
+
+// File is an open journal file
+type File struct {
+	f       *os.File
+	path    string
+	hdr     Header
+	compact bool
+	next    uint64 // offset of the next object to scan
+	last    uint64 // offset of the last entry handed out
+	dcache  map[uint64]Field
+	zdec    *zstd.Decoder
+}
+
+// Open opens a journal file and reads its header
+func Open(path string) (*File, error) {
+	fin, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	jf := &File{
+		f:      fin,
+		path:   path,
+		dcache: make(map[uint64]Field),
+	}
+	if err = jf.Refresh(); err != nil {
+		fin.Close()
+		return nil, err
+	}
+	jf.next = align8(jf.hdr.HeaderSize)
+	return jf, nil
+}
+
+// Path returns the path the file was opened with
+func (jf *File) Path() string {
+	return jf.path
+}
+
+// Header returns the header as of the last Refresh
+func (jf *File) Header() Header {
+	return jf.hdr
+}
+
+// Refresh re-reads the header so that entries appended to an online file can be read
+func (jf *File) Refresh() error {
+	buf := make([]byte, minHeaderSize)
+	if _, err := jf.f.ReadAt(buf, 0); err != nil {
+		if err == io.EOF {
+			return ErrBadSignature
+		}
+		return err
+	} else if !bytes.Equal(buf[:8], []byte(headerSignature)) {
+		return ErrBadSignature
+	}
+	h := Header{
+		CompatibleFlags:   le32(buf[8:]),
+		IncompatibleFlags: le32(buf[12:]),
+		State:             buf[16],
+		HeaderSize:        le64(buf[88:]),
+		ArenaSize:         le64(buf[96:]),
+		TailObjectOffset:  le64(buf[136:]),
+		NEntries:          le64(buf[152:]),
+		TailEntrySeqnum:   le64(buf[160:]),
+		HeadEntrySeqnum:   le64(buf[168:]),
+		HeadEntryRealtime: le64(buf[184:]),
+		TailEntryRealtime: le64(buf[192:]),
+	}
+	copy(h.FileID[:], buf[24:40])
+	copy(h.MachineID[:], buf[40:56])
+	copy(h.BootID[:], buf[56:72])
+	copy(h.SeqnumID[:], buf[72:88])
+	if h.IncompatibleFlags&^incompatSupported != 0 {
+		return fmt.Errorf("%w: %#x", ErrUnsupportedFeatures, h.IncompatibleFlags)
+	} else if h.HeaderSize < minHeaderSize {
+		return ErrCorrupt
+	}
+	jf.hdr = h
+	jf.compact = h.IncompatibleFlags&incompatCompact != 0
+	return nil
+}
+
+// Next returns the next entry with a sequence number after the given one, entries at or
+// before it are skipped without decoding their fields.  io.EOF means there are no more entries
+// for now, an online file may have more after a Refresh.  An *EntryError means a single entry
+// was bad and has been skipped, any other error means the rest of the file cannot be read.
+func (jf *File) Next(after uint64) (*Entry, error) {
+	end := jf.hdr.HeaderSize + jf.hdr.ArenaSize
+	for jf.next != 0 && jf.next <= jf.hdr.TailObjectOffset {
+		off := jf.next
+		typ, size, err := jf.objectHeader(off)
+		if err == errNotReady {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		} else if size < objectHeaderSize || typ == 0 {
+			//allocated but not written yet
+			return nil, io.EOF
+		} else if size > maxObjectSize || off+size > end {
+			return nil, ErrCorrupt
+		}
+		if typ != objectEntry {
+			jf.next = off + align8(size)
+			continue
+		}
+		ent, err := jf.readEntry(off, size, after)
+		if err == errNotReady {
+			return nil, io.EOF
+		}
+		jf.next = off + align8(size)
+		if err != nil {
+			return nil, err
+		}
+		if ent != nil {
+			jf.last = off
+			return ent, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+// unread backs up so that the last entry is handed out again
+func (jf *File) unread() {
+	if jf.last != 0 {
+		jf.next = jf.last
+	}
+}
+
+// Close closes the underlying file
+func (jf *File) Close() error {
+	if jf.zdec != nil {
+		jf.zdec.Close()
+	}
+	return jf.f.Close()
+}
+
+func (jf *File) objectHeader(off uint64) (typ uint8, size uint64, err error) {
+	var buf [objectHeaderSize]byte
+	if _, err = jf.f.ReadAt(buf[:], int64(off)); err != nil {
+		if err == io.EOF {
+			//the header claims an object we can't see yet
+			err = errNotReady
+		}
+		return
+	}
+	typ, size = buf[0], le64(buf[8:])
+	return
+}
+
+func (jf *File) readObject(off, size uint64) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := jf.f.ReadAt(buf, int64(off)); err != nil {
+		if err == io.EOF {
+			return nil, errNotReady
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readEntry decodes the entry object at off, nil is returned for entries at or before after.
+// Fields using a compression we cannot decode are left out of the entry.
+func (jf *File) readEntry(off, size, after uint64) (*Entry, error) {
+	if size < entryItemsOffset {
+		return nil, &EntryError{Err: ErrCorrupt}
+	}
+	buf, err := jf.readObject(off, size)
+	if err != nil {
+		return nil, err
+	}
+	ent := &Entry{
+		Seqnum:    le64(buf[16:]),
+		Monotonic: le64(buf[32:]),
+	}
+	if ent.Seqnum == 0 {
+		return nil, errNotReady
+	} else if ent.Seqnum <= after {
+		return nil, nil
+	}
+	ent.Realtime = time.UnixMicro(int64(le64(buf[24:])))
+	copy(ent.BootID[:], buf[40:56])
+
+	itemSize := uint64(16)
+	if jf.compact {
+		itemSize = 4
+	}
+	items := buf[entryItemsOffset:]
+	ent.Fields = make([]Field, 0, uint64(len(items))/itemSize)
+	for len(items) >= int(itemSize) {
+		var doff uint64
+		if jf.compact {
+			doff = uint64(le32(items))
+		} else {
+			doff = le64(items)
+		}
+		items = items[itemSize:]
+		if doff == 0 {
+			return nil, errNotReady
+		}
+		fld, err := jf.readData(doff)
+		if err == ErrUnsupportedCompression {
+			continue
+		} else if err == errNotReady {
+			return nil, err
+		} else if err != nil {
+			return nil, &EntryError{Seqnum: ent.Seqnum, Err: err}
+		}
+		ent.Fields = append(ent.Fields, fld)
+	}
+	return ent, nil
+}
+
+func (jf *File) readData(off uint64) (fld Field, err error) {
+	var ok bool
+	if fld, ok = jf.dcache[off]; ok {
+		return
+	}
+	typ, size, err := jf.objectHeader(off)
+	if err != nil {
+		return
+	} else if typ != objectData || size > maxObjectSize {
+		err = ErrCorrupt
+		return
+	}
+	start := uint64(dataPayload)
+	if jf.compact {
+		start = compactPayload
+	}
+	if size < start {
+		err = ErrCorrupt
+		return
+	}
+	buf, err := jf.readObject(off, size)
+	if err != nil {
+		return
+	}
+	payload := buf[start:]
+	if payload, err = jf.decompress(buf[1], payload); err != nil {
+		return
+	}
+	idx := bytes.IndexByte(payload, '=')
+	if idx <= 0 {
+		err = ErrCorrupt
+		return
+	}
+	fld = Field{
+		Name:  string(payload[:idx]),
+		Value: payload[idx+1:],
+	}
+	if len(jf.dcache) >= maxDataCache {
+		clear(jf.dcache)
+	}
+	jf.dcache[off] = fld
+	return
+}
+
+func (jf *File) decompress(flags uint8, payload []byte) ([]byte, error) {
+	switch {
+	case flags&objCompressedZSTD != 0:
+		if jf.zdec == nil {
+			var err error
+			if jf.zdec, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+				return nil, err
+			}
+		}
+		return jf.zdec.DecodeAll(payload, nil)
+	case flags&objCompressedLZ4 != 0:
+		//lz4 payloads are prefixed with the decompressed size
+		if len(payload) < 8 {
+			return nil, ErrCorrupt
+		}
+		sz := le64(payload)
+		if sz > maxObjectSize {
+			return nil, ErrCorrupt
+		}
+		out := make([]byte, sz)
+		n, err := lz4.UncompressBlock(payload[8:], out)
+		if err != nil {
+			return nil, err
+		}
+		return out[:n], nil
+	case flags&objCompressedXZ != 0:
+		return nil, ErrUnsupportedCompression
+	}
+	return payload, nil
+}
+
+func align8(v uint64) uint64 {
+	return (v + 7) &^ 7
+}
+
+func le32(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b)
+}
+
+func le64(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
@@ -0,0 +1,248 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journald
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const testHeaderSize = 272
+
+var testEpoch = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// testJournal builds journal files the way journald lays them out, minus the hash tables
+type testJournal struct {
+	seqnumID ID128
+	compact  bool
+	compress uint8 // object compression flag applied to long fields
+	entries  [][]string
+	start    uint64
+	partial  bool         // leave the last entry half written
+	xzField  string       // field flagged as XZ compressed
+	corrupt  map[int]bool // entries whose first item points at something that isn't a data object
+}
+
+func (tj *testJournal) bytes(t *testing.T) []byte {
+	buf := make([]byte, testHeaderSize)
+	copy(buf, headerSignature)
+	var incompat uint32
+	if tj.compact {
+		incompat |= incompatCompact
+	}
+	switch tj.compress {
+	case objCompressedZSTD:
+		incompat |= incompatCompressedZSTD
+	case objCompressedLZ4:
+		incompat |= incompatCompressedLZ4
+	}
+	binary.LittleEndian.PutUint32(buf[12:], incompat)
+	copy(buf[72:], tj.seqnumID[:])
+	binary.LittleEndian.PutUint64(buf[88:], testHeaderSize)
+
+	var tail uint64
+	appendObj := func(typ, flags uint8, body []byte) uint64 {
+		off := uint64(len(buf))
+		hdr := make([]byte, objectHeaderSize)
+		hdr[0], hdr[1] = typ, flags
+		binary.LittleEndian.PutUint64(hdr[8:], uint64(objectHeaderSize+len(body)))
+		buf = append(buf, hdr...)
+		buf = append(buf, body...)
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		tail = off
+		return off
+	}
+	//a field object up front that the reader has to skip over
+	appendObj(2, 0, make([]byte, 24))
+
+	datas := map[string]uint64{}
+	for i, fields := range tj.entries {
+		var offs []uint64
+		for _, f := range fields {
+			if off, ok := datas[f]; ok {
+				offs = append(offs, off)
+				continue
+			}
+			payload, flags := []byte(f), uint8(0)
+			if tj.compress != 0 && len(f) > 64 {
+				payload, flags = compressPayload(t, tj.compress, payload), tj.compress
+			}
+			if f == tj.xzField {
+				flags = objCompressedXZ
+			}
+			body := make([]byte, dataPayload-objectHeaderSize)
+			if tj.compact {
+				body = make([]byte, compactPayload-objectHeaderSize)
+			}
+			off := appendObj(objectData, flags, append(body, payload...))
+			datas[f] = off
+			offs = append(offs, off)
+		}
+		body := make([]byte, entryItemsOffset-objectHeaderSize)
+		seq := tj.start + uint64(i)
+		binary.LittleEndian.PutUint64(body[0:], seq)
+		binary.LittleEndian.PutUint64(body[8:], uint64(testEpoch.Add(time.Duration(seq)*time.Second).UnixMicro()))
+		binary.LittleEndian.PutUint64(body[16:], seq*1000)
+		partial := tj.partial && i == len(tj.entries)-1
+		if tj.corrupt[i] {
+			offs[0] = testHeaderSize //the field object up front
+		}
+		for _, off := range offs {
+			if partial {
+				off = 0
+			}
+			if tj.compact {
+				body = binary.LittleEndian.AppendUint32(body, uint32(off))
+			} else {
+				body = binary.LittleEndian.AppendUint64(body, off)
+				body = binary.LittleEndian.AppendUint64(body, 0)
+			}
+		}
+		appendObj(objectEntry, 0, body)
+	}
+	binary.LittleEndian.PutUint64(buf[96:], uint64(len(buf)-testHeaderSize))
+	binary.LittleEndian.PutUint64(buf[136:], tail)
+	binary.LittleEndian.PutUint64(buf[152:], uint64(len(tj.entries)))
+	if len(tj.entries) > 0 {
+		binary.LittleEndian.PutUint64(buf[168:], tj.start)
+		binary.LittleEndian.PutUint64(buf[160:], tj.start+uint64(len(tj.entries))-1)
+	}
+	return buf
+}
+
+func (tj *testJournal) write(t *testing.T, pth string) {
+	if err := os.WriteFile(pth, tj.bytes(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func compressPayload(t *testing.T, flag uint8, payload []byte) []byte {
+	switch flag {
+	case objCompressedZSTD:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer enc.Close()
+		return enc.EncodeAll(payload, nil)
+	case objCompressedLZ4:
+		out := make([]byte, lz4.CompressBlockBound(len(payload)))
+		n, err := lz4.CompressBlock(payload, out, nil)
+		if err != nil || n == 0 {
+			t.Fatalf("failed to compress %v", err)
+		}
+		return append(binary.LittleEndian.AppendUint64(nil, uint64(len(payload))), out[:n]...)
+	}
+	t.Fatalf("bad compression flag %d", flag)
+	return nil
+}
+
+const longMessage = `MESSAGE=a long message that is well past the compression threshold so it gets compressed, repeated repeated repeated repeated`
+
+func testEntries() [][]string {
+	return [][]string{
+		{`_SYSTEMD_UNIT=sshd.service`, `PRIORITY=6`, `MESSAGE=Accepted publickey for root`},
+		{`_SYSTEMD_UNIT=cron.service`, `PRIORITY=6`, `MESSAGE=tick`},
+		{`_SYSTEMD_UNIT=sshd.service`, `PRIORITY=3`, longMessage},
+	}
+}
+
+func readAll(t *testing.T, jf *File, after uint64) (ents []*Entry) {
+	for {
+		ent, err := jf.Next(after)
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ents = append(ents, ent)
+	}
+}
+
+func TestJournalRead(t *testing.T) {
+	for _, tj := range []testJournal{
+		{entries: testEntries(), start: 10},
+		{entries: testEntries(), start: 10, compact: true, compress: objCompressedZSTD},
+		{entries: testEntries(), start: 10, compress: objCompressedLZ4},
+	} {
+		pth := filepath.Join(t.TempDir(), `system.journal`)
+		tj.write(t, pth)
+		jf, err := Open(pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ents := readAll(t, jf, 0)
+		jf.Close()
+		if len(ents) != 3 {
+			t.Fatalf("bad entry count %d", len(ents))
+		}
+		for i, ent := range ents {
+			if ent.Seqnum != 10+uint64(i) {
+				t.Fatalf("bad seqnum %d", ent.Seqnum)
+			} else if !ent.Realtime.Equal(testEpoch.Add(time.Duration(ent.Seqnum) * time.Second)) {
+				t.Fatalf("bad realtime %v", ent.Realtime)
+			} else if len(ent.Fields) != 3 {
+				t.Fatalf("bad field count %d", len(ent.Fields))
+			}
+		}
+		if v, ok := ents[2].Get(`MESSAGE`); !ok || `MESSAGE=`+string(v) != longMessage {
+			t.Fatalf("bad long message %q", v)
+		} else if v, ok = ents[2].Get(`_SYSTEMD_UNIT`); !ok || string(v) != `sshd.service` {
+			t.Fatalf("bad shared field %q", v)
+		}
+
+		//skipping by sequence number
+		if jf, err = Open(pth); err != nil {
+			t.Fatal(err)
+		}
+		if ents = readAll(t, jf, 11); len(ents) != 1 || ents[0].Seqnum != 12 {
+			t.Fatalf("bad skip %+v", ents)
+		}
+		jf.Close()
+	}
+}
+
+func TestJournalGrowing(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `system.journal`)
+	tj := testJournal{entries: testEntries()[:2], start: 1, partial: true}
+	tj.write(t, pth)
+	jf, err := Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jf.Close()
+	//the half written entry isn't handed out
+	if ents := readAll(t, jf, 0); len(ents) != 1 {
+		t.Fatalf("bad entry count %d", len(ents))
+	}
+	tj.partial = false
+	tj.entries = testEntries()
+	tj.write(t, pth)
+	if err = jf.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if ents := readAll(t, jf, 0); len(ents) != 2 || ents[0].Seqnum != 2 || ents[1].Seqnum != 3 {
+		t.Fatalf("bad entries after growth %+v", ents)
+	}
+
+	if err = os.WriteFile(pth, []byte(`not a journal at all`), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err = Open(pth); err != ErrBadSignature {
+		t.Fatalf("bad error on garbage %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journald
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidMatch = errors.New("journal match must be of the form FIELD=value")
+)
+
+// Matcher filters entries the same way journalctl matches do, matches on the same field
+// are ORed together and matches on different fields are ANDed
+type Matcher struct {
+	fields map[string][]string
+}
+
+// NewMatcher builds a matcher from a list of FIELD=value matches, no matches means everything matches
+func NewMatcher(matches []string) (*Matcher, error) {
+	m := &Matcher{
+		fields: make(map[string][]string, len(matches)),
+	}
+	for _, v := range matches {
+		name, val, ok := strings.Cut(v, `=`)
+		if !ok || !ValidFieldName(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMatch, v)
+		}
+		m.fields[name] = append(m.fields[name], val)
+	}
+	return m, nil
+}
+
+// Match returns true if the entry satisfies every field in the matcher
+func (m *Matcher) Match(e *Entry) bool {
+	if m == nil {
+		return true
+	}
+	for name, vals := range m.fields {
+		var hit bool
+		for _, f := range e.Fields {
+			if f.Name != name {
+				continue
+			}
+			for _, v := range vals {
+				if string(f.Value) == v {
+					hit = true
+					break
+				}
+			}
+			if hit {
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidFieldName checks a field name against the rules journald applies: upper case
+// letters, digits, and underscores, not starting with a digit
+func ValidFieldName(name string) bool {
+	if name == `` || len(name) > 64 || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journald
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	journalExt = `.journal`
+)
+
+var (
+	DefaultPaths = []string{`/var/log/journal`, `/run/log/journal`}
+
+	ErrNoBookmark = errors.New("reader requires a bookmark handler")
+)
+
+type ReaderConfig struct {
+	Name        string   // unique name for the reader, used to scope bookmarks
+	Paths       []string // journal directories, machine-id subdirectories are searched too
+	Bookmark    *BookmarkHandler
+	Matcher     *Matcher
+	ReadHistory bool // read entries written before the reader was first started
+	Logger      ingest.IngestLogger
+}
+
+// Reader follows every journal file under a set of directories.  Files are grouped into
+// streams, e.g. system or user-1000 along with their archived copies, and each stream is
+// read in sequence number order with the last sequence number kept in the bookmark.
+type Reader struct {
+	ReaderConfig
+	files    map[string]*File
+	finished map[string]bool // archived files we have read all the way through
+	fresh    bool            // no bookmarks existed when we started
+}
+
+type stream struct {
+	key   string
+	files []*File
+}
+
+func NewReader(cfg ReaderConfig) (*Reader, error) {
+	if cfg.Bookmark == nil {
+		return nil, ErrNoBookmark
+	}
+	if len(cfg.Paths) == 0 {
+		cfg.Paths = DefaultPaths
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = ingest.NoLogger()
+	}
+	return &Reader{
+		ReaderConfig: cfg,
+		files:        map[string]*File{},
+		finished:     map[string]bool{},
+		fresh:        !cfg.Bookmark.HasPrefix(cfg.Name + `/`),
+	}, nil
+}
+
+// Poll hands every new entry that passes the matcher to fn, in order within each stream.
+// Bookmarks are updated in memory as entries are handed out, call Sync on the bookmark
+// handler to persist them.  If fn returns an error polling stops and the entry will be
+// handed out again on the next poll.
+func (r *Reader) Poll(fn func(*Entry) error) error {
+	streams, err := r.scan()
+	if err != nil {
+		return err
+	}
+	for _, s := range streams {
+		if err := r.readStream(s, fn); err != nil {
+			return err
+		}
+	}
+	r.fresh = false
+	return nil
+}
+
+func (r *Reader) readStream(s stream, fn func(*Entry) error) error {
+	last, err := r.Bookmark.Get(s.key)
+	if err != nil {
+		return err
+	}
+	if last == 0 && r.fresh && !r.ReadHistory {
+		//first start, skip everything that is already there
+		for _, jf := range s.files {
+			if tail := jf.Header().TailEntrySeqnum; tail > last {
+				last = tail
+			}
+		}
+		if err := r.Bookmark.Update(s.key, last); err != nil {
+			return err
+		}
+	}
+	for _, jf := range s.files {
+		var broken bool
+		for {
+			ent, err := jf.Next(last)
+			var eerr *EntryError
+			if err == io.EOF {
+				break
+			} else if errors.As(err, &eerr) {
+				//a bad entry will never decode, step over it so the rest of the stream keeps flowing
+				r.Logger.Warn("skipping bad journal entry", log.KV("file", jf.Path()), log.KV("seqnum", eerr.Seqnum), log.KVErr(eerr.Err))
+				if eerr.Seqnum > last {
+					last = eerr.Seqnum
+					if err := r.Bookmark.Update(s.key, last); err != nil {
+						return err
+					}
+				}
+				continue
+			} else if err != nil {
+				//a broken file shouldn't stop the rest of the stream
+				r.Logger.Error("failed to read journal file", log.KV("file", jf.Path()), log.KVErr(err))
+				broken = true
+				break
+			}
+			if r.Matcher.Match(ent) {
+				if err := fn(ent); err != nil {
+					jf.unread()
+					return err
+				}
+			}
+			last = ent.Seqnum
+			if err := r.Bookmark.Update(s.key, last); err != nil {
+				return err
+			}
+		}
+		if archived(jf.Path()) {
+			//archived files never change, one that is broken past this point won't get any better
+			r.finished[jf.Path()] = true
+			r.drop(jf)
+		} else if broken {
+			r.drop(jf)
+		}
+	}
+	return nil
+}
+
+// scan finds journal files, opens new ones, refreshes the ones we have, and groups them into streams
+func (r *Reader) scan() ([]stream, error) {
+	seen := map[string]bool{}
+	for _, p := range r.Paths {
+		for _, pattern := range []string{`*` + journalExt, `*/*` + journalExt} {
+			mtchs, err := filepath.Glob(filepath.Join(p, pattern))
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range mtchs {
+				seen[m] = true
+			}
+		}
+	}
+	//close out anything that was vacuumed or rotated away
+	for pth, jf := range r.files {
+		if !seen[pth] {
+			r.drop(jf)
+		}
+	}
+	for pth := range r.finished {
+		if !seen[pth] {
+			delete(r.finished, pth)
+		}
+	}
+	mp := map[string]*stream{}
+	for pth := range seen {
+		if r.finished[pth] {
+			continue
+		}
+		jf, ok := r.files[pth]
+		if !ok {
+			var err error
+			if jf, err = Open(pth); err != nil {
+				//journald may be in the middle of creating it, or it's not a journal at all
+				continue
+			}
+			r.files[pth] = jf
+		} else if err := jf.Refresh(); err != nil {
+			r.drop(jf)
+			continue
+		}
+		hdr := jf.Header()
+		key := r.Name + `/` + streamName(pth) + `/` + hdr.SeqnumID.String()
+		s, ok := mp[key]
+		if !ok {
+			s = &stream{key: key}
+			mp[key] = s
+		}
+		s.files = append(s.files, jf)
+	}
+	streams := make([]stream, 0, len(mp))
+	for _, s := range mp {
+		sort.Slice(s.files, func(i, j int) bool {
+			return s.files[i].Header().HeadEntrySeqnum < s.files[j].Header().HeadEntrySeqnum
+		})
+		streams = append(streams, *s)
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].key < streams[j].key })
+	return streams, nil
+}
+
+func (r *Reader) drop(jf *File) {
+	delete(r.files, jf.Path())
+	jf.Close()
+}
+
+// Close closes all open journal files, the bookmark handler is left open
+func (r *Reader) Close() (err error) {
+	for _, jf := range r.files {
+		if lerr := jf.Close(); lerr != nil {
+			err = lerr
+		}
+	}
+	r.files = map[string]*File{}
+	return
+}
+
+// streamName strips the archive suffix off a journal file name, system@xxx.journal is part of system
+func streamName(pth string) string {
+	name := strings.TrimSuffix(filepath.Base(pth), journalExt)
+	if idx := strings.IndexByte(name, '@'); idx >= 0 {
+		name = name[:idx]
+	}
+	return filepath.Join(filepath.Base(filepath.Dir(pth)), name)
+}
+
+// archived files are never written to again
+func archived(pth string) bool {
+	return strings.Contains(filepath.Base(pth), `@`)
+}
@@ -0,0 +1,226 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journald
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testSeqnumID = ID128{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+func entriesFrom(start, count int) (r [][]string) {
+	ents := testEntries()
+	for i := 0; i < count; i++ {
+		r = append(r, ents[(start+i)%len(ents)])
+	}
+	return
+}
+
+// newTestDir lays out a machine-id directory with an archived system file, an online
+// system file that picks up where it left off, and a user journal
+func newTestDir(t *testing.T) (dir, online string) {
+	dir = t.TempDir()
+	mdir := filepath.Join(dir, `0123456789abcdef0123456789abcdef`)
+	if err := os.Mkdir(mdir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	arch := testJournal{seqnumID: testSeqnumID, entries: entriesFrom(0, 3), start: 1}
+	arch.write(t, filepath.Join(mdir, `system@`+testSeqnumID.String()+`-0000000000000001-0005f0f0f0f0f0f0.journal`))
+	sys := testJournal{seqnumID: testSeqnumID, entries: entriesFrom(0, 2), start: 4}
+	online = filepath.Join(mdir, `system.journal`)
+	sys.write(t, online)
+	user := testJournal{seqnumID: ID128{0xff}, entries: entriesFrom(1, 1), start: 1}
+	user.write(t, filepath.Join(mdir, `user-1000.journal`))
+	return
+}
+
+func pollAll(t *testing.T, r *Reader) (ents []*Entry) {
+	if err := r.Poll(func(e *Entry) error {
+		ents = append(ents, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestReaderHistory(t *testing.T) {
+	dir, online := newTestDir(t)
+	bm, err := NewBookmark(filepath.Join(t.TempDir(), `bookmark`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bm.Close()
+	r, err := NewReader(ReaderConfig{Name: `test`, Paths: []string{dir}, Bookmark: bm, ReadHistory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	//system stream sorts first and reads the archived file before the online one
+	ents := pollAll(t, r)
+	if len(ents) != 6 {
+		t.Fatalf("bad entry count %d", len(ents))
+	}
+	for i := 0; i < 5; i++ {
+		if ents[i].Seqnum != uint64(i+1) {
+			t.Fatalf("entry %d out of order: %d", i, ents[i].Seqnum)
+		}
+	}
+	if ents = pollAll(t, r); len(ents) != 0 {
+		t.Fatalf("got %d entries on an idle poll", len(ents))
+	}
+
+	//the online file grows
+	sys := testJournal{seqnumID: testSeqnumID, entries: entriesFrom(0, 3), start: 4}
+	sys.write(t, online)
+	if ents = pollAll(t, r); len(ents) != 1 || ents[0].Seqnum != 6 {
+		t.Fatalf("bad entries after growth %+v", ents)
+	}
+
+	//a failed handler gets the entry again
+	sys.entries = entriesFrom(0, 4)
+	sys.write(t, online)
+	errTest := errors.New("test")
+	if err = r.Poll(func(*Entry) error { return errTest }); err != errTest {
+		t.Fatalf("bad poll error %v", err)
+	}
+	if ents = pollAll(t, r); len(ents) != 1 || ents[0].Seqnum != 7 {
+		t.Fatalf("bad entries after failed handler %+v", ents)
+	}
+}
+
+func TestReaderResume(t *testing.T) {
+	dir, online := newTestDir(t)
+	bmPath := filepath.Join(t.TempDir(), `bookmark`)
+	bm, err := NewBookmark(bmPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtchr, err := NewMatcher([]string{`_SYSTEMD_UNIT=sshd.service`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := ReaderConfig{Name: `test`, Paths: []string{dir}, Bookmark: bm, Matcher: mtchr}
+	r, err := NewReader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//first start without history skips what is already there
+	if ents := pollAll(t, r); len(ents) != 0 {
+		t.Fatalf("got %d entries on a fresh start", len(ents))
+	}
+	sys := testJournal{seqnumID: testSeqnumID, entries: entriesFrom(0, 4), start: 4}
+	sys.write(t, online)
+	if ents := pollAll(t, r); len(ents) != 2 || ents[0].Seqnum != 6 || ents[1].Seqnum != 7 {
+		t.Fatalf("bad matched entries %+v", ents)
+	}
+	r.Close()
+	if err = bm.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	//restart and pick up from the bookmark
+	sys.entries = entriesFrom(0, 6)
+	sys.write(t, online)
+	if cfg.Bookmark, err = NewBookmark(bmPath); err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Bookmark.Close()
+	if r, err = NewReader(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if ents := pollAll(t, r); len(ents) != 1 || ents[0].Seqnum != 9 {
+		t.Fatalf("bad entries after restart %+v", ents)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	e := &Entry{Fields: []Field{
+		{Name: `_SYSTEMD_UNIT`, Value: []byte(`sshd.service`)},
+		{Name: `PRIORITY`, Value: []byte(`3`)},
+	}}
+	tests := []struct {
+		matches []string
+		ok      bool
+	}{
+		{nil, true},
+		{[]string{`_SYSTEMD_UNIT=sshd.service`}, true},
+		{[]string{`_SYSTEMD_UNIT=cron.service`, `_SYSTEMD_UNIT=sshd.service`}, true},
+		{[]string{`_SYSTEMD_UNIT=sshd.service`, `PRIORITY=6`}, false},
+		{[]string{`SYSLOG_IDENTIFIER=sshd`}, false},
+	}
+	for _, tt := range tests {
+		m, err := NewMatcher(tt.matches)
+		if err != nil {
+			t.Fatal(err)
+		} else if m.Match(e) != tt.ok {
+			t.Fatalf("bad match result on %v", tt.matches)
+		}
+	}
+	for _, v := range []string{`nofield`, `lower=case`, `=value`, `1ABC=x`} {
+		if _, err := NewMatcher([]string{v}); !errors.Is(err, ErrInvalidMatch) {
+			t.Fatalf("invalid match %q not rejected: %v", v, err)
+		}
+	}
+}
+
+func TestReaderBadEntries(t *testing.T) {
+	for _, name := range []string{`system.journal`, `system@` + testSeqnumID.String() + `-0000000000000001-0005f0f0f0f0f0f0.journal`} {
+		dir := t.TempDir()
+		tj := testJournal{
+			seqnumID: testSeqnumID,
+			entries:  entriesFrom(0, 6),
+			start:    1,
+			xzField:  `MESSAGE=tick`,
+			corrupt:  map[int]bool{2: true},
+		}
+		tj.write(t, filepath.Join(dir, name))
+		bm, err := NewBookmark(filepath.Join(t.TempDir(), `bookmark`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer bm.Close()
+		r, err := NewReader(ReaderConfig{Name: `test`, Paths: []string{dir}, Bookmark: bm, ReadHistory: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		//the corrupt entry is skipped and the XZ field is dropped from its entries
+		ents := pollAll(t, r)
+		if len(ents) != 5 {
+			t.Fatalf("%s: bad entry count %d", name, len(ents))
+		}
+		for i, seq := range []uint64{1, 2, 4, 5, 6} {
+			if ents[i].Seqnum != seq {
+				t.Fatalf("%s: bad seqnum %d != %d", name, ents[i].Seqnum, seq)
+			}
+		}
+		if _, ok := ents[1].Get(`MESSAGE`); ok || len(ents[1].Fields) != 2 {
+			t.Fatalf("%s: XZ field not skipped %+v", name, ents[1].Fields)
+		} else if v, ok := ents[3].Get(`_SYSTEMD_UNIT`); !ok || string(v) != `cron.service` {
+			t.Fatalf("%s: bad entry after XZ field %+v", name, ents[3].Fields)
+		}
+		if last, err := bm.Get(`test/` + streamName(filepath.Join(dir, name)) + `/` + testSeqnumID.String()); err != nil || last != 6 {
+			t.Fatalf("%s: bad bookmark %d %v", name, last, err)
+		}
+
+		//nothing is handed out again and the file stays open if it is online
+		if ents = pollAll(t, r); len(ents) != 0 {
+			t.Fatalf("%s: got %d entries on an idle poll", name, len(ents))
+		} else if _, ok := r.files[filepath.Join(dir, name)]; ok == archived(name) {
+			t.Fatalf("%s: bad open state %v", name, ok)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/config"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 )
 
 var (
@@ -42,6 +43,7 @@ type global struct {
 }
 
 type cfgType struct {
+	base.ConfigLayout[cfgReadType]
 	global
 	Files        map[string]*files
 	Splunk       map[string]*splunk
@@ -16,7 +16,7 @@ import (
 
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/log"
-	"github.com/gravwell/gravwell/v3/ingesters/version"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
 )
 
 const (
@@ -28,40 +28,16 @@ func getIngestConnection(cfg *cfgType, lg *log.Logger) *ingest.IngestMuxer {
 	if err != nil {
 		lg.FatalCode(0, "failed to get tags from configuration", log.KVErr(err))
 	}
-	conns, err := cfg.Targets()
-	if err != nil {
-		lg.FatalCode(0, "failed to get backend targets from configuration", log.KVErr(err))
-	}
-
-	lmt, err := cfg.RateLimit()
-	if err != nil {
-		lg.FatalCode(0, "failed to get rate limit from configuration", log.KVErr(err))
-	}
-	lg.Info("Rate limiting connection", log.KV("bps", lmt))
-
 	//fire up the ingesters
-	id, ok := cfg.IngesterUUID()
-	if !ok {
+	if _, ok := cfg.IngesterUUID(); !ok {
 		lg.FatalCode(0, "Couldn't read ingester UUID")
 	}
-	ingestConfig := ingest.UniformMuxerConfig{
-		IngestStreamConfig: cfg.IngestStreamConfig,
-		Destinations:       conns,
-		Tags:               tags,
-		Auth:               cfg.Secret(),
-		IngesterName:       appName,
-		IngesterVersion:    version.GetVersion(),
-		IngesterUUID:       id.String(),
-		IngesterLabel:      cfg.Label,
-		RateLimitBps:       lmt,
-		VerifyCert:         !cfg.InsecureSkipTLSVerification(),
-		Logger:             lg,
-		CacheDepth:         cfg.Cache_Depth,
-		CachePath:          cfg.Ingest_Cache_Path,
-		CacheSize:          cfg.Max_Ingest_Cache,
-		CacheMode:          cfg.Cache_Mode,
-		LogSourceOverride:  net.ParseIP(cfg.Log_Source_Override),
+	ingestConfig, err := base.MuxerConfig(cfg.IngestConfig, tags, appName)
+	if err != nil {
+		lg.FatalCode(0, "failed to get ingest configuration", log.KVErr(err))
 	}
+	lg.Info("Rate limiting connection", log.KV("bps", ingestConfig.RateLimitBps))
+	ingestConfig.Logger = lg
 	igst, err := ingest.NewUniformMuxer(ingestConfig)
 	if err != nil {
 		lg.Fatal("failed build our ingest system", log.KVErr(err))
@@ -0,0 +1,281 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_FORMAT_CACHE_SIZE int = 1024
+)
+
+// FormatKey identifies a stream of data whose timestamp format is remembered when format
+// learning is enabled.  Any field may be empty, the zero key is used by Extract.
+type FormatKey struct {
+	Source   string
+	Tag      string
+	Filename string
+}
+
+// ProcessorStats reports how a single processor has fared while learning formats.
+// Hits are successful extractions, Misses are attempts that did not find a timestamp.
+type ProcessorStats struct {
+	Name   string
+	Hits   uint64
+	Misses uint64
+}
+
+// Stats reports the effectiveness of format learning.  A hit means the format remembered for
+// a key extracted the timestamp, a miss means the processors had to be scanned and a failure
+// means no processor could extract a timestamp.  Processors are listed in their current order.
+type Stats struct {
+	Hits       uint64
+	Misses     uint64
+	Failures   uint64
+	Keys       int
+	Evictions  uint64
+	Processors []ProcessorStats
+}
+
+// Merge adds the counters of another set of statistics, processors are matched by name
+// and stay in the order they were first seen
+func (s *Stats) Merge(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Failures += o.Failures
+	s.Keys += o.Keys
+	s.Evictions += o.Evictions
+	for _, op := range o.Processors {
+		var found bool
+		for i := range s.Processors {
+			if s.Processors[i].Name == op.Name {
+				s.Processors[i].Hits += op.Hits
+				s.Processors[i].Misses += op.Misses
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.Processors = append(s.Processors, op)
+		}
+	}
+}
+
+type procStat struct {
+	p      Processor
+	hits   uint64
+	misses uint64
+}
+
+// formatLearner remembers the last successful processor for each key and orders
+// the processors by how often they succeed so that misses find a match quickly
+type formatLearner struct {
+	mtx       sync.Mutex
+	size      int
+	keys      map[FormatKey]*procStat
+	order     []*procStat //replaced, never modified in place, so scans can hold it without the lock
+	hits      uint64
+	misses    uint64
+	failures  uint64
+	evictions uint64
+}
+
+func newFormatLearner(procs []Processor, size int) *formatLearner {
+	if size <= 0 {
+		size = DEFAULT_FORMAT_CACHE_SIZE
+	}
+	fl := &formatLearner{
+		size:  size,
+		keys:  make(map[FormatKey]*procStat),
+		order: make([]*procStat, 0, len(procs)),
+	}
+	for _, p := range procs {
+		fl.order = append(fl.order, &procStat{p: p})
+	}
+	return fl
+}
+
+// add puts a new processor at the front of the order, just like AddProcessor does
+func (fl *formatLearner) add(p Processor) {
+	fl.mtx.Lock()
+	fl.order = append([]*procStat{{p: p}}, fl.order...)
+	fl.mtx.Unlock()
+}
+
+// lookup returns the processor remembered for the key, if any, and the current order
+func (fl *formatLearner) lookup(key FormatKey) (last *procStat, order []*procStat) {
+	fl.mtx.Lock()
+	last, order = fl.keys[key], fl.order
+	fl.mtx.Unlock()
+	return
+}
+
+func (fl *formatLearner) hit(ps *procStat) {
+	fl.mtx.Lock()
+	fl.hits++
+	ps.hits++
+	fl.mtx.Unlock()
+}
+
+// learned records that the processor succeeded for the key after the processors in tried failed
+func (fl *formatLearner) learned(key FormatKey, ps *procStat, tried []*procStat) {
+	fl.mtx.Lock()
+	fl.misses++
+	fl.missed(tried)
+	ps.hits++
+	if _, ok := fl.keys[key]; !ok && len(fl.keys) >= fl.size {
+		fl.evict()
+	}
+	fl.keys[key] = ps
+	fl.reorder()
+	fl.mtx.Unlock()
+}
+
+// failed records that no processor could extract a timestamp for the key
+func (fl *formatLearner) failed(key FormatKey, tried []*procStat) {
+	fl.mtx.Lock()
+	fl.misses++
+	fl.failures++
+	fl.missed(tried)
+	delete(fl.keys, key)
+	fl.mtx.Unlock()
+}
+
+func (fl *formatLearner) missed(tried []*procStat) {
+	for _, ps := range tried {
+		ps.misses++
+	}
+}
+
+// evict drops a single key to make room, caller must hold the lock
+func (fl *formatLearner) evict() {
+	for k := range fl.keys {
+		delete(fl.keys, k)
+		fl.evictions++
+		return
+	}
+}
+
+// reorder sorts the processors by hits when they are out of order, caller must hold the lock
+func (fl *formatLearner) reorder() {
+	if sort.SliceIsSorted(fl.order, func(i, j int) bool { return fl.order[i].hits > fl.order[j].hits }) {
+		return
+	}
+	order := make([]*procStat, len(fl.order))
+	copy(order, fl.order)
+	//stable so that processors with the same hit count keep their original precedence
+	sort.SliceStable(order, func(i, j int) bool { return order[i].hits > order[j].hits })
+	fl.order = order
+}
+
+func (fl *formatLearner) stats() (s Stats) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+	s = Stats{
+		Hits:       fl.hits,
+		Misses:     fl.misses,
+		Failures:   fl.failures,
+		Keys:       len(fl.keys),
+		Evictions:  fl.evictions,
+		Processors: make([]ProcessorStats, 0, len(fl.order)),
+	}
+	for _, ps := range fl.order {
+		s.Processors = append(s.Processors, ProcessorStats{
+			Name:   ps.p.Name(),
+			Hits:   ps.hits,
+			Misses: ps.misses,
+		})
+	}
+	return
+}
+
+func (fl *formatLearner) reset() {
+	fl.mtx.Lock()
+	fl.keys = make(map[FormatKey]*procStat)
+	fl.hits, fl.misses, fl.failures, fl.evictions = 0, 0, 0, 0
+	for _, ps := range fl.order {
+		ps.hits, ps.misses = 0, 0
+	}
+	fl.mtx.Unlock()
+}
+
+// ExtractKey extracts a timestamp using the format remembered for the key, the processors are only
+// scanned when the remembered format misses.  Without format learning enabled this is just Extract.
+func (tg *TimeGrinder) ExtractKey(key FormatKey, data []byte) (t time.Time, ok bool, err error) {
+	if tg.learn == nil {
+		return tg.Extract(data)
+	}
+	if tg.override != nil {
+		if t, ok, _ = tg.override.Extract(data, tg.loc); ok {
+			return
+		}
+	}
+	last, order := tg.learn.lookup(key)
+	if last != nil {
+		if t, ok, _ = last.p.Extract(data, tg.loc); ok {
+			tg.learn.hit(last)
+			return
+		}
+	}
+	tried := make([]*procStat, 0, len(order))
+	if last != nil {
+		tried = append(tried, last)
+	} else if tg.EnableLeftMostSeed {
+		//first time we have seen this key, take the hit and find the left most format
+		if ps, lt, lok := tg.seedKey(data, order); lok {
+			tg.learn.learned(key, ps, nil)
+			t, ok = lt, true
+			return
+		}
+		tg.learn.failed(key, nil)
+		return
+	}
+	for _, ps := range order {
+		if ps == last {
+			continue
+		}
+		if t, ok, _ = ps.p.Extract(data, tg.loc); ok {
+			tg.learn.learned(key, ps, tried)
+			return
+		}
+		tried = append(tried, ps)
+	}
+	tg.learn.failed(key, tried)
+	ok = false
+	return
+}
+
+// seedKey runs every processor and picks the one that matched closest to the start of the data
+func (tg *TimeGrinder) seedKey(data []byte, order []*procStat) (best *procStat, t time.Time, ok bool) {
+	leftmost := -1
+	for _, ps := range order {
+		if lt, lok, offset := ps.p.Extract(data, tg.loc); lok && (leftmost < 0 || offset < leftmost) {
+			best, t, ok, leftmost = ps, lt, true, offset
+		}
+	}
+	return
+}
+
+// Stats returns the format learning counters, the zero value is returned when learning is not enabled
+func (tg *TimeGrinder) Stats() (s Stats) {
+	if tg.learn != nil {
+		s = tg.learn.stats()
+	}
+	return
+}
+
+// ResetStats forgets all learned formats and zeroes the format learning counters
+func (tg *TimeGrinder) ResetStats() {
+	if tg.learn != nil {
+		tg.learn.reset()
+	}
+}
@@ -0,0 +1,116 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatLearning(t *testing.T) {
+	tg, err := New(Config{EnableFormatLearning: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	syslog := FormatKey{Source: `10.0.0.1`, Tag: `syslog`}
+	app := FormatKey{Filename: `/var/log/app.log`}
+	lines := []struct {
+		key  FormatKey
+		data string
+		ts   time.Time
+	}{
+		{syslog, `2018-04-19T05:50:19Z host sshd[22]: accepted`, time.Date(2018, 4, 19, 5, 50, 19, 0, time.UTC)},
+		{app, `1524117019 app started`, time.Unix(1524117019, 0)},
+		{syslog, `2018-04-19T05:50:20Z host sshd[22]: closed`, time.Date(2018, 4, 19, 5, 50, 20, 0, time.UTC)},
+		{app, `1524117020 app stopped`, time.Unix(1524117020, 0)},
+		{syslog, `2018-04-19T05:50:21Z host cron[1]: tick`, time.Date(2018, 4, 19, 5, 50, 21, 0, time.UTC)},
+		{app, `1524117021 app started`, time.Unix(1524117021, 0)},
+	}
+	for i, l := range lines {
+		ts, ok, err := tg.ExtractKey(l.key, []byte(l.data))
+		if err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			t.Fatalf("%d failed to extract", i)
+		} else if !ts.Equal(l.ts) {
+			t.Fatalf("%d bad timestamp %v != %v", i, ts, l.ts)
+		}
+	}
+	//the first line of each key is a miss, everything after hits the remembered format
+	s := tg.Stats()
+	if s.Hits != 4 || s.Misses != 2 || s.Failures != 0 || s.Keys != 2 {
+		t.Fatalf("bad stats %+v", s)
+	} else if len(s.Processors) != tg.count {
+		t.Fatalf("bad processor count %d", len(s.Processors))
+	}
+	//the two formats that succeeded move to the front
+	for _, ps := range s.Processors[:2] {
+		if ps.Hits != 3 {
+			t.Fatalf("bad processor order %+v", s.Processors[:2])
+		}
+	}
+
+	if _, ok, _ := tg.ExtractKey(app, []byte(`no timestamp here`)); ok {
+		t.Fatal("extracted a timestamp from nothing")
+	}
+	if s = tg.Stats(); s.Failures != 1 || s.Keys != 1 {
+		t.Fatalf("bad stats after failure %+v", s)
+	}
+	tg.ResetStats()
+	if s = tg.Stats(); s.Hits != 0 || s.Misses != 0 || s.Keys != 0 || s.Processors[0].Hits != 0 {
+		t.Fatalf("bad stats after reset %+v", s)
+	}
+}
+
+func TestFormatLearningSeed(t *testing.T) {
+	tg, err := New(Config{EnableFormatLearning: true, EnableLeftMostSeed: true, FormatCacheSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tval := []byte(`2018-04-19T05:50:19-07:00 2018-04-15T00:00:00Z 1234567890 02-03-2018 12:30:00`)
+	tt, err := time.Parse(time.RFC3339, `2018-04-19T05:50:19-07:00`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []FormatKey{{Source: `a`}, {Source: `b`}} {
+		if ts, ok, err := tg.ExtractKey(k, tval); err != nil || !ok {
+			t.Fatalf("failed to extract %v %v", ok, err)
+		} else if !ts.Equal(tt) {
+			t.Fatalf("seeded the wrong format %v != %v", ts, tt)
+		}
+	}
+	if s := tg.Stats(); s.Keys != 1 || s.Evictions != 1 {
+		t.Fatalf("cache not bounded %+v", s)
+	}
+
+	//learning is off by default
+	if tg, err = New(Config{}); err != nil {
+		t.Fatal(err)
+	} else if _, ok, _ := tg.ExtractKey(FormatKey{}, tval); !ok {
+		t.Fatal("failed to extract without learning")
+	} else if s := tg.Stats(); s.Hits != 0 || s.Processors != nil {
+		t.Fatalf("stats without learning %+v", s)
+	}
+}
+
+func TestStatsMerge(t *testing.T) {
+	s := Stats{Hits: 1, Misses: 2, Keys: 1, Processors: []ProcessorStats{{Name: `a`, Hits: 1}, {Name: `b`, Misses: 2}}}
+	s.Merge(Stats{Hits: 3, Failures: 1, Keys: 2, Evictions: 4, Processors: []ProcessorStats{{Name: `b`, Hits: 2}, {Name: `c`, Misses: 1}}})
+	exp := Stats{Hits: 4, Misses: 2, Failures: 1, Keys: 3, Evictions: 4,
+		Processors: []ProcessorStats{{Name: `a`, Hits: 1}, {Name: `b`, Hits: 2, Misses: 2}, {Name: `c`, Misses: 1}},
+	}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatalf("bad merge %+v", s)
+	}
+	var z Stats
+	if z.Merge(Stats{}); !reflect.DeepEqual(z, Stats{}) {
+		t.Fatalf("bad empty merge %+v", z)
+	}
+}
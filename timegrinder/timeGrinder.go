@@ -44,6 +44,7 @@ type TimeGrinder struct {
 	seed     bool
 	override Processor
 	loc      *time.Location
+	learn    *formatLearner
 }
 
 // Config defines a few configuration options when instantiating a new TimeGrinder.
@@ -56,6 +57,12 @@ type Config struct {
 	FormatOverride string
 	// TSWindow sets maximum deltas into the past & future for timestamp parsing. Any timestamp extracted which falls outside those deltas from the current time will be considered invalid and skipped.
 	TSWindow TimestampWindow
+	// EnableFormatLearning remembers the last successful format for each FormatKey handed to ExtractKey
+	// and tries processors in order of how often they succeed, Extract uses a single shared key.
+	// When combined with EnableLeftMostSeed each new key is seeded with its left most format.
+	EnableFormatLearning bool
+	// FormatCacheSize caps the number of keys remembered by format learning, zero uses DEFAULT_FORMAT_CACHE_SIZE.
+	FormatCacheSize int
 }
 
 func Extract(b []byte) (t time.Time, ok bool, err error) {
@@ -198,6 +205,9 @@ func New(c Config) (tg *TimeGrinder, err error) {
 		loc:    time.UTC,
 		seed:   c.EnableLeftMostSeed,
 	}
+	if c.EnableFormatLearning {
+		tg.learn = newFormatLearner(procs, c.FormatCacheSize)
+	}
 	if c.FormatOverride != `` {
 		err = tg.SetFormatOverride(c.FormatOverride)
 	}
@@ -260,6 +270,9 @@ func (tg *TimeGrinder) AddProcessor(p Processor) (idx int, err error) {
 	p.SetWindow(tg.TSWindow)
 	tg.procs = append([]Processor{p}, tg.procs...)
 	tg.count++
+	if tg.learn != nil {
+		tg.learn.add(p)
+	}
 	idx = 0
 	return
 }
@@ -314,6 +327,10 @@ func (tg *TimeGrinder) Extract(data []byte) (t time.Time, ok bool, err error) {
 	var i int
 	var c int
 
+	if tg.learn != nil {
+		return tg.ExtractKey(FormatKey{}, data)
+	}
+
 	if tg.override != nil {
 		if t, ok, _ = tg.override.Extract(data, tg.loc); ok {
 			return
@@ -0,0 +1,88 @@
+/*************************************************************************
+ * Copyright 2026 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gravwell/gravwell/v3/ingest/config"
+)
+
+var (
+	ksPath   = flag.String("keystore", config.DefaultKeystorePath, "Path to the keystore, created if it does not exist")
+	passFile = flag.String("passphrase-file", "", "File containing the keystore passphrase, GRAVWELL_KEYSTORE_PASSPHRASE is used when empty")
+	set      = flag.String("set", "", "Name of an entry to set, the value is read from stdin")
+	del      = flag.String("delete", "", "Name of an entry to delete")
+	list     = flag.Bool("list", false, "List the names of every entry")
+)
+
+func main() {
+	flag.Parse()
+	pass, err := passphrase()
+	if err != nil {
+		log.Fatalf("Failed to get keystore passphrase: %v\n", err)
+	}
+	ks, err := config.OpenKeystore(*ksPath, pass)
+	if errors.Is(err, os.ErrNotExist) {
+		ks, err = config.NewKeystore(*ksPath, pass)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open keystore %q: %v\n", *ksPath, err)
+	}
+
+	var dirty bool
+	if *set != `` {
+		fmt.Fprintf(os.Stderr, "Enter the value for %q followed by a newline:\n", *set)
+		v, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatalf("Failed to read value: %v\n", err)
+		} else if err = ks.Set(*set, strings.TrimRight(v, "\r\n")); err != nil {
+			log.Fatalf("Failed to set %q: %v\n", *set, err)
+		}
+		dirty = true
+	}
+	if *del != `` {
+		if _, ok := ks.Get(*del); !ok {
+			log.Fatalf("Entry %q does not exist\n", *del)
+		}
+		ks.Delete(*del)
+		dirty = true
+	}
+	if dirty {
+		if err = ks.Save(); err != nil {
+			log.Fatalf("Failed to save keystore %q: %v\n", *ksPath, err)
+		}
+	}
+	if *list {
+		for _, name := range ks.Names() {
+			fmt.Println(name)
+		}
+	}
+}
+
+func passphrase() (string, error) {
+	if *passFile != `` {
+		b, err := os.ReadFile(*passFile)
+		if err != nil {
+			return ``, err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	if v, ok := os.LookupEnv(`GRAVWELL_KEYSTORE_PASSPHRASE`); ok && v != `` {
+		return v, nil
+	}
+	return ``, config.ErrKeystoreNoPassphrase
+}